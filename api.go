@@ -0,0 +1,40 @@
+//go:build !js
+
+package webrtc
+
+// API creates RTCPeerConnections sharing a MediaEngine and a SettingEngine,
+// New uses an API with the DefaultMediaEngine and the default settings.
+type API struct {
+	mediaEngine   *MediaEngine
+	settingEngine *SettingEngine
+}
+
+// NewAPI creates an API with the options applied
+func NewAPI(options ...func(*API)) *API {
+	api := &API{mediaEngine: DefaultMediaEngine}
+	for _, option := range options {
+		option(api)
+	}
+
+	if api.settingEngine == nil {
+		api.settingEngine = &SettingEngine{}
+	}
+	return api
+}
+
+// WithMediaEngine sets the MediaEngine of the RTCPeerConnections created by
+// the API. With a nil MediaEngine they carry data channels only, offering no
+// audio or video sections and accepting no tracks.
+func WithMediaEngine(m *MediaEngine) func(*API) {
+	return func(api *API) {
+		api.mediaEngine = m
+	}
+}
+
+// WithSettingEngine sets the SettingEngine of the RTCPeerConnections created
+// by the API, later changes to the SettingEngine do not affect the API
+func WithSettingEngine(s SettingEngine) func(*API) {
+	return func(api *API) {
+		api.settingEngine = &s
+	}
+}
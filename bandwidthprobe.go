@@ -0,0 +1,173 @@
+//go:build !js
+
+package webrtc
+
+import (
+	"encoding/binary"
+	"time"
+)
+
+const (
+	// bandwidthProbePings is how many pings the round trip time of the idle
+	// connection is measured with, the lowest is kept
+	bandwidthProbePings = 3
+
+	// bandwidthProbeDuration is how long the padding of a probe is sent for
+	bandwidthProbeDuration = 200 * time.Millisecond
+
+	// bandwidthProbeMaxDelay is how much later than a round trip the report
+	// of a probe can arrive before the queuing delay is considered to tell
+	// the bandwidth is exceeded
+	bandwidthProbeMaxDelay = 25 * time.Millisecond
+
+	defaultBandwidthProbeStartBitrate = 300000
+	defaultBandwidthProbeMaxBitrate   = 10000000
+	defaultBandwidthProbeTimeout      = 10 * time.Second
+)
+
+// RTCBandwidthProbeOptions configures RunRTCBandwidthProbe, the zero value of
+// a member selects its default
+type RTCBandwidthProbeOptions struct {
+	// StartBitrate is the rate of the first probe in bits per second, 300
+	// kbps by default.
+	StartBitrate uint64
+
+	// MaxBitrate is the rate the probes stop at in bits per second, 10 Mbps
+	// by default.
+	MaxBitrate uint64
+
+	// Timeout bounds the whole probing, 10 seconds by default.
+	Timeout time.Duration
+}
+
+// RTCBandwidthEstimate is the outcome of a bandwidth probe
+type RTCBandwidthEstimate struct {
+	// Bitrate is the estimated bandwidth to the remote peer in bits per
+	// second, the rate the remote peer received the highest probe which
+	// didn't exceed it with.
+	Bitrate uint64
+
+	// RTT is the round trip time of the connection before it was probed.
+	RTT time.Duration
+
+	// Probes is how many probes were sent.
+	Probes int
+}
+
+// RunRTCBandwidthProbe estimates the bandwidth to the remote peer over an
+// open data channel labeled RTCNetworkTestLabel, which the remote peer serves
+// with ServeRTCNetworkTest. Probes of padding are sent at a doubling rate,
+// until one of them is delayed by queuing or MaxBitrate is reached, so the
+// estimate can pick the bitrate of the media before it is sent. Data
+// channels retransmit what the network drops, so loss is only seen as the
+// delay of the retransmissions. The Onmessage handler of the channel is
+// replaced, and options may be nil.
+func RunRTCBandwidthProbe(d *RTCDataChannel, options *RTCBandwidthProbeOptions) (RTCBandwidthEstimate, error) {
+	send, replies := networkTestClient(d)
+	return runBandwidthProbe(send, replies, options)
+}
+
+func runBandwidthProbe(send func([]byte) error, replies <-chan []byte, options *RTCBandwidthProbeOptions) (RTCBandwidthEstimate, error) {
+	startBitrate := uint64(defaultBandwidthProbeStartBitrate)
+	maxBitrate := uint64(defaultBandwidthProbeMaxBitrate)
+	timeout := defaultBandwidthProbeTimeout
+	if options != nil {
+		if options.StartBitrate != 0 {
+			startBitrate = options.StartBitrate
+		}
+		if options.MaxBitrate != 0 {
+			maxBitrate = options.MaxBitrate
+		}
+		if options.Timeout != 0 {
+			timeout = options.Timeout
+		}
+	}
+
+	estimate := RTCBandwidthEstimate{}
+	deadline := time.After(timeout)
+
+	for seq := uint32(0); seq < bandwidthProbePings; seq++ {
+		rtt, err := pingNetworkTest(send, replies, seq, deadline)
+		if err != nil {
+			return estimate, err
+		}
+		if estimate.RTT == 0 || rtt < estimate.RTT {
+			estimate.RTT = rtt
+		}
+	}
+
+	chunk := make([]byte, networkTestChunkSize)
+	chunk[0] = networkTestData
+	for bitrate := startBitrate; ; bitrate *= 2 {
+		if bitrate > maxBitrate {
+			bitrate = maxBitrate
+		}
+		estimate.Probes++
+
+		// The padding is paced in bursts of the window of the network test
+		size := bitrate * uint64(bandwidthProbeDuration) / uint64(8*time.Second)
+		if size < networkTestChunkSize*networkTestWindow {
+			size = networkTestChunkSize * networkTestWindow
+		}
+		var sent uint64
+		start := time.Now()
+		for sent < size {
+			for i := 0; i < networkTestWindow && sent < size; i++ {
+				if err := send(chunk); err != nil {
+					return estimate, err
+				}
+				sent += uint64(len(chunk))
+			}
+			time.Sleep(time.Until(start.Add(time.Duration(sent * 8 * uint64(time.Second) / bitrate))))
+		}
+
+		if err := send([]byte{networkTestDone}); err != nil {
+			return estimate, err
+		}
+		expire := time.After(estimate.RTT + bandwidthProbeMaxDelay)
+		report, err := awaitNetworkTestReply(replies, networkTestReport, deadline, expire)
+		if err != nil {
+			return estimate, err
+		}
+
+		// A report which doesn't come within a round trip tells the padding
+		// was queued, or retransmitted
+		overused := report == nil
+		if report == nil {
+			if report, err = awaitNetworkTestReply(replies, networkTestReport, deadline, nil); err != nil {
+				return estimate, err
+			}
+		}
+		var received uint64
+		if len(report) == 9 {
+			received = binary.BigEndian.Uint64(report[1:])
+		}
+		if received > sent {
+			received = sent
+		}
+
+		// The padding reached the remote peer half a round trip after it
+		// was sent, and the report took the other half to come back
+		delivered := bitrate
+		if elapsed := time.Since(start) - estimate.RTT; elapsed > 0 {
+			if rate := uint64(float64(received*8) / elapsed.Seconds()); rate < delivered {
+				delivered = rate
+			}
+		}
+
+		switch {
+		case overused:
+			// When the first probe exceeded the bandwidth already, what went
+			// through of it is the estimate
+			if estimate.Bitrate == 0 {
+				estimate.Bitrate = delivered
+			}
+			return estimate, nil
+		case delivered > estimate.Bitrate:
+			estimate.Bitrate = delivered
+		}
+		if bitrate == maxBitrate {
+			return estimate, nil
+		}
+	}
+}
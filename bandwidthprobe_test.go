@@ -0,0 +1,67 @@
+//go:build !js
+
+package webrtc
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pions/webrtc/pkg/rtcerr"
+	"github.com/stretchr/testify/assert"
+)
+
+// linkSimulator queues the data beyond the capacity of a link, as data
+// channels do with what they retransmit
+type linkSimulator struct {
+	capacity  uint64
+	busyUntil time.Time
+}
+
+// delay returns how long the message waits for the data queued before it
+func (l *linkSimulator) delay(size int) time.Duration {
+	if l.capacity == 0 {
+		return 0
+	}
+
+	now := time.Now()
+	if l.busyUntil.Before(now) {
+		l.busyUntil = now
+	}
+	l.busyUntil = l.busyUntil.Add(time.Duration(uint64(size*8) * uint64(time.Second) / l.capacity))
+	return l.busyUntil.Sub(now)
+}
+
+func TestRunBandwidthProbe(t *testing.T) {
+	testCases := []struct {
+		capacity   uint64
+		options    *RTCBandwidthProbeOptions
+		minBitrate uint64
+		maxBitrate uint64
+	}{
+		// Limited by the link
+		{2000000, &RTCBandwidthProbeOptions{StartBitrate: 250000}, 900000, 2000000},
+		// Limited by the options
+		{0, &RTCBandwidthProbeOptions{StartBitrate: 300000, MaxBitrate: 1000000}, 900000, 1000000},
+	}
+
+	for i, testCase := range testCases {
+		link := &linkSimulator{capacity: testCase.capacity}
+		responder := &networkTestResponder{}
+		replies := make(chan []byte, networkTestReplyBuffer)
+		send := func(data []byte) error {
+			delay := link.delay(len(data))
+			if reply := responder.handle(data); reply != nil {
+				time.AfterFunc(delay, func() { replies <- reply })
+			}
+			return nil
+		}
+
+		estimate, err := runBandwidthProbe(send, replies, testCase.options)
+		assert.Nil(t, err, "testCase: %d", i)
+		assert.True(t, estimate.Bitrate >= testCase.minBitrate, "testCase: %d bitrate %d", i, estimate.Bitrate)
+		assert.True(t, estimate.Bitrate <= testCase.maxBitrate, "testCase: %d bitrate %d", i, estimate.Bitrate)
+	}
+
+	_, err := runBandwidthProbe(func([]byte) error { return nil }, nil, &RTCBandwidthProbeOptions{Timeout: 10 * time.Millisecond})
+	assert.Equal(t, &rtcerr.OperationError{Err: ErrNetworkTestTimeout}, err)
+}
@@ -0,0 +1,334 @@
+// webrtc-pipe bridges stdin and stdout to a data channel, like netcat over
+// WebRTC. One side creates the offer with -offer, the other answers it, the
+// descriptions are copy-pasted between the terminals or exchanged over HTTP
+// with -http. Once connected stdin is sent to the remote peer and what it
+// sends is written to stdout. The session ends when the stdin of either side
+// does, after what was read from it is delivered.
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/pions/webrtc"
+	"github.com/pions/webrtc/pkg/datachannel"
+	"github.com/pions/webrtc/pkg/ice"
+	"github.com/pions/webrtc/pkg/signal"
+)
+
+// chunkSize is the size of the messages stdin is sent in, each fits a
+// single SCTP packet
+const chunkSize = 1024
+
+// window is the number of messages sent and not yet acknowledged, sending
+// stdin blocks beyond it instead of queuing it whole in memory. The SCTP
+// association doesn't retransmit on a timer yet, a lost packet is only
+// recovered from the gap reported by the SACKs of the packets after it, so
+// bursts are kept short enough for the receiving side not to drop them.
+const window = 8
+
+// Data is sent in binary messages, text messages control the stream
+const (
+	// ack acknowledges a data message written to stdout
+	ack = "ACK"
+
+	// endOfStream is sent once stdin ended and its data was acknowledged
+	endOfStream = "EOF"
+)
+
+func main() {
+	offer := flag.Bool("offer", false, "Create the offer, the other side answers it")
+	address := flag.String("http", "", "Exchange the descriptions over HTTP on this address instead of copy-pasting them, the answering side listens on it")
+	stun := flag.String("stun", "stun:stun.l.google.com:19302", "STUN server gathering the server reflexive candidates, none if empty")
+	verbose := flag.Bool("v", false, "Log the ICE connection state to stderr")
+	flag.Parse()
+
+	if err := run(*offer, *address, *stun, *verbose); err != nil {
+		fmt.Fprintln(os.Stderr, "webrtc-pipe:", err)
+		os.Exit(1)
+	}
+}
+
+func run(offer bool, address, stun string, verbose bool) error {
+	config := webrtc.RTCConfiguration{}
+	if stun != "" {
+		config.IceServers = []webrtc.RTCIceServer{{URLs: []string{stun}}}
+	}
+	pc, err := webrtc.New(config)
+	if err != nil {
+		return err
+	}
+	defer pc.Close() // nolint: errcheck
+
+	if verbose {
+		pc.OnICEConnectionStateChange(func(state ice.ConnectionState) {
+			fmt.Fprintf(os.Stderr, "ICE connection state: %s\n", state.String())
+		})
+	}
+
+	// Copy-pasted descriptions and the data are read from the same buffer,
+	// what is read past the description isn't lost
+	stdin := bufio.NewReader(os.Stdin)
+	p := newPipe(pc, stdin)
+
+	var s signal.Signal
+	switch {
+	case address != "" && offer:
+		s = &httpOfferer{url: "http://" + address}
+	case address != "":
+		s = newHTTPAnswerer(address)
+	default:
+		fmt.Fprintln(os.Stderr, "Paste the remote description, the local one follows:")
+		s = signal.NewStream(stdin, os.Stderr)
+	}
+
+	if offer {
+		d, err := pc.CreateDataChannel("pipe", nil)
+		if err != nil {
+			return err
+		}
+		p.attach(d)
+		if err := signal.Offer(pc, s); err != nil {
+			return err
+		}
+	} else {
+		pc.OnDataChannel(p.attach)
+		if err := signal.Answer(pc, s); err != nil {
+			return err
+		}
+	}
+
+	select {
+	case err := <-p.done:
+		if err != nil {
+			return err
+		}
+		// The end of the stream is delivered before the connection is
+		// closed, both sides shut it down so the error of the one which
+		// lost the race is expected
+		_ = pc.GracefulClose()
+		return nil
+	case <-pc.Done():
+		return errors.New("connection closed")
+	}
+}
+
+// pipe copies stdin to the data channel and its messages to stdout
+type pipe struct {
+	pc    *webrtc.RTCPeerConnection
+	stdin io.Reader
+
+	// inFlight holds a token for every data message not yet acknowledged
+	inFlight chan struct{}
+	sending  sync.Once
+	done     chan error
+}
+
+func newPipe(pc *webrtc.RTCPeerConnection, stdin io.Reader) *pipe {
+	return &pipe{
+		pc:       pc,
+		stdin:    stdin,
+		inFlight: make(chan struct{}, window),
+		done:     make(chan error, 1),
+	}
+}
+
+// attach sets the handlers of the data channel
+func (p *pipe) attach(d *webrtc.RTCDataChannel) {
+	d.Lock()
+	defer d.Unlock()
+
+	// OnOpen may be called more than once for the channels of the remote
+	// peer, stdin is read by a single goroutine
+	d.OnOpen = func() {
+		p.sending.Do(func() { go p.send(d) })
+	}
+	d.Onmessage = func(payload datachannel.Payload) {
+		var data []byte
+		switch payload := payload.(type) {
+		case *datachannel.PayloadBinary:
+			data = payload.Data
+		case datachannel.PayloadBinary:
+			data = payload.Data
+		case *datachannel.PayloadString:
+			p.control(string(payload.Data))
+			return
+		case datachannel.PayloadString:
+			p.control(string(payload.Data))
+			return
+		}
+
+		if _, err := os.Stdout.Write(data); err != nil {
+			p.finish(err)
+			return
+		}
+		if err := d.Send(datachannel.PayloadString{Data: []byte(ack)}); err != nil {
+			p.finish(err)
+		}
+	}
+}
+
+// control handles a control message of the remote peer
+func (p *pipe) control(message string) {
+	switch message {
+	case ack:
+		select {
+		case <-p.inFlight:
+		default:
+		}
+	case endOfStream:
+		// The remote stdin ended, the data before was written
+		p.finish(nil)
+	}
+}
+
+// send sends stdin to the data channel until it ends, then the end of the
+// stream once the data is acknowledged
+func (p *pipe) send(d *webrtc.RTCDataChannel) {
+	buf := make([]byte, chunkSize)
+	for {
+		n, err := p.stdin.Read(buf)
+		if n > 0 {
+			if !p.acquire() {
+				return
+			}
+			data := append([]byte(nil), buf[:n]...)
+			if sendErr := d.Send(datachannel.PayloadBinary{Data: data}); sendErr != nil {
+				p.finish(sendErr)
+				return
+			}
+		}
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			p.finish(err)
+			return
+		}
+	}
+
+	// The window is free once every message was acknowledged
+	for i := 0; i < window; i++ {
+		if !p.acquire() {
+			return
+		}
+	}
+	if err := d.Send(datachannel.PayloadString{Data: []byte(endOfStream)}); err != nil {
+		p.finish(err)
+		return
+	}
+	p.finish(nil)
+}
+
+// acquire waits for room in the window, false if the connection closed
+func (p *pipe) acquire() bool {
+	select {
+	case p.inFlight <- struct{}{}:
+		return true
+	case <-p.pc.Done():
+		return false
+	}
+}
+
+// finish ends the session, the first result is kept
+func (p *pipe) finish(err error) {
+	select {
+	case p.done <- err:
+	default:
+	}
+}
+
+// httpOfferer posts the offer to the answering side, its response is the
+// answer
+type httpOfferer struct {
+	url    string
+	answer string
+}
+
+func (o *httpOfferer) Send(desc webrtc.RTCSessionDescription) error {
+	encoded, err := signal.Encode(desc)
+	if err != nil {
+		return err
+	}
+	resp, err := http.Post(o.url, "text/plain", strings.NewReader(encoded))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	} else if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("signaling failed: %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+	o.answer = string(body)
+	return nil
+}
+
+func (o *httpOfferer) Receive() (webrtc.RTCSessionDescription, error) {
+	return signal.Decode(o.answer)
+}
+
+// httpAnswerer serves a single offer, the answer is its response
+type httpAnswerer struct {
+	served  int32
+	offers  chan string
+	answers chan string
+	errs    chan error
+}
+
+func newHTTPAnswerer(address string) *httpAnswerer {
+	a := &httpAnswerer{
+		offers:  make(chan string, 1),
+		answers: make(chan string),
+		errs:    make(chan error, 1),
+	}
+	fmt.Fprintf(os.Stderr, "Waiting for the offer on %s\n", address)
+	go func() {
+		a.errs <- http.ListenAndServe(address, a)
+	}()
+	return a
+}
+
+func (a *httpAnswerer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Only the first offer is answered
+	if !atomic.CompareAndSwapInt32(&a.served, 0, 1) {
+		http.Error(w, "an offer was already answered", http.StatusConflict)
+		return
+	}
+	a.offers <- string(body)
+	fmt.Fprint(w, <-a.answers)
+}
+
+func (a *httpAnswerer) Receive() (webrtc.RTCSessionDescription, error) {
+	select {
+	case offer := <-a.offers:
+		return signal.Decode(offer)
+	case err := <-a.errs:
+		return webrtc.RTCSessionDescription{}, err
+	}
+}
+
+func (a *httpAnswerer) Send(desc webrtc.RTCSessionDescription) error {
+	encoded, err := signal.Encode(desc)
+	if err != nil {
+		return err
+	}
+	a.answers <- encoded
+	return nil
+}
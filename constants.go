@@ -0,0 +1,5 @@
+package webrtc
+
+// Unknown defines default public constant to use for "enum" like struct
+// comparisons when no value was defined.
+const Unknown = iota
@@ -0,0 +1,146 @@
+//go:build !js
+
+package webrtc
+
+import (
+	"time"
+
+	"github.com/pions/webrtc/pkg/ice"
+	"github.com/pions/webrtc/pkg/rtcerr"
+)
+
+// RTCDiagnosticsReport is the outcome of a self-test, see RunRTCDiagnostics
+type RTCDiagnosticsReport struct {
+	// OfferTime is how long the offer took to be created, the candidates of
+	// the configured STUN and TURN servers are gathered meanwhile.
+	OfferTime time.Duration
+
+	// ConnectTime is how long, from the creation of the offer, ICE took to
+	// connect the peers, and SetupTime how long until the data channel
+	// opened.
+	ConnectTime time.Duration
+	SetupTime   time.Duration
+
+	// CandidatePair is the stats of the candidate pair the offering peer
+	// selected, the types of its Local and Remote candidates tell whether
+	// the peers connected directly or through the NAT or the relay.
+	CandidatePair *RTCIceCandidatePairStats
+
+	// NetworkTest is the round trip time and throughput measured over the
+	// data channel.
+	NetworkTest RTCNetworkTestResult
+}
+
+// RunRTCDiagnostics tests the connectivity of this host with New, see
+// API.RunRTCDiagnostics
+func RunRTCDiagnostics(configuration RTCConfiguration, timeout time.Duration) (RTCDiagnosticsReport, error) {
+	return NewAPI().RunRTCDiagnostics(configuration, timeout)
+}
+
+// RunRTCDiagnostics connects two RTCPeerConnections of the API in the
+// process, both with the configuration, and reports how long the setup took,
+// which candidates were used and the network test run over their data
+// channel. The peers gather candidates with the STUN and TURN servers of the
+// configuration as they would with remote peers, with an IceTransportPolicy
+// of relay they connect through the TURN servers, which tests them. When the
+// test fails, or takes longer than the timeout, the report holds what was
+// measured until then.
+func (api *API) RunRTCDiagnostics(configuration RTCConfiguration, timeout time.Duration) (RTCDiagnosticsReport, error) {
+	report := RTCDiagnosticsReport{}
+	deadline := time.Now().Add(timeout)
+	timedOut := time.After(timeout)
+
+	offerer, err := api.NewRTCPeerConnection(configuration)
+	if err != nil {
+		return report, err
+	}
+	defer offerer.Close() // nolint: errcheck
+	answerer, err := api.NewRTCPeerConnection(configuration)
+	if err != nil {
+		return report, err
+	}
+	defer answerer.Close() // nolint: errcheck
+
+	connected := make(chan struct{}, 1)
+	failed := make(chan struct{}, 1)
+	offerer.OnICEConnectionStateChange(func(state ice.ConnectionState) {
+		switch state {
+		case ice.ConnectionStateConnected:
+			trySignal(connected)
+		case ice.ConnectionStateFailed:
+			trySignal(failed)
+		}
+	})
+	answerer.OnDataChannel(func(d *RTCDataChannel) {
+		if d.Label == RTCNetworkTestLabel {
+			ServeRTCNetworkTest(d)
+		}
+	})
+
+	d, err := offerer.CreateDataChannel(RTCNetworkTestLabel, nil)
+	if err != nil {
+		return report, err
+	}
+	opened := make(chan struct{}, 1)
+	d.Lock()
+	d.OnOpen = func() {
+		trySignal(opened)
+	}
+	d.Unlock()
+
+	start := time.Now()
+	offer, err := offerer.CreateOffer(nil)
+	if err != nil {
+		return report, err
+	}
+	report.OfferTime = time.Since(start)
+	if err = answerer.SetRemoteDescription(offer); err != nil {
+		return report, err
+	}
+	answer, err := answerer.CreateAnswer(nil)
+	if err != nil {
+		return report, err
+	}
+	if err = offerer.SetRemoteDescription(answer); err != nil {
+		return report, err
+	}
+
+	for connected != nil || opened != nil {
+		select {
+		case <-connected:
+			report.ConnectTime = time.Since(start)
+			report.CandidatePair = selectedCandidatePairStats(offerer)
+			connected = nil
+		case <-opened:
+			report.SetupTime = time.Since(start)
+			opened = nil
+		case <-failed:
+			return report, &rtcerr.OperationError{Err: ErrDiagnosticsConnectionFailed}
+		case <-timedOut:
+			return report, &rtcerr.OperationError{Err: ErrDiagnosticsTimeout}
+		}
+	}
+
+	report.NetworkTest, err = RunRTCNetworkTest(d, time.Until(deadline))
+	return report, err
+}
+
+// selectedCandidatePairStats returns the stats of the candidate pair ICE
+// selected, nil if none is
+func selectedCandidatePairStats(pc *RTCPeerConnection) *RTCIceCandidatePairStats {
+	stats := pc.GetStats()
+	transport, ok := stats[rtcTransportStatsID].(*RTCTransportStats)
+	if !ok {
+		return nil
+	}
+	pair, _ := stats[transport.SelectedCandidatePairID].(*RTCIceCandidatePairStats)
+	return pair
+}
+
+// trySignal signals the channel without blocking, it has room for one signal
+func trySignal(c chan struct{}) {
+	select {
+	case c <- struct{}{}:
+	default:
+	}
+}
@@ -0,0 +1,58 @@
+//go:build !js
+
+package webrtc
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pions/webrtc/internal/turn"
+	"github.com/pions/webrtc/pkg/rtcerr"
+	"github.com/pions/webrtc/pkg/vnet"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAPI_RunRTCDiagnostics(t *testing.T) {
+	router := vnet.NewRouter()
+	n, err := router.NewNet("10.0.0.9")
+	assert.Nil(t, err)
+	s := SettingEngine{}
+	s.SetNet(n)
+	api := NewAPI(WithMediaEngine(nil), WithSettingEngine(s))
+
+	report, err := api.RunRTCDiagnostics(RTCConfiguration{}, 30*time.Second)
+	assert.Nil(t, err)
+	assert.NotZero(t, report.ConnectTime)
+	assert.True(t, report.OfferTime <= report.SetupTime)
+	if assert.NotNil(t, report.CandidatePair) {
+		assert.Equal(t, RTCIceCandidateTypeHost, report.CandidatePair.Local.Type)
+		assert.Equal(t, RTCIceCandidateTypeHost, report.CandidatePair.Remote.Type)
+	}
+	assert.Equal(t, uint64(networkTestBytes), report.NetworkTest.BytesReceived)
+	assert.NotZero(t, report.NetworkTest.Throughput)
+
+	_, err = api.RunRTCDiagnostics(RTCConfiguration{}, time.Nanosecond)
+	assert.Equal(t, &rtcerr.OperationError{Err: ErrDiagnosticsTimeout}, err)
+}
+
+func TestAPI_RunRTCDiagnostics_Relay(t *testing.T) {
+	credentials := turn.Credentials{Username: "user", Password: "pass"}
+	server, err := turn.NewServer("127.0.0.1", credentials, 0)
+	assert.Nil(t, err)
+	defer func() { assert.Nil(t, server.Close()) }()
+
+	report, err := NewAPI(WithMediaEngine(nil)).RunRTCDiagnostics(RTCConfiguration{
+		IceServers: []RTCIceServer{{
+			URLs:       []string{"turn:" + server.UDPAddr().String()},
+			Username:   credentials.Username,
+			Credential: credentials.Password,
+		}},
+		IceTransportPolicy: RTCIceTransportPolicyRelay,
+	}, 30*time.Second)
+	assert.Nil(t, err)
+	if assert.NotNil(t, report.CandidatePair) {
+		assert.Equal(t, RTCIceCandidateTypeRelay, report.CandidatePair.Local.Type)
+		assert.Equal(t, RTCIceCandidateTypeRelay, report.CandidatePair.Remote.Type)
+	}
+	assert.Equal(t, uint64(networkTestBytes), report.NetworkTest.BytesReceived)
+}
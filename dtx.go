@@ -0,0 +1,94 @@
+//go:build !js
+
+package webrtc
+
+import (
+	"time"
+
+	"github.com/pions/webrtc/pkg/media"
+	"github.com/pions/webrtc/pkg/rtp"
+)
+
+const (
+	// dtxSilenceThreshold is how much later than expected a sample has to be
+	// written for the gap to be taken as silence rather than jitter
+	dtxSilenceThreshold = 60 * time.Millisecond
+
+	// comfortNoiseLevel is the noise level of comfort noise packets in -dBov,
+	// the level of the silence isn't known so the quietest one is signaled
+	comfortNoiseLevel = 127
+)
+
+// rtcAudioDTX follows the samples written to an audio stream. The silence
+// the application leaves out, by not writing samples or by writing empty
+// ones, advances the RTP timestamps so the receiver doesn't take it for
+// loss, and the first packet after it is marked as the start of a talkspurt.
+// https://tools.ietf.org/html/rfc3551#section-4.1
+type rtcAudioDTX struct {
+	clockRate uint32
+
+	// playout is when the audio written so far ends if it is played out
+	// from the first sample on in real time
+	playout time.Time
+	silent  bool
+}
+
+func newRTCAudioDTX(clockRate uint32) *rtcAudioDTX {
+	return &rtcAudioDTX{clockRate: clockRate, silent: true}
+}
+
+// packetize packetizes the sample, comfortNoise is the codec comfort noise
+// is sent with once the stream falls silent, nil sends none
+func (d *rtcAudioDTX) packetize(packetizer rtcPacketizer, sample media.RTCSample, comfortNoise *RTCRtpCodec, now time.Time) []*rtp.Packet {
+	if skipped := d.skippedSamples(now); skipped > 0 {
+		packetizer.SkipSamples(skipped)
+		d.silent = true
+	}
+	d.playout = d.playout.Add(d.duration(sample.Samples))
+
+	// An empty sample is silence of its duration
+	if len(sample.Data) == 0 {
+		var packets []*rtp.Packet
+		if !d.silent && comfortNoise != nil {
+			packets = packetizer.Packetize([]byte{comfortNoiseLevel}, 0)
+			for _, p := range packets {
+				p.PayloadType = comfortNoise.PayloadType
+				p.Marker = false
+			}
+		}
+		packetizer.SkipSamples(sample.Samples)
+		d.silent = true
+		return packets
+	}
+
+	packets := packetizer.Packetize(sample.Data, sample.Samples)
+	for i, p := range packets {
+		p.Marker = d.silent && i == 0
+	}
+	d.silent = false
+	return packets
+}
+
+// skippedSamples returns the samples the application didn't write before
+// the sample written now, applications writing ahead of real time skip none.
+// The playout of the next sample is moved to now.
+func (d *rtcAudioDTX) skippedSamples(now time.Time) uint32 {
+	if d.playout.IsZero() || d.clockRate == 0 {
+		d.playout = now
+		return 0
+	}
+
+	gap := now.Sub(d.playout)
+	if gap < dtxSilenceThreshold {
+		return 0
+	}
+	d.playout = now
+	return uint32(gap * time.Duration(d.clockRate) / time.Second)
+}
+
+func (d *rtcAudioDTX) duration(samples uint32) time.Duration {
+	if d.clockRate == 0 {
+		return 0
+	}
+	return time.Duration(samples) * time.Second / time.Duration(d.clockRate)
+}
@@ -0,0 +1,87 @@
+//go:build !js
+
+package webrtc
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pions/webrtc/pkg/media"
+	"github.com/pions/webrtc/pkg/rtp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRTCAudioDTX_packetize(t *testing.T) {
+	packetizer := newRTCPacketizer(1400, NewRTCRtpOpusCodec(DefaultPayloadTypeOpus, 48000, 2), 1, rtp.NewRandomSequencer())
+	comfortNoise := NewRTCRtpComfortNoiseCodec(13, 48000)
+	dtx := newRTCAudioDTX(48000)
+	sample := media.RTCSample{Data: []byte{0x01}, Samples: 960}
+
+	start := time.Now()
+	packets := dtx.packetize(packetizer, sample, comfortNoise, start)
+	assert.Len(t, packets, 1)
+	assert.True(t, packets[0].Marker, "the first packet starts a talkspurt")
+	first := packets[0].Timestamp
+
+	// Writing ahead of real time is not silence
+	packets = dtx.packetize(packetizer, sample, comfortNoise, start)
+	assert.False(t, packets[0].Marker)
+	assert.Equal(t, first+960, packets[0].Timestamp)
+
+	// Not writing for a second is, the audio written so far ended after 40ms
+	packets = dtx.packetize(packetizer, sample, comfortNoise, start.Add(1040*time.Millisecond))
+	assert.True(t, packets[0].Marker)
+	assert.Equal(t, first+2*960+48000, packets[0].Timestamp)
+
+	// Empty samples are silence, announced with comfort noise once
+	silence := media.RTCSample{Samples: 960}
+	packets = dtx.packetize(packetizer, silence, comfortNoise, start.Add(1040*time.Millisecond))
+	assert.Len(t, packets, 1)
+	assert.Equal(t, uint8(13), packets[0].PayloadType)
+	assert.False(t, packets[0].Marker)
+	assert.Equal(t, []byte{comfortNoiseLevel}, packets[0].Payload)
+	assert.Equal(t, first+3*960+48000, packets[0].Timestamp)
+
+	packets = dtx.packetize(packetizer, silence, comfortNoise, start.Add(1040*time.Millisecond))
+	assert.Len(t, packets, 0)
+
+	packets = dtx.packetize(packetizer, sample, nil, start.Add(1040*time.Millisecond))
+	assert.True(t, packets[0].Marker)
+	assert.Equal(t, uint8(DefaultPayloadTypeOpus), packets[0].PayloadType)
+	assert.Equal(t, first+5*960+48000, packets[0].Timestamp)
+}
+
+func TestRTCPeerConnection_comfortNoiseCodec(t *testing.T) {
+	newAPI := func(comfortNoise bool) *API {
+		m := NewMediaEngine()
+		m.RegisterCodec(NewRTCRtpOpusCodec(DefaultPayloadTypeOpus, 48000, 2))
+		if comfortNoise {
+			m.RegisterCodec(NewRTCRtpComfortNoiseCodec(13, 48000))
+		}
+		return NewAPI(WithMediaEngine(m))
+	}
+
+	for i, testCase := range []struct {
+		remoteComfortNoise bool
+		negotiated         bool
+	}{
+		{false, false},
+		{true, true},
+	} {
+		pc, err := newAPI(true).NewRTCPeerConnection(RTCConfiguration{})
+		assert.Nil(t, err)
+		remote, err := newAPI(testCase.remoteComfortNoise).NewRTCPeerConnection(RTCConfiguration{})
+		assert.Nil(t, err)
+
+		assert.Nil(t, pc.comfortNoiseCodec(48000), "testCase: %d", i)
+
+		offer, err := remote.CreateOffer(nil)
+		assert.Nil(t, err)
+		assert.Nil(t, pc.SetRemoteDescription(offer))
+		assert.Equal(t, testCase.negotiated, pc.comfortNoiseCodec(48000) != nil, "testCase: %d", i)
+		assert.Nil(t, pc.comfortNoiseCodec(8000), "testCase: %d", i)
+
+		assert.Nil(t, pc.Close())
+		assert.Nil(t, remote.Close())
+	}
+}
@@ -2,6 +2,8 @@ package webrtc
 
 import (
 	"errors"
+
+	"github.com/pions/webrtc/internal/sdp"
 )
 
 var (
@@ -30,6 +32,14 @@ var (
 	// chosen to generate a certificate is not supported.
 	ErrPrivateKeyType = errors.New("private key type not supported")
 
+	// ErrCertificatePEM indicates that PEM encoded data lacks the private key
+	// or the x509Cert of a certificate.
+	ErrCertificatePEM = errors.New("PEM data has no private key or certificate")
+
+	// ErrCertificateKeyMismatch indicates that the private key of a
+	// certificate doesn't belong to its x509Cert.
+	ErrCertificateKeyMismatch = errors.New("private key doesn't match the certificate")
+
 	// ErrModifyingPeerIdentity indicates that an attempt to modify
 	// PeerIdentity was made after RTCPeerConnection has been initialized.
 	ErrModifyingPeerIdentity = errors.New("peerIdentity cannot be modified")
@@ -50,6 +60,14 @@ var (
 	// IceCandidatePoolSize was made after RTCPeerConnection has been initialized.
 	ErrModifyingIceCandidatePoolSize = errors.New("ice candidate pool size cannot be modified")
 
+	// ErrModifyingSdpSemantics indicates that an attempt to modify
+	// SdpSemantics was made after RTCPeerConnection has been initialized.
+	ErrModifyingSdpSemantics = errors.New("sdp semantics cannot be modified")
+
+	// ErrIncorrectSdpSemantics indicates that a remote description uses Plan
+	// B while the RTCPeerConnection only accepts Unified Plan.
+	ErrIncorrectSdpSemantics = errors.New("remote description uses plan-b, sdp semantics is unified-plan")
+
 	// ErrStringSizeLimit indicates that the character size limit of string is
 	// exceeded. The limit is hardcoded to 65535 according to specifications.
 	ErrStringSizeLimit = errors.New("data channel label exceeds size limit")
@@ -68,4 +86,161 @@ var (
 	// set together. Such configuration is not supported by the specification
 	// and is mutually exclusive.
 	ErrRetransmitsOrPacketLifeTime = errors.New("both MaxPacketLifeTime and MaxRetransmits was set")
+
+	// ErrRIDEmpty indicates that an encoding was added to an RTCRtpSender
+	// without a RID to identify its simulcast layer.
+	ErrRIDEmpty = errors.New("encoding rid must not be empty")
+
+	// ErrExistingRID indicates that an encoding with the same RID was already
+	// added to the RTCRtpSender.
+	ErrExistingRID = errors.New("encoding rid already exists")
+
+	// ErrUnknownRID indicates that no encoding with the requested RID exists
+	// on the RTCRtpSender.
+	ErrUnknownRID = errors.New("encoding rid not found")
+
+	// ErrNoSenderTrack indicates that an operation requiring a track was made
+	// on an RTCRtpSender which has none.
+	ErrNoSenderTrack = errors.New("sender has no track")
+
+	// ErrSenderNotAttached indicates that an RTCRtpSender was used before it
+	// was added to an RTCPeerConnection.
+	ErrSenderNotAttached = errors.New("sender is not attached to a peer connection")
+
+	// ErrNoPayloader indicates that the codec of a track has no payloader and
+	// therefore samples cannot be packetized.
+	ErrNoPayloader = errors.New("codec payloader not set")
+
+	// ErrRawRTPTrack indicates that samples were written to a track created
+	// with NewRawRTPTrack, which forwards RTP packets without packetizing.
+	ErrRawRTPTrack = errors.New("samples can't be written to a raw rtp track")
+
+	// ErrTrackNotLocal indicates that media was written to a track received
+	// from the remote peer.
+	ErrTrackNotLocal = errors.New("track is not a local track")
+
+	// ErrInvalidMTU indicates that the MTU of the packetizer leaves no room
+	// for the payload after the RTP and payload headers.
+	ErrInvalidMTU = errors.New("mtu is too small")
+
+	// ErrInvalidFECGroupSize indicates that the amount of media packets
+	// protected by a single FEC packet is out of range.
+	ErrInvalidFECGroupSize = errors.New("fec group size must be between 1 and 48")
+
+	// ErrNoFECCodec indicates that FEC was enabled on a sender while no
+	// ulpfec codec of the kind of its track is registered.
+	ErrNoFECCodec = errors.New("no ulpfec codec registered")
+
+	// ErrCannotInsertDTMF indicates that DTMF tones were inserted on a sender
+	// which is not able to send them, see RTCDTMFSender.CanInsertDTMF.
+	ErrCannotInsertDTMF = errors.New("dtmf cannot be sent on this sender")
+
+	// ErrInvalidDTMFTone indicates that a tone passed to InsertDTMF is not
+	// one of 0-9, A-D, #, * or a comma.
+	ErrInvalidDTMFTone = errors.New("invalid dtmf tone")
+
+	// ErrNoParameters indicates that SetParameters was called before the
+	// parameters were retrieved with GetParameters.
+	ErrNoParameters = errors.New("GetParameters must be called before SetParameters")
+
+	// ErrParametersTransactionID indicates that the parameters passed to
+	// SetParameters were not returned by the last call to GetParameters.
+	ErrParametersTransactionID = errors.New("parameters were not returned by the last GetParameters")
+
+	// ErrModifiedEncodings indicates that SetParameters attempted to add,
+	// remove or reidentify encodings, which requires renegotiation.
+	ErrModifiedEncodings = errors.New("encodings can't be added, removed or reidentified")
+
+	// ErrInvalidPortRange indicates that the upper end of a port range is
+	// below its lower end, or that the range includes port 0 which stands
+	// for any port.
+	ErrInvalidPortRange = errors.New("port range is empty or includes port 0")
+
+	// ErrUnsupportedCodec indicates a codec preference doesn't match any
+	// codec registered with the MediaEngine.
+	ErrUnsupportedCodec = errors.New("codec is not registered with the media engine")
+
+	// ErrInvalidNAT1To1IP indicates that an entry passed to
+	// SettingEngine.SetNAT1To1IPs is neither an IP nor a public/private IP
+	// pair.
+	ErrInvalidNAT1To1IP = errors.New("nat 1:1 ip must be an ip or a public/private ip pair")
+
+	// ErrNAT1To1CandidateType indicates that the public IPs of a static NAT
+	// can only be announced as host or srflx candidates.
+	ErrNAT1To1CandidateType = errors.New("nat 1:1 ips must be announced as host or srflx candidates")
+
+	// ErrInvalidICECredentials indicates that the ICE credentials passed to
+	// SettingEngine.SetICECredentials are too short, too long or contain
+	// other than ice-chars.
+	ErrInvalidICECredentials = errors.New("ice ufrag must be 4 and pwd 22 to 256 ice-chars long")
+
+	// ErrSDPSizeLimit indicates that a remote description exceeds the
+	// maximum size, see SettingEngine.SetSDPLimits.
+	ErrSDPSizeLimit = sdp.ErrSizeLimit
+
+	// ErrSDPLineLengthLimit indicates that a line of a remote description
+	// exceeds the maximum length, see SettingEngine.SetSDPLimits.
+	ErrSDPLineLengthLimit = sdp.ErrLineLengthLimit
+
+	// ErrSDPAttributeLimit indicates that a remote description has more
+	// attributes than allowed, see SettingEngine.SetSDPLimits.
+	ErrSDPAttributeLimit = sdp.ErrAttributeLimit
+
+	// ErrNoRemoteDescription indicates that an operation requiring the
+	// remote description was made before it was set.
+	ErrNoRemoteDescription = errors.New("remote description is not set")
+
+	// ErrIceCandidateWithoutMedia indicates that a candidate was added
+	// without a sdpMid or sdpMLineIndex identifying its media section.
+	ErrIceCandidateWithoutMedia = errors.New("ice candidate has neither sdpMid nor sdpMLineIndex")
+
+	// ErrIceCandidateMediaNotFound indicates that the remote description has
+	// no media section with the sdpMid or sdpMLineIndex of a candidate.
+	ErrIceCandidateMediaNotFound = errors.New("media section of the ice candidate not found")
+
+	// ErrIceCandidateUfrag indicates that the usernameFragment of a candidate
+	// doesn't match the ufrag of its media section.
+	ErrIceCandidateUfrag = errors.New("ice candidate usernameFragment doesn't match its media section")
+
+	// ErrIceCandidateSyntax indicates that the candidate-attribute of a
+	// candidate can't be parsed.
+	ErrIceCandidateSyntax = errors.New("ice candidate-attribute is malformed")
+
+	// ErrIceCandidateNotSupported indicates that a candidate is of a type or
+	// transport the ICE agent doesn't support, like simultaneous-open TCP
+	// candidates.
+	ErrIceCandidateNotSupported = errors.New("ice candidate type or transport is not supported")
+
+	// ErrDTLSRoleHoldconn indicates that the remote peer doesn't want the
+	// DTLS connection to be established, which is not supported.
+	ErrDTLSRoleHoldconn = errors.New("dtls setup attribute holdconn is not supported")
+
+	// ErrNetworkTestTimeout indicates that a network test didn't complete
+	// within its timeout, the remote peer may not serve network tests.
+	ErrNetworkTestTimeout = errors.New("network test timed out")
+
+	// ErrInvalidTypePreference indicates that the preference of a candidate
+	// type is above 126, the highest RFC 8445 allows.
+	ErrInvalidTypePreference = errors.New("ice type preference is above 126")
+
+	// ErrDiagnosticsTimeout indicates that the peers of a self-test didn't
+	// open their data channel within its timeout.
+	ErrDiagnosticsTimeout = errors.New("diagnostics timed out")
+
+	// ErrDiagnosticsConnectionFailed indicates that ICE failed to connect
+	// the peers of a self-test.
+	ErrDiagnosticsConnectionFailed = errors.New("diagnostics ice connection failed")
+
+	// ErrNoBrowserPeerConnection indicates that the program is compiled to
+	// WebAssembly but runs where the browser API of WebRTC isn't available.
+	ErrNoBrowserPeerConnection = errors.New("RTCPeerConnection is not available in this JavaScript environment")
+
+	// ErrBrowserHostOnly indicates that the host-only transport policy was
+	// configured in a program compiled to WebAssembly, browsers only know
+	// the standard policies.
+	ErrBrowserHostOnly = errors.New("the host-only ice transport policy is not supported by the browser")
+
+	// ErrBrowserCertificates indicates that certificates were configured in
+	// a program compiled to WebAssembly, the browser generates its own.
+	ErrBrowserCertificates = errors.New("certificates can't be passed to the RTCPeerConnection of the browser")
 )
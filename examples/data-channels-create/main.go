@@ -34,9 +34,9 @@ func main() {
 
 	// Set the handler for ICE connection state
 	// This will notify you when the peer has connected/disconnected
-	peerConnection.OnICEConnectionStateChange = func(connectionState ice.ConnectionState) {
+	peerConnection.OnICEConnectionStateChange(func(connectionState ice.ConnectionState) {
 		fmt.Printf("ICE Connection State has changed: %s\n", connectionState.String())
-	}
+	})
 
 	dataChannel.Lock()
 
@@ -72,7 +72,7 @@ func main() {
 	check(err)
 
 	// Output the offer in base64 so we can paste it in browser
-	fmt.Println(base64.StdEncoding.EncodeToString([]byte(offer.Sdp)))
+	fmt.Println(base64.StdEncoding.EncodeToString([]byte(offer.SDP)))
 
 	// Wait for the answer to be pasted
 	sd := mustReadStdin()
@@ -80,7 +80,7 @@ func main() {
 	// Set the remote SessionDescription
 	answer := webrtc.RTCSessionDescription{
 		Type: webrtc.RTCSdpTypeAnswer,
-		Sdp:  sd,
+		SDP:  sd,
 	}
 
 	// Apply the answer as the remote description
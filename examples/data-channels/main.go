@@ -35,12 +35,12 @@ func main() {
 
 	// Set the handler for ICE connection state
 	// This will notify you when the peer has connected/disconnected
-	peerConnection.OnICEConnectionStateChange = func(connectionState ice.ConnectionState) {
+	peerConnection.OnICEConnectionStateChange(func(connectionState ice.ConnectionState) {
 		fmt.Printf("ICE Connection State has changed: %s\n", connectionState.String())
-	}
+	})
 
 	// Register data channel creation handling
-	peerConnection.OnDataChannel = func(d *webrtc.RTCDataChannel) {
+	peerConnection.OnDataChannel(func(d *webrtc.RTCDataChannel) {
 		fmt.Printf("New DataChannel %s %d\n", d.Label, d.ID)
 
 		d.Lock()
@@ -70,12 +70,12 @@ func main() {
 				fmt.Printf("Message '%s' from DataChannel '%s' no payload \n", p.PayloadType().String(), d.Label)
 			}
 		}
-	}
+	})
 
 	// Set the remote SessionDescription
 	offer := webrtc.RTCSessionDescription{
 		Type: webrtc.RTCSdpTypeOffer,
-		Sdp:  string(sd),
+		SDP:  string(sd),
 	}
 
 	err = peerConnection.SetRemoteDescription(offer)
@@ -86,7 +86,7 @@ func main() {
 	check(err)
 
 	// Get the LocalDescription and take it to base64 so we can paste in browser
-	fmt.Println(base64.StdEncoding.EncodeToString([]byte(answer.Sdp)))
+	fmt.Println(base64.StdEncoding.EncodeToString([]byte(answer.SDP)))
 
 	// Block forever
 	select {}
@@ -46,9 +46,9 @@ func main() {
 
 	// Set the handler for ICE connection state
 	// This will notify you when the peer has connected/disconnected
-	peerConnection.OnICEConnectionStateChange = func(connectionState ice.ConnectionState) {
+	peerConnection.OnICEConnectionStateChange(func(connectionState ice.ConnectionState) {
 		fmt.Printf("Connection State has changed %s \n", connectionState.String())
-	}
+	})
 
 	// Create a audio track
 	opusTrack, err := peerConnection.NewRTCTrack(webrtc.DefaultPayloadTypeOpus, "audio", "pion1")
@@ -73,7 +73,7 @@ func main() {
 	// Set the remote SessionDescription
 	offer := webrtc.RTCSessionDescription{
 		Type: webrtc.RTCSdpTypeOffer,
-		Sdp:  string(sd),
+		SDP:  string(sd),
 	}
 	if err := peerConnection.SetRemoteDescription(offer); err != nil {
 		panic(err)
@@ -86,7 +86,7 @@ func main() {
 	}
 
 	// Get the LocalDescription and take it to base64 so we can paste in browser
-	fmt.Println(base64.StdEncoding.EncodeToString([]byte(answer.Sdp)))
+	fmt.Println(base64.StdEncoding.EncodeToString([]byte(answer.SDP)))
 
 	// Start pushing buffers on these tracks
 	gst.CreatePipeline(webrtc.Opus, opusTrack.Samples).Start()
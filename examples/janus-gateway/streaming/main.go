@@ -40,11 +40,11 @@ func main() {
 		panic(err)
 	}
 
-	peerConnection.OnICEConnectionStateChange = func(connectionState ice.ConnectionState) {
+	peerConnection.OnICEConnectionStateChange(func(connectionState ice.ConnectionState) {
 		fmt.Printf("Connection State has changed %s \n", connectionState.String())
-	}
+	})
 
-	peerConnection.OnTrack = func(track *webrtc.RTCTrack) {
+	peerConnection.OnTrack(func(track *webrtc.RTCTrack) {
 		if track.Codec.Name == webrtc.Opus {
 			return
 		}
@@ -55,11 +55,11 @@ func main() {
 			panic(err)
 		}
 		for {
-			if err := i.AddPacket(<-track.Packets); err != nil {
+			if err := i.WriteRTP(<-track.Packets); err != nil {
 				panic(err)
 			}
 		}
-	}
+	})
 
 	// Janus
 	gateway, err := janus.Connect("ws://localhost:8188/")
@@ -101,7 +101,7 @@ func main() {
 	if msg.Jsep != nil {
 		if err := peerConnection.SetRemoteDescription(webrtc.RTCSessionDescription{
 			Type: webrtc.RTCSdpTypeOffer,
-			Sdp:  msg.Jsep["sdp"].(string),
+			SDP:  msg.Jsep["sdp"].(string),
 		}); err != nil {
 			panic(err)
 		}
@@ -116,7 +116,7 @@ func main() {
 			"request": "start",
 		}, map[string]interface{}{
 			"type":    "answer",
-			"sdp":     answer.Sdp,
+			"sdp":     answer.SDP,
 			"trickle": false,
 		}); err != nil {
 			panic(err)
@@ -10,6 +10,8 @@ import (
 	"github.com/pions/webrtc"
 	"github.com/pions/webrtc/pkg/ice"
 	"github.com/pions/webrtc/pkg/media/ivfwriter"
+	"github.com/pions/webrtc/pkg/media/oggwriter"
+	"github.com/pions/webrtc/pkg/rtp"
 )
 
 func main() {
@@ -28,7 +30,7 @@ func main() {
 	/* Everything below is the pion-WebRTC API, thanks for using it! */
 
 	// Setup the codecs you want to use.
-	// We'll use a VP8 codec but you can also define your own
+	// We'll use a VP8 and Opus codec but you can also define your own
 	webrtc.RegisterCodec(webrtc.NewRTCRtpOpusCodec(webrtc.DefaultPayloadTypeOpus, 48000, 2))
 	webrtc.RegisterCodec(webrtc.NewRTCRtpVP8Codec(webrtc.DefaultPayloadTypeVP8, 90000))
 
@@ -45,33 +47,49 @@ func main() {
 	}
 
 	// Set a handler for when a new remote track starts, this handler saves buffers to disk as
-	// an ivf file, since we could have multiple video tracks we provide a counter.
-	// In your application this is where you would handle/process video
-	peerConnection.OnTrack = func(track *webrtc.RTCTrack) {
-		if track.Codec.Name == webrtc.VP8 {
+	// an ivf file for video and an ogg file for audio.
+	// In your application this is where you would handle/process audio and video
+	peerConnection.OnTrack(func(track *webrtc.RTCTrack) {
+		var writer interface {
+			WriteRTP(*rtp.Packet) error
+			Close() error
+		}
+		var err error
+
+		switch track.Codec.Name {
+		case webrtc.VP8:
 			fmt.Println("Got VP8 track, saving to disk as output.ivf")
-			i, err := ivfwriter.New("output.ivf")
-			if err != nil {
+			writer, err = ivfwriter.New("output.ivf")
+		case webrtc.Opus:
+			fmt.Println("Got Opus track, saving to disk as output.ogg")
+			writer, err = oggwriter.New("output.ogg", track.Codec.ClockRate, uint8(track.Codec.Channels))
+		default:
+			return
+		}
+		if err != nil {
+			panic(err)
+		}
+
+		for packet := range track.Packets {
+			if err := writer.WriteRTP(packet); err != nil {
 				panic(err)
 			}
-			for {
-				if err := i.AddPacket(<-track.Packets); err != nil {
-					panic(err)
-				}
-			}
 		}
-	}
+		if err := writer.Close(); err != nil {
+			panic(err)
+		}
+	})
 
 	// Set the handler for ICE connection state
 	// This will notify you when the peer has connected/disconnected
-	peerConnection.OnICEConnectionStateChange = func(connectionState ice.ConnectionState) {
+	peerConnection.OnICEConnectionStateChange(func(connectionState ice.ConnectionState) {
 		fmt.Printf("Connection State has changed %s \n", connectionState.String())
-	}
+	})
 
 	// Set the remote SessionDescription
 	offer := webrtc.RTCSessionDescription{
 		Type: webrtc.RTCSdpTypeOffer,
-		Sdp:  string(sd),
+		SDP:  string(sd),
 	}
 	if err := peerConnection.SetRemoteDescription(offer); err != nil {
 		panic(err)
@@ -84,6 +102,6 @@ func main() {
 	}
 
 	// Get the LocalDescription and take it to base64 so we can paste in browser
-	fmt.Println(base64.StdEncoding.EncodeToString([]byte(answer.Sdp)))
+	fmt.Println(base64.StdEncoding.EncodeToString([]byte(answer.SDP)))
 	select {}
 }
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"bufio"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/pions/webrtc"
+	"github.com/pions/webrtc/pkg/ice"
+	"github.com/pions/webrtc/pkg/media/webmwriter"
+)
+
+func main() {
+	reader := bufio.NewReader(os.Stdin)
+	rawSd, err := reader.ReadString('\n')
+	if err != nil && err != io.EOF {
+		panic(err)
+	}
+
+	fmt.Println("")
+	sd, err := base64.StdEncoding.DecodeString(rawSd)
+	if err != nil {
+		panic(err)
+	}
+
+	/* Everything below is the pion-WebRTC API, thanks for using it! */
+
+	// Setup the codecs you want to use.
+	// WebM holds VP8 video and Opus audio
+	webrtc.RegisterCodec(webrtc.NewRTCRtpOpusCodec(webrtc.DefaultPayloadTypeOpus, 48000, 2))
+	webrtc.RegisterCodec(webrtc.NewRTCRtpVP8Codec(webrtc.DefaultPayloadTypeVP8, 90000))
+
+	// Create a new RTCPeerConnection
+	peerConnection, err := webrtc.New(webrtc.RTCConfiguration{
+		IceServers: []webrtc.RTCIceServer{
+			{
+				URLs: []string{"stun:stun.l.google.com:19302"},
+			},
+		},
+	})
+	if err != nil {
+		panic(err)
+	}
+
+	// The writer interleaves the video and the audio, it synchronizes them with
+	// the RTCP sender reports the browser sends for every track
+	writer, err := webmwriter.New("output.webm", 2)
+	if err != nil {
+		panic(err)
+	}
+
+	// Set a handler for when a new remote track starts, this handler adds the
+	// packets of the video and the audio track to the WebM file
+	peerConnection.OnTrack(func(track *webrtc.RTCTrack) {
+		for _, receiver := range peerConnection.GetReceivers() {
+			if receiver.Track == track {
+				receiver.OnSenderReport(writer.WriteSenderReport)
+			}
+		}
+
+		writeRTP := writer.WriteAudioRTP
+		if track.Codec.Name == webrtc.VP8 {
+			fmt.Println("Got VP8 track, saving to disk as output.webm")
+			writeRTP = writer.WriteVideoRTP
+		} else if track.Codec.Name != webrtc.Opus {
+			return
+		}
+
+		for packet := range track.Packets {
+			if err := writeRTP(packet); err != nil {
+				panic(err)
+			}
+		}
+	})
+
+	// Set the handler for ICE connection state
+	// This will notify you when the peer has connected/disconnected, the file
+	// is complete once the peer has disconnected
+	done := make(chan struct{})
+	peerConnection.OnICEConnectionStateChange(func(connectionState ice.ConnectionState) {
+		fmt.Printf("Connection State has changed %s \n", connectionState.String())
+		if connectionState != ice.ConnectionStateDisconnected && connectionState != ice.ConnectionStateFailed {
+			return
+		}
+		select {
+		case <-done:
+		default:
+			close(done)
+		}
+	})
+
+	// Set the remote SessionDescription
+	offer := webrtc.RTCSessionDescription{
+		Type: webrtc.RTCSdpTypeOffer,
+		SDP:  string(sd),
+	}
+	if err := peerConnection.SetRemoteDescription(offer); err != nil {
+		panic(err)
+	}
+
+	// Sets the LocalDescription, and starts our UDP listeners
+	answer, err := peerConnection.CreateAnswer(nil)
+	if err != nil {
+		panic(err)
+	}
+
+	// Get the LocalDescription and take it to base64 so we can paste in browser
+	fmt.Println(base64.StdEncoding.EncodeToString([]byte(answer.SDP)))
+
+	<-done
+	if err := peerConnection.Close(); err != nil {
+		panic(err)
+	}
+	if err := writer.Close(); err != nil {
+		panic(err)
+	}
+	fmt.Println("Saved output.webm")
+}
@@ -3,17 +3,13 @@ package main
 import (
 	"fmt"
 	"os"
-	"sync"
 	"time"
 
 	"bufio"
 	"encoding/base64"
 
 	"github.com/pions/webrtc"
-	"github.com/pions/webrtc/pkg/media"
-	"github.com/pions/webrtc/pkg/media/samplebuilder"
 	"github.com/pions/webrtc/pkg/rtcp"
-	"github.com/pions/webrtc/pkg/rtp/codecs"
 )
 
 var peerConnectionConfig = webrtc.RTCConfiguration{
@@ -59,11 +55,12 @@ func main() {
 	peerConnection, err := webrtc.New(peerConnectionConfig)
 	check(err)
 
-	outboundSamples := []chan<- media.RTCSample{}
-	var outboundSamplesLock sync.RWMutex
+	// The broadcast track forwards the packets of the publisher to every peer it is bound to
+	broadcast := webrtc.NewRTCBroadcastTrack(webrtc.DefaultPayloadTypeVP8, "video", "pion2")
+
 	// Set a handler for when a new remote track starts, this just distributes all our packets
 	// to connected peers
-	peerConnection.OnTrack = func(track *webrtc.RTCTrack) {
+	peerConnection.OnTrack(func(track *webrtc.RTCTrack) {
 		// Send a PLI on an interval so that the publisher is pushing a keyframe every rtcpPLIInterval
 		// This is a temporary fix until we implement incoming RTCP events, then we would push a PLI only when a viewer requests it
 		go func() {
@@ -79,24 +76,16 @@ func main() {
 			}
 		}()
 
-		// Transform RTP packets into samples, allowing us to distribute incoming packets from the publisher to everyone who has joined the broadcast
-		builder := samplebuilder.New(256, &codecs.VP8Packet{})
-		for {
-			outboundSamplesLock.RLock()
-			builder.Push(<-track.Packets)
-			for s := builder.Pop(); s != nil; s = builder.Pop() {
-				for _, outChan := range outboundSamples {
-					outChan <- *s
-				}
-			}
-			outboundSamplesLock.RUnlock()
+		// Distribute incoming packets from the publisher to everyone who has joined the broadcast
+		for packet := range track.Packets {
+			check(broadcast.WriteRTP(packet))
 		}
-	}
+	})
 
 	// Set the remote SessionDescription
 	check(peerConnection.SetRemoteDescription(webrtc.RTCSessionDescription{
 		Type: webrtc.RTCSdpTypeOffer,
-		Sdp:  string(sd),
+		SDP:  string(sd),
 	}))
 
 	// Sets the LocalDescription, and starts our UDP listeners
@@ -104,7 +93,7 @@ func main() {
 	check(err)
 
 	// Get the LocalDescription and take it to base64 so we can paste in browser
-	fmt.Println(base64.StdEncoding.EncodeToString([]byte(answer.Sdp)))
+	fmt.Println(base64.StdEncoding.EncodeToString([]byte(answer.SDP)))
 
 	for {
 		fmt.Println("")
@@ -115,21 +104,17 @@ func main() {
 		peerConnection, err := webrtc.New(peerConnectionConfig)
 		check(err)
 
-		// Create a single VP8 Track to send videa
-		vp8Track, err := peerConnection.NewRTCTrack(webrtc.DefaultPayloadTypeVP8, "video", "pion2")
+		// Create a single VP8 Track to send video, bound to the broadcast
+		vp8Track, err := broadcast.Bind(peerConnection)
 		check(err)
 
 		_, err = peerConnection.AddTrack(vp8Track)
 		check(err)
 
-		outboundSamplesLock.Lock()
-		outboundSamples = append(outboundSamples, vp8Track.Samples)
-		outboundSamplesLock.Unlock()
-
 		// Set the remote SessionDescription
 		check(peerConnection.SetRemoteDescription(webrtc.RTCSessionDescription{
 			Type: webrtc.RTCSdpTypeOffer,
-			Sdp:  string(recvOnlyOffer),
+			SDP:  string(recvOnlyOffer),
 		}))
 
 		// Sets the LocalDescription, and starts our UDP listeners
@@ -137,6 +122,6 @@ func main() {
 		check(err)
 
 		// Get the LocalDescription and take it to base64 so we can paste in browser
-		fmt.Println(base64.StdEncoding.EncodeToString([]byte(answer.Sdp)))
+		fmt.Println(base64.StdEncoding.EncodeToString([]byte(answer.SDP)))
 	}
 }
@@ -0,0 +1,13 @@
+//go:build !js
+
+package webrtc
+
+// GatheringCompletePromise returns a channel which is closed once ICE
+// gathering of the RTCPeerConnection is complete, gathering is started if
+// it wasn't already. The candidates are embedded in the offer or answer
+// created once the channel is closed, so applications which don't trickle
+// candidates can wait for it before creating and signaling their description.
+func GatheringCompletePromise(pc *RTCPeerConnection) <-chan struct{} {
+	pc.startGathering()
+	return pc.gathered
+}
@@ -0,0 +1,33 @@
+//go:build !js
+
+package webrtc
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGatheringCompletePromise(t *testing.T) {
+	pc, err := New(RTCConfiguration{})
+	assert.Nil(t, err)
+	defer func() { assert.Nil(t, pc.Close()) }()
+
+	select {
+	case <-GatheringCompletePromise(pc):
+	case <-time.After(time.Second):
+		assert.FailNow(t, "gathering not completed")
+	}
+
+	pc.RLock()
+	assert.Equal(t, RTCIceGatheringStateComplete, pc.IceGatheringState)
+	pc.RUnlock()
+
+	// Gathering completes only once
+	select {
+	case <-GatheringCompletePromise(pc):
+	default:
+		assert.Fail(t, "promise not closed")
+	}
+}
@@ -20,13 +20,53 @@ bool openssl_global_init() {
   return SSL_library_init();
 }
 
+// The AEAD profiles of RFC 7714 are only known to OpenSSL 1.1.0 and later
+bool dtls_srtp_aead_supported() {
+#ifdef SRTP_AEAD_AES_128_GCM
+  return true;
+#else
+  return false;
+#endif
+}
+
 int dtls_trivial_verify_callback(int preverify_ok, X509_STORE_CTX *ctx) {
   (void)preverify_ok;
   (void)ctx;
   return 1;
 }
 
-SSL_CTX *dtls_build_sslctx(tlscfg *cfg) {
+// The certificate is DER encoded and the private key PKCS#8 DER encoded.
+tlscfg *dtls_load_tlscfg(const unsigned char *cert_der, int cert_len, const unsigned char *key_der, int key_len) {
+  tlscfg *cfg = (tlscfg *)calloc(1, sizeof(tlscfg));
+  PKCS8_PRIV_KEY_INFO *key_info = NULL;
+
+  if ((cfg->cert = d2i_X509(NULL, &cert_der, cert_len)) == NULL) {
+    goto error;
+  }
+
+  if ((key_info = d2i_PKCS8_PRIV_KEY_INFO(NULL, &key_der, key_len)) == NULL) {
+    goto error;
+  }
+
+  if ((cfg->pkey = EVP_PKCS82PKEY(key_info)) == NULL) {
+    goto error;
+  }
+
+  PKCS8_PRIV_KEY_INFO_free(key_info);
+  return cfg;
+
+error:
+  if (key_info) {
+    PKCS8_PRIV_KEY_INFO_free(key_info);
+  }
+  if (cfg->cert) {
+    X509_free(cfg->cert);
+  }
+  free(cfg);
+  return NULL;
+}
+
+SSL_CTX *dtls_build_sslctx(tlscfg *cfg, const char *srtp_profiles) {
   if (cfg == NULL) {
     return NULL;
   }
@@ -57,7 +97,7 @@ SSL_CTX *dtls_build_sslctx(tlscfg *cfg) {
   SSL_CTX_set_read_ahead(ctx, 1);
   SSL_CTX_set_verify(ctx, SSL_VERIFY_PEER | SSL_VERIFY_FAIL_IF_NO_PEER_CERT, dtls_trivial_verify_callback);
 
-  if (SSL_CTX_set_tlsext_use_srtp(ctx, "SRTP_AES128_CM_SHA1_32:SRTP_AES128_CM_SHA1_80") != 0) {
+  if (SSL_CTX_set_tlsext_use_srtp(ctx, srtp_profiles) != 0) {
     goto error;
   }
 
@@ -128,6 +168,8 @@ error:
 }
 
 extern void go_handle_sendto(const char *local, const char *remote, char *buf, int len);
+extern void go_handle_failure(const char *local, const char *reason);
+
 ptrdiff_t dtls_sess_send_pending(dtls_sess *sess, char *local, char *remote) {
   if (sess->ssl == NULL) {
     return -2;
@@ -289,8 +331,7 @@ dtls_decrypted *dtls_handle_incoming(dtls_sess *sess, void *buf, int len, char *
   decrypted_len = SSL_read(sess->ssl, decrypted, len);
 
   if ((decrypted_len < 0) && SSL_get_error(sess->ssl, decrypted_len) == SSL_ERROR_SSL) {
-     fprintf(stderr, "DTLS failure occurred on dtls session %p due to reason '%s'\n", sess,
-             ERR_reason_error_string(ERR_get_error()));
+     go_handle_failure(local, ERR_reason_error_string(ERR_get_error()));
      free(decrypted);
      return ret;
   }
@@ -321,8 +362,7 @@ bool dtls_handle_outgoing(dtls_sess *sess, void *buf, int len, char *local, char
   int written = SSL_write(sess->ssl, buf, len);
   if (written != len) {
     if (SSL_get_error(sess->ssl, written) == SSL_ERROR_SSL) {
-      fprintf(stderr, "DTLS failure occurred on dtls session %p due to reason '%s'\n", sess,
-          ERR_reason_error_string(ERR_get_error()));
+      go_handle_failure(local, ERR_reason_error_string(ERR_get_error()));
     }
     return false;
   }
@@ -336,36 +376,84 @@ bool dtls_handle_outgoing(dtls_sess *sess, void *buf, int len, char *local, char
 
 dtls_cert_pair *dtls_get_certpair(dtls_sess *sess) {
   if (sess->type == DTLS_CONTYPE_EXISTING) {
-    unsigned char dtls_buffer[SRTP_MASTER_KEY_KEY_LEN * 2 + SRTP_MASTER_KEY_SALT_LEN * 2];
+    SRTP_PROTECTION_PROFILE *profile = SSL_get_selected_srtp_profile(sess->ssl);
+    if (profile == NULL) {
+      return NULL;
+    }
+
+    int key_len = SRTP_MASTER_KEY_KEY_LEN;
+    int salt_len = SRTP_MASTER_KEY_SALT_LEN;
+    switch (profile->id) {
+#ifdef SRTP_AEAD_AES_128_GCM
+    case SRTP_AEAD_AES_128_GCM:
+      key_len = SRTP_AEAD_AES_128_GCM_KEY_LEN;
+      salt_len = SRTP_AEAD_AES_GCM_SALT_LEN;
+      break;
+    case SRTP_AEAD_AES_256_GCM:
+      key_len = SRTP_AEAD_AES_256_GCM_KEY_LEN;
+      salt_len = SRTP_AEAD_AES_GCM_SALT_LEN;
+      break;
+#endif
+    }
+
+    unsigned char dtls_buffer[SRTP_AEAD_AES_256_GCM_KEY_LEN * 2 + SRTP_MASTER_KEY_SALT_LEN * 2];
+    size_t buffer_len = key_len * 2 + salt_len * 2;
 
     const char *label = "EXTRACTOR-dtls_srtp";
-    if (!SSL_export_keying_material(sess->ssl, dtls_buffer, sizeof(dtls_buffer), label, strlen(label), NULL, 0, 0)) {
-      fprintf(stderr, "SSL_export_keying_material failed");
+    if (!SSL_export_keying_material(sess->ssl, dtls_buffer, buffer_len, label, strlen(label), NULL, 0, 0)) {
+      go_handle_failure(NULL, "SSL_export_keying_material failed");
       return NULL;
     }
 
     size_t offset = 0;
     dtls_cert_pair *ret = calloc(1, sizeof(dtls_cert_pair));
-    ret->key_length = SRTP_MASTER_KEY_KEY_LEN + SRTP_MASTER_KEY_SALT_LEN;
-
-    memcpy(&ret->client_write_key[0], &dtls_buffer[offset], SRTP_MASTER_KEY_KEY_LEN);
-    offset += SRTP_MASTER_KEY_KEY_LEN;
-    memcpy(&ret->server_write_key[0], &dtls_buffer[offset], SRTP_MASTER_KEY_KEY_LEN);
-    offset += SRTP_MASTER_KEY_KEY_LEN;
-    memcpy(&ret->client_write_key[SRTP_MASTER_KEY_KEY_LEN], &dtls_buffer[offset], SRTP_MASTER_KEY_SALT_LEN);
-    offset += SRTP_MASTER_KEY_SALT_LEN;
-    memcpy(&ret->server_write_key[SRTP_MASTER_KEY_KEY_LEN], &dtls_buffer[offset], SRTP_MASTER_KEY_SALT_LEN);
-
-    switch (SSL_get_selected_srtp_profile(sess->ssl)->id) {
+    ret->key_length = key_len + salt_len;
+    ret->master_key_length = key_len;
+
+    memcpy(&ret->client_write_key[0], &dtls_buffer[offset], key_len);
+    offset += key_len;
+    memcpy(&ret->server_write_key[0], &dtls_buffer[offset], key_len);
+    offset += key_len;
+    memcpy(&ret->client_write_key[key_len], &dtls_buffer[offset], salt_len);
+    offset += salt_len;
+    memcpy(&ret->server_write_key[key_len], &dtls_buffer[offset], salt_len);
+
+    switch (profile->id) {
     case SRTP_AES128_CM_SHA1_80:
       memcpy(&ret->profile, "SRTP_AES128_CM_SHA1_80", strlen("SRTP_AES128_CM_SHA1_80"));
       break;
     case SRTP_AES128_CM_SHA1_32:
       memcpy(&ret->profile, "SRTP_AES128_CM_SHA1_32", strlen("SRTP_AES128_CM_SHA1_32"));
       break;
+#ifdef SRTP_AEAD_AES_128_GCM
+    case SRTP_AEAD_AES_128_GCM:
+      memcpy(&ret->profile, "SRTP_AEAD_AES_128_GCM", strlen("SRTP_AEAD_AES_128_GCM"));
+      break;
+    case SRTP_AEAD_AES_256_GCM:
+      memcpy(&ret->profile, "SRTP_AEAD_AES_256_GCM", strlen("SRTP_AEAD_AES_256_GCM"));
+      break;
+#endif
     }
 
     return ret;
   }
   return NULL;
 }
+
+// The DER encoding of the certificate the peer presented is written to a
+// buffer the caller frees, its length is returned or -1 without one.
+int dtls_get_peer_certificate(dtls_sess *sess, unsigned char **der) {
+  X509 *cert = SSL_get_peer_certificate(sess->ssl);
+  if (cert == NULL) {
+    return -1;
+  }
+
+  int len = i2d_X509(cert, NULL);
+  if (len > 0) {
+    *der = (unsigned char *)malloc(len);
+    unsigned char *p = *der;
+    i2d_X509(cert, &p);
+  }
+  X509_free(cert);
+  return len;
+}
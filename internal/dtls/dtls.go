@@ -15,10 +15,11 @@ import (
 	"fmt"
 	"net"
 	"strconv"
+	"strings"
 	"sync"
 	"unsafe"
+	"github.com/pions/webrtc/pkg/logging"
 	"github.com/pkg/errors"
-	"golang.org/x/net/ipv4"
 )
 
 func init() {
@@ -34,6 +35,7 @@ type ConnectionState uint8
 const (
 	New ConnectionState = iota + 1
 	Established
+	Failed
 )
 
 func (a ConnectionState) String() string {
@@ -42,14 +44,26 @@ func (a ConnectionState) String() string {
 		return "New"
 	case Established:
 		return "Established"
+	case Failed:
+		return "Failed"
 	default:
 		return fmt.Sprintf("Invalid ConnectionState %d", a)
 	}
 }
 
-var listenerMap = make(map[string]*ipv4.PacketConn)
+// listener is a socket OpenSSL sends on, and the logger of its failures
+type listener struct {
+	conn net.PacketConn
+	log  logging.LeveledLogger
+}
+
+var listenerMap = make(map[string]*listener)
 var listenerMapLock = &sync.Mutex{}
 
+// log is the logger of packets sent on unknown sockets, and of the failures
+// of sessions which aren't handling a packet of a socket
+var log = logging.NewDefaultLoggerFactory().NewLogger(logging.ScopeDTLS)
+
 //export go_handle_sendto
 func go_handle_sendto(rawLocal *C.char, rawRemote *C.char, rawBuf *C.char, rawBufLen C.int) {
 	local := C.GoString(rawLocal)
@@ -59,23 +73,40 @@ func go_handle_sendto(rawLocal *C.char, rawRemote *C.char, rawBuf *C.char, rawBu
 
 	listenerMapLock.Lock()
 	defer listenerMapLock.Unlock()
-	if conn, ok := listenerMap[local]; ok {
+	if l, ok := listenerMap[local]; ok {
 		strIP, strPort, err := net.SplitHostPort(remote)
 		if err != nil {
-			fmt.Println(err)
+			l.log.Warnf("Failed to parse DTLS remote address: %v", err)
 			return
 		}
 		port, err := strconv.Atoi(strPort)
 		if err != nil {
-			fmt.Println(err)
+			l.log.Warnf("Failed to parse DTLS remote port: %v", err)
 			return
 		}
-		_, err = conn.WriteTo(buf, nil, &net.UDPAddr{IP: net.ParseIP(strIP), Port: port})
+		_, err = l.conn.WriteTo(buf, &net.UDPAddr{IP: net.ParseIP(strIP), Port: port})
 		if err != nil {
-			fmt.Println(err)
+			l.log.Warnf("Failed to send DTLS packet: %v", err)
 		}
 	} else {
-		fmt.Printf("Could not find ipv4.PacketConn for %s \n", local)
+		log.Warnf("Could not find net.PacketConn for %s", local)
+	}
+}
+
+// go_handle_failure logs a failure of OpenSSL, with the logger of the socket
+// the session handled a packet of, local is NULL when there is none
+//export go_handle_failure
+func go_handle_failure(rawLocal *C.char, rawReason *C.char) {
+	local := C.GoString(rawLocal)
+	reason := C.GoString(rawReason)
+
+	listenerMapLock.Lock()
+	l, ok := listenerMap[local]
+	listenerMapLock.Unlock()
+	if ok {
+		l.log.Warnf("DTLS failure on %s: %s", local, reason)
+	} else {
+		log.Warnf("DTLS failure: %s", reason)
 	}
 }
 
@@ -86,27 +117,81 @@ type State struct {
 	state    ConnectionState
 	notifier func(ConnectionState)
 
+	// remoteFingerprints are the hashes the certificate of the remote peer
+	// is verified against, err is why the handshake failed
+	remoteFingerprints []Fingerprint
+	err                error
+
+	// remoteCert is the DER encoding of the certificate the remote peer
+	// presented, kept after the session is cleaned up
+	remoteCert []byte
+
 	tlscfg      *_Ctype_struct_tlscfg
 	sslctx      *_Ctype_struct_ssl_ctx_st
 	dtlsSession *_Ctype_struct_dtls_sess
 }
 
-// NewState creates a new DTLS session
-func NewState(notifier func(ConnectionState)) (s *State, err error) {
+// DefaultSRTPProtectionProfiles are the SRTP profiles offered with the
+// use_srtp extension when none are given, in order of preference. The AEAD
+// profiles are left out when OpenSSL doesn't provide them.
+var DefaultSRTPProtectionProfiles = defaultSRTPProtectionProfiles()
+
+func defaultSRTPProtectionProfiles() []string {
+	profiles := []string{"SRTP_AES128_CM_SHA1_32", "SRTP_AES128_CM_SHA1_80"}
+	if C.dtls_srtp_aead_supported() {
+		profiles = append([]string{"SRTP_AEAD_AES_128_GCM", "SRTP_AEAD_AES_256_GCM"}, profiles...)
+	}
+	return profiles
+}
+
+// Certificate is the certificate DTLS authenticates with
+type Certificate struct {
+	// Certificate is DER encoded and PrivateKey PKCS#8 DER encoded
+	Certificate []byte
+	PrivateKey  []byte
+}
+
+// NewState creates a new DTLS session, offering the SRTP profiles in order of
+// preference. A certificate is generated if none is given.
+func NewState(notifier func(ConnectionState), srtpProfiles []string, certificate *Certificate) (s *State, err error) {
+	if len(srtpProfiles) == 0 {
+		srtpProfiles = DefaultSRTPProtectionProfiles
+	}
+
 	s = &State{
-		tlscfg:   C.dtls_build_tlscfg(),
 		state:    New,
 		notifier: notifier,
 	}
 
-	s.sslctx = C.dtls_build_sslctx(s.tlscfg)
+	if certificate == nil {
+		s.tlscfg = C.dtls_build_tlscfg()
+	} else {
+		rawCertificate := C.CBytes(certificate.Certificate)
+		rawPrivateKey := C.CBytes(certificate.PrivateKey)
+		s.tlscfg = C.dtls_load_tlscfg((*C.uchar)(rawCertificate), C.int(len(certificate.Certificate)), (*C.uchar)(rawPrivateKey), C.int(len(certificate.PrivateKey)))
+		C.free(rawCertificate)
+		C.free(rawPrivateKey)
+		if s.tlscfg == nil {
+			return nil, errors.Errorf("Failed to load the DTLS certificate")
+		}
+	}
+
+	rawProfiles := C.CString(strings.Join(srtpProfiles, ":"))
+	defer C.free(unsafe.Pointer(rawProfiles))
+
+	s.sslctx = C.dtls_build_sslctx(s.tlscfg, rawProfiles)
+	if s.sslctx == nil {
+		C.dtls_session_cleanup(nil, nil, s.tlscfg)
+		return nil, errors.Errorf("Failed to build SSL context with SRTP profiles %s", strings.Join(srtpProfiles, ", "))
+	}
 
 	return s, err
 }
 
-// Start allocates DTLS/ICE state that is dependent on if we are offering or answering
-func (s *State) Start(isOffer bool) {
-	s.dtlsSession = C.dtls_build_session(s.sslctx, C.bool(isOffer))
+// Start allocates the DTLS session, the client initiates the handshake and
+// the server accepts it
+func (s *State) Start(isClient bool) {
+	s.dtlsSession = C.dtls_build_session(s.sslctx, C.bool(!isClient))
 }
 
 func (s *State) setState(state ConnectionState) {
@@ -118,9 +203,16 @@ func (s *State) setState(state ConnectionState) {
 	}
 }
 
-// Close cleans up the associated OpenSSL resources
+// Close cleans up the associated OpenSSL resources, packets handled
+// afterwards are rejected
 func (s *State) Close() {
+	s.Lock()
+	defer s.Unlock()
+
 	C.dtls_session_cleanup(s.sslctx, s.dtlsSession, s.tlscfg)
+	s.sslctx = nil
+	s.dtlsSession = nil
+	s.tlscfg = nil
 }
 
 // Fingerprint generates a SHA-256 fingerprint of the certificate
@@ -145,10 +237,13 @@ func (s *State) Fingerprint() string {
 }
 
 // CertPair is the client+server key and profile extracted for SRTP
+// The write keys are the master key followed by the master salt, the length
+// of the master key depends on the profile
 type CertPair struct {
-	ClientWriteKey []byte
-	ServerWriteKey []byte
-	Profile        string
+	ClientWriteKey  []byte
+	ServerWriteKey  []byte
+	MasterKeyLength int
+	Profile         string
 }
 
 // HandleDTLSPacket checks if the packet is a DTLS packet, and if it is passes to the DTLS session
@@ -159,6 +254,9 @@ func (s *State) HandleDTLSPacket(packet []byte, local, remote string) ([]byte, e
 
 	if s.dtlsSession == nil {
 		return nil, errors.Errorf("Unable to handle DTLS packet, session has not started")
+	} else if s.state == Failed {
+		// The remote peer couldn't be verified, it is not talked to anymore
+		return nil, nil
 	}
 
 	rawLocal := C.CString(local)
@@ -177,6 +275,11 @@ func (s *State) HandleDTLSPacket(packet []byte, local, remote string) ([]byte, e
 		}()
 
 		if bool(ret.init) && s.state == New {
+			if err := s.verifyRemoteCertificate(); err != nil {
+				s.err = err
+				s.setState(Failed)
+				return nil, err
+			}
 			s.setState(Established)
 		}
 
@@ -211,21 +314,34 @@ func (s *State) GetCertPair() *CertPair {
 	s.Lock()
 	defer s.Unlock()
 
-	if s.dtlsSession == nil {
+	if s.dtlsSession == nil || s.state != Established {
 		return nil
 	}
 
 	if ret := C.dtls_get_certpair(s.dtlsSession); ret != nil {
 		defer C.free(unsafe.Pointer(ret))
 		return &CertPair{
-			ClientWriteKey: []byte(C.GoStringN(&ret.client_write_key[0], ret.key_length)),
-			ServerWriteKey: []byte(C.GoStringN(&ret.server_write_key[0], ret.key_length)),
-			Profile:        C.GoString(&ret.profile[0]),
+			ClientWriteKey:  []byte(C.GoStringN(&ret.client_write_key[0], ret.key_length)),
+			ServerWriteKey:  []byte(C.GoStringN(&ret.server_write_key[0], ret.key_length)),
+			MasterKeyLength: int(ret.master_key_length),
+			Profile:         C.GoString(&ret.profile[0]),
 		}
 	}
 	return nil
 }
 
+// remoteCertificate returns the DER encoding of the certificate the remote
+// peer presented during the handshake, nil if it presented none
+func (s *State) remoteCertificate() []byte {
+	var der *C.uchar
+	length := C.dtls_get_peer_certificate(s.dtlsSession, &der)
+	if length <= 0 {
+		return nil
+	}
+	defer C.free(unsafe.Pointer(der))
+	return C.GoBytes(unsafe.Pointer(der), length)
+}
+
 // DoHandshake sends the DTLS handshake it the remote peer
 func (s *State) DoHandshake(local, remote string) {
 	s.Lock()
@@ -244,11 +360,12 @@ func (s *State) DoHandshake(local, remote string) {
 	C.dtls_do_handshake(s.dtlsSession, rawLocal, rawRemote)
 }
 
-// AddListener adds the socket to a map that can be accessed by OpenSSL for sending
+// AddListener adds the socket to a map that can be accessed by OpenSSL for sending,
+// failures to send are written to the logger
 // This only needed until DTLS is rewritten in native Go
-func AddListener(src string, conn *ipv4.PacketConn) {
+func AddListener(src string, conn net.PacketConn, log logging.LeveledLogger) {
 	listenerMapLock.Lock()
-	listenerMap[src] = conn
+	listenerMap[src] = &listener{conn: conn, log: log}
 	listenerMapLock.Unlock()
 }
 
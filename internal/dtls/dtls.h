@@ -47,17 +47,25 @@ typedef struct dtls_decrypted {
 #define SRTP_MASTER_KEY_KEY_LEN 16
 #define SRTP_MASTER_KEY_SALT_LEN 14
 
+// The AEAD profiles of RFC 7714 use a shorter salt, and longer keys for AES-256
+#define SRTP_AEAD_AES_128_GCM_KEY_LEN 16
+#define SRTP_AEAD_AES_256_GCM_KEY_LEN 32
+#define SRTP_AEAD_AES_GCM_SALT_LEN 12
+
 typedef struct dtls_cert_pair {
-  char client_write_key[SRTP_MASTER_KEY_KEY_LEN + SRTP_MASTER_KEY_SALT_LEN];
-  char server_write_key[SRTP_MASTER_KEY_KEY_LEN + SRTP_MASTER_KEY_SALT_LEN];
+  char client_write_key[SRTP_AEAD_AES_256_GCM_KEY_LEN + SRTP_MASTER_KEY_SALT_LEN];
+  char server_write_key[SRTP_AEAD_AES_256_GCM_KEY_LEN + SRTP_MASTER_KEY_SALT_LEN];
   char profile[PROFILE_STRING_LENGTH];
   int key_length;
+  int master_key_length;
 } dtls_cert_pair;
 
 bool openssl_global_init();
+bool dtls_srtp_aead_supported();
 
 tlscfg *dtls_build_tlscfg();
-SSL_CTX *dtls_build_sslctx(tlscfg *cfg);
+tlscfg *dtls_load_tlscfg(const unsigned char *cert_der, int cert_len, const unsigned char *key_der, int key_len);
+SSL_CTX *dtls_build_sslctx(tlscfg *cfg, const char *srtp_profiles);
 dtls_sess *dtls_build_session(SSL_CTX *cfg, bool is_offer);
 
 ptrdiff_t dtls_do_handshake(dtls_sess *sess, char *local, char *remote);
@@ -65,6 +73,7 @@ dtls_decrypted *dtls_handle_incoming(dtls_sess *sess, void *buf, int len, char *
 bool dtls_handle_outgoing(dtls_sess *sess, void *buf, int len, char *local, char *remote);
 
 dtls_cert_pair *dtls_get_certpair(dtls_sess *sess);
+int dtls_get_peer_certificate(dtls_sess *sess, unsigned char **der);
 
 void dtls_session_cleanup(SSL_CTX *ssl_ctx, dtls_sess *dtls_session, tlscfg *cfg);
 
@@ -0,0 +1,129 @@
+package dtls
+
+import (
+	"crypto"
+	"fmt"
+	"strings"
+
+	// Register the hash functions of the fingerprints
+	_ "crypto/md5"  // nolint: gosec
+	_ "crypto/sha1" // nolint: gosec
+	_ "crypto/sha256"
+	_ "crypto/sha512"
+
+	"github.com/pkg/errors"
+)
+
+var (
+	// ErrNoFingerprint indicates that the remote peer didn't signal the
+	// fingerprint of its certificate with a supported hash function
+	ErrNoFingerprint = errors.New("no fingerprint of the remote certificate with a supported hash function")
+
+	// ErrFingerprintMismatch indicates that the certificate the remote peer
+	// presented during the handshake doesn't match its fingerprints
+	ErrFingerprintMismatch = errors.New("remote certificate doesn't match its fingerprint")
+
+	errNoRemoteCertificate = errors.New("remote peer presented no certificate")
+)
+
+// Fingerprint is a hash of a certificate, as signaled by an a=fingerprint
+// attribute
+// https://tools.ietf.org/html/rfc8122#section-5
+type Fingerprint struct {
+	// Algorithm is the name of the hash function, like sha-256
+	Algorithm string
+
+	// Value is the hash as uppercase hex bytes separated by colons
+	Value string
+}
+
+// fingerprintHash returns the hash function of its textual name, md2 of the
+// registry is not supported
+// https://www.iana.org/assignments/hash-function-text-names
+func fingerprintHash(algorithm string) (crypto.Hash, bool) {
+	switch strings.ToLower(algorithm) {
+	case "md5":
+		return crypto.MD5, true
+	case "sha-1":
+		return crypto.SHA1, true
+	case "sha-224":
+		return crypto.SHA224, true
+	case "sha-256":
+		return crypto.SHA256, true
+	case "sha-384":
+		return crypto.SHA384, true
+	case "sha-512":
+		return crypto.SHA512, true
+	default:
+		return 0, false
+	}
+}
+
+// SetRemoteFingerprints sets the fingerprints the certificate of the remote
+// peer has to match, the handshake fails otherwise
+func (s *State) SetRemoteFingerprints(fingerprints []Fingerprint) {
+	s.Lock()
+	defer s.Unlock()
+	s.remoteFingerprints = fingerprints
+}
+
+// Err returns why the handshake failed, nil unless the state is Failed
+func (s *State) Err() error {
+	s.Lock()
+	defer s.Unlock()
+	return s.err
+}
+
+// verifyRemoteCertificate checks the certificate of the remote peer against
+// its fingerprints
+// Note: the caller should hold the State lock.
+func (s *State) verifyRemoteCertificate() error {
+	cert := s.remoteCertificate()
+	if cert == nil {
+		return errNoRemoteCertificate
+	}
+	s.remoteCert = cert
+	return verifyFingerprints(cert, s.remoteFingerprints)
+}
+
+// RemoteCertificate returns the DER encoding of the certificate the remote
+// peer presented, nil until the handshake completed
+func (s *State) RemoteCertificate() []byte {
+	s.Lock()
+	defer s.Unlock()
+	return s.remoteCert
+}
+
+// verifyFingerprints checks that a fingerprint with a supported hash function
+// matches the certificate
+func verifyFingerprints(cert []byte, fingerprints []Fingerprint) error {
+	supported := false
+	for _, fingerprint := range fingerprints {
+		hash, ok := fingerprintHash(fingerprint.Algorithm)
+		if !ok {
+			continue
+		}
+		supported = true
+
+		h := hash.New()
+		if _, err := h.Write(cert); err != nil {
+			return err
+		}
+		if strings.EqualFold(formatFingerprint(h.Sum(nil)), fingerprint.Value) {
+			return nil
+		}
+	}
+
+	if !supported {
+		return ErrNoFingerprint
+	}
+	return ErrFingerprintMismatch
+}
+
+func formatFingerprint(digest []byte) string {
+	hexBytes := make([]string, len(digest))
+	for i, b := range digest {
+		hexBytes[i] = fmt.Sprintf("%.2X", b)
+	}
+	return strings.Join(hexBytes, ":")
+}
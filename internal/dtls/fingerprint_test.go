@@ -0,0 +1,29 @@
+package dtls
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVerifyFingerprints(t *testing.T) {
+	cert := []byte("certificate")
+	// sha-256 of cert
+	sha256 := "03:D6:6D:D0:88:35:C1:CA:3F:12:8C:CE:AC:D1:F3:1A:C9:41:63:09:6B:20:F4:45:AE:84:28:5B:C0:83:2D:72"
+
+	testCases := []struct {
+		fingerprints []Fingerprint
+		err          error
+	}{
+		{[]Fingerprint{{"sha-256", sha256}}, nil},
+		{[]Fingerprint{{"SHA-256", "03:d6:6d:d0:88:35:c1:ca:3f:12:8c:ce:ac:d1:f3:1a:c9:41:63:09:6b:20:f4:45:ae:84:28:5b:c0:83:2d:72"}}, nil},
+		{[]Fingerprint{{"sha-1", "00:11"}, {"sha-256", sha256}}, nil},
+		{[]Fingerprint{{"sha-256", "00:11"}}, ErrFingerprintMismatch},
+		{[]Fingerprint{{"md2", sha256}}, ErrNoFingerprint},
+		{nil, ErrNoFingerprint},
+	}
+
+	for i, testCase := range testCases {
+		assert.Equal(t, testCase.err, verifyFingerprints(cert, testCase.fingerprints), "testCase: %d", i)
+	}
+}
@@ -0,0 +1,30 @@
+package network
+
+import (
+	"time"
+
+	"github.com/pions/webrtc/pkg/rtp"
+)
+
+// SetAbsSendTimeExtension sets the id of the abs-send-time RTP header
+// extension, as negotiated by a=extmap. Every RTP packet sent carries the
+// time it is sent in it, allowing the remote peer to estimate the bandwidth
+// from the changes of the one-way delay. Zero disables it.
+func (m *Manager) SetAbsSendTimeExtension(id uint8) {
+	m.srtpOutboundContextLock.Lock()
+	defer m.srtpOutboundContextLock.Unlock()
+
+	m.absSendTimeExtensionID = id
+}
+
+// stampAbsSendTime sets the abs-send-time of the packet to now, if it is
+// negotiated
+// Note: the caller should hold the srtpOutboundContextLock.
+func (m *Manager) stampAbsSendTime(packet *rtp.Packet) {
+	if m.absSendTimeExtensionID == 0 {
+		return
+	}
+	if err := packet.SetExtension(m.absSendTimeExtensionID, rtp.NewAbsSendTime(time.Now()).Marshal()); err != nil {
+		m.rtpLog.Warnf("Failed to set abs-send-time: %v", err)
+	}
+}
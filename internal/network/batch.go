@@ -0,0 +1,181 @@
+package network
+
+import (
+	"net"
+	"sync"
+
+	"github.com/pions/webrtc/pkg/logging"
+	"github.com/pkg/errors"
+	"golang.org/x/net/ipv4"
+	"golang.org/x/net/ipv6"
+)
+
+// batchReadWriter moves several packets per syscall, ipv4.PacketConn and
+// ipv6.PacketConn share their Message type
+type batchReadWriter interface {
+	ReadBatch(ms []ipv4.Message, flags int) (int, error)
+	WriteBatch(ms []ipv4.Message, flags int) (int, error)
+}
+
+// batchConn reads and writes the packets of a UDP socket in batches of up to
+// batchSize, recvmmsg and sendmmsg move a batch with one syscall on Linux,
+// other platforms move one packet per syscall.
+//
+// Reads fill a batch with the packets the socket holds, ReadFrom returns them
+// one by one before the next batch is read. A write is made at once if no
+// other is in progress, the packets written meanwhile are copied and queued
+// and written with one syscall once it is done, so writes are never delayed.
+type batchConn struct {
+	net.PacketConn
+	batch     batchReadWriter
+	batchSize int
+	log       logging.LeveledLogger
+
+	readLock     sync.Mutex
+	readMessages []ipv4.Message
+	readCount    int
+	readIndex    int
+
+	// writeLock guards the queue of the packets written while another write
+	// is in progress and the free buffers they are copied to, the messages
+	// are only used by the write in progress
+	writeLock     sync.Mutex
+	writing       bool
+	writeQueue    []queuedWrite
+	freeBuffers   [][]byte
+	writeMessages []ipv4.Message
+}
+
+// queuedWrite is a packet waiting for the write in progress
+type queuedWrite struct {
+	buffer []byte
+	addr   net.Addr
+}
+
+func newBatchConn(conn *net.UDPConn, batchSize int, log logging.LeveledLogger) *batchConn {
+	var batch batchReadWriter = ipv4.NewPacketConn(conn)
+	if addr, ok := conn.LocalAddr().(*net.UDPAddr); ok && addr.IP.To4() == nil {
+		batch = ipv6.NewPacketConn(conn)
+	}
+
+	c := &batchConn{
+		PacketConn:    conn,
+		batch:         batch,
+		batchSize:     batchSize,
+		log:           log,
+		readMessages:  make([]ipv4.Message, batchSize),
+		writeMessages: make([]ipv4.Message, batchSize),
+	}
+	for i := range c.readMessages {
+		c.readMessages[i].Buffers = [][]byte{make([]byte, receiveMTU)}
+		c.writeMessages[i].Buffers = make([][]byte, 1)
+	}
+	return c
+}
+
+// ReadFrom returns the next packet of the batch read last, reading a batch
+// if all of its packets were returned
+func (c *batchConn) ReadFrom(b []byte) (int, net.Addr, error) {
+	c.readLock.Lock()
+	defer c.readLock.Unlock()
+
+	if c.readIndex == c.readCount {
+		c.readIndex, c.readCount = 0, 0
+		n, err := c.batch.ReadBatch(c.readMessages, 0)
+		if err != nil {
+			return 0, nil, err
+		}
+		c.readCount = n
+	}
+
+	message := &c.readMessages[c.readIndex]
+	c.readIndex++
+	return copy(b, message.Buffers[0][:message.N]), message.Addr, nil
+}
+
+// WriteTo writes the packet, with the packets queued meanwhile once it is
+// written. If another write is in progress the packet is queued instead,
+// unless the queue is full.
+func (c *batchConn) WriteTo(b []byte, addr net.Addr) (int, error) {
+	c.writeLock.Lock()
+	if c.writing {
+		if len(c.writeQueue) >= c.batchSize {
+			c.writeLock.Unlock()
+			return c.PacketConn.WriteTo(b, addr)
+		}
+
+		// The caller may reuse b once this returns
+		buffer := c.freeBuffer()
+		buffer = append(buffer[:0], b...)
+		c.writeQueue = append(c.writeQueue, queuedWrite{buffer: buffer, addr: addr})
+		c.writeLock.Unlock()
+		return len(b), nil
+	}
+	c.writing = true
+	c.writeLock.Unlock()
+
+	c.writeMessages[0].Buffers[0], c.writeMessages[0].Addr = b, addr
+	err := c.writeBatch(c.writeMessages[:1])
+	c.flushQueue()
+	if err != nil {
+		return 0, err
+	}
+	return len(b), nil
+}
+
+// flushQueue writes the queued packets a batch at a time until none is left,
+// then the write in progress is done
+func (c *batchConn) flushQueue() {
+	for {
+		c.writeLock.Lock()
+		queue := c.writeQueue
+		if len(queue) == 0 {
+			c.writing = false
+			c.writeLock.Unlock()
+			return
+		}
+		c.writeQueue = nil
+		c.writeLock.Unlock()
+
+		for i, write := range queue {
+			c.writeMessages[i].Buffers[0], c.writeMessages[i].Addr = write.buffer, write.addr
+		}
+		if err := c.writeBatch(c.writeMessages[:len(queue)]); err != nil {
+			c.log.Warnf("Failed to write queued packets: %v", err)
+		}
+
+		c.writeLock.Lock()
+		for _, write := range queue {
+			c.freeBuffers = append(c.freeBuffers, write.buffer)
+		}
+		if c.writeQueue == nil {
+			c.writeQueue = queue[:0]
+		}
+		c.writeLock.Unlock()
+	}
+}
+
+// writeBatch writes the messages, WriteBatch may write a part of them
+func (c *batchConn) writeBatch(messages []ipv4.Message) error {
+	for len(messages) > 0 {
+		n, err := c.batch.WriteBatch(messages, 0)
+		if err != nil {
+			return err
+		} else if n == 0 {
+			return errors.New("no packet of the batch was written")
+		}
+		messages = messages[n:]
+	}
+	return nil
+}
+
+// freeBuffer returns a buffer a queued packet is copied to
+// Note: the caller should hold the writeLock.
+func (c *batchConn) freeBuffer() []byte {
+	if n := len(c.freeBuffers); n > 0 {
+		buffer := c.freeBuffers[n-1]
+		c.freeBuffers = c.freeBuffers[:n-1]
+		return buffer
+	}
+	return make([]byte, 0, receiveMTU)
+}
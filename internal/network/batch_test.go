@@ -0,0 +1,74 @@
+package network
+
+import (
+	"fmt"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pions/webrtc/pkg/logging"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestBatchConn(t *testing.T, batchSize int) *batchConn {
+	conn, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	assert.Nil(t, err)
+	return newBatchConn(conn, batchSize, logging.NewDefaultLoggerFactory().NewLogger(logging.ScopeRTP))
+}
+
+func TestBatchConn(t *testing.T) {
+	sender, receiver := newTestBatchConn(t, 8), newTestBatchConn(t, 8)
+	defer func() {
+		assert.Nil(t, sender.Close())
+		assert.Nil(t, receiver.Close())
+	}()
+
+	// Writes from several goroutines are queued while one is written
+	const writers, packets = 4, 50
+	var wg sync.WaitGroup
+	for w := 0; w < writers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			buffer := make([]byte, 16)
+			for i := 0; i < packets; i++ {
+				n := copy(buffer, fmt.Sprintf("%d-%d", w, i))
+				_, err := sender.WriteTo(buffer[:n], receiver.LocalAddr())
+				assert.Nil(t, err)
+				// The buffer is reused like the pooled ones of the ports
+				copy(buffer, "garbage")
+			}
+		}(w)
+	}
+	wg.Wait()
+
+	received := make(map[string]bool)
+	buffer := make([]byte, receiveMTU)
+	assert.Nil(t, receiver.SetReadDeadline(time.Now().Add(5*time.Second)))
+	for len(received) < writers*packets {
+		n, addr, err := receiver.ReadFrom(buffer)
+		if !assert.Nil(t, err) {
+			return
+		}
+		assert.Equal(t, sender.LocalAddr().String(), addr.String())
+		received[string(buffer[:n])] = true
+	}
+	for w := 0; w < writers; w++ {
+		for i := 0; i < packets; i++ {
+			assert.True(t, received[fmt.Sprintf("%d-%d", w, i)])
+		}
+	}
+	assert.False(t, sender.writing)
+	assert.Empty(t, sender.writeQueue)
+}
+
+func TestBatchConn_Close(t *testing.T) {
+	c := newTestBatchConn(t, 4)
+	assert.Nil(t, c.Close())
+
+	_, _, err := c.ReadFrom(make([]byte, receiveMTU))
+	assert.Error(t, err)
+	_, err = c.WriteTo([]byte{0x00}, &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 9})
+	assert.Error(t, err)
+}
@@ -0,0 +1,60 @@
+package network
+
+import (
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/pions/webrtc/pkg/pcap"
+	"github.com/pions/webrtc/pkg/rtp"
+)
+
+// capturing reports if the packets of the protocol are mirrored to the
+// PacketCapture of the settings
+func (m *Manager) capturing(protocol pcap.Protocol) bool {
+	if m.settings.PacketCapture == nil {
+		return false
+	}
+	return protocol != pcap.ProtocolSCTP || m.settings.CaptureSCTP
+}
+
+// capture mirrors a packet sent to or received from the remote address on
+// the port, the payload is copied as the caller may reuse it
+func (p *port) capture(protocol pcap.Protocol, inbound bool, remote fmt.Stringer, payload []byte) {
+	if !p.m.capturing(protocol) {
+		return
+	}
+
+	remoteAddr, err := net.ResolveUDPAddr("udp", remote.String())
+	if err != nil {
+		p.m.rtpLog.Warnf("Failed to capture packet: %v", err)
+		return
+	}
+
+	packet := pcap.Packet{
+		Timestamp:   time.Now(),
+		Protocol:    protocol,
+		Inbound:     inbound,
+		Source:      &net.UDPAddr{IP: p.listeningAddr.IP, Port: p.listeningAddr.Port},
+		Destination: remoteAddr,
+		Payload:     append([]byte{}, payload...),
+	}
+	if inbound {
+		packet.Source, packet.Destination = packet.Destination, packet.Source
+	}
+	p.m.settings.PacketCapture(packet)
+}
+
+// captureRTP mirrors an RTP packet, it is only marshaled if it is captured
+func (p *port) captureRTP(inbound bool, remote fmt.Stringer, packet *rtp.Packet) {
+	if !p.m.capturing(pcap.ProtocolRTP) {
+		return
+	}
+
+	raw, err := packet.Marshal()
+	if err != nil {
+		p.m.rtpLog.Warnf("Failed to capture packet: %v", err)
+		return
+	}
+	p.capture(pcap.ProtocolRTP, inbound, remote, raw)
+}
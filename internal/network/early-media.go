@@ -0,0 +1,52 @@
+package network
+
+import (
+	"github.com/pions/webrtc/pkg/rtp"
+)
+
+const (
+	// EarlyMediaBufferSize is the maximum amount of packets held per SSRC while
+	// no buffer transport can be generated for it yet, e.g. when SRTP arrives
+	// before the local answer has been applied. Channels handed out by a
+	// BufferTransportGenerator should be able to hold this many packets so the
+	// held packets can be flushed into them without loss.
+	EarlyMediaBufferSize = 64
+
+	// maxEarlyMediaStreams bounds how many distinct SSRCs are held at once
+	maxEarlyMediaStreams = 16
+)
+
+// holdEarlyMedia stores a packet that could not be delivered yet, the oldest
+// packets are discarded once EarlyMediaBufferSize is reached.
+// Note: the caller should hold the srtpInboundContextLock.
+func (m *Manager) holdEarlyMedia(packet *rtp.Packet) {
+	held, ok := m.earlyMedia[packet.SSRC]
+	if !ok && len(m.earlyMedia) >= maxEarlyMediaStreams {
+		return
+	}
+
+	if len(held) >= EarlyMediaBufferSize {
+		held = held[1:]
+	}
+	m.earlyMedia[packet.SSRC] = append(held, packet)
+}
+
+// dropEarlyMedia discards the packets held for the SSRC, the stream is never
+// delivered and its packets are dropped from now on
+// Note: the caller should hold the srtpInboundContextLock.
+func (m *Manager) dropEarlyMedia(ssrc uint32) {
+	delete(m.earlyMedia, ssrc)
+	m.droppedStreams[ssrc] = struct{}{}
+}
+
+// flushEarlyMedia delivers all packets held for the SSRC to the now bound buffer transport
+// Note: the caller should hold the srtpInboundContextLock.
+func (m *Manager) flushEarlyMedia(ssrc uint32, bufferTransport chan<- *rtp.Packet) {
+	for _, packet := range m.earlyMedia[ssrc] {
+		select {
+		case bufferTransport <- packet:
+		default:
+		}
+	}
+	delete(m.earlyMedia, ssrc)
+}
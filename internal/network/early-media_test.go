@@ -0,0 +1,61 @@
+package network
+
+import (
+	"testing"
+
+	"github.com/pions/webrtc/pkg/rtp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestManager_EarlyMedia(t *testing.T) {
+	m := &Manager{earlyMedia: make(map[uint32][]*rtp.Packet)}
+
+	for i := 0; i < EarlyMediaBufferSize+10; i++ {
+		m.holdEarlyMedia(&rtp.Packet{SSRC: 5000, SequenceNumber: uint16(i)})
+	}
+	assert.Equal(t, EarlyMediaBufferSize, len(m.earlyMedia[5000]))
+
+	// Oldest packets are discarded first
+	assert.Equal(t, uint16(10), m.earlyMedia[5000][0].SequenceNumber)
+
+	for i := 0; i < maxEarlyMediaStreams+5; i++ {
+		m.holdEarlyMedia(&rtp.Packet{SSRC: uint32(i)})
+	}
+	assert.Equal(t, maxEarlyMediaStreams, len(m.earlyMedia))
+
+	bufferTransport := make(chan *rtp.Packet, EarlyMediaBufferSize)
+	m.flushEarlyMedia(5000, bufferTransport)
+	assert.Equal(t, EarlyMediaBufferSize, len(bufferTransport))
+	assert.Equal(t, uint16(10), (<-bufferTransport).SequenceNumber)
+
+	_, stillHeld := m.earlyMedia[5000]
+	assert.False(t, stillHeld)
+}
+
+func TestManager_DropUnboundMedia(t *testing.T) {
+	hold, calls := true, 0
+	m := &Manager{
+		bufferTransports: make(map[uint32]chan<- *rtp.Packet),
+		earlyMedia:       make(map[uint32][]*rtp.Packet),
+		droppedStreams:   make(map[uint32]struct{}),
+		bufferTransportGenerator: func(ssrc uint32, payloadType uint8, mid string) (chan<- *rtp.Packet, bool) {
+			calls++
+			return nil, hold
+		},
+	}
+
+	// Packets are held while the generator may still bind the stream
+	assert.Nil(t, m.deliverRTP(&rtp.Packet{SSRC: 5000}))
+	assert.Nil(t, m.deliverRTP(&rtp.Packet{SSRC: 5000}))
+	assert.Equal(t, 2, calls)
+	assert.Equal(t, 2, len(m.earlyMedia[5000]))
+
+	// Once it can't the held packets are dropped, so are later ones without
+	// asking the generator again
+	hold = false
+	assert.Nil(t, m.deliverRTP(&rtp.Packet{SSRC: 5000}))
+	assert.Nil(t, m.deliverRTP(&rtp.Packet{SSRC: 5000}))
+	assert.Equal(t, 3, calls)
+	_, stillHeld := m.earlyMedia[5000]
+	assert.False(t, stillHeld)
+}
@@ -0,0 +1,53 @@
+package network
+
+import (
+	"github.com/pions/webrtc/internal/ulpfec"
+	"github.com/pions/webrtc/pkg/rtp"
+)
+
+// SetFEC configures the recovery of inbound media from RFC 5109 FEC
+// packets. ssrcs maps each FEC SSRC to the SSRC of the stream it protects,
+// as signaled by a=ssrc-group:FEC. payloadTypes are the payload types of
+// the ulpfec codec.
+func (m *Manager) SetFEC(ssrcs map[uint32]uint32, payloadTypes []uint8) {
+	m.srtpInboundContextLock.Lock()
+	defer m.srtpInboundContextLock.Unlock()
+
+	m.fecPayloadTypes = make(map[uint8]bool)
+	for _, payloadType := range payloadTypes {
+		m.fecPayloadTypes[payloadType] = true
+	}
+
+	m.fecSSRCs = ssrcs
+	m.fecDecoders = make(map[uint32]*ulpfec.Decoder)
+	for _, ssrc := range ssrcs {
+		m.fecDecoders[ssrc] = ulpfec.NewDecoder(ssrc)
+	}
+}
+
+// handleFEC records media packets of protected streams and returns the
+// media packet recovered by an FEC packet, isFEC reports if the packet
+// was an FEC packet and must not be delivered.
+// Note: the caller should hold the srtpInboundContextLock.
+func (m *Manager) handleFEC(packet *rtp.Packet) (recovered *rtp.Packet, isFEC bool) {
+	if !m.fecPayloadTypes[packet.PayloadType] {
+		if decoder, ok := m.fecDecoders[packet.SSRC]; ok {
+			if err := decoder.PushMedia(packet); err != nil {
+				m.rtpLog.Warnf("Failed to record packet for FEC recovery: %v", err)
+			}
+		}
+		return nil, false
+	}
+
+	ssrc, ok := m.fecSSRCs[packet.SSRC]
+	if !ok {
+		return nil, true
+	}
+
+	recovered, err := m.fecDecoders[ssrc].PushFEC(packet)
+	if err != nil {
+		m.rtpLog.Warnf("Failed to handle FEC packet: %v", err)
+		return nil, true
+	}
+	return recovered, true
+}
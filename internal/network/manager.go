@@ -2,25 +2,36 @@ package network
 
 import (
 	"fmt"
+	"math/rand"
+	"net"
+	"strconv"
 	"sync"
+	"time"
 
 	"github.com/pions/pkg/stun"
 	"github.com/pions/webrtc/internal/dtls"
 	"github.com/pions/webrtc/internal/sctp"
 	"github.com/pions/webrtc/internal/srtp"
 	webrtcStun "github.com/pions/webrtc/internal/stun"
+	"github.com/pions/webrtc/internal/turn"
+	"github.com/pions/webrtc/internal/ulpfec"
+	"github.com/pions/webrtc/internal/util"
 	"github.com/pions/webrtc/pkg/datachannel"
 	"github.com/pions/webrtc/pkg/ice"
+	"github.com/pions/webrtc/pkg/interceptor"
+	"github.com/pions/webrtc/pkg/logging"
+	"github.com/pions/webrtc/pkg/pcap"
 	"github.com/pions/webrtc/pkg/rtp"
 	"github.com/pkg/errors"
+	"golang.org/x/net/proxy"
 )
 
 // Manager contains all network state (DTLS, SRTP) that is shared between ports
 // It is also used to perform operations that involve multiple ports
 type Manager struct {
-	IceAgent    *ice.Agent
-	iceNotifier ICENotifier
-	isOffer     bool
+	IceAgent     *ice.Agent
+	iceNotifier  ICENotifier
+	isDTLSClient bool
 
 	dtlsState *dtls.State
 
@@ -30,38 +41,264 @@ type Manager struct {
 	dataChannelEventHandler DataChannelEventHandler
 
 	bufferTransportGenerator BufferTransportGenerator
+	rtpObserver              RTPObserver
 	bufferTransports         map[uint32]chan<- *rtp.Packet
+	bufferTransportsClosed   bool
+	earlyMedia               map[uint32][]*rtp.Packet
+	droppedStreams           map[uint32]struct{}
+	rtxSSRCs                 map[uint32]uint32
+	rtxPayloadTypes          map[uint8]uint8
+	fecSSRCs                 map[uint32]uint32
+	fecPayloadTypes          map[uint8]bool
+	fecDecoders              map[uint32]*ulpfec.Decoder
+	midExtensionID           uint8
+
+	rtcpHandler RTCPHandler
+
+	// Every RTP and RTCP packet passes through the chain of the interceptor
+	// of the settings, these are its ends
+	outboundRTP  interceptor.RTPWriter
+	inboundRTP   interceptor.RTPWriter
+	outboundRTCP interceptor.RTCPWriter
+	inboundRTCP  interceptor.RTCPWriter
+
+	dtlsNotifier DTLSNotifier
 
 	srtpInboundContextLock sync.RWMutex
 	srtpInboundContext     *srtp.Context
 
+	// srtpAuthFailures counts the inbound SRTP and SRTCP packets which
+	// failed to decrypt, guarded by the srtpInboundContextLock
+	srtpAuthFailures uint64
+
 	srtpOutboundContextLock sync.RWMutex
 	srtpOutboundContext     *srtp.Context
+	rtcpAddr                *net.UDPAddr
+	rtcpReducedSize         bool
+	payloadTypes            map[uint8]uint8
+
+	// absSendTimeExtensionID is the id of the abs-send-time header extension
+	// stamped on outbound RTP packets, zero if it isn't negotiated
+	absSendTimeExtensionID uint8
+
+	// rtcpSSRC and rtcpCNAME identify the reports leading compound RTCP
+	// packets
+	rtcpSSRC  uint32
+	rtcpCNAME string
+
+	// The RTCP component of remote peers which don't multiplex RTCP with
+	// RTP runs a DTLS handshake of its own, SRTCP on its ports is keyed by
+	// it, RFC 5764. The component is closed once RTCP is multiplexed.
+	rtcpDTLSState        *dtls.State
+	rtcpCertPair         *dtls.CertPair
+	srtcpInboundContext  *srtp.Context
+	srtcpOutboundContext *srtp.Context
+	rtcpComponentClosed  bool
 
 	sctpAssociation *sctp.Association
 
+	// sctpConnectLock guards connecting the SCTP association once it is
+	// enabled and DTLS is established
+	sctpConnectLock sync.Mutex
+	sctpEnabled     bool
+	dtlsEstablished bool
+	sctpConnected   bool
+
+	// sctpClosed is closed once the SCTP association is closed
+	sctpClosed     chan struct{}
+	sctpClosedOnce sync.Once
+
 	portsLock sync.RWMutex
 	ports     []*port
+	closed    bool
+
+	// networks are the address families srflx candidates are gathered for
+	networks []string
+
+	settings Settings
+
+	// The diagnostics of media, of the DTLS handshake, of data channels and
+	// of TURN allocations are written to the loggers of their scopes
+	rtpLog  logging.LeveledLogger
+	dtlsLog logging.LeveledLogger
+	sctpLog logging.LeveledLogger
+	iceLog  logging.LeveledLogger
+}
+
+// Settings tune the Manager beyond what the WebRTC API allows, the zero
+// value uses the defaults
+type Settings struct {
+	// PortMin and PortMax limit the ports host candidates listen on, zero
+	// allows any port
+	PortMin uint16
+	PortMax uint16
+
+	// InterfaceFilter selects the interfaces host candidates are gathered on
+	// by their name, nil gathers on every interface
+	InterfaceFilter func(string) bool
+
+	// DisableHostCandidates, DisableSrflxCandidates and
+	// DisableRelayCandidates prevent gathering candidates of the type
+	DisableHostCandidates  bool
+	DisableSrflxCandidates bool
+	DisableRelayCandidates bool
+
+	// TCPCandidates gathers passive and active TCP host candidates next to
+	// the UDP ones, RFC 6544, for peers that can't use UDP
+	TCPCandidates bool
+
+	// RTCPCandidates gathers a host candidate of the RTCP component on a
+	// port of its own next to every UDP host candidate, for peers which
+	// don't multiplex RTCP with RTP. The component authenticates its DTLS
+	// handshake with the Certificate, which is required. It is ignored with
+	// a UDPMux.
+	RTCPCandidates bool
+
+	// NAT1To1IPs maps the IPs of host candidates to the public IPs they are
+	// reachable at through a static 1:1 NAT, the public IP keyed by the
+	// unspecified IP of an address family, 0.0.0.0 or ::, is used for the
+	// IPs of the family without a mapping of their own
+	NAT1To1IPs map[string]string
+
+	// NAT1To1AsSrflx announces the public IPs as srflx candidates next to
+	// the host candidates, instead of replacing the IP of host candidates
+	NAT1To1AsSrflx bool
+
+	// UDPMux shares its socket with the host candidate of every Manager
+	// using it, instead of listening on a port of every interface. Srflx
+	// candidates aren't gathered, NAT1To1IPs announces the public IP.
+	UDPMux *UDPMux
+
+	// Net opens the sockets of host candidates in place of the network of
+	// the OS, like a virtual network does. Host candidates are gathered on
+	// its IPs, srflx, relay and TCP candidates aren't gathered.
+	Net Net
+
+	// ProxyDialer opens the TCP connections to TURN servers, like an HTTP
+	// or SOCKS proxy, nil uses the proxy of the HTTPS_PROXY or ALL_PROXY
+	// variables of the environment
+	ProxyDialer proxy.Dialer
+
+	// BatchSize reads and writes up to that many packets per syscall on the
+	// UDP ports of host candidates, with recvmmsg and sendmmsg on Linux.
+	// Zero or one moves a packet per syscall, it is ignored with a Net or a
+	// UDPMux.
+	BatchSize int
+
+	// SRTPProtectionProfiles are offered during the DTLS handshake in order
+	// of preference, nil uses dtls.DefaultSRTPProtectionProfiles
+	SRTPProtectionProfiles []string
+
+	// Certificate is the certificate DTLS authenticates with, nil generates
+	// one
+	Certificate *dtls.Certificate
+
+	// ICEUfrag and ICEPwd are the local ICE credentials, empty ones are
+	// generated by the agent
+	ICEUfrag string
+	ICEPwd   string
+
+	// PriorityPolicy computes the priorities of the local candidates
+	PriorityPolicy ice.PriorityPolicy
+
+	// LoggerFactory creates the loggers of the Manager and its ICE agent,
+	// nil uses logging.NewDefaultLoggerFactory
+	LoggerFactory logging.LoggerFactory
+
+	// Interceptor is bound to the RTP and RTCP packets sent and received,
+	// nil passes them on unchanged
+	Interceptor interceptor.Interceptor
+
+	// PacketCapture is called with every RTP and RTCP packet sent before it
+	// is encrypted and received after it is decrypted, and with the SCTP
+	// packets carried by DTLS if CaptureSCTP is set. It is called on the
+	// goroutines sending and receiving, it must not block.
+	PacketCapture func(pcap.Packet)
+	CaptureSCTP   bool
 }
 
 // NewManager creates a new network.Manager
-func NewManager(btg BufferTransportGenerator, dcet DataChannelEventHandler, ntf ICENotifier) (m *Manager, err error) {
+func NewManager(btg BufferTransportGenerator, dcet DataChannelEventHandler, ntf ICENotifier, obs RTPObserver, rh RTCPHandler, dn DTLSNotifier, settings Settings) (m *Manager, err error) {
 	m = &Manager{
 		iceNotifier:              ntf,
+		rtpObserver:              obs,
+		rtcpHandler:              rh,
+		dtlsNotifier:             dn,
 		bufferTransports:         make(map[uint32]chan<- *rtp.Packet),
+		earlyMedia:               make(map[uint32][]*rtp.Packet),
+		droppedStreams:           make(map[uint32]struct{}),
 		bufferTransportGenerator: btg,
 		dataChannelEventHandler:  dcet,
+		sctpClosed:               make(chan struct{}),
+		sctpEnabled:              true,
+		settings:                 settings,
+		rtcpSSRC:                 rand.Uint32(),
+		rtcpCNAME:                util.RandSeq(16),
+	}
+
+	loggerFactory := settings.LoggerFactory
+	if loggerFactory == nil {
+		loggerFactory = logging.NewDefaultLoggerFactory()
+	}
+	m.rtpLog = loggerFactory.NewLogger(logging.ScopeRTP)
+	m.dtlsLog = loggerFactory.NewLogger(logging.ScopeDTLS)
+	m.sctpLog = loggerFactory.NewLogger(logging.ScopeSCTP)
+	m.iceLog = loggerFactory.NewLogger(logging.ScopeICE)
+
+	var i interceptor.Interceptor = interceptor.NoOp{}
+	if settings.Interceptor != nil {
+		i = settings.Interceptor
 	}
-	m.dtlsState, err = dtls.NewState(m.handleDTLSState)
+	m.outboundRTP = i.BindOutboundRTP(interceptor.RTPWriterFunc(m.writeRTP))
+	m.inboundRTP = i.BindInboundRTP(interceptor.RTPWriterFunc(m.deliverRTP))
+	m.outboundRTCP = i.BindOutboundRTCP(interceptor.RTCPWriterFunc(m.writeRTCP))
+	m.inboundRTCP = i.BindInboundRTCP(interceptor.RTCPWriterFunc(m.handleRTCP))
+
+	m.dtlsState, err = dtls.NewState(m.handleDTLSState, settings.SRTPProtectionProfiles, settings.Certificate)
 	if err != nil {
 		return nil, err
 	}
 
-	m.sctpAssociation = sctp.NewAssocation(m.dataChannelOutboundHandler, m.dataChannelInboundHandler, m.handleSCTPState)
+	if settings.RTCPCandidates && settings.UDPMux == nil {
+		if settings.Certificate == nil {
+			return nil, errors.New("RTCP candidates require a certificate")
+		}
+		m.rtcpDTLSState, err = dtls.NewState(m.handleRTCPDTLSState, settings.SRTPProtectionProfiles, settings.Certificate)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	m.sctpAssociation = sctp.NewAssocation(m.dataChannelOutboundHandler, m.dataChannelInboundHandler, m.handleSCTPState, m.sctpLog)
 
 	m.IceAgent = ice.NewAgent(m.iceNotifier)
-	for _, i := range localInterfaces() {
-		p, portErr := newPort(i+":0", m)
+	m.IceAgent.SetLogger(m.iceLog)
+	m.IceAgent.SetPriorityPolicy(settings.PriorityPolicy)
+	if settings.ICEUfrag != "" && settings.ICEPwd != "" {
+		m.IceAgent.LocalUfrag = settings.ICEUfrag
+		m.IceAgent.LocalPwd = settings.ICEPwd
+	}
+
+	ips := localInterfaces(settings.InterfaceFilter)
+	m.networks = []string{"udp4"}
+	if len(ips) > 0 && isIPv6(ips[0]) {
+		m.networks = []string{"udp6", "udp4"}
+	}
+	if settings.Net != nil {
+		ips = nil
+		for _, ip := range settings.Net.IPs() {
+			ips = append(ips, ip.String())
+		}
+	}
+	if settings.UDPMux != nil {
+		ips = []string{settings.UDPMux.addr.IP.String()}
+	}
+	if settings.DisableHostCandidates {
+		ips = nil
+	}
+
+	for n, i := range ips {
+		p, portErr := m.listenUDP(i)
 		if portErr != nil {
 			return nil, portErr
 		}
@@ -69,59 +306,265 @@ func NewManager(btg BufferTransportGenerator, dcet DataChannelEventHandler, ntf
 		m.ports = append(m.ports, p)
 		m.IceAgent.AddLocalCandidate(&ice.CandidateHost{
 			CandidateBase: ice.CandidateBase{
-				Protocol: ice.ProtoTypeUDP,
-				Address:  p.listeningAddr.IP.String(),
-				Port:     p.listeningAddr.Port,
-				Conn:     p.conn,
+				Protocol:        ice.ProtoTypeUDP,
+				Address:         p.listeningAddr.IP.String(),
+				Port:            p.listeningAddr.Port,
+				LocalPreference: localPreference(n),
+				Conn:            p.conn,
 			},
 		})
+
+		// The public IP of a static NAT is known up front, no STUN server
+		// is needed to learn it
+		if publicIP, ok := m.publicIP(i); ok && settings.NAT1To1AsSrflx && !settings.DisableSrflxCandidates {
+			m.IceAgent.AddLocalCandidate(&ice.CandidateSrflx{
+				CandidateBase: ice.CandidateBase{
+					Protocol:        ice.ProtoTypeUDP,
+					Address:         publicIP,
+					Port:            p.listeningAddr.Port,
+					LocalPreference: localPreference(n),
+					Conn:            p.conn,
+				},
+				RemoteAddress: p.listeningAddr.IP.String(),
+				RemotePort:    p.listeningAddr.Port,
+			})
+		}
+
+		if m.rtcpDTLSState != nil {
+			if err = m.addRTCPCandidate(i, n); err != nil {
+				return nil, err
+			}
+		}
+
+		if settings.TCPCandidates && settings.Net == nil {
+			if err = m.addTCPCandidates(i, n); err != nil {
+				return nil, err
+			}
+		}
 	}
 
 	return m, err
 }
 
+// addTCPCandidates listens for TCP connections on the IP and adds a passive
+// candidate for them and an active one to connect to remote passive
+// candidates with. Their local preference is lower than the one of UDP
+// candidates, RFC 6544 Section 4.2.
+func (m *Manager) addTCPCandidates(ip string, n int) error {
+	p, err := m.listen(listenTCP, ip, ice.ComponentRTP)
+	if err != nil {
+		return err
+	}
+	m.ports = append(m.ports, p)
+
+	otherPreference := localPreference(n) >> 3
+	m.IceAgent.AddLocalCandidate(&ice.CandidateHost{
+		CandidateBase: ice.CandidateBase{
+			Protocol:        ice.ProtoTypeTCP,
+			TCPType:         ice.TCPTypePassive,
+			Address:         p.listeningAddr.IP.String(),
+			Port:            p.listeningAddr.Port,
+			LocalPreference: tcpDirectionPreferencePassive<<13 | otherPreference,
+			Conn:            p.conn,
+		},
+	})
+	m.IceAgent.AddLocalCandidate(&ice.CandidateHost{
+		CandidateBase: ice.CandidateBase{
+			Protocol:        ice.ProtoTypeTCP,
+			TCPType:         ice.TCPTypeActive,
+			Address:         p.listeningAddr.IP.String(),
+			Port:            tcpActivePort,
+			LocalPreference: tcpDirectionPreferenceActive<<13 | otherPreference,
+			Conn:            p.conn,
+		},
+	})
+	return nil
+}
+
+// publicIP returns the IP the private IP is mapped to by a static 1:1 NAT,
+// an IPv4 address is never mapped to an IPv6 one and the other way round
+func (m *Manager) publicIP(privateIP string) (string, bool) {
+	if publicIP, ok := m.settings.NAT1To1IPs[privateIP]; ok {
+		return publicIP, true
+	}
+
+	ip := net.ParseIP(privateIP)
+	if ip == nil {
+		return "", false
+	}
+	unspecified := net.IPv6unspecified
+	if ip.To4() != nil {
+		unspecified = net.IPv4zero
+	}
+	publicIP, ok := m.settings.NAT1To1IPs[unspecified.String()]
+	return publicIP, ok
+}
+
+// LocalCandidates returns the local candidates to be announced to the remote
+// peer, with the IP of host candidates behind a static 1:1 NAT replaced by
+// the public IP unless they are announced as srflx candidates
+func (m *Manager) LocalCandidates() []ice.Candidate {
+	m.IceAgent.RLock()
+	defer m.IceAgent.RUnlock()
+
+	candidates := make([]ice.Candidate, 0, len(m.IceAgent.LocalCandidates))
+	for _, c := range m.IceAgent.LocalCandidates {
+		if host, ok := c.(*ice.CandidateHost); ok && !m.settings.NAT1To1AsSrflx {
+			if publicIP, ok := m.publicIP(host.CandidateBase.Address); ok {
+				mapped := *host
+				mapped.CandidateBase.Address = publicIP
+				c = &mapped
+			}
+		}
+		candidates = append(candidates, c)
+	}
+	return candidates
+}
+
+// listenUDP opens a UDP port on the IP, or a conn of the UDPMux if there is
+// one
+func (m *Manager) listenUDP(ip string) (*port, error) {
+	if m.settings.UDPMux == nil {
+		return m.listen(m.udpListenFunc(), ip, ice.ComponentRTP)
+	}
+
+	conn, err := m.settings.UDPMux.getConn(m.IceAgent.LocalUfrag)
+	if err != nil {
+		return nil, err
+	}
+	return startPort(conn, m.settings.UDPMux.addr, m, ice.ComponentRTP), nil
+}
+
+// udpListenFunc returns the function UDP ports are opened with, on the Net
+// of the settings if there is one
+func (m *Manager) udpListenFunc() func(address string) (net.PacketConn, error) {
+	if m.settings.Net != nil {
+		return func(address string) (net.PacketConn, error) {
+			return m.settings.Net.ListenPacket("udp", address)
+		}
+	}
+	if m.settings.BatchSize > 1 {
+		return func(address string) (net.PacketConn, error) {
+			conn, err := listenUDP(address)
+			if err != nil {
+				return nil, err
+			}
+			// Ports which aren't sockets of the OS move a packet per call
+			udpConn, ok := conn.(*net.UDPConn)
+			if !ok {
+				return conn, nil
+			}
+			return newBatchConn(udpConn, m.settings.BatchSize, m.rtpLog), nil
+		}
+	}
+	return listenUDP
+}
+
+// listen opens a port of the component on the IP, within the port range if
+// there is one. Ports are found by their address, so a TCP port never takes
+// the number of a UDP port on the same IP.
+func (m *Manager) listen(listenFunc func(address string) (net.PacketConn, error), ip string, component uint16) (*port, error) {
+	if m.settings.PortMax == 0 {
+		for {
+			conn, err := listenFunc(net.JoinHostPort(ip, "0"))
+			if err != nil {
+				return nil, err
+			}
+
+			addr, err := stun.NewTransportAddr(conn.LocalAddr())
+			if err != nil {
+				return nil, err
+			}
+			if _, inUse := m.port(addr); inUse != nil {
+				return startPort(conn, addr, m, component), nil
+			}
+			if err := conn.Close(); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	var err error
+	for portNumber := int(m.settings.PortMin); portNumber <= int(m.settings.PortMax); portNumber++ {
+		addr := &stun.TransportAddr{IP: net.ParseIP(ip), Port: portNumber}
+		if _, inUse := m.port(addr); inUse == nil {
+			err = errors.Errorf("%s is in use", addr.String())
+			continue
+		}
+
+		var conn net.PacketConn
+		if conn, err = listenFunc(net.JoinHostPort(ip, strconv.Itoa(portNumber))); err != nil {
+			continue
+		}
+		if addr, err = stun.NewTransportAddr(conn.LocalAddr()); err != nil {
+			return nil, err
+		}
+		return startPort(conn, addr, m, component), nil
+	}
+	return nil, errors.Wrapf(err, "No free port between %d and %d", m.settings.PortMin, m.settings.PortMax)
+}
+
 func (m *Manager) handleDTLSState(state dtls.ConnectionState) {
-	if state == dtls.Established {
-		m.sctpAssociation.Connect()
+	switch state {
+	case dtls.Established:
+		m.sctpConnectLock.Lock()
+		m.dtlsEstablished = true
+		m.connectSCTP()
+		m.sctpConnectLock.Unlock()
 	}
+	m.dtlsNotifier(state)
 }
 
 func (m *Manager) handleSCTPState(state sctp.AssociationState) {
-	if state == sctp.Established {
+	switch state {
+	case sctp.Established:
+		m.sctpAssociation.Lock()
+		streams := m.sctpAssociation.Streams()
+		m.sctpAssociation.Unlock()
+
 		// Temporary way to signal sending OpenChannel messages
-		m.dataChannelEventHandler(&DataChannelOpen{})
+		m.dataChannelEventHandler(&DataChannelOpen{Streams: streams})
+	case sctp.Closed:
+		m.sctpClosedOnce.Do(func() {
+			close(m.sctpClosed)
+		})
+		m.dataChannelEventHandler(&DataChannelClose{})
 	}
 }
 
-// AddURL takes an ICE Url, allocates any state and adds the candidate
-func (m *Manager) AddURL(url *ice.URL) error {
+// AddURL takes an ICE Url, allocates any state and adds the candidate, TURN
+// servers authenticate the allocation of relay candidates and its refreshes
+// with the credentials
+func (m *Manager) AddURL(url *ice.URL, credentials turn.CredentialsFunc) error {
 	switch url.Scheme {
 	case ice.SchemeTypeSTUN:
-		laddr, xoraddr, err := webrtcStun.AllocateUDP(url)
-		if err != nil {
-			return err
+		if m.settings.DisableSrflxCandidates || m.settings.UDPMux != nil || m.settings.Net != nil {
+			return nil
 		}
 
-		p, err := newPort(laddr.String(), m)
-		if err != nil {
+		// Dual-stack hosts gather a candidate per address family, failing
+		// only if none could be gathered
+		var err error
+		gathered := false
+		for n, network := range m.networks {
+			if networkErr := m.addSrflxCandidate(network, url, localPreference(n)); networkErr != nil {
+				if err != nil {
+					err = errors.Wrapf(networkErr, " also: %s", err.Error())
+				} else {
+					err = networkErr
+				}
+				continue
+			}
+			gathered = true
+		}
+		if !gathered {
 			return err
 		}
-
-		c := &ice.CandidateSrflx{
-			CandidateBase: ice.CandidateBase{
-				Protocol: ice.ProtoTypeUDP,
-				Address:  xoraddr.IP.String(),
-				Port:     xoraddr.Port,
-				Conn:     p.conn,
-			},
-			RemoteAddress: laddr.IP.String(),
-			RemotePort:    laddr.Port,
+	case ice.SchemeTypeTURN, ice.SchemeTypeTURNS:
+		if m.settings.DisableRelayCandidates || m.settings.Net != nil {
+			return nil
 		}
-
-		m.portsLock.Lock()
-		defer m.portsLock.Unlock()
-		m.ports = append(m.ports, p)
-		m.IceAgent.AddLocalCandidate(c)
+		return m.addRelayCandidate(url, credentials)
 	default:
 		return errors.Errorf("%s is not implemented", url.Scheme.String())
 	}
@@ -129,9 +572,85 @@ func (m *Manager) AddURL(url *ice.URL) error {
 	return nil
 }
 
-// Start allocates DTLS/ICE state that is dependent on if we are offering or answering
-func (m *Manager) Start(isOffer bool, remoteUfrag, remotePwd string) error {
-	m.isOffer = isOffer
+// addSrflxCandidate learns the public address of the network from the STUN
+// server and adds it as a srflx candidate
+func (m *Manager) addSrflxCandidate(network string, url *ice.URL, localPreference uint16) error {
+	laddr, xoraddr, err := webrtcStun.AllocateUDP(network, url)
+	if err != nil {
+		return err
+	}
+
+	p, err := newPort(laddr.String(), m)
+	if err != nil {
+		return err
+	}
+
+	c := &ice.CandidateSrflx{
+		CandidateBase: ice.CandidateBase{
+			Protocol:        ice.ProtoTypeUDP,
+			Address:         xoraddr.IP.String(),
+			Port:            xoraddr.Port,
+			LocalPreference: localPreference,
+			Conn:            p.conn,
+		},
+		RemoteAddress: laddr.IP.String(),
+		RemotePort:    laddr.Port,
+	}
+
+	m.portsLock.Lock()
+	defer m.portsLock.Unlock()
+	// Candidates are gathered in the background, the Manager may have been
+	// closed meanwhile
+	if m.closed {
+		return p.close()
+	}
+	m.ports = append(m.ports, p)
+	m.IceAgent.AddLocalCandidate(c)
+	return nil
+}
+
+// addRelayCandidate allocates a relayed transport address on the TURN server
+// and adds it as a relay candidate, its packets are relayed over the
+// connection to the server
+func (m *Manager) addRelayCandidate(url *ice.URL, credentials turn.CredentialsFunc) error {
+	conn, err := m.dialTURN(url)
+	if err != nil {
+		return err
+	}
+	allocation, err := turn.Allocate(conn, credentials, m.iceLog)
+	if err != nil {
+		return err
+	}
+
+	relayedAddr, mappedAddr := allocation.RelayedAddr(), allocation.MappedAddr()
+	p := startPort(allocation, &stun.TransportAddr{IP: relayedAddr.IP, Port: relayedAddr.Port}, m, ice.ComponentRTP)
+	c := &ice.CandidateRelay{
+		CandidateBase: ice.CandidateBase{
+			Protocol: ice.ProtoTypeUDP,
+			Address:  relayedAddr.IP.String(),
+			Port:     relayedAddr.Port,
+			Conn:     p.conn,
+		},
+		RelatedAddress: mappedAddr.IP.String(),
+		RelatedPort:    mappedAddr.Port,
+	}
+
+	m.portsLock.Lock()
+	defer m.portsLock.Unlock()
+	if m.closed {
+		return p.close()
+	}
+	m.ports = append(m.ports, p)
+	m.IceAgent.AddLocalCandidate(c)
+	return nil
+}
+
+// Start allocates DTLS/ICE state that is dependent on if we are offering or
+// answering, and on the DTLS role negotiated with the a=setup attributes
+func (m *Manager) Start(isOffer, isDTLSClient bool, remoteUfrag, remotePwd string) error {
+	m.certPairLock.Lock()
+	m.isDTLSClient = isDTLSClient
+	m.certPairLock.Unlock()
 
 	// Start the sctpAssociation
 	m.sctpAssociation.Start(isOffer)
@@ -140,8 +659,13 @@ func (m *Manager) Start(isOffer bool, remoteUfrag, remotePwd string) error {
 		return err
 	}
 	// Start DTLS
-	m.dtlsState.Start(isOffer)
+	m.dtlsState.Start(isDTLSClient)
 
+	m.portsLock.RLock()
+	defer m.portsLock.RUnlock()
+	if m.rtcpDTLSState != nil && !m.rtcpComponentClosed {
+		m.rtcpDTLSState.Start(isDTLSClient)
+	}
 	return nil
 }
 
@@ -149,11 +673,22 @@ func (m *Manager) Start(isOffer bool, remoteUfrag, remotePwd string) error {
 func (m *Manager) Close() {
 	m.portsLock.Lock()
 	defer m.portsLock.Unlock()
+	m.closed = true
 
+	// SCTP ends before the DTLS transport carrying it, which ends before
+	// the ICE transport
+	m.sctpAssociation.Lock()
 	err := m.sctpAssociation.Close()
+	m.sctpAssociation.Unlock()
 	m.dtlsState.Close()
+	if m.rtcpDTLSState != nil {
+		m.rtcpDTLSState.Close()
+	}
 	m.IceAgent.Close()
 
+	// The lock is held while media is delivered, which may have called Close
+	go m.closeBufferTransports()
+
 	for i := len(m.ports) - 1; i >= 0; i-- {
 		if portError := m.ports[i].close(); portError != nil {
 			if err != nil {
@@ -167,17 +702,78 @@ func (m *Manager) Close() {
 	}
 }
 
+// ShutdownSCTP gracefully closes the SCTP association, the data channel
+// messages sent so far are delivered to the remote peer first. It waits at
+// most the timeout for the remote peer to acknowledge the shutdown.
+func (m *Manager) ShutdownSCTP(timeout time.Duration) error {
+	m.sctpAssociation.Lock()
+	err := m.sctpAssociation.Shutdown()
+	m.sctpAssociation.Unlock()
+	if err != nil {
+		return err
+	}
+
+	select {
+	case <-m.sctpClosed:
+		return nil
+	case <-time.After(timeout):
+		return errors.Errorf("SCTP shutdown not acknowledged within %v", timeout)
+	}
+}
+
+// closeBufferTransports closes the channels media is delivered on, no media
+// is delivered afterwards
+func (m *Manager) closeBufferTransports() {
+	m.srtpInboundContextLock.Lock()
+	defer m.srtpInboundContextLock.Unlock()
+
+	m.bufferTransportsClosed = true
+	for ssrc, bufferTransport := range m.bufferTransports {
+		close(bufferTransport)
+		delete(m.bufferTransports, ssrc)
+	}
+	m.earlyMedia = make(map[uint32][]*rtp.Packet)
+}
+
+// SetRemoteFingerprints sets the fingerprints the certificate of the remote
+// peer is verified against during the DTLS handshake
+func (m *Manager) SetRemoteFingerprints(fingerprints []dtls.Fingerprint) {
+	m.dtlsState.SetRemoteFingerprints(fingerprints)
+	if m.rtcpDTLSState != nil {
+		m.rtcpDTLSState.SetRemoteFingerprints(fingerprints)
+	}
+}
+
+// DTLSErr returns why the DTLS handshake failed
+func (m *Manager) DTLSErr() error {
+	return m.dtlsState.Err()
+}
+
+// DTLSRemoteCertificate returns the DER encoding of the certificate the
+// remote peer authenticated the DTLS handshake with, nil until it completed
+func (m *Manager) DTLSRemoteCertificate() []byte {
+	return m.dtlsState.RemoteCertificate()
+}
+
 // DTLSFingerprint generates the fingerprint included in an SessionDescription
 func (m *Manager) DTLSFingerprint() string {
 	return m.dtlsState.Fingerprint()
 }
 
-// SendRTP finds a connected port and sends the passed RTP packet
+// SendRTP passes the RTP packet, under its negotiated payload type, through
+// the interceptor, which sends it on a connected port
 func (m *Manager) SendRTP(packet *rtp.Packet) {
+	m.mapPayloadType(packet)
+	if err := m.outboundRTP.WriteRTP(packet); err != nil {
+		m.rtpLog.Warnf("Failed to send RTP packet: %v", err)
+	}
+}
 
+// writeRTP sends the RTP packet on a connected port
+func (m *Manager) writeRTP(packet *rtp.Packet) error {
 	local, remote := m.IceAgent.SelectedPair()
 	if local == nil || remote == nil {
-		return
+		return nil
 	}
 
 	m.portsLock.RLock()
@@ -187,22 +783,44 @@ func (m *Manager) SendRTP(packet *rtp.Packet) {
 			p.sendRTP(packet, remote)
 		}
 	}
+	return nil
 }
 
-// SendRTCP finds a connected port and sends the passed RTCP packet
+// SendRTCP passes the RTCP packet through the interceptor, which sends it
+// on a connected port
 func (m *Manager) SendRTCP(pkt []byte) {
+	if err := m.outboundRTCP.WriteRTCP(pkt); err != nil {
+		m.rtpLog.Warnf("Failed to send RTCP packet: %v", err)
+	}
+}
+
+// writeRTCP sends the RTCP packet on a connected port
+func (m *Manager) writeRTCP(pkt []byte) error {
 	local, remote := m.IceAgent.SelectedPair()
 	if local == nil || remote == nil {
-		return
+		return nil
+	}
+	dst := m.rtcpDestination(remote)
+
+	// Peers which don't multiplex RTCP with RTP receive it on the pair of
+	// the RTCP component once one is found
+	if rtcpLocal, rtcpRemote := m.IceAgent.SelectedRTCPPair(); rtcpLocal != nil && rtcpRemote != nil {
+		local, dst = rtcpLocal, rtcpRemote
+	}
+
+	pkt, err := m.compoundRTCP(pkt)
+	if err != nil {
+		return err
 	}
 
 	m.portsLock.RLock()
 	defer m.portsLock.RUnlock()
 	for _, p := range m.ports {
 		if p.listeningAddr.Equal(local) {
-			p.sendRTCP(pkt, remote)
+			p.sendRTCP(pkt, dst)
 		}
 	}
+	return nil
 }
 
 // SendDataChannelMessage sends a DataChannel message to a connected peer
@@ -256,7 +874,7 @@ func (m *Manager) dataChannelInboundHandler(data []byte, streamIdentifier uint16
 	case sctp.PayloadTypeWebRTCDCEP:
 		msg, err := datachannel.Parse(data)
 		if err != nil {
-			fmt.Println(errors.Wrap(err, "Failed to parse DataChannel packet"))
+			m.sctpLog.Warn(errors.Wrap(err, "Failed to parse DataChannel packet").Error())
 			return
 		}
 		switch msg := msg.(type) {
@@ -265,18 +883,18 @@ func (m *Manager) dataChannelInboundHandler(data []byte, streamIdentifier uint16
 			ack := datachannel.ChannelAck{}
 			ackMsg, err := ack.Marshal()
 			if err != nil {
-				fmt.Println("Error Marshaling ChannelOpen ACK", err)
+				m.sctpLog.Errorf("Error Marshaling ChannelOpen ACK: %v", err)
 				return
 			}
 			if err = m.sctpAssociation.HandleOutbound(ackMsg, streamIdentifier, sctp.PayloadTypeWebRTCDCEP); err != nil {
-				fmt.Println("Error sending ChannelOpen ACK", err)
+				m.sctpLog.Errorf("Error sending ChannelOpen ACK: %v", err)
 				return
 			}
 			m.dataChannelEventHandler(&DataChannelCreated{streamIdentifier: streamIdentifier, Label: string(msg.Label)})
 		case *datachannel.ChannelAck:
 			// TODO: handle ChannelAck (https://tools.ietf.org/html/draft-ietf-rtcweb-data-protocol-09#section-5.2)
 		default:
-			fmt.Println("Unhandled DataChannel message", msg)
+			m.sctpLog.Warnf("Unhandled DataChannel message %v", msg)
 		}
 	case sctp.PayloadTypeWebRTCString:
 		fallthrough
@@ -289,7 +907,7 @@ func (m *Manager) dataChannelInboundHandler(data []byte, streamIdentifier uint16
 		payload := &datachannel.PayloadBinary{Data: data}
 		m.dataChannelEventHandler(&DataChannelMessage{streamIdentifier: streamIdentifier, Payload: payload})
 	default:
-		fmt.Printf("Unhandled Payload Protocol Identifier %v \n", payloadType)
+		m.sctpLog.Warnf("Unhandled Payload Protocol Identifier %v", payloadType)
 	}
 }
 
@@ -297,7 +915,7 @@ func (m *Manager) dataChannelOutboundHandler(raw []byte) {
 	local, remote := m.IceAgent.SelectedPair()
 	if remote == nil || local == nil {
 		// Send data on any valid pair
-		fmt.Println("dataChannelOutboundHandler: no valid candidates, dropping packet")
+		m.sctpLog.Warn("dataChannelOutboundHandler: no valid candidates, dropping packet")
 		return
 	}
 
@@ -305,7 +923,7 @@ func (m *Manager) dataChannelOutboundHandler(raw []byte) {
 	defer m.portsLock.RUnlock()
 	p, err := m.port(local)
 	if err != nil {
-		fmt.Println("dataChannelOutboundHandler: no valid port for candidate, dropping packet")
+		m.sctpLog.Warn("dataChannelOutboundHandler: no valid port for candidate, dropping packet")
 		return
 
 	}
@@ -321,6 +939,13 @@ func (m *Manager) port(local *stun.TransportAddr) (*port, error) {
 	return nil, errors.New("port not found")
 }
 
+// SetSCTPMaxStreams limits the streams of the SCTP association to the count
+// negotiated in the session descriptions, it has to be called before the
+// association is established
+func (m *Manager) SetSCTPMaxStreams(streams uint16) {
+	m.sctpAssociation.SetMaxStreams(streams)
+}
+
 // SendOpenChannelMessage sends the message to open a datachannel to the connected peer
 func (m *Manager) SendOpenChannelMessage(streamIdentifier uint16, label string) error {
 	msg := &datachannel.ChannelOpen{
@@ -0,0 +1,25 @@
+package network
+
+import (
+	"github.com/pions/webrtc/pkg/rtp"
+)
+
+// SetMIDExtension sets the id of the RTP header extension carrying the mid
+// of the media section a stream belongs to, as negotiated by a=extmap. The
+// mid is handed to the BufferTransportGenerator, allowing streams to be
+// bound when the remote doesn't signal its SSRCs. Zero disables it.
+func (m *Manager) SetMIDExtension(id uint8) {
+	m.srtpInboundContextLock.Lock()
+	defer m.srtpInboundContextLock.Unlock()
+
+	m.midExtensionID = id
+}
+
+// getMID returns the mid carried by the packet, or an empty string if it has none
+// Note: the caller should hold the srtpInboundContextLock.
+func (m *Manager) getMID(packet *rtp.Packet) string {
+	if m.midExtensionID == 0 {
+		return ""
+	}
+	return string(packet.GetExtension(m.midExtensionID))
+}
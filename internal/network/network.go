@@ -1,14 +1,43 @@
 package network
 
 import (
+	"net"
+
+	"github.com/pions/webrtc/internal/dtls"
 	"github.com/pions/webrtc/pkg/datachannel"
 	"github.com/pions/webrtc/pkg/ice"
 	"github.com/pions/webrtc/pkg/rtp"
 )
 
+// Net is a network the Manager opens its sockets on, the conns have to
+// exchange the packets with *net.UDPAddr addresses
+type Net interface {
+	// IPs returns the IPs of the interfaces host candidates are gathered on
+	IPs() []net.IP
+
+	// ListenPacket announces on the local address like net.ListenPacket
+	ListenPacket(network, address string) (net.PacketConn, error)
+}
+
 // BufferTransportGenerator generates a new channel for the associated SSRC
 // This channel is used to send RTP packets to users of pion-WebRTC
-type BufferTransportGenerator func(uint32, uint8) chan<- *rtp.Packet
+// If no channel is returned the packets of the SSRC are dropped without
+// calling the generator again, unless hold is set: then the packets are held
+// and the generator is called again for the next packet of the same SSRC
+// The mid is only known if the stream carries the MID header extension,
+// see Manager.SetMIDExtension
+type BufferTransportGenerator func(ssrc uint32, payloadType uint8, mid string) (bufferTransport chan<- *rtp.Packet, hold bool)
+
+// RTPObserver is notified of every inbound RTP packet after it has been
+// decrypted, before it is delivered to its buffer transport
+type RTPObserver func(*rtp.Packet)
+
+// RTCPHandler is handed every inbound RTCP compound packet after it has been decrypted
+type RTCPHandler func([]byte)
+
+// DTLSNotifier notifies the RTCPeerConnection if DTLS state has changed, the
+// reason the handshake failed for is returned by Manager.DTLSErr
+type DTLSNotifier func(dtls.ConnectionState)
 
 // ICENotifier notifies the RTCPeerConnection if ICE state has changed
 type ICENotifier func(ice.ConnectionState)
@@ -52,10 +81,23 @@ func (d *DataChannelMessage) StreamIdentifier() uint16 {
 	return d.streamIdentifier
 }
 
-// DataChannelOpen is emitted when all channels should be opened
-type DataChannelOpen struct{}
+// DataChannelOpen is emitted when all channels should be opened, once the
+// SCTP association is established
+type DataChannelOpen struct {
+	// Streams is the number of streams negotiated for the association
+	Streams uint16
+}
 
 // StreamIdentifier returns the streamIdentifier
 func (d *DataChannelOpen) StreamIdentifier() uint16 {
 	return 0
 }
+
+// DataChannelClose is emitted when the SCTP association is closed, every
+// channel is closed with it
+type DataChannelClose struct{}
+
+// StreamIdentifier returns the streamIdentifier
+func (d *DataChannelClose) StreamIdentifier() uint16 {
+	return 0
+}
@@ -0,0 +1,26 @@
+package network
+
+import (
+	"github.com/pions/webrtc/pkg/rtp"
+)
+
+// SetPayloadTypes sets the payload types outbound packets are sent with.
+// payloadTypes maps the payload type of each local codec to the one it was
+// negotiated under, as the answer takes the payload types of the offer.
+// Payload types which aren't mapped are sent unchanged.
+func (m *Manager) SetPayloadTypes(payloadTypes map[uint8]uint8) {
+	m.srtpOutboundContextLock.Lock()
+	defer m.srtpOutboundContextLock.Unlock()
+
+	m.payloadTypes = payloadTypes
+}
+
+// mapPayloadType rewrites the payload type of the packet to the negotiated one
+func (m *Manager) mapPayloadType(packet *rtp.Packet) {
+	m.srtpOutboundContextLock.RLock()
+	defer m.srtpOutboundContextLock.RUnlock()
+
+	if payloadType, ok := m.payloadTypes[packet.PayloadType]; ok {
+		packet.PayloadType = payloadType
+	}
+}
@@ -0,0 +1,28 @@
+package network
+
+import (
+	"testing"
+
+	"github.com/pions/webrtc/pkg/rtp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestManager_mapPayloadType(t *testing.T) {
+	m := &Manager{}
+	m.SetPayloadTypes(map[uint8]uint8{96: 120, 97: 121})
+
+	testCases := []struct {
+		payloadType uint8
+		mapped      uint8
+	}{
+		{96, 120},
+		{97, 121},
+		{111, 111},
+	}
+
+	for i, testCase := range testCases {
+		packet := &rtp.Packet{PayloadType: testCase.payloadType}
+		m.mapPayloadType(packet)
+		assert.Equal(t, testCase.mapped, packet.PayloadType, "testCase: %d", i)
+	}
+}
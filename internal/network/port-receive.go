@@ -1,76 +1,176 @@
 package network
 
 import (
-	"bytes"
-	"encoding/binary"
-	"fmt"
 	"net"
+	"sync"
 
 	"github.com/pions/webrtc/internal/dtls"
 	"github.com/pions/webrtc/internal/sctp"
 	"github.com/pions/webrtc/internal/srtp"
+	"github.com/pions/webrtc/pkg/ice"
+	"github.com/pions/webrtc/pkg/pcap"
 	"github.com/pions/webrtc/pkg/rtp"
 	"github.com/pkg/errors"
 )
 
+// receiveBufferPool holds the buffers packets are read into. SRTP and SRTCP
+// packets are decrypted in place and the buffer returned once they have been
+// handled, other packets are copied out as their handlers may retain them.
+var receiveBufferPool = sync.Pool{
+	New: func() interface{} {
+		buffer := make([]byte, receiveMTU)
+		return &buffer
+	},
+}
+
+// rtpPacketPool holds the packets SRTP is unmarshaled and decrypted into,
+// packets passed on to the interceptor are copied out of them
+var rtpPacketPool = sync.Pool{
+	New: func() interface{} {
+		return &rtp.Packet{}
+	},
+}
+
 type incomingPacket struct {
 	srcAddr *net.UDPAddr
 	buffer  []byte
+
+	// pooled is the receive buffer the buffer points into, if it is
+	// returned to the receiveBufferPool once the packet has been handled
+	pooled *[]byte
+}
+
+// isSRTP reports if the packet is SRTP or SRTCP
+// https://tools.ietf.org/html/rfc5764#page-14
+func isSRTP(buffer []byte) bool {
+	return len(buffer) > 0 && 127 < buffer[0] && buffer[0] < 192
 }
 
-func (p *port) handleSRTP(buffer []byte) {
+func (p *port) handleSRTP(buffer []byte, srcAddr net.Addr) {
 	p.m.srtpInboundContextLock.Lock()
 	defer p.m.srtpInboundContextLock.Unlock()
-	if p.m.srtpInboundContext == nil {
-		fmt.Printf("Got RTP packet but no SRTP Context to handle it \n")
+
+	context := p.m.srtpInboundContext
+	if p.component == ice.ComponentRTCP {
+		context = p.m.srtcpInboundContext
+	}
+	if context == nil {
+		p.m.rtpLog.Debug("Got RTP packet but no SRTP Context to handle it")
 		return
 	}
 
 	if len(buffer) > 4 {
-		var rtcpPacketType uint8
-
-		r := bytes.NewReader([]byte{buffer[1]})
-		if err := binary.Read(r, binary.BigEndian, &rtcpPacketType); err != nil {
-			fmt.Println("Failed to check packet for RTCP")
-			return
-		}
-
-		if rtcpPacketType >= 192 && rtcpPacketType <= 223 {
-			decrypted, err := p.m.srtpInboundContext.DecryptRTCP(buffer)
+		// The packet type of RTCP overlaps the marker bit and payload type
+		// of RTP, RFC 5761 Section 4
+		if rtcpPacketType := buffer[1]; rtcpPacketType >= 192 && rtcpPacketType <= 223 {
+			decrypted, err := context.DecryptRTCP(buffer)
 			if err != nil {
-				fmt.Println(err)
-				fmt.Println(decrypted)
+				p.m.srtpAuthFailures++
+				p.m.rtpLog.Warnf("Failed to decrypt RTCP packet: %v", err)
 				return
 			}
+			p.capture(pcap.ProtocolRTCP, true, srcAddr, decrypted)
+			if err := p.m.inboundRTCP.WriteRTCP(decrypted); err != nil {
+				p.m.rtpLog.Warnf("Failed to handle RTCP packet: %v", err)
+			}
 			return
 		}
 	}
 
-	packet := &rtp.Packet{}
+	packet := rtpPacketPool.Get().(*rtp.Packet)
+	defer rtpPacketPool.Put(packet)
 	if err := packet.Unmarshal(buffer); err != nil {
-		fmt.Println("Failed to unmarshal RTP packet")
+		p.m.rtpLog.Warn("Failed to unmarshal RTP packet")
+		return
+	}
+
+	if ok := context.DecryptRTP(packet); !ok {
+		p.m.srtpAuthFailures++
+		p.m.rtpLog.Warn("Failed to decrypt packet")
 		return
 	}
+	p.captureRTP(true, srcAddr, packet)
 
-	if ok := p.m.srtpInboundContext.DecryptRTP(packet); !ok {
-		fmt.Println("Failed to decrypt packet")
+	if ok := p.m.unwrapRTX(packet); !ok {
+		return
+	}
+
+	if recovered, isFEC := p.m.handleFEC(packet); isFEC {
+		if recovered != nil {
+			p.receiveRTP(recovered)
+		}
 		return
 	}
 
-	bufferTransport := p.m.bufferTransports[packet.SSRC]
+	// The packet is passed on and may be retained, it can't keep pointing
+	// into the pooled packet and buffer
+	owned := &rtp.Packet{}
+	if err := owned.Unmarshal(append([]byte{}, packet.Raw...)); err != nil {
+		p.m.rtpLog.Warn("Failed to unmarshal RTP packet")
+		return
+	}
+	p.receiveRTP(owned)
+}
+
+// receiveRTP passes a decrypted packet through the interceptor, which
+// delivers it
+// Note: the caller should hold the srtpInboundContextLock.
+func (p *port) receiveRTP(packet *rtp.Packet) {
+	if err := p.m.inboundRTP.WriteRTP(packet); err != nil {
+		p.m.rtpLog.Warnf("Failed to handle RTP packet: %v", err)
+	}
+}
+
+// handleRTCP hands a decrypted RTCP packet to the RTCPHandler
+func (m *Manager) handleRTCP(pkt []byte) error {
+	if m.rtcpHandler != nil {
+		m.rtcpHandler(pkt)
+	}
+	return nil
+}
+
+// deliverRTP hands a decrypted packet to the buffer transport of its SSRC
+// Note: the caller should hold the srtpInboundContextLock.
+func (m *Manager) deliverRTP(packet *rtp.Packet) error {
+	if m.bufferTransportsClosed {
+		return nil
+	}
+
+	if m.rtpObserver != nil {
+		m.rtpObserver(packet)
+	}
+
+	// Keepalives of silent streams carry nothing but padding
+	if packet.Padding && len(packet.Payload) > 0 && int(packet.Payload[len(packet.Payload)-1]) == len(packet.Payload) {
+		return nil
+	}
+
+	bufferTransport := m.bufferTransports[packet.SSRC]
 	if bufferTransport == nil {
-		bufferTransport = p.m.bufferTransportGenerator(packet.SSRC, packet.PayloadType)
+		if _, ok := m.droppedStreams[packet.SSRC]; ok {
+			return nil
+		}
+
+		var hold bool
+		bufferTransport, hold = m.bufferTransportGenerator(packet.SSRC, packet.PayloadType, m.getMID(packet))
 		if bufferTransport == nil {
-			return
+			if hold {
+				// The track can't be bound yet, hold on to the packet until it is
+				m.holdEarlyMedia(packet)
+			} else {
+				m.dropEarlyMedia(packet.SSRC)
+			}
+			return nil
 		}
-		p.m.bufferTransports[packet.SSRC] = bufferTransport
+		m.bufferTransports[packet.SSRC] = bufferTransport
+		m.flushEarlyMedia(packet.SSRC, bufferTransport)
 	}
 
 	select {
 	case bufferTransport <- packet:
 	default:
 	}
-
+	return nil
 }
 
 func (p *port) handleSCTP(raw []byte, a *sctp.Association) {
@@ -78,66 +178,96 @@ func (p *port) handleSCTP(raw []byte, a *sctp.Association) {
 	defer p.m.sctpAssociation.Unlock()
 
 	if err := a.HandleInbound(raw); err != nil {
-		fmt.Println(errors.Wrap(err, "Failed to push SCTP packet"))
+		p.m.sctpLog.Warn(errors.Wrap(err, "Failed to push SCTP packet").Error())
 	}
 }
 
-func (p *port) handleDTLS(raw []byte, srcAddr string) {
-	decrypted, err := p.m.dtlsState.HandleDTLSPacket(raw, p.listeningAddr.String(), srcAddr)
+func (p *port) handleDTLS(raw []byte, srcAddr net.Addr) {
+	if p.component == ice.ComponentRTCP {
+		p.handleRTCPDTLS(raw, srcAddr.String())
+		return
+	}
+
+	decrypted, err := p.m.dtlsState.HandleDTLSPacket(raw, p.listeningAddr.String(), srcAddr.String())
 	if err != nil {
-		fmt.Println(err)
+		p.m.dtlsLog.Warnf("Failed to handle DTLS packet: %v", err)
 		return
 	}
 
 	if len(decrypted) > 0 {
+		p.capture(pcap.ProtocolSCTP, true, srcAddr, decrypted)
 		p.handleSCTP(decrypted, p.m.sctpAssociation)
 	}
 
 	p.m.certPairLock.Lock()
+	defer p.m.certPairLock.Unlock()
 	if certPair := p.m.dtlsState.GetCertPair(); certPair != nil && p.m.certPair == nil {
-		var err error
 		p.m.certPair = certPair
 
-		p.m.srtpInboundContextLock.Lock()
-		p.m.srtpInboundContext, err = srtp.CreateContext(p.m.certPair.ServerWriteKey[0:16], p.m.certPair.ServerWriteKey[16:], p.m.certPair.Profile)
-		p.m.srtpInboundContextLock.Unlock()
+		inbound, outbound, err := p.m.srtpContexts(certPair)
 		if err != nil {
-			fmt.Println("Failed to build SRTP context, this is fatal")
+			p.m.rtpLog.Error("Failed to build SRTP context, this is fatal")
 			return
 		}
 
+		p.m.srtpInboundContextLock.Lock()
+		p.m.srtpInboundContext = inbound
+		p.m.srtpInboundContextLock.Unlock()
+
 		p.m.srtpOutboundContextLock.Lock()
-		p.m.srtpOutboundContext, err = srtp.CreateContext(p.m.certPair.ClientWriteKey[0:16], p.m.certPair.ClientWriteKey[16:], p.m.certPair.Profile)
+		p.m.srtpOutboundContext = outbound
 		p.m.srtpOutboundContextLock.Unlock()
-		if err != nil {
-			fmt.Println("Failed to build SRTP context, this is fatal")
-			return
-		}
+	}
+}
 
+// srtpContexts builds the contexts SRTP is decrypted and encrypted with
+// from the keys of a DTLS handshake
+// Note: the caller should hold the certPairLock.
+func (m *Manager) srtpContexts(certPair *dtls.CertPair) (inbound, outbound *srtp.Context, err error) {
+	// Each side encrypts with its own write key
+	inboundKey, outboundKey := certPair.ClientWriteKey, certPair.ServerWriteKey
+	if m.isDTLSClient {
+		inboundKey, outboundKey = certPair.ServerWriteKey, certPair.ClientWriteKey
 	}
-	p.m.certPairLock.Unlock()
 
+	if inbound, err = srtp.CreateContext(inboundKey[:certPair.MasterKeyLength], inboundKey[certPair.MasterKeyLength:], certPair.Profile); err != nil {
+		return nil, nil, err
+	}
+	if outbound, err = srtp.CreateContext(outboundKey[:certPair.MasterKeyLength], outboundKey[certPair.MasterKeyLength:], certPair.Profile); err != nil {
+		return nil, nil, err
+	}
+	return inbound, outbound, nil
 }
 
 const receiveMTU = 8192
 
 func (p *port) networkLoop() {
-	incomingPackets := make(chan *incomingPacket, 15)
+	p.m.profileGoroutine(profileSectionRead)
+	incomingPackets := make(chan incomingPacket, 15)
 	go func() {
-		buffer := make([]byte, receiveMTU)
+		p.m.profileGoroutine(profileSectionRead)
 		for {
-			n, _, srcAddr, err := p.conn.ReadFrom(buffer)
+			pooled := receiveBufferPool.Get().(*[]byte)
+			n, srcAddr, err := p.conn.ReadFrom(*pooled)
 			if err != nil {
+				receiveBufferPool.Put(pooled)
 				close(incomingPackets)
 				break
 			}
 
-			bufferCopy := make([]byte, n)
-			copy(bufferCopy, buffer[:n])
+			in := incomingPacket{buffer: (*pooled)[:n], srcAddr: srcAddr.(*net.UDPAddr), pooled: pooled}
+			if !isSRTP(in.buffer) {
+				in.buffer = append([]byte{}, in.buffer...)
+				in.pooled = nil
+				receiveBufferPool.Put(pooled)
+			}
 
 			select {
-			case incomingPackets <- &incomingPacket{buffer: bufferCopy, srcAddr: srcAddr.(*net.UDPAddr)}:
+			case incomingPackets <- in:
 			default:
+				if in.pooled != nil {
+					receiveBufferPool.Put(in.pooled)
+				}
 			}
 		}
 	}()
@@ -146,28 +276,48 @@ func (p *port) networkLoop() {
 		in, socketOpen := <-incomingPackets
 		if !socketOpen {
 			// incomingPackets channel has closed, this port is finished processing
-			dtls.RemoveListener(p.listeningAddr.String())
+			if !p.muxed {
+				dtls.RemoveListener(p.listeningAddr.String())
+			}
 			return
 		}
 
-		if len(in.buffer) == 0 {
-			fmt.Println("Inbound buffer is not long enough to demux")
-			continue
+		p.handleIncoming(in)
+		if in.pooled != nil {
+			receiveBufferPool.Put(in.pooled)
 		}
+	}
+}
+
+// handleIncoming demuxes a packet read from the conn to its protocol
+func (p *port) handleIncoming(in incomingPacket) {
+	if len(in.buffer) == 0 {
+		p.m.rtpLog.Warn("Inbound buffer is not long enough to demux")
+		return
+	}
 
-		// https://tools.ietf.org/html/rfc5764#page-14
-		if 127 < in.buffer[0] && in.buffer[0] < 192 {
-			p.handleSRTP(in.buffer)
-		} else if 19 < in.buffer[0] && in.buffer[0] < 64 {
-			p.handleDTLS(in.buffer, in.srcAddr.String())
-		} else if in.buffer[0] < 2 {
+	// https://tools.ietf.org/html/rfc5764#page-14
+	if isSRTP(in.buffer) {
+		p.m.profile(profileSectionSRTP, in.buffer, func() {
+			p.handleSRTP(in.buffer, in.srcAddr)
+		})
+	} else if 19 < in.buffer[0] && in.buffer[0] < 64 {
+		p.m.profile(profileSectionSCTP, in.buffer, func() {
+			p.handleDTLS(in.buffer, in.srcAddr)
+		})
+	} else if in.buffer[0] < 2 {
+		p.m.profile(profileSectionICE, in.buffer, func() {
 			p.m.IceAgent.HandleInbound(in.buffer, p.listeningAddr, in.srcAddr)
-		}
+		})
+	}
 
-		p.m.certPairLock.RLock()
-		if !p.m.isOffer && p.m.certPair == nil {
-			p.m.dtlsState.DoHandshake(p.listeningAddr.String(), in.srcAddr.String())
+	p.m.certPairLock.RLock()
+	if p.m.isDTLSClient && p.component == ice.ComponentRTCP {
+		if p.m.rtcpCertPair == nil {
+			p.m.rtcpDTLSState.DoHandshake(p.listeningAddr.String(), in.srcAddr.String())
 		}
-		p.m.certPairLock.RUnlock()
+	} else if p.m.isDTLSClient && p.m.certPair == nil {
+		p.m.dtlsState.DoHandshake(p.listeningAddr.String(), in.srcAddr.String())
 	}
+	p.m.certPairLock.RUnlock()
 }
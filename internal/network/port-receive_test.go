@@ -0,0 +1,73 @@
+package network
+
+import (
+	"net"
+	"testing"
+
+	"github.com/pions/webrtc/internal/srtp"
+	"github.com/pions/webrtc/pkg/ice"
+	"github.com/pions/webrtc/pkg/interceptor"
+	"github.com/pions/webrtc/pkg/logging"
+	"github.com/pions/webrtc/pkg/rtp"
+)
+
+// BenchmarkPort_ReceiveRTP encrypts a packet like the remote peer does and
+// passes it through the receive path, from the buffer it is read into to
+// the buffer transport of its track
+func BenchmarkPort_ReceiveRTP(b *testing.B) {
+	for _, profile := range []string{"SRTP_AES128_CM_SHA1_80", "SRTP_AEAD_AES_128_GCM"} {
+		b.Run(profile, func(b *testing.B) {
+			key, salt := make([]byte, 16), make([]byte, 14)
+			if profile == "SRTP_AEAD_AES_128_GCM" {
+				salt = make([]byte, 12)
+			}
+			outbound, err := srtp.CreateContext(key, salt, profile)
+			if err != nil {
+				b.Fatal(err)
+			}
+			inbound, err := srtp.CreateContext(key, salt, profile)
+			if err != nil {
+				b.Fatal(err)
+			}
+
+			bufferTransport := make(chan *rtp.Packet, 1)
+			m := &Manager{
+				rtpLog:             logging.NewDefaultLoggerFactory().NewLogger(logging.ScopeRTP),
+				srtpInboundContext: inbound,
+				bufferTransports:   map[uint32]chan<- *rtp.Packet{1: bufferTransport},
+				earlyMedia:         make(map[uint32][]*rtp.Packet),
+				droppedStreams:     make(map[uint32]struct{}),
+			}
+			m.inboundRTP = interceptor.RTPWriterFunc(m.deliverRTP)
+			p := &port{m: m, component: ice.ComponentRTP}
+			srcAddr := &net.UDPAddr{IP: net.IPv4(192, 0, 2, 1), Port: 5000}
+
+			payload := make([]byte, 1200, 1300)
+			sent := &rtp.Packet{Version: 2, SSRC: 1}
+			encrypted := make([]byte, receiveMTU)
+
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				sent.SequenceNumber = uint16(i)
+				sent.Payload = payload
+				if !outbound.EncryptRTP(sent) {
+					b.Fatal("failed to encrypt")
+				}
+				n, err := sent.MarshalTo(encrypted)
+				if err != nil {
+					b.Fatal(err)
+				}
+
+				// Like the networkLoop reads the packet
+				pooled := receiveBufferPool.Get().(*[]byte)
+				in := incomingPacket{buffer: (*pooled)[:copy(*pooled, encrypted[:n])], srcAddr: srcAddr, pooled: pooled}
+				p.handleIncoming(in)
+				receiveBufferPool.Put(in.pooled)
+
+				if received := <-bufferTransport; len(received.Payload) != len(payload) {
+					b.Fatalf("received %d bytes of payload, expected %d", len(received.Payload), len(payload))
+				}
+			}
+		})
+	}
+}
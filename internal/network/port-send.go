@@ -3,54 +3,94 @@ package network
 import (
 	"fmt"
 	"net"
+	"sync"
 
+	"github.com/pions/webrtc/pkg/ice"
+	"github.com/pions/webrtc/pkg/pcap"
 	"github.com/pions/webrtc/pkg/rtp"
 )
 
+// sendBufferPool holds the buffers encrypted RTP packets are marshaled to
+// before they are written, the conns don't retain what is written
+var sendBufferPool = sync.Pool{
+	New: func() interface{} {
+		buffer := make([]byte, receiveMTU)
+		return &buffer
+	},
+}
+
 func (p *port) sendRTP(packet *rtp.Packet, dst net.Addr) {
+	p.m.profile(profileSectionSRTP, nil, func() {
+		p.encryptRTP(packet, dst)
+	})
+}
+
+func (p *port) encryptRTP(packet *rtp.Packet, dst net.Addr) {
 	p.m.srtpOutboundContextLock.Lock()
 	defer p.m.srtpOutboundContextLock.Unlock()
 	if p.m.srtpOutboundContext == nil {
-		// TODO log-level
-		// fmt.Printf("Tried to send RTP packet but no SRTP Context to handle it \n")
+		p.m.rtpLog.Trace("Tried to send RTP packet but no SRTP Context to handle it")
 		return
 	}
+	p.m.stampAbsSendTime(packet)
+	p.captureRTP(false, dst, packet)
 
-	if ok := p.m.srtpOutboundContext.EncryptRTP(packet); ok {
-		raw, err := packet.Marshal()
-		if err != nil {
-			fmt.Printf("Failed to marshal packet: %s \n", err.Error())
-		}
-		if _, err := p.conn.WriteTo(raw, nil, dst); err != nil {
-			fmt.Printf("Failed to send packet: %s \n", err.Error())
-		}
-	} else {
-		fmt.Println("Failed to encrypt packet")
+	if ok := p.m.srtpOutboundContext.EncryptRTP(packet); !ok {
+		p.m.rtpLog.Warn("Failed to encrypt packet")
+		return
 	}
-}
 
-func (p *port) sendSCTP(buf []byte, dst fmt.Stringer) {
-	_, err := p.m.dtlsState.Send(buf, p.listeningAddr.String(), dst.String())
+	buffer := sendBufferPool.Get().(*[]byte)
+	defer sendBufferPool.Put(buffer)
+
+	n, err := packet.MarshalTo(*buffer)
 	if err != nil {
-		fmt.Println(err)
+		p.m.rtpLog.Warnf("Failed to marshal packet: %s", err.Error())
+		return
 	}
+	if _, err := p.conn.WriteTo((*buffer)[:n], dst); err != nil {
+		p.m.rtpLog.Warnf("Failed to send packet: %s", err.Error())
+	}
+}
+
+func (p *port) sendSCTP(buf []byte, dst fmt.Stringer) {
+	p.m.profile(profileSectionSCTP, nil, func() {
+		_, err := p.m.dtlsState.Send(buf, p.listeningAddr.String(), dst.String())
+		if err != nil {
+			p.m.sctpLog.Warnf("Failed to send SCTP packet: %v", err)
+			return
+		}
+		p.capture(pcap.ProtocolSCTP, false, dst, buf)
+	})
 }
 
 func (p *port) sendRTCP(buf []byte, dst net.Addr) {
+	p.m.profile(profileSectionSRTP, nil, func() {
+		p.encryptRTCP(buf, dst)
+	})
+}
+
+func (p *port) encryptRTCP(buf []byte, dst net.Addr) {
 	p.m.srtpOutboundContextLock.Lock()
 	defer p.m.srtpOutboundContextLock.Unlock()
-	if p.m.srtpOutboundContext == nil {
-		fmt.Printf("Tried to send RTCP packet but no SRTP Context to handle it \n")
+
+	context := p.m.srtpOutboundContext
+	if p.component == ice.ComponentRTCP {
+		context = p.m.srtcpOutboundContext
+	}
+	if context == nil {
+		p.m.rtpLog.Debug("Tried to send RTCP packet but no SRTP Context to handle it")
 		return
 	}
+	p.capture(pcap.ProtocolRTCP, false, dst, buf)
 
-	encrypted, err := p.m.srtpOutboundContext.EncryptRTCP(buf)
+	encrypted, err := context.EncryptRTCP(buf)
 	if err != nil {
-		fmt.Println(err)
+		p.m.rtpLog.Warnf("Failed to encrypt RTCP packet: %v", err)
 		return
 	}
 
-	if _, err := p.conn.WriteTo(encrypted, nil, dst); err != nil {
-		fmt.Printf("Failed to send packet: %s \n", err.Error())
+	if _, err := p.conn.WriteTo(encrypted, dst); err != nil {
+		p.m.rtpLog.Warnf("Failed to send packet: %s", err.Error())
 	}
 }
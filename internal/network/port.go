@@ -5,38 +5,67 @@ import (
 
 	"github.com/pions/pkg/stun"
 	"github.com/pions/webrtc/internal/dtls"
-	"golang.org/x/net/ipv4"
+	"github.com/pions/webrtc/pkg/ice"
 )
 
 type port struct {
-	conn          *ipv4.PacketConn
+	conn          net.PacketConn
 	listeningAddr *stun.TransportAddr
 
+	// component is the ICE component the port carries, ice.ComponentRTCP
+	// ports carry nothing but SRTCP and the DTLS handshake keying it
+	component uint16
+
+	// muxed is set if the conn is one of a UDPMux
+	muxed bool
+
 	m *Manager
 }
 
-func newPort(address string, m *Manager) (*port, error) {
-	listener, err := net.ListenPacket("udp4", address)
+func listenUDP(address string) (net.PacketConn, error) {
+	return net.ListenPacket("udp", address)
+}
+
+// listenTCP listens for ICE-TCP connections, RFC 6544, the conn carries the
+// packets of both the passive and active TCP candidates of the address
+func listenTCP(address string) (net.PacketConn, error) {
+	conn, err := newTCPPacketConn(address)
 	if err != nil {
 		return nil, err
 	}
+	return conn, nil
+}
 
-	addr, err := stun.NewTransportAddr(listener.LocalAddr())
+func newPort(address string, m *Manager) (*port, error) {
+	conn, err := listenUDP(address)
 	if err != nil {
 		return nil, err
 	}
 
-	conn := ipv4.NewPacketConn(listener)
-	dtls.AddListener(addr.String(), conn)
+	addr, err := stun.NewTransportAddr(conn.LocalAddr())
+	if err != nil {
+		return nil, err
+	}
+	return startPort(conn, addr, m, ice.ComponentRTP), nil
+}
 
+func startPort(conn net.PacketConn, addr *stun.TransportAddr, m *Manager, component uint16) *port {
+	_, muxed := conn.(*udpMuxedConn)
 	p := &port{
 		listeningAddr: addr,
-		conn:          conn,
+		conn:          newCountingConn(conn),
+		component:     component,
+		muxed:         muxed,
 		m:             m,
 	}
 
+	// The socket of a UDPMux is registered once for all of its conns
+	if !muxed {
+		dtls.AddListener(addr.String(), p.conn, m.dtlsLog)
+	}
+
 	go p.networkLoop()
-	return p, nil
+	return p
 }
 
 func (p *port) close() error {
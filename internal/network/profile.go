@@ -0,0 +1,12 @@
+package network
+
+// The sections of the hot paths profiled by builds with the pionprofile
+// tag, which label CPU profile samples with the connection and the section
+// they belong to, and publish the packets handled and the time spent per
+// section with expvar
+const (
+	profileSectionRead = "read"
+	profileSectionICE  = "ice"
+	profileSectionSRTP = "srtp"
+	profileSectionSCTP = "sctp"
+)
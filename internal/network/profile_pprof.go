@@ -0,0 +1,56 @@
+//go:build pionprofile
+
+package network
+
+import (
+	"context"
+	"encoding/binary"
+	"expvar"
+	"runtime/pprof"
+	"strconv"
+	"time"
+)
+
+// profileVars counts the packets handled and the nanoseconds spent in every
+// section, published by expvar as pion_webrtc_profile
+var profileVars = expvar.NewMap("pion_webrtc_profile")
+
+// profileLabels identify the connection by the local ICE ufrag, which
+// appears in its descriptions
+func (m *Manager) profileLabels(section string) []string {
+	return []string{"pion_connection", m.IceAgent.LocalUfrag, "pion_section", section}
+}
+
+// profileGoroutine labels the CPU profile samples of the calling goroutine
+// with the connection and the section
+func (m *Manager) profileGoroutine(section string) {
+	pprof.SetGoroutineLabels(pprof.WithLabels(context.Background(), pprof.Labels(m.profileLabels(section)...)))
+}
+
+// profile handles a packet of the section with f, its CPU profile samples
+// are labeled with the connection, the section and the SSRC of SRTP packets
+func (m *Manager) profile(section string, packet []byte, f func()) {
+	labels := m.profileLabels(section)
+	if ssrc, ok := profileSSRC(section, packet); ok {
+		labels = append(labels, "pion_ssrc", strconv.FormatUint(uint64(ssrc), 10))
+	}
+
+	start := time.Now()
+	pprof.Do(context.Background(), pprof.Labels(labels...), func(context.Context) {
+		f()
+	})
+	profileVars.Add(section+"_packets", 1)
+	profileVars.Add(section+"_ns", int64(time.Since(start)))
+}
+
+// profileSSRC returns the SSRC of the sender of an SRTP or SRTCP packet,
+// which is not encrypted
+func profileSSRC(section string, packet []byte) (uint32, bool) {
+	if section != profileSectionSRTP || len(packet) < 12 {
+		return 0, false
+	}
+	if packet[1] >= 192 && packet[1] <= 223 {
+		return binary.BigEndian.Uint32(packet[4:]), true
+	}
+	return binary.BigEndian.Uint32(packet[8:]), true
+}
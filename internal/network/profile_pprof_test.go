@@ -0,0 +1,47 @@
+//go:build pionprofile
+
+package network
+
+import (
+	"expvar"
+	"testing"
+
+	"github.com/pions/webrtc/pkg/ice"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProfileSSRC(t *testing.T) {
+	rtp := []byte{0x80, 0x60, 0x00, 0x01, 0x00, 0x00, 0x00, 0x01, 0x00, 0x00, 0x03, 0xe8}
+	rtcp := []byte{0x80, 0xc8, 0x00, 0x06, 0x00, 0x00, 0x07, 0xd0, 0x00, 0x00, 0x00, 0x00}
+
+	testCases := []struct {
+		section string
+		packet  []byte
+		ssrc    uint32
+		ok      bool
+	}{
+		{profileSectionSRTP, rtp, 1000, true},
+		{profileSectionSRTP, rtcp, 2000, true},
+		{profileSectionSRTP, rtp[:8], 0, false},
+		{profileSectionSCTP, rtp, 0, false},
+	}
+
+	for i, testCase := range testCases {
+		ssrc, ok := profileSSRC(testCase.section, testCase.packet)
+		assert.Equal(t, testCase.ssrc, ssrc, "testCase: %d", i)
+		assert.Equal(t, testCase.ok, ok, "testCase: %d", i)
+	}
+}
+
+func TestManager_Profile(t *testing.T) {
+	m := &Manager{IceAgent: ice.NewAgent(nil)}
+
+	called := false
+	m.profile(profileSectionICE, nil, func() { called = true })
+	assert.True(t, called)
+
+	packets, ok := profileVars.Get(profileSectionICE + "_packets").(*expvar.Int)
+	if assert.True(t, ok) {
+		assert.Equal(t, int64(1), packets.Value())
+	}
+}
@@ -0,0 +1,148 @@
+package network
+
+import (
+	"bufio"
+	"crypto/tls"
+	"encoding/base64"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+
+	"github.com/pions/webrtc/pkg/ice"
+	"github.com/pkg/errors"
+	"golang.org/x/net/proxy"
+)
+
+// proxyFromEnvironment returns the dialer of the proxy configured by the
+// HTTPS_PROXY or ALL_PROXY variables of the environment, bypassing it for
+// the hosts of NO_PROXY. http:// proxies are tunneled through with CONNECT,
+// socks5:// ones with x/net/proxy. Without a proxy it dials directly.
+func proxyFromEnvironment(getenv func(string) string) (proxy.Dialer, error) {
+	lookup := func(names ...string) string {
+		for _, name := range names {
+			if value := getenv(name); value != "" {
+				return value
+			}
+		}
+		return ""
+	}
+
+	raw := lookup("HTTPS_PROXY", "https_proxy", "ALL_PROXY", "all_proxy")
+	if raw == "" {
+		return proxy.Direct, nil
+	}
+	proxyURL, err := url.Parse(raw)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid proxy URL")
+	}
+
+	var dialer proxy.Dialer
+	switch proxyURL.Scheme {
+	case "http":
+		dialer = &httpProxyDialer{proxyURL: proxyURL, forward: proxy.Direct}
+	case "socks5":
+		if dialer, err = proxy.FromURL(proxyURL, proxy.Direct); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, errors.Errorf("unsupported proxy scheme %s", proxyURL.Scheme)
+	}
+
+	if noProxy := lookup("NO_PROXY", "no_proxy"); noProxy != "" {
+		perHost := proxy.NewPerHost(dialer, proxy.Direct)
+		perHost.AddFromString(noProxy)
+		dialer = perHost
+	}
+	return dialer, nil
+}
+
+// httpProxyDialer opens TCP connections through an HTTP proxy with CONNECT
+// https://tools.ietf.org/html/rfc7231#section-4.3.6
+type httpProxyDialer struct {
+	proxyURL *url.URL
+	forward  proxy.Dialer
+}
+
+// Dial connects to the proxy and asks it to tunnel to the address. The
+// client speaks first on the protocols tunneled, nothing is read past the
+// response of the proxy.
+func (d *httpProxyDialer) Dial(network, addr string) (net.Conn, error) {
+	proxyAddr := d.proxyURL.Host
+	if d.proxyURL.Port() == "" {
+		proxyAddr = net.JoinHostPort(proxyAddr, "80")
+	}
+	conn, err := d.forward.Dial(network, proxyAddr)
+	if err != nil {
+		return nil, err
+	}
+
+	req := &http.Request{
+		Method: http.MethodConnect,
+		URL:    &url.URL{Opaque: addr},
+		Host:   addr,
+		Header: make(http.Header),
+	}
+	if user := d.proxyURL.User; user != nil {
+		password, _ := user.Password()
+		credentials := base64.StdEncoding.EncodeToString([]byte(user.Username() + ":" + password))
+		req.Header.Set("Proxy-Authorization", "Basic "+credentials)
+	}
+	if err = req.Write(conn); err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+
+	resp, err := http.ReadResponse(bufio.NewReader(conn), req)
+	if err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+	if err = resp.Body.Close(); err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		_ = conn.Close()
+		return nil, errors.Errorf("proxy refused to connect to %s: %s", addr, resp.Status)
+	}
+	return conn, nil
+}
+
+// dialTURN opens the connection to the TURN server of a turn: or turns: URL.
+// Connections over TCP are opened through the proxy dialer of the settings
+// or the one of the environment, turns: connections are wrapped in TLS.
+// Proxies don't carry UDP, turn: URLs over UDP are dialed directly.
+func (m *Manager) dialTURN(url *ice.URL) (net.Conn, error) {
+	address := net.JoinHostPort(url.Host, strconv.Itoa(url.Port))
+	switch {
+	case url.Scheme == ice.SchemeTypeTURN && url.Proto == ice.ProtoTypeUDP:
+		return net.DialTimeout("udp", address, tcpDialTimeout)
+	case url.Proto != ice.ProtoTypeTCP || (url.Scheme != ice.SchemeTypeTURN && url.Scheme != ice.SchemeTypeTURNS):
+		return nil, errors.Errorf("%s is not supported", url.String())
+	}
+
+	dialer := m.settings.ProxyDialer
+	if dialer == nil {
+		var err error
+		if dialer, err = proxyFromEnvironment(os.Getenv); err != nil {
+			return nil, err
+		}
+	}
+
+	conn, err := dialer.Dial("tcp", address)
+	if err != nil {
+		return nil, err
+	}
+	if !url.IsSecure() {
+		return conn, nil
+	}
+
+	tlsConn := tls.Client(conn, &tls.Config{ServerName: url.Host})
+	if err := tlsConn.Handshake(); err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+	return tlsConn, nil
+}
@@ -0,0 +1,98 @@
+package network
+
+import (
+	"bufio"
+	"net"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/pions/webrtc/pkg/ice"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/proxy"
+)
+
+func TestProxyFromEnvironment(t *testing.T) {
+	testCases := []struct {
+		env     map[string]string
+		dialer  interface{}
+		wantErr bool
+	}{
+		{map[string]string{}, proxy.Direct, false},
+		{map[string]string{"HTTPS_PROXY": "http://proxy:3128"}, &httpProxyDialer{}, false},
+		{map[string]string{"all_proxy": "http://proxy:3128"}, &httpProxyDialer{}, false},
+		{map[string]string{"ALL_PROXY": "socks5://proxy:1080", "NO_PROXY": "localhost"}, &proxy.PerHost{}, false},
+		{map[string]string{"HTTPS_PROXY": "ftp://proxy"}, nil, true},
+		{map[string]string{"HTTPS_PROXY": "http://%zz"}, nil, true},
+	}
+
+	for i, testCase := range testCases {
+		dialer, err := proxyFromEnvironment(func(name string) string { return testCase.env[name] })
+		if testCase.wantErr {
+			assert.Error(t, err, "testCase: %d", i)
+			continue
+		}
+		assert.Nil(t, err, "testCase: %d", i)
+		assert.IsType(t, testCase.dialer, dialer, "testCase: %d", i)
+	}
+}
+
+func TestManager_dialTURNThroughProxy(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	defer func() { assert.Nil(t, listener.Close()) }()
+
+	// The proxy accepts one CONNECT and echoes what is tunneled
+	requests := make(chan *http.Request, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer func() { _ = conn.Close() }()
+
+		reader := bufio.NewReader(conn)
+		req, err := http.ReadRequest(reader)
+		if err != nil {
+			return
+		}
+		requests <- req
+		if _, err = conn.Write([]byte("HTTP/1.1 200 Connection established\r\n\r\n")); err != nil {
+			return
+		}
+
+		buffer := make([]byte, 16)
+		n, err := reader.Read(buffer)
+		if err != nil {
+			return
+		}
+		_, _ = conn.Write(buffer[:n])
+	}()
+
+	proxyURL := &url.URL{Scheme: "http", User: url.UserPassword("user", "pass"), Host: listener.Addr().String()}
+	m := &Manager{settings: Settings{ProxyDialer: &httpProxyDialer{proxyURL: proxyURL, forward: proxy.Direct}}}
+
+	turnURL, err := ice.ParseURL("turn:turn.example.org:3478?transport=tcp")
+	assert.Nil(t, err)
+	conn, err := m.dialTURN(turnURL)
+	assert.Nil(t, err)
+	defer func() { assert.Nil(t, conn.Close()) }()
+
+	req := <-requests
+	assert.Equal(t, http.MethodConnect, req.Method)
+	assert.Equal(t, "turn.example.org:3478", req.Host)
+	assert.Equal(t, "Basic dXNlcjpwYXNz", req.Header.Get("Proxy-Authorization"))
+
+	_, err = conn.Write([]byte{0x00, 0x01})
+	assert.Nil(t, err)
+	buffer := make([]byte, 16)
+	n, err := conn.Read(buffer)
+	assert.Nil(t, err)
+	assert.Equal(t, []byte{0x00, 0x01}, buffer[:n])
+
+	// TURN over DTLS isn't supported
+	dtlsURL, err := ice.ParseURL("turns:turn.example.org:5349?transport=udp")
+	assert.Nil(t, err)
+	_, err = m.dialTURN(dtlsURL)
+	assert.Error(t, err)
+}
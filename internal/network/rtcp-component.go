@@ -0,0 +1,96 @@
+package network
+
+import (
+	"github.com/pions/webrtc/internal/dtls"
+	"github.com/pions/webrtc/pkg/ice"
+)
+
+// addRTCPCandidate listens for the RTCP component on the IP and adds a host
+// candidate for it, with the local preference of the RTP candidate
+func (m *Manager) addRTCPCandidate(ip string, n int) error {
+	p, err := m.listen(m.udpListenFunc(), ip, ice.ComponentRTCP)
+	if err != nil {
+		return err
+	}
+	m.ports = append(m.ports, p)
+
+	m.IceAgent.AddLocalCandidate(&ice.CandidateHost{
+		CandidateBase: ice.CandidateBase{
+			Protocol:        ice.ProtoTypeUDP,
+			Address:         p.listeningAddr.IP.String(),
+			Port:            p.listeningAddr.Port,
+			LocalPreference: localPreference(n),
+			Component:       ice.ComponentRTCP,
+			Conn:            p.conn,
+		},
+	})
+	return nil
+}
+
+// CloseRTCPComponent releases the candidates and ports of the RTCP component
+// once the remote peer multiplexes RTCP with RTP, RFC 5761 Section 5.1.3
+func (m *Manager) CloseRTCPComponent() {
+	if m.rtcpDTLSState == nil {
+		return
+	}
+
+	m.portsLock.Lock()
+	defer m.portsLock.Unlock()
+	if m.rtcpComponentClosed {
+		return
+	}
+	m.rtcpComponentClosed = true
+
+	m.IceAgent.RemoveLocalComponent(ice.ComponentRTCP)
+	m.rtcpDTLSState.Close()
+
+	ports := m.ports[:0]
+	for _, p := range m.ports {
+		if p.component != ice.ComponentRTCP {
+			ports = append(ports, p)
+		} else if err := p.close(); err != nil {
+			m.rtpLog.Warnf("Failed to close RTCP port: %v", err)
+		}
+	}
+	m.ports = ports
+}
+
+// handleRTCPDTLS passes the packet to the DTLS handshake of the RTCP
+// component, SRTCP on its ports is keyed once the handshake completed
+func (p *port) handleRTCPDTLS(raw []byte, srcAddr string) {
+	// Application data isn't sent on the RTCP component
+	if _, err := p.m.rtcpDTLSState.HandleDTLSPacket(raw, p.listeningAddr.String(), srcAddr); err != nil {
+		p.m.dtlsLog.Warnf("Failed to handle DTLS packet of the RTCP component: %v", err)
+		return
+	}
+
+	p.m.certPairLock.Lock()
+	defer p.m.certPairLock.Unlock()
+	certPair := p.m.rtcpDTLSState.GetCertPair()
+	if certPair == nil || p.m.rtcpCertPair != nil {
+		return
+	}
+	p.m.rtcpCertPair = certPair
+
+	inbound, outbound, err := p.m.srtpContexts(certPair)
+	if err != nil {
+		p.m.rtpLog.Errorf("Failed to build SRTCP context of the RTCP component: %v", err)
+		return
+	}
+
+	p.m.srtpInboundContextLock.Lock()
+	p.m.srtcpInboundContext = inbound
+	p.m.srtpInboundContextLock.Unlock()
+
+	p.m.srtpOutboundContextLock.Lock()
+	p.m.srtcpOutboundContext = outbound
+	p.m.srtpOutboundContextLock.Unlock()
+}
+
+// handleRTCPDTLSState reports a failed handshake of the RTCP component, the
+// state of the DTLS transport follows the RTP component only
+func (m *Manager) handleRTCPDTLSState(state dtls.ConnectionState) {
+	if state == dtls.Failed {
+		m.dtlsLog.Warnf("DTLS handshake of the RTCP component failed: %v", m.rtcpDTLSState.Err())
+	}
+}
@@ -0,0 +1,86 @@
+package network
+
+import (
+	"net"
+
+	"github.com/pions/webrtc/pkg/rtcp"
+)
+
+// SetRTCPAddress sets where RTCP is sent for remote peers which don't
+// multiplex it with RTP, as signaled by a=rtcp, RFC 3605. Without an IP the
+// port is used on the IP of the selected remote candidate. Nil multiplexes
+// RTCP with RTP, which is the default.
+func (m *Manager) SetRTCPAddress(addr *net.UDPAddr) {
+	m.srtpOutboundContextLock.Lock()
+	defer m.srtpOutboundContextLock.Unlock()
+
+	m.rtcpAddr = addr
+}
+
+// rtcpDestination returns where RTCP for the selected remote candidate is sent
+func (m *Manager) rtcpDestination(remote *net.UDPAddr) *net.UDPAddr {
+	m.srtpOutboundContextLock.RLock()
+	defer m.srtpOutboundContextLock.RUnlock()
+
+	if m.rtcpAddr == nil {
+		return remote
+	}
+	dst := &net.UDPAddr{IP: m.rtcpAddr.IP, Port: m.rtcpAddr.Port}
+	if dst.IP == nil || dst.IP.IsUnspecified() {
+		dst.IP = remote.IP
+	}
+	return dst
+}
+
+// SetRTCPReducedSize sets whether the remote peer accepts reduced-size RTCP,
+// RFC 5506, which allows feedback to be sent on its own. Otherwise RTCP
+// packets are sent as compound packets led by a report, RFC 3550 Section
+// 6.1, which is the default.
+func (m *Manager) SetRTCPReducedSize(reducedSize bool) {
+	m.srtpOutboundContextLock.Lock()
+	defer m.srtpOutboundContextLock.Unlock()
+
+	m.rtcpReducedSize = reducedSize
+}
+
+// RTCPSSRC returns the SSRC of the reports leading compound RTCP packets,
+// which reports about the streams received if no stream is sent
+func (m *Manager) RTCPSSRC() uint32 {
+	return m.rtcpSSRC
+}
+
+// compoundRTCP returns the RTCP packet as it is sent to the remote peer.
+// Unless reduced-size RTCP is negotiated packets which don't start with a
+// report are led by an empty receiver report and the CNAME of the SSRC
+// reporting it.
+func (m *Manager) compoundRTCP(pkt []byte) ([]byte, error) {
+	m.srtpOutboundContextLock.RLock()
+	reducedSize := m.rtcpReducedSize
+	m.srtpOutboundContextLock.RUnlock()
+	if reducedSize {
+		return pkt, nil
+	}
+
+	var header rtcp.Header
+	if err := header.Unmarshal(pkt); err != nil {
+		return nil, err
+	}
+	if header.Type == rtcp.TypeSenderReport || header.Type == rtcp.TypeReceiverReport {
+		return pkt, nil
+	}
+
+	report, err := rtcp.ReceiverReport{SSRC: m.rtcpSSRC}.Marshal()
+	if err != nil {
+		return nil, err
+	}
+	description, err := rtcp.SourceDescription{Chunks: []rtcp.SourceDescriptionChunk{{
+		Source: m.rtcpSSRC,
+		Items:  []rtcp.SourceDescriptionItem{{Type: rtcp.SDESCNAME, Text: m.rtcpCNAME}},
+	}}}.Marshal()
+	if err != nil {
+		return nil, err
+	}
+
+	compound := append(report, description...)
+	return append(compound, pkt...), nil
+}
@@ -0,0 +1,87 @@
+package network
+
+import (
+	"bytes"
+	"net"
+	"testing"
+
+	"github.com/pions/webrtc/pkg/rtcp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestManager_RTCPDestination(t *testing.T) {
+	remote := &net.UDPAddr{IP: net.ParseIP("192.0.2.1"), Port: 5000}
+
+	testCases := []struct {
+		rtcpAddr *net.UDPAddr
+		dst      *net.UDPAddr
+	}{
+		{nil, remote},
+		{&net.UDPAddr{Port: 5001}, &net.UDPAddr{IP: remote.IP, Port: 5001}},
+		{&net.UDPAddr{IP: net.IPv4zero, Port: 5001}, &net.UDPAddr{IP: remote.IP, Port: 5001}},
+		{&net.UDPAddr{IP: net.ParseIP("192.0.2.2"), Port: 5005}, &net.UDPAddr{IP: net.ParseIP("192.0.2.2"), Port: 5005}},
+		// A later description multiplexing RTCP resets the address
+		{nil, remote},
+	}
+
+	m := &Manager{}
+	for i, testCase := range testCases {
+		m.SetRTCPAddress(testCase.rtcpAddr)
+		assert.Equal(t, testCase.dst, m.rtcpDestination(remote), "testCase: %d", i)
+	}
+}
+
+func TestManager_compoundRTCP(t *testing.T) {
+	m := &Manager{rtcpSSRC: 1234, rtcpCNAME: "cname"}
+
+	pli, err := (&rtcp.PictureLossIndication{SenderSSRC: 1, MediaSSRC: 5000}).Marshal()
+	assert.Nil(t, err)
+	report, err := (&rtcp.SenderReport{SSRC: 5000}).Marshal()
+	assert.Nil(t, err)
+
+	// Feedback is led by a receiver report and the CNAME of its SSRC
+	compound, err := m.compoundRTCP(pli)
+	assert.Nil(t, err)
+
+	r := rtcp.NewReader(bytes.NewReader(compound))
+	var types []rtcp.PacketType
+	for {
+		header, data, err := r.ReadPacket()
+		if err != nil {
+			break
+		}
+		types = append(types, header.Type)
+
+		switch header.Type {
+		case rtcp.TypeReceiverReport:
+			rr := &rtcp.ReceiverReport{}
+			assert.Nil(t, rr.Unmarshal(data))
+			assert.Equal(t, uint32(1234), rr.SSRC)
+		case rtcp.TypeSourceDescription:
+			sdes := &rtcp.SourceDescription{}
+			assert.Nil(t, sdes.Unmarshal(data))
+			assert.Equal(t, []rtcp.SourceDescriptionChunk{{
+				Source: 1234,
+				Items:  []rtcp.SourceDescriptionItem{{Type: rtcp.SDESCNAME, Text: "cname"}},
+			}}, sdes.Chunks)
+		default:
+			assert.Equal(t, pli, data)
+		}
+	}
+	assert.Equal(t, []rtcp.PacketType{rtcp.TypeReceiverReport, rtcp.TypeSourceDescription, rtcp.TypePayloadSpecificFeedback}, types)
+
+	// Reports already lead a compound packet
+	compound, err = m.compoundRTCP(report)
+	assert.Nil(t, err)
+	assert.Equal(t, report, compound)
+
+	// Reduced-size RTCP is sent as it is
+	m.SetRTCPReducedSize(true)
+	compound, err = m.compoundRTCP(pli)
+	assert.Nil(t, err)
+	assert.Equal(t, pli, compound)
+
+	m.SetRTCPReducedSize(false)
+	_, err = m.compoundRTCP([]byte{0x80})
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,55 @@
+package network
+
+import (
+	"encoding/binary"
+
+	"github.com/pions/webrtc/pkg/rtp"
+)
+
+// rtxOriginalSequenceNumberLength is the size of the OSN field every RTX
+// payload starts with https://tools.ietf.org/html/rfc4588#section-4
+const rtxOriginalSequenceNumberLength = 2
+
+// SetRTX configures how inbound RFC 4588 retransmissions are unwrapped.
+// ssrcs maps each RTX SSRC to the SSRC of the stream it repairs, as
+// signaled by a=ssrc-group:FID. payloadTypes maps each RTX payload type to
+// its associated payload type, as signaled by the apt fmtp parameter.
+func (m *Manager) SetRTX(ssrcs map[uint32]uint32, payloadTypes map[uint8]uint8) {
+	m.srtpInboundContextLock.Lock()
+	defer m.srtpInboundContextLock.Unlock()
+
+	m.rtxSSRCs = ssrcs
+	m.rtxPayloadTypes = payloadTypes
+}
+
+// unwrapRTX restores the original packet from a retransmission, it returns
+// false if the packet must be dropped. Packets that are not retransmissions
+// are left untouched.
+// Note: the caller should hold the srtpInboundContextLock.
+func (m *Manager) unwrapRTX(packet *rtp.Packet) bool {
+	apt, ok := m.rtxPayloadTypes[packet.PayloadType]
+	if !ok {
+		return true
+	}
+
+	ssrc, ok := m.rtxSSRCs[packet.SSRC]
+	if !ok {
+		// We can't tell which stream is being repaired
+		return false
+	}
+
+	// Packets without an OSN are padding only, used for bandwidth probing
+	if len(packet.Payload) < rtxOriginalSequenceNumberLength {
+		return false
+	}
+
+	packet.SSRC = ssrc
+	packet.PayloadType = apt
+	packet.SequenceNumber = binary.BigEndian.Uint16(packet.Payload)
+	packet.Payload = packet.Payload[rtxOriginalSequenceNumberLength:]
+
+	if _, err := packet.Marshal(); err != nil {
+		return false
+	}
+	return true
+}
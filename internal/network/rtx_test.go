@@ -0,0 +1,30 @@
+package network
+
+import (
+	"testing"
+
+	"github.com/pions/webrtc/pkg/rtp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestManager_UnwrapRTX(t *testing.T) {
+	m := &Manager{}
+	m.SetRTX(map[uint32]uint32{2000: 1000}, map[uint8]uint8{97: 96})
+
+	media := &rtp.Packet{SSRC: 1000, PayloadType: 96, SequenceNumber: 5, Payload: []byte{0x01}}
+	assert.True(t, m.unwrapRTX(media))
+	assert.Equal(t, uint16(5), media.SequenceNumber)
+
+	rtx := &rtp.Packet{Version: 2, SSRC: 2000, PayloadType: 97, SequenceNumber: 77, Payload: []byte{0x00, 0x05, 0x01}}
+	assert.True(t, m.unwrapRTX(rtx))
+	assert.Equal(t, uint32(1000), rtx.SSRC)
+	assert.Equal(t, uint8(96), rtx.PayloadType)
+	assert.Equal(t, uint16(5), rtx.SequenceNumber)
+	assert.Equal(t, []byte{0x01}, rtx.Payload)
+
+	padding := &rtp.Packet{SSRC: 2000, PayloadType: 97}
+	assert.False(t, m.unwrapRTX(padding))
+
+	unknown := &rtp.Packet{SSRC: 3000, PayloadType: 97, Payload: []byte{0x00, 0x05}}
+	assert.False(t, m.unwrapRTX(unknown))
+}
@@ -0,0 +1,24 @@
+package network
+
+// SetSCTPEnabled sets whether the SCTP association carrying data channels
+// is connected, it is only if a data channel section was negotiated. An
+// association enabled once DTLS is established is connected right away. It
+// is enabled by default.
+func (m *Manager) SetSCTPEnabled(enabled bool) {
+	m.sctpConnectLock.Lock()
+	defer m.sctpConnectLock.Unlock()
+
+	m.sctpEnabled = enabled
+	m.connectSCTP()
+}
+
+// connectSCTP connects the SCTP association once, after it is enabled and
+// DTLS is established
+// Note: the caller should hold the sctpConnectLock.
+func (m *Manager) connectSCTP() {
+	if !m.sctpEnabled || !m.dtlsEstablished || m.sctpConnected {
+		return
+	}
+	m.sctpConnected = true
+	m.sctpAssociation.Connect()
+}
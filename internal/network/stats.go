@@ -0,0 +1,111 @@
+package network
+
+import (
+	"net"
+	"sync"
+
+	"github.com/pions/webrtc/pkg/ice"
+)
+
+// maxCountedAddrs bounds the remote addresses a countingConn counts the
+// bytes of, anyone can send to a port from as many addresses as they like
+const maxCountedAddrs = 256
+
+// byteCount is the bytes sent to and received from a remote address
+type byteCount struct {
+	sent     uint64
+	received uint64
+}
+
+// countingConn counts the bytes sent and received per remote address on the
+// conn of a port, which carries every protocol of its candidates
+type countingConn struct {
+	net.PacketConn
+
+	lock   sync.Mutex
+	counts map[string]*byteCount
+}
+
+func newCountingConn(conn net.PacketConn) *countingConn {
+	return &countingConn{PacketConn: conn, counts: make(map[string]*byteCount)}
+}
+
+// ReadFrom reads a packet of the conn and counts its bytes
+func (c *countingConn) ReadFrom(b []byte) (int, net.Addr, error) {
+	n, addr, err := c.PacketConn.ReadFrom(b)
+	if err == nil {
+		c.count(addr.String(), 0, uint64(n))
+	}
+	return n, addr, err
+}
+
+// WriteTo writes a packet to the conn and counts its bytes
+func (c *countingConn) WriteTo(b []byte, addr net.Addr) (int, error) {
+	n, err := c.PacketConn.WriteTo(b, addr)
+	if err == nil {
+		c.count(addr.String(), uint64(n), 0)
+	}
+	return n, err
+}
+
+func (c *countingConn) count(addr string, sent, received uint64) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	count, ok := c.counts[addr]
+	if !ok {
+		if len(c.counts) >= maxCountedAddrs {
+			return
+		}
+		count = &byteCount{}
+		c.counts[addr] = count
+	}
+	count.sent += sent
+	count.received += received
+}
+
+// bytes returns the bytes sent to and received from the address
+func (c *countingConn) bytes(addr string) (sent, received uint64) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if count, ok := c.counts[addr]; ok {
+		return count.sent, count.received
+	}
+	return 0, 0
+}
+
+// CandidatePairStats are the stats of the connectivity checks of a
+// candidate pair and the bytes of every protocol exchanged on it
+type CandidatePairStats struct {
+	ice.CandidatePairStats
+
+	BytesSent     uint64
+	BytesReceived uint64
+}
+
+// CandidatePairStats returns the stats of every candidate pair the ICE agent
+// checked
+func (m *Manager) CandidatePairStats() []CandidatePairStats {
+	var stats []CandidatePairStats
+	for _, s := range m.IceAgent.CandidatePairStats() {
+		pairStats := CandidatePairStats{CandidatePairStats: s}
+
+		remote := s.Remote.GetBase()
+		if conn, ok := s.Local.GetBase().Conn.(*countingConn); ok {
+			addr := &net.UDPAddr{IP: net.ParseIP(remote.Address), Port: remote.Port}
+			pairStats.BytesSent, pairStats.BytesReceived = conn.bytes(addr.String())
+		}
+		stats = append(stats, pairStats)
+	}
+	return stats
+}
+
+// SRTPAuthFailures returns the number of inbound SRTP and SRTCP packets which
+// failed authentication or replay protection
+func (m *Manager) SRTPAuthFailures() uint64 {
+	m.srtpInboundContextLock.RLock()
+	defer m.srtpInboundContextLock.RUnlock()
+
+	return m.srtpAuthFailures
+}
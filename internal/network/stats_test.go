@@ -0,0 +1,41 @@
+package network
+
+import (
+	"net"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCountingConn(t *testing.T) {
+	a, err := net.ListenPacket("udp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	b, err := net.ListenPacket("udp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	defer func() { assert.Nil(t, b.Close()) }()
+
+	conn := newCountingConn(a)
+	defer func() { assert.Nil(t, conn.Close()) }()
+
+	_, err = conn.WriteTo([]byte{0x01, 0x02, 0x03}, b.LocalAddr())
+	assert.Nil(t, err)
+	_, err = b.WriteTo([]byte{0x04}, conn.LocalAddr())
+	assert.Nil(t, err)
+	buffer := make([]byte, receiveMTU)
+	_, _, err = conn.ReadFrom(buffer)
+	assert.Nil(t, err)
+
+	sent, received := conn.bytes(b.LocalAddr().String())
+	assert.Equal(t, uint64(3), sent)
+	assert.Equal(t, uint64(1), received)
+
+	// Addresses beyond the bound aren't counted
+	for i := 0; i < maxCountedAddrs; i++ {
+		conn.count("10.0.0.1:"+strconv.Itoa(i), 1, 0)
+	}
+	sent, _ = conn.bytes("10.0.0.1:" + strconv.Itoa(maxCountedAddrs-1))
+	assert.Equal(t, uint64(0), sent)
+	sent, _ = conn.bytes(b.LocalAddr().String())
+	assert.Equal(t, uint64(3), sent)
+}
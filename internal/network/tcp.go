@@ -0,0 +1,223 @@
+package network
+
+import (
+	"encoding/binary"
+	"io"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	tcpDialTimeout = 5 * time.Second
+
+	// tcpActivePort is signaled for active candidates, which don't listen
+	// https://tools.ietf.org/html/rfc6544#section-4.5
+	tcpActivePort = 9
+
+	// Direction preferences of host candidates, RFC 6544 Section 4.2
+	tcpDirectionPreferenceActive  uint16 = 6
+	tcpDirectionPreferencePassive uint16 = 4
+)
+
+var (
+	errTCPNotConnected = errors.New("no TCP connection to the address")
+	errTCPFrameSize    = errors.New("packet too large for a RFC 4571 frame")
+	errTCPClosed       = errors.New("TCP packet conn closed")
+)
+
+// tcpPacketConn carries the packets of ICE-TCP candidates, RFC 6544, over
+// connections accepted by its listener or dialed to remote passive
+// candidates. Packets are framed with a length prefix, RFC 4571.
+//
+// Peers are identified by the IP and port of their connection, reported as
+// *net.UDPAddr so the rest of the Manager can treat them like UDP peers.
+type tcpPacketConn struct {
+	listener net.Listener
+	packets  chan *incomingPacket
+
+	connsLock sync.Mutex
+	conns     map[string]net.Conn
+	dialing   map[string]bool
+
+	closed    chan struct{}
+	closeOnce sync.Once
+}
+
+func newTCPPacketConn(address string) (*tcpPacketConn, error) {
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &tcpPacketConn{
+		listener: listener,
+		packets:  make(chan *incomingPacket, 15),
+		conns:    make(map[string]net.Conn),
+		dialing:  make(map[string]bool),
+		closed:   make(chan struct{}),
+	}
+	go c.acceptLoop()
+	return c, nil
+}
+
+func (c *tcpPacketConn) acceptLoop() {
+	for {
+		conn, err := c.listener.Accept()
+		if err != nil {
+			return
+		}
+		c.addConn(conn)
+	}
+}
+
+func (c *tcpPacketConn) addConn(conn net.Conn) {
+	remote, ok := conn.RemoteAddr().(*net.TCPAddr)
+	if !ok {
+		_ = conn.Close()
+		return
+	}
+	srcAddr := &net.UDPAddr{IP: remote.IP, Port: remote.Port}
+
+	c.connsLock.Lock()
+	select {
+	case <-c.closed:
+		c.connsLock.Unlock()
+		_ = conn.Close()
+		return
+	default:
+	}
+	c.conns[srcAddr.String()] = conn
+	c.connsLock.Unlock()
+
+	go c.readLoop(conn, srcAddr)
+}
+
+func (c *tcpPacketConn) readLoop(conn net.Conn, srcAddr *net.UDPAddr) {
+	defer func() {
+		c.connsLock.Lock()
+		if c.conns[srcAddr.String()] == conn {
+			delete(c.conns, srcAddr.String())
+		}
+		c.connsLock.Unlock()
+		_ = conn.Close()
+	}()
+
+	header := make([]byte, 2)
+	for {
+		if _, err := io.ReadFull(conn, header); err != nil {
+			return
+		}
+		buffer := make([]byte, binary.BigEndian.Uint16(header))
+		if _, err := io.ReadFull(conn, buffer); err != nil {
+			return
+		}
+
+		select {
+		case c.packets <- &incomingPacket{buffer: buffer, srcAddr: srcAddr}:
+		case <-c.closed:
+			return
+		}
+	}
+}
+
+// dial opens a connection to a remote passive candidate from the IP of the
+// listener, packets sent before it is established are dropped like lost
+// UDP packets would be
+func (c *tcpPacketConn) dial(key string) {
+	defer func() {
+		c.connsLock.Lock()
+		delete(c.dialing, key)
+		c.connsLock.Unlock()
+	}()
+
+	local, ok := c.listener.Addr().(*net.TCPAddr)
+	if !ok {
+		return
+	}
+	dialer := &net.Dialer{
+		LocalAddr: &net.TCPAddr{IP: local.IP},
+		Timeout:   tcpDialTimeout,
+	}
+	conn, err := dialer.Dial("tcp", key)
+	if err != nil {
+		return
+	}
+	c.addConn(conn)
+}
+
+// ReadFrom reads the next packet of any connection
+func (c *tcpPacketConn) ReadFrom(b []byte) (int, net.Addr, error) {
+	select {
+	case p := <-c.packets:
+		return copy(b, p.buffer), p.srcAddr, nil
+	case <-c.closed:
+		return 0, nil, errTCPClosed
+	}
+}
+
+// WriteTo frames the packet onto the connection of the address, dialing
+// one if there is none yet
+func (c *tcpPacketConn) WriteTo(b []byte, addr net.Addr) (int, error) {
+	if len(b) > 0xFFFF {
+		return 0, errTCPFrameSize
+	}
+
+	key := addr.String()
+	c.connsLock.Lock()
+	conn, ok := c.conns[key]
+	if !ok {
+		if !c.dialing[key] {
+			c.dialing[key] = true
+			go c.dial(key)
+		}
+		c.connsLock.Unlock()
+		return 0, errTCPNotConnected
+	}
+	c.connsLock.Unlock()
+
+	frame := make([]byte, 2+len(b))
+	binary.BigEndian.PutUint16(frame, uint16(len(b)))
+	copy(frame[2:], b)
+	if _, err := conn.Write(frame); err != nil {
+		return 0, err
+	}
+	return len(b), nil
+}
+
+// Close closes the listener and every connection
+func (c *tcpPacketConn) Close() error {
+	var err error
+	c.closeOnce.Do(func() {
+		c.connsLock.Lock()
+		close(c.closed)
+		for _, conn := range c.conns {
+			_ = conn.Close()
+		}
+		c.connsLock.Unlock()
+		err = c.listener.Close()
+	})
+	return err
+}
+
+// LocalAddr returns the address of the listener
+func (c *tcpPacketConn) LocalAddr() net.Addr {
+	return c.listener.Addr()
+}
+
+// SetDeadline is not supported, packets are read from every connection
+func (c *tcpPacketConn) SetDeadline(t time.Time) error {
+	return nil
+}
+
+// SetReadDeadline is not supported, packets are read from every connection
+func (c *tcpPacketConn) SetReadDeadline(t time.Time) error {
+	return nil
+}
+
+// SetWriteDeadline is not supported, packets are written to every connection
+func (c *tcpPacketConn) SetWriteDeadline(t time.Time) error {
+	return nil
+}
@@ -0,0 +1,52 @@
+package network
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTCPPacketConn(t *testing.T) {
+	active, err := newTCPPacketConn("127.0.0.1:0")
+	assert.Nil(t, err)
+	defer func() { assert.Nil(t, active.Close()) }()
+
+	passive, err := newTCPPacketConn("127.0.0.1:0")
+	assert.Nil(t, err)
+	defer func() { assert.Nil(t, passive.Close()) }()
+
+	passiveAddr := passive.LocalAddr().(*net.TCPAddr)
+	dst := &net.UDPAddr{IP: passiveAddr.IP, Port: passiveAddr.Port}
+
+	// The first packet only starts dialing, like a lost UDP packet
+	_, err = active.WriteTo([]byte{0x01}, dst)
+	assert.Equal(t, errTCPNotConnected, err)
+
+	var n int
+	for start := time.Now(); time.Since(start) < time.Second; time.Sleep(10 * time.Millisecond) {
+		if n, err = active.WriteTo([]byte{0x02, 0x03}, dst); err == nil {
+			break
+		}
+	}
+	assert.Nil(t, err)
+	assert.Equal(t, 2, n)
+
+	buffer := make([]byte, receiveMTU)
+	n, srcAddr, err := passive.ReadFrom(buffer)
+	assert.Nil(t, err)
+	assert.Equal(t, []byte{0x02, 0x03}, buffer[:n])
+
+	// Replies go over the accepted connection
+	_, err = passive.WriteTo([]byte{0x04}, srcAddr)
+	assert.Nil(t, err)
+
+	n, srcAddr, err = active.ReadFrom(buffer)
+	assert.Nil(t, err)
+	assert.Equal(t, []byte{0x04}, buffer[:n])
+	assert.Equal(t, dst.String(), srcAddr.String())
+
+	_, err = active.WriteTo(make([]byte, 0x10000), dst)
+	assert.Equal(t, errTCPFrameSize, err)
+}
@@ -0,0 +1,263 @@
+package network
+
+import (
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pions/pkg/stun"
+	"github.com/pions/webrtc/internal/dtls"
+	"github.com/pions/webrtc/pkg/logging"
+	"github.com/pkg/errors"
+)
+
+// ErrUDPMuxUnspecifiedIP indicates that the socket of a UDPMux is bound to
+// an unspecified IP, which can't be announced in host candidates
+var ErrUDPMuxUnspecifiedIP = errors.New("UDP mux has to be bound to a specific IP")
+
+// ErrUDPMuxUfragInUse indicates that the packets of the ufrag are already
+// routed to another agent of the UDPMux
+var ErrUDPMuxUfragInUse = errors.New("UDP mux already routes the ufrag to another agent")
+
+var (
+	errUDPMuxClosed     = errors.New("UDP mux closed")
+	errUDPMuxConnClosed = errors.New("UDP mux conn closed")
+)
+
+// UDPMux shares a single UDP socket between the ICE agents of many Managers.
+// Packets are handed to an agent by the ufrag in the USERNAME of the STUN
+// requests of its remote peer, and by the remote address afterwards.
+type UDPMux struct {
+	conn net.PacketConn
+	addr *stun.TransportAddr
+
+	lock         sync.RWMutex
+	connsByUfrag map[string]*udpMuxedConn
+	connsByAddr  map[string]*udpMuxedConn
+
+	closed    chan struct{}
+	closeOnce sync.Once
+}
+
+// NewUDPMux shares the conn, the IP it is bound to has to be specified since
+// it is announced in the host candidates of every agent
+func NewUDPMux(conn net.PacketConn) (*UDPMux, error) {
+	addr, err := stun.NewTransportAddr(conn.LocalAddr())
+	if err != nil {
+		return nil, err
+	} else if addr.IP == nil || addr.IP.IsUnspecified() {
+		return nil, ErrUDPMuxUnspecifiedIP
+	}
+
+	m := &UDPMux{
+		conn:         conn,
+		addr:         addr,
+		connsByUfrag: make(map[string]*udpMuxedConn),
+		connsByAddr:  make(map[string]*udpMuxedConn),
+		closed:       make(chan struct{}),
+	}
+
+	// Every DTLS session writes to the shared socket, its failures go to the
+	// default logger since no single Manager owns it
+	dtls.AddListener(addr.String(), conn, logging.NewDefaultLoggerFactory().NewLogger(logging.ScopeDTLS))
+
+	go m.readLoop()
+	return m, nil
+}
+
+// LocalAddr returns the address of the shared socket
+func (m *UDPMux) LocalAddr() net.Addr {
+	return m.conn.LocalAddr()
+}
+
+// Close closes the shared socket, the conns of the agents are closed with it
+func (m *UDPMux) Close() error {
+	var err error
+	m.closeOnce.Do(func() {
+		close(m.closed)
+		dtls.RemoveListener(m.addr.String())
+		err = m.conn.Close()
+	})
+	return err
+}
+
+// HasUfrag reports whether the packets of the ufrag are routed to an agent
+func (m *UDPMux) HasUfrag(ufrag string) bool {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+	_, ok := m.connsByUfrag[ufrag]
+	return ok
+}
+
+// getConn returns the conn receiving the packets of the agent with the ufrag,
+// which no other agent may have
+func (m *UDPMux) getConn(ufrag string) (*udpMuxedConn, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	select {
+	case <-m.closed:
+		return nil, errUDPMuxClosed
+	default:
+	}
+
+	if _, ok := m.connsByUfrag[ufrag]; ok {
+		return nil, ErrUDPMuxUfragInUse
+	}
+	c := &udpMuxedConn{
+		mux:     m,
+		ufrag:   ufrag,
+		packets: make(chan *incomingPacket, 15),
+		closed:  make(chan struct{}),
+	}
+	m.connsByUfrag[ufrag] = c
+	return c, nil
+}
+
+func (m *UDPMux) removeConn(c *udpMuxedConn) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if m.connsByUfrag[c.ufrag] == c {
+		delete(m.connsByUfrag, c.ufrag)
+	}
+	for addr, addrConn := range m.connsByAddr {
+		if addrConn == c {
+			delete(m.connsByAddr, addr)
+		}
+	}
+}
+
+// registerAddr sends the packets of the remote address to the conn, unless
+// they already go to another one
+func (m *UDPMux) registerAddr(c *udpMuxedConn, addr net.Addr) {
+	key := addr.String()
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	if _, ok := m.connsByAddr[key]; !ok {
+		m.connsByAddr[key] = c
+	}
+}
+
+func (m *UDPMux) readLoop() {
+	buffer := make([]byte, receiveMTU)
+	for {
+		n, srcAddr, err := m.conn.ReadFrom(buffer)
+		if err != nil {
+			m.lock.Lock()
+			for _, c := range m.connsByUfrag {
+				c.closeOnce.Do(func() { close(c.closed) })
+			}
+			m.lock.Unlock()
+			return
+		}
+
+		udpAddr, ok := srcAddr.(*net.UDPAddr)
+		if !ok {
+			continue
+		}
+		c := m.route(buffer[:n], udpAddr)
+		if c == nil {
+			continue
+		}
+
+		bufferCopy := make([]byte, n)
+		copy(bufferCopy, buffer[:n])
+		select {
+		case c.packets <- &incomingPacket{buffer: bufferCopy, srcAddr: udpAddr}:
+		default:
+		}
+	}
+}
+
+// route finds the conn of the packet, STUN requests from unknown addresses
+// are routed by the ufrag of their USERNAME
+// https://tools.ietf.org/html/rfc8445#section-7.2.2
+func (m *UDPMux) route(buffer []byte, srcAddr net.Addr) *udpMuxedConn {
+	m.lock.RLock()
+	c, ok := m.connsByAddr[srcAddr.String()]
+	m.lock.RUnlock()
+	if ok {
+		return c
+	}
+
+	if len(buffer) == 0 || buffer[0] >= 2 {
+		return nil
+	}
+	msg, err := stun.NewMessage(buffer)
+	if err != nil {
+		return nil
+	}
+	username, ok := msg.GetOneAttribute(stun.AttrUsername)
+	if !ok {
+		return nil
+	}
+
+	// USERNAME is the ufrag of the receiving agent followed by the one of
+	// the sending agent
+	ufrag := strings.Split(string(username.Value), ":")[0]
+	m.lock.RLock()
+	c, ok = m.connsByUfrag[ufrag]
+	m.lock.RUnlock()
+	if !ok {
+		return nil
+	}
+
+	m.registerAddr(c, srcAddr)
+	return c
+}
+
+// udpMuxedConn carries the packets of a single agent over the UDPMux
+type udpMuxedConn struct {
+	mux     *UDPMux
+	ufrag   string
+	packets chan *incomingPacket
+
+	closed    chan struct{}
+	closeOnce sync.Once
+}
+
+// ReadFrom reads the next packet routed to the agent
+func (c *udpMuxedConn) ReadFrom(b []byte) (int, net.Addr, error) {
+	select {
+	case p := <-c.packets:
+		return copy(b, p.buffer), p.srcAddr, nil
+	case <-c.closed:
+		return 0, nil, errUDPMuxConnClosed
+	}
+}
+
+// WriteTo writes to the shared socket, replies from the address are routed
+// to the agent from now on
+func (c *udpMuxedConn) WriteTo(b []byte, addr net.Addr) (int, error) {
+	c.mux.registerAddr(c, addr)
+	return c.mux.conn.WriteTo(b, addr)
+}
+
+// Close stops routing packets to the agent, the shared socket stays open
+func (c *udpMuxedConn) Close() error {
+	c.closeOnce.Do(func() { close(c.closed) })
+	c.mux.removeConn(c)
+	return nil
+}
+
+// LocalAddr returns the address of the shared socket
+func (c *udpMuxedConn) LocalAddr() net.Addr {
+	return c.mux.LocalAddr()
+}
+
+// SetDeadline is not supported, the socket is shared
+func (c *udpMuxedConn) SetDeadline(t time.Time) error {
+	return nil
+}
+
+// SetReadDeadline is not supported, the socket is shared
+func (c *udpMuxedConn) SetReadDeadline(t time.Time) error {
+	return nil
+}
+
+// SetWriteDeadline is not supported, the socket is shared
+func (c *udpMuxedConn) SetWriteDeadline(t time.Time) error {
+	return nil
+}
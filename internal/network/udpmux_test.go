@@ -0,0 +1,103 @@
+package network
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/pions/pkg/stun"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewUDPMux(t *testing.T) {
+	conn, err := net.ListenPacket("udp", ":0")
+	assert.NoError(t, err)
+	defer func() { assert.NoError(t, conn.Close()) }()
+
+	_, err = NewUDPMux(conn)
+	assert.Equal(t, ErrUDPMuxUnspecifiedIP, err)
+}
+
+func TestUDPMux(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	mux, err := NewUDPMux(conn)
+	assert.NoError(t, err)
+	defer func() { assert.NoError(t, mux.Close()) }()
+
+	connA, err := mux.getConn("ufragA")
+	assert.NoError(t, err)
+	connB, err := mux.getConn("ufragB")
+	assert.NoError(t, err)
+
+	// A ufrag is routed to a single agent
+	assert.True(t, mux.HasUfrag("ufragA"))
+	_, err = mux.getConn("ufragA")
+	assert.Equal(t, ErrUDPMuxUfragInUse, err)
+
+	peerA, err := net.ListenPacket("udp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	defer func() { assert.NoError(t, peerA.Close()) }()
+	peerB, err := net.ListenPacket("udp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	defer func() { assert.NoError(t, peerB.Close()) }()
+
+	bindingRequest := func(username string) []byte {
+		msg, buildErr := stun.Build(stun.ClassRequest, stun.MethodBinding, stun.GenerateTransactionId(),
+			&stun.Username{Username: username},
+			&stun.Fingerprint{},
+		)
+		assert.NoError(t, buildErr)
+		return msg.Pack()
+	}
+	read := func(c *udpMuxedConn) ([]byte, net.Addr) {
+		buffer := make([]byte, receiveMTU)
+		done := make(chan struct{})
+		var n int
+		var addr net.Addr
+		go func() {
+			n, addr, _ = c.ReadFrom(buffer)
+			close(done)
+		}()
+		select {
+		case <-done:
+		case <-time.After(time.Second):
+			t.Fatal("timed out reading from the UDP mux")
+		}
+		return buffer[:n], addr
+	}
+
+	// Unknown peers are dropped until they send a connectivity check
+	_, err = peerA.WriteTo([]byte{0x80}, mux.LocalAddr())
+	assert.NoError(t, err)
+
+	request := bindingRequest("ufragA:remote")
+	_, err = peerA.WriteTo(request, mux.LocalAddr())
+	assert.NoError(t, err)
+	packet, addr := read(connA)
+	assert.Equal(t, request, packet)
+	assert.Equal(t, peerA.LocalAddr().String(), addr.String())
+
+	// Afterwards all of their packets are routed by address
+	_, err = peerA.WriteTo([]byte{0x80}, mux.LocalAddr())
+	assert.NoError(t, err)
+	packet, _ = read(connA)
+	assert.Equal(t, []byte{0x80}, packet)
+
+	// Peers an agent writes to are routed to it
+	_, err = connB.WriteTo([]byte{0x01}, peerB.LocalAddr())
+	assert.NoError(t, err)
+	_, err = peerB.WriteTo([]byte{0x81}, mux.LocalAddr())
+	assert.NoError(t, err)
+	packet, _ = read(connB)
+	assert.Equal(t, []byte{0x81}, packet)
+
+	// Closed conns stop receiving, the others keep going
+	assert.NoError(t, connA.Close())
+	_, _, err = connA.ReadFrom(make([]byte, receiveMTU))
+	assert.Equal(t, errUDPMuxConnClosed, err)
+	_, err = peerB.WriteTo([]byte{0x82}, mux.LocalAddr())
+	assert.NoError(t, err)
+	packet, _ = read(connB)
+	assert.Equal(t, []byte{0x82}, packet)
+}
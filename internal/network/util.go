@@ -1,11 +1,20 @@
 package network
 
-import "net"
+import (
+	"net"
+
+	"github.com/pions/webrtc/pkg/ice"
+)
+
+// localInterfaces returns the IPs host candidates are gathered on. IPv6
+// and IPv4 addresses are interleaved, starting with IPv6, to be assigned
+// descending local preferences, RFC 8421 Section 4.
+func localInterfaces(filter func(string) bool) []string {
+	var ipv6, ipv4 []string
 
-func localInterfaces() (ips []string) {
 	ifaces, err := net.Interfaces()
 	if err != nil {
-		return ips
+		return nil
 	}
 
 	for _, iface := range ifaces {
@@ -15,9 +24,12 @@ func localInterfaces() (ips []string) {
 		if iface.Flags&net.FlagLoopback != 0 {
 			continue // loopback interface
 		}
+		if filter != nil && !filter(iface.Name) {
+			continue // filtered out by the settings
+		}
 		addrs, err := iface.Addrs()
 		if err != nil {
-			return ips
+			break
 		}
 		for _, addr := range addrs {
 			var ip net.IP
@@ -30,12 +42,40 @@ func localInterfaces() (ips []string) {
 			if ip == nil || ip.IsLoopback() {
 				continue
 			}
-			ip = ip.To4()
-			if ip == nil {
-				continue // not an ipv4 address
+			if ip.To4() != nil {
+				ipv4 = append(ipv4, ip.To4().String())
+			} else if ip.IsGlobalUnicast() {
+				// Link-local addresses would require the zone of their interface
+				ipv6 = append(ipv6, ip.String())
 			}
-			ips = append(ips, ip.String())
 		}
 	}
-	return ips
+	return interleave(ipv6, ipv4)
+}
+
+func interleave(a, b []string) []string {
+	out := make([]string, 0, len(a)+len(b))
+	for i := 0; i < len(a) || i < len(b); i++ {
+		if i < len(a) {
+			out = append(out, a[i])
+		}
+		if i < len(b) {
+			out = append(out, b[i])
+		}
+	}
+	return out
+}
+
+// localPreference returns the local preference of the nth local candidate
+// of a type, preferring the ones gathered first
+func localPreference(n int) uint16 {
+	if n >= int(ice.DefaultLocalPreference) {
+		return 1
+	}
+	return ice.DefaultLocalPreference - uint16(n)
+}
+
+func isIPv6(ip string) bool {
+	parsed := net.ParseIP(ip)
+	return parsed != nil && parsed.To4() == nil
 }
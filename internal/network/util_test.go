@@ -0,0 +1,45 @@
+package network
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInterleave(t *testing.T) {
+	assert.Equal(t,
+		[]string{"2001:db8::1", "192.0.2.1", "2001:db8::2", "192.0.2.2", "192.0.2.3"},
+		interleave([]string{"2001:db8::1", "2001:db8::2"}, []string{"192.0.2.1", "192.0.2.2", "192.0.2.3"}),
+	)
+	assert.Equal(t, []string{"192.0.2.1"}, interleave(nil, []string{"192.0.2.1"}))
+}
+
+func TestLocalPreference(t *testing.T) {
+	assert.Equal(t, uint16(65535), localPreference(0))
+	assert.Equal(t, uint16(65534), localPreference(1))
+	assert.Equal(t, uint16(1), localPreference(70000))
+}
+
+func TestManager_publicIP(t *testing.T) {
+	m := &Manager{settings: Settings{NAT1To1IPs: map[string]string{
+		"0.0.0.0":  "203.0.113.1",
+		"10.0.0.1": "203.0.113.2",
+	}}}
+
+	testCases := []struct {
+		privateIP string
+		publicIP  string
+		ok        bool
+	}{
+		{"10.0.0.1", "203.0.113.2", true},
+		{"10.0.0.2", "203.0.113.1", true},
+		// IPv6 addresses aren't mapped to the IPv4 address of the NAT
+		{"2001:db8::1", "", false},
+	}
+
+	for i, testCase := range testCases {
+		publicIP, ok := m.publicIP(testCase.privateIP)
+		assert.Equal(t, testCase.publicIP, publicIP, "testCase: %d", i)
+		assert.Equal(t, testCase.ok, ok, "testCase: %d", i)
+	}
+}
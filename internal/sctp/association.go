@@ -9,9 +9,14 @@ import (
 	"math/rand"
 	"time"
 
+	"github.com/pions/webrtc/pkg/logging"
 	"github.com/pkg/errors"
 )
 
+// MaxMessageSize is the size of the largest message an Association sends,
+// the length of larger ones doesn't fit the fragment offsets
+const MaxMessageSize = math.MaxUint16
+
 // AssociationState is an enum for the states that an Association will transition
 // through while connecting
 // https://tools.ietf.org/html/rfc4960#section-13.2
@@ -27,6 +32,7 @@ const (
 	ShutdownPending
 	ShutdownReceived
 	ShutdownSent
+	Closed
 )
 
 func (a AssociationState) String() string {
@@ -47,6 +53,8 @@ func (a AssociationState) String() string {
 		return "ShutdownReceived"
 	case ShutdownAckSent:
 		return "ShutdownAckSent"
+	case Closed:
+		return "Closed"
 	default:
 		return fmt.Sprintf("Invalid AssociationState %d", a)
 	}
@@ -111,6 +119,8 @@ type Association struct {
 	// Put a blocking goroutine in port-receive (vs callbacks)
 	outboundHandler func([]byte)
 	dataHandler     func([]byte, uint16, PayloadProtocolIdentifier)
+
+	log logging.LeveledLogger
 }
 
 // HandleInbound parses incoming raw packets
@@ -135,8 +145,8 @@ func (a *Association) HandleInbound(raw []byte) error {
 
 func (a *Association) packetizeOutbound(raw []byte, streamIdentifier uint16, payloadType PayloadProtocolIdentifier) ([]*chunkPayloadData, error) {
 
-	if len(raw) > math.MaxUint16 {
-		return nil, errors.Errorf("Outbound packet larger than maximum message size %v", math.MaxUint16)
+	if len(raw) > MaxMessageSize {
+		return nil, errors.Errorf("Outbound packet larger than maximum message size %v", MaxMessageSize)
 	}
 
 	seqNum, ok := a.outboundStreams[streamIdentifier]
@@ -171,8 +181,30 @@ func (a *Association) packetizeOutbound(raw []byte, streamIdentifier uint16, pay
 	return chunks, nil
 }
 
+// SetMaxStreams limits the inbound and outbound streams offered in the INIT
+// or INIT ACK, it has no effect once the association is established
+func (a *Association) SetMaxStreams(streams uint16) {
+	a.myMaxNumInboundStreams = streams
+	a.myMaxNumOutboundStreams = streams
+}
+
+// Streams returns the number of streams negotiated with the INIT and INIT
+// ACK, it is the limit offered until the association is established
+func (a *Association) Streams() uint16 {
+	return min(a.myMaxNumInboundStreams, a.myMaxNumOutboundStreams)
+}
+
 // HandleOutbound sends outbound raw packets
 func (a *Association) HandleOutbound(raw []byte, streamIdentifier uint16, payloadType PayloadProtocolIdentifier) error {
+	// https://tools.ietf.org/html/rfc4960#section-9.2
+	// Upon receipt of the SHUTDOWN primitive from its upper layer, the
+	// endpoint enters the SHUTDOWN-PENDING state [...] and stops accepting
+	// new data from its SCTP user
+	switch a.state {
+	case ShutdownPending, ShutdownSent, ShutdownReceived, ShutdownAckSent:
+		return errors.Errorf("Unable to send outbound packet in state %s", a.state.String())
+	}
+
 	chunks, err := a.packetizeOutbound(raw, streamIdentifier, payloadType)
 	if err != nil {
 		return errors.Wrap(err, "Unable to packetize outbound packet")
@@ -197,11 +229,25 @@ func (a *Association) HandleOutbound(raw []byte, streamIdentifier uint16, payloa
 
 // Close ends the SCTP Association and cleans up any state
 func (a *Association) Close() error {
+	a.setState(Closed)
 	return nil
 }
 
-// NewAssocation creates a new Association and the state needed to manage it
-func NewAssocation(outboundHandler func([]byte), dataHandler func([]byte, uint16, PayloadProtocolIdentifier), notifier func(AssociationState)) *Association {
+// Shutdown gracefully closes the SCTP Association, the peer is sent a
+// SHUTDOWN once it acknowledged all the data sent so far. The Association is
+// Closed when the peer acknowledged the SHUTDOWN, no data is sent meanwhile.
+func (a *Association) Shutdown() error {
+	if a.state != Established {
+		return errors.Errorf("Unable to shut down the association in state %s", a.state.String())
+	}
+
+	a.setState(ShutdownPending)
+	return a.progressShutdown()
+}
+
+// NewAssocation creates a new Association and the state needed to manage it,
+// its diagnostics are written to the logger
+func NewAssocation(outboundHandler func([]byte), dataHandler func([]byte, uint16, PayloadProtocolIdentifier), notifier func(AssociationState), log logging.LeveledLogger) *Association {
 	rs := rand.NewSource(time.Now().UnixNano())
 	r := rand.New(rs)
 
@@ -222,6 +268,7 @@ func NewAssocation(outboundHandler func([]byte), dataHandler func([]byte, uint16
 		state:                     Open,
 		notifier:                  notifier,
 		peerCumulativeTSNAckPoint: tsn - 1,
+		log:                       log,
 	}
 }
 
@@ -293,7 +340,7 @@ func (a *Association) Connect() {
 	if a.isInitiating {
 		err := a.send(a.createInit())
 		if err != nil {
-			fmt.Printf("Failed to send init: %v", err)
+			a.log.Warnf("Failed to send init: %v", err)
 		}
 		a.setState(CookieWait)
 	}
@@ -365,7 +412,7 @@ func (a *Association) handleInitAck(p *packet, i *chunkInitAck) (*packet, error)
 	a.peerLastTSN = i.initialTSN - 1
 	if a.sourcePort != p.destinationPort ||
 		a.destinationPort != p.sourcePort {
-		fmt.Println("handleInitAck: port mismatch")
+		a.log.Warn("handleInitAck: port mismatch")
 	}
 
 	outbound := &packet{}
@@ -417,7 +464,7 @@ func (a *Association) handleData(d *chunkPayloadData) *packet {
 		}
 
 		a.peerLastTSN++
-		pd, popOk = a.payloadQueue.pop(a.peerLastTSN)
+		pd, popOk = a.payloadQueue.pop(a.peerLastTSN + 1)
 	}
 
 	outbound := &packet{}
@@ -443,24 +490,17 @@ func (a *Association) handleSack(d *chunkSelectiveAck) ([]*packet, error) {
 	// less than the Cumulative TSN Ack Point indicates an out-of-
 	// order SACK.
 
-	// This is an old SACK, toss
-	if a.peerCumulativeTSNAckPoint >= d.cumulativeTSNAck {
+	// This is an old SACK, toss. A SACK at the ack point still reports the
+	// gaps of the data received since, which is retransmitted below.
+	if a.peerCumulativeTSNAckPoint > d.cumulativeTSNAck {
 		return nil, errors.Errorf("SACK Cumulative ACK %v is older than ACK point %v",
 			d.cumulativeTSNAck, a.peerCumulativeTSNAckPoint)
 	}
 
-	// New ack point, so pop all ACKed packets from inflightQueue
-	// We add 1 because the "currentAckPoint" has already been popped from the inflight queue
-	// For the first SACK we take care of this by setting the ackpoint to cumAck - 1
-	for i := a.peerCumulativeTSNAckPoint + 1; i <= d.cumulativeTSNAck; i++ {
-		_, ok := a.inflightQueue.pop(i)
-		if !ok {
-			return nil, errors.Errorf("TSN %v unable to be popped from inflight queue", i)
-		}
+	if err := a.acknowledge(d.cumulativeTSNAck); err != nil {
+		return nil, err
 	}
 
-	a.peerCumulativeTSNAckPoint = d.cumulativeTSNAck
-
 	var sackDataPackets []*packet
 	var prevEnd uint16
 	for _, g := range d.gapAckBlocks {
@@ -483,6 +523,115 @@ func (a *Association) handleSack(d *chunkSelectiveAck) ([]*packet, error) {
 	return sackDataPackets, nil
 }
 
+// acknowledge moves the ack point to the Cumulative TSN Ack of a SACK or
+// SHUTDOWN chunk, the acknowledged data isn't retransmitted anymore
+func (a *Association) acknowledge(cumulativeTSNAck uint32) error {
+	// New ack point, so pop all ACKed packets from inflightQueue
+	// We add 1 because the "currentAckPoint" has already been popped from the inflight queue
+	// For the first SACK we take care of this by setting the ackpoint to cumAck - 1
+	for i := a.peerCumulativeTSNAckPoint + 1; i <= cumulativeTSNAck; i++ {
+		_, ok := a.inflightQueue.pop(i)
+		if !ok {
+			return errors.Errorf("TSN %v unable to be popped from inflight queue", i)
+		}
+	}
+
+	a.peerCumulativeTSNAckPoint = cumulativeTSNAck
+	return nil
+}
+
+// progressShutdown sends the SHUTDOWN, or the SHUTDOWN ACK, once the peer
+// acknowledged all the data sent
+// https://tools.ietf.org/html/rfc4960#section-9.2
+func (a *Association) progressShutdown() error {
+	if a.inflightQueue.size() != 0 {
+		return nil
+	}
+
+	switch a.state {
+	case ShutdownPending:
+		if err := a.send(&packet{
+			verificationTag: a.peerVerificationTag,
+			sourcePort:      a.sourcePort,
+			destinationPort: a.destinationPort,
+			chunks:          []chunk{&chunkShutdown{cumulativeTSNAck: a.peerLastTSN}},
+		}); err != nil {
+			return err
+		}
+		a.setState(ShutdownSent)
+	case ShutdownReceived:
+		if err := a.send(&packet{
+			verificationTag: a.peerVerificationTag,
+			sourcePort:      a.sourcePort,
+			destinationPort: a.destinationPort,
+			chunks:          []chunk{&chunkShutdownAck{}},
+		}); err != nil {
+			return err
+		}
+		a.setState(ShutdownAckSent)
+	}
+	return nil
+}
+
+func (a *Association) handleShutdown(c *chunkShutdown) error {
+	switch a.state {
+	case Established, ShutdownPending:
+		// The Cumulative TSN Ack acknowledges our data like the one of a SACK
+		if c.cumulativeTSNAck > a.peerCumulativeTSNAckPoint {
+			if err := a.acknowledge(c.cumulativeTSNAck); err != nil {
+				return err
+			}
+		}
+		a.setState(ShutdownReceived)
+		return a.progressShutdown()
+	case ShutdownSent:
+		// https://tools.ietf.org/html/rfc4960#section-9.2
+		// If an endpoint is in the SHUTDOWN-SENT state and receives a
+		// SHUTDOWN chunk from its peer, the endpoint shall respond
+		// immediately with a SHUTDOWN ACK to its peer, and move into the
+		// SHUTDOWN-ACK-SENT state
+		a.setState(ShutdownReceived)
+		return a.progressShutdown()
+	case ShutdownReceived, ShutdownAckSent:
+		// A retransmission, the SHUTDOWN ACK follows or has been sent
+		return nil
+	default:
+		return errors.Errorf("TODO Handle Shutdown when in state %s", a.state.String())
+	}
+}
+
+func (a *Association) handleShutdownAck() error {
+	switch a.state {
+	case ShutdownSent, ShutdownAckSent:
+		// https://tools.ietf.org/html/rfc4960#section-9.2
+		// Upon the receipt of the SHUTDOWN ACK, the SHUTDOWN sender shall
+		// [...] send a SHUTDOWN COMPLETE chunk to its peer, and remove all
+		// record of the association
+		if err := a.send(&packet{
+			verificationTag: a.peerVerificationTag,
+			sourcePort:      a.sourcePort,
+			destinationPort: a.destinationPort,
+			chunks:          []chunk{&chunkShutdownComplete{}},
+		}); err != nil {
+			return err
+		}
+		a.setState(Closed)
+		return nil
+	default:
+		return errors.Errorf("TODO Handle Shutdown acks when in state %s", a.state.String())
+	}
+}
+
+func (a *Association) handleShutdownComplete() error {
+	switch a.state {
+	case ShutdownAckSent:
+		a.setState(Closed)
+		return nil
+	default:
+		return errors.Errorf("TODO Handle Shutdown completes when in state %s", a.state.String())
+	}
+}
+
 func (a *Association) send(p *packet) error {
 	raw, err := p.marshal()
 	if err != nil {
@@ -533,14 +682,15 @@ func (a *Association) handleChunk(p *packet, c chunk) error {
 			return errors.Errorf("TODO Handle Init acks when in state %s", a.state.String())
 		}
 	case *chunkAbort:
-		fmt.Println("Abort chunk, with errors:")
+		a.log.Info("Abort chunk, with errors:")
 		for _, e := range c.errorCauses {
-			fmt.Printf("error cause: %s\n", e)
+			a.log.Infof("error cause: %s", e)
 		}
+		a.setState(Closed)
 	case *chunkHeartbeat:
 		hbi, ok := c.params[0].(*paramHeartbeatInfo)
 		if !ok {
-			fmt.Println("Failed to handle Heartbeat, no ParamHeartbeatInfo")
+			a.log.Warn("Failed to handle Heartbeat, no ParamHeartbeatInfo")
 		}
 
 		return a.send(&packet{
@@ -582,7 +732,15 @@ func (a *Association) handleChunk(p *packet, c chunk) error {
 
 		// TODO Abort
 	case *chunkPayloadData:
-		return a.send(a.handleData(c))
+		p := a.handleData(c)
+		if a.state == ShutdownSent {
+			// https://tools.ietf.org/html/rfc4960#section-9.2
+			// The sender of the SHUTDOWN MUST continue to respond to each
+			// received packet containing one or more DATA chunks with a
+			// SHUTDOWN chunk
+			p.chunks = append(p.chunks, &chunkShutdown{cumulativeTSNAck: a.peerLastTSN})
+		}
+		return a.send(p)
 	case *chunkSelectiveAck:
 		p, err := a.handleSack(c)
 		if err != nil {
@@ -594,6 +752,13 @@ func (a *Association) handleChunk(p *packet, c chunk) error {
 				return errors.Wrap(err, "Failure handling SACK")
 			}
 		}
+		return a.progressShutdown()
+	case *chunkShutdown:
+		return a.handleShutdown(c)
+	case *chunkShutdownAck:
+		return a.handleShutdownAck()
+	case *chunkShutdownComplete:
+		return a.handleShutdownComplete()
 	default:
 		return errors.New("unhandled chunk type")
 	}
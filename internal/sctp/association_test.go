@@ -1,8 +1,12 @@
 package sctp
 
 import (
+	"bytes"
 	"fmt"
+	"io/ioutil"
 	"testing"
+
+	"github.com/pions/webrtc/pkg/logging"
 )
 
 func TestAssociationInit(t *testing.T) {
@@ -19,3 +23,166 @@ func TestAssociationInit(t *testing.T) {
 		// t.Error(errors.Wrap(err, "Failed to HandleInbound"))
 	}
 }
+
+func TestAssociationShutdown(t *testing.T) {
+	log := logging.NewDefaultLeveledLoggerForScope(logging.ScopeSCTP, logging.LogLevelDisabled, ioutil.Discard)
+
+	// Packets are queued and delivered one at a time, like a network does
+	var toA, toB [][]byte
+	var received [][]byte
+	a := NewAssocation(func(raw []byte) { toB = append(toB, raw) }, func([]byte, uint16, PayloadProtocolIdentifier) {}, nil, log)
+	b := NewAssocation(func(raw []byte) { toA = append(toA, raw) }, func(data []byte, streamIdentifier uint16, payloadType PayloadProtocolIdentifier) {
+		received = append(received, data)
+	}, nil, log)
+	deliver := func() {
+		for len(toA) != 0 || len(toB) != 0 {
+			if len(toB) != 0 {
+				raw := toB[0]
+				toB = toB[1:]
+				if err := b.HandleInbound(raw); err != nil {
+					t.Fatalf("Failed to HandleInbound: %v", err)
+				}
+			}
+			if len(toA) != 0 {
+				raw := toA[0]
+				toA = toA[1:]
+				if err := a.HandleInbound(raw); err != nil {
+					t.Fatalf("Failed to HandleInbound: %v", err)
+				}
+			}
+		}
+	}
+
+	a.Start(true)
+	b.Start(false)
+	a.Connect()
+	deliver()
+	if a.state != Established || b.state != Established {
+		t.Fatalf("Association not established: %s %s", a.state, b.state)
+	}
+
+	// The SHUTDOWN waits for the data in flight to be acknowledged
+	if err := a.HandleOutbound([]byte("bye"), 1, PayloadTypeWebRTCString); err != nil {
+		t.Fatalf("Failed to HandleOutbound: %v", err)
+	}
+	if err := a.Shutdown(); err != nil {
+		t.Fatalf("Failed to Shutdown: %v", err)
+	}
+	if a.state != ShutdownPending {
+		t.Errorf("Expected state %s, got %s", ShutdownPending, a.state)
+	}
+	if err := a.HandleOutbound([]byte("late"), 1, PayloadTypeWebRTCString); err == nil {
+		t.Error("HandleOutbound succeeded during the shutdown")
+	}
+
+	deliver()
+	if a.state != Closed || b.state != Closed {
+		t.Errorf("Association not closed: %s %s", a.state, b.state)
+	}
+	if len(received) != 1 || !bytes.Equal(received[0], []byte("bye")) {
+		t.Errorf("Data sent before the shutdown not delivered: %v", received)
+	}
+	if err := a.Shutdown(); err == nil {
+		t.Error("Shutdown of a closed association succeeded")
+	}
+}
+
+func TestAssociationRetransmit(t *testing.T) {
+	log := logging.NewDefaultLeveledLoggerForScope(logging.ScopeSCTP, logging.LogLevelDisabled, ioutil.Discard)
+
+	var toA, toB [][]byte
+	var received [][]byte
+	a := NewAssocation(func(raw []byte) { toB = append(toB, raw) }, func([]byte, uint16, PayloadProtocolIdentifier) {}, nil, log)
+	b := NewAssocation(func(raw []byte) { toA = append(toA, raw) }, func(data []byte, streamIdentifier uint16, payloadType PayloadProtocolIdentifier) {
+		received = append(received, data)
+	}, nil, log)
+	deliver := func() {
+		for len(toA) != 0 || len(toB) != 0 {
+			if len(toB) != 0 {
+				raw := toB[0]
+				toB = toB[1:]
+				if err := b.HandleInbound(raw); err != nil {
+					t.Fatalf("Failed to HandleInbound: %v", err)
+				}
+			}
+			if len(toA) != 0 {
+				raw := toA[0]
+				toA = toA[1:]
+				if err := a.HandleInbound(raw); err != nil {
+					t.Fatalf("Failed to HandleInbound: %v", err)
+				}
+			}
+		}
+	}
+
+	a.Start(true)
+	b.Start(false)
+	a.Connect()
+	deliver()
+
+	for _, message := range []string{"lost", "gap"} {
+		if err := a.HandleOutbound([]byte(message), 1, PayloadTypeWebRTCString); err != nil {
+			t.Fatalf("Failed to HandleOutbound: %v", err)
+		}
+	}
+
+	// The first message is lost, the SACK of the second reports the gap
+	// without moving the ack point, the lost message is retransmitted and both
+	// are delivered in order
+	toB = toB[1:]
+	deliver()
+	if len(received) != 2 || string(received[0]) != "lost" || string(received[1]) != "gap" {
+		t.Errorf("Lost message not retransmitted: %q", received)
+	}
+}
+
+func TestAssociationReorder(t *testing.T) {
+	log := logging.NewDefaultLeveledLoggerForScope(logging.ScopeSCTP, logging.LogLevelDisabled, ioutil.Discard)
+
+	var toA, toB [][]byte
+	var received [][]byte
+	a := NewAssocation(func(raw []byte) { toB = append(toB, raw) }, func([]byte, uint16, PayloadProtocolIdentifier) {}, nil, log)
+	b := NewAssocation(func(raw []byte) { toA = append(toA, raw) }, func(data []byte, streamIdentifier uint16, payloadType PayloadProtocolIdentifier) {
+		received = append(received, data)
+	}, nil, log)
+
+	a.Start(true)
+	b.Start(false)
+	a.Connect()
+	for len(toA) != 0 || len(toB) != 0 {
+		if len(toB) != 0 {
+			raw := toB[0]
+			toB = toB[1:]
+			if err := b.HandleInbound(raw); err != nil {
+				t.Fatalf("Failed to HandleInbound: %v", err)
+			}
+		}
+		if len(toA) != 0 {
+			raw := toA[0]
+			toA = toA[1:]
+			if err := a.HandleInbound(raw); err != nil {
+				t.Fatalf("Failed to HandleInbound: %v", err)
+			}
+		}
+	}
+
+	for _, message := range []string{"first", "second", "third"} {
+		if err := a.HandleOutbound([]byte(message), 1, PayloadTypeWebRTCString); err != nil {
+			t.Fatalf("Failed to HandleOutbound: %v", err)
+		}
+	}
+	if len(toB) != 3 {
+		t.Fatalf("Sent %d packets, want 3", len(toB))
+	}
+
+	// The messages queued behind the first are delivered as soon as it
+	// arrives, without waiting for more data
+	for _, i := range []int{1, 2, 0} {
+		if err := b.HandleInbound(toB[i]); err != nil {
+			t.Fatalf("Failed to HandleInbound: %v", err)
+		}
+	}
+	if len(received) != 3 || string(received[0]) != "first" || string(received[1]) != "second" || string(received[2]) != "third" {
+		t.Errorf("Queued messages not delivered: %q", received)
+	}
+}
@@ -0,0 +1,65 @@
+package sctp
+
+import (
+	"encoding/binary"
+
+	"github.com/pkg/errors"
+)
+
+/*
+chunkShutdown represents an SCTP Chunk of type SHUTDOWN
+
+An endpoint in an association MUST use this chunk to initiate a
+graceful close of the association with its peer.
+
+ 0                   1                   2                   3
+ 0 1 2 3 4 5 6 7 8 9 0 1 2 3 4 5 6 7 8 9 0 1 2 3 4 5 6 7 8 9 0 1
++-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+|   Type = 7    | Chunk  Flags  |      Length = 8               |
++-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+|                      Cumulative TSN Ack                       |
++-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+*/
+type chunkShutdown struct {
+	chunkHeader
+	cumulativeTSNAck uint32
+}
+
+const (
+	cumulativeTSNAckLength = 4
+)
+
+func (c *chunkShutdown) unmarshal(raw []byte) error {
+	if err := c.chunkHeader.unmarshal(raw); err != nil {
+		return err
+	}
+
+	if c.typ != SHUTDOWN {
+		return errors.Errorf("ChunkType is not of type SHUTDOWN, actually is %s", c.typ.String())
+	}
+
+	if len(c.raw) != cumulativeTSNAckLength {
+		return errors.Errorf("SHUTDOWN chunk has a value of %d bytes, expected %d", len(c.raw), cumulativeTSNAckLength)
+	}
+
+	c.cumulativeTSNAck = binary.BigEndian.Uint32(c.raw[0:])
+	return nil
+}
+
+func (c *chunkShutdown) marshal() ([]byte, error) {
+	out := make([]byte, cumulativeTSNAckLength)
+	binary.BigEndian.PutUint32(out[0:], c.cumulativeTSNAck)
+
+	c.chunkHeader.typ = SHUTDOWN
+	c.chunkHeader.raw = out
+	return c.chunkHeader.marshal()
+}
+
+func (c *chunkShutdown) check() (abort bool, err error) {
+	return false, nil
+}
+
+// String makes chunkShutdown printable
+func (c *chunkShutdown) String() string {
+	return c.chunkHeader.String()
+}
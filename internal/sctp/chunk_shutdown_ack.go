@@ -0,0 +1,47 @@
+package sctp
+
+import (
+	"github.com/pkg/errors"
+)
+
+/*
+chunkShutdownAck represents an SCTP Chunk of type SHUTDOWN ACK
+
+This chunk MUST be used to acknowledge the receipt of the SHUTDOWN
+chunk at the completion of the shutdown process.
+
+ 0                   1                   2                   3
+ 0 1 2 3 4 5 6 7 8 9 0 1 2 3 4 5 6 7 8 9 0 1 2 3 4 5 6 7 8 9 0 1
++-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+|   Type = 8    |Chunk  Flags   |      Length = 4               |
++-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+*/
+type chunkShutdownAck struct {
+	chunkHeader
+}
+
+func (c *chunkShutdownAck) unmarshal(raw []byte) error {
+	if err := c.chunkHeader.unmarshal(raw); err != nil {
+		return err
+	}
+
+	if c.typ != SHUTDOWNACK {
+		return errors.Errorf("ChunkType is not of type SHUTDOWN ACK, actually is %s", c.typ.String())
+	}
+
+	return nil
+}
+
+func (c *chunkShutdownAck) marshal() ([]byte, error) {
+	c.chunkHeader.typ = SHUTDOWNACK
+	return c.chunkHeader.marshal()
+}
+
+func (c *chunkShutdownAck) check() (abort bool, err error) {
+	return false, nil
+}
+
+// String makes chunkShutdownAck printable
+func (c *chunkShutdownAck) String() string {
+	return c.chunkHeader.String()
+}
@@ -89,6 +89,12 @@ func (p *packet) unmarshal(raw []byte) error {
 			c = &chunkPayloadData{}
 		case SACK:
 			c = &chunkSelectiveAck{}
+		case SHUTDOWN:
+			c = &chunkShutdown{}
+		case SHUTDOWNACK:
+			c = &chunkShutdownAck{}
+		case SHUTDOWNCOMPLETE:
+			c = &chunkShutdownComplete{}
 		default:
 			return errors.Errorf("Failed to unmarshal, contains unknown chunk type %s", chunkType(raw[offset]).String())
 		}
@@ -99,3 +99,7 @@ func (r *payloadQueue) getGapAckBlocks(cumulativeTSN uint32) (gapAckBlocks []gap
 
 	return gapAckBlocks
 }
+
+func (r *payloadQueue) size() int {
+	return len(r.orderedPackets)
+}
@@ -0,0 +1,303 @@
+package sdp
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/pions/webrtc/pkg/ice"
+)
+
+// Constants for the direction attributes of media sections
+// https://tools.ietf.org/html/rfc4566#section-6
+const (
+	AttrKeySendRecv = "sendrecv"
+	AttrKeySendOnly = "sendonly"
+	AttrKeyRecvOnly = "recvonly"
+	AttrKeyInactive = "inactive"
+)
+
+// Constants for the attributes describing the formats of media sections
+const (
+	AttrKeyRtpMap = "rtpmap"
+	AttrKeyFmtp   = "fmtp"
+)
+
+// Key returns the name of the attribute, the part before the first colon
+func (a *Attribute) Key() string {
+	return strings.SplitN(string(*a), ":", 2)[0]
+}
+
+// Value returns the value of the attribute, the part after the first colon.
+// It is empty for property attributes, like a=rtcp-mux.
+func (a *Attribute) Value() string {
+	split := strings.SplitN(string(*a), ":", 2)
+	if len(split) != 2 {
+		return ""
+	}
+	return split[1]
+}
+
+// valueOf returns the value of the attribute if its name is key
+func (a *Attribute) valueOf(key string) (string, bool) {
+	split := strings.SplitN(string(*a), ":", 2)
+	if len(split) != 2 || split[0] != key {
+		return "", false
+	}
+	return split[1], true
+}
+
+// Mid returns the identification of the media section of an a=mid
+// attribute, ok is false for other attributes
+// https://tools.ietf.org/html/rfc5888#section-4
+func (a *Attribute) Mid() (mid string, ok bool) {
+	return a.valueOf(AttrKeyMID)
+}
+
+// Direction returns the direction of an a=sendrecv, a=sendonly, a=recvonly
+// or a=inactive attribute, ok is false for other attributes
+func (a *Attribute) Direction() (direction string, ok bool) {
+	switch string(*a) {
+	case AttrKeySendRecv, AttrKeySendOnly, AttrKeyRecvOnly, AttrKeyInactive:
+		return string(*a), true
+	}
+	return "", false
+}
+
+// SSRCAttribute is a source-level attribute of an a=ssrc line
+// https://tools.ietf.org/html/rfc5576#section-4.1
+type SSRCAttribute struct {
+	SSRC uint32
+
+	// Attribute is the source-level attribute, like msid or cname, and
+	// Value its value. Both are empty if the line announces the SSRC only.
+	Attribute string
+	Value     string
+}
+
+// SSRC parses an a=ssrc attribute, ok is false for other attributes or if
+// the SSRC is malformed
+func (a *Attribute) SSRC() (ssrc SSRCAttribute, ok bool) {
+	value, ok := a.valueOf(AttrKeySsrc)
+	if !ok {
+		return SSRCAttribute{}, false
+	}
+
+	// a=ssrc:<ssrc-id> <attribute>[:<value>]
+	fields := strings.SplitN(value, " ", 2)
+	id, err := strconv.ParseUint(fields[0], 10, 32)
+	if err != nil {
+		return SSRCAttribute{}, false
+	}
+	ssrc.SSRC = uint32(id)
+	if len(fields) == 2 {
+		split := strings.SplitN(fields[1], ":", 2)
+		ssrc.Attribute = split[0]
+		if len(split) == 2 {
+			ssrc.Value = split[1]
+		}
+	}
+	return ssrc, true
+}
+
+// SSRCGroup is a group of SSRCs of an a=ssrc-group line, like the SSRC of a
+// track and the one repairing it
+// https://tools.ietf.org/html/rfc5576#section-4.2
+type SSRCGroup struct {
+	Semantics string
+	SSRCs     []uint32
+}
+
+// SSRCGroup parses an a=ssrc-group attribute, ok is false for other
+// attributes or if an SSRC is malformed
+func (a *Attribute) SSRCGroup() (group SSRCGroup, ok bool) {
+	value, ok := a.valueOf(AttrKeySsrcGroup)
+	if !ok {
+		return SSRCGroup{}, false
+	}
+
+	// a=ssrc-group:<semantics> <ssrc-id> ...
+	fields := strings.Fields(value)
+	if len(fields) < 2 {
+		return SSRCGroup{}, false
+	}
+	group.Semantics = fields[0]
+	for _, field := range fields[1:] {
+		ssrc, err := strconv.ParseUint(field, 10, 32)
+		if err != nil {
+			return SSRCGroup{}, false
+		}
+		group.SSRCs = append(group.SSRCs, uint32(ssrc))
+	}
+	return group, true
+}
+
+// RTPMap maps a payload type to an encoding, as announced by a=rtpmap
+// https://tools.ietf.org/html/rfc4566#section-6
+type RTPMap struct {
+	PayloadType        uint8
+	EncodingName       string
+	ClockRate          uint32
+	EncodingParameters string
+}
+
+// RTPMap parses an a=rtpmap attribute, ok is false for other attributes or
+// if the attribute is malformed
+func (a *Attribute) RTPMap() (rtpMap RTPMap, ok bool) {
+	value, ok := a.valueOf(AttrKeyRtpMap)
+	if !ok {
+		return RTPMap{}, false
+	}
+
+	// a=rtpmap:<payload type> <encoding name>/<clock rate> [/<encoding parameters>]
+	fields := strings.Fields(value)
+	if len(fields) != 2 {
+		return RTPMap{}, false
+	}
+	payloadType, err := strconv.ParseUint(fields[0], 10, 7)
+	if err != nil {
+		return RTPMap{}, false
+	}
+	rtpMap.PayloadType = uint8(payloadType)
+
+	split := strings.Split(fields[1], "/")
+	rtpMap.EncodingName = split[0]
+	if len(split) > 1 {
+		clockRate, err := strconv.ParseUint(split[1], 10, 32)
+		if err != nil {
+			return RTPMap{}, false
+		}
+		rtpMap.ClockRate = uint32(clockRate)
+	}
+	if len(split) > 2 {
+		rtpMap.EncodingParameters = split[2]
+	}
+	return rtpMap, true
+}
+
+// Fmtp holds the format specific parameters of a payload type, as announced
+// by a=fmtp
+// https://tools.ietf.org/html/rfc4566#section-6
+type Fmtp struct {
+	PayloadType uint8
+	Parameters  string
+}
+
+// Parameter returns the value of the parameter of a parameter list like
+// apt=96;rtx-time=3000, ok is false if it is not in the list
+func (f Fmtp) Parameter(name string) (value string, ok bool) {
+	for _, param := range strings.Split(f.Parameters, ";") {
+		split := strings.SplitN(strings.TrimSpace(param), "=", 2)
+		if len(split) == 2 && strings.EqualFold(split[0], name) {
+			return split[1], true
+		}
+	}
+	return "", false
+}
+
+// Fmtp parses an a=fmtp attribute, ok is false for other attributes or if
+// the payload type is malformed
+func (a *Attribute) Fmtp() (fmtp Fmtp, ok bool) {
+	value, ok := a.valueOf(AttrKeyFmtp)
+	if !ok {
+		return Fmtp{}, false
+	}
+
+	// a=fmtp:<format> <format specific parameters>
+	fields := strings.SplitN(value, " ", 2)
+	payloadType, err := strconv.ParseUint(fields[0], 10, 7)
+	if err != nil {
+		return Fmtp{}, false
+	}
+	fmtp.PayloadType = uint8(payloadType)
+	if len(fields) == 2 {
+		fmtp.Parameters = strings.TrimSpace(fields[1])
+	}
+	return fmtp, true
+}
+
+// ExtMap maps an RTP header extension to an id, as announced by a=extmap
+// https://tools.ietf.org/html/rfc8285#section-8
+type ExtMap struct {
+	Value     uint8
+	Direction string
+	URI       string
+
+	// ExtensionAttributes are the attributes following the URI, if any
+	ExtensionAttributes string
+}
+
+// ExtMap parses an a=extmap attribute, ok is false for other attributes or
+// if the attribute is malformed
+func (a *Attribute) ExtMap() (extMap ExtMap, ok bool) {
+	value, ok := a.valueOf(AttrKeyExtMap)
+	if !ok {
+		return ExtMap{}, false
+	}
+
+	// a=extmap:<value>["/"<direction>] <URI> <extensionattributes>
+	fields := strings.SplitN(value, " ", 3)
+	if len(fields) < 2 {
+		return ExtMap{}, false
+	}
+	split := strings.SplitN(fields[0], "/", 2)
+	id, err := strconv.ParseUint(split[0], 10, 8)
+	if err != nil {
+		return ExtMap{}, false
+	}
+	extMap.Value = uint8(id)
+	if len(split) == 2 {
+		extMap.Direction = split[1]
+	}
+	extMap.URI = fields[1]
+	if len(fields) == 3 {
+		extMap.ExtensionAttributes = fields[2]
+	}
+	return extMap, true
+}
+
+// Fingerprint parses an a=fingerprint attribute, the algorithm is
+// lowercased. ok is false for other attributes or if it is malformed.
+func (a *Attribute) Fingerprint() (fingerprint Fingerprint, ok bool) {
+	value, ok := a.valueOf(AttrKeyFingerprint)
+	if !ok {
+		return Fingerprint{}, false
+	}
+
+	// a=fingerprint:<hash-func> <fingerprint>
+	fields := strings.Fields(value)
+	if len(fields) != 2 {
+		return Fingerprint{}, false
+	}
+	return Fingerprint{Algorithm: strings.ToLower(fields[0]), Value: fields[1]}, true
+}
+
+// Candidate parses an a=candidate attribute, ok is false for other
+// attributes. The candidate is nil if the attribute is malformed.
+func (a *Attribute) Candidate() (c ice.Candidate, ok bool) {
+	if a.Key() != AttrKeyCandidate {
+		return nil, false
+	}
+	return ICECandidateUnmarshal(string(*a)), true
+}
+
+// Mid returns the identification of the media section, empty if it has no
+// a=mid attribute
+func (m *MediaDescription) Mid() string {
+	for _, a := range m.Attributes {
+		if mid, ok := a.Mid(); ok {
+			return mid
+		}
+	}
+	return ""
+}
+
+// Direction returns the direction attribute of the media section, which is
+// sendrecv without one, RFC 3264 Section 5.1
+func (m *MediaDescription) Direction() string {
+	for _, a := range m.Attributes {
+		if direction, ok := a.Direction(); ok {
+			return direction
+		}
+	}
+	return AttrKeySendRecv
+}
@@ -0,0 +1,162 @@
+package sdp
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAttribute_KeyValue(t *testing.T) {
+	testCases := []struct {
+		attribute Attribute
+		key       string
+		value     string
+	}{
+		{"rtcp-mux", "rtcp-mux", ""},
+		{"mid:0", "mid", "0"},
+		{"fingerprint:sha-256 AB:CD", "fingerprint", "sha-256 AB:CD"},
+	}
+
+	for i, testCase := range testCases {
+		assert.Equal(t, testCase.key, testCase.attribute.Key(), "testCase: %d", i)
+		assert.Equal(t, testCase.value, testCase.attribute.Value(), "testCase: %d", i)
+	}
+}
+
+func TestAttribute_MidDirection(t *testing.T) {
+	a := Attribute("mid:audio")
+	mid, ok := a.Mid()
+	assert.True(t, ok)
+	assert.Equal(t, "audio", mid)
+
+	// Attributes sharing a prefix aren't mistaken for one another
+	for _, a := range []Attribute{"midi:1", "sendrecv:1", "sendonlyx"} {
+		_, ok = a.Mid()
+		assert.False(t, ok, string(a))
+		_, ok = a.Direction()
+		assert.False(t, ok, string(a))
+	}
+
+	a = Attribute("recvonly")
+	direction, ok := a.Direction()
+	assert.True(t, ok)
+	assert.Equal(t, AttrKeyRecvOnly, direction)
+
+	m := NewJSEPMediaDescription("audio", []string{})
+	assert.Equal(t, "", m.Mid())
+	assert.Equal(t, AttrKeySendRecv, m.Direction())
+	m.WithValueAttribute(AttrKeyMID, "0").WithPropertyAttribute(AttrKeyInactive)
+	assert.Equal(t, "0", m.Mid())
+	assert.Equal(t, AttrKeyInactive, m.Direction())
+}
+
+func TestAttribute_SSRC(t *testing.T) {
+	testCases := []struct {
+		attribute Attribute
+		ssrc      SSRCAttribute
+		ok        bool
+	}{
+		{"ssrc:1000 msid:stream track", SSRCAttribute{1000, "msid", "stream track"}, true},
+		{"ssrc:1000 cname:a:b", SSRCAttribute{1000, "cname", "a:b"}, true},
+		{"ssrc:2000", SSRCAttribute{SSRC: 2000}, true},
+		{"ssrc:x cname:a", SSRCAttribute{}, false},
+		{"ssrc-group:FID 1 2", SSRCAttribute{}, false},
+	}
+
+	for i, testCase := range testCases {
+		ssrc, ok := testCase.attribute.SSRC()
+		assert.Equal(t, testCase.ok, ok, "testCase: %d", i)
+		assert.Equal(t, testCase.ssrc, ssrc, "testCase: %d", i)
+	}
+}
+
+func TestAttribute_SSRCGroup(t *testing.T) {
+	testCases := []struct {
+		attribute Attribute
+		group     SSRCGroup
+		ok        bool
+	}{
+		{"ssrc-group:FID 1000 2000", SSRCGroup{"FID", []uint32{1000, 2000}}, true},
+		{"ssrc-group:SIM 1 2 3", SSRCGroup{"SIM", []uint32{1, 2, 3}}, true},
+		{"ssrc-group:FID 1000 x", SSRCGroup{}, false},
+		{"ssrc-group:FID", SSRCGroup{}, false},
+		{"ssrc:1000 cname:a", SSRCGroup{}, false},
+	}
+
+	for i, testCase := range testCases {
+		group, ok := testCase.attribute.SSRCGroup()
+		assert.Equal(t, testCase.ok, ok, "testCase: %d", i)
+		assert.Equal(t, testCase.group, group, "testCase: %d", i)
+	}
+}
+
+func TestAttribute_RTPMapFmtp(t *testing.T) {
+	testCases := []struct {
+		attribute Attribute
+		rtpMap    RTPMap
+		ok        bool
+	}{
+		{"rtpmap:111 opus/48000/2", RTPMap{111, "opus", 48000, "2"}, true},
+		{"rtpmap:96 VP8/90000", RTPMap{96, "VP8", 90000, ""}, true},
+		{"rtpmap:96 VP8/x", RTPMap{}, false},
+		{"rtpmap:200 VP8/90000", RTPMap{}, false},
+		{"fmtp:96 apt=100", RTPMap{}, false},
+	}
+
+	for i, testCase := range testCases {
+		rtpMap, ok := testCase.attribute.RTPMap()
+		assert.Equal(t, testCase.ok, ok, "testCase: %d", i)
+		assert.Equal(t, testCase.rtpMap, rtpMap, "testCase: %d", i)
+	}
+
+	a := Attribute("fmtp:97 apt=96; rtx-time=3000")
+	fmtp, ok := a.Fmtp()
+	assert.True(t, ok)
+	assert.Equal(t, Fmtp{97, "apt=96; rtx-time=3000"}, fmtp)
+	value, ok := fmtp.Parameter("rtx-time")
+	assert.True(t, ok)
+	assert.Equal(t, "3000", value)
+	_, ok = fmtp.Parameter("apt=96")
+	assert.False(t, ok)
+}
+
+func TestAttribute_ExtMap(t *testing.T) {
+	testCases := []struct {
+		attribute Attribute
+		extMap    ExtMap
+		ok        bool
+	}{
+		{"extmap:4 " + ExtMapURIMID, ExtMap{Value: 4, URI: ExtMapURIMID}, true},
+		{"extmap:2/sendonly urn:example attr", ExtMap{2, "sendonly", "urn:example", "attr"}, true},
+		{"extmap:x urn:example", ExtMap{}, false},
+		{"extmap:3", ExtMap{}, false},
+	}
+
+	for i, testCase := range testCases {
+		extMap, ok := testCase.attribute.ExtMap()
+		assert.Equal(t, testCase.ok, ok, "testCase: %d", i)
+		assert.Equal(t, testCase.extMap, extMap, "testCase: %d", i)
+	}
+}
+
+func TestAttribute_FingerprintCandidate(t *testing.T) {
+	a := Attribute("fingerprint:SHA-256 AB:CD")
+	fingerprint, ok := a.Fingerprint()
+	assert.True(t, ok)
+	assert.Equal(t, Fingerprint{"sha-256", "AB:CD"}, fingerprint)
+
+	a = Attribute("candidate:1 1 udp 2130706431 192.168.1.1 5000 typ host")
+	c, ok := a.Candidate()
+	assert.True(t, ok)
+	assert.NotNil(t, c)
+	assert.Equal(t, 5000, c.GetBase().Port)
+
+	a = Attribute("candidate:garbage")
+	c, ok = a.Candidate()
+	assert.True(t, ok)
+	assert.Nil(t, c)
+
+	a = Attribute(AttrKeyEndOfCandidates)
+	_, ok = a.Candidate()
+	assert.False(t, ok)
+}
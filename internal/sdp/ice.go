@@ -12,7 +12,6 @@ import (
 func ICECandidateUnmarshal(raw string) ice.Candidate {
 	split := strings.Fields(raw)
 	if len(split) < 8 {
-		fmt.Printf("Attribute not long enough to be ICE candidate (%d) %s \n", len(split), raw)
 		return nil
 	}
 
@@ -28,6 +27,16 @@ func ICECandidateUnmarshal(raw string) ice.Candidate {
 		return ""
 	}
 
+	component, err := strconv.ParseUint(split[1], 10, 16)
+	if err != nil {
+		return nil
+	}
+
+	priority, err := strconv.ParseUint(split[3], 10, 32)
+	if err != nil {
+		return nil
+	}
+
 	port, err := strconv.Atoi(split[5])
 	if err != nil {
 		return nil
@@ -36,21 +45,51 @@ func ICECandidateUnmarshal(raw string) ice.Candidate {
 	// TODO verify valid address
 	address := split[4]
 
+	protocol := ice.NewProtoType(strings.ToLower(split[2]))
+	var tcpType ice.TCPType
+	switch protocol {
+	case ice.ProtoTypeUDP:
+	case ice.ProtoTypeTCP:
+		// Simultaneous-open candidates are not supported
+		if tcpType = ice.NewTCPType(getValue("tcptype")); tcpType == ice.TCPType(ice.Unknown) {
+			return nil
+		}
+	default:
+		return nil
+	}
+
 	switch getValue("typ") {
 	case "host":
 		return &ice.CandidateHost{
 			CandidateBase: ice.CandidateBase{
-				Protocol: ice.ProtoTypeUDP,
-				Address:  address,
-				Port:     port,
+				Protocol:         protocol,
+				Address:          address,
+				Port:             port,
+				TCPType:          tcpType,
+				Component:        uint16(component),
+				SignaledPriority: uint32(priority),
 			},
 		}
 	case "srflx":
 		return &ice.CandidateSrflx{
 			CandidateBase: ice.CandidateBase{
-				Protocol: ice.ProtoTypeUDP,
-				Address:  address,
-				Port:     port,
+				Protocol:         protocol,
+				Address:          address,
+				Port:             port,
+				TCPType:          tcpType,
+				Component:        uint16(component),
+				SignaledPriority: uint32(priority),
+			},
+		}
+	case "relay":
+		return &ice.CandidateRelay{
+			CandidateBase: ice.CandidateBase{
+				Protocol:         protocol,
+				Address:          address,
+				Port:             port,
+				TCPType:          tcpType,
+				Component:        uint16(component),
+				SignaledPriority: uint32(priority),
 			},
 		}
 	default:
@@ -60,25 +99,36 @@ func ICECandidateUnmarshal(raw string) ice.Candidate {
 
 func iceSrflxCandidateString(c *ice.CandidateSrflx, component int) string {
 	return fmt.Sprintf("udpcandidate %d udp %d %s %d typ srflx raddr %s rport %d generation 0",
-		component, c.CandidateBase.Priority(ice.SrflxCandidatePreference, uint16(component)), c.CandidateBase.Address, c.CandidateBase.Port, c.RemoteAddress, c.RemotePort)
+		component, ice.CandidatePriority(c), c.CandidateBase.Address, c.CandidateBase.Port, c.RemoteAddress, c.RemotePort)
+}
+
+func iceRelayCandidateString(c *ice.CandidateRelay, component int) string {
+	return fmt.Sprintf("udpcandidate %d udp %d %s %d typ relay raddr %s rport %d generation 0",
+		component, ice.CandidatePriority(c), c.CandidateBase.Address, c.CandidateBase.Port, c.RelatedAddress, c.RelatedPort)
 }
 
 func iceHostCandidateString(c *ice.CandidateHost, component int) string {
+	if c.CandidateBase.Protocol == ice.ProtoTypeTCP {
+		return fmt.Sprintf("tcpcandidate %d tcp %d %s %d typ host tcptype %s generation 0",
+			component, ice.CandidatePriority(c), c.CandidateBase.Address, c.CandidateBase.Port, c.CandidateBase.TCPType)
+	}
 	return fmt.Sprintf("udpcandidate %d udp %d %s %d typ host generation 0",
-		component, c.CandidateBase.Priority(ice.HostCandidatePreference, uint16(component)), c.CandidateBase.Address, c.CandidateBase.Port)
+		component, ice.CandidatePriority(c), c.CandidateBase.Address, c.CandidateBase.Port)
 }
 
-// ICECandidateMarshal takes a candidate and returns a string representation
+// ICECandidateMarshal takes a candidate and returns a string representation,
+// announcing it for its component
 func ICECandidateMarshal(c ice.Candidate) []string {
 	out := make([]string, 0)
 
+	component := int(c.GetBase().GetComponent())
 	switch c := c.(type) {
 	case *ice.CandidateSrflx:
-		out = append(out, iceSrflxCandidateString(c, 1))
-		out = append(out, iceSrflxCandidateString(c, 2))
+		out = append(out, iceSrflxCandidateString(c, component))
 	case *ice.CandidateHost:
-		out = append(out, iceHostCandidateString(c, 1))
-		out = append(out, iceHostCandidateString(c, 2))
+		out = append(out, iceHostCandidateString(c, component))
+	case *ice.CandidateRelay:
+		out = append(out, iceRelayCandidateString(c, component))
 	}
 
 	return out
@@ -0,0 +1,98 @@
+package sdp
+
+import (
+	"testing"
+
+	"github.com/pions/webrtc/pkg/ice"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestICECandidateUnmarshal(t *testing.T) {
+	testCases := []struct {
+		raw               string
+		expectedProtocol  ice.ProtoType
+		expectedTCPType   ice.TCPType
+		expectedPort      int
+		expectedComponent uint16
+	}{
+		{"1 1 udp 2130706431 192.0.2.1 5000 typ host generation 0", ice.ProtoTypeUDP, ice.TCPType(ice.Unknown), 5000, ice.ComponentRTP},
+		{"1 1 UDP 2130706431 192.0.2.1 5000 typ host", ice.ProtoTypeUDP, ice.TCPType(ice.Unknown), 5000, ice.ComponentRTP},
+		{"1 2 udp 2130706430 192.0.2.1 5001 typ host", ice.ProtoTypeUDP, ice.TCPType(ice.Unknown), 5001, ice.ComponentRTCP},
+		{"2 1 tcp 1518280447 192.0.2.1 9 typ host tcptype active generation 0", ice.ProtoTypeTCP, ice.TCPTypeActive, 9, ice.ComponentRTP},
+		{"3 1 tcp 1518214911 192.0.2.1 5001 typ host tcptype passive generation 0", ice.ProtoTypeTCP, ice.TCPTypePassive, 5001, ice.ComponentRTP},
+	}
+
+	for i, testCase := range testCases {
+		c := ICECandidateUnmarshal(testCase.raw)
+		if !assert.NotNil(t, c, "testCase: %d %v", i, testCase) {
+			continue
+		}
+		assert.Equal(t, testCase.expectedProtocol, c.GetBase().Protocol, "testCase: %d %v", i, testCase)
+		assert.Equal(t, testCase.expectedTCPType, c.GetBase().TCPType, "testCase: %d %v", i, testCase)
+		assert.Equal(t, testCase.expectedPort, c.GetBase().Port, "testCase: %d %v", i, testCase)
+		assert.Equal(t, testCase.expectedComponent, c.GetBase().GetComponent(), "testCase: %d %v", i, testCase)
+	}
+
+	// Simultaneous-open and unknown protocols are not supported
+	assert.Nil(t, ICECandidateUnmarshal("4 1 tcp 1518149375 192.0.2.1 5002 typ host tcptype so generation 0"))
+	assert.Nil(t, ICECandidateUnmarshal("5 1 sctp 1518149375 192.0.2.1 5002 typ host generation 0"))
+}
+
+func TestICECandidateMarshal_TCP(t *testing.T) {
+	c := &ice.CandidateHost{
+		CandidateBase: ice.CandidateBase{
+			Protocol: ice.ProtoTypeTCP,
+			TCPType:  ice.TCPTypePassive,
+			Address:  "192.0.2.1",
+			Port:     5001,
+		},
+	}
+
+	for _, raw := range ICECandidateMarshal(c) {
+		parsed := ICECandidateUnmarshal(raw)
+		if assert.NotNil(t, parsed, raw) {
+			assert.Equal(t, c.CandidateBase.Protocol, parsed.GetBase().Protocol)
+			assert.Equal(t, c.CandidateBase.TCPType, parsed.GetBase().TCPType)
+			assert.Equal(t, c.CandidateBase.Port, parsed.GetBase().Port)
+		}
+	}
+}
+
+func TestICECandidateMarshal_Component(t *testing.T) {
+	c := &ice.CandidateHost{
+		CandidateBase: ice.CandidateBase{
+			Protocol:  ice.ProtoTypeUDP,
+			Address:   "192.0.2.1",
+			Port:      5001,
+			Component: ice.ComponentRTCP,
+		},
+	}
+
+	raw := ICECandidateMarshal(c)
+	if assert.Equal(t, 1, len(raw)) {
+		assert.Equal(t, "udpcandidate 2 udp 2130706430 192.0.2.1 5001 typ host generation 0", raw[0])
+	}
+}
+
+func TestICECandidateMarshal_Relay(t *testing.T) {
+	c := &ice.CandidateRelay{
+		CandidateBase: ice.CandidateBase{
+			Protocol: ice.ProtoTypeUDP,
+			Address:  "203.0.113.1",
+			Port:     49152,
+		},
+		RelatedAddress: "198.51.100.1",
+		RelatedPort:    5000,
+	}
+
+	raw := ICECandidateMarshal(c)
+	if assert.Equal(t, 1, len(raw)) {
+		assert.Equal(t, "udpcandidate 1 udp 16777215 203.0.113.1 49152 typ relay raddr 198.51.100.1 rport 5000 generation 0", raw[0])
+
+		parsed, ok := ICECandidateUnmarshal(raw[0]).(*ice.CandidateRelay)
+		if assert.True(t, ok) {
+			assert.Equal(t, c.CandidateBase.Address, parsed.CandidateBase.Address)
+			assert.Equal(t, c.CandidateBase.Port, parsed.CandidateBase.Port)
+		}
+	}
+}
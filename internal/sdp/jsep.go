@@ -3,6 +3,8 @@ package sdp
 import (
 	"fmt"
 	"net"
+	"strconv"
+	"strings"
 	"time"
 )
 
@@ -12,12 +14,43 @@ const (
 	AttrKeyGroup           = "group"
 	AttrKeySsrc            = "ssrc"
 	AttrKeySsrcGroup       = "ssrc-group"
+	AttrKeyMsid            = "msid"
 	AttrKeyMsidSemantic    = "msid-semantic"
 	AttrKeyConnectionSetup = "setup"
 	AttrKeyMID             = "mid"
 	AttrKeyICELite         = "ice-lite"
+	AttrKeyRtcp            = "rtcp"
 	AttrKeyRtcpMux         = "rtcp-mux"
 	AttrKeyRtcpRsize       = "rtcp-rsize"
+	AttrKeyRtcpFb          = "rtcp-fb"
+	AttrKeyRID             = "rid"
+	AttrKeySimulcast       = "simulcast"
+	AttrKeyCandidate       = "candidate"
+	AttrKeyEndOfCandidates = "end-of-candidates"
+	AttrKeyExtMap          = "extmap"
+	AttrKeyFingerprint     = "fingerprint"
+	AttrKeyMaxMessageSize  = "max-message-size"
+	AttrKeyBundleOnly      = "bundle-only"
+	AttrKeySCTPPort        = "sctp-port"
+	AttrKeySCTPMap         = "sctpmap"
+	AttrKeyICEUfrag        = "ice-ufrag"
+	AttrKeyICEPwd          = "ice-pwd"
+)
+
+// FormatWebRTCDataChannel is the format of data channel sections announcing
+// the SCTP port with a=sctp-port
+// https://tools.ietf.org/html/draft-ietf-mmusic-data-channel-sdpneg-22#section-5.1
+const FormatWebRTCDataChannel = "webrtc-datachannel"
+
+// Constants for RTP header extensions used in JSEP
+const (
+	// ExtMapURIMID carries the mid of the media section of an RTP stream
+	// https://tools.ietf.org/html/draft-ietf-mmusic-sdp-bundle-negotiation-54#section-14.1
+	ExtMapURIMID = "urn:ietf:params:rtp-hdrext:sdes:mid"
+
+	// ExtMapURIAbsSendTime carries the time an RTP packet was sent
+	// https://webrtc.org/experiments/rtp-hdrext/abs-send-time/
+	ExtMapURIAbsSendTime = "http://www.webrtc.org/experiments/rtp-hdrext/abs-send-time"
 )
 
 // Constants for semantic tokens used in JSEP
@@ -26,6 +59,8 @@ const (
 	SemanticTokenFlowIdentification     = "FID"
 	SemanticTokenForwardErrorCorrection = "FEC"
 	SemanticTokenWebRTCMediaStreams     = "WMS"
+	SemanticTokenSimulcast              = "SIM"
+	SemanticTokenBundle                 = "BUNDLE"
 )
 
 // API to match draft-ietf-rtcweb-jsep
@@ -56,7 +91,7 @@ func NewJSEPSessionDescription(fingerprint string, identity bool) *SessionDescri
 		},
 		Attributes: []Attribute{
 			// 	"Attribute(ice-options:trickle)", // TODO: implement trickle ICE
-			Attribute("fingerprint:sha-256 " + fingerprint),
+			Attribute(AttrKeyFingerprint + ":sha-256 " + fingerprint),
 		},
 	}
 
@@ -127,7 +162,7 @@ func (d *MediaDescription) WithICECredentials(username, password string) *MediaD
 
 // WithCodec adds codec information to the media description
 func (d *MediaDescription) WithCodec(payloadType uint8, name string, clockrate uint32, channels uint16, fmtp string) *MediaDescription {
-	d.MediaName.Formats = append(d.MediaName.Formats, int(payloadType))
+	d.MediaName.Formats = append(d.MediaName.Formats, strconv.Itoa(int(payloadType)))
 	rtpmap := fmt.Sprintf("%d %s/%d", payloadType, name, clockrate)
 	if channels > 0 {
 		rtpmap = rtpmap + fmt.Sprintf("/%d", channels)
@@ -148,7 +183,51 @@ func (d *MediaDescription) WithMediaSource(ssrc uint32, cname, streamLabel, labe
 		WithValueAttribute("ssrc", fmt.Sprintf("%d label:%s", ssrc, label))          // Deprecated but not phased out?
 }
 
+// WithSimulcast adds the a=rid lines for every layer, the a=simulcast line
+// listing them and groups their SSRCs with a SIM ssrc-group
+// https://tools.ietf.org/html/draft-ietf-mmusic-sdp-simulcast-13#section-5.1
+func (d *MediaDescription) WithSimulcast(direction string, rids []string, ssrcs []uint32) *MediaDescription {
+	for _, rid := range rids {
+		d.WithValueAttribute(AttrKeyRID, rid+" "+direction)
+	}
+	return d.
+		WithSSRCGroup(SemanticTokenSimulcast, ssrcs...).
+		WithValueAttribute(AttrKeySimulcast, direction+" "+strings.Join(rids, ";"))
+}
+
+// WithSSRCGroup adds an a=ssrc-group line grouping the SSRCs with the given semantics
+// https://tools.ietf.org/html/rfc5576#section-4.2
+func (d *MediaDescription) WithSSRCGroup(semantics string, ssrcs ...uint32) *MediaDescription {
+	value := semantics
+	for _, ssrc := range ssrcs {
+		value += " " + strconv.FormatUint(uint64(ssrc), 10)
+	}
+	return d.WithValueAttribute(AttrKeySsrcGroup, value)
+}
+
+// WithExtMap adds an a=extmap line mapping the RTP header extension to the id
+// https://tools.ietf.org/html/rfc8285#section-8
+func (d *MediaDescription) WithExtMap(id uint8, uri string) *MediaDescription {
+	return d.WithValueAttribute(AttrKeyExtMap, fmt.Sprintf("%d %s", id, uri))
+}
+
+// WithBundleOnly marks the media description to be accepted only if it is
+// bundled, it has port zero and no candidates of its own
+// https://tools.ietf.org/html/draft-ietf-mmusic-sdp-bundle-negotiation-54#section-6
+func (d *MediaDescription) WithBundleOnly() *MediaDescription {
+	d.MediaName.Port = RangedPort{Value: 0}
+
+	attributes := d.Attributes[:0]
+	for _, a := range d.Attributes {
+		if !strings.HasPrefix(*a.String(), AttrKeyCandidate+":") && *a.String() != AttrKeyEndOfCandidates {
+			attributes = append(attributes, a)
+		}
+	}
+	d.Attributes = attributes
+	return d.WithPropertyAttribute(AttrKeyBundleOnly)
+}
+
 // WithCandidate adds an ICE candidate to the media description
 func (d *MediaDescription) WithCandidate(value string) *MediaDescription {
-	return d.WithValueAttribute("candidate", value)
+	return d.WithValueAttribute(AttrKeyCandidate, value)
 }
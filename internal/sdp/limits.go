@@ -0,0 +1,70 @@
+package sdp
+
+import (
+	"github.com/pkg/errors"
+	"strings"
+)
+
+var (
+	// ErrSizeLimit indicates that a session description exceeds Limits.MaxSize
+	ErrSizeLimit = errors.New("sdp: session description exceeds size limit")
+
+	// ErrLineLengthLimit indicates that a line of a session description
+	// exceeds Limits.MaxLineLength
+	ErrLineLengthLimit = errors.New("sdp: line exceeds length limit")
+
+	// ErrAttributeLimit indicates that a session description has more
+	// attributes than Limits.MaxAttributes
+	ErrAttributeLimit = errors.New("sdp: attribute count exceeds limit")
+)
+
+// Limits bounds the session descriptions accepted by UnmarshalWithLimits,
+// a zero field disables its limit
+type Limits struct {
+	// MaxSize is the maximum size of the session description in bytes
+	MaxSize int
+	// MaxLineLength is the maximum length of a line in bytes, excluding
+	// the line ending
+	MaxLineLength int
+	// MaxAttributes is the maximum amount of a= lines, counting both
+	// session and media attributes
+	MaxAttributes int
+}
+
+// UnmarshalWithLimits deserializes the session description like Unmarshal,
+// after checking it doesn't exceed the limits. This protects from
+// pathological descriptions received from untrusted peers.
+func (s *SessionDescription) UnmarshalWithLimits(value string, limits Limits) error {
+	if err := limits.check(value); err != nil {
+		return err
+	}
+	return s.Unmarshal(value)
+}
+
+func (l Limits) check(value string) error {
+	if l.MaxSize > 0 && len(value) > l.MaxSize {
+		return ErrSizeLimit
+	}
+
+	attributes := 0
+	for len(value) > 0 {
+		line := value
+		if i := strings.IndexByte(value, '\n'); i != -1 {
+			line, value = value[:i], value[i+1:]
+		} else {
+			value = ""
+		}
+
+		if l.MaxLineLength > 0 && len(strings.TrimSuffix(line, "\r")) > l.MaxLineLength {
+			return ErrLineLengthLimit
+		}
+		if strings.HasPrefix(line, "a=") {
+			attributes++
+		}
+	}
+
+	if l.MaxAttributes > 0 && attributes > l.MaxAttributes {
+		return ErrAttributeLimit
+	}
+	return nil
+}
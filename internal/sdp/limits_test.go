@@ -0,0 +1,28 @@
+package sdp
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUnmarshalWithLimits(t *testing.T) {
+	attributes := strings.Count(CanonicalUnmarshalSDP, "\na=")
+
+	testCases := []struct {
+		limits      Limits
+		expectedErr error
+	}{
+		{Limits{}, nil},
+		{Limits{MaxSize: len(CanonicalUnmarshalSDP), MaxLineLength: 256, MaxAttributes: attributes}, nil},
+		{Limits{MaxSize: len(CanonicalUnmarshalSDP) - 1}, ErrSizeLimit},
+		{Limits{MaxLineLength: 16}, ErrLineLengthLimit},
+		{Limits{MaxAttributes: attributes - 1}, ErrAttributeLimit},
+	}
+
+	for i, testCase := range testCases {
+		sd := &SessionDescription{}
+		assert.Equal(t, testCase.expectedErr, sd.UnmarshalWithLimits(CanonicalUnmarshalSDP, testCase.limits), "testCase: %d", i)
+	}
+}
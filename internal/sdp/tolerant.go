@@ -0,0 +1,114 @@
+package sdp
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// sessionLineOrder and mediaLineOrder rank the types of the lines of the
+// session and of media sections in the order of the grammar. Repeat times
+// share the rank of the time they follow.
+// https://tools.ietf.org/html/rfc4566#section-5
+var (
+	sessionLineOrder = map[byte]int{
+		'v': 0, 'o': 1, 's': 2, 'i': 3, 'u': 4, 'e': 5, 'p': 6,
+		'c': 7, 'b': 8, 't': 9, 'r': 9, 'z': 10, 'k': 11, 'a': 12,
+	}
+	mediaLineOrder = map[byte]int{
+		'm': 0, 'i': 1, 'c': 2, 'b': 3, 'k': 4, 'a': 5,
+	}
+)
+
+// UnmarshalTolerant deserializes the session description like
+// UnmarshalWithLimits, after repairing the deviations from the grammar
+// common with SIP gateways, see Normalize. The limits apply to the
+// description as it was received. A warning describes every deviation.
+func (s *SessionDescription) UnmarshalTolerant(value string, limits Limits) (warnings []string, err error) {
+	if err := limits.check(value); err != nil {
+		return nil, err
+	}
+
+	value, warnings = Normalize(value)
+	return warnings, s.Unmarshal(value)
+}
+
+// Normalize rewrites the session description into the form Unmarshal
+// accepts, returning a warning for every deviation it repaired. Lines may
+// end with a bare LF or CR, be surrounded by whitespace or be empty, and
+// may be out of the order of the grammar within the session or a media
+// section, like c= following the a= lines of a media section. Malformed
+// lines and lines of unknown types are dropped.
+func Normalize(value string) (normalized string, warnings []string) {
+	warn := func(format string, a ...interface{}) {
+		warnings = append(warnings, fmt.Sprintf(format, a...))
+	}
+
+	if strings.Count(value, "\n") != strings.Count(value, "\r\n") ||
+		strings.Count(value, "\r") != strings.Count(value, "\r\n") {
+		warn("lines are not terminated with CRLF")
+	}
+	value = strings.Replace(value, "\r\n", "\n", -1)
+	value = strings.Replace(value, "\r", "\n", -1)
+
+	var sections [][]string
+	inMedia, trimmed, empty := false, false, false
+	for _, line := range strings.Split(strings.TrimSuffix(value, "\n"), "\n") {
+		if t := strings.TrimSpace(line); t != line {
+			line, trimmed = t, true
+		}
+		if line == "" {
+			empty = true
+			continue
+		}
+		if len(line) < 2 || line[1] != '=' {
+			warn("dropped malformed line `%s`", line)
+			continue
+		}
+
+		inMedia = inMedia || line[0] == 'm'
+		order := sessionLineOrder
+		if inMedia {
+			order = mediaLineOrder
+		}
+		if _, ok := order[line[0]]; !ok {
+			warn("dropped line of unknown type `%s`", line)
+			continue
+		}
+
+		if len(sections) == 0 || line[0] == 'm' {
+			sections = append(sections, nil)
+		}
+		sections[len(sections)-1] = append(sections[len(sections)-1], line)
+	}
+	if trimmed {
+		warn("lines are surrounded by whitespace")
+	}
+	if empty {
+		warn("dropped empty lines")
+	}
+
+	var b strings.Builder
+	for _, section := range sections {
+		order := sessionLineOrder
+		if section[0][0] == 'm' {
+			order = mediaLineOrder
+		}
+
+		less := func(i, j int) bool { return order[section[i][0]] < order[section[j][0]] }
+		if !sort.SliceIsSorted(section, less) {
+			sort.SliceStable(section, less)
+			if section[0][0] == 'm' {
+				warn("reordered the lines of the media section `%s`", section[0])
+			} else {
+				warn("reordered the lines of the session")
+			}
+		}
+
+		for _, line := range section {
+			b.WriteString(line)
+			b.WriteString("\r\n")
+		}
+	}
+	return b.String(), warnings
+}
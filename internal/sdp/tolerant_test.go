@@ -0,0 +1,78 @@
+package sdp
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalize(t *testing.T) {
+	testCases := []struct {
+		value      string
+		normalized string
+		warnings   []string
+	}{
+		{
+			"v=0\r\no=- 1 1 IN IP4 0.0.0.0\r\ns=-\r\nt=0 0\r\n",
+			"v=0\r\no=- 1 1 IN IP4 0.0.0.0\r\ns=-\r\nt=0 0\r\n",
+			nil,
+		},
+		{
+			"v=0\no=- 1 1 IN IP4 0.0.0.0\rs=-\r\nt=0 0",
+			"v=0\r\no=- 1 1 IN IP4 0.0.0.0\r\ns=-\r\nt=0 0\r\n",
+			[]string{"lines are not terminated with CRLF"},
+		},
+		{
+			"v=0\r\no=- 1 1 IN IP4 0.0.0.0\r\ns=-\r\n\r\nt=0 0 \r\n",
+			"v=0\r\no=- 1 1 IN IP4 0.0.0.0\r\ns=-\r\nt=0 0\r\n",
+			[]string{"lines are surrounded by whitespace", "dropped empty lines"},
+		},
+		{
+			"v=0\r\no=- 1 1 IN IP4 0.0.0.0\r\ns=-\r\na=ice-lite\r\nt=0 0\r\nr=7d 1h 0\r\n" +
+				"m=audio 9 RTP/SAVPF 0\r\na=rtpmap:0 PCMU/8000\r\nc=IN IP4 192.0.2.1\r\n" +
+				"m=video 9 RTP/SAVPF 96\r\nb=AS:500\r\n",
+			"v=0\r\no=- 1 1 IN IP4 0.0.0.0\r\ns=-\r\nt=0 0\r\nr=7d 1h 0\r\na=ice-lite\r\n" +
+				"m=audio 9 RTP/SAVPF 0\r\nc=IN IP4 192.0.2.1\r\na=rtpmap:0 PCMU/8000\r\n" +
+				"m=video 9 RTP/SAVPF 96\r\nb=AS:500\r\n",
+			[]string{
+				"reordered the lines of the session",
+				"reordered the lines of the media section `m=audio 9 RTP/SAVPF 0`",
+			},
+		},
+		{
+			"v=0\r\no=- 1 1 IN IP4 0.0.0.0\r\ns=-\r\nt=0 0\r\ny=1234\r\n" +
+				"m=audio 9 RTP/SAVPF 0\r\nu=http://example.com\r\ngarbage\r\n",
+			"v=0\r\no=- 1 1 IN IP4 0.0.0.0\r\ns=-\r\nt=0 0\r\n" +
+				"m=audio 9 RTP/SAVPF 0\r\n",
+			[]string{
+				"dropped line of unknown type `y=1234`",
+				"dropped line of unknown type `u=http://example.com`",
+				"dropped malformed line `garbage`",
+			},
+		},
+	}
+
+	for i, testCase := range testCases {
+		normalized, warnings := Normalize(testCase.value)
+		assert.Equal(t, testCase.normalized, normalized, "testCase: %d", i)
+		assert.Equal(t, testCase.warnings, warnings, "testCase: %d", i)
+	}
+}
+
+func TestSessionDescription_UnmarshalTolerant(t *testing.T) {
+	value := "v=0\no=- 1 1 IN IP4 0.0.0.0\ns=-\nt=0 0\n" +
+		"m=audio 9 RTP/SAVPF 0\na=rtpmap:0 PCMU/8000\nc=IN IP4 192.0.2.1\n"
+
+	assert.NotNil(t, (&SessionDescription{}).Unmarshal(value))
+
+	s := &SessionDescription{}
+	warnings, err := s.UnmarshalTolerant(value, Limits{})
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(warnings))
+	assert.Equal(t, 1, len(s.MediaDescriptions))
+	assert.Equal(t, "192.0.2.1", s.MediaDescriptions[0].ConnectionInformation.Address.IP.String())
+
+	// The limits apply to the description as it was received
+	_, err = (&SessionDescription{}).UnmarshalTolerant(value+"\n\n\n", Limits{MaxSize: len(value)})
+	assert.Equal(t, ErrSizeLimit, err)
+}
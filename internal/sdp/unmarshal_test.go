@@ -64,7 +64,8 @@ const (
 
 	MediaNameSDP = TimingSDP +
 		"m=video 51372 RTP/AVP 99\r\n" +
-		"m=audio 54400 RTP/SAVPF 0 96\r\n"
+		"m=audio 54400 RTP/SAVPF 0 96\r\n" +
+		"m=application 9 UDP/DTLS/SCTP webrtc-datachannel\r\n"
 
 	MediaTitleSDP = MediaNameSDP +
 		"i=Vivamus a posuere nisl\r\n"
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"io"
 	"math/rand"
+	"net"
 	"strconv"
 	"strings"
 
@@ -45,6 +46,21 @@ func (t ConnectionRole) String() string {
 	}
 }
 
+func newConnectionRole(raw string) ConnectionRole {
+	switch raw {
+	case "active":
+		return ConnectionRoleActive
+	case "passive":
+		return ConnectionRolePassive
+	case "actpass":
+		return ConnectionRoleActpass
+	case "holdconn":
+		return ConnectionRoleHoldconn
+	default:
+		return ConnectionRole(0)
+	}
+}
+
 func newSessionID() uint64 {
 	r := rand.New(rand.NewSource(time.Now().UnixNano()))
 	return uint64(r.Uint32()*2) >> 2
@@ -57,6 +73,10 @@ type Codec struct {
 	ClockRate          uint32
 	EncodingParameters string
 	Fmtp               string
+
+	// RTCPFeedback holds the a=rtcp-fb values of the payload type and of
+	// the wildcard, like "nack pli"
+	RTCPFeedback []string
 }
 
 func (c Codec) String() string {
@@ -71,45 +91,536 @@ func (s *SessionDescription) GetCodecForPayloadType(payloadType uint8) (Codec, e
 
 	found := false
 	payloadTypeString := strconv.Itoa(int(payloadType))
-	rtpmapPrefix := "rtpmap:" + payloadTypeString
-	fmtpPrefix := "fmtp:" + payloadTypeString
+	rtcpFbPrefixes := []string{payloadTypeString + " ", "* "}
 
 	for _, m := range s.MediaDescriptions {
 		for _, a := range m.Attributes {
-			if strings.HasPrefix(*a.String(), rtpmapPrefix) {
+			if rtpMap, ok := a.RTPMap(); ok && rtpMap.PayloadType == payloadType {
 				found = true
-				// a=rtpmap:<payload type> <encoding name>/<clock rate> [/<encoding parameters>]
-				split := strings.Split(*a.String(), " ")
-				if len(split) == 2 {
-					split = strings.Split(split[1], "/")
-					codec.Name = split[0]
-					parts := len(split)
-					if parts > 1 {
-						rate, err := strconv.Atoi(split[1])
-						if err != nil {
-							return codec, err
-						}
-						codec.ClockRate = uint32(rate)
-					}
-					if parts > 2 {
-						codec.EncodingParameters = split[2]
+				codec.Name = rtpMap.EncodingName
+				codec.ClockRate = rtpMap.ClockRate
+				codec.EncodingParameters = rtpMap.EncodingParameters
+			} else if fmtp, ok := a.Fmtp(); ok && fmtp.PayloadType == payloadType {
+				codec.Fmtp = fmtp.Parameters
+			} else if value, ok := a.valueOf(AttrKeyRtcpFb); ok {
+				// a=rtcp-fb:<payload type> <type> [<parameter>]
+				for _, prefix := range rtcpFbPrefixes {
+					if strings.HasPrefix(value, prefix) {
+						codec.RTCPFeedback = append(codec.RTCPFeedback, strings.TrimSpace(value[len(prefix):]))
 					}
 				}
-			} else if strings.HasPrefix(*a.String(), fmtpPrefix) {
-				// a=fmtp:<format> <format specific parameters>
-				split := strings.Split(*a.String(), " ")
-				if len(split) == 2 {
-					codec.Fmtp = split[1]
-				}
 			}
 		}
 		if found {
 			return codec, nil
 		}
+		codec.RTCPFeedback = nil
 	}
 	return codec, errors.New("payload type not found")
 }
 
+// GetBundleGroup returns the mids of the media sections bundled by the
+// a=group:BUNDLE attribute, ok is false if the description has none
+// https://tools.ietf.org/html/draft-ietf-mmusic-sdp-bundle-negotiation-54#section-7.1
+func (s *SessionDescription) GetBundleGroup() (mids []string, ok bool) {
+	for _, a := range s.Attributes {
+		value, ok := a.valueOf(AttrKeyGroup)
+		if !ok {
+			continue
+		}
+		// a=group:<semantics> <identification-tag> ...
+		fields := strings.Fields(value)
+		if len(fields) > 0 && fields[0] == SemanticTokenBundle {
+			return fields[1:], true
+		}
+	}
+	return nil, false
+}
+
+// GetSSRCGroups returns the SSRCs of every a=ssrc-group with the given semantics
+func (s *SessionDescription) GetSSRCGroups(semantics string) [][]uint32 {
+	var groups [][]uint32
+	for _, m := range s.MediaDescriptions {
+		for _, a := range m.Attributes {
+			if group, ok := a.SSRCGroup(); ok && group.Semantics == semantics {
+				groups = append(groups, group.SSRCs)
+			}
+		}
+	}
+	return groups
+}
+
+// MediaSource is an SSRC announced with a=ssrc lines, along with the media
+// section it belongs to and the stream and track it carries
+// https://tools.ietf.org/html/rfc5576#section-4.1
+type MediaSource struct {
+	SSRC        uint32
+	Mid         string
+	StreamLabel string
+	Label       string
+}
+
+// GetMediaSources returns every SSRC announced with a=ssrc in order of
+// appearance. The stream and track are taken from its msid attribute, the
+// deprecated mslabel and label attributes or the a=msid line of its media
+// section, whichever is present first.
+func (s *SessionDescription) GetMediaSources() []MediaSource {
+	var sources []MediaSource
+	for _, m := range s.MediaDescriptions {
+		var section []MediaSource
+		indexes := make(map[uint32]int)
+		mid, streamLabel, label := "", "", ""
+		for _, a := range m.Attributes {
+			if value, ok := a.Mid(); ok {
+				mid = value
+				continue
+			} else if value, ok := a.valueOf(AttrKeyMsid); ok {
+				// a=msid:<stream id> <track id>
+				fields := strings.Fields(value)
+				if len(fields) == 2 {
+					streamLabel, label = fields[0], fields[1]
+				}
+				continue
+			}
+
+			ssrc, ok := a.SSRC()
+			if !ok {
+				continue
+			}
+			i, ok := indexes[ssrc.SSRC]
+			if !ok {
+				i = len(section)
+				indexes[ssrc.SSRC] = i
+				section = append(section, MediaSource{SSRC: ssrc.SSRC})
+			}
+
+			source := &section[i]
+			switch {
+			case ssrc.Attribute == AttrKeyMsid:
+				msid := strings.Fields(ssrc.Value)
+				if len(msid) == 2 {
+					source.StreamLabel, source.Label = msid[0], msid[1]
+				}
+			case ssrc.Attribute == "mslabel" && source.StreamLabel == "":
+				source.StreamLabel = ssrc.Value
+			case ssrc.Attribute == "label" && source.Label == "":
+				source.Label = ssrc.Value
+			}
+		}
+
+		for i := range section {
+			section[i].Mid = mid
+			if section[i].StreamLabel == "" && section[i].Label == "" {
+				section[i].StreamLabel, section[i].Label = streamLabel, label
+			}
+		}
+		sources = append(sources, section...)
+	}
+	return sources
+}
+
+// GetMidForPayloadType returns the mid of the first media section offering
+// the payload type, ok is false if there is none
+func (s *SessionDescription) GetMidForPayloadType(payloadType uint8) (mid string, ok bool) {
+	for _, m := range s.MediaDescriptions {
+		for _, format := range m.MediaName.Formats {
+			if format == strconv.Itoa(int(payloadType)) {
+				return m.Mid(), true
+			}
+		}
+	}
+	return "", false
+}
+
+// GetMediaSourceForMid returns the track of the media section with the mid
+// as announced by its a=msid line, for streams that were not announced with
+// a=ssrc lines. The SSRC of the returned source is left zero.
+func (s *SessionDescription) GetMediaSourceForMid(mid string) (source MediaSource, ok bool) {
+	for _, m := range s.MediaDescriptions {
+		if m.Mid() != mid {
+			continue
+		}
+		for _, a := range m.Attributes {
+			value, ok := a.valueOf(AttrKeyMsid)
+			if !ok {
+				continue
+			}
+			// a=msid:<stream id> <track id>
+			fields := strings.Fields(value)
+			if len(fields) == 2 {
+				return MediaSource{Mid: mid, StreamLabel: fields[0], Label: fields[1]}, true
+			}
+		}
+		return MediaSource{Mid: mid}, true
+	}
+	return MediaSource{}, false
+}
+
+// GetMediaForMid returns the media section with the mid, ok is false if
+// there is none
+func (s *SessionDescription) GetMediaForMid(mid string) (m *MediaDescription, ok bool) {
+	for _, m := range s.MediaDescriptions {
+		if m.Mid() == mid {
+			return m, true
+		}
+	}
+	return nil, false
+}
+
+// GetCodecsForMedia returns the codecs of every media section of the media
+// type, like audio or video, in order of preference. Payload types offered
+// by several sections are returned once.
+func (s *SessionDescription) GetCodecsForMedia(media string) []Codec {
+	var codecs []Codec
+	seen := make(map[uint64]bool)
+	for _, m := range s.MediaDescriptions {
+		if m.MediaName.Media != media {
+			continue
+		}
+
+		section := &SessionDescription{MediaDescriptions: []*MediaDescription{m}}
+		for _, raw := range m.MediaName.Formats {
+			format, err := strconv.ParseUint(raw, 10, 7)
+			if err != nil || seen[format] {
+				continue
+			}
+			if codec, err := section.GetCodecForPayloadType(uint8(format)); err == nil {
+				seen[format] = true
+				codecs = append(codecs, codec)
+			}
+		}
+	}
+	return codecs
+}
+
+// GetExtMapURIsForMedia returns the URIs of the RTP header extensions mapped
+// by a=extmap lines in the media sections of the media type
+func (s *SessionDescription) GetExtMapURIsForMedia(media string) []string {
+	var uris []string
+	seen := make(map[string]bool)
+	for _, m := range s.MediaDescriptions {
+		if m.MediaName.Media != media {
+			continue
+		}
+
+		for _, a := range m.Attributes {
+			extMap, ok := a.ExtMap()
+			if !ok || seen[extMap.URI] {
+				continue
+			}
+			seen[extMap.URI] = true
+			uris = append(uris, extMap.URI)
+		}
+	}
+	return uris
+}
+
+// GetICECredentials returns the ufrag and password of the ICE session of the
+// media section, sections without a=ice-ufrag and a=ice-pwd lines use the
+// ones of the session level
+// https://tools.ietf.org/html/draft-ietf-mmusic-ice-sip-sdp-21#section-5.4
+func (s *SessionDescription) GetICECredentials(m *MediaDescription) (ufrag, pwd string) {
+	for _, attributes := range [][]Attribute{m.Attributes, s.Attributes} {
+		for _, a := range attributes {
+			if value, ok := a.valueOf(AttrKeyICEUfrag); ok && ufrag == "" {
+				ufrag = value
+			} else if value, ok := a.valueOf(AttrKeyICEPwd); ok && pwd == "" {
+				pwd = value
+			}
+		}
+	}
+	return ufrag, pwd
+}
+
+// GetConnectionRole returns the role of the a=setup attribute of the first
+// media section which has one, every section is bundled on a single DTLS
+// transport. The session level attribute applies to sections without one,
+// ok is false if there is none at all.
+// https://tools.ietf.org/html/rfc4145#section-4
+func (s *SessionDescription) GetConnectionRole() (role ConnectionRole, ok bool) {
+	attributes := [][]Attribute{}
+	for _, m := range s.MediaDescriptions {
+		attributes = append(attributes, m.Attributes)
+	}
+	attributes = append(attributes, s.Attributes)
+
+	for _, sectionAttributes := range attributes {
+		for _, a := range sectionAttributes {
+			if value, ok := a.valueOf(AttrKeyConnectionSetup); ok {
+				if role = newConnectionRole(value); role != ConnectionRole(0) {
+					return role, true
+				}
+			}
+		}
+	}
+	return ConnectionRole(0), false
+}
+
+// Fingerprint is the hash of the certificate of a DTLS session, as announced
+// by an a=fingerprint attribute
+// https://tools.ietf.org/html/rfc8122#section-5
+type Fingerprint struct {
+	Algorithm string
+	Value     string
+}
+
+// GetFingerprints returns the fingerprints of the first media section which
+// has a=fingerprint attributes, every section is bundled on a single DTLS
+// transport. The session level attributes apply to sections without any.
+func (s *SessionDescription) GetFingerprints() []Fingerprint {
+	attributes := [][]Attribute{}
+	for _, m := range s.MediaDescriptions {
+		attributes = append(attributes, m.Attributes)
+	}
+	attributes = append(attributes, s.Attributes)
+
+	for _, sectionAttributes := range attributes {
+		var fingerprints []Fingerprint
+		for _, a := range sectionAttributes {
+			if fingerprint, ok := a.Fingerprint(); ok {
+				fingerprints = append(fingerprints, fingerprint)
+			}
+		}
+		if fingerprints != nil {
+			return fingerprints
+		}
+	}
+	return nil
+}
+
+// GetExtMapID returns the id the RTP header extension is mapped to by an
+// a=extmap line, ok is false if the extension is not negotiated
+// https://tools.ietf.org/html/rfc8285#section-8
+func (s *SessionDescription) GetExtMapID(uri string) (id uint8, ok bool) {
+	for _, m := range s.MediaDescriptions {
+		for _, a := range m.Attributes {
+			if extMap, ok := a.ExtMap(); ok && extMap.URI == uri {
+				return extMap.Value, true
+			}
+		}
+	}
+	return 0, false
+}
+
+// GetSCTPStreams returns the number of SCTP streams announced by the
+// a=sctpmap line of the data channel section, ok is false if none is
+// announced
+// https://tools.ietf.org/html/draft-ietf-mmusic-sctp-sdp-05#section-5
+func (s *SessionDescription) GetSCTPStreams() (streams uint16, ok bool) {
+	for _, m := range s.MediaDescriptions {
+		for _, a := range m.Attributes {
+			// a=sctpmap:<sctpmap-number> <app> [<streams>]
+			value, ok := a.valueOf(AttrKeySCTPMap)
+			if !ok {
+				continue
+			}
+			fields := strings.Fields(value)
+			if len(fields) != 3 {
+				continue
+			}
+			if streams, err := strconv.ParseUint(fields[2], 10, 16); err == nil {
+				return uint16(streams), true
+			}
+		}
+	}
+	return 0, false
+}
+
+// GetDataMedia returns the data channel section, the application section
+// carrying SCTP, ok is false if there is none
+func (s *SessionDescription) GetDataMedia() (m *MediaDescription, ok bool) {
+	for _, m := range s.MediaDescriptions {
+		if m.MediaName.Media == "application" && indexOf("SCTP", m.MediaName.Protos) != -1 {
+			return m, true
+		}
+	}
+	return nil, false
+}
+
+// UsesSCTPPort reports whether the data channel section takes the format of
+// draft-ietf-mmusic-sctp-sdp-26, with the webrtc-datachannel format and the
+// SCTP port announced by a=sctp-port, rather than the legacy format with the
+// SCTP port as format and a=sctpmap
+// https://tools.ietf.org/html/draft-ietf-mmusic-sctp-sdp-26#section-4
+func (s *SessionDescription) UsesSCTPPort() bool {
+	m, ok := s.GetDataMedia()
+	return ok && indexOf(FormatWebRTCDataChannel, m.MediaName.Formats) != -1
+}
+
+// GetSCTPPort returns the SCTP port of the data channel section, announced by
+// a=sctp-port or by the format of the legacy format. ok is false if the
+// description has no data channel section or the port is malformed.
+func (s *SessionDescription) GetSCTPPort() (port uint16, ok bool) {
+	m, ok := s.GetDataMedia()
+	if !ok {
+		return 0, false
+	}
+
+	for _, a := range m.Attributes {
+		if value, ok := a.valueOf(AttrKeySCTPPort); ok {
+			port, err := strconv.ParseUint(value, 10, 16)
+			if err != nil {
+				return 0, false
+			}
+			return uint16(port), true
+		}
+	}
+	for _, format := range m.MediaName.Formats {
+		if port, err := strconv.ParseUint(format, 10, 16); err == nil {
+			return uint16(port), true
+		}
+	}
+	return 0, false
+}
+
+// GetMaxMessageSize returns the size of the largest message the data
+// channels of the remote peer receive, announced by a=max-message-size, ok is
+// false if none is announced. A size of 0 means messages of any size.
+// https://tools.ietf.org/html/draft-ietf-mmusic-sctp-sdp-26#section-6
+func (s *SessionDescription) GetMaxMessageSize() (size uint64, ok bool) {
+	for _, m := range s.MediaDescriptions {
+		for _, a := range m.Attributes {
+			value, ok := a.valueOf(AttrKeyMaxMessageSize)
+			if !ok {
+				continue
+			}
+			if size, err := strconv.ParseUint(value, 10, 64); err == nil {
+				return size, true
+			}
+		}
+	}
+	return 0, false
+}
+
+// GetRTCPAddress returns where the remote peer receives RTCP if it doesn't
+// multiplex RTCP with RTP, ok is false if every audio and video section
+// carries a=rtcp-mux. The address is announced by a=rtcp, or defaults to the
+// port following the RTP port, on the IP of the c= line of the section or
+// the session. The IP is nil if the description doesn't specify one.
+// https://tools.ietf.org/html/rfc3605#section-2.1
+func (s *SessionDescription) GetRTCPAddress() (addr *net.UDPAddr, ok bool) {
+	for _, m := range s.MediaDescriptions {
+		if m.MediaName.Media != "audio" && m.MediaName.Media != "video" {
+			continue
+		}
+
+		muxed := false
+		addr = &net.UDPAddr{Port: m.MediaName.Port.Value + 1}
+		for _, c := range []*ConnectionInformation{s.ConnectionInformation, m.ConnectionInformation} {
+			if c != nil && c.Address != nil {
+				addr.IP = c.Address.IP
+			}
+		}
+		for _, a := range m.Attributes {
+			if *a.String() == AttrKeyRtcpMux {
+				muxed = true
+			} else if value, ok := a.valueOf(AttrKeyRtcp); ok {
+				// a=rtcp:<port> [<nettype> <addrtype> <connection-address>]
+				fields := strings.Fields(value)
+				if len(fields) == 0 {
+					continue
+				}
+				if port, err := strconv.ParseUint(fields[0], 10, 16); err == nil {
+					addr.Port = int(port)
+				}
+				if len(fields) == 4 {
+					addr.IP = net.ParseIP(fields[3])
+				}
+			}
+		}
+		if muxed {
+			continue
+		}
+
+		if addr.IP != nil && addr.IP.IsUnspecified() {
+			addr.IP = nil
+		}
+		return addr, true
+	}
+	return nil, false
+}
+
+// IsICELite reports whether the ICE agent of the peer is a lite
+// implementation, announced by the session-level a=ice-lite
+// https://tools.ietf.org/html/draft-ietf-mmusic-ice-sip-sdp-24#section-4.2.3
+func (s *SessionDescription) IsICELite() bool {
+	for _, a := range s.Attributes {
+		if *a.String() == AttrKeyICELite {
+			return true
+		}
+	}
+	return false
+}
+
+// HasRTCPReducedSize reports whether the remote peer accepts reduced-size
+// RTCP, which requires every audio and video section to carry
+// a=rtcp-rsize
+// https://tools.ietf.org/html/rfc5506#section-5
+func (s *SessionDescription) HasRTCPReducedSize() bool {
+	found := false
+	for _, m := range s.MediaDescriptions {
+		if m.MediaName.Media != "audio" && m.MediaName.Media != "video" {
+			continue
+		}
+
+		reducedSize := false
+		for _, a := range m.Attributes {
+			if *a.String() == AttrKeyRtcpRsize {
+				reducedSize = true
+			}
+		}
+		if !reducedSize {
+			return false
+		}
+		found = true
+	}
+	return found
+}
+
+// GetPayloadTypesForCodec returns the payload types mapped to the codec name by a=rtpmap
+func (s *SessionDescription) GetPayloadTypesForCodec(name string) []uint8 {
+	var payloadTypes []uint8
+	for _, m := range s.MediaDescriptions {
+		for _, a := range m.Attributes {
+			if rtpMap, ok := a.RTPMap(); ok && strings.EqualFold(rtpMap.EncodingName, name) {
+				payloadTypes = append(payloadTypes, rtpMap.PayloadType)
+			}
+		}
+	}
+	return payloadTypes
+}
+
+// GetRTXPayloadTypes returns the payload type of every rtx codec mapped to
+// the payload type it is associated with by its apt parameter
+// https://tools.ietf.org/html/rfc4588#section-8.6
+func (s *SessionDescription) GetRTXPayloadTypes() map[uint8]uint8 {
+	payloadTypes := make(map[uint8]uint8)
+	for _, m := range s.MediaDescriptions {
+		rtx := make(map[uint8]bool)
+		for _, a := range m.Attributes {
+			if rtpMap, ok := a.RTPMap(); ok && strings.EqualFold(rtpMap.EncodingName, "rtx") {
+				rtx[rtpMap.PayloadType] = true
+			}
+		}
+
+		for _, a := range m.Attributes {
+			// a=fmtp:<payload type> apt=<associated payload type>
+			fmtp, ok := a.Fmtp()
+			if !ok || !rtx[fmtp.PayloadType] {
+				continue
+			}
+			value, ok := fmtp.Parameter("apt")
+			if !ok {
+				continue
+			}
+			if apt, err := strconv.ParseUint(value, 10, 7); err == nil {
+				payloadTypes[fmtp.PayloadType] = uint8(apt)
+			}
+		}
+	}
+	return payloadTypes
+}
+
 type lexer struct {
 	desc  *SessionDescription
 	input *bufio.Reader
@@ -0,0 +1,395 @@
+package sdp
+
+import (
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSessionDescription_Repair(t *testing.T) {
+	media := NewJSEPMediaDescription("video", []string{}).
+		WithCodec(96, "VP8", 90000, 0, "").
+		WithCodec(97, "rtx", 90000, 0, "apt=96").
+		WithCodec(100, "H264", 90000, 0, "packetization-mode=1").
+		WithCodec(125, "ulpfec", 90000, 0, "").
+		WithSSRCGroup(SemanticTokenFlowIdentification, 1000, 2000).
+		WithSSRCGroup(SemanticTokenSimulcast, 1000, 3000, 4000).
+		WithValueAttribute(AttrKeyExtMap, "4/recvonly "+ExtMapURIMID)
+	s := (&SessionDescription{}).WithMedia(media)
+
+	assert.Equal(t, map[uint8]uint8{97: 96}, s.GetRTXPayloadTypes())
+	assert.Equal(t, []uint8{125}, s.GetPayloadTypesForCodec("ulpfec"))
+	assert.Equal(t, [][]uint32{{1000, 2000}}, s.GetSSRCGroups(SemanticTokenFlowIdentification))
+	assert.Equal(t, [][]uint32{{1000, 3000, 4000}}, s.GetSSRCGroups(SemanticTokenSimulcast))
+
+	id, ok := s.GetExtMapID(ExtMapURIMID)
+	assert.True(t, ok)
+	assert.Equal(t, uint8(4), id)
+	_, ok = s.GetExtMapID("urn:ietf:params:rtp-hdrext:sdes:rtp-stream-id")
+	assert.False(t, ok)
+}
+
+func TestSessionDescription_GetMediaSources(t *testing.T) {
+	planB := NewJSEPMediaDescription("video", []string{}).
+		WithValueAttribute(AttrKeyMID, "video").
+		WithMediaSource(1000, "cname", "stream", "camera").
+		WithValueAttribute(AttrKeySsrc, "2000 cname:cname").
+		WithValueAttribute(AttrKeySsrc, "2000 mslabel:stream").
+		WithValueAttribute(AttrKeySsrc, "2000 label:screen")
+	unified := NewJSEPMediaDescription("audio", []string{}).
+		WithValueAttribute(AttrKeyMID, "0").
+		WithValueAttribute(AttrKeyMsid, "stream microphone").
+		WithValueAttribute(AttrKeySsrc, "3000 cname:cname")
+	s := (&SessionDescription{}).WithMedia(planB).WithMedia(unified)
+
+	assert.Equal(t, []MediaSource{
+		{SSRC: 1000, Mid: "video", StreamLabel: "stream", Label: "camera"},
+		{SSRC: 2000, Mid: "video", StreamLabel: "stream", Label: "screen"},
+		{SSRC: 3000, Mid: "0", StreamLabel: "stream", Label: "microphone"},
+	}, s.GetMediaSources())
+}
+
+func TestSessionDescription_GetMediaSourceForMid(t *testing.T) {
+	audio := NewJSEPMediaDescription("audio", []string{}).
+		WithCodec(111, "opus", 48000, 2, "").
+		WithValueAttribute(AttrKeyMID, "0").
+		WithValueAttribute(AttrKeyMsid, "stream microphone")
+	video := NewJSEPMediaDescription("video", []string{}).
+		WithCodec(96, "VP8", 90000, 0, "").
+		WithValueAttribute(AttrKeyMID, "1")
+	s := (&SessionDescription{}).WithMedia(audio).WithMedia(video)
+
+	mid, ok := s.GetMidForPayloadType(96)
+	assert.True(t, ok)
+	assert.Equal(t, "1", mid)
+	_, ok = s.GetMidForPayloadType(100)
+	assert.False(t, ok)
+
+	source, ok := s.GetMediaSourceForMid("0")
+	assert.True(t, ok)
+	assert.Equal(t, MediaSource{Mid: "0", StreamLabel: "stream", Label: "microphone"}, source)
+	source, ok = s.GetMediaSourceForMid("1")
+	assert.True(t, ok)
+	assert.Equal(t, MediaSource{Mid: "1"}, source)
+	_, ok = s.GetMediaSourceForMid("2")
+	assert.False(t, ok)
+
+	media, ok := s.GetMediaForMid("1")
+	assert.True(t, ok)
+	assert.Equal(t, video, media)
+	_, ok = s.GetMediaForMid("2")
+	assert.False(t, ok)
+}
+
+func TestSessionDescription_GetCodecsForMedia(t *testing.T) {
+	audio := NewJSEPMediaDescription("audio", []string{}).
+		WithCodec(111, "opus", 48000, 2, "minptime=10").
+		WithCodec(0, "PCMU", 8000, 0, "").
+		WithExtMap(1, ExtMapURIMID)
+	video := NewJSEPMediaDescription("video", []string{}).
+		WithCodec(96, "VP8", 90000, 0, "").
+		WithExtMap(1, ExtMapURIMID).
+		WithExtMap(2, "urn:3gpp:video-orientation")
+	secondVideo := NewJSEPMediaDescription("video", []string{}).
+		WithCodec(96, "VP8", 90000, 0, "").
+		WithExtMap(1, ExtMapURIMID)
+	s := (&SessionDescription{}).WithMedia(audio).WithMedia(video).WithMedia(secondVideo)
+
+	assert.Equal(t, []Codec{
+		{PayloadType: 111, Name: "opus", ClockRate: 48000, EncodingParameters: "2", Fmtp: "minptime=10"},
+		{PayloadType: 0, Name: "PCMU", ClockRate: 8000},
+	}, s.GetCodecsForMedia("audio"))
+	assert.Equal(t, []Codec{
+		{PayloadType: 96, Name: "VP8", ClockRate: 90000},
+	}, s.GetCodecsForMedia("video"))
+	assert.Nil(t, s.GetCodecsForMedia("application"))
+
+	assert.Equal(t, []string{ExtMapURIMID}, s.GetExtMapURIsForMedia("audio"))
+	assert.Equal(t, []string{ExtMapURIMID, "urn:3gpp:video-orientation"}, s.GetExtMapURIsForMedia("video"))
+}
+
+func TestSessionDescription_GetCodecForPayloadType(t *testing.T) {
+	video := NewJSEPMediaDescription("video", []string{}).
+		WithCodec(9, "G722", 8000, 0, "").
+		WithCodec(96, "VP8", 90000, 0, "").
+		WithValueAttribute(AttrKeyRtcpFb, "96 nack").
+		WithValueAttribute(AttrKeyRtcpFb, "96 nack pli").
+		WithValueAttribute(AttrKeyRtcpFb, "* ccm fir")
+	s := (&SessionDescription{}).WithMedia(video)
+
+	codec, err := s.GetCodecForPayloadType(96)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"nack", "nack pli", "ccm fir"}, codec.RTCPFeedback)
+
+	// The feedback of payload type 96 doesn't apply to 9
+	codec, err = s.GetCodecForPayloadType(9)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"ccm fir"}, codec.RTCPFeedback)
+}
+
+func TestSessionDescription_GetSCTPStreams(t *testing.T) {
+	testCases := []struct {
+		attribute string
+		streams   uint16
+		ok        bool
+	}{
+		{"sctpmap:5000 webrtc-datachannel 1024", 1024, true},
+		{"sctpmap:5000 webrtc-datachannel 65535", 65535, true},
+		{"sctpmap:5000 webrtc-datachannel", 0, false},
+		{"sctpmap:5000 webrtc-datachannel 65536", 0, false},
+		{"sctp-port:5000", 0, false},
+	}
+
+	for i, testCase := range testCases {
+		media := NewJSEPMediaDescription("application", []string{}).
+			WithPropertyAttribute(testCase.attribute)
+		s := (&SessionDescription{}).WithMedia(media)
+
+		streams, ok := s.GetSCTPStreams()
+		assert.Equal(t, testCase.streams, streams, "testCase: %d", i)
+		assert.Equal(t, testCase.ok, ok, "testCase: %d", i)
+	}
+}
+
+func TestSessionDescription_GetMaxMessageSize(t *testing.T) {
+	testCases := []struct {
+		attribute string
+		size      uint64
+		ok        bool
+	}{
+		{"max-message-size:262144", 262144, true},
+		{"max-message-size:0", 0, true},
+		{"max-message-size:", 0, false},
+		{"max-message-size:-1", 0, false},
+		{"sctp-port:5000", 0, false},
+	}
+
+	for i, testCase := range testCases {
+		media := NewJSEPMediaDescription("application", []string{}).
+			WithPropertyAttribute(testCase.attribute)
+		s := (&SessionDescription{}).WithMedia(media)
+
+		size, ok := s.GetMaxMessageSize()
+		assert.Equal(t, testCase.size, size, "testCase: %d", i)
+		assert.Equal(t, testCase.ok, ok, "testCase: %d", i)
+	}
+}
+
+func TestSessionDescription_GetSCTPPort(t *testing.T) {
+	testCases := []struct {
+		protos       []string
+		formats      []string
+		attribute    string
+		port         uint16
+		ok           bool
+		usesSCTPPort bool
+	}{
+		{[]string{"UDP", "DTLS", "SCTP"}, []string{"webrtc-datachannel"}, "sctp-port:5000", 5000, true, true},
+		{[]string{"UDP", "DTLS", "SCTP"}, []string{"webrtc-datachannel"}, "sctp-port:5001", 5001, true, true},
+		{[]string{"UDP", "DTLS", "SCTP"}, []string{"webrtc-datachannel"}, "sctp-port:65536", 0, false, true},
+		{[]string{"UDP", "DTLS", "SCTP"}, []string{"webrtc-datachannel"}, "max-message-size:262144", 0, false, true},
+		{[]string{"DTLS", "SCTP"}, []string{"5000"}, "sctpmap:5000 webrtc-datachannel 1024", 5000, true, false},
+		{[]string{"UDP", "TLS", "RTP", "SAVPF"}, []string{"5000"}, "sctp-port:5000", 0, false, false},
+	}
+
+	for i, testCase := range testCases {
+		media := NewJSEPMediaDescription("application", []string{}).
+			WithPropertyAttribute(testCase.attribute)
+		media.MediaName.Protos = testCase.protos
+		media.MediaName.Formats = testCase.formats
+		s := (&SessionDescription{}).WithMedia(media)
+
+		port, ok := s.GetSCTPPort()
+		assert.Equal(t, testCase.port, port, "testCase: %d", i)
+		assert.Equal(t, testCase.ok, ok, "testCase: %d", i)
+		assert.Equal(t, testCase.usesSCTPPort, s.UsesSCTPPort(), "testCase: %d", i)
+	}
+}
+
+func TestSessionDescription_IsICELite(t *testing.T) {
+	s := &SessionDescription{}
+	assert.False(t, s.IsICELite())
+
+	// Only the session-level attribute announces a lite agent
+	s.WithMedia(NewJSEPMediaDescription("audio", []string{}).WithPropertyAttribute(AttrKeyICELite))
+	assert.False(t, s.IsICELite())
+
+	s.WithPropertyAttribute(AttrKeyICELite)
+	assert.True(t, s.IsICELite())
+}
+
+func TestSessionDescription_GetBundleGroup(t *testing.T) {
+	testCases := []struct {
+		attributes []string
+		mids       []string
+		ok         bool
+	}{
+		{[]string{"group:BUNDLE audio video data"}, []string{"audio", "video", "data"}, true},
+		{[]string{"group:LS audio video", "group:BUNDLE 0"}, []string{"0"}, true},
+		{[]string{"group:BUNDLE"}, []string{}, true},
+		{[]string{"group:LS audio video"}, nil, false},
+		{nil, nil, false},
+	}
+
+	for i, testCase := range testCases {
+		s := &SessionDescription{}
+		for _, attribute := range testCase.attributes {
+			s.WithPropertyAttribute(attribute)
+		}
+
+		mids, ok := s.GetBundleGroup()
+		assert.Equal(t, testCase.mids, mids, "testCase: %d", i)
+		assert.Equal(t, testCase.ok, ok, "testCase: %d", i)
+	}
+}
+
+func TestSessionDescription_GetICECredentials(t *testing.T) {
+	s := (&SessionDescription{}).
+		WithValueAttribute("ice-ufrag", "sessionUfrag").
+		WithValueAttribute("ice-pwd", "sessionPwd").
+		WithMedia(NewJSEPMediaDescription("audio", []string{}).WithICECredentials("audioUfrag", "audioPwd")).
+		WithMedia(NewJSEPMediaDescription("video", []string{}))
+
+	testCases := []struct {
+		media *MediaDescription
+		ufrag string
+		pwd   string
+	}{
+		{s.MediaDescriptions[0], "audioUfrag", "audioPwd"},
+		{s.MediaDescriptions[1], "sessionUfrag", "sessionPwd"},
+	}
+
+	for i, testCase := range testCases {
+		ufrag, pwd := s.GetICECredentials(testCase.media)
+		assert.Equal(t, testCase.ufrag, ufrag, "testCase: %d", i)
+		assert.Equal(t, testCase.pwd, pwd, "testCase: %d", i)
+	}
+}
+
+func TestSessionDescription_GetConnectionRole(t *testing.T) {
+	testCases := []struct {
+		s    *SessionDescription
+		role ConnectionRole
+		ok   bool
+	}{
+		{
+			(&SessionDescription{}).WithMedia(NewJSEPMediaDescription("audio", []string{}).WithValueAttribute(AttrKeyConnectionSetup, "passive")),
+			ConnectionRolePassive,
+			true,
+		},
+		{
+			(&SessionDescription{}).
+				WithValueAttribute(AttrKeyConnectionSetup, "active").
+				WithMedia(NewJSEPMediaDescription("audio", []string{})),
+			ConnectionRoleActive,
+			true,
+		},
+		{
+			(&SessionDescription{}).
+				WithMedia(NewJSEPMediaDescription("audio", []string{}).WithValueAttribute(AttrKeyConnectionSetup, "unknown")).
+				WithMedia(NewJSEPMediaDescription("video", []string{}).WithValueAttribute(AttrKeyConnectionSetup, "actpass")),
+			ConnectionRoleActpass,
+			true,
+		},
+		{
+			(&SessionDescription{}).WithMedia(NewJSEPMediaDescription("audio", []string{})),
+			ConnectionRole(0),
+			false,
+		},
+	}
+
+	for i, testCase := range testCases {
+		role, ok := testCase.s.GetConnectionRole()
+		assert.Equal(t, testCase.role, role, "testCase: %d", i)
+		assert.Equal(t, testCase.ok, ok, "testCase: %d", i)
+	}
+}
+
+func TestSessionDescription_GetFingerprints(t *testing.T) {
+	testCases := []struct {
+		s            *SessionDescription
+		fingerprints []Fingerprint
+	}{
+		{
+			(&SessionDescription{}).
+				WithValueAttribute(AttrKeyFingerprint, "sha-1 4A:AD:B9:B1:3F:82:18:3B:54:02:12:DF:3E:5D:49:6B:19:E5:7C:AB").
+				WithMedia(NewJSEPMediaDescription("audio", []string{}).
+					WithValueAttribute(AttrKeyFingerprint, "SHA-256 D7:06:10:DE").
+					WithValueAttribute(AttrKeyFingerprint, "sha-512 0F:2D")),
+			[]Fingerprint{{"sha-256", "D7:06:10:DE"}, {"sha-512", "0F:2D"}},
+		},
+		{
+			(&SessionDescription{}).
+				WithValueAttribute(AttrKeyFingerprint, "sha-256 D7:06:10:DE").
+				WithMedia(NewJSEPMediaDescription("audio", []string{})),
+			[]Fingerprint{{"sha-256", "D7:06:10:DE"}},
+		},
+		{
+			(&SessionDescription{}).
+				WithMedia(NewJSEPMediaDescription("audio", []string{}).WithValueAttribute(AttrKeyFingerprint, "malformed")),
+			nil,
+		},
+	}
+
+	for i, testCase := range testCases {
+		assert.Equal(t, testCase.fingerprints, testCase.s.GetFingerprints(), "testCase: %d", i)
+	}
+}
+
+func TestSessionDescription_GetRTCPAddress(t *testing.T) {
+	testCases := []struct {
+		attributes []string
+		addr       *net.UDPAddr
+		ok         bool
+	}{
+		{[]string{"rtcp:9 IN IP4 0.0.0.0", AttrKeyRtcpMux}, nil, false},
+		{[]string{"rtcp:5005 IN IP4 192.0.2.2"}, &net.UDPAddr{IP: net.ParseIP("192.0.2.2"), Port: 5005}, true},
+		{[]string{"rtcp:5005"}, &net.UDPAddr{IP: net.ParseIP("192.0.2.1"), Port: 5005}, true},
+		{[]string{}, &net.UDPAddr{IP: net.ParseIP("192.0.2.1"), Port: 5001}, true},
+	}
+
+	for i, testCase := range testCases {
+		media := NewJSEPMediaDescription("audio", []string{})
+		media.MediaName.Port = RangedPort{Value: 5000}
+		media.ConnectionInformation.Address.IP = net.ParseIP("192.0.2.1")
+		for _, attribute := range testCase.attributes {
+			media.WithPropertyAttribute(attribute)
+		}
+		s := (&SessionDescription{}).WithMedia(media)
+
+		addr, ok := s.GetRTCPAddress()
+		assert.Equal(t, testCase.addr, addr, "testCase: %d", i)
+		assert.Equal(t, testCase.ok, ok, "testCase: %d", i)
+	}
+
+	// Unspecified IPs are left to the selected remote candidate
+	s := (&SessionDescription{}).WithMedia(NewJSEPMediaDescription("video", []string{}))
+	addr, ok := s.GetRTCPAddress()
+	assert.True(t, ok)
+	assert.Equal(t, &net.UDPAddr{Port: 10}, addr)
+}
+
+func TestSessionDescription_HasRTCPReducedSize(t *testing.T) {
+	reducedSize := func(media string) *MediaDescription {
+		return NewJSEPMediaDescription(media, []string{}).WithPropertyAttribute(AttrKeyRtcpRsize)
+	}
+
+	testCases := []struct {
+		media    []*MediaDescription
+		expected bool
+	}{
+		{[]*MediaDescription{reducedSize("audio"), reducedSize("video")}, true},
+		{[]*MediaDescription{reducedSize("audio"), NewJSEPMediaDescription("video", []string{})}, false},
+		{[]*MediaDescription{reducedSize("video"), NewJSEPMediaDescription("application", []string{})}, true},
+		{[]*MediaDescription{NewJSEPMediaDescription("application", []string{})}, false},
+	}
+
+	for i, testCase := range testCases {
+		s := &SessionDescription{}
+		for _, m := range testCase.media {
+			s.WithMedia(m)
+		}
+		assert.Equal(t, testCase.expected, s.HasRTCPReducedSize(), "testCase: %d", i)
+	}
+}
@@ -0,0 +1,137 @@
+package srtp
+
+import (
+	"encoding/binary"
+
+	"github.com/pions/webrtc/pkg/rtp"
+)
+
+// The AEAD_AES_128_GCM and AEAD_AES_256_GCM profiles encrypt and
+// authenticate with AES-GCM in a single pass
+// https://tools.ietf.org/html/rfc7714
+
+const (
+	aeadSaltLen     = 12
+	aeadAuthTagSize = 16
+)
+
+// rtpAEADIV generates the IV of a SRTP packet, which is only valid until the
+// next packet is protected
+// https://tools.ietf.org/html/rfc7714#section-8.1
+// IV = (0x0000 || SSRC || ROC || SEQ) XOR salt
+func (c *Context) rtpAEADIV(sequenceNumber uint16, rolloverCounter uint32, ssrc uint32) []byte {
+	iv := c.counter[:aeadSaltLen]
+	iv[0], iv[1] = 0, 0
+	binary.BigEndian.PutUint32(iv[2:], ssrc)
+	binary.BigEndian.PutUint32(iv[6:], rolloverCounter)
+	binary.BigEndian.PutUint16(iv[10:], sequenceNumber)
+
+	for i := range iv {
+		iv[i] ^= c.srtpSessionSalt[i]
+	}
+	return iv
+}
+
+// rtcpAEADIV generates the IV of a SRTCP packet
+// https://tools.ietf.org/html/rfc7714#section-9.1
+// IV = (0x0000 || SSRC || 0x0000 || 0 || SRTCP index) XOR salt
+func (c *Context) rtcpAEADIV(index uint32, ssrc uint32) []byte {
+	iv := make([]byte, aeadSaltLen)
+	binary.BigEndian.PutUint32(iv[2:], ssrc)
+	binary.BigEndian.PutUint32(iv[8:], index&0x7fffffff)
+
+	for i := range iv {
+		iv[i] ^= c.srtcpSessionSalt[i]
+	}
+	return iv
+}
+
+// The header of SRTP packets is authenticated, the payload is encrypted and
+// followed by the authentication tag
+// https://tools.ietf.org/html/rfc7714#section-8.2
+func (c *Context) decryptRTPAEAD(packet *rtp.Packet, rolloverCounter uint32) bool {
+	if len(packet.Raw) < packet.PayloadOffset || len(packet.Payload) < aeadAuthTagSize {
+		return false
+	}
+
+	iv := c.rtpAEADIV(packet.SequenceNumber, rolloverCounter, packet.SSRC)
+	payload, err := c.srtpAEAD.Open(packet.Payload[:0], iv, packet.Payload, packet.Raw[:packet.PayloadOffset])
+	if err != nil {
+		return false
+	}
+
+	// Replace payload with decrypted
+	packet.Payload = payload
+	packet.Raw = packet.Raw[0:packet.PayloadOffset]
+	packet.Raw = append(packet.Raw, packet.Payload...)
+
+	return true
+}
+
+func (c *Context) encryptRTPAEAD(packet *rtp.Packet, rolloverCounter uint32) bool {
+	if err := c.marshalRTP(packet); err != nil {
+		return false
+	}
+	header := packet.Raw[:packet.PayloadOffset]
+
+	// The payload is sealed in place, like the AES-CM profiles encrypt it
+	iv := c.rtpAEADIV(packet.SequenceNumber, rolloverCounter, packet.SSRC)
+	packet.Payload = c.srtpAEAD.Seal(packet.Payload[:0], iv, packet.Payload, header)
+	return true
+}
+
+// The first 8 octets and the E flag and SRTCP index trailing the packet are
+// authenticated, the rest is encrypted and followed by the authentication tag
+// https://tools.ietf.org/html/rfc7714#section-9.2
+func (c *Context) decryptRTCPAEAD(encrypted []byte) ([]byte, error) {
+	if len(encrypted) < 8+aeadAuthTagSize+srtcpIndexSize {
+		return nil, errRTCPTooShort
+	}
+
+	tailOffset := len(encrypted) - srtcpIndexSize
+	trailer := encrypted[tailOffset:]
+	index := binary.BigEndian.Uint32(trailer) & 0x7fffffff
+	ssrc := binary.BigEndian.Uint32(encrypted[4:])
+	iv := c.rtcpAEADIV(index, ssrc)
+
+	window := c.getSRTCPWindow(ssrc)
+	if window.replayed(uint64(index)) {
+		return nil, errRTCPReplayed
+	}
+
+	// Packets without the E flag are only authenticated, all of the packet
+	// is additional authenticated data
+	if trailer[0]>>7 == 0 {
+		tagOffset := tailOffset - aeadAuthTagSize
+		aad := append(append([]byte{}, encrypted[:tagOffset]...), trailer...)
+		if _, err := c.srtcpAEAD.Open(nil, iv, encrypted[tagOffset:tailOffset], aad); err != nil {
+			return nil, errRTCPAuthentication
+		}
+		window.accept(uint64(index))
+		return append([]byte{}, encrypted[:tagOffset]...), nil
+	}
+
+	aad := append(append([]byte{}, encrypted[:8]...), trailer...)
+	out, err := c.srtcpAEAD.Open(append([]byte{}, encrypted[:8]...), iv, encrypted[8:tailOffset], aad)
+	if err != nil {
+		return nil, errRTCPAuthentication
+	}
+	window.accept(uint64(index))
+	return out, nil
+}
+
+func (c *Context) encryptRTCPAEAD(decrypted []byte, index uint32) ([]byte, error) {
+	if len(decrypted) < 8 {
+		return nil, errRTCPTooShort
+	}
+
+	// Add SRTCP Index and set Encryption bit
+	trailer := make([]byte, srtcpIndexSize)
+	binary.BigEndian.PutUint32(trailer, index)
+	trailer[0] |= 0x80
+
+	ssrc := binary.BigEndian.Uint32(decrypted[4:])
+	aad := append(append([]byte{}, decrypted[:8]...), trailer...)
+	out := c.srtcpAEAD.Seal(append([]byte{}, decrypted[:8]...), c.rtcpAEADIV(index, ssrc), decrypted[8:], aad)
+	return append(out, trailer...), nil
+}
@@ -6,6 +6,7 @@ import (
 	"crypto/hmac"
 	"crypto/sha1" // #nosec
 	"encoding/binary"
+	"hash"
 
 	"github.com/pkg/errors"
 )
@@ -22,53 +23,138 @@ const (
 	keyLen  = 16
 	saltLen = 14
 
-	maxROCDisorder    = 100
-	maxSequenceNumber = 65535
+	// replayWindowSize is how many of the latest packets of a SSRC are
+	// remembered, older packets are rejected
+	// https://tools.ietf.org/html/rfc3711#section-3.3.2
+	replayWindowSize = 64
 
 	authTagSize    = 10
 	srtcpIndexSize = 4
 )
 
-// Encode/Decode state for a single SSRC
+// protectionProfile describes the keys and transforms of a SRTP protection
+// profile negotiated by DTLS
+// https://tools.ietf.org/html/rfc5764#section-4.1.2
+// https://tools.ietf.org/html/rfc7714#section-14.2
+type protectionProfile struct {
+	keyLen  int
+	saltLen int
+
+	// rtpAuthTagSize is the length of the HMAC-SHA1 tag of SRTP packets,
+	// SRTCP packets always carry an 80 bit tag
+	rtpAuthTagSize int
+
+	// aead profiles encrypt and authenticate with AES-GCM instead
+	aead bool
+}
+
+var protectionProfiles = map[string]protectionProfile{
+	"SRTP_AES128_CM_SHA1_80": {keyLen: keyLen, saltLen: saltLen, rtpAuthTagSize: 10},
+	"SRTP_AES128_CM_SHA1_32": {keyLen: keyLen, saltLen: saltLen, rtpAuthTagSize: 4},
+	"SRTP_AEAD_AES_128_GCM":  {keyLen: 16, saltLen: aeadSaltLen, aead: true},
+	"SRTP_AEAD_AES_256_GCM":  {keyLen: 32, saltLen: aeadSaltLen, aead: true},
+}
+
+// Encode/Decode state for a single SSRC, the index of a SRTP packet is its
+// rollover counter (ROC) followed by its sequence number
 type ssrcState struct {
-	ssrc                 uint32
-	rolloverCounter      uint32
-	rolloverHasProcessed bool
-	lastSequenceNumber   uint16
+	ssrc   uint32
+	window replayWindow
+}
+
+// replayWindow remembers the highest index processed and which of the
+// packets before it have been received
+// https://tools.ietf.org/html/rfc3711#section-3.3.2
+type replayWindow struct {
+	initialized bool
+	latest      uint64
+
+	// bit i is set if packet latest-i has been received
+	mask uint64
+}
+
+// replayed reports whether the packet has been received before or is too
+// old to tell
+func (w *replayWindow) replayed(index uint64) bool {
+	if !w.initialized || index > w.latest {
+		return false
+	}
+	diff := w.latest - index
+	return diff >= replayWindowSize || w.mask&(1<<diff) != 0
+}
+
+// accept records the packet as received, only authenticated packets may
+// move the window
+func (w *replayWindow) accept(index uint64) {
+	switch {
+	case !w.initialized:
+		w.initialized, w.latest, w.mask = true, index, 1
+	case index > w.latest:
+		if diff := index - w.latest; diff < replayWindowSize {
+			w.mask = w.mask<<diff | 1
+		} else {
+			w.mask = 1
+		}
+		w.latest = index
+	default:
+		if diff := w.latest - index; diff < replayWindowSize {
+			w.mask |= 1 << diff
+		}
+	}
 }
 
 // Context represents a SRTP cryptographic context
 // Context can only be used for one-way operations
 // it must either used ONLY for encryption or ONLY for decryption
+// and by one goroutine at a time, it reuses its buffers across packets
 type Context struct {
+	profile    protectionProfile
 	masterKey  []byte
 	masterSalt []byte
 
 	ssrcStates         map[uint32]*ssrcState
+	srtcpWindows       map[uint32]*replayWindow
 	srtpSessionKey     []byte
 	srtpSessionSalt    []byte
 	srtpSessionAuthTag []byte
 	srtpBlock          cipher.Block
+	srtpAEAD           cipher.AEAD
+
+	// srtpMAC authenticates SRTP packets, rtpBuffer holds the authenticated
+	// portion of the packet being protected and rtpTag its tag
+	srtpMAC   hash.Hash
+	rtpBuffer []byte
+	rtpTag    []byte
+
+	// counter holds the IV of the packet being protected
+	counter [aes.BlockSize]byte
 
 	srtcpSessionKey     []byte
 	srtcpSessionSalt    []byte
 	srtcpSessionAuthTag []byte
 	srtcpIndex          uint32
 	srtcpBlock          cipher.Block
+	srtcpAEAD           cipher.AEAD
 }
 
-// CreateContext creates a new SRTP Context
-func CreateContext(masterKey, masterSalt []byte, profile string) (c *Context, err error) {
-	if masterKeyLen := len(masterKey); masterKeyLen != keyLen {
-		return c, errors.Errorf("SRTP Master Key must be len %d, got %d", masterKey, keyLen)
-	} else if masterSaltLen := len(masterSalt); masterSaltLen != saltLen {
-		return c, errors.Errorf("SRTP Salt must be len %d, got %d", saltLen, masterSaltLen)
+// CreateContext creates a new SRTP Context for the protection profile, the
+// lengths of the master key and salt depend on it
+func CreateContext(masterKey, masterSalt []byte, profileName string) (c *Context, err error) {
+	profile, ok := protectionProfiles[profileName]
+	if !ok {
+		return c, errors.Errorf("SRTP protection profile %s is not supported", profileName)
+	} else if masterKeyLen := len(masterKey); masterKeyLen != profile.keyLen {
+		return c, errors.Errorf("SRTP Master Key must be len %d, got %d", profile.keyLen, masterKeyLen)
+	} else if masterSaltLen := len(masterSalt); masterSaltLen != profile.saltLen {
+		return c, errors.Errorf("SRTP Salt must be len %d, got %d", profile.saltLen, masterSaltLen)
 	}
 
 	c = &Context{
-		masterKey:  masterKey,
-		masterSalt: masterSalt,
-		ssrcStates: map[uint32]*ssrcState{},
+		profile:      profile,
+		masterKey:    masterKey,
+		masterSalt:   masterSalt,
+		ssrcStates:   map[uint32]*ssrcState{},
+		srtcpWindows: map[uint32]*replayWindow{},
 	}
 
 	if c.srtpSessionKey, err = c.generateSessionKey(labelSRTPEncryption); err != nil {
@@ -80,6 +166,7 @@ func CreateContext(masterKey, masterSalt []byte, profile string) (c *Context, er
 	} else if c.srtpBlock, err = aes.NewCipher(c.srtpSessionKey); err != nil {
 		return nil, err
 	}
+	c.srtpMAC = hmac.New(sha1.New, c.srtpSessionAuthTag)
 
 	if c.srtcpSessionKey, err = c.generateSessionKey(labelSRTCPEncryption); err != nil {
 		return nil, err
@@ -91,6 +178,14 @@ func CreateContext(masterKey, masterSalt []byte, profile string) (c *Context, er
 		return nil, err
 	}
 
+	if profile.aead {
+		if c.srtpAEAD, err = cipher.NewGCM(c.srtpBlock); err != nil {
+			return nil, err
+		} else if c.srtcpAEAD, err = cipher.NewGCM(c.srtcpBlock); err != nil {
+			return nil, err
+		}
+	}
+
 	return c, nil
 }
 
@@ -99,7 +194,8 @@ func (c *Context) generateSessionKey(label byte) ([]byte, error) {
 	// The input block for AES-CM is generated by exclusive-oring the master salt with the
 	// concatenation of the encryption key label 0x00 with (index DIV kdr),
 	// - index is 'rollover count' and DIV is 'divided by'
-	sessionKey := make([]byte, len(c.masterSalt))
+	// - The 96 bit salt of the AEAD profiles is padded on the right to 112 bits
+	sessionKey := make([]byte, saltLen)
 	copy(sessionKey, c.masterSalt)
 
 	labelAndIndexOverKdr := []byte{label, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00}
@@ -108,7 +204,7 @@ func (c *Context) generateSessionKey(label byte) ([]byte, error) {
 	}
 
 	// then padding on the right with two null octets (which implements the multiply-by-2^16 operation, see Section 4.3.3).
-	sessionKey = append(sessionKey, []byte{0x00, 0x00}...)
+	firstRun := append(sessionKey, []byte{0x00, 0x00}...)
 
 	//The resulting value is then AES-CM- encrypted using the master key to get the cipher key.
 	block, err := aes.NewCipher(c.masterKey)
@@ -116,15 +212,22 @@ func (c *Context) generateSessionKey(label byte) ([]byte, error) {
 		return nil, err
 	}
 
-	block.Encrypt(sessionKey, sessionKey)
-	return sessionKey, nil
+	block.Encrypt(firstRun, firstRun)
+	if len(c.masterKey) <= len(firstRun) {
+		return firstRun[:len(c.masterKey)], nil
+	}
+
+	// - AES-256 keys take a second run of the key stream
+	secondRun := append(sessionKey, []byte{0x00, 0x01}...)
+	block.Encrypt(secondRun, secondRun)
+	return append(firstRun, secondRun...)[:len(c.masterKey)], nil
 }
 
 func (c *Context) generateSessionSalt(label byte) ([]byte, error) {
 	// https://tools.ietf.org/html/rfc3711#appendix-B.3
 	// The input block for AES-CM is generated by exclusive-oring the master salt with
 	// the concatenation of the encryption salt label
-	sessionSalt := make([]byte, len(c.masterSalt))
+	sessionSalt := make([]byte, saltLen)
 	copy(sessionSalt, c.masterSalt)
 
 	labelAndIndexOverKdr := []byte{label, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00}
@@ -140,13 +243,13 @@ func (c *Context) generateSessionSalt(label byte) ([]byte, error) {
 	}
 
 	block.Encrypt(sessionSalt, sessionSalt)
-	return sessionSalt[0:saltLen], nil
+	return sessionSalt[0:len(c.masterSalt)], nil
 }
 func (c *Context) generateSessionAuthTag(label byte) ([]byte, error) {
 	// https://tools.ietf.org/html/rfc3711#appendix-B.3
 	// We now show how the auth key is generated.  The input block for AES-
 	// CM is generated as above, but using the authentication key label.
-	sessionAuthTag := make([]byte, len(c.masterSalt))
+	sessionAuthTag := make([]byte, saltLen)
 	copy(sessionAuthTag, c.masterSalt)
 
 	labelAndIndexOverKdr := []byte{label, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00}
@@ -176,8 +279,12 @@ func (c *Context) generateSessionAuthTag(label byte) ([]byte, error) {
 // -       passing through 65,535
 // i = 2^16 * ROC + SEQ
 // IV = (salt*2 ^ 16) | (ssrc*2 ^ 64) | (i*2 ^ 16)
+// The counter is only valid until the next packet is protected.
 func (c *Context) generateCounter(sequenceNumber uint16, rolloverCounter uint32, ssrc uint32, sessionSalt []byte) []byte {
-	counter := make([]byte, 16)
+	counter := c.counter[:]
+	for i := range counter {
+		counter[i] = 0
+	}
 
 	binary.BigEndian.PutUint32(counter[4:], ssrc)
 	binary.BigEndian.PutUint32(counter[8:], rolloverCounter)
@@ -190,7 +297,7 @@ func (c *Context) generateCounter(sequenceNumber uint16, rolloverCounter uint32,
 	return counter
 }
 
-func (c *Context) generateAuthTag(buf []byte, authTag []byte) ([]byte, error) {
+func (c *Context) generateAuthTag(buf []byte, authTag []byte, tagSize int) ([]byte, error) {
 	// https://tools.ietf.org/html/rfc3711#section-4.2
 	// In the case of SRTP, M SHALL consist of the Authenticated
 	// Portion of the packet (as specified in Figure 1) concatenated with
@@ -211,5 +318,5 @@ func (c *Context) generateAuthTag(buf []byte, authTag []byte) ([]byte, error) {
 		return nil, err
 	}
 
-	return mac.Sum(nil)[0:10], nil
+	return mac.Sum(nil)[0:tagSize], nil
 }
@@ -2,32 +2,69 @@ package srtp
 
 import (
 	"crypto/cipher"
+	"crypto/hmac"
 	"encoding/binary"
+
+	"github.com/pkg/errors"
+)
+
+var (
+	errRTCPTooShort       = errors.New("SRTCP packet is too short")
+	errRTCPAuthentication = errors.New("SRTCP packet failed authentication")
+	errRTCPReplayed       = errors.New("SRTCP packet has been received before")
 )
 
-// DecryptRTCP decrypts a buffer that contains a RTCP packet
+// DecryptRTCP decrypts a buffer that contains a RTCP packet, packets which
+// fail authentication or have been received before are rejected
 // We can't pass *rtcp.Packet as the encrypt will obscure significant fields
 func (c *Context) DecryptRTCP(encrypted []byte) ([]byte, error) {
-	tailOffset := len(encrypted) - (authTagSize + srtcpIndexSize)
-	out := append([]byte{}, encrypted[0:tailOffset]...)
+	if c.profile.aead {
+		return c.decryptRTCPAEAD(encrypted)
+	} else if len(encrypted) < 8+authTagSize+srtcpIndexSize {
+		return nil, errRTCPTooShort
+	}
 
-	isEncrypted := encrypted[tailOffset] >> 7
-	if isEncrypted == 0 {
-		return out, nil
+	tailOffset := len(encrypted) - (authTagSize + srtcpIndexSize)
+	authTag, err := c.generateAuthTag(encrypted[:tailOffset+srtcpIndexSize], c.srtcpSessionAuthTag, authTagSize)
+	if err != nil {
+		return nil, err
+	} else if !hmac.Equal(authTag, encrypted[tailOffset+srtcpIndexSize:]) {
+		return nil, errRTCPAuthentication
 	}
 
 	srtcpIndexBuffer := append([]byte{}, encrypted[tailOffset:tailOffset+srtcpIndexSize]...)
+	isEncrypted := srtcpIndexBuffer[0] >> 7
 	srtcpIndexBuffer[0] &= 0x7f // unset Encryption bit
 
 	index := binary.BigEndian.Uint32(srtcpIndexBuffer)
 	ssrc := binary.BigEndian.Uint32(encrypted[4:])
 
+	window := c.getSRTCPWindow(ssrc)
+	if window.replayed(uint64(index)) {
+		return nil, errRTCPReplayed
+	}
+	window.accept(uint64(index))
+
+	out := append([]byte{}, encrypted[0:tailOffset]...)
+	if isEncrypted == 0 {
+		return out, nil
+	}
+
 	stream := cipher.NewCTR(c.srtcpBlock, c.generateCounter(uint16(index&0xffff), index>>16, ssrc, c.srtcpSessionSalt))
 	stream.XORKeyStream(out[8:], out[8:])
 
 	return out, nil
 }
 
+func (c *Context) getSRTCPWindow(ssrc uint32) *replayWindow {
+	w, ok := c.srtcpWindows[ssrc]
+	if !ok {
+		w = &replayWindow{}
+		c.srtcpWindows[ssrc] = w
+	}
+	return w
+}
+
 // EncryptRTCP encrypts a buffer that contains a RTCP packet
 func (c *Context) EncryptRTCP(decrypted []byte) ([]byte, error) {
 	out := append([]byte{}, decrypted[:]...)
@@ -39,6 +76,10 @@ func (c *Context) EncryptRTCP(decrypted []byte) ([]byte, error) {
 		c.srtcpIndex = 0
 	}
 
+	if c.profile.aead {
+		return c.encryptRTCPAEAD(decrypted, c.srtcpIndex)
+	}
+
 	// Encrypt everything after header
 	stream := cipher.NewCTR(c.srtcpBlock, c.generateCounter(uint16(c.srtcpIndex&0xffff), c.srtcpIndex>>16, ssrc, c.srtcpSessionSalt))
 	stream.XORKeyStream(out[8:], out[8:])
@@ -48,7 +89,7 @@ func (c *Context) EncryptRTCP(decrypted []byte) ([]byte, error) {
 	binary.BigEndian.PutUint32(out[len(out)-4:], c.srtcpIndex)
 	out[len(out)-4] |= 0x80
 
-	authTag, err := c.generateAuthTag(out, c.srtcpSessionAuthTag)
+	authTag, err := c.generateAuthTag(out, c.srtcpSessionAuthTag, authTagSize)
 	if err != nil {
 		return nil, err
 	}
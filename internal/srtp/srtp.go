@@ -2,22 +2,53 @@ package srtp
 
 import (
 	"crypto/cipher"
+	"crypto/hmac"
 	"encoding/binary"
 
 	"github.com/pions/webrtc/pkg/rtp"
 )
 
-// DecryptRTP decrypts a RTP packet with an encrypted payload
+// DecryptRTP decrypts a RTP packet with an encrypted payload, packets which
+// fail authentication or have been received before are rejected
 func (c *Context) DecryptRTP(packet *rtp.Packet) bool {
 	s := c.getSSRCState(packet.SSRC)
 
-	c.updateRolloverCount(packet.SequenceNumber, s)
+	rolloverCounter := s.rolloverCount(packet.SequenceNumber)
+	index := uint64(rolloverCounter)<<16 | uint64(packet.SequenceNumber)
+	if s.window.replayed(index) {
+		return false
+	}
 
-	stream := cipher.NewCTR(c.srtpBlock, c.generateCounter(packet.SequenceNumber, s.rolloverCounter, s.ssrc, c.srtpSessionSalt))
-	stream.XORKeyStream(packet.Payload, packet.Payload)
+	if c.profile.aead {
+		if !c.decryptRTPAEAD(packet, rolloverCounter) {
+			return false
+		}
+	} else if !c.decryptRTPCM(packet, rolloverCounter) {
+		return false
+	}
+
+	s.window.accept(index)
+	return true
+}
 
-	// TODO remove tags, need to assert value
-	packet.Payload = packet.Payload[:len(packet.Payload)-10]
+func (c *Context) decryptRTPCM(packet *rtp.Packet, rolloverCounter uint32) bool {
+	if len(packet.Raw) < packet.PayloadOffset || len(packet.Payload) < c.profile.rtpAuthTagSize {
+		return false
+	}
+	tagOffset := len(packet.Payload) - c.profile.rtpAuthTagSize
+
+	// The header and the encrypted payload are authenticated along with the ROC
+	c.rtpBuffer = append(c.rtpBuffer[:0], packet.Raw[:packet.PayloadOffset]...)
+	c.rtpBuffer = append(c.rtpBuffer, packet.Payload[:tagOffset]...)
+
+	authTag, err := c.rtpAuthTag(rolloverCounter)
+	if err != nil || !hmac.Equal(authTag, packet.Payload[tagOffset:]) {
+		return false
+	}
+
+	packet.Payload = packet.Payload[:tagOffset]
+	stream := cipher.NewCTR(c.srtpBlock, c.generateCounter(packet.SequenceNumber, rolloverCounter, packet.SSRC, c.srtpSessionSalt))
+	stream.XORKeyStream(packet.Payload, packet.Payload)
 
 	// Replace payload with decrypted
 	packet.Raw = packet.Raw[0:packet.PayloadOffset]
@@ -30,20 +61,20 @@ func (c *Context) DecryptRTP(packet *rtp.Packet) bool {
 func (c *Context) EncryptRTP(packet *rtp.Packet) bool {
 	s := c.getSSRCState(packet.SSRC)
 
-	c.updateRolloverCount(packet.SequenceNumber, s)
+	rolloverCounter := c.updateRolloverCount(packet.SequenceNumber, s)
 
-	stream := cipher.NewCTR(c.srtpBlock, c.generateCounter(packet.SequenceNumber, s.rolloverCounter, s.ssrc, c.srtpSessionSalt))
+	if c.profile.aead {
+		return c.encryptRTPAEAD(packet, rolloverCounter)
+	}
+
+	stream := cipher.NewCTR(c.srtpBlock, c.generateCounter(packet.SequenceNumber, rolloverCounter, packet.SSRC, c.srtpSessionSalt))
 	stream.XORKeyStream(packet.Payload, packet.Payload)
 
-	fullPkt, err := packet.Marshal()
-	if err != nil {
+	if err := c.marshalRTP(packet); err != nil {
 		return false
 	}
 
-	fullPkt = append(fullPkt, make([]byte, 4)...)
-	binary.BigEndian.PutUint32(fullPkt[len(fullPkt)-4:], s.rolloverCounter)
-
-	authTag, err := c.generateAuthTag(fullPkt, c.srtpSessionAuthTag)
+	authTag, err := c.rtpAuthTag(rolloverCounter)
 	if err != nil {
 		return false
 	}
@@ -52,27 +83,69 @@ func (c *Context) EncryptRTP(packet *rtp.Packet) bool {
 	return true
 }
 
-// https://tools.ietf.org/html/rfc3550#appendix-A.1
-func (c *Context) updateRolloverCount(sequenceNumber uint16, s *ssrcState) {
-	if !s.rolloverHasProcessed {
-		s.rolloverHasProcessed = true
-	} else if sequenceNumber == 0 { // We exactly hit the rollover count
+// marshalRTP marshals the packet to the rtpBuffer, which grows to the
+// largest packet marshaled. Raw is left holding a copy like Marshal leaves
+// it, reusing its capacity, and the Payload points into it.
+func (c *Context) marshalRTP(packet *rtp.Packet) error {
+	size := packet.MarshalSize()
+	if cap(c.rtpBuffer) < size {
+		c.rtpBuffer = make([]byte, size)
+	}
+	c.rtpBuffer = c.rtpBuffer[:size]
+
+	if _, err := packet.MarshalTo(c.rtpBuffer); err != nil {
+		return err
+	}
+	packet.Raw = append(packet.Raw[:0], c.rtpBuffer...)
+	packet.Payload = packet.Raw[packet.PayloadOffset:]
+	return nil
+}
+
+// rtpAuthTag appends the ROC to the authenticated portion of the packet in
+// the rtpBuffer and generates its authentication tag, which is only valid
+// until the next packet is authenticated
+func (c *Context) rtpAuthTag(rolloverCounter uint32) ([]byte, error) {
+	c.rtpBuffer = append(c.rtpBuffer, 0, 0, 0, 0)
+	binary.BigEndian.PutUint32(c.rtpBuffer[len(c.rtpBuffer)-4:], rolloverCounter)
+
+	c.srtpMAC.Reset()
+	if _, err := c.srtpMAC.Write(c.rtpBuffer); err != nil {
+		return nil, err
+	}
+
+	c.rtpTag = c.srtpMAC.Sum(c.rtpTag[:0])
+	return c.rtpTag[:c.profile.rtpAuthTagSize], nil
+}
+
+// updateRolloverCount returns the ROC of the packet and records it as the
+// latest one sent if it is
+func (c *Context) updateRolloverCount(sequenceNumber uint16, s *ssrcState) uint32 {
+	rolloverCounter := s.rolloverCount(sequenceNumber)
+	s.window.accept(uint64(rolloverCounter)<<16 | uint64(sequenceNumber))
+	return rolloverCounter
+}
+
+// rolloverCount estimates the ROC of the packet from the highest index
+// processed, the sequence number is assumed to be within 2^15 of it
+// https://tools.ietf.org/html/rfc3711#appendix-A
+func (s *ssrcState) rolloverCount(sequenceNumber uint16) uint32 {
+	if !s.window.initialized {
+		return 0
+	}
 
-		// Only update rolloverCounter if lastSequenceNumber is greater then maxROCDisorder
-		// otherwise we already incremented for disorder
-		if s.lastSequenceNumber > maxROCDisorder {
-			s.rolloverCounter++
+	rolloverCounter := uint32(s.window.latest >> 16)
+	lastSequenceNumber := int(uint16(s.window.latest))
+	switch {
+	case lastSequenceNumber < 1<<15 && int(sequenceNumber)-lastSequenceNumber > 1<<15:
+		// A packet from before the latest rollover, there is none before the first
+		if rolloverCounter > 0 {
+			rolloverCounter--
 		}
-	} else if s.lastSequenceNumber < maxROCDisorder && sequenceNumber > (maxSequenceNumber-maxROCDisorder) {
-		// Our last sequence number incremented because we crossed 0, but then our current number was within maxROCDisorder of the max
-		// So we fell behind, drop to account for jitter
-		s.rolloverCounter--
-	} else if sequenceNumber < maxROCDisorder && s.lastSequenceNumber > (maxSequenceNumber-maxROCDisorder) {
-		// our current is within a maxROCDisorder of 0
-		// and our last sequence number was a high sequence number, increment to account for jitter
-		s.rolloverCounter++
+	case lastSequenceNumber >= 1<<15 && lastSequenceNumber-1<<15 > int(sequenceNumber):
+		// The sequence number rolled over
+		rolloverCounter++
 	}
-	s.lastSequenceNumber = sequenceNumber
+	return rolloverCounter
 }
 
 func (c *Context) getSSRCState(ssrc uint32) *ssrcState {
@@ -78,7 +78,7 @@ func TestValidPacketCounter(t *testing.T) {
 
 	s := &ssrcState{ssrc: 4160032510}
 	expectedCounter := []byte{0xcf, 0x90, 0x1e, 0xa5, 0xda, 0xd3, 0x2c, 0x15, 0x00, 0xa2, 0x24, 0xae, 0xae, 0xaf, 0x00, 0x00}
-	counter := c.generateCounter(32846, s.rolloverCounter, s.ssrc, c.srtpSessionSalt)
+	counter := c.generateCounter(32846, 0, s.ssrc, c.srtpSessionSalt)
 	if !bytes.Equal(counter, expectedCounter) {
 		t.Errorf("Session Key % 02x does not match expected % 02x", counter, expectedCounter)
 	}
@@ -96,29 +96,35 @@ func TestRolloverCount(t *testing.T) {
 	s := &ssrcState{ssrc: defaultSsrc}
 
 	// Set initial seqnum
-	c.updateRolloverCount(65530, s)
+	if c.updateRolloverCount(65530, s) != 0 {
+		t.Errorf("rolloverCounter was not 0 for the initial seqnum")
+	}
 
 	// We rolled over to 0
-	c.updateRolloverCount(0, s)
-	if s.rolloverCounter != 1 {
+	if c.updateRolloverCount(0, s) != 1 {
 		t.Errorf("rolloverCounter was not updated after it crossed 0")
 	}
 
-	c.updateRolloverCount(65530, s)
-	if s.rolloverCounter != 0 {
+	if c.updateRolloverCount(65530, s) != 0 {
 		t.Errorf("rolloverCounter was not updated when it rolled back, failed to handle out of order")
 	}
 
-	c.updateRolloverCount(5, s)
-	if s.rolloverCounter != 1 {
+	if c.updateRolloverCount(5, s) != 1 {
 		t.Errorf("rolloverCounter was not updated when it rolled over initial, to handle out of order")
 	}
 
-	c.updateRolloverCount(6, s)
-	c.updateRolloverCount(7, s)
-	c.updateRolloverCount(8, s)
-	if s.rolloverCounter != 1 {
-		t.Errorf("rolloverCounter was improperly updated for non-significant packets")
+	for _, sequenceNumber := range []uint16{6, 7, 8} {
+		if c.updateRolloverCount(sequenceNumber, s) != 1 {
+			t.Errorf("rolloverCounter was improperly updated for non-significant packets")
+		}
+	}
+
+	// The ROC keeps counting past the first rollover
+	for i := 0; i < 4*(1<<16); i += 1 << 12 {
+		c.updateRolloverCount(uint16(8+i), s)
+	}
+	if c.updateRolloverCount(8, s) != 5 {
+		t.Errorf("rolloverCounter was not updated after several rollovers")
 	}
 }
 
@@ -207,3 +213,196 @@ func TestRTCPLifecycle(t *testing.T) {
 	assert.Equal(encryptResult, encrypted, "RTCP failed to encrypt")
 
 }
+
+func TestProtectionProfiles(t *testing.T) {
+	testCases := []struct {
+		profile string
+		keyLen  int
+		saltLen int
+		tagSize int
+	}{
+		{"SRTP_AES128_CM_SHA1_80", 16, 14, 10},
+		{"SRTP_AES128_CM_SHA1_32", 16, 14, 4},
+		{"SRTP_AEAD_AES_128_GCM", 16, 12, 16},
+		{"SRTP_AEAD_AES_256_GCM", 32, 12, 16},
+	}
+
+	for i, testCase := range testCases {
+		_, err := CreateContext(make([]byte, testCase.keyLen+1), make([]byte, testCase.saltLen), testCase.profile)
+		assert.NotNil(t, err, "testCase: %d", i)
+		_, err = CreateContext(make([]byte, testCase.keyLen), make([]byte, testCase.saltLen+1), testCase.profile)
+		assert.NotNil(t, err, "testCase: %d", i)
+
+		masterKey := make([]byte, testCase.keyLen)
+		masterSalt := make([]byte, testCase.saltLen)
+		for j := range masterKey {
+			masterKey[j] = byte(j)
+		}
+		encryptContext, err := CreateContext(masterKey, masterSalt, testCase.profile)
+		assert.Nil(t, err, "testCase: %d", i)
+		decryptContext, err := CreateContext(masterKey, masterSalt, testCase.profile)
+		assert.Nil(t, err, "testCase: %d", i)
+
+		decrypted := []byte{0x00, 0x01, 0x02, 0x03, 0x04, 0x05}
+		pkt := &rtp.Packet{Version: 2, PayloadType: 96, SequenceNumber: 5000, SSRC: 1, Payload: append([]byte{}, decrypted...)}
+		assert.True(t, encryptContext.EncryptRTP(pkt), "testCase: %d", i)
+		assert.Equal(t, len(decrypted)+testCase.tagSize, len(pkt.Payload), "testCase: %d", i)
+
+		raw, err := pkt.Marshal()
+		assert.Nil(t, err, "testCase: %d", i)
+		received := &rtp.Packet{}
+		assert.Nil(t, received.Unmarshal(raw), "testCase: %d", i)
+		assert.True(t, decryptContext.DecryptRTP(received), "testCase: %d", i)
+		assert.Equal(t, decrypted, received.Payload, "testCase: %d", i)
+
+		rtcp := []byte{0x81, 0xc9, 0x00, 0x07, 0x00, 0x00, 0x00, 0x01, 0xde, 0xad, 0xbe, 0xef}
+		encrypted, err := encryptContext.EncryptRTCP(rtcp)
+		assert.Nil(t, err, "testCase: %d", i)
+		rtcpDecrypted, err := decryptContext.DecryptRTCP(encrypted)
+		assert.Nil(t, err, "testCase: %d", i)
+		assert.Equal(t, rtcp, rtcpDecrypted, "testCase: %d", i)
+	}
+
+	if _, err := CreateContext(make([]byte, keyLen), make([]byte, saltLen), "SRTP_NULL_SHA1_80"); err == nil {
+		t.Errorf("CreateContext accepted an unsupported profile")
+	}
+}
+
+func TestAEADAuthentication(t *testing.T) {
+	masterKey := []byte{0x00, 0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08, 0x09, 0x0a, 0x0b, 0x0c, 0x0d, 0x0e, 0x0f}
+	masterSalt := []byte{0xa0, 0xa1, 0xa2, 0xa3, 0xa4, 0xa5, 0xa6, 0xa7, 0xa8, 0xa9, 0xaa, 0xab}
+
+	encryptContext, err := CreateContext(masterKey, masterSalt, "SRTP_AEAD_AES_128_GCM")
+	assert.Nil(t, err)
+	decryptContext, err := CreateContext(masterKey, masterSalt, "SRTP_AEAD_AES_128_GCM")
+	assert.Nil(t, err)
+
+	pkt := &rtp.Packet{Version: 2, PayloadType: 96, SequenceNumber: 1, SSRC: 1, Payload: []byte{0x00, 0x01, 0x02, 0x03}}
+	assert.True(t, encryptContext.EncryptRTP(pkt))
+	raw, err := pkt.Marshal()
+	assert.Nil(t, err)
+
+	// The header is authenticated as well as the payload
+	raw[1] ^= 0x01
+	received := &rtp.Packet{}
+	assert.Nil(t, received.Unmarshal(raw))
+	assert.False(t, decryptContext.DecryptRTP(received))
+
+	rtcp := []byte{0x81, 0xc9, 0x00, 0x07, 0x00, 0x00, 0x00, 0x01, 0xde, 0xad, 0xbe, 0xef}
+	encrypted, err := encryptContext.EncryptRTCP(rtcp)
+	assert.Nil(t, err)
+	encrypted[len(encrypted)-1] ^= 0x01
+	_, err = decryptContext.DecryptRTCP(encrypted)
+	assert.Equal(t, errRTCPAuthentication, err)
+}
+
+func TestReplayProtection(t *testing.T) {
+	masterKey := []byte{0x0d, 0xcd, 0x21, 0x3e, 0x4c, 0xbc, 0xf2, 0x8f, 0x01, 0x7f, 0x69, 0x94, 0x40, 0x1e, 0x28, 0x89}
+	masterSalt := []byte{0x62, 0x77, 0x60, 0x38, 0xc0, 0x6d, 0xc9, 0x41, 0x9f, 0x6d, 0xd9, 0x43, 0x3e, 0x7c}
+
+	for i, profile := range []string{"SRTP_AES128_CM_SHA1_80", "SRTP_AEAD_AES_128_GCM"} {
+		salt := masterSalt
+		if profile == "SRTP_AEAD_AES_128_GCM" {
+			salt = masterSalt[:aeadSaltLen]
+		}
+		encryptContext, err := CreateContext(masterKey, salt, profile)
+		assert.Nil(t, err, "testCase: %d", i)
+		decryptContext, err := CreateContext(masterKey, salt, profile)
+		assert.Nil(t, err, "testCase: %d", i)
+
+		encrypt := func(sequenceNumber uint16) []byte {
+			pkt := &rtp.Packet{Version: 2, PayloadType: 96, SequenceNumber: sequenceNumber, SSRC: 1, Payload: []byte{0x00, 0x01, 0x02, 0x03}}
+			assert.True(t, encryptContext.EncryptRTP(pkt), "testCase: %d", i)
+			raw, err := pkt.Marshal()
+			assert.Nil(t, err, "testCase: %d", i)
+			return append([]byte{}, raw...)
+		}
+		decrypt := func(raw []byte) bool {
+			received := &rtp.Packet{}
+			assert.Nil(t, received.Unmarshal(append([]byte{}, raw...)), "testCase: %d", i)
+			return decryptContext.DecryptRTP(received)
+		}
+
+		first, second, third := encrypt(65534), encrypt(65535), encrypt(0)
+		assert.True(t, decrypt(first), "testCase: %d", i)
+		assert.False(t, decrypt(first), "testCase: %d", i)
+
+		// Reordered packets are accepted once, across the rollover too
+		assert.True(t, decrypt(third), "testCase: %d", i)
+		assert.True(t, decrypt(second), "testCase: %d", i)
+		assert.False(t, decrypt(second), "testCase: %d", i)
+		assert.False(t, decrypt(third), "testCase: %d", i)
+
+		// Packets older than the replay window are rejected
+		old := encrypt(1)
+		for sequenceNumber := uint16(2); sequenceNumber < 2+replayWindowSize; sequenceNumber++ {
+			assert.True(t, decrypt(encrypt(sequenceNumber)), "testCase: %d", i)
+		}
+		assert.False(t, decrypt(old), "testCase: %d", i)
+
+		// A tampered packet is rejected without advancing the window
+		tampered := encrypt(2 + replayWindowSize)
+		tampered[len(tampered)-1] ^= 0x01
+		assert.False(t, decrypt(tampered), "testCase: %d", i)
+		tampered[len(tampered)-1] ^= 0x01
+		assert.True(t, decrypt(tampered), "testCase: %d", i)
+
+		rtcp := []byte{0x81, 0xc9, 0x00, 0x07, 0x00, 0x00, 0x00, 0x01, 0xde, 0xad, 0xbe, 0xef}
+		encrypted, err := encryptContext.EncryptRTCP(rtcp)
+		assert.Nil(t, err, "testCase: %d", i)
+		_, err = decryptContext.DecryptRTCP(append([]byte{}, encrypted...))
+		assert.Nil(t, err, "testCase: %d", i)
+		_, err = decryptContext.DecryptRTCP(append([]byte{}, encrypted...))
+		assert.Equal(t, errRTCPReplayed, err, "testCase: %d", i)
+
+		encrypted, err = encryptContext.EncryptRTCP(rtcp)
+		assert.Nil(t, err, "testCase: %d", i)
+		encrypted[len(encrypted)-1] ^= 0x01
+		_, err = decryptContext.DecryptRTCP(encrypted)
+		assert.Equal(t, errRTCPAuthentication, err, "testCase: %d", i)
+	}
+}
+
+// BenchmarkRTPRoundTrip encrypts, marshals, unmarshals and decrypts a packet
+// like the send and receive paths do, reusing the packets and buffers. Only
+// the AES-CM profiles allocate, crypto/cipher allocates their CTR streams.
+func BenchmarkRTPRoundTrip(b *testing.B) {
+	for _, profile := range []string{cipherContextAlgo, "SRTP_AEAD_AES_128_GCM"} {
+		b.Run(profile, func(b *testing.B) {
+			p := protectionProfiles[profile]
+			encryptContext, err := CreateContext(make([]byte, p.keyLen), make([]byte, p.saltLen), profile)
+			if err != nil {
+				b.Fatal(err)
+			}
+			decryptContext, err := CreateContext(make([]byte, p.keyLen), make([]byte, p.saltLen), profile)
+			if err != nil {
+				b.Fatal(err)
+			}
+
+			payload := make([]byte, 1200, 1200+aeadAuthTagSize)
+			sent := &rtp.Packet{Version: 2, SSRC: 1}
+			received := &rtp.Packet{}
+			buffer := make([]byte, 1500)
+
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				sent.SequenceNumber = uint16(i)
+				sent.Payload = payload
+				if !encryptContext.EncryptRTP(sent) {
+					b.Fatal("failed to encrypt")
+				}
+				n, err := sent.MarshalTo(buffer)
+				if err != nil {
+					b.Fatal(err)
+				}
+
+				if err := received.Unmarshal(buffer[:n]); err != nil {
+					b.Fatal(err)
+				}
+				if !decryptContext.DecryptRTP(received) {
+					b.Fatal("failed to decrypt")
+				}
+			}
+		})
+	}
+}
@@ -1,8 +1,8 @@
 package stun
 
 import (
-	"fmt"
 	"net"
+	"strconv"
 	"time"
 
 	"github.com/pions/pkg/stun"
@@ -13,13 +13,12 @@ import (
 // TODO: This file doesn't make sense
 // Package ICE should rely on stun, not the other way around.
 
-// AllocateUDP crafts and sends a STUN binding
+// AllocateUDP crafts and sends a STUN binding over the network, udp4 or udp6
 // On success will return our XORMappedAddress
-func AllocateUDP(url *ice.URL) (*net.UDPAddr, *stun.XorAddress, error) {
+func AllocateUDP(network string, url *ice.URL) (*net.UDPAddr, *stun.XorAddress, error) {
 	// TODO Do we want the timeout to be configurable?
 	// proto := url.Proto.String()
-	// TODO: Temporary fix for nat traversal issue: Find a permanent solution.
-	client, err := stun.NewClient("udp4", fmt.Sprintf("%s:%d", url.Host, url.Port), time.Second*5)
+	client, err := stun.NewClient(network, net.JoinHostPort(url.Host, strconv.Itoa(url.Port)), time.Second*5)
 	if err != nil {
 		return nil, nil, errors.Wrapf(err, "Failed to create STUN client")
 	}
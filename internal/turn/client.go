@@ -0,0 +1,618 @@
+// Package turn implements the client of TURN, RFC 5766, allocating the
+// relayed transport addresses of relay candidates
+package turn
+
+import (
+	"crypto/md5" // nolint: gosec
+	"encoding/binary"
+	"io"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/pions/pkg/stun"
+	"github.com/pions/webrtc/pkg/logging"
+	"github.com/pkg/errors"
+)
+
+const (
+	// requestTimeout is how long a request waits for its response
+	requestTimeout = 5 * time.Second
+
+	// initialRTO is the first retransmission timeout of requests sent over
+	// UDP, doubled with every retransmission, RFC 5389 Section 7.2.1
+	initialRTO = 500 * time.Millisecond
+
+	// permissionRefreshInterval is how often the permission of a peer is
+	// renewed while packets are sent to it, permissions expire after five
+	// minutes, RFC 5766 Section 8
+	permissionRefreshInterval = 4 * time.Minute
+
+	// maxMessageSize bounds the STUN messages read from the server
+	maxMessageSize = 8192
+
+	messageHeaderSize = 20
+
+	// maxChallenges is how many times a request is sent again with a new
+	// nonce, the first request of the client isn't authenticated yet
+	maxChallenges = 2
+
+	// protocolUDP is the REQUESTED-TRANSPORT of allocations
+	protocolUDP = 17
+)
+
+// The error codes of responses the requests are retried on
+const (
+	codeUnauthorized = 401
+	codeStaleNonce   = 438
+)
+
+var (
+	errClosed          = errors.New("TURN allocation closed")
+	errRequestTimeout  = errors.New("TURN request timed out")
+	errNoRelayedAddr   = errors.New("TURN allocation has no XOR-RELAYED-ADDRESS")
+	errInvalidPeerAddr = errors.New("TURN peers are addressed by *net.UDPAddr")
+)
+
+// Credentials are the long-term credentials of the TURN server, RFC 5389
+// Section 10.2
+type Credentials struct {
+	Username string
+	Password string
+
+	// AccessToken is the OAuth access token sent in the ACCESS-TOKEN of
+	// requests, which are signed with the MacKey in place of the password.
+	// The Username is the key identifier of the token, RFC 7635 Section 6.
+	AccessToken []byte
+	MacKey      []byte
+}
+
+// key returns the key of the MESSAGE-INTEGRITY of requests to the realm
+func (c Credentials) key(realm string) []byte {
+	if c.MacKey != nil {
+		return c.MacKey
+	}
+	key := md5.Sum([]byte(c.Username + ":" + realm + ":" + c.Password))
+	return key[:]
+}
+
+// CredentialsFunc returns the credentials of the requests sent until the
+// time, it is called before the allocation and before every refresh of it
+// so time-limited credentials are renewed before they expire
+type CredentialsFunc func(until time.Time) (Credentials, error)
+
+// StaticCredentials returns a CredentialsFunc of credentials that don't
+// expire
+func StaticCredentials(credentials Credentials) CredentialsFunc {
+	return func(time.Time) (Credentials, error) {
+		return credentials, nil
+	}
+}
+
+// Allocation is the relayed transport address allocated on a TURN server,
+// the packets of relay candidates are sent from it to peers in Send
+// indications and received in Data indications, RFC 5766 Section 10.
+//
+// Peers are identified by their address, reported as *net.UDPAddr like the
+// ones of the other ports of the Manager.
+type Allocation struct {
+	conn   net.Conn
+	stream bool
+	log    logging.LeveledLogger
+
+	relayedAddr *net.UDPAddr
+	mappedAddr  *net.UDPAddr
+
+	renewCredentials CredentialsFunc
+
+	lock         sync.Mutex
+	credentials  Credentials
+	realm        string
+	nonce        string
+	lifetime     time.Duration
+	transactions map[string]chan *stun.Message
+	permissions  map[string]time.Time
+
+	writeLock  sync.Mutex
+	readBuffer []byte
+	packets    chan *packet
+
+	closed    chan struct{}
+	closeOnce sync.Once
+}
+
+type packet struct {
+	buffer  []byte
+	srcAddr *net.UDPAddr
+}
+
+// Allocate authenticates with the credentials to the TURN server at the
+// other end of the conn and allocates a relayed transport address on it,
+// refreshed until the Allocation is closed. The conn is a UDP conn dialed
+// to the server or a TCP or TLS connection to it, RFC 5766 Section 2.1.
+func Allocate(conn net.Conn, credentials CredentialsFunc, log logging.LeveledLogger) (*Allocation, error) {
+	initial, err := credentials(time.Now())
+	if err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+
+	_, isUDP := conn.(*net.UDPConn)
+	a := &Allocation{
+		conn:             conn,
+		stream:           !isUDP,
+		log:              log,
+		renewCredentials: credentials,
+		credentials:      initial,
+		transactions:     make(map[string]chan *stun.Message),
+		permissions:      make(map[string]time.Time),
+		readBuffer:       make([]byte, maxMessageSize),
+		packets:          make(chan *packet, 15),
+		closed:           make(chan struct{}),
+	}
+	go a.readLoop()
+
+	if err := a.allocate(); err != nil {
+		_ = a.shutdown()
+		return nil, err
+	}
+	go a.refreshLoop()
+	return a, nil
+}
+
+// allocate sends the Allocate request, RFC 5766 Section 6.1
+func (a *Allocation) allocate() error {
+	res, err := a.request(stun.MethodAllocate, requestedTransport{})
+	if err != nil {
+		return err
+	}
+
+	attr, ok := res.GetOneAttribute(stun.AttrXORRelayedAddress)
+	if !ok {
+		return errNoRelayedAddr
+	}
+	var relayed stun.XorAddress
+	if err = relayed.Unpack(res, attr); err != nil {
+		return err
+	}
+	a.relayedAddr = &net.UDPAddr{IP: relayed.IP, Port: relayed.Port}
+
+	// The server reflexive address is the related address of the relay
+	// candidate, the local address of the conn if the server omits it
+	ip, port := addrIPPort(a.conn.LocalAddr())
+	a.mappedAddr = &net.UDPAddr{IP: ip, Port: port}
+	if attr, ok = res.GetOneAttribute(stun.AttrXORMappedAddress); ok {
+		var mapped stun.XorAddress
+		if err = mapped.Unpack(res, attr); err != nil {
+			return err
+		}
+		a.mappedAddr = &net.UDPAddr{IP: mapped.IP, Port: mapped.Port}
+	}
+
+	return a.updateLifetime(res)
+}
+
+// refreshLoop refreshes the allocation before its lifetime ends, RFC 5766
+// Section 7. The credentials are renewed for the requests sent until the
+// next refresh first, the previous ones are kept if that fails.
+func (a *Allocation) refreshLoop() {
+	for {
+		a.lock.Lock()
+		lifetime := a.lifetime
+		a.lock.Unlock()
+
+		select {
+		case <-time.After(refreshInterval(lifetime)):
+		case <-a.closed:
+			return
+		}
+
+		if credentials, err := a.renewCredentials(time.Now().Add(refreshInterval(lifetime))); err != nil {
+			a.log.Warnf("Failed to renew the credentials of the TURN allocation %s: %v", a.relayedAddr, err)
+		} else {
+			a.lock.Lock()
+			a.credentials = credentials
+			a.lock.Unlock()
+		}
+
+		res, err := a.request(stun.MethodRefresh)
+		if err == nil {
+			err = a.updateLifetime(res)
+		}
+		if err != nil {
+			a.log.Warnf("Failed to refresh the TURN allocation %s: %v", a.relayedAddr, err)
+		}
+	}
+}
+
+// refreshInterval returns how long after it was granted the lifetime is
+// refreshed, a minute before it ends or halfway through shorter ones
+func refreshInterval(lifetime time.Duration) time.Duration {
+	margin := time.Minute
+	if lifetime/2 < margin {
+		margin = lifetime / 2
+	}
+	return lifetime - margin
+}
+
+func (a *Allocation) updateLifetime(res *stun.Message) error {
+	attr, ok := res.GetOneAttribute(stun.AttrLifetime)
+	if !ok {
+		return nil
+	}
+	var lifetime stun.Lifetime
+	if err := lifetime.Unpack(res, attr); err != nil {
+		return err
+	}
+
+	a.lock.Lock()
+	a.lifetime = time.Duration(lifetime.Duration) * time.Second
+	a.lock.Unlock()
+	return nil
+}
+
+// permit installs the permission of the peer, RFC 5766 Section 9. The
+// server drops the packets sent to the peer until it is installed, like
+// lost UDP packets would be.
+func (a *Allocation) permit(peer *net.UDPAddr) {
+	key := peer.IP.String()
+	a.lock.Lock()
+	if granted, ok := a.permissions[key]; ok && time.Since(granted) < permissionRefreshInterval {
+		a.lock.Unlock()
+		return
+	}
+	a.permissions[key] = time.Now()
+	a.lock.Unlock()
+
+	go func() {
+		peerAddr := &stun.XorPeerAddress{XorAddress: stun.XorAddress{IP: peer.IP, Port: peer.Port}}
+		if _, err := a.request(stun.MethodCreatePermission, peerAddr); err != nil {
+			a.log.Warnf("Failed to create the TURN permission of %s: %v", key, err)
+
+			a.lock.Lock()
+			delete(a.permissions, key)
+			a.lock.Unlock()
+		}
+	}()
+}
+
+// request sends the request authenticated with the long-term credentials,
+// it is sent again with the realm and nonce of the server if the server
+// challenges it or the nonce went stale, RFC 5389 Section 10.2
+func (a *Allocation) request(method stun.Method, attrs ...stun.Attribute) (*stun.Message, error) {
+	for attempt := 0; ; attempt++ {
+		res, err := a.roundTrip(method, a.authenticate(attrs)...)
+		if err != nil {
+			return nil, err
+		} else if res.Class == stun.ClassSuccessResponse {
+			return res, nil
+		}
+
+		code, reason := errorCode(res)
+		if attempt < maxChallenges && (code == codeUnauthorized || code == codeStaleNonce) {
+			if err = a.challenged(res); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		return nil, errors.Errorf("TURN %s request failed: %d %s", method, code, reason)
+	}
+}
+
+// authenticate appends the attributes of the long-term credentials to the
+// attributes of the request, none until the server sent its nonce
+func (a *Allocation) authenticate(attrs []stun.Attribute) []stun.Attribute {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	if a.nonce == "" {
+		return attrs
+	}
+
+	attrs = append(attrs[:len(attrs):len(attrs)],
+		&stun.Username{Username: a.credentials.Username},
+		&stun.Realm{Realm: a.realm},
+		&stun.Nonce{Nonce: a.nonce},
+	)
+	if a.credentials.AccessToken != nil {
+		attrs = append(attrs, accessToken(a.credentials.AccessToken))
+	}
+	return append(attrs, &stun.MessageIntegrity{Key: a.credentials.key(a.realm)})
+}
+
+// challenged learns the realm and the nonce of the error response
+func (a *Allocation) challenged(res *stun.Message) error {
+	var realm stun.Realm
+	var nonce stun.Nonce
+	realmAttr, hasRealm := res.GetOneAttribute(stun.AttrRealm)
+	nonceAttr, hasNonce := res.GetOneAttribute(stun.AttrNonce)
+	if !hasRealm || !hasNonce {
+		return errors.New("TURN server challenged without a realm and a nonce")
+	}
+	if err := realm.Unpack(res, realmAttr); err != nil {
+		return err
+	}
+	if err := nonce.Unpack(res, nonceAttr); err != nil {
+		return err
+	}
+
+	a.lock.Lock()
+	a.realm, a.nonce = realm.Realm, nonce.Nonce
+	a.lock.Unlock()
+	return nil
+}
+
+// roundTrip sends the request until its response is received, requests
+// sent over UDP are retransmitted
+func (a *Allocation) roundTrip(method stun.Method, attrs ...stun.Attribute) (*stun.Message, error) {
+	transactionID := stun.GenerateTransactionId()
+	msg, err := stun.Build(stun.ClassRequest, method, transactionID, attrs...)
+	if err != nil {
+		return nil, err
+	}
+
+	responses := make(chan *stun.Message, 1)
+	a.lock.Lock()
+	a.transactions[string(transactionID)] = responses
+	a.lock.Unlock()
+	defer func() {
+		a.lock.Lock()
+		delete(a.transactions, string(transactionID))
+		a.lock.Unlock()
+	}()
+
+	timeout := time.After(requestTimeout)
+	rto := initialRTO
+	for {
+		if err := a.send(msg.Pack()); err != nil {
+			return nil, err
+		}
+
+		var retransmit <-chan time.Time
+		if !a.stream {
+			retransmit = time.After(rto)
+			rto *= 2
+		}
+
+		select {
+		case res := <-responses:
+			return res, nil
+		case <-retransmit:
+		case <-timeout:
+			return nil, errRequestTimeout
+		case <-a.closed:
+			return nil, errClosed
+		}
+	}
+}
+
+func (a *Allocation) send(raw []byte) error {
+	// Messages sent over a stream must not interleave
+	a.writeLock.Lock()
+	defer a.writeLock.Unlock()
+	_, err := a.conn.Write(raw)
+	return err
+}
+
+func (a *Allocation) readLoop() {
+	defer a.shutdown()
+
+	for {
+		raw, err := a.readMessage()
+		if err != nil {
+			return
+		}
+		msg, err := stun.NewMessage(raw)
+		if err != nil {
+			a.log.Debugf("Failed to parse a message of the TURN server: %v", err)
+			continue
+		}
+
+		switch msg.Class {
+		case stun.ClassIndication:
+			if msg.Method == stun.MethodData {
+				a.handleData(msg)
+			}
+		case stun.ClassSuccessResponse, stun.ClassErrorResponse:
+			a.lock.Lock()
+			responses, ok := a.transactions[string(msg.TransactionID)]
+			a.lock.Unlock()
+			if ok {
+				select {
+				case responses <- msg:
+				default:
+				}
+			}
+		}
+	}
+}
+
+// readMessage reads the next message of the server
+func (a *Allocation) readMessage() ([]byte, error) {
+	if a.stream {
+		return readStreamMessage(a.conn)
+	}
+
+	n, err := a.conn.Read(a.readBuffer)
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte{}, a.readBuffer[:n]...), nil
+}
+
+// readStreamMessage reads the next message of a TCP or TLS connection, they
+// are framed by the length of their header, RFC 5766 Section 2.1
+func readStreamMessage(r io.Reader) ([]byte, error) {
+	header := make([]byte, messageHeaderSize)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, err
+	}
+	raw := make([]byte, messageHeaderSize+int(binary.BigEndian.Uint16(header[2:])))
+	copy(raw, header)
+	if _, err := io.ReadFull(r, raw[messageHeaderSize:]); err != nil {
+		return nil, err
+	}
+	return raw, nil
+}
+
+// handleData queues the packet of a Data indication to be read
+func (a *Allocation) handleData(msg *stun.Message) {
+	peerAttr, hasPeer := msg.GetOneAttribute(stun.AttrXORPeerAddress)
+	dataAttr, hasData := msg.GetOneAttribute(stun.AttrData)
+	if !hasPeer || !hasData {
+		return
+	}
+	var peer stun.XorAddress
+	if err := peer.Unpack(msg, peerAttr); err != nil {
+		return
+	}
+
+	select {
+	case a.packets <- &packet{buffer: dataAttr.Value, srcAddr: &net.UDPAddr{IP: peer.IP, Port: peer.Port}}:
+	case <-a.closed:
+	}
+}
+
+// RelayedAddr returns the relayed transport address, the address of the
+// relay candidate
+func (a *Allocation) RelayedAddr() *net.UDPAddr {
+	return a.relayedAddr
+}
+
+// MappedAddr returns the server reflexive address the server received the
+// requests from, the related address of the relay candidate
+func (a *Allocation) MappedAddr() *net.UDPAddr {
+	return a.mappedAddr
+}
+
+// ReadFrom reads the next packet relayed from a peer
+func (a *Allocation) ReadFrom(b []byte) (int, net.Addr, error) {
+	select {
+	case p := <-a.packets:
+		return copy(b, p.buffer), p.srcAddr, nil
+	case <-a.closed:
+		return 0, nil, errClosed
+	}
+}
+
+// WriteTo sends the packet to the peer in a Send indication, permitting the
+// peer first
+func (a *Allocation) WriteTo(b []byte, addr net.Addr) (int, error) {
+	peer, ok := addr.(*net.UDPAddr)
+	if !ok {
+		return 0, errInvalidPeerAddr
+	}
+	a.permit(peer)
+
+	msg, err := stun.Build(stun.ClassIndication, stun.MethodSend, stun.GenerateTransactionId(),
+		&stun.XorPeerAddress{XorAddress: stun.XorAddress{IP: peer.IP, Port: peer.Port}},
+		&stun.Data{Data: b},
+	)
+	if err != nil {
+		return 0, err
+	}
+	if err = a.send(msg.Pack()); err != nil {
+		return 0, err
+	}
+	return len(b), nil
+}
+
+// Close deletes the allocation on the server and closes the conn to it
+func (a *Allocation) Close() error {
+	select {
+	case <-a.closed:
+		return nil
+	default:
+	}
+
+	// A refresh with a lifetime of zero deletes the allocation, its response
+	// isn't waited for
+	msg, err := stun.Build(stun.ClassRequest, stun.MethodRefresh, stun.GenerateTransactionId(),
+		a.authenticate([]stun.Attribute{&stun.Lifetime{}})...)
+	if err == nil {
+		err = a.send(msg.Pack())
+	}
+	if err != nil {
+		a.log.Debugf("Failed to delete the TURN allocation %s: %v", a.relayedAddr, err)
+	}
+	return a.shutdown()
+}
+
+func (a *Allocation) shutdown() error {
+	var err error
+	a.closeOnce.Do(func() {
+		close(a.closed)
+		err = a.conn.Close()
+	})
+	return err
+}
+
+// LocalAddr returns the relayed transport address
+func (a *Allocation) LocalAddr() net.Addr {
+	return a.relayedAddr
+}
+
+// SetDeadline is not supported, packets are relayed from every peer
+func (a *Allocation) SetDeadline(t time.Time) error {
+	return nil
+}
+
+// SetReadDeadline is not supported, packets are relayed from every peer
+func (a *Allocation) SetReadDeadline(t time.Time) error {
+	return nil
+}
+
+// SetWriteDeadline is not supported, packets are relayed to every peer
+func (a *Allocation) SetWriteDeadline(t time.Time) error {
+	return nil
+}
+
+// requestedTransport is the REQUESTED-TRANSPORT of allocations, UDP, which
+// the stun package doesn't pack
+type requestedTransport struct{}
+
+func (requestedTransport) Pack(message *stun.Message) error {
+	message.AddAttribute(stun.AttrRequestedTransport, []byte{protocolUDP, 0, 0, 0})
+	return nil
+}
+
+func (requestedTransport) Unpack(message *stun.Message, rawAttribute *stun.RawAttribute) error {
+	return nil
+}
+
+// attrAccessToken is the type of the ACCESS-TOKEN attribute, RFC 7635
+// Section 6.2
+const attrAccessToken stun.AttrType = 0x001B
+
+// accessToken is the ACCESS-TOKEN of requests authenticated with OAuth
+type accessToken []byte
+
+func (t accessToken) Pack(message *stun.Message) error {
+	message.AddAttribute(attrAccessToken, t)
+	return nil
+}
+
+func (t accessToken) Unpack(message *stun.Message, rawAttribute *stun.RawAttribute) error {
+	return nil
+}
+
+// errorCode returns the code and reason of the ERROR-CODE of the response,
+// which the stun package doesn't unpack
+func errorCode(res *stun.Message) (int, string) {
+	attr, ok := res.GetOneAttribute(stun.AttrErrorCode)
+	if !ok || len(attr.Value) < 4 {
+		return 0, ""
+	}
+	return int(attr.Value[2]&0x07)*100 + int(attr.Value[3]), string(attr.Value[4:])
+}
+
+// addrIPPort returns the IP and port of a UDP or TCP address
+func addrIPPort(addr net.Addr) (net.IP, int) {
+	switch addr := addr.(type) {
+	case *net.UDPAddr:
+		return addr.IP, addr.Port
+	case *net.TCPAddr:
+		return addr.IP, addr.Port
+	default:
+		return nil, 0
+	}
+}
@@ -0,0 +1,186 @@
+package turn
+
+import (
+	"errors"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pions/webrtc/pkg/logging"
+	"github.com/stretchr/testify/assert"
+)
+
+var testCredentials = Credentials{Username: "user", Password: "pass"}
+
+func TestAllocate(t *testing.T) {
+	server, err := NewServer("127.0.0.1", testCredentials, 0)
+	assert.Nil(t, err)
+	defer func() { assert.Nil(t, server.Close()) }()
+
+	log := logging.NewDefaultLoggerFactory().NewLogger(logging.ScopeICE)
+	dialers := []func() (net.Conn, error){
+		func() (net.Conn, error) { return net.Dial("udp", server.UDPAddr().String()) },
+		func() (net.Conn, error) { return net.Dial("tcp", server.TCPAddr().String()) },
+	}
+	for i, dial := range dialers {
+		conn, err := dial()
+		assert.Nil(t, err, "testCase: %d", i)
+		a, err := Allocate(conn, StaticCredentials(testCredentials), log)
+		if !assert.Nil(t, err, "testCase: %d", i) {
+			continue
+		}
+		assert.Equal(t, a.RelayedAddr(), a.LocalAddr(), "testCase: %d", i)
+		assert.Equal(t, conn.LocalAddr().String(), a.MappedAddr().String(), "testCase: %d", i)
+
+		peer, err := net.ListenPacket("udp", "127.0.0.1:0")
+		assert.Nil(t, err, "testCase: %d", i)
+
+		// The first packet permits the peer, the packets sent before the
+		// permission is installed are dropped
+		buffer := make([]byte, 16)
+		received := make(chan net.Addr, 1)
+		go func() {
+			n, addr, err := peer.ReadFrom(buffer)
+			if err == nil && string(buffer[:n]) == "ping" {
+				received <- addr
+			}
+		}()
+		var relayedAddr net.Addr
+		for relayedAddr == nil {
+			_, err = a.WriteTo([]byte("ping"), peer.LocalAddr())
+			assert.Nil(t, err, "testCase: %d", i)
+			select {
+			case relayedAddr = <-received:
+			case <-time.After(50 * time.Millisecond):
+			}
+		}
+		assert.Equal(t, a.RelayedAddr().String(), relayedAddr.String(), "testCase: %d", i)
+
+		_, err = peer.WriteTo([]byte("pong"), relayedAddr)
+		assert.Nil(t, err, "testCase: %d", i)
+		n, srcAddr, err := a.ReadFrom(buffer)
+		assert.Nil(t, err, "testCase: %d", i)
+		assert.Equal(t, "pong", string(buffer[:n]), "testCase: %d", i)
+		assert.Equal(t, peer.LocalAddr().String(), srcAddr.String(), "testCase: %d", i)
+
+		assert.Nil(t, peer.Close(), "testCase: %d", i)
+		assert.Nil(t, a.Close(), "testCase: %d", i)
+		_, _, err = a.ReadFrom(buffer)
+		assert.Equal(t, errClosed, err, "testCase: %d", i)
+	}
+
+	// The allocations are deleted when closed
+	for server.Allocations() != 0 {
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestAllocate_Unauthorized(t *testing.T) {
+	server, err := NewServer("127.0.0.1", testCredentials, 0)
+	assert.Nil(t, err)
+	defer func() { assert.Nil(t, server.Close()) }()
+
+	conn, err := net.Dial("udp", server.UDPAddr().String())
+	assert.Nil(t, err)
+	_, err = Allocate(conn, StaticCredentials(Credentials{Username: "user", Password: "wrong"}), logging.NewDefaultLoggerFactory().NewLogger(logging.ScopeICE))
+	assert.Error(t, err)
+	assert.Equal(t, 0, server.Allocations())
+}
+
+func TestAllocate_OAuth(t *testing.T) {
+	credentials := Credentials{Username: "kid", AccessToken: []byte("token"), MacKey: make([]byte, 20)}
+	server, err := NewServer("127.0.0.1", credentials, 0)
+	assert.Nil(t, err)
+	defer func() { assert.Nil(t, server.Close()) }()
+
+	conn, err := net.Dial("udp", server.UDPAddr().String())
+	assert.Nil(t, err)
+	a, err := Allocate(conn, StaticCredentials(credentials), logging.NewDefaultLoggerFactory().NewLogger(logging.ScopeICE))
+	assert.Nil(t, err)
+	assert.Equal(t, 1, server.Allocations())
+	assert.Nil(t, a.Close())
+}
+
+func TestAllocation_Refresh(t *testing.T) {
+	server, err := NewServer("127.0.0.1", testCredentials, 2*time.Second)
+	assert.Nil(t, err)
+	defer func() { assert.Nil(t, server.Close()) }()
+
+	conn, err := net.Dial("udp", server.UDPAddr().String())
+	assert.Nil(t, err)
+	a, err := Allocate(conn, StaticCredentials(testCredentials), logging.NewDefaultLoggerFactory().NewLogger(logging.ScopeICE))
+	assert.Nil(t, err)
+
+	// The allocation outlives its lifetime as it is refreshed halfway
+	// through it
+	time.Sleep(3 * time.Second)
+	assert.Equal(t, 1, server.Allocations())
+	assert.Nil(t, a.Close())
+}
+
+func TestAllocation_RefreshCredentials(t *testing.T) {
+	server, err := NewServer("127.0.0.1", testCredentials, 2*time.Second)
+	assert.Nil(t, err)
+	defer func() { assert.Nil(t, server.Close()) }()
+
+	renewed := Credentials{Username: "user", Password: "renewed"}
+	var lock sync.Mutex
+	var until []time.Time
+	credentials := func(at time.Time) (Credentials, error) {
+		lock.Lock()
+		defer lock.Unlock()
+		until = append(until, at)
+		if len(until) == 1 {
+			return testCredentials, nil
+		}
+		return renewed, nil
+	}
+
+	conn, err := net.Dial("udp", server.UDPAddr().String())
+	assert.Nil(t, err)
+	a, err := Allocate(conn, credentials, logging.NewDefaultLoggerFactory().NewLogger(logging.ScopeICE))
+	assert.Nil(t, err)
+
+	// The refresh is rejected unless it is sent with the renewed
+	// credentials, which are asked for until the next refresh
+	server.SetCredentials(renewed)
+	time.Sleep(3 * time.Second)
+	assert.Equal(t, 1, server.Allocations())
+
+	lock.Lock()
+	assert.True(t, len(until) >= 2)
+	assert.WithinDuration(t, until[0].Add(2*time.Second), until[1], 500*time.Millisecond)
+	lock.Unlock()
+	assert.Nil(t, a.Close())
+}
+
+func TestAllocate_CredentialsError(t *testing.T) {
+	server, err := NewServer("127.0.0.1", testCredentials, 0)
+	assert.Nil(t, err)
+	defer func() { assert.Nil(t, server.Close()) }()
+
+	expected := errors.New("expired")
+	conn, err := net.Dial("udp", server.UDPAddr().String())
+	assert.Nil(t, err)
+	_, err = Allocate(conn, func(time.Time) (Credentials, error) {
+		return Credentials{}, expected
+	}, logging.NewDefaultLoggerFactory().NewLogger(logging.ScopeICE))
+	assert.Equal(t, expected, err)
+	assert.Equal(t, 0, server.Allocations())
+}
+
+func TestRefreshInterval(t *testing.T) {
+	testCases := []struct {
+		lifetime time.Duration
+		expected time.Duration
+	}{
+		{10 * time.Minute, 9 * time.Minute},
+		{time.Minute, 30 * time.Second},
+		{2 * time.Second, time.Second},
+	}
+
+	for i, testCase := range testCases {
+		assert.Equal(t, testCase.expected, refreshInterval(testCase.lifetime), "testCase: %d", i)
+	}
+}
@@ -0,0 +1,364 @@
+package turn
+
+import (
+	"crypto/hmac"
+	"crypto/sha1" // nolint: gosec
+	"encoding/binary"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/pions/pkg/stun"
+)
+
+const (
+	// defaultLifetime is the lifetime of allocations, RFC 5766 Section 2.2
+	defaultLifetime = 10 * time.Minute
+
+	serverRealm = "pion"
+	serverNonce = "f0b2e4a5c7d9"
+
+	// messageIntegritySize is the size of the MESSAGE-INTEGRITY attribute
+	messageIntegritySize = 24
+)
+
+// Server is a minimal TURN server for the tests of relay candidates, it
+// relays UDP for the clients of a single user authenticated with the
+// long-term credential mechanism. Clients reach it over UDP and TCP.
+type Server struct {
+	lifetime time.Duration
+	udpConn  net.PacketConn
+	listener net.Listener
+
+	lock        sync.Mutex
+	credentials Credentials
+	allocations map[string]*serverAllocation
+}
+
+type serverAllocation struct {
+	relay       net.PacketConn
+	client      *serverClient
+	expiry      *time.Timer
+	permissions map[string]bool
+}
+
+// serverClient is the transport a client reaches the server over
+type serverClient struct {
+	addr      net.Addr
+	writeLock sync.Mutex
+	write     func([]byte) error
+}
+
+// NewServer listens for clients on ports of the IP, over UDP and TCP.
+// Allocations not refreshed within the lifetime expire, zero is the default
+// lifetime of 10 minutes.
+func NewServer(ip string, credentials Credentials, lifetime time.Duration) (*Server, error) {
+	if lifetime == 0 {
+		lifetime = defaultLifetime
+	}
+
+	udpConn, err := net.ListenPacket("udp", net.JoinHostPort(ip, "0"))
+	if err != nil {
+		return nil, err
+	}
+	listener, err := net.Listen("tcp", net.JoinHostPort(ip, "0"))
+	if err != nil {
+		_ = udpConn.Close()
+		return nil, err
+	}
+
+	s := &Server{
+		lifetime:    lifetime,
+		udpConn:     udpConn,
+		listener:    listener,
+		credentials: credentials,
+		allocations: make(map[string]*serverAllocation),
+	}
+	go s.udpLoop()
+	go s.acceptLoop()
+	return s, nil
+}
+
+// UDPAddr returns the address clients reach the server at over UDP
+func (s *Server) UDPAddr() net.Addr {
+	return s.udpConn.LocalAddr()
+}
+
+// TCPAddr returns the address clients reach the server at over TCP
+func (s *Server) TCPAddr() net.Addr {
+	return s.listener.Addr()
+}
+
+// SetCredentials replaces the credentials of the user, requests with the
+// previous ones are rejected
+func (s *Server) SetCredentials(credentials Credentials) {
+	s.lock.Lock()
+	s.credentials = credentials
+	s.lock.Unlock()
+}
+
+// Allocations returns how many allocations haven't expired or been deleted
+func (s *Server) Allocations() int {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return len(s.allocations)
+}
+
+// Close stops listening and deletes every allocation
+func (s *Server) Close() error {
+	s.lock.Lock()
+	for key, a := range s.allocations {
+		s.deleteAllocation(key, a)
+	}
+	s.lock.Unlock()
+
+	err := s.listener.Close()
+	if udpErr := s.udpConn.Close(); err == nil {
+		err = udpErr
+	}
+	return err
+}
+
+func (s *Server) udpLoop() {
+	buffer := make([]byte, maxMessageSize)
+	for {
+		n, addr, err := s.udpConn.ReadFrom(buffer)
+		if err != nil {
+			return
+		}
+		client := &serverClient{addr: addr, write: func(raw []byte) error {
+			_, err := s.udpConn.WriteTo(raw, addr)
+			return err
+		}}
+		s.handle(append([]byte{}, buffer[:n]...), client)
+	}
+}
+
+func (s *Server) acceptLoop() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		go s.streamLoop(conn)
+	}
+}
+
+func (s *Server) streamLoop(conn net.Conn) {
+	client := &serverClient{addr: conn.RemoteAddr(), write: func(raw []byte) error {
+		_, err := conn.Write(raw)
+		return err
+	}}
+	defer func() {
+		_ = conn.Close()
+		s.lock.Lock()
+		if a, ok := s.allocations[clientKey(client)]; ok {
+			s.deleteAllocation(clientKey(client), a)
+		}
+		s.lock.Unlock()
+	}()
+
+	for {
+		raw, err := readStreamMessage(conn)
+		if err != nil {
+			return
+		}
+		s.handle(raw, client)
+	}
+}
+
+// clientKey identifies the allocation of the client by its transport
+// address, RFC 5766 Section 2.2
+func clientKey(client *serverClient) string {
+	return client.addr.Network() + ":" + client.addr.String()
+}
+
+func (s *Server) handle(raw []byte, client *serverClient) {
+	msg, err := stun.NewMessage(raw)
+	if err != nil {
+		return
+	}
+
+	switch {
+	case msg.Class == stun.ClassIndication && msg.Method == stun.MethodSend:
+		s.handleSend(msg, client)
+		return
+	case msg.Class != stun.ClassRequest:
+		return
+	case !s.authenticated(msg):
+		s.respond(client, msg, stun.ClassErrorResponse, &stun.Err401Unauthorized,
+			&stun.Realm{Realm: serverRealm}, &stun.Nonce{Nonce: serverNonce})
+		return
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	switch msg.Method {
+	case stun.MethodAllocate:
+		s.handleAllocate(msg, client)
+	case stun.MethodRefresh:
+		s.handleRefresh(msg, client)
+	case stun.MethodCreatePermission:
+		s.handleCreatePermission(msg, client)
+	default:
+		s.respond(client, msg, stun.ClassErrorResponse, &stun.Err400BadRequest)
+	}
+}
+
+// authenticated checks the USERNAME and MESSAGE-INTEGRITY of the request,
+// the HMAC covers the message up to the attribute with the length of the
+// message ending with it, RFC 5389 Section 15.4
+func (s *Server) authenticated(msg *stun.Message) bool {
+	usernameAttr, hasUsername := msg.GetOneAttribute(stun.AttrUsername)
+	integrityAttr, hasIntegrity := msg.GetOneAttribute(stun.AttrMessageIntegrity)
+	if !hasUsername || !hasIntegrity {
+		return false
+	}
+
+	s.lock.Lock()
+	credentials := s.credentials
+	s.lock.Unlock()
+	if string(usernameAttr.Value) != credentials.Username {
+		return false
+	}
+
+	signed := append([]byte{}, msg.Raw[:integrityAttr.Offset]...)
+	binary.BigEndian.PutUint16(signed[2:], uint16(len(signed)-messageHeaderSize+messageIntegritySize))
+	mac := hmac.New(sha1.New, credentials.key(serverRealm))
+	mac.Write(signed) // writes to a hash never fail
+	return hmac.Equal(mac.Sum(nil), integrityAttr.Value)
+}
+
+func (s *Server) handleAllocate(msg *stun.Message, client *serverClient) {
+	key := clientKey(client)
+	if _, ok := s.allocations[key]; ok {
+		s.respond(client, msg, stun.ClassErrorResponse, &stun.Err437AllocationMismatch)
+		return
+	}
+
+	ip := s.udpConn.LocalAddr().(*net.UDPAddr).IP
+	relay, err := net.ListenPacket("udp", net.JoinHostPort(ip.String(), "0"))
+	if err != nil {
+		s.respond(client, msg, stun.ClassErrorResponse, &stun.Err508InsufficentCapacity)
+		return
+	}
+	a := &serverAllocation{relay: relay, client: client, permissions: make(map[string]bool)}
+	a.expiry = time.AfterFunc(s.lifetime, func() {
+		s.lock.Lock()
+		defer s.lock.Unlock()
+		if s.allocations[key] == a {
+			s.deleteAllocation(key, a)
+		}
+	})
+	s.allocations[key] = a
+	go s.relayLoop(a)
+
+	relayedAddr := relay.LocalAddr().(*net.UDPAddr)
+	mappedIP, mappedPort := addrIPPort(client.addr)
+	s.respond(client, msg, stun.ClassSuccessResponse,
+		&stun.XorRelayedAddress{XorAddress: stun.XorAddress{IP: relayedAddr.IP, Port: relayedAddr.Port}},
+		&stun.XorMappedAddress{XorAddress: stun.XorAddress{IP: mappedIP, Port: mappedPort}},
+		&stun.Lifetime{Duration: uint32(s.lifetime / time.Second)},
+	)
+}
+
+func (s *Server) handleRefresh(msg *stun.Message, client *serverClient) {
+	key := clientKey(client)
+	a, ok := s.allocations[key]
+	if !ok {
+		s.respond(client, msg, stun.ClassErrorResponse, &stun.Err437AllocationMismatch)
+		return
+	}
+
+	var lifetime stun.Lifetime
+	if attr, ok := msg.GetOneAttribute(stun.AttrLifetime); ok && lifetime.Unpack(msg, attr) == nil && lifetime.Duration == 0 {
+		s.deleteAllocation(key, a)
+		s.respond(client, msg, stun.ClassSuccessResponse, &stun.Lifetime{})
+		return
+	}
+	a.expiry.Reset(s.lifetime)
+	s.respond(client, msg, stun.ClassSuccessResponse, &stun.Lifetime{Duration: uint32(s.lifetime / time.Second)})
+}
+
+func (s *Server) handleCreatePermission(msg *stun.Message, client *serverClient) {
+	a, ok := s.allocations[clientKey(client)]
+	if !ok {
+		s.respond(client, msg, stun.ClassErrorResponse, &stun.Err437AllocationMismatch)
+		return
+	}
+
+	var peer stun.XorAddress
+	attr, ok := msg.GetOneAttribute(stun.AttrXORPeerAddress)
+	if !ok || peer.Unpack(msg, attr) != nil {
+		s.respond(client, msg, stun.ClassErrorResponse, &stun.Err400BadRequest)
+		return
+	}
+	a.permissions[peer.IP.String()] = true
+	s.respond(client, msg, stun.ClassSuccessResponse)
+}
+
+// handleSend relays the data of the Send indication to a permitted peer
+func (s *Server) handleSend(msg *stun.Message, client *serverClient) {
+	var peer stun.XorAddress
+	peerAttr, hasPeer := msg.GetOneAttribute(stun.AttrXORPeerAddress)
+	dataAttr, hasData := msg.GetOneAttribute(stun.AttrData)
+	if !hasPeer || !hasData || peer.Unpack(msg, peerAttr) != nil {
+		return
+	}
+
+	s.lock.Lock()
+	a, ok := s.allocations[clientKey(client)]
+	permitted := ok && a.permissions[peer.IP.String()]
+	s.lock.Unlock()
+	if permitted {
+		_, _ = a.relay.WriteTo(dataAttr.Value, &net.UDPAddr{IP: peer.IP, Port: peer.Port})
+	}
+}
+
+// relayLoop relays the packets of permitted peers to the client in Data
+// indications
+func (s *Server) relayLoop(a *serverAllocation) {
+	buffer := make([]byte, maxMessageSize)
+	for {
+		n, addr, err := a.relay.ReadFrom(buffer)
+		if err != nil {
+			return
+		}
+		peer := addr.(*net.UDPAddr)
+
+		s.lock.Lock()
+		permitted := a.permissions[peer.IP.String()]
+		s.lock.Unlock()
+		if !permitted {
+			continue
+		}
+
+		msg, err := stun.Build(stun.ClassIndication, stun.MethodData, stun.GenerateTransactionId(),
+			&stun.XorPeerAddress{XorAddress: stun.XorAddress{IP: peer.IP, Port: peer.Port}},
+			&stun.Data{Data: buffer[:n]},
+		)
+		if err == nil {
+			_ = a.client.send(msg.Pack())
+		}
+	}
+}
+
+func (s *Server) respond(client *serverClient, req *stun.Message, class stun.MessageClass, attrs ...stun.Attribute) {
+	res, err := stun.Build(class, req.Method, req.TransactionID, attrs...)
+	if err == nil {
+		_ = client.send(res.Pack())
+	}
+}
+
+// deleteAllocation deletes the allocation, the lock is held
+func (s *Server) deleteAllocation(key string, a *serverAllocation) {
+	a.expiry.Stop()
+	_ = a.relay.Close()
+	delete(s.allocations, key)
+}
+
+func (c *serverClient) send(raw []byte) error {
+	c.writeLock.Lock()
+	defer c.writeLock.Unlock()
+	return c.write(raw)
+}
@@ -0,0 +1,96 @@
+package ulpfec
+
+import (
+	"encoding/binary"
+
+	"github.com/pions/webrtc/pkg/rtp"
+)
+
+// historySize is the amount of media packets remembered for recovery, it has
+// to divide 65536 so the history wraps together with the sequence numbers
+const historySize = 256
+
+// Decoder recovers lost media packets of a single stream from its FEC packets
+type Decoder struct {
+	ssrc    uint32
+	history [historySize][]byte
+}
+
+// NewDecoder creates a Decoder for the media stream identified by ssrc
+func NewDecoder(ssrc uint32) *Decoder {
+	return &Decoder{ssrc: ssrc}
+}
+
+// PushMedia records a received media packet, it is used to recover the
+// packets lost in its group
+func (d *Decoder) PushMedia(packet *rtp.Packet) error {
+	clone := *packet
+	raw, err := clone.Marshal()
+	if err != nil {
+		return err
+	}
+	d.history[packet.SequenceNumber%historySize] = raw
+	return nil
+}
+
+// PushFEC processes an FEC packet and returns the media packet it recovered.
+// Nothing is returned if no packet of its group is missing, or if too many
+// are missing to be recovered.
+func (d *Decoder) PushFEC(packet *rtp.Packet) (*rtp.Packet, error) {
+	h := &header{}
+	protection, err := h.unmarshal(packet.Payload)
+	if err != nil {
+		return nil, err
+	}
+
+	missing := -1
+	var present [][]byte
+	for offset := uint16(0); offset < MaxGroupSize; offset++ {
+		if !h.protects(offset) {
+			continue
+		}
+
+		sequenceNumber := h.sequenceNumberBase + offset
+		if raw := d.get(sequenceNumber); raw != nil {
+			present = append(present, raw)
+		} else if missing != -1 {
+			return nil, nil
+		} else {
+			missing = int(sequenceNumber)
+		}
+	}
+	if missing == -1 {
+		return nil, nil
+	}
+
+	payload := append([]byte{}, protection...)
+	for _, raw := range present {
+		payload = h.xorPacket(raw, payload)
+	}
+	if int(h.lengthRecovery) > len(payload) {
+		return nil, errPacketTooShort
+	}
+
+	raw := make([]byte, rtpHeaderLength, rtpHeaderLength+int(h.lengthRecovery))
+	raw[0] = 0x80 | h.recoveryBits[0]&0x3f
+	raw[1] = h.recoveryBits[1]
+	binary.BigEndian.PutUint16(raw[2:], uint16(missing))
+	binary.BigEndian.PutUint32(raw[4:], h.timestampRecovery)
+	binary.BigEndian.PutUint32(raw[8:], d.ssrc)
+	raw = append(raw, payload[:h.lengthRecovery]...)
+
+	recovered := &rtp.Packet{}
+	if err := recovered.Unmarshal(raw); err != nil {
+		return nil, err
+	}
+	d.history[uint16(missing)%historySize] = raw
+	return recovered, nil
+}
+
+func (d *Decoder) get(sequenceNumber uint16) []byte {
+	raw := d.history[sequenceNumber%historySize]
+	if raw == nil || binary.BigEndian.Uint16(raw[2:]) != sequenceNumber {
+		return nil
+	}
+	return raw
+}
@@ -0,0 +1,90 @@
+package ulpfec
+
+import (
+	"github.com/pions/webrtc/pkg/rtp"
+)
+
+// Encoder generates FEC packets protecting consecutive groups of media packets
+type Encoder struct {
+	groupSize   int
+	ssrc        uint32
+	payloadType uint8
+	sequencer   rtp.Sequencer
+
+	group [][]byte
+}
+
+// NewEncoder creates an Encoder emitting an FEC packet every groupSize media
+// packets, on its own stream identified by ssrc and payloadType
+func NewEncoder(groupSize int, ssrc uint32, payloadType uint8) *Encoder {
+	if groupSize < 1 {
+		groupSize = 1
+	} else if groupSize > MaxGroupSize {
+		groupSize = MaxGroupSize
+	}
+
+	return &Encoder{
+		groupSize:   groupSize,
+		ssrc:        ssrc,
+		payloadType: payloadType,
+		sequencer:   rtp.NewRandomSequencer(),
+	}
+}
+
+// SSRC returns the SSRC of the FEC stream
+func (e *Encoder) SSRC() uint32 {
+	return e.ssrc
+}
+
+// Push adds a media packet to the current group, once the group is complete
+// the FEC packet protecting it is returned
+func (e *Encoder) Push(packet *rtp.Packet) (*rtp.Packet, error) {
+	// Marshal a copy, the send path encrypts the payload in place
+	clone := *packet
+	raw, err := clone.Marshal()
+	if err != nil {
+		return nil, err
+	}
+
+	// Sequence numbers have to stay within the reach of the mask
+	if len(e.group) > 0 && uint16(packet.SequenceNumber-sequenceNumber(e.group[0])) >= MaxGroupSize {
+		e.group = nil
+	}
+
+	e.group = append(e.group, raw)
+	if len(e.group) < e.groupSize {
+		return nil, nil
+	}
+
+	group := e.group
+	e.group = nil
+	return e.protect(group, packet.Timestamp), nil
+}
+
+func (e *Encoder) protect(group [][]byte, timestamp uint32) *rtp.Packet {
+	h := &header{sequenceNumberBase: sequenceNumber(group[0])}
+
+	var payload []byte
+	for _, raw := range group {
+		offset := sequenceNumber(raw) - h.sequenceNumberBase
+		if offset >= shortMaskSize {
+			h.longMask = true
+		}
+		h.mask |= 1 << (47 - offset)
+		payload = h.xorPacket(raw, payload)
+	}
+	h.protectionLength = uint16(len(payload))
+
+	return &rtp.Packet{
+		Version:        2,
+		PayloadType:    e.payloadType,
+		SequenceNumber: e.sequencer.NextSequenceNumber(),
+		Timestamp:      timestamp,
+		SSRC:           e.ssrc,
+		Payload:        h.marshal(payload),
+	}
+}
+
+func sequenceNumber(raw []byte) uint16 {
+	return uint16(raw[2])<<8 | uint16(raw[3])
+}
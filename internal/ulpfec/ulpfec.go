@@ -0,0 +1,124 @@
+// Package ulpfec implements the generic forward error correction of RFC 5109.
+// Only level 0 protection is used, every FEC packet protects all of the media
+// packets of its group so a single loss per group can be recovered.
+package ulpfec
+
+import (
+	"encoding/binary"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	rtpHeaderLength = 12
+	fecHeaderLength = 10
+
+	levelHeaderLengthShort = 4
+	levelHeaderLengthLong  = 8
+
+	// MaxGroupSize is the largest amount of media packets a single FEC packet
+	// can protect, limited by the 48 bit long mask
+	MaxGroupSize = 48
+
+	shortMaskSize = 16
+)
+
+var (
+	errPacketTooShort = errors.New("ulpfec: packet too short")
+	errExtensionFlag  = errors.New("ulpfec: unsupported extension flag")
+)
+
+// header holds the recovery fields of an FEC packet https://tools.ietf.org/html/rfc5109#section-7.3
+type header struct {
+	longMask           bool
+	recoveryBits       [2]byte // P, X, CC, M and PT recovery
+	sequenceNumberBase uint16
+	timestampRecovery  uint32
+	lengthRecovery     uint16
+
+	protectionLength uint16
+	mask             uint64
+}
+
+func (h *header) levelHeaderLength() int {
+	if h.longMask {
+		return levelHeaderLengthLong
+	}
+	return levelHeaderLengthShort
+}
+
+func (h *header) marshal(payload []byte) []byte {
+	raw := make([]byte, fecHeaderLength+h.levelHeaderLength(), fecHeaderLength+h.levelHeaderLength()+len(payload))
+	raw[0] = h.recoveryBits[0] & 0x3f
+	if h.longMask {
+		raw[0] |= 0x40
+	}
+	raw[1] = h.recoveryBits[1]
+	binary.BigEndian.PutUint16(raw[2:], h.sequenceNumberBase)
+	binary.BigEndian.PutUint32(raw[4:], h.timestampRecovery)
+	binary.BigEndian.PutUint16(raw[8:], h.lengthRecovery)
+
+	binary.BigEndian.PutUint16(raw[10:], h.protectionLength)
+	if h.longMask {
+		binary.BigEndian.PutUint16(raw[12:], uint16(h.mask>>32))
+		binary.BigEndian.PutUint32(raw[14:], uint32(h.mask))
+	} else {
+		binary.BigEndian.PutUint16(raw[12:], uint16(h.mask>>32))
+	}
+	return append(raw, payload...)
+}
+
+// unmarshal parses the FEC headers from the payload of an FEC packet and
+// returns the level 0 protection payload
+func (h *header) unmarshal(raw []byte) ([]byte, error) {
+	if len(raw) < fecHeaderLength+levelHeaderLengthShort {
+		return nil, errPacketTooShort
+	}
+	if raw[0]&0x80 != 0 {
+		return nil, errExtensionFlag
+	}
+
+	h.longMask = raw[0]&0x40 != 0
+	h.recoveryBits = [2]byte{raw[0] & 0x3f, raw[1]}
+	h.sequenceNumberBase = binary.BigEndian.Uint16(raw[2:])
+	h.timestampRecovery = binary.BigEndian.Uint32(raw[4:])
+	h.lengthRecovery = binary.BigEndian.Uint16(raw[8:])
+
+	if len(raw) < fecHeaderLength+h.levelHeaderLength() {
+		return nil, errPacketTooShort
+	}
+	h.protectionLength = binary.BigEndian.Uint16(raw[10:])
+	h.mask = uint64(binary.BigEndian.Uint16(raw[12:])) << 32
+	if h.longMask {
+		h.mask |= uint64(binary.BigEndian.Uint32(raw[14:]))
+	}
+
+	payload := raw[fecHeaderLength+h.levelHeaderLength():]
+	if len(payload) < int(h.protectionLength) {
+		return nil, errPacketTooShort
+	}
+	return payload[:h.protectionLength], nil
+}
+
+// protects reports if the media packet with the offset from the
+// sequence number base is covered by the mask
+func (h *header) protects(offset uint16) bool {
+	return offset < MaxGroupSize && h.mask&(1<<(47-offset)) != 0
+}
+
+// xorPacket folds a marshaled media packet into the recovery fields and payload
+func (h *header) xorPacket(raw []byte, payload []byte) []byte {
+	h.recoveryBits[0] ^= raw[0]
+	h.recoveryBits[1] ^= raw[1]
+	h.timestampRecovery ^= binary.BigEndian.Uint32(raw[4:])
+	h.lengthRecovery ^= uint16(len(raw) - rtpHeaderLength)
+
+	body := raw[rtpHeaderLength:]
+	for len(payload) < len(body) {
+		payload = append(payload, 0)
+	}
+	for i, b := range body {
+		payload[i] ^= b
+	}
+	return payload
+}
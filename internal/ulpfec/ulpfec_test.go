@@ -0,0 +1,95 @@
+package ulpfec
+
+import (
+	"testing"
+
+	"github.com/pions/webrtc/pkg/rtp"
+	"github.com/stretchr/testify/assert"
+)
+
+func mediaPacket(sequenceNumber uint16, payload []byte) *rtp.Packet {
+	return &rtp.Packet{
+		Version:        2,
+		PayloadType:    96,
+		SequenceNumber: sequenceNumber,
+		Timestamp:      3000 + uint32(sequenceNumber),
+		SSRC:           5000,
+		Marker:         sequenceNumber%2 == 0,
+		Payload:        payload,
+	}
+}
+
+func TestRecovery(t *testing.T) {
+	for _, groupSize := range []int{1, 3, 20} {
+		encoder := NewEncoder(groupSize, 6000, 125)
+		decoder := NewDecoder(5000)
+
+		var fec *rtp.Packet
+		var sent []*rtp.Packet
+		for i := 0; i < groupSize; i++ {
+			p := mediaPacket(uint16(65530+i), make([]byte, 10+i*3))
+			for j := range p.Payload {
+				p.Payload[j] = byte(i + j)
+			}
+			sent = append(sent, p)
+
+			var err error
+			fec, err = encoder.Push(p)
+			assert.Nil(t, err)
+			if i < groupSize-1 {
+				assert.Nil(t, fec)
+			}
+		}
+		if !assert.NotNil(t, fec) {
+			continue
+		}
+		assert.Equal(t, uint32(6000), fec.SSRC)
+		assert.Equal(t, uint8(125), fec.PayloadType)
+
+		// Lose the packet in the middle of the group
+		lost := groupSize / 2
+		for i, p := range sent {
+			if i != lost {
+				assert.Nil(t, decoder.PushMedia(p))
+			}
+		}
+
+		recovered, err := decoder.PushFEC(fec)
+		assert.Nil(t, err)
+		if !assert.NotNil(t, recovered) {
+			continue
+		}
+		assert.Equal(t, sent[lost].SequenceNumber, recovered.SequenceNumber)
+		assert.Equal(t, sent[lost].Timestamp, recovered.Timestamp)
+		assert.Equal(t, sent[lost].Marker, recovered.Marker)
+		assert.Equal(t, sent[lost].PayloadType, recovered.PayloadType)
+		assert.Equal(t, sent[lost].SSRC, recovered.SSRC)
+		assert.Equal(t, sent[lost].Payload, recovered.Payload)
+
+		// The packet is known now, there is nothing left to recover
+		recovered, err = decoder.PushFEC(fec)
+		assert.Nil(t, err)
+		assert.Nil(t, recovered)
+	}
+}
+
+func TestRecoveryTooManyLost(t *testing.T) {
+	encoder := NewEncoder(3, 6000, 125)
+	decoder := NewDecoder(5000)
+
+	var fec *rtp.Packet
+	for i := 0; i < 3; i++ {
+		p := mediaPacket(uint16(i), []byte{byte(i)})
+		if i == 0 {
+			assert.Nil(t, decoder.PushMedia(p))
+		}
+		fec, _ = encoder.Push(p)
+	}
+
+	recovered, err := decoder.PushFEC(fec)
+	assert.Nil(t, err)
+	assert.Nil(t, recovered)
+
+	_, err = decoder.PushFEC(&rtp.Packet{Payload: []byte{0x00}})
+	assert.Equal(t, errPacketTooShort, err)
+}
@@ -0,0 +1,125 @@
+//go:build js && wasm
+
+package webrtc
+
+import (
+	"errors"
+	"syscall/js"
+
+	"github.com/pions/webrtc/pkg/rtcerr"
+)
+
+// awaitPromise waits for the promise of a browser API to settle and returns
+// its value, or its rejection as an error. It blocks the calling goroutine,
+// so it must not be called from a callback of the browser, which would block
+// its event loop.
+func awaitPromise(promise js.Value) (js.Value, error) {
+	resolved := make(chan js.Value, 1)
+	rejected := make(chan js.Value, 1)
+
+	onResolved := js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+		resolved <- argument(args, 0)
+		return nil
+	})
+	defer onResolved.Release()
+	onRejected := js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+		rejected <- argument(args, 0)
+		return nil
+	})
+	defer onRejected.Release()
+
+	promise.Call("then", onResolved, onRejected)
+	select {
+	case value := <-resolved:
+		return value, nil
+	case reason := <-rejected:
+		return js.Undefined(), browserError(reason)
+	}
+}
+
+// callBrowser calls a browser API which may throw, the exception is
+// returned as an error
+func callBrowser(f func() js.Value) (value js.Value, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			jsErr, ok := r.(js.Error)
+			if !ok {
+				panic(r)
+			}
+			err = browserError(jsErr.Value)
+		}
+	}()
+	return f(), nil
+}
+
+// browserError maps a DOMException to the rtcerr type of the same name
+func browserError(reason js.Value) error {
+	if reason.Type() != js.TypeObject {
+		return &rtcerr.UnknownError{Err: errors.New(reason.String())}
+	}
+
+	err := errors.New(reason.Get("message").String())
+	switch reason.Get("name").String() {
+	case "InvalidStateError":
+		return &rtcerr.InvalidStateError{Err: err}
+	case "InvalidAccessError":
+		return &rtcerr.InvalidAccessError{Err: err}
+	case "NotSupportedError":
+		return &rtcerr.NotSupportedError{Err: err}
+	case "InvalidModificationError":
+		return &rtcerr.InvalidModificationError{Err: err}
+	case "SyntaxError":
+		return &rtcerr.SyntaxError{Err: err}
+	case "TypeError":
+		return &rtcerr.TypeError{Err: err}
+	case "OperationError":
+		return &rtcerr.OperationError{Err: err}
+	case "RangeError":
+		return &rtcerr.RangeError{Err: err}
+	default:
+		return &rtcerr.UnknownError{Err: err}
+	}
+}
+
+// argument returns the i-th argument of a callback, undefined if it is
+// missing
+func argument(args []js.Value, i int) js.Value {
+	if i < len(args) {
+		return args[i]
+	}
+	return js.Undefined()
+}
+
+// stringOrNull returns the string as a JavaScript value, null if it is nil
+func stringOrNull(s *string) interface{} {
+	if s == nil {
+		return nil
+	}
+	return *s
+}
+
+// uint16OrNull returns the number as a JavaScript value, null if it is nil
+func uint16OrNull(n *uint16) interface{} {
+	if n == nil {
+		return nil
+	}
+	return int(*n)
+}
+
+// valueToString returns the string, nil if the value is null or undefined
+func valueToString(v js.Value) *string {
+	if v.IsNull() || v.IsUndefined() {
+		return nil
+	}
+	s := v.String()
+	return &s
+}
+
+// valueToUint16 returns the number, nil if the value is null or undefined
+func valueToUint16(v js.Value) *uint16 {
+	if v.IsNull() || v.IsUndefined() {
+		return nil
+	}
+	n := uint16(v.Int())
+	return &n
+}
@@ -1,6 +1,12 @@
+//go:build !js
+
 package webrtc
 
 import (
+	"sync"
+	"sync/atomic"
+	"time"
+
 	"github.com/pions/webrtc/pkg/media"
 	"github.com/pions/webrtc/pkg/rtp"
 )
@@ -19,6 +25,293 @@ type RTCTrack struct {
 	Ssrc        uint32
 	Codec       *RTCRtpCodec
 	Packets     <-chan *rtp.Packet
-	Samples     chan<- media.RTCSample
-	RawRTP      chan<- *rtp.Packet
+
+	// Samples and RawRTP queue the media of a local track, they are no
+	// longer read once the RTCPeerConnection is done and aren't closed as
+	// writers would panic. Writers select on Done not to block then.
+	Samples chan<- media.RTCSample
+	RawRTP  chan<- *rtp.Packet
+
+	// sender is the RTCRtpSender the track was added with, guarded by the
+	// lock of the RTCPeerConnection
+	sender *RTCRtpSender
+
+	// sequencer numbers the packets of a sample track, it is shared with
+	// everything sent on the SSRC of the track, like DTMF events
+	sequencer rtp.Sequencer
+
+	// pc is the RTCPeerConnection sending a local track until done is
+	// closed, both are nil for remote tracks
+	pc   *RTCPeerConnection
+	done <-chan struct{}
+
+	// onQueueFullHandler is called when the Samples of a local track are
+	// queued to capacity, guarded by queueLock. queueFullPending is set
+	// until a scheduled call returns, the queue found full meanwhile is
+	// reported by it.
+	queueLock          sync.Mutex
+	onQueueFullHandler func()
+	queueFullPending   int32
+
+	// sendLock serializes the media sent on a local track, it guards the
+	// packetizer, its MTU and the DTX of the samples. packetizer is nil for
+	// raw RTP tracks.
+	sendLock   sync.Mutex
+	packetizer rtcPacketizer
+	mtu        int
+	dtx        *rtcAudioDTX
+
+	// rtpTimestampOffset rebases the timestamps of the packets written to a
+	// sample track onto the clock of the packetizer, it is set by the first
+	// packet written after a sample while rtpTimestampBased is false
+	rtpTimestampOffset uint32
+	rtpTimestampBased  bool
+
+	// clockDuration and clockSamples are the media written with WriteSample
+	// so far, timestamps are derived from their total so durations which
+	// aren't a whole number of samples don't drift
+	clockLock     sync.Mutex
+	clockDuration time.Duration
+	clockSamples  uint64
+}
+
+// rtcPacketizer is the packetizer of rtp.NewPacketizer, whose MTU and
+// contributing sources change between samples and whose timestamp skips
+// the silence of DTX
+type rtcPacketizer interface {
+	rtp.Packetizer
+	SetMTU(mtu int)
+	SetCSRC(csrc []uint32)
+	SkipSamples(skippedSamples uint32)
+	NextTimestamp() uint32
+}
+
+// absSendTimeExtensionSize is the room the abs-send-time header extension
+// takes in an RTP packet, its element padded to 32 bits and the extension
+// header
+const absSendTimeExtensionSize = 8
+
+// newRTCPacketizer returns the packetizer of the samples of the codec sent
+// on the SSRC
+func newRTCPacketizer(mtu int, codec *RTCRtpCodec, ssrc uint32, sequencer rtp.Sequencer) rtcPacketizer {
+	return rtp.NewPacketizer(
+		packetizerMTU(mtu),
+		codec.PayloadType,
+		ssrc,
+		codec.Payloader,
+		sequencer,
+		codec.ClockRate,
+	).(rtcPacketizer)
+}
+
+// packetizerMTU returns the MTU of the packetizer of media sent with the MTU,
+// the abs-send-time is stamped on the packets once they are packetized so
+// its room is reserved
+func packetizerMTU(mtu int) int {
+	return mtu - absSendTimeExtensionSize
+}
+
+// WriteSample packetizes the media, which plays for the duration, and sends
+// it on the track. The RTP timestamp advances by the duration in units of
+// the clock rate of the codec. The media is sent on the calling goroutine
+// before WriteSample returns, in order with the writes of other goroutines.
+// It returns ErrRawRTPTrack for tracks created with NewRawRTPTrack and
+// ErrConnectionClosed once the RTCPeerConnection is closed.
+func (t *RTCTrack) WriteSample(data []byte, duration time.Duration) error {
+	if t.done == nil {
+		return ErrTrackNotLocal
+	} else if t.packetizer == nil {
+		return ErrRawRTPTrack
+	} else if t.isDone() {
+		return ErrConnectionClosed
+	}
+
+	t.writeSample(media.RTCSample{Data: data, Samples: t.samplesFor(duration)})
+	return nil
+}
+
+// WriteMixedSample is WriteSample for media mixed from several streams, like
+// the output of an audio mixer. Every packet of the media lists the SSRCs of
+// the streams mixed as its contributing sources, which lets receivers
+// attribute it to the original participants. At most 15 are listed, and
+// with small MTUs as many as take half the room of the packets, the rest
+// are left out.
+func (t *RTCTrack) WriteMixedSample(data []byte, duration time.Duration, csrc []uint32) error {
+	if t.done == nil {
+		return ErrTrackNotLocal
+	} else if t.packetizer == nil {
+		return ErrRawRTPTrack
+	} else if t.isDone() {
+		return ErrConnectionClosed
+	}
+
+	t.writeSample(media.RTCSample{Data: data, Samples: t.samplesFor(duration), CSRC: csrc})
+	return nil
+}
+
+// WriteRTP sends a copy of the RTP packet on the track, the packet is left
+// untouched. Tracks created with NewRawRTPTrack forward it as is, like
+// RawRTP. Other tracks send it on their SSRC, numbered in sequence with the
+// packetized samples, and with its timestamp moved onto their clock: the
+// packets written after a sample follow it, keeping the spacing of their
+// timestamps. Either way the contributing sources listed by the packet are
+// kept. The packet is sent on the calling goroutine before WriteRTP
+// returns. It returns ErrConnectionClosed once the RTCPeerConnection is
+// closed.
+func (t *RTCTrack) WriteRTP(p *rtp.Packet) error {
+	if t.done == nil {
+		return ErrTrackNotLocal
+	} else if t.isDone() {
+		return ErrConnectionClosed
+	}
+
+	t.writeRTP(p)
+	return nil
+}
+
+// SetMTU sets the size of the RTP packets the media written to the track is
+// packetized into, overriding SettingEngine.SetPacketizerMTU. Simulcast
+// layers take the MTU the track has when they are added. It returns
+// ErrTrackNotLocal for remote tracks, ErrRawRTPTrack for tracks created with
+// NewRawRTPTrack, which aren't packetized, and ErrInvalidMTU if the MTU is
+// smaller than 64 bytes.
+func (t *RTCTrack) SetMTU(mtu int) error {
+	if t.done == nil {
+		return ErrTrackNotLocal
+	} else if t.packetizer == nil {
+		return ErrRawRTPTrack
+	} else if mtu < minPacketizerMTU {
+		return ErrInvalidMTU
+	}
+
+	t.sendLock.Lock()
+	defer t.sendLock.Unlock()
+	t.mtu = mtu
+	t.packetizer.SetMTU(packetizerMTU(mtu))
+	return nil
+}
+
+// getMTU returns the MTU of the packets of a local track
+func (t *RTCTrack) getMTU() int {
+	t.sendLock.Lock()
+	defer t.sendLock.Unlock()
+	return t.mtu
+}
+
+// OnQueueFull sets an event handler which is called when a sample written
+// to the Samples channel of a local track finds the queue of the track full,
+// see SettingEngine.SetTrackQueuePolicy. With RTCTrackQueuePolicyBlock it
+// is called when writers are blocked, otherwise whenever a sample is
+// discarded. Calls don't overlap, the queue found full until one returns
+// isn't reported again. A producer may react by lowering its bitrate or
+// skipping frames.
+func (t *RTCTrack) OnQueueFull(f func()) {
+	t.queueLock.Lock()
+	defer t.queueLock.Unlock()
+	t.onQueueFullHandler = f
+}
+
+// queueFull has the scheduler call the handler of OnQueueFull, unless a
+// call is scheduled already
+func (t *RTCTrack) queueFull() {
+	t.queueLock.Lock()
+	handler := t.onQueueFullHandler
+	t.queueLock.Unlock()
+
+	if handler == nil || !atomic.CompareAndSwapInt32(&t.queueFullPending, 0, 1) {
+		return
+	}
+	workers.schedule(func() {
+		handler()
+		atomic.StoreInt32(&t.queueFullPending, 0)
+	})
+}
+
+// writeSample packetizes the sample and sends its packets
+func (t *RTCTrack) writeSample(sample media.RTCSample) {
+	t.sendLock.Lock()
+	defer t.sendLock.Unlock()
+
+	t.rtpTimestampBased = false
+	t.packetizer.SetCSRC(sampleCSRC(sample))
+	var packets []*rtp.Packet
+	if t.dtx != nil {
+		packets = t.dtx.packetize(t.packetizer, sample, t.pc.comfortNoiseCodec(t.Codec.ClockRate), time.Now())
+	} else {
+		packets = t.packetizer.Packetize(sample.Data, sample.Samples)
+	}
+	for _, p := range packets {
+		t.pc.sendRTP(t, p)
+	}
+}
+
+// sampleCSRC returns the contributing sources the packets of the sample
+// list, a copy as the packets may outlive the sample
+func sampleCSRC(sample media.RTCSample) []uint32 {
+	csrc := sample.CSRC
+	if len(csrc) > rtp.MaxCSRC {
+		csrc = csrc[:rtp.MaxCSRC]
+	}
+	return append([]uint32(nil), csrc...)
+}
+
+// writeRTP sends a copy of the packet, a sample track sends it in sequence
+// with the packets of its samples
+func (t *RTCTrack) writeRTP(p *rtp.Packet) {
+	t.sendLock.Lock()
+	defer t.sendLock.Unlock()
+
+	// The copy is encrypted in place when it is sent
+	p = p.Clone()
+	if t.packetizer != nil {
+		if !t.rtpTimestampBased {
+			t.rtpTimestampOffset = t.packetizer.NextTimestamp() - p.Timestamp
+			t.rtpTimestampBased = true
+		}
+
+		p.SSRC = t.Ssrc
+		p.SequenceNumber = t.sequencer.NextSequenceNumber()
+		p.Timestamp += t.rtpTimestampOffset
+
+		// The samples written next continue from the packet
+		if skipped := int32(p.Timestamp - t.packetizer.NextTimestamp()); skipped > 0 {
+			t.packetizer.SkipSamples(uint32(skipped))
+		}
+	}
+	t.pc.sendRTP(t, p)
+}
+
+// Done returns a channel which is closed once the RTCPeerConnection of a
+// local track is done, see RTCPeerConnection.Done. It is nil for remote
+// tracks, which end with their Packets.
+func (t *RTCTrack) Done() <-chan struct{} {
+	return t.done
+}
+
+// isDone reports whether the RTCPeerConnection of a local track is closed,
+// it takes precedence over the room left in the buffers of the track
+func (t *RTCTrack) isDone() bool {
+	select {
+	case <-t.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// samplesFor returns the RTP timestamp increment of media playing for the
+// duration, in units of the clock rate of the codec. The increments follow
+// the total duration written, rounded to the nearest sample, so durations
+// truncated to nanoseconds like time.Second/30 don't drift.
+func (t *RTCTrack) samplesFor(duration time.Duration) uint32 {
+	t.clockLock.Lock()
+	defer t.clockLock.Unlock()
+
+	t.clockDuration += duration
+	rate := uint64(t.Codec.ClockRate)
+	total := uint64(t.clockDuration/time.Second)*rate + (uint64(t.clockDuration%time.Second)*rate+uint64(time.Second/2))/uint64(time.Second)
+
+	samples := total - t.clockSamples
+	t.clockSamples = total
+	return uint32(samples)
 }
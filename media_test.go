@@ -0,0 +1,219 @@
+//go:build !js
+
+package webrtc
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pions/webrtc/pkg/rtp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRTCTrack_samplesFor(t *testing.T) {
+	testCases := []struct {
+		clockRate uint32
+		duration  time.Duration
+		writes    int
+		expected  uint64
+	}{
+		{48000, 20 * time.Millisecond, 50, 48000},
+		{90000, time.Second / 30, 30, 90000},
+		{90000, time.Second / 30, 1, 3000},
+		{8000, 0, 10, 0},
+	}
+
+	for i, testCase := range testCases {
+		track := &RTCTrack{Codec: &RTCRtpCodec{RTCRtpCodecCapability: RTCRtpCodecCapability{ClockRate: testCase.clockRate}}}
+		var total uint64
+		for j := 0; j < testCase.writes; j++ {
+			total += uint64(track.samplesFor(testCase.duration))
+		}
+		assert.Equal(t, testCase.expected, total, "testCase: %d", i)
+	}
+}
+
+func TestRTCTrack_WriteSample(t *testing.T) {
+	RegisterDefaultCodecs()
+
+	pc, err := New(RTCConfiguration{})
+	assert.Nil(t, err)
+
+	track, err := pc.NewRTCSampleTrack(DefaultPayloadTypeVP8, "video", "pion")
+	assert.Nil(t, err)
+	sender, err := pc.AddTrack(track)
+	assert.Nil(t, err)
+
+	sent := make(chan rtp.Packet, 10)
+	sender.OnSentRTPPacket(func(p *rtp.Packet) {
+		sent <- *p
+	})
+
+	// Every frame advances the timestamp by 1/30s of the 90kHz clock
+	var packets []rtp.Packet
+	for i := 0; i < 3; i++ {
+		assert.Nil(t, track.WriteSample([]byte{0x00, 0x01}, time.Second/30))
+		select {
+		case p := <-sent:
+			packets = append(packets, p)
+		case <-time.After(time.Second):
+			t.Fatal("sample not sent")
+		}
+	}
+	assert.Equal(t, packets[0].Timestamp+3000, packets[1].Timestamp)
+	assert.Equal(t, packets[1].Timestamp+3000, packets[2].Timestamp)
+
+	// Packets written to a sample track continue its stream, keeping the
+	// spacing of their timestamps
+	written := []*rtp.Packet{
+		{Version: 2, SSRC: 1, SequenceNumber: 10, Timestamp: 90000, PayloadType: DefaultPayloadTypeVP8, Payload: []byte{0x00}},
+		{Version: 2, SSRC: 1, SequenceNumber: 11, Timestamp: 93000, PayloadType: DefaultPayloadTypeVP8, Payload: []byte{0x00}},
+	}
+	for i, w := range written {
+		assert.Nil(t, track.WriteRTP(w))
+		select {
+		case p := <-sent:
+			assert.Equal(t, track.Ssrc, p.SSRC)
+			assert.Equal(t, packets[2].SequenceNumber+1+uint16(i), p.SequenceNumber)
+			assert.Equal(t, packets[2].Timestamp+3000+3000*uint32(i), p.Timestamp)
+		case <-time.After(time.Second):
+			t.Fatal("packet not sent")
+		}
+	}
+
+	// The written packets are left untouched
+	assert.Equal(t, uint32(1), written[0].SSRC)
+	assert.Equal(t, uint16(10), written[0].SequenceNumber)
+	assert.Equal(t, uint32(90000), written[0].Timestamp)
+
+	raw, err := pc.NewRawRTPTrack(DefaultPayloadTypeVP8, 123456, "raw", "pion")
+	assert.Nil(t, err)
+	assert.Equal(t, ErrRawRTPTrack, raw.WriteSample([]byte{0x00}, time.Second/30))
+	assert.Equal(t, ErrTrackNotLocal, (&RTCTrack{}).WriteSample([]byte{0x00}, time.Second/30))
+	assert.Equal(t, ErrTrackNotLocal, (&RTCTrack{}).WriteRTP(&rtp.Packet{}))
+
+	assert.Nil(t, pc.Close())
+	assert.Equal(t, ErrConnectionClosed, track.WriteSample([]byte{0x00}, time.Second/30))
+	assert.Equal(t, ErrConnectionClosed, raw.WriteRTP(&rtp.Packet{}))
+}
+
+func TestRTCTrack_WriteMixedSample(t *testing.T) {
+	RegisterDefaultCodecs()
+
+	pc, err := New(RTCConfiguration{})
+	assert.Nil(t, err)
+	defer func() { assert.Nil(t, pc.Close()) }()
+
+	track, err := pc.NewRTCSampleTrack(DefaultPayloadTypeVP8, "video", "pion")
+	assert.Nil(t, err)
+	sender, err := pc.AddTrack(track)
+	assert.Nil(t, err)
+	assert.Nil(t, track.SetMTU(300))
+
+	sent := make(chan rtp.Packet, 100)
+	sender.OnSentRTPPacket(func(p *rtp.Packet) {
+		sent <- *p
+	})
+
+	// Every packet of the sample lists the contributing sources, within the MTU
+	write := func(csrc []uint32, expected []uint32) {
+		assert.Nil(t, track.WriteMixedSample(make([]byte, 1000), time.Second/30, csrc))
+		for {
+			select {
+			case p := <-sent:
+				assert.Equal(t, expected, p.CSRC)
+				raw, err := p.Marshal()
+				assert.Nil(t, err)
+				assert.True(t, len(raw) <= 300, "packet of %d bytes exceeds the MTU", len(raw))
+				if p.Marker {
+					return
+				}
+			case <-time.After(time.Second):
+				t.Fatal("sample not sent")
+			}
+		}
+	}
+	write([]uint32{1111, 2222}, []uint32{1111, 2222})
+
+	csrc := make([]uint32, 20)
+	for i := range csrc {
+		csrc[i] = uint32(i)
+	}
+	write(csrc, csrc[:rtp.MaxCSRC])
+
+	// Samples which aren't mixed list none
+	write(nil, nil)
+
+	// Small MTUs leave out the CSRCs taking the room of the payload
+	assert.Nil(t, track.SetMTU(minPacketizerMTU))
+	write(csrc, csrc[:5])
+
+	raw, err := pc.NewRawRTPTrack(DefaultPayloadTypeVP8, 123456, "raw", "pion")
+	assert.Nil(t, err)
+	assert.Equal(t, ErrRawRTPTrack, raw.WriteMixedSample([]byte{0x00}, time.Second/30, []uint32{1}))
+}
+
+func TestRTCTrack_SetMTU(t *testing.T) {
+	RegisterDefaultCodecs()
+
+	s := SettingEngine{}
+	assert.Equal(t, ErrInvalidMTU, s.SetPacketizerMTU(minPacketizerMTU-1))
+	assert.Nil(t, s.SetPacketizerMTU(600))
+
+	pc, err := NewAPI(WithSettingEngine(s)).NewRTCPeerConnection(RTCConfiguration{})
+	assert.Nil(t, err)
+	defer func() { assert.Nil(t, pc.Close()) }()
+
+	track, err := pc.NewRTCSampleTrack(DefaultPayloadTypeVP8, "video", "pion")
+	assert.Nil(t, err)
+
+	// Every packet fits the MTU, the RTP header and the abs-send-time
+	// stamped as it is sent included
+	assertMTU := func(mtu int) {
+		for _, p := range track.packetizer.Packetize(make([]byte, 2000), 3000) {
+			assert.Nil(t, p.SetExtension(defaultAbsSendTimeExtensionID, rtp.NewAbsSendTime(time.Now()).Marshal()))
+			raw, err := p.Marshal()
+			assert.Nil(t, err)
+			assert.True(t, len(raw) <= mtu, "packet of %d bytes exceeds %d", len(raw), mtu)
+		}
+	}
+	assertMTU(600)
+	assert.Nil(t, track.SetMTU(300))
+	assertMTU(300)
+	assert.Equal(t, ErrInvalidMTU, track.SetMTU(minPacketizerMTU-1))
+
+	rawTrack, err := pc.NewRawRTPTrack(DefaultPayloadTypeVP8, 123456, "raw", "pion")
+	assert.Nil(t, err)
+	assert.Equal(t, ErrRawRTPTrack, rawTrack.SetMTU(1000))
+	assert.Equal(t, ErrTrackNotLocal, (&RTCTrack{}).SetMTU(1000))
+}
+
+func TestRTCTrack_OnQueueFull(t *testing.T) {
+	track := &RTCTrack{}
+	track.queueFull()
+
+	calls := make(chan struct{}, 10)
+	release := make(chan struct{})
+	track.OnQueueFull(func() {
+		calls <- struct{}{}
+		<-release
+	})
+
+	// The queue found full while a call is pending is reported by it
+	for i := 0; i < 5; i++ {
+		track.queueFull()
+	}
+	<-calls
+	track.queueFull()
+	close(release)
+
+	time.Sleep(50 * time.Millisecond)
+	assert.Empty(t, calls)
+
+	track.queueFull()
+	select {
+	case <-calls:
+	case <-time.After(time.Second):
+		t.Fatal("queue full not reported once the call returned")
+	}
+}
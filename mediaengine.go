@@ -1,7 +1,10 @@
+//go:build !js
+
 package webrtc
 
 import (
 	"strconv"
+	"strings"
 
 	"github.com/pions/webrtc/internal/sdp"
 	"github.com/pions/webrtc/pkg/rtp"
@@ -18,18 +21,27 @@ func RegisterCodec(codec *RTCRtpCodec) {
 
 // PayloadTypes for the default codecs
 const (
-	DefaultPayloadTypeOpus = 111
-	DefaultPayloadTypeVP8  = 96
-	DefaultPayloadTypeVP9  = 98
-	DefaultPayloadTypeH264 = 100
+	DefaultPayloadTypeOpus    = 111
+	DefaultPayloadTypeVP8     = 96
+	DefaultPayloadTypeVP8RTX  = 97
+	DefaultPayloadTypeVP9     = 98
+	DefaultPayloadTypeVP9RTX  = 99
+	DefaultPayloadTypeH264    = 100
+	DefaultPayloadTypeH264RTX = 101
+
+	DefaultPayloadTypeTelephoneEvent = 126
 )
 
 // RegisterDefaultCodecs is a helper that registers the default codecs supported by pions-webrtc
 func RegisterDefaultCodecs() {
 	RegisterCodec(NewRTCRtpOpusCodec(DefaultPayloadTypeOpus, 48000, 2))
+	RegisterCodec(NewRTCRtpTelephoneEventCodec(DefaultPayloadTypeTelephoneEvent, 48000))
 	RegisterCodec(NewRTCRtpVP8Codec(DefaultPayloadTypeVP8, 90000))
 	RegisterCodec(NewRTCRtpH264Codec(DefaultPayloadTypeH264, 90000))
 	RegisterCodec(NewRTCRtpVP9Codec(DefaultPayloadTypeVP9, 90000))
+	RegisterCodec(NewRTCRtpRTXCodec(DefaultPayloadTypeVP8RTX, 90000, DefaultPayloadTypeVP8))
+	RegisterCodec(NewRTCRtpRTXCodec(DefaultPayloadTypeH264RTX, 90000, DefaultPayloadTypeH264))
+	RegisterCodec(NewRTCRtpRTXCodec(DefaultPayloadTypeVP9RTX, 90000, DefaultPayloadTypeVP9))
 }
 
 // DefaultMediaEngine is the default MediaEngine used by RTCPeerConnections
@@ -52,8 +64,17 @@ func (m *MediaEngine) RegisterCodec(codec *RTCRtpCodec) uint8 {
 	return codec.PayloadType
 }
 
+// registeredCodecs returns the registered codecs, none for a nil
+// MediaEngine which RTCPeerConnections carrying data channels only use
+func (m *MediaEngine) registeredCodecs() []*RTCRtpCodec {
+	if m == nil {
+		return nil
+	}
+	return m.codecs
+}
+
 func (m *MediaEngine) getCodec(payloadType uint8) (*RTCRtpCodec, error) {
-	for _, codec := range m.codecs {
+	for _, codec := range m.registeredCodecs() {
 		if codec.PayloadType == payloadType {
 			return codec, nil
 		}
@@ -62,21 +83,228 @@ func (m *MediaEngine) getCodec(payloadType uint8) (*RTCRtpCodec, error) {
 }
 
 func (m *MediaEngine) getCodecSDP(sdpCodec sdp.Codec) (*RTCRtpCodec, error) {
-	for _, codec := range m.codecs {
-		if codec.Name == sdpCodec.Name &&
-			codec.ClockRate == sdpCodec.ClockRate &&
-			(sdpCodec.EncodingParameters == "" ||
-				strconv.Itoa(int(codec.Channels)) == sdpCodec.EncodingParameters) &&
-			codec.SdpFmtpLine == sdpCodec.Fmtp { // TODO: Protocol specific matching?
+	for _, codec := range m.registeredCodecs() {
+		if codecMatches(codec, sdpCodec) {
 			return codec, nil
 		}
 	}
 	return nil, errors.New("Codec not found")
 }
 
+// codecMatches reports whether the SDP codec describes the codec
+func codecMatches(codec *RTCRtpCodec, sdpCodec sdp.Codec) bool {
+	return strings.EqualFold(codec.Name, sdpCodec.Name) &&
+		codec.ClockRate == sdpCodec.ClockRate &&
+		(sdpCodec.EncodingParameters == "" ||
+			strconv.Itoa(int(codec.Channels)) == sdpCodec.EncodingParameters) &&
+		fmtpMatches(codec.Name, codec.SdpFmtpLine, sdpCodec.Fmtp)
+}
+
+// fmtpMatches reports whether the format parameters of the codec describe
+// the same stream. Most parameters only tune the codec, H264 has to agree on
+// the packetization mode and the profile, RFC 6184 Section 8.1. rtx has to
+// agree on the associated payload type.
+func fmtpMatches(name, local, remote string) bool {
+	switch {
+	case strings.EqualFold(name, H264):
+		l, r := parseFmtp(local), parseFmtp(remote)
+		return fmtpValue(l, "packetization-mode", "0") == fmtpValue(r, "packetization-mode", "0") &&
+			h264Profile(l) == h264Profile(r)
+	case strings.EqualFold(name, RTX):
+		return local == remote
+	default:
+		return true
+	}
+}
+
+// parseFmtp returns the parameters of an a=fmtp line, keyed by their
+// lowercased names
+func parseFmtp(line string) map[string]string {
+	params := make(map[string]string)
+	for _, param := range strings.Split(line, ";") {
+		split := strings.SplitN(strings.TrimSpace(param), "=", 2)
+		if len(split) == 2 {
+			params[strings.ToLower(split[0])] = split[1]
+		}
+	}
+	return params
+}
+
+func fmtpValue(params map[string]string, key, defaultValue string) string {
+	if value, ok := params[key]; ok {
+		return value
+	}
+	return defaultValue
+}
+
+// h264Profile returns the profile_idc of the profile-level-id parameter,
+// which defaults to the baseline profile
+func h264Profile(params map[string]string) string {
+	profileLevelID := fmtpValue(params, "profile-level-id", "420010")
+	if len(profileLevelID) < 2 {
+		return ""
+	}
+	return strings.ToLower(profileLevelID[:2])
+}
+
+// matchOfferedCodecs pairs the codecs with the ones the remote peer offered,
+// returning the offered codec for the payload type of every codec both
+// peers support. Every offered codec is paired once, rtx codecs are paired
+// if their associated codecs are.
+func matchOfferedCodecs(codecs []*RTCRtpCodec, offered []sdp.Codec) map[uint8]sdp.Codec {
+	matches := make(map[uint8]sdp.Codec)
+	paired := make(map[uint8]bool)
+	pair := func(codec *RTCRtpCodec, accept func(o sdp.Codec) bool) {
+		for _, o := range offered {
+			if !paired[o.PayloadType] && accept(o) {
+				matches[codec.PayloadType] = o
+				paired[o.PayloadType] = true
+				return
+			}
+		}
+	}
+
+	for _, codec := range codecs {
+		if codec.Name == RTX {
+			continue
+		}
+		pair(codec, func(o sdp.Codec) bool {
+			return !strings.EqualFold(o.Name, RTX) && codecMatches(codec, o)
+		})
+	}
+
+	for _, codec := range codecs {
+		if codec.Name != RTX {
+			continue
+		}
+		apt, err := strconv.ParseUint(parseFmtp(codec.SdpFmtpLine)["apt"], 10, 7)
+		if err != nil {
+			continue
+		}
+		associated, ok := matches[uint8(apt)]
+		if !ok {
+			continue
+		}
+		pair(codec, func(o sdp.Codec) bool {
+			return strings.EqualFold(o.Name, RTX) && o.ClockRate == codec.ClockRate &&
+				parseFmtp(o.Fmtp)["apt"] == strconv.Itoa(int(associated.PayloadType))
+		})
+	}
+	return matches
+}
+
+// getRTXCodec returns the retransmission codec associated with the payload type, if any
+func (m *MediaEngine) getRTXCodec(payloadType uint8) *RTCRtpCodec {
+	apt := "apt=" + strconv.Itoa(int(payloadType))
+	for _, codec := range m.registeredCodecs() {
+		if codec.Name == RTX && codec.SdpFmtpLine == apt {
+			return codec
+		}
+	}
+	return nil
+}
+
+// getFECCodec returns the forward error correction codec of the kind, if any
+func (m *MediaEngine) getFECCodec(kind RTCRtpCodecType) *RTCRtpCodec {
+	for _, codec := range m.registeredCodecs() {
+		if codec.Name == ULPFEC && codec.Type == kind {
+			return codec
+		}
+	}
+	return nil
+}
+
+// getTelephoneEventCodec returns the DTMF codec sharing the clock rate of the audio codec, if any
+func (m *MediaEngine) getTelephoneEventCodec(clockRate uint32) *RTCRtpCodec {
+	for _, codec := range m.registeredCodecs() {
+		if codec.Name == TelephoneEvent && codec.ClockRate == clockRate {
+			return codec
+		}
+	}
+	return nil
+}
+
+// getComfortNoiseCodec returns the comfort noise codec sharing the clock rate of the audio codec, if any
+func (m *MediaEngine) getComfortNoiseCodec(clockRate uint32) *RTCRtpCodec {
+	for _, codec := range m.registeredCodecs() {
+		if codec.Name == ComfortNoise && codec.ClockRate == clockRate {
+			return codec
+		}
+	}
+	return nil
+}
+
+// getRTCPFeedback returns the RTCP feedback of the codec: the feedback it was
+// created with, and generic NACKs if it has an RTX codec as retransmissions
+// are requested with them. Once the remote description is set it is
+// limited to the feedback the remote peer announced for the payload type
+// the codec is negotiated under.
+func (m *MediaEngine) getRTCPFeedback(codec *RTCRtpCodec, payloadType uint8, remote *sdp.SessionDescription) []RTCRtcpFeedback {
+	feedback := append([]RTCRtcpFeedback{}, codec.RTCPFeedback...)
+	nack := RTCRtcpFeedback{Type: RTCRtcpFeedbackTypeNACK}
+	if m.getRTXCodec(codec.PayloadType) != nil && !hasRTCPFeedback(feedback, nack) {
+		feedback = append(feedback, nack)
+	}
+	if remote == nil {
+		return feedback
+	}
+
+	sdpCodec, err := remote.GetCodecForPayloadType(payloadType)
+	if err != nil {
+		return feedback
+	}
+	var remoteFeedback []RTCRtcpFeedback
+	for _, raw := range sdpCodec.RTCPFeedback {
+		remoteFeedback = append(remoteFeedback, newRTCRtcpFeedback(raw))
+	}
+
+	var negotiated []RTCRtcpFeedback
+	for _, f := range feedback {
+		if hasRTCPFeedback(remoteFeedback, f) {
+			negotiated = append(negotiated, f)
+		}
+	}
+	return negotiated
+}
+
+// getCodecCapability returns the registered codec matching the capability
+func (m *MediaEngine) getCodecCapability(capability RTCRtpCodecCapability) *RTCRtpCodec {
+	for _, codec := range m.registeredCodecs() {
+		if strings.EqualFold(codec.MimeType, capability.MimeType) &&
+			codec.ClockRate == capability.ClockRate &&
+			codec.Channels == capability.Channels &&
+			codec.SdpFmtpLine == capability.SdpFmtpLine {
+			return codec
+		}
+	}
+	return nil
+}
+
+// GetCapabilities returns the codecs registered for the kind and the RTP
+// header extensions supported along with them, in the order they are
+// offered in
+func (m *MediaEngine) GetCapabilities(kind RTCRtpCodecType) RTCRtpCapabilities {
+	capabilities := RTCRtpCapabilities{
+		Codecs:           []RTCRtpCodecCapability{},
+		HeaderExtensions: []RTCRtpHeaderExtensionCapability{},
+	}
+	if kind != RTCRtpCodecTypeAudio && kind != RTCRtpCodecTypeVideo {
+		return capabilities
+	}
+
+	for _, codec := range m.getCodecsByKind(kind) {
+		capabilities.Codecs = append(capabilities.Codecs, codec.RTCRtpCodecCapability)
+	}
+	capabilities.HeaderExtensions = append(capabilities.HeaderExtensions,
+		RTCRtpHeaderExtensionCapability{URI: sdp.ExtMapURIMID},
+		RTCRtpHeaderExtensionCapability{URI: sdp.ExtMapURIAbsSendTime},
+	)
+	return capabilities
+}
+
 func (m *MediaEngine) getCodecsByKind(kind RTCRtpCodecType) []*RTCRtpCodec {
 	var codecs []*RTCRtpCodec
-	for _, codec := range m.codecs {
+	for _, codec := range m.registeredCodecs() {
 		if codec.Type == kind {
 			codecs = append(codecs, codec)
 		}
@@ -90,8 +318,24 @@ const (
 	VP8  = "VP8"
 	VP9  = "VP9"
 	H264 = "H264"
+	RTX  = "rtx"
+
+	ULPFEC = "ulpfec"
+
+	TelephoneEvent = "telephone-event"
+	ComfortNoise   = "CN"
 )
 
+// defaultVideoRTCPFeedback is the RTCP feedback of the video codecs created
+// by the helpers: bandwidth estimates and keyframe requests
+func defaultVideoRTCPFeedback() []RTCRtcpFeedback {
+	return []RTCRtcpFeedback{
+		{Type: RTCRtcpFeedbackTypeGoogREMB},
+		{Type: RTCRtcpFeedbackTypeCCM, Parameter: "fir"},
+		{Type: RTCRtcpFeedbackTypeNACK, Parameter: "pli"},
+	}
+}
+
 // NewRTCRtpOpusCodec is a helper to create an Opus codec
 func NewRTCRtpOpusCodec(payloadType uint8, clockrate uint32, channels uint16) *RTCRtpCodec {
 	c := NewRTCRtpCodec(RTCRtpCodecTypeAudio,
@@ -113,6 +357,7 @@ func NewRTCRtpVP8Codec(payloadType uint8, clockrate uint32) *RTCRtpCodec {
 		"",
 		payloadType,
 		&codecs.VP8Payloader{})
+	c.RTCPFeedback = defaultVideoRTCPFeedback()
 	return c
 }
 
@@ -125,6 +370,7 @@ func NewRTCRtpVP9Codec(payloadType uint8, clockrate uint32) *RTCRtpCodec {
 		"",
 		payloadType,
 		nil) // TODO
+	c.RTCPFeedback = defaultVideoRTCPFeedback()
 	return c
 }
 
@@ -137,6 +383,66 @@ func NewRTCRtpH264Codec(payloadType uint8, clockrate uint32) *RTCRtpCodec {
 		"level-asymmetry-allowed=1;packetization-mode=1;profile-level-id=42001f",
 		payloadType,
 		&codecs.H264Payloader{})
+	c.RTCPFeedback = defaultVideoRTCPFeedback()
+	return c
+}
+
+// NewRTCRtpRTXCodec is a helper to create an RTX codec which carries
+// retransmissions of the codec identified by the associated payload type
+// https://tools.ietf.org/html/rfc4588
+func NewRTCRtpRTXCodec(payloadType uint8, clockrate uint32, associatedPayloadType uint8) *RTCRtpCodec {
+	c := NewRTCRtpCodec(RTCRtpCodecTypeVideo,
+		RTX,
+		clockrate,
+		0,
+		"apt="+strconv.Itoa(int(associatedPayloadType)),
+		payloadType,
+		nil)
+	return c
+}
+
+// NewRTCRtpTelephoneEventCodec is a helper to create a telephone-event codec,
+// used to send DTMF tones. The clockrate has to match the one of the audio
+// codec the events are sent along with.
+// https://tools.ietf.org/html/rfc4733
+func NewRTCRtpTelephoneEventCodec(payloadType uint8, clockrate uint32) *RTCRtpCodec {
+	c := NewRTCRtpCodec(RTCRtpCodecTypeAudio,
+		TelephoneEvent,
+		clockrate,
+		0,
+		"0-16",
+		payloadType,
+		nil)
+	return c
+}
+
+// NewRTCRtpComfortNoiseCodec is a helper to create a comfort noise codec,
+// sent when an audio track falls silent so the receiver plays noise instead
+// of concealing loss. The clockrate has to match the one of the audio codec,
+// and it is only sent once the remote peer negotiated it.
+// https://tools.ietf.org/html/rfc3389
+func NewRTCRtpComfortNoiseCodec(payloadType uint8, clockrate uint32) *RTCRtpCodec {
+	c := NewRTCRtpCodec(RTCRtpCodecTypeAudio,
+		ComfortNoise,
+		clockrate,
+		0,
+		"",
+		payloadType,
+		nil)
+	return c
+}
+
+// NewRTCRtpULPFECCodec is a helper to create a ULPFEC codec, used to
+// negotiate forward error correction of video
+// https://tools.ietf.org/html/rfc5109
+func NewRTCRtpULPFECCodec(payloadType uint8, clockrate uint32) *RTCRtpCodec {
+	c := NewRTCRtpCodec(RTCRtpCodecTypeVideo,
+		ULPFEC,
+		clockrate,
+		0,
+		"",
+		payloadType,
+		nil)
 	return c
 }
 
@@ -202,6 +508,9 @@ type RTCRtpCodecCapability struct {
 	ClockRate   uint32
 	Channels    uint16
 	SdpFmtpLine string
+
+	// RTCPFeedback is the RTCP feedback announced for the codec
+	RTCPFeedback []RTCRtcpFeedback
 }
 
 // RTCRtpHeaderExtensionCapability is used to define a RFC5285 RTP header extension supported by the codec.
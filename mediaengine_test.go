@@ -0,0 +1,119 @@
+//go:build !js
+
+package webrtc
+
+import (
+	"testing"
+
+	"github.com/pions/webrtc/internal/sdp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMediaEngine_GetCapabilities(t *testing.T) {
+	m := NewMediaEngine()
+	m.RegisterCodec(NewRTCRtpOpusCodec(DefaultPayloadTypeOpus, 48000, 2))
+	m.RegisterCodec(NewRTCRtpVP8Codec(DefaultPayloadTypeVP8, 90000))
+	m.RegisterCodec(NewRTCRtpRTXCodec(DefaultPayloadTypeVP8RTX, 90000, DefaultPayloadTypeVP8))
+
+	audio := m.GetCapabilities(RTCRtpCodecTypeAudio)
+	assert.Equal(t, []RTCRtpCodecCapability{
+		{MimeType: "audio/opus", ClockRate: 48000, Channels: 2, SdpFmtpLine: "minptime=10;useinbandfec=1"},
+	}, audio.Codecs)
+	assert.Equal(t, []RTCRtpHeaderExtensionCapability{{URI: sdp.ExtMapURIMID}, {URI: sdp.ExtMapURIAbsSendTime}}, audio.HeaderExtensions)
+
+	video := m.GetCapabilities(RTCRtpCodecTypeVideo)
+	assert.Equal(t, 2, len(video.Codecs))
+	assert.Equal(t, "video/VP8", video.Codecs[0].MimeType)
+	assert.Equal(t, "video/rtx", video.Codecs[1].MimeType)
+
+	unknown := m.GetCapabilities(RTCRtpCodecType(Unknown))
+	assert.Empty(t, unknown.Codecs)
+	assert.Empty(t, unknown.HeaderExtensions)
+}
+
+func TestMediaEngine_getRTCPFeedback(t *testing.T) {
+	m := NewMediaEngine()
+	vp8 := NewRTCRtpVP8Codec(DefaultPayloadTypeVP8, 90000)
+	m.RegisterCodec(vp8)
+	opus := NewRTCRtpOpusCodec(DefaultPayloadTypeOpus, 48000, 2)
+	m.RegisterCodec(opus)
+
+	assert.Equal(t, []RTCRtcpFeedback{
+		{Type: RTCRtcpFeedbackTypeGoogREMB},
+		{Type: RTCRtcpFeedbackTypeCCM, Parameter: "fir"},
+		{Type: RTCRtcpFeedbackTypeNACK, Parameter: "pli"},
+	}, m.getRTCPFeedback(vp8, vp8.PayloadType, nil))
+	assert.Empty(t, m.getRTCPFeedback(opus, opus.PayloadType, nil))
+
+	// Codecs with retransmissions request them with generic NACKs
+	m.RegisterCodec(NewRTCRtpRTXCodec(DefaultPayloadTypeVP8RTX, 90000, DefaultPayloadTypeVP8))
+	feedback := m.getRTCPFeedback(vp8, vp8.PayloadType, nil)
+	assert.Equal(t, 4, len(feedback))
+	assert.Equal(t, RTCRtcpFeedback{Type: RTCRtcpFeedbackTypeNACK}, feedback[3])
+
+	// The remote peer limits the feedback to the one it announced
+	remote := (&sdp.SessionDescription{}).WithMedia(sdp.NewJSEPMediaDescription("video", []string{}).
+		WithCodec(DefaultPayloadTypeVP8, VP8, 90000, 0, "").
+		WithValueAttribute(sdp.AttrKeyRtcpFb, "96 nack").
+		WithValueAttribute(sdp.AttrKeyRtcpFb, "96 transport-cc"))
+	assert.Equal(t, []RTCRtcpFeedback{{Type: RTCRtcpFeedbackTypeNACK}}, m.getRTCPFeedback(vp8, vp8.PayloadType, remote))
+}
+
+func TestMatchOfferedCodecs(t *testing.T) {
+	vp8 := NewRTCRtpVP8Codec(DefaultPayloadTypeVP8, 90000)
+	vp8RTX := NewRTCRtpRTXCodec(DefaultPayloadTypeVP8RTX, 90000, DefaultPayloadTypeVP8)
+	h264 := NewRTCRtpH264Codec(DefaultPayloadTypeH264, 90000)
+	h264RTX := NewRTCRtpRTXCodec(DefaultPayloadTypeH264RTX, 90000, DefaultPayloadTypeH264)
+	opus := NewRTCRtpOpusCodec(DefaultPayloadTypeOpus, 48000, 2)
+	codecs := []*RTCRtpCodec{vp8, vp8RTX, h264, h264RTX, opus}
+
+	testCases := []struct {
+		offered []sdp.Codec
+		matches map[uint8]uint8
+	}{
+		// Codecs are matched by name, the payload types of the offer win
+		{
+			[]sdp.Codec{
+				{PayloadType: 100, Name: "vp8", ClockRate: 90000},
+				{PayloadType: 101, Name: RTX, ClockRate: 90000, Fmtp: "apt=100"},
+				{PayloadType: 109, Name: Opus, ClockRate: 48000, EncodingParameters: "2", Fmtp: "minptime=20"},
+			},
+			map[uint8]uint8{DefaultPayloadTypeVP8: 100, DefaultPayloadTypeVP8RTX: 101, DefaultPayloadTypeOpus: 109},
+		},
+		// rtx is only matched along with its associated codec
+		{
+			[]sdp.Codec{
+				{PayloadType: 96, Name: VP9, ClockRate: 90000},
+				{PayloadType: 97, Name: RTX, ClockRate: 90000, Fmtp: "apt=96"},
+			},
+			map[uint8]uint8{},
+		},
+		// H264 has to agree on the packetization mode and profile
+		{
+			[]sdp.Codec{
+				{PayloadType: 102, Name: H264, ClockRate: 90000, Fmtp: "packetization-mode=1;profile-level-id=640032"},
+				{PayloadType: 103, Name: H264, ClockRate: 90000, Fmtp: "profile-level-id=42001f"},
+				{PayloadType: 104, Name: H264, ClockRate: 90000, Fmtp: "packetization-mode=1;profile-level-id=42e01f"},
+				{PayloadType: 105, Name: RTX, ClockRate: 90000, Fmtp: "apt=102"},
+				{PayloadType: 106, Name: RTX, ClockRate: 90000, Fmtp: "apt=104"},
+			},
+			map[uint8]uint8{DefaultPayloadTypeH264: 104, DefaultPayloadTypeH264RTX: 106},
+		},
+		// Opus has to agree on the clock rate and channels
+		{
+			[]sdp.Codec{
+				{PayloadType: 111, Name: Opus, ClockRate: 48000, EncodingParameters: "1"},
+				{PayloadType: 112, Name: Opus, ClockRate: 16000, EncodingParameters: "2"},
+			},
+			map[uint8]uint8{},
+		},
+	}
+
+	for i, testCase := range testCases {
+		matches := make(map[uint8]uint8)
+		for payloadType, o := range matchOfferedCodecs(codecs, testCase.offered) {
+			matches[payloadType] = o.PayloadType
+		}
+		assert.Equal(t, testCase.matches, matches, "testCase: %d", i)
+	}
+}
@@ -0,0 +1,233 @@
+//go:build !js
+
+package webrtc
+
+import (
+	"encoding/binary"
+	"sync/atomic"
+	"time"
+
+	"github.com/pions/webrtc/pkg/datachannel"
+	"github.com/pions/webrtc/pkg/rtcerr"
+)
+
+// RTCNetworkTestLabel is the label of the data channel network tests are run
+// on, applications answer the channels with this label using
+// ServeRTCNetworkTest.
+const RTCNetworkTestLabel = "pion-network-test"
+
+const (
+	// networkTestPings is how many pings the round trip time is measured with
+	networkTestPings = 10
+
+	// networkTestChunkSize and networkTestBytes are the size of the messages
+	// the throughput is measured with and how much data is sent in total
+	networkTestChunkSize = 1024
+	networkTestBytes     = 256 * 1024
+
+	// networkTestWindow is how many messages are sent before the remote peer
+	// has to report their arrival, the data channel doesn't retransmit lost
+	// messages so the sender must not outpace the network
+	networkTestWindow = 8
+
+	// networkTestReplyBuffer is how many replies are queued for the test
+	// before further ones are dropped
+	networkTestReplyBuffer = 16
+)
+
+// The first byte of every network test message is its type
+const (
+	networkTestPing byte = iota + 1
+	networkTestPong
+	networkTestData
+	networkTestDone
+	networkTestReport
+)
+
+// RTCNetworkTestResult is the outcome of a network test
+type RTCNetworkTestResult struct {
+	// RTT, MinRTT and MaxRTT are the mean, the lowest and the highest round
+	// trip time of the pings.
+	RTT    time.Duration
+	MinRTT time.Duration
+	MaxRTT time.Duration
+
+	// BytesSent is how much data was sent to measure the throughput, and
+	// BytesReceived how much of it the remote peer reported to have received.
+	BytesSent     uint64
+	BytesReceived uint64
+
+	// Throughput is the rate, in bits per second, the remote peer received
+	// the data with.
+	Throughput uint64
+}
+
+// RunRTCNetworkTest measures the round trip time and the throughput to the
+// remote peer over an open data channel labeled RTCNetworkTestLabel, which
+// the remote peer serves with ServeRTCNetworkTest. The Onmessage handler of
+// the channel is replaced, and the test fails if it takes longer than the
+// timeout.
+func RunRTCNetworkTest(d *RTCDataChannel, timeout time.Duration) (RTCNetworkTestResult, error) {
+	send, replies := networkTestClient(d)
+	return runNetworkTest(send, replies, timeout)
+}
+
+// ServeRTCNetworkTest answers the network tests the remote peer runs over the
+// data channel, its Onmessage handler is replaced.
+func ServeRTCNetworkTest(d *RTCDataChannel) {
+	responder := &networkTestResponder{}
+
+	d.Lock()
+	d.Onmessage = func(p datachannel.Payload) {
+		payload, ok := p.(*datachannel.PayloadBinary)
+		if !ok {
+			return
+		}
+		if reply := responder.handle(payload.Data); reply != nil {
+			if err := d.Send(datachannel.PayloadBinary{Data: reply}); err != nil {
+				d.rtcPeerConnection.log.Warnf("failed to answer network test: %v", err)
+			}
+		}
+	}
+	d.Unlock()
+}
+
+// networkTestClient replaces the Onmessage handler of the data channel to
+// queue the replies of the remote peer, it returns the function sending the
+// messages of a test and the queue
+func networkTestClient(d *RTCDataChannel) (func([]byte) error, <-chan []byte) {
+	replies := make(chan []byte, networkTestReplyBuffer)
+	d.Lock()
+	d.Onmessage = func(p datachannel.Payload) {
+		if payload, ok := p.(*datachannel.PayloadBinary); ok {
+			select {
+			case replies <- payload.Data:
+			default:
+			}
+		}
+	}
+	d.Unlock()
+
+	return func(data []byte) error {
+		return d.Send(datachannel.PayloadBinary{Data: data})
+	}, replies
+}
+
+// awaitNetworkTestReply returns the next reply of the type, others are left
+// over from an earlier test. It fails once the deadline passes, and returns
+// no reply once expire does, expire may be nil.
+func awaitNetworkTestReply(replies <-chan []byte, messageType byte, deadline, expire <-chan time.Time) ([]byte, error) {
+	for {
+		select {
+		case reply := <-replies:
+			if len(reply) > 0 && reply[0] == messageType {
+				return reply, nil
+			}
+		case <-expire:
+			return nil, nil
+		case <-deadline:
+			return nil, &rtcerr.OperationError{Err: ErrNetworkTestTimeout}
+		}
+	}
+}
+
+// pingNetworkTest returns the round trip time of a ping
+func pingNetworkTest(send func([]byte) error, replies <-chan []byte, seq uint32, deadline <-chan time.Time) (time.Duration, error) {
+	ping := make([]byte, 5)
+	ping[0] = networkTestPing
+	binary.BigEndian.PutUint32(ping[1:], seq)
+
+	sent := time.Now()
+	if err := send(ping); err != nil {
+		return 0, err
+	}
+	for {
+		pong, err := awaitNetworkTestReply(replies, networkTestPong, deadline, nil)
+		if err != nil {
+			return 0, err
+		}
+		if len(pong) == 5 && binary.BigEndian.Uint32(pong[1:]) == seq {
+			return time.Since(sent), nil
+		}
+	}
+}
+
+func runNetworkTest(send func([]byte) error, replies <-chan []byte, timeout time.Duration) (RTCNetworkTestResult, error) {
+	result := RTCNetworkTestResult{}
+	deadline := time.After(timeout)
+
+	var rttSum time.Duration
+	for seq := uint32(0); seq < networkTestPings; seq++ {
+		rtt, err := pingNetworkTest(send, replies, seq, deadline)
+		if err != nil {
+			return result, err
+		}
+		rttSum += rtt
+		if result.MinRTT == 0 || rtt < result.MinRTT {
+			result.MinRTT = rtt
+		}
+		if rtt > result.MaxRTT {
+			result.MaxRTT = rtt
+		}
+	}
+	result.RTT = rttSum / networkTestPings
+
+	chunk := make([]byte, networkTestChunkSize)
+	chunk[0] = networkTestData
+	start := time.Now()
+	for result.BytesSent < networkTestBytes {
+		for i := 0; i < networkTestWindow && result.BytesSent < networkTestBytes; i++ {
+			if err := send(chunk); err != nil {
+				return result, err
+			}
+			result.BytesSent += uint64(len(chunk))
+		}
+		if err := send([]byte{networkTestDone}); err != nil {
+			return result, err
+		}
+
+		report, err := awaitNetworkTestReply(replies, networkTestReport, deadline, nil)
+		if err != nil {
+			return result, err
+		}
+		if len(report) == 9 {
+			result.BytesReceived += binary.BigEndian.Uint64(report[1:])
+		}
+	}
+
+	elapsed := time.Since(start)
+	if elapsed > 0 {
+		result.Throughput = uint64(float64(result.BytesReceived*8) / elapsed.Seconds())
+	}
+	return result, nil
+}
+
+// networkTestResponder answers the messages of a network test, it counts the
+// data it received until the test is done
+type networkTestResponder struct {
+	received uint64
+}
+
+// handle returns the reply to the message, if there is any
+func (r *networkTestResponder) handle(message []byte) []byte {
+	if len(message) == 0 {
+		return nil
+	}
+
+	switch message[0] {
+	case networkTestPing:
+		pong := append([]byte{}, message...)
+		pong[0] = networkTestPong
+		return pong
+	case networkTestData:
+		atomic.AddUint64(&r.received, uint64(len(message)))
+		return nil
+	case networkTestDone:
+		report := make([]byte, 9)
+		report[0] = networkTestReport
+		binary.BigEndian.PutUint64(report[1:], atomic.SwapUint64(&r.received, 0))
+		return report
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,57 @@
+//go:build !js
+
+package webrtc
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pions/webrtc/pkg/rtcerr"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRunNetworkTest(t *testing.T) {
+	responder := &networkTestResponder{}
+	replies := make(chan []byte, networkTestReplyBuffer)
+	send := func(data []byte) error {
+		if reply := responder.handle(data); reply != nil {
+			replies <- reply
+		}
+		return nil
+	}
+
+	result, err := runNetworkTest(send, replies, 5*time.Second)
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(networkTestBytes), result.BytesSent)
+	assert.Equal(t, uint64(networkTestBytes), result.BytesReceived)
+	assert.True(t, result.MinRTT <= result.RTT && result.RTT <= result.MaxRTT)
+
+	// The count starts over for the next test
+	assert.Equal(t, uint64(0), responder.received)
+
+	_, err = runNetworkTest(func([]byte) error { return nil }, replies, 10*time.Millisecond)
+	assert.Equal(t, &rtcerr.OperationError{Err: ErrNetworkTestTimeout}, err)
+}
+
+func TestNetworkTestResponder_handle(t *testing.T) {
+	testCases := []struct {
+		message []byte
+		reply   []byte
+	}{
+		{[]byte{}, nil},
+		{[]byte{networkTestPing, 0, 0, 0, 1}, []byte{networkTestPong, 0, 0, 0, 1}},
+		{[]byte{networkTestData, 0, 0}, nil},
+		{[]byte{networkTestDone}, []byte{networkTestReport, 0, 0, 0, 0, 0, 0, 0, 3}},
+		{[]byte{networkTestDone}, []byte{networkTestReport, 0, 0, 0, 0, 0, 0, 0, 0}},
+		{[]byte{networkTestPong, 0, 0, 0, 1}, nil},
+	}
+
+	responder := &networkTestResponder{}
+	for i, testCase := range testCases {
+		assert.Equal(t,
+			testCase.reply,
+			responder.handle(testCase.message),
+			"testCase: %d", i,
+		)
+	}
+}
@@ -0,0 +1,43 @@
+package webrtc
+
+import (
+	"sync"
+)
+
+// operations is an ordered queue of handlers, they run one at a time on a
+// worker of the scheduler without holding any lock, so a handler may use the
+// object which invoked it. The worker is only taken while handlers are queued.
+type operations struct {
+	sync.Mutex
+
+	handlers []func()
+	running  bool
+}
+
+// push queues the handler behind the ones of the earlier events
+func (q *operations) push(handler func()) {
+	q.Lock()
+	defer q.Unlock()
+
+	q.handlers = append(q.handlers, handler)
+	if !q.running {
+		q.running = true
+		workers.schedule(q.run)
+	}
+}
+
+func (q *operations) run() {
+	for {
+		q.Lock()
+		if len(q.handlers) == 0 {
+			q.running = false
+			q.Unlock()
+			return
+		}
+		handler := q.handlers[0]
+		q.handlers = q.handlers[1:]
+		q.Unlock()
+
+		handler()
+	}
+}
@@ -3,14 +3,17 @@
 package ice
 
 import (
-	"fmt"
+	"context"
 	"math/rand"
 	"net"
+	"sort"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/pions/pkg/stun"
 	"github.com/pions/webrtc/internal/util"
+	"github.com/pions/webrtc/pkg/logging"
 	"github.com/pkg/errors"
 )
 
@@ -62,8 +65,32 @@ type Agent struct {
 	gatheringState  GatheringState
 
 	haveStarted   bool
+	startedAt     time.Time
 	isControlling bool
-	taskLoopChan  chan bool
+
+	// remoteLite is set if the remote agent is a lite implementation, the
+	// agent is controlling and nominates the pairs of nominatedPairs then.
+	// nominationRequests maps the transaction IDs of the checks nominating
+	// them to their pair.
+	remoteLite         bool
+	nominatedPairs     map[uint16]CandidatePair
+	nominationRequests map[string]CandidatePair
+
+	// closed ends the task loop once the agent is closed
+	closed    chan struct{}
+	closeOnce sync.Once
+
+	checkInterval       time.Duration
+	candidateTimeout    time.Duration
+	connectionTimeout   time.Duration
+	disconnectedTimeout time.Duration
+	keepaliveInterval   time.Duration
+
+	mDNSResolver MulticastDNSResolver
+	mDNSTimeout  time.Duration
+
+	priorityPolicy  PriorityPolicy
+	candidateFilter CandidateFilter
 
 	LocalUfrag      string
 	LocalPwd        string
@@ -73,19 +100,66 @@ type Agent struct {
 	remotePwd        string
 	remoteCandidates map[string]Candidate
 
+	// remoteCandidatesComplete is set once the remote peer signaled the end
+	// of its candidates, pendingResolutions counts the .local candidates
+	// still being resolved
+	remoteCandidatesComplete bool
+	pendingResolutions       int
+
 	selectedPair CandidatePair
 	validPairs   []CandidatePair
+
+	// rtcpPair is the pair of the RTCP component, checked next to the one
+	// of RTP if there are local RTCP candidates. Until a pair is selected
+	// it is the first one found valid.
+	rtcpPair         CandidatePair
+	rtcpPairSelected bool
+
+	// selectedPairRTT is the round trip time of the last consent request
+	// answered on the selected pair, selectedPairNotifier is called when
+	// another pair is selected
+	selectedPairRTT      time.Duration
+	selectedPairNotifier func(local, remote Candidate)
+
+	// consentReceived is when consent to send on the selected pair was last
+	// granted, consentSent when it was last requested. consentRequests maps
+	// the transaction IDs of the requests in flight to when they were sent.
+	consentReceived time.Time
+	consentSent     time.Time
+	consentInterval time.Duration
+	consentRequests map[string]time.Time
+
+	// pairStats holds the stats of every pair checked, checkRequests maps
+	// the transaction IDs of the checks in flight to when they were sent
+	pairStats     map[CandidatePair]*CandidatePairStats
+	checkRequests map[string]time.Time
+
+	log logging.LeveledLogger
 }
 
 const (
-	// taskLoopInterval is the interval at which the agent performs checks
-	taskLoopInterval = 2 * time.Second
+	// defaultCheckInterval is the interval at which the agent performs checks
+	defaultCheckInterval = 2 * time.Second
+
+	// defaultKeepaliveInterval used to keep candidates alive
+	defaultKeepaliveInterval = 10 * time.Second
 
-	// keepaliveInterval used to keep candidates alive
-	keepaliveInterval = 10 * time.Second
+	// defaultConnectionTimeout is how long the selected pair may go without
+	// consent before the connection fails, RFC 7675 Section 5.1
+	defaultConnectionTimeout = 30 * time.Second
 
-	// connectionTimeout used to declare a connection dead
-	connectionTimeout = 30 * time.Second
+	// defaultDisconnectedTimeout is how long the selected pair may go
+	// without consent before the connection is disconnected
+	defaultDisconnectedTimeout = 10 * time.Second
+
+	// consentInterval is how often consent to send on the selected pair is
+	// requested, randomized by up to 20% to either side. Disconnected
+	// timeouts below twice the interval shorten it.
+	consentInterval = 5 * time.Second
+
+	// defaultCandidateTimeout is how long the agent waits for a first
+	// remote candidate before it fails
+	defaultCandidateTimeout = 30 * time.Second
 )
 
 // NewAgent creates a new Agent
@@ -97,6 +171,20 @@ func NewAgent(notifier func(ConnectionState)) *Agent {
 		gatheringState:   GatheringStateComplete, // TODO trickle-ice
 		connectionState:  ConnectionStateNew,
 		remoteCandidates: make(map[string]Candidate),
+		checkInterval:       defaultCheckInterval,
+		candidateTimeout:    defaultCandidateTimeout,
+		connectionTimeout:   defaultConnectionTimeout,
+		disconnectedTimeout: defaultDisconnectedTimeout,
+		keepaliveInterval:   defaultKeepaliveInterval,
+		consentRequests:     make(map[string]time.Time),
+		pairStats:           make(map[CandidatePair]*CandidatePairStats),
+		checkRequests:       make(map[string]time.Time),
+		nominatedPairs:      make(map[uint16]CandidatePair),
+		nominationRequests:  make(map[string]CandidatePair),
+		mDNSResolver:        NewMulticastDNSResolver(),
+		mDNSTimeout:         defaultMulticastDNSTimeout,
+		log:                 logging.NewDefaultLoggerFactory().NewLogger(logging.ScopeICE),
+		closed:              make(chan struct{}),
 
 		LocalUfrag: util.RandSeq(16),
 		LocalPwd:   util.RandSeq(32),
@@ -116,66 +204,195 @@ func (a *Agent) Start(isControlling bool, remoteUfrag, remotePwd string) error {
 		return errors.Errorf("remotePwd is empty")
 	}
 
-	a.isControlling = isControlling
+	a.haveStarted = true
+	a.startedAt = time.Now()
+	a.isControlling = isControlling || a.remoteLite
 	a.remoteUfrag = remoteUfrag
 	a.remotePwd = remotePwd
 
-	go a.taskLoop()
+	go a.taskLoop(a.checkInterval)
 	return nil
 }
 
-func (a *Agent) pingCandidate(local, remote Candidate) {
-	var msg *stun.Message
-	var err error
+// SetCandidateTimeout sets how long the agent waits for the first remote
+// candidate once started. Remote peers which don't trickle and have no
+// candidate in their description would otherwise keep the agent checking
+// forever, after the timeout the agent fails instead. A timeout of zero
+// waits indefinitely, pairing candidates whenever they arrive.
+func (a *Agent) SetCandidateTimeout(timeout time.Duration) {
+	a.Lock()
+	defer a.Unlock()
+	a.candidateTimeout = timeout
+}
+
+// SetCheckInterval sets how often the agent checks connectivity until a
+// pair is selected, and the consent and keepalives of the selected pair
+// after. Timeouts and intervals shorter than it take effect at the next
+// check. It applies once the agent starts, a non-positive interval restores
+// the default of 2 seconds.
+func (a *Agent) SetCheckInterval(interval time.Duration) {
+	a.Lock()
+	defer a.Unlock()
+	if interval <= 0 {
+		interval = defaultCheckInterval
+	}
+	a.checkInterval = interval
+}
+
+// SetConnectionTimeout sets how long the selected pair may go without the
+// remote peer answering a consent request before the agent fails. The
+// default is 30 seconds.
+func (a *Agent) SetConnectionTimeout(timeout time.Duration) {
+	a.Lock()
+	defer a.Unlock()
+	a.connectionTimeout = timeout
+}
+
+// SetDisconnectedTimeout sets how long the selected pair may go without the
+// remote peer answering a consent request before the agent is disconnected,
+// it is connected again once a consent request is answered. The default is
+// 10 seconds.
+func (a *Agent) SetDisconnectedTimeout(timeout time.Duration) {
+	a.Lock()
+	defer a.Unlock()
+	a.disconnectedTimeout = timeout
+}
+
+// SetKeepaliveInterval sets how long the selected pair may go without
+// sending anything before a keepalive is sent on it. The default is 10
+// seconds.
+func (a *Agent) SetKeepaliveInterval(interval time.Duration) {
+	a.Lock()
+	defer a.Unlock()
+	a.keepaliveInterval = interval
+}
+
+// SetLogger sets where the diagnostics of the agent are written, the
+// default is silent unless the environment enables the ice scope, see
+// logging.NewDefaultLoggerFactory
+func (a *Agent) SetLogger(logger logging.LeveledLogger) {
+	a.Lock()
+	defer a.Unlock()
+	a.log = logger
+}
+
+// SetSelectedPairNotifier sets the function called with copies of the
+// candidates of the selected pair whenever another pair is selected
+func (a *Agent) SetSelectedPairNotifier(notifier func(local, remote Candidate)) {
+	a.Lock()
+	defer a.Unlock()
+	a.selectedPairNotifier = notifier
+}
+
+// SetMulticastDNSResolver sets the resolver of the .local hostnames of
+// remote candidates, a nil resolver disables mDNS and such candidates are
+// discarded. The default resolver queries every multicast capable interface.
+func (a *Agent) SetMulticastDNSResolver(resolver MulticastDNSResolver) {
+	a.Lock()
+	defer a.Unlock()
+	a.mDNSResolver = resolver
+}
+
+// CandidateFilter reports whether the candidate may be paired, local tells
+// whether it is a local or a remote candidate
+type CandidateFilter func(c Candidate, local bool) bool
+
+// SetCandidateFilter sets the filter enforcing which candidates are paired,
+// nil pairs every candidate. Local candidates it rejects are neither checked
+// nor answer checks, remote candidates it rejects are discarded when they
+// are added or learned from a check.
+func (a *Agent) SetCandidateFilter(filter CandidateFilter) {
+	a.Lock()
+	defer a.Unlock()
+	a.candidateFilter = filter
+}
+
+// allowed reports whether the candidate passes the candidate filter
+// Note: the caller should hold the agent lock.
+func (a *Agent) allowed(c Candidate, local bool) bool {
+	return a.candidateFilter == nil || a.candidateFilter(c, local)
+}
+
+// SetPriorityPolicy sets the policy computing the priorities of the local
+// candidates added after it, which order the checks of the candidate pairs
+func (a *Agent) SetPriorityPolicy(policy PriorityPolicy) {
+	a.Lock()
+	defer a.Unlock()
+	a.priorityPolicy = policy
+}
+
+// SetMulticastDNSTimeout sets how long the .local hostname of a remote
+// candidate is resolved before the candidate is discarded. The default is
+// 5 seconds, environments blocking multicast can lower it to fail fast.
+func (a *Agent) SetMulticastDNSTimeout(timeout time.Duration) {
+	a.Lock()
+	defer a.Unlock()
+	a.mDNSTimeout = timeout
+}
+
+// remoteCredentials returns the ufrag and password of the ICE session of the
+// remote candidate
+func (a *Agent) remoteCredentials(remote Candidate) (ufrag, pwd string) {
+	if base := remote.GetBase(); base.Ufrag != "" && base.Pwd != "" {
+		return base.Ufrag, base.Pwd
+	}
+	return a.remoteUfrag, a.remotePwd
+}
+
+// pingCandidate sends a STUN Binding Request to the remote candidate, the
+// transaction ID of the request is returned
+func (a *Agent) pingCandidate(local, remote Candidate) []byte {
+	transactionID := stun.GenerateTransactionId()
+	remoteUfrag, remotePwd := a.remoteCredentials(remote)
+	attributes := []stun.Attribute{&stun.Username{Username: remoteUfrag + ":" + a.LocalUfrag}}
 
 	// The controlling agent MUST include the USE-CANDIDATE attribute in
 	// order to nominate a candidate pair (Section 8.1.1).  The controlled
 	// agent MUST NOT include the USE-CANDIDATE attribute in a Binding
 	// request.
-
-	if a.isControlling {
-		msg, err = stun.Build(stun.ClassRequest, stun.MethodBinding, stun.GenerateTransactionId(),
-			&stun.Username{Username: a.remoteUfrag + ":" + a.LocalUfrag},
-			&stun.UseCandidate{},
-			&stun.IceControlling{TieBreaker: a.tieBreaker},
-			&stun.Priority{Priority: uint32(local.GetBase().Priority(HostCandidatePreference, 1))},
-			&stun.MessageIntegrity{
-				Key: []byte(a.remotePwd),
-			},
-			&stun.Fingerprint{},
-		)
-	} else {
-		msg, err = stun.Build(stun.ClassRequest, stun.MethodBinding, stun.GenerateTransactionId(),
-			&stun.Username{Username: a.remoteUfrag + ":" + a.LocalUfrag},
-			&stun.IceControlled{TieBreaker: a.tieBreaker},
-			&stun.Priority{Priority: uint32(local.GetBase().Priority(HostCandidatePreference, 1))},
-			&stun.MessageIntegrity{
-				Key: []byte(a.remotePwd),
-			},
-			&stun.Fingerprint{},
-		)
+	nominates := a.nominates(local, remote)
+	switch {
+	case nominates:
+		attributes = append(attributes, &stun.UseCandidate{}, &stun.IceControlling{TieBreaker: a.tieBreaker})
+	case a.isControlling:
+		attributes = append(attributes, &stun.IceControlling{TieBreaker: a.tieBreaker})
+	default:
+		attributes = append(attributes, &stun.IceControlled{TieBreaker: a.tieBreaker})
 	}
 
+	msg, err := stun.Build(stun.ClassRequest, stun.MethodBinding, transactionID, append(attributes,
+		&stun.Priority{Priority: a.priorityPolicy.peerReflexivePriority(local)},
+		&stun.MessageIntegrity{
+			Key: []byte(remotePwd),
+		},
+		&stun.Fingerprint{},
+	)...)
 	if err != nil {
-		fmt.Println(err)
-		return
+		a.log.Warnf("Failed to send STUN binding request: %v", err)
+		return nil
 	}
 
+	if nominates && a.remoteLite && !a.componentSelected(local.GetBase().GetComponent()) {
+		a.nominationRequests[string(transactionID)] = newCandidatePair(local, remote)
+	}
+	a.recordCheckRequest(transactionID, local, remote)
 	a.sendSTUN(msg, local, remote)
+	return transactionID
 }
 
 // keepaliveCandidate sends a STUN Binding Indication to the remote candidate
 func (a *Agent) keepaliveCandidate(local, remote Candidate) {
+	remoteUfrag, remotePwd := a.remoteCredentials(remote)
 	msg, err := stun.Build(stun.ClassIndication, stun.MethodBinding, stun.GenerateTransactionId(),
-		&stun.Username{Username: a.remoteUfrag + ":" + a.LocalUfrag},
+		&stun.Username{Username: remoteUfrag + ":" + a.LocalUfrag},
 		&stun.MessageIntegrity{
-			Key: []byte(a.remotePwd),
+			Key: []byte(remotePwd),
 		},
 		&stun.Fingerprint{},
 	)
 
 	if err != nil {
-		fmt.Println(err)
+		a.log.Warnf("Failed to send STUN binding request: %v", err)
 		return
 	}
 
@@ -188,8 +405,7 @@ func (a *Agent) sendSTUN(msg *stun.Message, local, remote Candidate) {
 		// TODO: Determine if we should always drop the err
 		// E.g.: maybe handle for known valid pairs or to
 		// discard pairs faster.
-		_ = err
-		// fmt.Printf("failed to send STUN message: %v", err)
+		a.log.Tracef("failed to send STUN message: %v", err)
 	}
 }
 
@@ -205,45 +421,110 @@ func (a *Agent) updateConnectionState(newState ConnectionState) {
 }
 
 func (a *Agent) setValidPair(local, remote Candidate, selected bool) {
-	// TODO: avoid duplicates
+	stats := a.candidatePairStats(local, remote)
+	stats.State = CandidatePairStateSucceeded
+	stats.Nominated = stats.Nominated || selected
+
 	p := newCandidatePair(local, remote)
+	if local.GetBase().GetComponent() == ComponentRTCP {
+		a.setValidRTCPPair(p, selected)
+		return
+	} else if p == a.selectedPair {
+		return
+	}
 
 	if selected {
 		a.selectedPair = p
 		a.validPairs = nil
+		a.consentReceived = time.Now()
+		a.consentSent = time.Time{}
+		a.consentRequests = make(map[string]time.Time)
+		a.selectedPairRTT = 0
+		if a.selectedPairNotifier != nil {
+			// Call handler async since we are holding the agent lock
+			go a.selectedPairNotifier(copyCandidate(local), copyCandidate(remote))
+		}
 		// TODO: only set state to connected on selecting final pair?
 		a.updateConnectionState(ConnectionStateConnected)
 	} else {
 		// keep track of pairs with succesfull bindings since any of them
 		// can be used for communication until the final pair is selected:
 		// https://tools.ietf.org/html/draft-ietf-ice-rfc5245bis-20#section-12
+		for _, validPair := range a.validPairs {
+			if validPair == p {
+				return
+			}
+		}
 		a.validPairs = append(a.validPairs, p)
 	}
 }
 
-func (a *Agent) taskLoop() {
+// setValidRTCPPair remembers the pair of the RTCP component, the connection
+// state follows the RTP component only
+func (a *Agent) setValidRTCPPair(p CandidatePair, selected bool) {
+	if selected {
+		a.rtcpPair = p
+		a.rtcpPairSelected = true
+	} else if a.rtcpPair.local == nil {
+		a.rtcpPair = p
+	}
+}
+
+func (a *Agent) taskLoop(interval time.Duration) {
 	// TODO this should be dynamic, and grow when the connection is stable
-	t := time.NewTicker(taskLoopInterval)
+	t := time.NewTicker(interval)
 	a.updateConnectionState(ConnectionStateChecking)
 
 	for {
 		select {
 		case <-t.C:
 			a.Lock()
+			a.expireCheckRequests()
+			if a.candidatesTimedOut() {
+				a.log.Error(errors.Wrapf(ErrNoRemoteCandidates, "ICE failed after %s", time.Since(a.startedAt)).Error())
+				a.updateConnectionState(ConnectionStateFailed)
+				a.Unlock()
+				t.Stop()
+				return
+			}
+
 			if a.validateSelectedPair() {
+				a.checkConsent()
 				a.checkKeepalive()
+				a.pingAllCandidates()
+			} else if a.connectionState == ConnectionStateFailed {
+				a.Unlock()
+				t.Stop()
+				return
 			} else {
 				a.pingAllCandidates()
 			}
 			a.Unlock()
-		case <-a.taskLoopChan:
+		case <-a.closed:
 			t.Stop()
 			return
 		}
 	}
 }
 
-// validateSelectedPair checks if the selected pair is (still) valid
+// candidatesTimedOut checks if the remote peer failed to provide any
+// candidate within the candidate timeout, or signaled the end of its
+// candidates without providing any
+// Note: the caller should hold the agent lock.
+func (a *Agent) candidatesTimedOut() bool {
+	if len(a.remoteCandidates) != 0 || a.pendingResolutions != 0 {
+		return false
+	} else if a.remoteCandidatesComplete {
+		return true
+	} else if a.candidateTimeout == 0 {
+		return false
+	}
+	return time.Since(a.startedAt) > a.candidateTimeout
+}
+
+// validateSelectedPair checks if the selected pair is (still) valid. The
+// agent is disconnected while the remote peer doesn't grant consent to send
+// on it, and fails once consent expires, RFC 7675 Section 5.1.
 // Note: the caller should hold the agent lock.
 func (a *Agent) validateSelectedPair() bool {
 	if a.selectedPair.remote == nil || a.selectedPair.local == nil {
@@ -251,13 +532,71 @@ func (a *Agent) validateSelectedPair() bool {
 		return false
 	}
 
-	if time.Since(a.selectedPair.remote.GetBase().LastReceived) > connectionTimeout {
+	sinceConsent := time.Since(a.consentReceived)
+	if sinceConsent > a.connectionTimeout {
+		a.log.Error(errors.Wrapf(ErrConsentExpired, "ICE failed after %s without consent", sinceConsent).Error())
+		a.candidatePairStats(a.selectedPair.local, a.selectedPair.remote).State = CandidatePairStateFailed
 		a.selectedPair.remote = nil
 		a.selectedPair.local = nil
+		a.updateConnectionState(ConnectionStateFailed)
+		return false
+	} else if sinceConsent > a.disconnectedTimeout {
 		a.updateConnectionState(ConnectionStateDisconnected)
+	}
+
+	return true
+}
+
+// checkConsent sends a consent request on the selected pair once the
+// randomized consent interval passed since the last one, requests which
+// weren't answered before the connection timeout are forgotten
+// Note: the caller should hold the agent lock.
+func (a *Agent) checkConsent() {
+	now := time.Now()
+	if now.Sub(a.consentSent) < a.consentInterval {
+		return
+	}
+
+	for transactionID, sent := range a.consentRequests {
+		if now.Sub(sent) > a.connectionTimeout {
+			delete(a.consentRequests, transactionID)
+		}
+	}
+
+	if transactionID := a.pingCandidate(a.selectedPair.local, a.selectedPair.remote); transactionID != nil {
+		a.consentRequests[string(transactionID)] = now
+	}
+	a.consentSent = now
+
+	// Consent is requested at least twice before the pair is disconnected
+	interval := consentInterval
+	if half := a.disconnectedTimeout / 2; half > 0 && half < interval {
+		interval = half
+	}
+	a.consentInterval = time.Duration((0.8 + 0.4*rand.Float64()) * float64(interval))
+}
+
+// handleConsentResponse grants consent to send on the selected pair if the
+// message answers one of its consent requests, it reports whether the
+// message was such an answer
+// Note: the caller should hold the agent lock.
+func (a *Agent) handleConsentResponse(m *stun.Message, localCandidate, remoteCandidate Candidate) bool {
+	if m.Class != stun.ClassSuccessResponse || m.Method != stun.MethodBinding {
+		return false
+	}
+
+	transactionID := string(m.TransactionID)
+	sent, ok := a.consentRequests[transactionID]
+	if !ok {
 		return false
 	}
+	delete(a.consentRequests, transactionID)
 
+	if newCandidatePair(localCandidate, remoteCandidate) == a.selectedPair {
+		a.consentReceived = time.Now()
+		a.selectedPairRTT = a.consentReceived.Sub(sent)
+		a.updateConnectionState(ConnectionStateConnected)
+	}
 	return true
 }
 
@@ -269,42 +608,223 @@ func (a *Agent) checkKeepalive() {
 		return
 	}
 
-	if time.Since(a.selectedPair.remote.GetBase().LastSent) > keepaliveInterval {
+	if time.Since(a.selectedPair.remote.GetBase().LastSent) > a.keepaliveInterval {
 		a.keepaliveCandidate(a.selectedPair.local, a.selectedPair.remote)
 	}
+	if a.rtcpPair.remote != nil && time.Since(a.rtcpPair.remote.GetBase().LastSent) > a.keepaliveInterval {
+		a.keepaliveCandidate(a.rtcpPair.local, a.rtcpPair.remote)
+	}
 }
 
-// pingAllCandidates sends STUN Binding Requests to all candidates
+// pingAllCandidates sends STUN Binding Requests to all candidate pairs of
+// the same address family and component, unless a pair of the component is
+// selected already. IPv6 and IPv4 pairs are checked interleaved, starting
+// with IPv6, which is preferred without starving IPv4 when it is broken,
+// RFC 8421 Section 4.
 // Note: the caller should hold the agent lock.
 func (a *Agent) pingAllCandidates() {
+	var ipv6Pairs, ipv4Pairs []CandidatePair
 	for _, localCandidate := range a.LocalCandidates {
+		if a.componentSelected(localCandidate.GetBase().GetComponent()) || !a.allowed(localCandidate, true) {
+			continue
+		}
 		for _, remoteCandidate := range a.remoteCandidates {
-			a.pingCandidate(localCandidate, remoteCandidate)
+			if !localCandidate.GetBase().canPair(remoteCandidate.GetBase()) {
+				continue // an address family can't reach the other, nor a protocol
+			}
+
+			if localCandidate.GetBase().IsIPv6() {
+				ipv6Pairs = append(ipv6Pairs, newCandidatePair(localCandidate, remoteCandidate))
+			} else {
+				ipv4Pairs = append(ipv4Pairs, newCandidatePair(localCandidate, remoteCandidate))
+			}
+		}
+	}
+
+	a.sortPairs(ipv6Pairs)
+	a.sortPairs(ipv4Pairs)
+	for i := 0; i < len(ipv6Pairs) || i < len(ipv4Pairs); i++ {
+		if i < len(ipv6Pairs) {
+			a.pingCandidate(ipv6Pairs[i].local, ipv6Pairs[i].remote)
 		}
+		if i < len(ipv4Pairs) {
+			a.pingCandidate(ipv4Pairs[i].local, ipv4Pairs[i].remote)
+		}
+	}
+}
+
+// sortPairs sorts the pairs in the descending order of their priority
+// Note: the caller should hold the agent lock.
+func (a *Agent) sortPairs(pairs []CandidatePair) {
+	sort.SliceStable(pairs, func(i, j int) bool {
+		return a.pairPriority(pairs[i]) > a.pairPriority(pairs[j])
+	})
+}
+
+// pairPriority returns the priority of the pair given the role of the agent
+// Note: the caller should hold the agent lock.
+func (a *Agent) pairPriority(p CandidatePair) uint64 {
+	local, remote := CandidatePriority(p.local), CandidatePriority(p.remote)
+	if a.isControlling {
+		return pairPriority(local, remote)
+	}
+	return pairPriority(remote, local)
+}
+
+// componentSelected reports whether a pair of the component is selected
+// Note: the caller should hold the agent lock.
+func (a *Agent) componentSelected(component uint16) bool {
+	if component == ComponentRTCP {
+		return a.rtcpPairSelected
 	}
+	return a.selectedPair.local != nil && a.selectedPair.remote != nil
 }
 
-// AddRemoteCandidate adds a new remote candidate
+// AddRemoteCandidate adds a new remote candidate, candidates with a .local
+// hostname are added once it has been resolved
 func (a *Agent) AddRemoteCandidate(c Candidate) {
+	if isMulticastDNSHost(c.GetBase().Address) {
+		a.Lock()
+		a.pendingResolutions++
+		a.Unlock()
+
+		go func() {
+			a.resolveRemoteCandidate(c)
+
+			a.Lock()
+			a.pendingResolutions--
+			a.Unlock()
+		}()
+		return
+	}
+
 	a.Lock()
 	defer a.Unlock()
+	if !a.allowed(c, false) {
+		a.log.Debugf("Discarding remote candidate %s rejected by the filter", c.String())
+		return
+	}
 	if _, found := a.remoteCandidates[c.String()]; !found {
 		a.remoteCandidates[c.String()] = c
 	}
 }
 
-// AddLocalCandidate adds a new local candidate
+func (a *Agent) resolveRemoteCandidate(c Candidate) {
+	a.RLock()
+	resolver, timeout := a.mDNSResolver, a.mDNSTimeout
+	a.RUnlock()
+
+	host := c.GetBase().Address
+	if resolver == nil {
+		a.log.Warn(errors.Wrapf(ErrMulticastDNSDisabled, "discarding remote candidate %s", host).Error())
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	ip, err := resolver.Resolve(ctx, host)
+	if err != nil {
+		a.log.Warn(errors.Wrapf(err, "discarding remote candidate %s", host).Error())
+		return
+	}
+
+	c.GetBase().Address = ip.String()
+	a.AddRemoteCandidate(c)
+}
+
+// RemoveRemoteCandidate removes a remote candidate the remote peer stopped
+// using, pairs using it are pruned. If it was part of the selected pair
+// the agent is disconnected until another pair is selected.
+func (a *Agent) RemoveRemoteCandidate(c Candidate) {
+	a.Lock()
+	defer a.Unlock()
+
+	key := c.String()
+	delete(a.remoteCandidates, key)
+
+	validPairs := a.validPairs[:0]
+	for _, p := range a.validPairs {
+		if p.remote.String() != key {
+			validPairs = append(validPairs, p)
+		}
+	}
+	a.validPairs = validPairs
+	a.removePairStats(func(p CandidatePair) bool {
+		return p.remote.String() == key
+	})
+
+	if a.rtcpPair.remote != nil && a.rtcpPair.remote.String() == key {
+		a.rtcpPair = CandidatePair{}
+		a.rtcpPairSelected = false
+	}
+
+	if a.selectedPair.remote != nil && a.selectedPair.remote.String() == key {
+		a.selectedPair.remote = nil
+		a.selectedPair.local = nil
+		a.updateConnectionState(ConnectionStateDisconnected)
+	}
+}
+
+// RemoteCandidates returns copies of the remote candidates which have been
+// added or learned from connectivity checks
+func (a *Agent) RemoteCandidates() []Candidate {
+	a.RLock()
+	defer a.RUnlock()
+
+	candidates := make([]Candidate, 0, len(a.remoteCandidates))
+	for _, c := range a.remoteCandidates {
+		candidates = append(candidates, copyCandidate(c))
+	}
+	return candidates
+}
+
+// SetRemoteCandidatesComplete records that the remote peer signaled the end
+// of its candidates, the agent fails right away if none were provided
+// instead of waiting for the candidate timeout.
+func (a *Agent) SetRemoteCandidatesComplete() {
+	a.Lock()
+	defer a.Unlock()
+	a.remoteCandidatesComplete = true
+}
+
+// AddLocalCandidate adds a new local candidate, its SignaledPriority is set
+// from the PriorityPolicy
 func (a *Agent) AddLocalCandidate(c Candidate) {
 	a.Lock()
 	defer a.Unlock()
+	c.GetBase().SignaledPriority = a.priorityPolicy.Priority(c)
 	a.LocalCandidates = append(a.LocalCandidates, c)
 }
 
+// RemoveLocalComponent removes the local candidates of the component and
+// its pair, like those of the RTCP component once the remote peer
+// multiplexes RTCP with RTP
+func (a *Agent) RemoveLocalComponent(component uint16) {
+	a.Lock()
+	defer a.Unlock()
+
+	candidates := a.LocalCandidates[:0]
+	for _, c := range a.LocalCandidates {
+		if c.GetBase().GetComponent() != component {
+			candidates = append(candidates, c)
+		}
+	}
+	a.LocalCandidates = candidates
+	a.removePairStats(func(p CandidatePair) bool {
+		return p.local.GetBase().GetComponent() == component
+	})
+
+	if component == ComponentRTCP {
+		a.rtcpPair = CandidatePair{}
+		a.rtcpPairSelected = false
+	}
+}
+
 // Close cleans up the Agent
 func (a *Agent) Close() {
-	if a.taskLoopChan != nil {
-		close(a.taskLoopChan)
-	}
+	a.closeOnce.Do(func() {
+		close(a.closed)
+	})
 }
 
 func isCandidateMatch(c Candidate, testAddress string, testPort int) bool {
@@ -331,8 +851,18 @@ func getTransportAddrCandidate(candidates []Candidate, addr *stun.TransportAddr)
 	return nil
 }
 
-func getUDPAddrCandidate(candidates map[string]Candidate, addr *net.UDPAddr) Candidate {
+func getUDPAddrCandidate(candidates map[string]Candidate, addr *net.UDPAddr, protocol ProtoType, component uint16) Candidate {
 	for _, c := range candidates {
+		if c.GetBase().Protocol != protocol || c.GetBase().GetComponent() != component {
+			continue
+		}
+
+		// Active TCP candidates connect from an ephemeral port, not the
+		// one they were signaled with
+		if c.GetBase().TCPType == TCPTypeActive && c.GetBase().Address == addr.IP.String() {
+			return c
+		}
+
 		if isCandidateMatch(c, addr.IP.String(), addr.Port) {
 			return c
 		}
@@ -354,15 +884,16 @@ func (a *Agent) sendBindingSuccess(m *stun.Message, localCandidate, remoteCandid
 		},
 		&stun.Fingerprint{},
 	); err != nil {
-		fmt.Printf("Failed to handle inbound ICE from: %s to: %s error: %s", localCandidate.String(), remoteCandidate.String(), err.Error())
+		a.log.Warnf("Failed to handle inbound ICE from: %s to: %s error: %s", localCandidate.String(), remoteCandidate.String(), err.Error())
 	} else {
+		a.candidatePairStats(localCandidate, remoteCandidate).ResponsesSent++
 		a.sendSTUN(out, localCandidate, remoteCandidate)
 	}
 }
 
 func (a *Agent) handleInboundControlled(m *stun.Message, localCandidate, remoteCandidate Candidate) {
 	if _, isControlled := m.GetOneAttribute(stun.AttrIceControlled); isControlled && !a.isControlling {
-		fmt.Println("inbound isControlled && a.isControlling == false")
+		a.log.Debug("inbound isControlled && a.isControlling == false")
 		return
 	}
 
@@ -379,18 +910,25 @@ func (a *Agent) handleInboundControlled(m *stun.Message, localCandidate, remoteC
 
 func (a *Agent) handleInboundControlling(m *stun.Message, localCandidate, remoteCandidate Candidate) {
 	if _, isControlling := m.GetOneAttribute(stun.AttrIceControlling); isControlling && a.isControlling {
-		fmt.Println("inbound isControlling && a.isControlling == true")
+		a.log.Debug("inbound isControlling && a.isControlling == true")
 		return
 	} else if _, useCandidate := m.GetOneAttribute(stun.AttrUseCandidate); useCandidate && a.isControlling {
-		fmt.Println("useCandidate && a.isControlling == true")
+		a.log.Debug("useCandidate && a.isControlling == true")
 		return
 	}
 
 	successResponse := m.Method == stun.MethodBinding && m.Class == stun.ClassSuccessResponse
-	// Remember the working pair and select it when receiving a success response
-	a.setValidPair(localCandidate, remoteCandidate, successResponse)
+	// Remember the working pair and select it when receiving a success
+	// response, with regular nomination once the pair is nominated
+	selected := successResponse
+	if successResponse && a.remoteLite {
+		selected = a.handleNominationResponse(string(m.TransactionID))
+	}
+	a.setValidPair(localCandidate, remoteCandidate, selected)
 
-	if !successResponse {
+	if successResponse && !selected {
+		a.nominate(localCandidate, remoteCandidate)
+	} else if !successResponse {
 		// Send success response
 		a.sendBindingSuccess(m, localCandidate, remoteCandidate)
 
@@ -399,6 +937,74 @@ func (a *Agent) handleInboundControlling(m *stun.Message, localCandidate, remote
 	}
 }
 
+// addPeerReflexiveCandidate adds a remote candidate for the source of a
+// connectivity check which doesn't match any signaled remote candidate, such
+// as the address a NAT mapped the remote peer to. Only requests carrying the
+// ufrags of this session are trusted, nil is returned for others.
+// https://tools.ietf.org/html/rfc8445#section-7.3.1.3
+// Note: the caller should hold the agent lock.
+func (a *Agent) addPeerReflexiveCandidate(m *stun.Message, localCandidate Candidate, remote *net.UDPAddr) Candidate {
+	if m.Class != stun.ClassRequest || m.Method != stun.MethodBinding || a.remoteUfrag == "" {
+		return nil
+	}
+
+	// USERNAME is the ufrag of the receiving agent followed by the one of
+	// the sending agent
+	username, ok := m.GetOneAttribute(stun.AttrUsername)
+	if !ok || !strings.HasPrefix(string(username.Value), a.LocalUfrag+":") {
+		return nil
+	}
+	ufrag, pwd, ok := a.findRemoteCredentials(string(username.Value)[len(a.LocalUfrag)+1:])
+	if !ok {
+		return nil
+	}
+
+	c := &CandidatePeerReflexive{
+		CandidateBase: CandidateBase{
+			Protocol:  localCandidate.GetBase().Protocol,
+			Address:   remote.IP.String(),
+			Port:      remote.Port,
+			Component: localCandidate.GetBase().GetComponent(),
+			Ufrag:     ufrag,
+			Pwd:       pwd,
+		},
+	}
+
+	// The PRIORITY of the check is the one the remote peer gives the
+	// candidate, RFC 8445 Section 7.3.1.3
+	priority := &stun.Priority{}
+	if raw, ok := m.GetOneAttribute(stun.AttrPriority); ok && priority.Unpack(m, raw) == nil {
+		c.SignaledPriority = priority.Priority
+	}
+
+	// Peers connecting to a passive TCP candidate are active
+	if c.Protocol == ProtoTypeTCP {
+		c.TCPType = TCPTypeActive
+	}
+
+	if _, found := a.remoteCandidates[c.String()]; found || !a.allowed(c, false) {
+		return nil // the address is signaled for another protocol, or filtered
+	}
+	a.remoteCandidates[c.String()] = c
+	return c
+}
+
+// findRemoteCredentials returns the credentials of the ICE session of the
+// remote peer with the ufrag, the ones the agent was started with or those
+// of a media section a remote candidate was signaled in.
+// Note: the caller should hold the agent lock.
+func (a *Agent) findRemoteCredentials(ufrag string) (string, string, bool) {
+	if ufrag == a.remoteUfrag {
+		return "", "", true
+	}
+	for _, c := range a.remoteCandidates {
+		if base := c.GetBase(); base.Ufrag == ufrag && base.Pwd != "" {
+			return base.Ufrag, base.Pwd, true
+		}
+	}
+	return "", "", false
+}
+
 // HandleInbound processes traffic from a remote candidate
 func (a *Agent) HandleInbound(buf []byte, local *stun.TransportAddr, remote *net.UDPAddr) {
 	a.Lock()
@@ -406,23 +1012,34 @@ func (a *Agent) HandleInbound(buf []byte, local *stun.TransportAddr, remote *net
 
 	localCandidate := getTransportAddrCandidate(a.LocalCandidates, local)
 	if localCandidate == nil {
-		// TODO debug
-		// fmt.Printf("Could not find local candidate for %s:%d ", local.IP.String(), local.Port)
+		a.log.Debugf("Could not find local candidate for %s:%d", local.IP.String(), local.Port)
+		return
+	} else if !a.allowed(localCandidate, true) {
 		return
 	}
 
-	remoteCandidate := getUDPAddrCandidate(a.remoteCandidates, remote)
-	if remoteCandidate == nil {
-		// TODO debug
-		// fmt.Printf("Could not find remote candidate for %s:%d ", remote.IP.String(), remote.Port)
+	m, err := stun.NewMessage(buf)
+	if err != nil {
+		a.log.Warnf("Failed to handle decode ICE from: %s to: %s error: %s", local.String(), remote.String(), err.Error())
 		return
 	}
 
+	remoteCandidate := getUDPAddrCandidate(a.remoteCandidates, remote, localCandidate.GetBase().Protocol, localCandidate.GetBase().GetComponent())
+	if remoteCandidate == nil {
+		if remoteCandidate = a.addPeerReflexiveCandidate(m, localCandidate, remote); remoteCandidate == nil {
+			return
+		}
+	}
+
+	// Replies to an active TCP candidate go to the port it connected from
+	if remoteCandidate.GetBase().TCPType == TCPTypeActive {
+		remoteCandidate.GetBase().Port = remote.Port
+	}
+
 	remoteCandidate.GetBase().seen(false)
+	a.recordInbound(m, localCandidate, remoteCandidate)
 
-	m, err := stun.NewMessage(buf)
-	if err != nil {
-		fmt.Println(fmt.Sprintf("Failed to handle decode ICE from: %s to: %s error: %s", local.String(), remote.String(), err.Error()))
+	if a.handleConsentResponse(m, localCandidate, remoteCandidate) {
 		return
 	}
 
@@ -448,3 +1065,28 @@ func (a *Agent) SelectedPair() (local *stun.TransportAddr, remote *net.UDPAddr)
 
 	return a.selectedPair.getAddrs()
 }
+
+// SelectedRTCPPair gets the addresses of the pair of the RTCP component (or
+// returns nil)
+func (a *Agent) SelectedRTCPPair() (local *stun.TransportAddr, remote *net.UDPAddr) {
+	a.RLock()
+	defer a.RUnlock()
+
+	if a.rtcpPair.remote == nil || a.rtcpPair.local == nil {
+		return nil, nil
+	}
+	return a.rtcpPair.getAddrs()
+}
+
+// SelectedCandidatePair returns copies of the candidates of the selected
+// pair, or nil if none is selected. rtt is the round trip time of the last
+// consent request answered on the pair, zero until one is answered.
+func (a *Agent) SelectedCandidatePair() (local, remote Candidate, rtt time.Duration) {
+	a.RLock()
+	defer a.RUnlock()
+
+	if a.selectedPair.remote == nil || a.selectedPair.local == nil {
+		return nil, nil, 0
+	}
+	return copyCandidate(a.selectedPair.local), copyCandidate(a.selectedPair.remote), a.selectedPairRTT
+}
@@ -1,7 +1,12 @@
 package ice
 
 import (
+	"net"
 	"testing"
+	"time"
+
+	"github.com/pions/pkg/stun"
+	"github.com/stretchr/testify/assert"
 )
 
 func TestTimeConsuming(t *testing.T) {
@@ -21,3 +26,556 @@ func TestTimeConsuming(t *testing.T) {
 // Output:
 // [a a b]
 // }
+
+func TestAgent_CandidateTimeout(t *testing.T) {
+	a := NewAgent(nil)
+	a.haveStarted = true
+	a.startedAt = time.Now().Add(-2 * defaultCandidateTimeout)
+	assert.True(t, a.candidatesTimedOut())
+
+	a.SetCandidateTimeout(0)
+	assert.False(t, a.candidatesTimedOut())
+
+	a.SetCandidateTimeout(defaultCandidateTimeout)
+	a.AddRemoteCandidate(&CandidateHost{
+		CandidateBase: CandidateBase{
+			Protocol: ProtoTypeUDP,
+			Address:  "192.168.0.2",
+			Port:     5000,
+		},
+	})
+	assert.False(t, a.candidatesTimedOut())
+}
+
+func TestAgent_RemoteCandidatesComplete(t *testing.T) {
+	a := NewAgent(nil)
+	a.haveStarted = true
+	a.startedAt = time.Now()
+	assert.False(t, a.candidatesTimedOut())
+
+	a.pendingResolutions = 1
+	a.SetRemoteCandidatesComplete()
+	assert.False(t, a.candidatesTimedOut())
+
+	a.pendingResolutions = 0
+	assert.True(t, a.candidatesTimedOut())
+}
+
+func TestAgent_RemoveRemoteCandidate(t *testing.T) {
+	newCandidate := func(address string) Candidate {
+		return &CandidateHost{
+			CandidateBase: CandidateBase{
+				Protocol: ProtoTypeUDP,
+				Address:  address,
+				Port:     5000,
+			},
+		}
+	}
+	local := newCandidate("192.168.0.1")
+	stale, other := newCandidate("192.168.0.2"), newCandidate("192.168.0.3")
+
+	a := NewAgent(nil)
+	a.AddRemoteCandidate(stale)
+	a.AddRemoteCandidate(other)
+	a.setValidPair(local, stale, false)
+	a.setValidPair(local, other, false)
+
+	a.RemoveRemoteCandidate(newCandidate("192.168.0.2"))
+	assert.Equal(t, 1, len(a.remoteCandidates))
+	assert.Equal(t, []CandidatePair{newCandidatePair(local, other)}, a.validPairs)
+	assert.Equal(t, 1, len(a.CandidatePairStats()))
+
+	a.setValidPair(local, other, true)
+	assert.Equal(t, ConnectionState(ConnectionStateConnected), a.connectionState)
+	a.RemoveRemoteCandidate(other)
+	assert.Equal(t, 0, len(a.remoteCandidates))
+	assert.Nil(t, a.selectedPair.remote)
+	assert.Equal(t, ConnectionState(ConnectionStateDisconnected), a.connectionState)
+}
+
+func TestAgent_SetCandidateFilter(t *testing.T) {
+	conn, err := net.ListenPacket("udp4", "127.0.0.1:0")
+	assert.Nil(t, err)
+	defer func() { assert.Nil(t, conn.Close()) }()
+
+	localAddr := conn.LocalAddr().(*net.UDPAddr)
+	local := &CandidateHost{
+		CandidateBase: CandidateBase{
+			Protocol: ProtoTypeUDP,
+			Address:  "127.0.0.1",
+			Port:     localAddr.Port,
+			Conn:     conn,
+		},
+	}
+
+	a := NewAgent(nil)
+	a.remoteUfrag, a.remotePwd = "remote", "password"
+	a.AddLocalCandidate(local)
+	a.SetCandidateFilter(func(c Candidate, isLocal bool) bool {
+		_, isHost := c.(*CandidateHost)
+		return isHost
+	})
+
+	a.AddRemoteCandidate(&CandidateSrflx{CandidateBase: CandidateBase{Protocol: ProtoTypeUDP, Address: "192.0.2.1", Port: 5000}})
+	a.AddRemoteCandidate(&CandidateHost{CandidateBase: CandidateBase{Protocol: ProtoTypeUDP, Address: "192.0.2.2", Port: 5000}})
+	assert.Equal(t, 1, len(a.RemoteCandidates()))
+
+	// Peer reflexive candidates aren't learned from the checks
+	m, err := stun.Build(stun.ClassRequest, stun.MethodBinding, stun.GenerateTransactionId(),
+		&stun.Username{Username: a.LocalUfrag + ":remote"},
+		&stun.Fingerprint{},
+	)
+	assert.Nil(t, err)
+	a.HandleInbound(m.Pack(), &stun.TransportAddr{IP: localAddr.IP, Port: localAddr.Port}, &net.UDPAddr{IP: net.ParseIP("192.0.2.3"), Port: 5000})
+	assert.Equal(t, 1, len(a.RemoteCandidates()))
+	assert.Empty(t, a.validPairs)
+}
+
+func TestAgent_RTCPComponent(t *testing.T) {
+	newCandidate := func(address string, component uint16) Candidate {
+		return &CandidateHost{
+			CandidateBase: CandidateBase{
+				Protocol:  ProtoTypeUDP,
+				Address:   address,
+				Port:      5000,
+				Component: component,
+			},
+		}
+	}
+	local, remote := newCandidate("192.168.0.1", ComponentRTP), newCandidate("192.168.0.2", ComponentRTP)
+	localRTCP, remoteRTCP := newCandidate("192.168.0.1", ComponentRTCP), newCandidate("192.168.0.3", ComponentRTCP)
+	other := newCandidate("192.168.0.4", ComponentRTCP)
+
+	a := NewAgent(nil)
+	a.AddLocalCandidate(local)
+	a.AddLocalCandidate(localRTCP)
+	a.AddRemoteCandidate(remoteRTCP)
+	a.AddRemoteCandidate(other)
+
+	// The RTCP pair is used until one is selected, it doesn't connect
+	a.setValidPair(localRTCP, remoteRTCP, false)
+	a.setValidPair(localRTCP, other, false)
+	assert.Equal(t, newCandidatePair(localRTCP, remoteRTCP), a.rtcpPair)
+	assert.Nil(t, a.validPairs)
+	assert.Equal(t, ConnectionState(ConnectionStateNew), a.connectionState)
+	assert.False(t, a.componentSelected(ComponentRTCP))
+
+	a.setValidPair(localRTCP, other, true)
+	assert.Equal(t, newCandidatePair(localRTCP, other), a.rtcpPair)
+	assert.True(t, a.componentSelected(ComponentRTCP))
+	assert.False(t, a.componentSelected(ComponentRTP))
+
+	a.setValidPair(local, remote, true)
+	assert.True(t, a.componentSelected(ComponentRTP))
+	rtpLocal, _ := a.SelectedPair()
+	rtcpLocal, rtcpRemote := a.SelectedRTCPPair()
+	assert.Equal(t, 5000, rtpLocal.Port)
+	assert.Equal(t, 5000, rtcpLocal.Port)
+	assert.Equal(t, "192.168.0.4", rtcpRemote.IP.String())
+
+	a.RemoveLocalComponent(ComponentRTCP)
+	assert.Equal(t, []Candidate{local}, a.LocalCandidates)
+	assert.Equal(t, 1, len(a.CandidatePairStats()))
+	rtcpLocal, rtcpRemote = a.SelectedRTCPPair()
+	assert.Nil(t, rtcpLocal)
+	assert.Nil(t, rtcpRemote)
+}
+
+func TestAgent_Consent(t *testing.T) {
+	conn, err := net.ListenPacket("udp4", "127.0.0.1:0")
+	assert.Nil(t, err)
+	defer func() { assert.Nil(t, conn.Close()) }()
+
+	local := &CandidateHost{
+		CandidateBase: CandidateBase{
+			Protocol: ProtoTypeUDP,
+			Address:  "127.0.0.1",
+			Port:     conn.LocalAddr().(*net.UDPAddr).Port,
+			Conn:     conn,
+		},
+	}
+	remote := &CandidateHost{
+		CandidateBase: CandidateBase{
+			Protocol: ProtoTypeUDP,
+			Address:  "127.0.0.1",
+			Port:     9,
+		},
+	}
+
+	a := NewAgent(nil)
+	a.remoteUfrag, a.remotePwd = "remote", "password"
+	a.setValidPair(local, remote, false)
+	a.setValidPair(local, remote, false)
+	assert.Equal(t, 1, len(a.validPairs))
+
+	a.setValidPair(local, remote, true)
+	assert.Equal(t, ConnectionState(ConnectionStateConnected), a.connectionState)
+
+	assert.True(t, a.validateSelectedPair())
+	a.checkConsent()
+	assert.Equal(t, 1, len(a.consentRequests))
+	a.checkConsent()
+	assert.Equal(t, 1, len(a.consentRequests), "consent is requested once per interval")
+
+	var transactionID string
+	for id := range a.consentRequests {
+		transactionID = id
+	}
+	response := func(transactionID string) *stun.Message {
+		m, err := stun.Build(stun.ClassSuccessResponse, stun.MethodBinding, []byte(transactionID))
+		assert.Nil(t, err)
+		return m
+	}
+
+	// Missing consent disconnects until it is granted again
+	a.consentReceived = time.Now().Add(-defaultDisconnectedTimeout - time.Second)
+	assert.True(t, a.validateSelectedPair())
+	assert.Equal(t, ConnectionState(ConnectionStateDisconnected), a.connectionState)
+
+	assert.False(t, a.handleConsentResponse(response("unknown-transaction"), local, remote))
+	assert.True(t, a.handleConsentResponse(response(transactionID), local, remote))
+	assert.Equal(t, ConnectionState(ConnectionStateConnected), a.connectionState)
+	assert.Empty(t, a.consentRequests)
+
+	// Expired consent fails
+	a.consentReceived = time.Now().Add(-defaultConnectionTimeout - time.Second)
+	assert.False(t, a.validateSelectedPair())
+	assert.Equal(t, ConnectionState(ConnectionStateFailed), a.connectionState)
+	assert.Nil(t, a.selectedPair.remote)
+}
+
+func TestAgent_PeerReflexiveCandidate(t *testing.T) {
+	conn, err := net.ListenPacket("udp4", "127.0.0.1:0")
+	assert.Nil(t, err)
+	defer func() { assert.Nil(t, conn.Close()) }()
+
+	localAddr := conn.LocalAddr().(*net.UDPAddr)
+	local := &CandidateHost{
+		CandidateBase: CandidateBase{
+			Protocol: ProtoTypeUDP,
+			Address:  "127.0.0.1",
+			Port:     localAddr.Port,
+			Conn:     conn,
+		},
+	}
+
+	a := NewAgent(nil)
+	a.remoteUfrag, a.remotePwd = "remote", "password"
+	a.AddLocalCandidate(local)
+
+	check := func(username string) []byte {
+		m, err := stun.Build(stun.ClassRequest, stun.MethodBinding, stun.GenerateTransactionId(),
+			&stun.Username{Username: username},
+			&stun.Priority{Priority: 1862270975},
+			&stun.Fingerprint{},
+		)
+		assert.Nil(t, err)
+		return m.Pack()
+	}
+	transportAddr := &stun.TransportAddr{IP: localAddr.IP, Port: localAddr.Port}
+
+	// Checks of other sessions are dropped
+	a.HandleInbound(check("other:remote"), transportAddr, &net.UDPAddr{IP: net.ParseIP("192.0.2.1"), Port: 5000})
+	assert.Empty(t, a.remoteCandidates)
+
+	a.HandleInbound(check(a.LocalUfrag+":remote"), transportAddr, &net.UDPAddr{IP: net.ParseIP("192.0.2.1"), Port: 5000})
+	if assert.Equal(t, 1, len(a.remoteCandidates)) {
+		remote, ok := a.remoteCandidates["192.0.2.1:5000"].(*CandidatePeerReflexive)
+		if assert.True(t, ok) {
+			assert.Equal(t, ProtoTypeUDP, remote.Protocol)
+			assert.Equal(t, uint32(1862270975), remote.SignaledPriority)
+			assert.Equal(t, []CandidatePair{newCandidatePair(local, remote)}, a.validPairs)
+		}
+	}
+}
+
+func TestAgent_RemoteCredentials(t *testing.T) {
+	conn, err := net.ListenPacket("udp4", "127.0.0.1:0")
+	assert.Nil(t, err)
+	defer func() { assert.Nil(t, conn.Close()) }()
+	remoteConn, err := net.ListenPacket("udp4", "127.0.0.1:0")
+	assert.Nil(t, err)
+	defer func() { assert.Nil(t, remoteConn.Close()) }()
+
+	localAddr := conn.LocalAddr().(*net.UDPAddr)
+	local := &CandidateHost{
+		CandidateBase: CandidateBase{
+			Protocol: ProtoTypeUDP,
+			Address:  "127.0.0.1",
+			Port:     localAddr.Port,
+			Conn:     conn,
+		},
+	}
+
+	a := NewAgent(nil)
+	a.remoteUfrag, a.remotePwd = "audio", "audioPassword"
+	a.AddLocalCandidate(local)
+
+	// Candidates of a media section with credentials of its own are checked
+	// with those
+	remote := &CandidateHost{
+		CandidateBase: CandidateBase{
+			Protocol: ProtoTypeUDP,
+			Address:  "127.0.0.1",
+			Port:     remoteConn.LocalAddr().(*net.UDPAddr).Port,
+			Ufrag:    "video",
+			Pwd:      "videoPassword",
+		},
+	}
+	a.AddRemoteCandidate(remote)
+	assert.NotNil(t, a.pingCandidate(local, remote))
+
+	buffer := make([]byte, 1500)
+	n, _, err := remoteConn.ReadFrom(buffer)
+	assert.Nil(t, err)
+	m, err := stun.NewMessage(buffer[:n])
+	assert.Nil(t, err)
+	username, ok := m.GetOneAttribute(stun.AttrUsername)
+	if assert.True(t, ok) {
+		assert.Equal(t, "video:"+a.LocalUfrag, string(username.Value))
+	}
+
+	// Peer reflexive candidates inherit the credentials of the section
+	check, err := stun.Build(stun.ClassRequest, stun.MethodBinding, stun.GenerateTransactionId(),
+		&stun.Username{Username: a.LocalUfrag + ":video"},
+		&stun.Fingerprint{},
+	)
+	assert.Nil(t, err)
+	a.HandleInbound(check.Pack(), &stun.TransportAddr{IP: localAddr.IP, Port: localAddr.Port}, &net.UDPAddr{IP: net.ParseIP("192.0.2.1"), Port: 5000})
+	if prflx, ok := a.remoteCandidates["192.0.2.1:5000"]; assert.True(t, ok) {
+		ufrag, pwd := a.remoteCredentials(prflx)
+		assert.Equal(t, "video", ufrag)
+		assert.Equal(t, "videoPassword", pwd)
+	}
+}
+
+func TestAgent_SelectedCandidatePair(t *testing.T) {
+	local := &CandidateHost{
+		CandidateBase: CandidateBase{Protocol: ProtoTypeUDP, Address: "127.0.0.1", Port: 5000},
+	}
+	remote := &CandidateSrflx{
+		CandidateBase: CandidateBase{Protocol: ProtoTypeUDP, Address: "192.0.2.1", Port: 6000},
+	}
+
+	selected := make(chan [2]Candidate, 1)
+	a := NewAgent(nil)
+	a.SetSelectedPairNotifier(func(local, remote Candidate) {
+		selected <- [2]Candidate{local, remote}
+	})
+
+	l, r, rtt := a.SelectedCandidatePair()
+	assert.Nil(t, l)
+	assert.Nil(t, r)
+	assert.Equal(t, time.Duration(0), rtt)
+
+	a.setValidPair(local, remote, true)
+	select {
+	case pair := <-selected:
+		assert.Equal(t, local, pair[0])
+		assert.Equal(t, remote, pair[1])
+	case <-time.After(time.Second):
+		t.Fatal("selected pair not notified")
+	}
+
+	// The RTT is measured by the consent requests of the pair
+	a.consentRequests["transaction"] = time.Now().Add(-50 * time.Millisecond)
+	m, err := stun.Build(stun.ClassSuccessResponse, stun.MethodBinding, []byte("transaction"))
+	assert.Nil(t, err)
+	assert.True(t, a.handleConsentResponse(m, local, remote))
+
+	l, r, rtt = a.SelectedCandidatePair()
+	assert.Equal(t, local, l)
+	assert.Equal(t, remote, r)
+	assert.False(t, l == Candidate(local), "candidates are copied")
+	assert.True(t, rtt >= 50*time.Millisecond)
+}
+
+func TestAgent_Close(t *testing.T) {
+	a := NewAgent(nil)
+	a.Close()
+	a.Close()
+
+	select {
+	case <-a.closed:
+	default:
+		t.Fatal("closed channel of a closed agent is open")
+	}
+}
+
+func TestAgent_RemoteLite(t *testing.T) {
+	a := NewAgent(nil)
+	a.SetRemoteLite(true)
+	assert.Nil(t, a.Start(false, "remote", "password"))
+	a.RLock()
+	assert.True(t, a.isControlling, "the agent controls a lite agent")
+	a.RUnlock()
+	a.Close()
+
+	conn, err := net.ListenPacket("udp4", "127.0.0.1:0")
+	assert.Nil(t, err)
+	defer func() { assert.Nil(t, conn.Close()) }()
+	remoteConn, err := net.ListenPacket("udp4", "127.0.0.1:0")
+	assert.Nil(t, err)
+	defer func() { assert.Nil(t, remoteConn.Close()) }()
+
+	local := &CandidateHost{
+		CandidateBase: CandidateBase{
+			Protocol: ProtoTypeUDP,
+			Address:  "127.0.0.1",
+			Port:     conn.LocalAddr().(*net.UDPAddr).Port,
+			Conn:     conn,
+		},
+	}
+	remote := &CandidateHost{
+		CandidateBase: CandidateBase{
+			Protocol: ProtoTypeUDP,
+			Address:  "127.0.0.1",
+			Port:     remoteConn.LocalAddr().(*net.UDPAddr).Port,
+		},
+	}
+
+	a = NewAgent(nil)
+	a.remoteUfrag, a.remotePwd = "remote", "password"
+	a.isControlling, a.remoteLite = true, true
+
+	// The checks the lite agent receives, and whether they nominate
+	receive := func() (transactionID []byte, useCandidate bool) {
+		buf := make([]byte, 1500)
+		assert.Nil(t, remoteConn.SetReadDeadline(time.Now().Add(time.Second)))
+		n, _, err := remoteConn.ReadFrom(buf)
+		assert.Nil(t, err)
+		m, err := stun.NewMessage(buf[:n])
+		assert.Nil(t, err)
+		_, useCandidate = m.GetOneAttribute(stun.AttrUseCandidate)
+		return m.TransactionID, useCandidate
+	}
+	response := func(transactionID []byte) *stun.Message {
+		m, err := stun.Build(stun.ClassSuccessResponse, stun.MethodBinding, transactionID)
+		assert.Nil(t, err)
+		return m
+	}
+
+	// Regular nomination: the first check doesn't nominate the pair
+	a.pingCandidate(local, remote)
+	transactionID, useCandidate := receive()
+	assert.False(t, useCandidate)
+
+	// Once it is valid the pair is nominated, and selected when the
+	// nomination succeeds
+	a.handleInboundControlling(response(transactionID), local, remote)
+	assert.Nil(t, a.selectedPair.remote)
+	assert.Equal(t, []CandidatePair{newCandidatePair(local, remote)}, a.validPairs)
+
+	transactionID, useCandidate = receive()
+	assert.True(t, useCandidate)
+	a.handleInboundControlling(response(transactionID), local, remote)
+	assert.Equal(t, newCandidatePair(local, remote), a.selectedPair)
+	assert.Empty(t, a.nominationRequests)
+}
+
+func TestAgent_SetCheckInterval(t *testing.T) {
+	a := NewAgent(nil)
+	a.SetCheckInterval(100 * time.Millisecond)
+	assert.Equal(t, 100*time.Millisecond, a.checkInterval)
+
+	a.SetCheckInterval(0)
+	assert.Equal(t, defaultCheckInterval, a.checkInterval)
+
+	conn, err := net.ListenPacket("udp4", "127.0.0.1:0")
+	assert.Nil(t, err)
+	defer func() { assert.Nil(t, conn.Close()) }()
+	local := &CandidateHost{
+		CandidateBase: CandidateBase{
+			Protocol: ProtoTypeUDP,
+			Address:  "127.0.0.1",
+			Port:     conn.LocalAddr().(*net.UDPAddr).Port,
+			Conn:     conn,
+		},
+	}
+	remote := &CandidateHost{
+		CandidateBase: CandidateBase{Protocol: ProtoTypeUDP, Address: "127.0.0.1", Port: 9},
+	}
+	a.remoteUfrag, a.remotePwd = "remote", "password"
+	a.setValidPair(local, remote, true)
+
+	// Consent is requested twice within short disconnected timeouts
+	a.SetDisconnectedTimeout(time.Second)
+	a.checkConsent()
+	assert.True(t, a.consentInterval <= 600*time.Millisecond)
+
+	a.SetDisconnectedTimeout(defaultDisconnectedTimeout)
+	a.consentSent = time.Time{}
+	a.checkConsent()
+	assert.True(t, a.consentInterval >= 4*time.Second)
+}
+
+func TestAgent_RemoteLiteNominationFails(t *testing.T) {
+	conn, err := net.ListenPacket("udp4", "127.0.0.1:0")
+	assert.Nil(t, err)
+	defer func() { assert.Nil(t, conn.Close()) }()
+
+	local := &CandidateHost{
+		CandidateBase: CandidateBase{
+			Protocol: ProtoTypeUDP,
+			Address:  "127.0.0.1",
+			Port:     conn.LocalAddr().(*net.UDPAddr).Port,
+			Conn:     conn,
+		},
+	}
+	remote := &CandidateHost{
+		CandidateBase: CandidateBase{
+			Protocol: ProtoTypeUDP,
+			Address:  "127.0.0.1",
+			Port:     9,
+		},
+	}
+	// The server reflexive candidate makes a pair of a lower priority
+	lower := &CandidateSrflx{
+		CandidateBase: CandidateBase{
+			Protocol: ProtoTypeUDP,
+			Address:  "127.0.0.1",
+			Port:     10,
+		},
+	}
+
+	a := NewAgent(nil)
+	a.remoteUfrag, a.remotePwd = "remote", "password"
+	a.isControlling, a.remoteLite = true, true
+	a.connectionTimeout = 0
+
+	response := func(transactionID []byte) *stun.Message {
+		m, err := stun.Build(stun.ClassSuccessResponse, stun.MethodBinding, transactionID)
+		assert.Nil(t, err)
+		return m
+	}
+
+	a.handleInboundControlling(response(a.pingCandidate(local, lower)), local, lower)
+	assert.Equal(t, newCandidatePair(local, lower), a.nominatedPairs[ComponentRTP])
+
+	// A valid pair of a higher priority is nominated instead, a response to
+	// the nomination of the other pair doesn't select it then
+	var lowerNomination string
+	for transactionID := range a.nominationRequests {
+		lowerNomination = transactionID
+	}
+	a.handleInboundControlling(response(a.pingCandidate(local, remote)), local, remote)
+	assert.Equal(t, newCandidatePair(local, remote), a.nominatedPairs[ComponentRTP])
+	a.handleInboundControlling(response([]byte(lowerNomination)), local, lower)
+	assert.Nil(t, a.selectedPair.remote)
+
+	// The nomination fails once its checks are lost, the next valid pair is
+	// nominated then
+	a.expireCheckRequests()
+	assert.Empty(t, a.nominatedPairs)
+	assert.Empty(t, a.nominationRequests)
+
+	a.handleInboundControlling(response(a.pingCandidate(local, lower)), local, lower)
+	assert.Equal(t, newCandidatePair(local, lower), a.nominatedPairs[ComponentRTP])
+
+	// The agent nominates aggressively again once the remote agent isn't
+	// lite
+	a.SetRemoteLite(false)
+	assert.Empty(t, a.nominatedPairs)
+	assert.Empty(t, a.nominationRequests)
+	assert.True(t, a.nominates(local, remote))
+}
@@ -2,17 +2,29 @@ package ice
 
 import (
 	"fmt"
-	"math/rand"
 	"net"
 	"time"
-
-	"golang.org/x/net/ipv4"
 )
 
 // Preference enums when generate Priority
 const (
 	HostCandidatePreference  uint16 = 126
+	PrflxCandidatePreference uint16 = 110
 	SrflxCandidatePreference uint16 = 100
+	RelayCandidatePreference uint16 = 0
+
+	// DefaultLocalPreference is the local preference of candidates which
+	// don't set one, the highest possible
+	DefaultLocalPreference uint16 = 65535
+)
+
+// The components of a media stream, RFC 8445 Section 4.1.1.1
+const (
+	// ComponentRTP carries RTP, and RTCP multiplexed with it
+	ComponentRTP uint16 = 1
+
+	// ComponentRTCP carries RTCP for peers which don't multiplex it with RTP
+	ComponentRTCP uint16 = 2
 )
 
 // Candidate represents an ICE candidate
@@ -24,12 +36,38 @@ type Candidate interface {
 // CandidateBase represents an ICE candidate, a base with enough attributes
 // for host candidates, see CandidateSrflx and CandidateRelay for more
 type CandidateBase struct {
-	Protocol     ProtoType
-	Address      string
-	Port         int
+	Protocol ProtoType
+	Address  string
+	Port     int
+
+	// LocalPreference orders local candidates of the same type, zero uses
+	// DefaultLocalPreference. Dual-stack hosts interleave the preferences
+	// of their IPv6 and IPv4 addresses, RFC 8421 Section 4.
+	LocalPreference uint16
+
+	// SignaledPriority is the priority the candidate is signaled with, the
+	// agent sets it from its PriorityPolicy when a local candidate is added.
+	// For remote candidates it is the priority the remote peer signaled,
+	// zero if unknown, see CandidatePriority.
+	SignaledPriority uint32
+
+	// TCPType is the connection role of candidates with ProtoTypeTCP
+	TCPType TCPType
+
+	// Component is the component of the media stream the candidate is for,
+	// zero is ComponentRTP
+	Component uint16
+
+	// Ufrag and Pwd are the ICE credentials of the media section a remote
+	// candidate was signaled in, remote peers which don't bundle their media
+	// use distinct ones per section. Empty ones fall back to the credentials
+	// the agent was started with.
+	Ufrag string
+	Pwd   string
+
 	LastSent     time.Time
 	LastReceived time.Time
-	Conn         *ipv4.PacketConn // TODO: make private
+	Conn         net.PacketConn // TODO: make private
 }
 
 func (c *CandidateBase) addr() net.Addr {
@@ -40,7 +78,7 @@ func (c *CandidateBase) addr() net.Addr {
 }
 
 func (c *CandidateBase) sendTo(raw []byte, dst *CandidateBase) error {
-	if _, err := c.Conn.WriteTo(raw, nil, dst.addr()); err != nil {
+	if _, err := c.Conn.WriteTo(raw, dst.addr()); err != nil {
 		return fmt.Errorf("failed to send packet: %v", err)
 	}
 	c.seen(true)
@@ -56,11 +94,42 @@ func (c *CandidateBase) seen(outbound bool) {
 }
 
 // Priority computes the priority for this ICE Candidate
-func (c *CandidateBase) Priority(typePreference uint16, component uint16) uint16 {
-	localPreference := uint16(rand.New(rand.NewSource(time.Now().UnixNano())).Uint32() / 2)
-	return (2^24)*typePreference +
-		(2^8)*localPreference +
-		(2^0)*(256-component)
+// https://tools.ietf.org/html/rfc8445#section-5.1.2.1
+func (c *CandidateBase) Priority(typePreference uint16, component uint16) uint32 {
+	localPreference := c.LocalPreference
+	if localPreference == 0 {
+		localPreference = DefaultLocalPreference
+	}
+	return DefaultPriorityFormula(typePreference, localPreference, component)
+}
+
+// GetComponent returns the component of the media stream the candidate is
+// for
+func (c *CandidateBase) GetComponent() uint16 {
+	if c.Component == 0 {
+		return ComponentRTP
+	}
+	return c.Component
+}
+
+// canPair reports whether a connectivity check can be sent from the local
+// candidate to the remote one, only candidates of the same component are
+// paired. TCP checks are only sent from active to passive candidates, RFC
+// 6544 Section 6.2.
+func (c *CandidateBase) canPair(remote *CandidateBase) bool {
+	if c.Protocol != remote.Protocol || c.IsIPv6() != remote.IsIPv6() || c.GetComponent() != remote.GetComponent() {
+		return false
+	}
+	if c.Protocol == ProtoTypeTCP {
+		return c.TCPType == TCPTypeActive && remote.TCPType == TCPTypePassive
+	}
+	return true
+}
+
+// IsIPv6 reports whether the address of the candidate is an IPv6 address
+func (c *CandidateBase) IsIPv6() bool {
+	ip := net.ParseIP(c.Address)
+	return ip != nil && ip.To4() == nil
 }
 
 // CandidateHost is a Candidate of typ Host
@@ -104,3 +173,61 @@ func (c *CandidateSrflx) GetBase() *CandidateBase {
 func (c *CandidateSrflx) String() string {
 	return fmt.Sprintf("%s:%d", c.RemoteAddress, c.RemotePort)
 }
+
+// CandidateRelay is a Candidate of typ Relay, the relayed transport address
+// allocated on a TURN server, which is its base. The related address is the
+// server reflexive address the server saw the allocation requests from.
+// https://tools.ietf.org/html/rfc8445#section-5.1.1.2
+type CandidateRelay struct {
+	CandidateBase
+	RelatedAddress string
+	RelatedPort    int
+}
+
+// GetBase returns the CandidateBase, attributes shared between all Candidates
+func (c *CandidateRelay) GetBase() *CandidateBase {
+	return &c.CandidateBase
+}
+
+// String makes the CandidateRelay printable
+func (c *CandidateRelay) String() string {
+	return fmt.Sprintf("%s:%d", c.CandidateBase.Address, c.CandidateBase.Port)
+}
+
+// CandidatePeerReflexive is a Candidate of typ Peer-Reflexive, learned from
+// the source address of a connectivity check of the remote peer
+// https://tools.ietf.org/html/rfc8445#section-7.3.1.3
+type CandidatePeerReflexive struct {
+	CandidateBase
+}
+
+// GetBase returns the CandidateBase, attributes shared between all Candidates
+func (c *CandidatePeerReflexive) GetBase() *CandidateBase {
+	return &c.CandidateBase
+}
+
+// String makes the CandidatePeerReflexive printable
+func (c *CandidatePeerReflexive) String() string {
+	return fmt.Sprintf("%s:%d", c.CandidateBase.Address, c.CandidateBase.Port)
+}
+
+// copyCandidate returns a copy of the candidate, which the agent doesn't
+// modify once handed out
+func copyCandidate(c Candidate) Candidate {
+	switch c := c.(type) {
+	case *CandidateHost:
+		copied := *c
+		return &copied
+	case *CandidateSrflx:
+		copied := *c
+		return &copied
+	case *CandidatePeerReflexive:
+		copied := *c
+		return &copied
+	case *CandidateRelay:
+		copied := *c
+		return &copied
+	default:
+		return c
+	}
+}
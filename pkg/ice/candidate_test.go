@@ -0,0 +1,70 @@
+package ice
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCandidateBase_Priority(t *testing.T) {
+	testCases := []struct {
+		candidate        CandidateBase
+		typePreference   uint16
+		component        uint16
+		expectedPriority uint32
+	}{
+		{CandidateBase{}, HostCandidatePreference, 1, 2130706431},
+		{CandidateBase{LocalPreference: 65534}, HostCandidatePreference, 1, 2130706175},
+		{CandidateBase{}, SrflxCandidatePreference, 2, 1694498814},
+	}
+
+	for i, testCase := range testCases {
+		assert.Equal(t,
+			testCase.expectedPriority,
+			testCase.candidate.Priority(testCase.typePreference, testCase.component),
+			"testCase: %d %v", i, testCase,
+		)
+	}
+}
+
+func TestCandidateBase_IsIPv6(t *testing.T) {
+	assert.True(t, (&CandidateBase{Address: "2001:db8::1"}).IsIPv6())
+	assert.False(t, (&CandidateBase{Address: "192.0.2.1"}).IsIPv6())
+	assert.False(t, (&CandidateBase{Address: "::ffff:192.0.2.1"}).IsIPv6())
+	assert.False(t, (&CandidateBase{Address: "pion.local"}).IsIPv6())
+}
+
+func TestCandidateBase_canPair(t *testing.T) {
+	udp := CandidateBase{Protocol: ProtoTypeUDP, Address: "192.0.2.1"}
+	udp6 := CandidateBase{Protocol: ProtoTypeUDP, Address: "2001:db8::1"}
+	active := CandidateBase{Protocol: ProtoTypeTCP, TCPType: TCPTypeActive, Address: "192.0.2.1"}
+	passive := CandidateBase{Protocol: ProtoTypeTCP, TCPType: TCPTypePassive, Address: "192.0.2.1"}
+	rtp := CandidateBase{Protocol: ProtoTypeUDP, Address: "192.0.2.1", Component: ComponentRTP}
+	rtcp := CandidateBase{Protocol: ProtoTypeUDP, Address: "192.0.2.1", Component: ComponentRTCP}
+
+	testCases := []struct {
+		local, remote CandidateBase
+		expected      bool
+	}{
+		{udp, udp, true},
+		{udp, udp6, false},
+		{udp, passive, false},
+		{active, udp, false},
+		{active, passive, true},
+		{passive, active, false},
+		{active, active, false},
+		{passive, passive, false},
+		{udp, rtp, true},
+		{rtcp, rtcp, true},
+		{udp, rtcp, false},
+		{rtcp, rtp, false},
+	}
+
+	for i, testCase := range testCases {
+		assert.Equal(t,
+			testCase.expected,
+			testCase.local.canPair(&testCase.remote),
+			"testCase: %d %v", i, testCase,
+		)
+	}
+}
@@ -25,4 +25,24 @@ var (
 
 	// ErrProtoType indicates an unsupported transport type was provided.
 	ErrProtoType = errors.New("invalid transport protocol type")
+
+	// ErrNoRemoteCandidates indicates the remote peer provided no candidate
+	// before the candidate timeout expired.
+	ErrNoRemoteCandidates = errors.New("no remote candidates received")
+
+	// ErrConsentExpired indicates the remote peer stopped answering the
+	// consent requests on the selected pair, RFC 7675.
+	ErrConsentExpired = errors.New("consent to send expired")
+
+	// ErrMulticastDNSNotResolved indicates the .local hostname of a remote
+	// candidate wasn't resolved before the mDNS timeout expired.
+	ErrMulticastDNSNotResolved = errors.New("mDNS hostname not resolved")
+
+	// ErrMulticastDNSNoInterface indicates no interface was able to send
+	// the mDNS query.
+	ErrMulticastDNSNoInterface = errors.New("no interface to send mDNS queries")
+
+	// ErrMulticastDNSDisabled indicates a remote candidate with a .local
+	// hostname was discarded since mDNS resolution is disabled.
+	ErrMulticastDNSDisabled = errors.New("mDNS resolution is disabled")
 )
@@ -0,0 +1,157 @@
+package ice
+
+import (
+	"context"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"golang.org/x/net/dns/dnsmessage"
+	"golang.org/x/net/ipv4"
+)
+
+const (
+	// mDNSAddress is the IPv4 multicast group of mDNS, RFC 6762 section 3
+	mDNSAddress = "224.0.0.251:5353"
+
+	// defaultMulticastDNSTimeout is how long a .local hostname of a remote
+	// candidate is queried before the candidate is discarded
+	defaultMulticastDNSTimeout = 5 * time.Second
+)
+
+// MulticastDNSResolver resolves the .local hostnames remote peers use in
+// place of the addresses of their host candidates
+// https://tools.ietf.org/html/draft-ietf-rtcweb-mdns-ice-candidates-02
+type MulticastDNSResolver interface {
+	Resolve(ctx context.Context, host string) (net.IP, error)
+}
+
+func isMulticastDNSHost(host string) bool {
+	return strings.HasSuffix(strings.ToLower(strings.TrimSuffix(host, ".")), ".local")
+}
+
+type multicastDNSResolver struct {
+	interfaces []net.Interface
+}
+
+// NewMulticastDNSResolver creates a resolver sending one-shot queries on the
+// given interfaces, or on every multicast capable interface if none are given
+func NewMulticastDNSResolver(interfaces ...net.Interface) MulticastDNSResolver {
+	return &multicastDNSResolver{interfaces: interfaces}
+}
+
+// Resolve queries the A record of the host until it is answered, or the
+// context is done
+func (r *multicastDNSResolver) Resolve(ctx context.Context, host string) (net.IP, error) {
+	name, err := dnsmessage.NewName(strings.TrimSuffix(host, ".") + ".")
+	if err != nil {
+		return nil, errors.Wrapf(ErrHost, "%s: %v", host, err)
+	}
+
+	query, err := (&dnsmessage.Message{
+		Questions: []dnsmessage.Question{{
+			Name:  name,
+			Type:  dnsmessage.TypeA,
+			Class: dnsmessage.ClassINET,
+		}},
+	}).Pack()
+	if err != nil {
+		return nil, err
+	}
+
+	interfaces := r.interfaces
+	if len(interfaces) == 0 {
+		if interfaces, err = net.Interfaces(); err != nil {
+			return nil, err
+		}
+	}
+
+	// A query from an ephemeral port is answered with unicast responses,
+	// RFC 6762 section 5.1
+	conn, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4zero})
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = conn.Close()
+	}()
+
+	dst, err := net.ResolveUDPAddr("udp4", mDNSAddress)
+	if err != nil {
+		return nil, err
+	}
+
+	p := ipv4.NewPacketConn(conn)
+	sent := false
+	for i := range interfaces {
+		iface := interfaces[i]
+		if iface.Flags&net.FlagUp == 0 || iface.Flags&net.FlagMulticast == 0 {
+			continue
+		}
+		if err := p.SetMulticastInterface(&iface); err != nil {
+			continue
+		}
+		if _, err := conn.WriteTo(query, dst); err == nil {
+			sent = true
+		}
+	}
+	if !sent {
+		return nil, ErrMulticastDNSNoInterface
+	}
+
+	// Unblock the read below as soon as the context is done
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			_ = conn.SetReadDeadline(time.Now())
+		case <-done:
+		}
+	}()
+
+	buf := make([]byte, 1500)
+	for {
+		n, _, err := conn.ReadFrom(buf)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, errors.Wrapf(ErrMulticastDNSNotResolved, "%s: %v", host, ctx.Err())
+			}
+			return nil, err
+		}
+
+		if ip := parseMulticastDNSAnswer(buf[:n], name); ip != nil {
+			return ip, nil
+		}
+	}
+}
+
+func parseMulticastDNSAnswer(raw []byte, name dnsmessage.Name) net.IP {
+	var p dnsmessage.Parser
+	if _, err := p.Start(raw); err != nil {
+		return nil
+	}
+	if err := p.SkipAllQuestions(); err != nil {
+		return nil
+	}
+
+	for {
+		header, err := p.AnswerHeader()
+		if err != nil {
+			return nil
+		}
+		if header.Type != dnsmessage.TypeA || !strings.EqualFold(header.Name.String(), name.String()) {
+			if err := p.SkipAnswer(); err != nil {
+				return nil
+			}
+			continue
+		}
+
+		a, err := p.AResource()
+		if err != nil {
+			return nil
+		}
+		return net.IP(a.A[:])
+	}
+}
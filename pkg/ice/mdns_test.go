@@ -0,0 +1,76 @@
+package ice
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+type testResolver map[string]net.IP
+
+func (r testResolver) Resolve(ctx context.Context, host string) (net.IP, error) {
+	if ip, ok := r[host]; ok {
+		return ip, nil
+	}
+	<-ctx.Done()
+	return nil, ErrMulticastDNSNotResolved
+}
+
+func TestAgent_MulticastDNS(t *testing.T) {
+	newCandidate := func(address string) Candidate {
+		return &CandidateHost{
+			CandidateBase: CandidateBase{
+				Protocol: ProtoTypeUDP,
+				Address:  address,
+				Port:     5000,
+			},
+		}
+	}
+
+	a := NewAgent(nil)
+	a.SetMulticastDNSResolver(testResolver{"peer.local": net.ParseIP("192.168.0.2")})
+	a.SetMulticastDNSTimeout(10 * time.Millisecond)
+
+	a.resolveRemoteCandidate(newCandidate("unknown.local"))
+	assert.Equal(t, 0, len(a.remoteCandidates))
+
+	a.resolveRemoteCandidate(newCandidate("peer.local"))
+	assert.Equal(t, 1, len(a.remoteCandidates))
+	for _, c := range a.remoteCandidates {
+		assert.Equal(t, "192.168.0.2", c.GetBase().Address)
+	}
+
+	a = NewAgent(nil)
+	a.SetMulticastDNSResolver(nil)
+	a.resolveRemoteCandidate(newCandidate("peer.local"))
+	assert.Equal(t, 0, len(a.remoteCandidates))
+}
+
+func TestParseMulticastDNSAnswer(t *testing.T) {
+	name := dnsmessage.MustNewName("peer.local.")
+	raw, err := (&dnsmessage.Message{
+		Header: dnsmessage.Header{Response: true, Authoritative: true},
+		Answers: []dnsmessage.Resource{
+			{
+				Header: dnsmessage.ResourceHeader{Name: dnsmessage.MustNewName("other.local."), Type: dnsmessage.TypeA, Class: dnsmessage.ClassINET},
+				Body:   &dnsmessage.AResource{A: [4]byte{10, 0, 0, 1}},
+			},
+			{
+				Header: dnsmessage.ResourceHeader{Name: name, Type: dnsmessage.TypeA, Class: dnsmessage.ClassINET},
+				Body:   &dnsmessage.AResource{A: [4]byte{192, 168, 0, 2}},
+			},
+		},
+	}).Pack()
+	assert.Nil(t, err)
+
+	assert.Equal(t, "192.168.0.2", parseMulticastDNSAnswer(raw, name).String())
+	assert.Nil(t, parseMulticastDNSAnswer(raw, dnsmessage.MustNewName("missing.local.")))
+	assert.Nil(t, parseMulticastDNSAnswer([]byte{0x00}, name))
+
+	assert.True(t, isMulticastDNSHost("peer.LOCAL."))
+	assert.False(t, isMulticastDNSHost("192.168.0.2"))
+}
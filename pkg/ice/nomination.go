@@ -0,0 +1,98 @@
+package ice
+
+// SetRemoteLite sets whether the remote agent is a lite implementation, as
+// announced by a=ice-lite. Lite agents never check connectivity, so the agent
+// is controlling whichever peer offered. It nominates with regular
+// nomination then: a lite agent selects every pair it sees USE-CANDIDATE on,
+// only the valid pair of the highest priority is nominated.
+// https://tools.ietf.org/html/rfc8445#section-6.1.1
+func (a *Agent) SetRemoteLite(lite bool) {
+	a.Lock()
+	defer a.Unlock()
+
+	a.remoteLite = lite
+	if !lite {
+		a.nominatedPairs = make(map[uint16]CandidatePair)
+		a.nominationRequests = make(map[string]CandidatePair)
+	}
+}
+
+// nominates reports whether checks of the pair carry USE-CANDIDATE. With
+// aggressive nomination the controlling agent nominates every pair it
+// checks, with regular nomination only the one picked for its component.
+// Note: the caller should hold the agent lock.
+func (a *Agent) nominates(local, remote Candidate) bool {
+	if !a.isControlling {
+		return false
+	} else if !a.remoteLite {
+		return true
+	}
+
+	nominated, ok := a.nominatedPairs[local.GetBase().GetComponent()]
+	return ok && nominated == newCandidatePair(local, remote)
+}
+
+// nominate picks the valid pair for its component unless one of a higher
+// priority is picked already, and nominates it by repeating the check with
+// USE-CANDIDATE
+// Note: the caller should hold the agent lock.
+func (a *Agent) nominate(local, remote Candidate) {
+	component := local.GetBase().GetComponent()
+	if a.componentSelected(component) {
+		return
+	}
+
+	p := newCandidatePair(local, remote)
+	if nominated, ok := a.nominatedPairs[component]; ok && a.pairPriority(nominated) >= a.pairPriority(p) {
+		return
+	}
+	a.nominatedPairs[component] = p
+	a.pingCandidate(local, remote)
+}
+
+// handleNominationResponse reports whether the success response answers a
+// check which nominated the pair picked for its component, which selects
+// the pair
+// Note: the caller should hold the agent lock.
+func (a *Agent) handleNominationResponse(transactionID string) bool {
+	p, ok := a.nominationRequests[transactionID]
+	if !ok {
+		return false
+	}
+	delete(a.nominationRequests, transactionID)
+
+	component := p.local.GetBase().GetComponent()
+	if a.nominatedPairs[component] != p {
+		return false
+	}
+
+	// The checks still nominating the pair are answered by the selection
+	for id, request := range a.nominationRequests {
+		if request.local.GetBase().GetComponent() == component {
+			delete(a.nominationRequests, id)
+		}
+	}
+	return true
+}
+
+// expireNominationRequest forgets the check nominating a pair which wasn't
+// answered. The nomination failed once none of the checks of the pair is
+// pending, another valid pair is picked then.
+// Note: the caller should hold the agent lock.
+func (a *Agent) expireNominationRequest(transactionID string) {
+	p, ok := a.nominationRequests[transactionID]
+	if !ok {
+		return
+	}
+	delete(a.nominationRequests, transactionID)
+
+	for _, request := range a.nominationRequests {
+		if request == p {
+			return
+		}
+	}
+	component := p.local.GetBase().GetComponent()
+	if a.nominatedPairs[component] == p {
+		delete(a.nominatedPairs, component)
+	}
+}
@@ -0,0 +1,122 @@
+package ice
+
+// TypePreferences are the preferences of the candidate types in the
+// priorities of candidates, from 0 to 126 with 126 preferred the most
+// https://tools.ietf.org/html/rfc8445#section-5.1.2.2
+type TypePreferences struct {
+	Host            uint16
+	PeerReflexive   uint16
+	ServerReflexive uint16
+	Relay           uint16
+}
+
+// DefaultTypePreferences prefer direct connections, as RFC 8445 recommends
+var DefaultTypePreferences = TypePreferences{
+	Host:            HostCandidatePreference,
+	PeerReflexive:   PrflxCandidatePreference,
+	ServerReflexive: SrflxCandidatePreference,
+	Relay:           RelayCandidatePreference,
+}
+
+// PriorityFormula combines the type preference, the local preference and the
+// component of a candidate into its priority
+type PriorityFormula func(typePreference, localPreference, component uint16) uint32
+
+// DefaultPriorityFormula is the formula of RFC 8445, the type preference
+// outweighs the local preference which outweighs the component
+// https://tools.ietf.org/html/rfc8445#section-5.1.2.1
+func DefaultPriorityFormula(typePreference, localPreference, component uint16) uint32 {
+	return (1<<24)*uint32(typePreference) +
+		(1<<8)*uint32(localPreference) +
+		(1<<0)*uint32(256-component)
+}
+
+// PriorityPolicy computes the priorities of the local candidates, which
+// order the checks of the candidate pairs of both peers. Its zero value
+// follows RFC 8445.
+type PriorityPolicy struct {
+	// TypePreferences are the preferences of the candidate types,
+	// DefaultTypePreferences if nil
+	TypePreferences *TypePreferences
+
+	// LocalPreference returns the local preference of the candidate from
+	// the one it was gathered with, to rank the interfaces of the host
+	// otherwise. It is left as is if nil.
+	LocalPreference func(c Candidate, preference uint16) uint16
+
+	// Formula computes the priorities, DefaultPriorityFormula if nil
+	Formula PriorityFormula
+}
+
+// Priority returns the priority of the local candidate
+func (p *PriorityPolicy) Priority(c Candidate) uint32 {
+	preferences := p.typePreferences()
+	typePreference := preferences.Host
+	switch c.(type) {
+	case *CandidateSrflx:
+		typePreference = preferences.ServerReflexive
+	case *CandidatePeerReflexive:
+		typePreference = preferences.PeerReflexive
+	case *CandidateRelay:
+		typePreference = preferences.Relay
+	}
+	return p.priority(typePreference, c)
+}
+
+// peerReflexivePriority returns the priority the remote peer gives the peer
+// reflexive candidate it learns from the checks of the local candidate, the
+// PRIORITY of the checks, RFC 8445 Section 7.1.1
+func (p *PriorityPolicy) peerReflexivePriority(c Candidate) uint32 {
+	return p.priority(p.typePreferences().PeerReflexive, c)
+}
+
+func (p *PriorityPolicy) priority(typePreference uint16, c Candidate) uint32 {
+	base := c.GetBase()
+	localPreference := base.LocalPreference
+	if localPreference == 0 {
+		localPreference = DefaultLocalPreference
+	}
+	if p.LocalPreference != nil {
+		localPreference = p.LocalPreference(c, localPreference)
+	}
+
+	formula := p.Formula
+	if formula == nil {
+		formula = DefaultPriorityFormula
+	}
+	return formula(typePreference, localPreference, base.GetComponent())
+}
+
+func (p *PriorityPolicy) typePreferences() TypePreferences {
+	if p.TypePreferences == nil {
+		return DefaultTypePreferences
+	}
+	return *p.TypePreferences
+}
+
+// CandidatePriority returns the priority the candidate is signaled with, the
+// one of the default PriorityPolicy for candidates without a
+// SignaledPriority
+func CandidatePriority(c Candidate) uint32 {
+	if priority := c.GetBase().SignaledPriority; priority != 0 {
+		return priority
+	}
+	return (&PriorityPolicy{}).Priority(c)
+}
+
+// pairPriority returns the priority of the candidate pair given the
+// priorities of the candidates of the controlling and the controlled agent,
+// pairs are checked in its descending order
+// https://tools.ietf.org/html/rfc8445#section-6.1.2.3
+func pairPriority(controlling, controlled uint32) uint64 {
+	g, d := uint64(controlling), uint64(controlled)
+	min, max := g, d
+	if min > max {
+		min, max = max, min
+	}
+	priority := (1<<32)*min + 2*max
+	if g > d {
+		priority++
+	}
+	return priority
+}
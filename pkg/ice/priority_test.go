@@ -0,0 +1,81 @@
+package ice
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPriorityPolicy_Priority(t *testing.T) {
+	host := &CandidateHost{CandidateBase: CandidateBase{Address: "10.8.0.2"}}
+	srflx := &CandidateSrflx{CandidateBase: CandidateBase{LocalPreference: 65534}}
+	relay := &CandidateRelay{CandidateBase: CandidateBase{Address: "203.0.113.1"}}
+
+	testCases := []struct {
+		policy           PriorityPolicy
+		candidate        Candidate
+		expectedPriority uint32
+	}{
+		{PriorityPolicy{}, host, 2130706431},
+		{PriorityPolicy{}, srflx, 1694498559},
+		{PriorityPolicy{TypePreferences: &TypePreferences{Host: 0, ServerReflexive: 126}}, srflx, 2130706175},
+		{PriorityPolicy{}, relay, 16777215},
+		{PriorityPolicy{TypePreferences: &TypePreferences{Relay: 10}}, relay, 184549375},
+		{PriorityPolicy{LocalPreference: func(c Candidate, preference uint16) uint16 {
+			if c.GetBase().Address == "10.8.0.2" {
+				return preference / 2
+			}
+			return preference
+		}}, host, 2122317823},
+		{PriorityPolicy{Formula: func(typePreference, localPreference, component uint16) uint32 {
+			return uint32(typePreference)
+		}}, srflx, uint32(SrflxCandidatePreference)},
+	}
+
+	for i, testCase := range testCases {
+		assert.Equal(t,
+			testCase.expectedPriority,
+			testCase.policy.Priority(testCase.candidate),
+			"testCase: %d", i,
+		)
+	}
+}
+
+func TestCandidatePriority(t *testing.T) {
+	assert.Equal(t, uint32(2130706431), CandidatePriority(&CandidateHost{}))
+	assert.Equal(t, uint32(5), CandidatePriority(&CandidateHost{CandidateBase: CandidateBase{SignaledPriority: 5}}))
+}
+
+func TestPairPriority(t *testing.T) {
+	testCases := []struct {
+		controlling, controlled uint32
+		expectedPriority        uint64
+	}{
+		{1, 2, 1<<32 + 4},
+		{2, 1, 1<<32 + 5},
+		{3, 3, 3<<32 + 6},
+	}
+
+	for i, testCase := range testCases {
+		assert.Equal(t,
+			testCase.expectedPriority,
+			pairPriority(testCase.controlling, testCase.controlled),
+			"testCase: %d", i,
+		)
+	}
+}
+
+func TestAgent_sortPairs(t *testing.T) {
+	preferred := &CandidateSrflx{CandidateBase: CandidateBase{Address: "192.0.2.1"}}
+	other := &CandidateHost{CandidateBase: CandidateBase{Address: "10.0.0.1"}}
+	remote := &CandidateHost{CandidateBase: CandidateBase{Address: "10.0.0.2", SignaledPriority: 1000}}
+
+	a := NewAgent(nil)
+	a.SetPriorityPolicy(PriorityPolicy{TypePreferences: &TypePreferences{Host: 100, ServerReflexive: 126}})
+	a.AddLocalCandidate(other)
+	a.AddLocalCandidate(preferred)
+
+	pairs := []CandidatePair{newCandidatePair(other, remote), newCandidatePair(preferred, remote)}
+	a.sortPairs(pairs)
+	assert.Equal(t, []CandidatePair{newCandidatePair(preferred, remote), newCandidatePair(other, remote)}, pairs)
+}
@@ -0,0 +1,148 @@
+package ice
+
+import (
+	"time"
+
+	"github.com/pions/pkg/stun"
+)
+
+// CandidatePairState is the state of the connectivity checks of a candidate
+// pair
+// https://www.w3.org/TR/webrtc-stats/#dom-rtcstatsicecandidatepairstate
+type CandidatePairState int
+
+const (
+	// CandidatePairStateInProgress indicates a check of the pair was sent
+	// but none succeeded yet
+	CandidatePairStateInProgress CandidatePairState = iota + 1
+
+	// CandidatePairStateSucceeded indicates a check of the pair succeeded
+	CandidatePairStateSucceeded
+
+	// CandidatePairStateFailed indicates the pair was selected and the
+	// remote peer stopped granting consent to send on it
+	CandidatePairStateFailed
+)
+
+func (s CandidatePairState) String() string {
+	switch s {
+	case CandidatePairStateInProgress:
+		return "in-progress"
+	case CandidatePairStateSucceeded:
+		return "succeeded"
+	case CandidatePairStateFailed:
+		return "failed"
+	default:
+		return ErrUnknownType.Error()
+	}
+}
+
+// CandidatePairStats are the statistics of the connectivity checks of a
+// candidate pair, consent requests and their responses included
+// https://www.w3.org/TR/webrtc-stats/#candidatepair-dict*
+type CandidatePairStats struct {
+	Local  Candidate
+	Remote Candidate
+	State  CandidatePairState
+
+	// Nominated is set once the pair has been selected, it stays set after
+	// another pair is selected
+	Nominated bool
+
+	RequestsSent      uint64
+	RequestsReceived  uint64
+	ResponsesSent     uint64
+	ResponsesReceived uint64
+
+	// CurrentRoundTripTime is the round trip time of the last check answered
+	// on the pair, TotalRoundTripTime the sum of those of every answered
+	// check, divide it by ResponsesReceived for the average
+	CurrentRoundTripTime time.Duration
+	TotalRoundTripTime   time.Duration
+}
+
+// CandidatePairStats returns the stats of every pair a check was sent or
+// received on, with copies of their candidates
+func (a *Agent) CandidatePairStats() []CandidatePairStats {
+	a.RLock()
+	defer a.RUnlock()
+
+	stats := make([]CandidatePairStats, 0, len(a.pairStats))
+	for _, s := range a.pairStats {
+		copied := *s
+		copied.Local = copyCandidate(s.Local)
+		copied.Remote = copyCandidate(s.Remote)
+		stats = append(stats, copied)
+	}
+	return stats
+}
+
+// candidatePairStats returns the stats of the pair, creating them on first
+// use
+// Note: the caller should hold the agent lock.
+func (a *Agent) candidatePairStats(local, remote Candidate) *CandidatePairStats {
+	p := newCandidatePair(local, remote)
+	stats, ok := a.pairStats[p]
+	if !ok {
+		stats = &CandidatePairStats{Local: local, Remote: remote, State: CandidatePairStateInProgress}
+		a.pairStats[p] = stats
+	}
+	return stats
+}
+
+// removePairStats forgets the stats of the pairs the candidates of which
+// were removed
+// Note: the caller should hold the agent lock.
+func (a *Agent) removePairStats(removed func(p CandidatePair) bool) {
+	for p := range a.pairStats {
+		if removed(p) {
+			delete(a.pairStats, p)
+		}
+	}
+}
+
+// recordCheckRequest counts a check sent on the pair, its response times the
+// round trip
+// Note: the caller should hold the agent lock.
+func (a *Agent) recordCheckRequest(transactionID []byte, local, remote Candidate) {
+	a.candidatePairStats(local, remote).RequestsSent++
+	a.checkRequests[string(transactionID)] = time.Now()
+}
+
+// recordInbound counts the check or response received on the pair, a
+// success response times the round trip of its request and marks the pair
+// succeeded
+// Note: the caller should hold the agent lock.
+func (a *Agent) recordInbound(m *stun.Message, local, remote Candidate) {
+	if m.Method != stun.MethodBinding {
+		return
+	}
+
+	switch m.Class {
+	case stun.ClassRequest:
+		a.candidatePairStats(local, remote).RequestsReceived++
+	case stun.ClassSuccessResponse:
+		stats := a.candidatePairStats(local, remote)
+		stats.ResponsesReceived++
+		stats.State = CandidatePairStateSucceeded
+
+		transactionID := string(m.TransactionID)
+		if sent, ok := a.checkRequests[transactionID]; ok {
+			delete(a.checkRequests, transactionID)
+			stats.CurrentRoundTripTime = time.Since(sent)
+			stats.TotalRoundTripTime += stats.CurrentRoundTripTime
+		}
+	}
+}
+
+// expireCheckRequests forgets the checks which weren't answered within the
+// connection timeout, and fails the nominations they carried
+// Note: the caller should hold the agent lock.
+func (a *Agent) expireCheckRequests() {
+	for transactionID, sent := range a.checkRequests {
+		if time.Since(sent) > a.connectionTimeout {
+			delete(a.checkRequests, transactionID)
+			a.expireNominationRequest(transactionID)
+		}
+	}
+}
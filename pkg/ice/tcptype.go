@@ -0,0 +1,39 @@
+package ice
+
+// TCPType is the connection role of a TCP candidate
+// https://tools.ietf.org/html/rfc6544#section-4.5
+type TCPType int
+
+const (
+	// TCPTypeActive candidates open connections to passive candidates but
+	// don't accept any, they are signaled with the discard port 9
+	TCPTypeActive TCPType = iota + 1
+
+	// TCPTypePassive candidates accept connections from active candidates
+	// but don't open any
+	TCPTypePassive
+)
+
+// NewTCPType defines a procedure for creating a new TCPType from a raw
+// string naming the connection role.
+func NewTCPType(raw string) TCPType {
+	switch raw {
+	case "active":
+		return TCPTypeActive
+	case "passive":
+		return TCPTypePassive
+	default:
+		return TCPType(Unknown)
+	}
+}
+
+func (t TCPType) String() string {
+	switch t {
+	case TCPTypeActive:
+		return "active"
+	case TCPTypePassive:
+		return "passive"
+	default:
+		return ErrUnknownType.Error()
+	}
+}
@@ -0,0 +1,46 @@
+package interceptor
+
+// Chain composes Interceptors into one. The first Interceptor is the
+// closest to the application: outbound packets pass through the
+// Interceptors in order before they reach the network, inbound packets in
+// reverse order before they reach the application.
+type Chain struct {
+	interceptors []Interceptor
+}
+
+// NewChain creates a Chain of the Interceptors
+func NewChain(interceptors ...Interceptor) *Chain {
+	return &Chain{interceptors: interceptors}
+}
+
+// BindOutboundRTP binds every Interceptor, the last one writes to next
+func (c *Chain) BindOutboundRTP(next RTPWriter) RTPWriter {
+	for i := len(c.interceptors) - 1; i >= 0; i-- {
+		next = c.interceptors[i].BindOutboundRTP(next)
+	}
+	return next
+}
+
+// BindInboundRTP binds every Interceptor, the first one writes to next
+func (c *Chain) BindInboundRTP(next RTPWriter) RTPWriter {
+	for _, i := range c.interceptors {
+		next = i.BindInboundRTP(next)
+	}
+	return next
+}
+
+// BindOutboundRTCP binds every Interceptor, the last one writes to next
+func (c *Chain) BindOutboundRTCP(next RTCPWriter) RTCPWriter {
+	for i := len(c.interceptors) - 1; i >= 0; i-- {
+		next = c.interceptors[i].BindOutboundRTCP(next)
+	}
+	return next
+}
+
+// BindInboundRTCP binds every Interceptor, the first one writes to next
+func (c *Chain) BindInboundRTCP(next RTCPWriter) RTCPWriter {
+	for _, i := range c.interceptors {
+		next = i.BindInboundRTCP(next)
+	}
+	return next
+}
@@ -0,0 +1,97 @@
+package interceptor
+
+import (
+	"testing"
+
+	"github.com/pions/webrtc/pkg/rtp"
+	"github.com/stretchr/testify/assert"
+)
+
+// recorder appends its name to the trace of every packet it sees
+type recorder struct {
+	name  string
+	trace *[]string
+}
+
+func (r *recorder) BindOutboundRTP(next RTPWriter) RTPWriter {
+	return RTPWriterFunc(func(p *rtp.Packet) error {
+		*r.trace = append(*r.trace, "outbound rtp "+r.name)
+		return next.WriteRTP(p)
+	})
+}
+
+func (r *recorder) BindInboundRTP(next RTPWriter) RTPWriter {
+	return RTPWriterFunc(func(p *rtp.Packet) error {
+		*r.trace = append(*r.trace, "inbound rtp "+r.name)
+		return next.WriteRTP(p)
+	})
+}
+
+func (r *recorder) BindOutboundRTCP(next RTCPWriter) RTCPWriter {
+	return RTCPWriterFunc(func(raw []byte) error {
+		*r.trace = append(*r.trace, "outbound rtcp "+r.name)
+		return next.WriteRTCP(raw)
+	})
+}
+
+func (r *recorder) BindInboundRTCP(next RTCPWriter) RTCPWriter {
+	return RTCPWriterFunc(func(raw []byte) error {
+		*r.trace = append(*r.trace, "inbound rtcp "+r.name)
+		return next.WriteRTCP(raw)
+	})
+}
+
+// dropper drops outbound RTP packets and leaves everything else alone
+type dropper struct {
+	NoOp
+}
+
+func (dropper) BindOutboundRTP(next RTPWriter) RTPWriter {
+	return RTPWriterFunc(func(p *rtp.Packet) error {
+		return nil
+	})
+}
+
+func TestChain(t *testing.T) {
+	var trace []string
+	chain := NewChain(&recorder{"a", &trace}, &recorder{"b", &trace})
+
+	rtpEnd := RTPWriterFunc(func(p *rtp.Packet) error {
+		trace = append(trace, "end")
+		return nil
+	})
+	rtcpEnd := RTCPWriterFunc(func(raw []byte) error {
+		trace = append(trace, "end")
+		return nil
+	})
+
+	assert.Nil(t, chain.BindOutboundRTP(rtpEnd).WriteRTP(&rtp.Packet{}))
+	assert.Nil(t, chain.BindInboundRTP(rtpEnd).WriteRTP(&rtp.Packet{}))
+	assert.Nil(t, chain.BindOutboundRTCP(rtcpEnd).WriteRTCP(nil))
+	assert.Nil(t, chain.BindInboundRTCP(rtcpEnd).WriteRTCP(nil))
+
+	// The first Interceptor is the closest to the application
+	assert.Equal(t, []string{
+		"outbound rtp a", "outbound rtp b", "end",
+		"inbound rtp b", "inbound rtp a", "end",
+		"outbound rtcp a", "outbound rtcp b", "end",
+		"inbound rtcp b", "inbound rtcp a", "end",
+	}, trace)
+}
+
+func TestChain_Drop(t *testing.T) {
+	var trace []string
+	chain := NewChain(&recorder{"a", &trace}, dropper{}, &recorder{"b", &trace})
+
+	end := RTPWriterFunc(func(p *rtp.Packet) error {
+		trace = append(trace, "end")
+		return nil
+	})
+	assert.Nil(t, chain.BindOutboundRTP(end).WriteRTP(&rtp.Packet{}))
+	assert.Equal(t, []string{"outbound rtp a"}, trace)
+
+	// NoOp passes the other directions on
+	trace = nil
+	assert.Nil(t, chain.BindInboundRTP(end).WriteRTP(&rtp.Packet{}))
+	assert.Equal(t, []string{"inbound rtp b", "inbound rtp a", "end"}, trace)
+}
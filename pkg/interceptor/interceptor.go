@@ -0,0 +1,90 @@
+// Package interceptor provides the chain the RTP and RTCP packets of a
+// RTCPeerConnection pass through between the application and the network.
+// Features like NACK responders, congestion control feedback, statistics or
+// packet dumps are written as Interceptors and composed with Chain, without
+// changes to the transport.
+package interceptor
+
+import (
+	"github.com/pions/webrtc/pkg/rtp"
+)
+
+// RTPWriter is a stage of the chain RTP packets are passed along
+type RTPWriter interface {
+	WriteRTP(p *rtp.Packet) error
+}
+
+// RTPWriterFunc adapts a function to an RTPWriter
+type RTPWriterFunc func(p *rtp.Packet) error
+
+// WriteRTP calls f(p)
+func (f RTPWriterFunc) WriteRTP(p *rtp.Packet) error {
+	return f(p)
+}
+
+// RTCPWriter is a stage of the chain RTCP compound packets are passed along,
+// unencrypted and marshaled
+type RTCPWriter interface {
+	WriteRTCP(raw []byte) error
+}
+
+// RTCPWriterFunc adapts a function to an RTCPWriter
+type RTCPWriterFunc func(raw []byte) error
+
+// WriteRTCP calls f(raw)
+func (f RTCPWriterFunc) WriteRTCP(raw []byte) error {
+	return f(raw)
+}
+
+// Interceptor sees the packets of the RTCPeerConnections it is bound to.
+// Every RTCPeerConnection binds it once per direction when it is created,
+// the writers returned are used for its packets only, so they can hold the
+// state of the connection. A writer passes packets on to the next writer,
+// it may modify them, drop them by not passing them on or send packets of
+// its own, like RTCP feedback written to the outbound RTCP writer.
+//
+// Outbound packets are seen before they are encrypted, inbound packets
+// after they are decrypted and retransmissions and FEC have been unwrapped.
+// Inbound writers are called with the receive path blocked, they should
+// hand slow work off.
+type Interceptor interface {
+	// BindOutboundRTP returns the writer of the RTP packets sent by the
+	// tracks of a RTCPeerConnection, next sends them to the network
+	BindOutboundRTP(next RTPWriter) RTPWriter
+
+	// BindInboundRTP returns the writer of the RTP packets received by a
+	// RTCPeerConnection, next delivers them to its remote tracks
+	BindInboundRTP(next RTPWriter) RTPWriter
+
+	// BindOutboundRTCP returns the writer of the RTCP packets sent by a
+	// RTCPeerConnection, next sends them to the network
+	BindOutboundRTCP(next RTCPWriter) RTCPWriter
+
+	// BindInboundRTCP returns the writer of the RTCP packets received by a
+	// RTCPeerConnection, next delivers them to its senders and receivers
+	BindInboundRTCP(next RTCPWriter) RTCPWriter
+}
+
+// NoOp is an Interceptor passing every packet on unchanged. Embed it in
+// Interceptors which only care about some of the directions.
+type NoOp struct{}
+
+// BindOutboundRTP returns next
+func (NoOp) BindOutboundRTP(next RTPWriter) RTPWriter {
+	return next
+}
+
+// BindInboundRTP returns next
+func (NoOp) BindInboundRTP(next RTPWriter) RTPWriter {
+	return next
+}
+
+// BindOutboundRTCP returns next
+func (NoOp) BindOutboundRTCP(next RTCPWriter) RTCPWriter {
+	return next
+}
+
+// BindInboundRTCP returns next
+func (NoOp) BindInboundRTCP(next RTCPWriter) RTCPWriter {
+	return next
+}
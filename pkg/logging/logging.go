@@ -0,0 +1,164 @@
+// Package logging provides the leveled loggers the subsystems of pion-WebRTC
+// write their diagnostics to. Every subsystem asks a LoggerFactory for the
+// logger of its scope, so the verbosity is set per subsystem.
+package logging
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"sync"
+)
+
+// The scopes of the subsystems of pion-WebRTC
+const (
+	ScopeICE  = "ice"
+	ScopeDTLS = "dtls"
+	ScopeSCTP = "sctp"
+	ScopeRTP  = "rtp"
+	ScopePC   = "pc"
+)
+
+// LeveledLogger is the logger of a subsystem, every message has one of the
+// levels and is only written if the logger is at least that verbose
+type LeveledLogger interface {
+	Trace(msg string)
+	Tracef(format string, args ...interface{})
+	Debug(msg string)
+	Debugf(format string, args ...interface{})
+	Info(msg string)
+	Infof(format string, args ...interface{})
+	Warn(msg string)
+	Warnf(format string, args ...interface{})
+	Error(msg string)
+	Errorf(format string, args ...interface{})
+}
+
+// LoggerFactory creates the logger of the scope of a subsystem
+type LoggerFactory interface {
+	NewLogger(scope string) LeveledLogger
+}
+
+// DefaultLeveledLogger writes the messages of the level and the levels
+// below it to a writer, prefixed with the level and the scope
+type DefaultLeveledLogger struct {
+	level LogLevel
+
+	lock   sync.Mutex
+	logger *log.Logger
+}
+
+// NewDefaultLeveledLoggerForScope creates a DefaultLeveledLogger of the
+// scope, writing to the writer
+func NewDefaultLeveledLoggerForScope(scope string, level LogLevel, writer io.Writer) *DefaultLeveledLogger {
+	return &DefaultLeveledLogger{
+		level:  level,
+		logger: log.New(writer, fmt.Sprintf("%s ", scope), log.Lmicroseconds),
+	}
+}
+
+func (l *DefaultLeveledLogger) logf(level LogLevel, format string, args ...interface{}) {
+	if level > l.level {
+		return
+	}
+
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	if err := l.logger.Output(3, level.String()+": "+fmt.Sprintf(format, args...)); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to write log message: %v\n", err)
+	}
+}
+
+// Trace writes a message to follow the flow of packets and state machines
+func (l *DefaultLeveledLogger) Trace(msg string) { l.logf(LogLevelTrace, "%s", msg) }
+
+// Tracef formats and writes a trace message
+func (l *DefaultLeveledLogger) Tracef(format string, args ...interface{}) {
+	l.logf(LogLevelTrace, format, args...)
+}
+
+// Debug writes a message helping to debug the subsystem
+func (l *DefaultLeveledLogger) Debug(msg string) { l.logf(LogLevelDebug, "%s", msg) }
+
+// Debugf formats and writes a debug message
+func (l *DefaultLeveledLogger) Debugf(format string, args ...interface{}) {
+	l.logf(LogLevelDebug, format, args...)
+}
+
+// Info writes a message about the normal operation of the subsystem
+func (l *DefaultLeveledLogger) Info(msg string) { l.logf(LogLevelInfo, "%s", msg) }
+
+// Infof formats and writes an info message
+func (l *DefaultLeveledLogger) Infof(format string, args ...interface{}) {
+	l.logf(LogLevelInfo, format, args...)
+}
+
+// Warn writes a message about something unexpected the subsystem recovered
+// from, like a malformed packet it dropped
+func (l *DefaultLeveledLogger) Warn(msg string) { l.logf(LogLevelWarn, "%s", msg) }
+
+// Warnf formats and writes a warning
+func (l *DefaultLeveledLogger) Warnf(format string, args ...interface{}) {
+	l.logf(LogLevelWarn, format, args...)
+}
+
+// Error writes a message about a failure of the subsystem
+func (l *DefaultLeveledLogger) Error(msg string) { l.logf(LogLevelError, "%s", msg) }
+
+// Errorf formats and writes an error
+func (l *DefaultLeveledLogger) Errorf(format string, args ...interface{}) {
+	l.logf(LogLevelError, format, args...)
+}
+
+// DefaultLoggerFactory creates DefaultLeveledLoggers writing to the Writer.
+// ScopeLevels sets the level of the scopes, others use the DefaultLogLevel.
+type DefaultLoggerFactory struct {
+	Writer          io.Writer
+	DefaultLogLevel LogLevel
+	ScopeLevels     map[string]LogLevel
+}
+
+// NewDefaultLoggerFactory creates a DefaultLoggerFactory writing to stdout,
+// which is silent unless the environment enables the levels of scopes.
+// PIONS_LOG_ERROR, PIONS_LOG_WARN, PIONS_LOG_INFO, PIONS_LOG_DEBUG and
+// PIONS_LOG_TRACE hold comma separated scopes, or "all", logging at the
+// level, for instance PIONS_LOG_DEBUG=ice,dtls.
+func NewDefaultLoggerFactory() *DefaultLoggerFactory {
+	factory := &DefaultLoggerFactory{
+		Writer:          os.Stdout,
+		DefaultLogLevel: LogLevelDisabled,
+		ScopeLevels:     make(map[string]LogLevel),
+	}
+
+	// The more verbose levels take precedence
+	for _, level := range []LogLevel{LogLevelError, LogLevelWarn, LogLevelInfo, LogLevelDebug, LogLevelTrace} {
+		value := os.Getenv("PIONS_LOG_" + strings.ToUpper(level.String()))
+		if value == "" {
+			continue
+		}
+		for _, scope := range strings.Split(strings.ToLower(value), ",") {
+			if scope = strings.TrimSpace(scope); scope == "all" {
+				factory.DefaultLogLevel = level
+			} else if scope != "" {
+				factory.ScopeLevels[scope] = level
+			}
+		}
+	}
+	return factory
+}
+
+// NewLogger creates the logger of the scope
+func (f *DefaultLoggerFactory) NewLogger(scope string) LeveledLogger {
+	level, ok := f.ScopeLevels[scope]
+	if !ok {
+		level = f.DefaultLogLevel
+	}
+
+	writer := f.Writer
+	if writer == nil {
+		writer = os.Stdout
+	}
+	return NewDefaultLeveledLoggerForScope(scope, level, writer)
+}
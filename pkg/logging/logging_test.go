@@ -0,0 +1,75 @@
+package logging
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDefaultLeveledLogger(t *testing.T) {
+	testCases := []struct {
+		level    LogLevel
+		expected []string
+	}{
+		{LogLevelDisabled, nil},
+		{LogLevelError, []string{"Error: error 1"}},
+		{LogLevelWarn, []string{"Warn: warn 1", "Error: error 1"}},
+		{LogLevelTrace, []string{"Trace: trace 1", "Debug: debug 1", "Info: info 1", "Warn: warn 1", "Error: error 1"}},
+	}
+
+	for i, testCase := range testCases {
+		buffer := &bytes.Buffer{}
+		logger := NewDefaultLeveledLoggerForScope("test", testCase.level, buffer)
+		logger.Tracef("trace %d", 1)
+		logger.Debugf("debug %d", 1)
+		logger.Infof("info %d", 1)
+		logger.Warnf("warn %d", 1)
+		logger.Errorf("error %d", 1)
+
+		var lines []string
+		for _, line := range strings.Split(strings.TrimSpace(buffer.String()), "\n") {
+			if line == "" {
+				continue
+			}
+			assert.True(t, strings.HasPrefix(line, "test "), "testCase: %d", i)
+			lines = append(lines, line[strings.Index(line, " ")+1:][len("00:00:00.000000 "):])
+		}
+		assert.Equal(t, testCase.expected, lines, "testCase: %d", i)
+	}
+}
+
+func TestDefaultLoggerFactory(t *testing.T) {
+	buffer := &bytes.Buffer{}
+	factory := &DefaultLoggerFactory{
+		Writer:          buffer,
+		DefaultLogLevel: LogLevelError,
+		ScopeLevels:     map[string]LogLevel{ScopeICE: LogLevelDisabled},
+	}
+
+	factory.NewLogger(ScopeICE).Error("ice")
+	factory.NewLogger(ScopeDTLS).Error("dtls")
+	factory.NewLogger(ScopeDTLS).Warn("dtls")
+	assert.NotContains(t, buffer.String(), "ice")
+	assert.Equal(t, 1, strings.Count(buffer.String(), "dtls "))
+}
+
+func TestNewDefaultLoggerFactory(t *testing.T) {
+	for _, key := range []string{"PIONS_LOG_ERROR", "PIONS_LOG_WARN", "PIONS_LOG_INFO", "PIONS_LOG_DEBUG", "PIONS_LOG_TRACE"} {
+		defer os.Setenv(key, os.Getenv(key))
+		assert.Nil(t, os.Unsetenv(key))
+	}
+
+	factory := NewDefaultLoggerFactory()
+	assert.Equal(t, LogLevelDisabled, factory.DefaultLogLevel)
+	assert.Empty(t, factory.ScopeLevels)
+
+	assert.Nil(t, os.Setenv("PIONS_LOG_ERROR", "all"))
+	assert.Nil(t, os.Setenv("PIONS_LOG_DEBUG", "ice, DTLS"))
+	assert.Nil(t, os.Setenv("PIONS_LOG_TRACE", "dtls"))
+	factory = NewDefaultLoggerFactory()
+	assert.Equal(t, LogLevelError, factory.DefaultLogLevel)
+	assert.Equal(t, map[string]LogLevel{ScopeICE: LogLevelDebug, ScopeDTLS: LogLevelTrace}, factory.ScopeLevels)
+}
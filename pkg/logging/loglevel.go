@@ -0,0 +1,45 @@
+package logging
+
+// LogLevel is the verbosity of a logger, a logger writes the messages of its
+// level and of the levels below it
+type LogLevel int
+
+const (
+	// LogLevelDisabled writes no message
+	LogLevelDisabled LogLevel = iota
+
+	// LogLevelError writes failures of a subsystem
+	LogLevelError
+
+	// LogLevelWarn writes what a subsystem recovered from, like malformed
+	// packets it dropped
+	LogLevelWarn
+
+	// LogLevelInfo writes the normal operation of a subsystem
+	LogLevelInfo
+
+	// LogLevelDebug writes what helps debugging a subsystem
+	LogLevelDebug
+
+	// LogLevelTrace writes the flow of packets and state machines
+	LogLevelTrace
+)
+
+func (l LogLevel) String() string {
+	switch l {
+	case LogLevelDisabled:
+		return "Disabled"
+	case LogLevelError:
+		return "Error"
+	case LogLevelWarn:
+		return "Warn"
+	case LogLevelInfo:
+		return "Info"
+	case LogLevelDebug:
+		return "Debug"
+	case LogLevelTrace:
+		return "Trace"
+	default:
+		return "Unknown"
+	}
+}
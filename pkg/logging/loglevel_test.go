@@ -0,0 +1,30 @@
+package logging
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLogLevel_String(t *testing.T) {
+	testCases := []struct {
+		level          LogLevel
+		expectedString string
+	}{
+		{LogLevelDisabled, "Disabled"},
+		{LogLevelError, "Error"},
+		{LogLevelWarn, "Warn"},
+		{LogLevelInfo, "Info"},
+		{LogLevelDebug, "Debug"},
+		{LogLevelTrace, "Trace"},
+		{LogLevel(42), "Unknown"},
+	}
+
+	for i, testCase := range testCases {
+		assert.Equal(t,
+			testCase.expectedString,
+			testCase.level.String(),
+			"testCase: %d %v", i, testCase,
+		)
+	}
+}
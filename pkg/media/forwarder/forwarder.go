@@ -0,0 +1,158 @@
+// Package forwarder rewrites the RTP packets an SFU forwards from incoming
+// streams to an outgoing one. The outgoing stream keeps one SSRC and
+// continuous sequence numbers and timestamps when the forwarder switches
+// between sources, like the simulcast layers of a publisher.
+package forwarder
+
+import (
+	"errors"
+	"time"
+
+	"github.com/pions/webrtc/pkg/rtp"
+)
+
+var errShortPacket = errors.New("forwarder: packet is too short")
+
+const vp8ClockRate = 90000
+
+// Rewriter rewrites the packets of the sources forwarded on one outgoing
+// stream. A packet with another SSRC than the previous one switches the
+// source, its packets continue the outgoing stream where the previous
+// source stopped. The first packet of a video source should be a keyframe.
+type Rewriter struct {
+	ssrc      uint32
+	clockRate uint32
+	vp8       bool
+
+	started       bool
+	sourceSSRC    uint32
+	lastSequence  uint16
+	lastTimestamp uint32
+	lastSent      time.Time
+
+	sequenceOffset  uint16
+	timestampOffset uint32
+
+	// The VP8 picture IDs and TL0PICIDX are continued across sources too
+	hasPictureID    bool
+	lastPictureID   uint16
+	pictureIDOffset uint16
+	hasTL0          bool
+	lastTL0         uint8
+	tl0Offset       uint8
+
+	// now is the clock timestamps are remapped with across sources
+	now func() time.Time
+}
+
+// New creates a Rewriter of an outgoing stream with the SSRC and the clock
+// rate of its codec
+func New(ssrc, clockRate uint32) *Rewriter {
+	return &Rewriter{
+		ssrc:      ssrc,
+		clockRate: clockRate,
+		now:       time.Now,
+	}
+}
+
+// NewVP8 creates a Rewriter of an outgoing VP8 stream, which also rewrites
+// the picture IDs and TL0PICIDX of the VP8 payload descriptors
+func NewVP8(ssrc uint32) *Rewriter {
+	r := New(ssrc, vp8ClockRate)
+	r.vp8 = true
+	return r
+}
+
+// Rewrite returns a copy of the packet rewritten for the outgoing stream,
+// the packet is left untouched
+func (r *Rewriter) Rewrite(p *rtp.Packet) (*rtp.Packet, error) {
+	out := p.Clone()
+
+	var descriptor *vp8Descriptor
+	if r.vp8 {
+		var err error
+		if descriptor, err = parseVP8Descriptor(out.Payload); err != nil {
+			return nil, err
+		}
+	}
+
+	if !r.started || p.SSRC != r.sourceSSRC {
+		r.switchSource(p, descriptor)
+	}
+
+	out.SSRC = r.ssrc
+	out.SequenceNumber = p.SequenceNumber + r.sequenceOffset
+	out.Timestamp = p.Timestamp + r.timestampOffset
+	if descriptor != nil {
+		r.rewriteVP8(out.Payload, descriptor)
+	}
+
+	// Reordered packets don't move the stream back
+	if int16(out.SequenceNumber-r.lastSequence) > 0 || !r.started {
+		r.lastSequence = out.SequenceNumber
+	}
+	if int32(out.Timestamp-r.lastTimestamp) > 0 || !r.started {
+		r.lastTimestamp = out.Timestamp
+		r.lastSent = r.now()
+	}
+	r.started = true
+	return out, nil
+}
+
+// switchSource maps the first packet of a source right after the last one
+// sent. Its timestamp advances by the time elapsed since then.
+func (r *Rewriter) switchSource(p *rtp.Packet, descriptor *vp8Descriptor) {
+	r.sourceSSRC = p.SSRC
+	if !r.started {
+		r.sequenceOffset = 0
+		r.timestampOffset = 0
+	} else {
+		elapsed := uint32(r.now().Sub(r.lastSent) * time.Duration(r.clockRate) / time.Second)
+		if elapsed == 0 {
+			elapsed = 1
+		}
+
+		r.sequenceOffset = r.lastSequence + 1 - p.SequenceNumber
+		r.timestampOffset = r.lastTimestamp + elapsed - p.Timestamp
+	}
+
+	if descriptor == nil {
+		return
+	}
+	if descriptor.hasPictureID {
+		r.pictureIDOffset = 0
+		if r.hasPictureID {
+			r.pictureIDOffset = r.lastPictureID + 1 - descriptor.pictureID
+		}
+	}
+	if descriptor.hasTL0 {
+		r.tl0Offset = 0
+		if r.hasTL0 {
+			r.tl0Offset = r.lastTL0 + 1 - descriptor.tl0
+		}
+	}
+}
+
+func (r *Rewriter) rewriteVP8(payload []byte, descriptor *vp8Descriptor) {
+	if descriptor.hasPictureID {
+		pictureID := descriptor.pictureID + r.pictureIDOffset
+		descriptor.writePictureID(payload, pictureID)
+
+		mask := descriptor.pictureIDMask()
+		pictureID &= mask
+		if diff := (pictureID - r.lastPictureID) & mask; !r.hasPictureID || (diff != 0 && diff <= mask/2) {
+			r.lastPictureID = pictureID
+		}
+		r.hasPictureID = true
+	}
+
+	if descriptor.hasTL0 {
+		tl0 := descriptor.tl0 + r.tl0Offset
+		payload[descriptor.tl0Offset] = tl0
+
+		if !r.hasTL0 || int8(tl0-r.lastTL0) > 0 {
+			r.lastTL0 = tl0
+		}
+		r.hasTL0 = true
+	}
+}
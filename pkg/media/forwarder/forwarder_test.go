@@ -0,0 +1,86 @@
+package forwarder
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pions/webrtc/pkg/rtp"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time { return c.now }
+
+func TestRewriter_SwitchSource(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(1000, 0)}
+	r := New(1234, 48000)
+	r.now = clock.Now
+
+	testCases := []struct {
+		advance           time.Duration
+		ssrc              uint32
+		sequence          uint16
+		timestamp         uint32
+		expectedSequence  uint16
+		expectedTimestamp uint32
+	}{
+		// The first source is forwarded as is
+		{0, 1, 65534, 4294966000, 65534, 4294966000},
+		{20 * time.Millisecond, 1, 65535, 4294966960, 65535, 4294966960},
+		// The second source continues right after it, its timestamp
+		// advancing by the time elapsed, across the wraparound
+		{100 * time.Millisecond, 2, 300, 50000, 0, 4464},
+		{20 * time.Millisecond, 2, 301, 50960, 1, 5424},
+		// Reordered packets keep their spacing
+		{0, 2, 303, 52880, 3, 7344},
+		{0, 2, 302, 51920, 2, 6384},
+		// Switching back continues after the highest packet sent
+		{20 * time.Millisecond, 1, 10, 1000, 4, 8304},
+	}
+
+	for i, testCase := range testCases {
+		clock.now = clock.now.Add(testCase.advance)
+		p := &rtp.Packet{Version: 2, SSRC: testCase.ssrc, SequenceNumber: testCase.sequence, Timestamp: testCase.timestamp, Payload: []byte{0x01}}
+		out, err := r.Rewrite(p)
+		assert.Nil(t, err, "testCase: %d", i)
+		assert.Equal(t, uint32(1234), out.SSRC, "testCase: %d", i)
+		assert.Equal(t, testCase.expectedSequence, out.SequenceNumber, "testCase: %d", i)
+		assert.Equal(t, testCase.expectedTimestamp, out.Timestamp, "testCase: %d", i)
+		assert.Equal(t, testCase.ssrc, p.SSRC, "testCase: %d", i)
+	}
+}
+
+func TestRewriter_VP8(t *testing.T) {
+	r := NewVP8(1234)
+
+	testCases := []struct {
+		ssrc     uint32
+		payload  []byte
+		expected []byte
+	}{
+		// 15 bit picture IDs and TL0PICIDX
+		{1, []byte{0x90, 0xc0, 0x80, 0x64, 0x10, 0xaa}, []byte{0x90, 0xc0, 0x80, 0x64, 0x10, 0xaa}},
+		{1, []byte{0x90, 0xc0, 0x80, 0x65, 0x11, 0xaa}, []byte{0x90, 0xc0, 0x80, 0x65, 0x11, 0xaa}},
+		{2, []byte{0x90, 0xc0, 0x93, 0x88, 0xf0, 0xbb}, []byte{0x90, 0xc0, 0x80, 0x66, 0x12, 0xbb}},
+		{2, []byte{0x90, 0xc0, 0x93, 0x89, 0xf0, 0xbb}, []byte{0x90, 0xc0, 0x80, 0x67, 0x12, 0xbb}},
+		// 7 bit picture IDs keep their length
+		{3, []byte{0x90, 0x80, 0x7f, 0xcc}, []byte{0x90, 0x80, 0x68, 0xcc}},
+		{3, []byte{0x90, 0x80, 0x00, 0xcc}, []byte{0x90, 0x80, 0x69, 0xcc}},
+		// Descriptors without the fields are untouched
+		{3, []byte{0x10, 0xdd}, []byte{0x10, 0xdd}},
+	}
+
+	for i, testCase := range testCases {
+		out, err := r.Rewrite(&rtp.Packet{SSRC: testCase.ssrc, Payload: testCase.payload})
+		assert.Nil(t, err, "testCase: %d", i)
+		assert.Equal(t, testCase.expected, out.Payload, "testCase: %d", i)
+	}
+
+	for i, payload := range [][]byte{{}, {0x80}, {0x80, 0x80}, {0x80, 0x80, 0x80}, {0x80, 0x40}} {
+		_, err := r.Rewrite(&rtp.Packet{SSRC: 3, Payload: payload})
+		assert.Equal(t, errShortPacket, err, "testCase: %d", i)
+	}
+}
@@ -0,0 +1,78 @@
+package forwarder
+
+import "encoding/binary"
+
+// vp8Descriptor is the location of the fields of a VP8 payload descriptor
+// the Rewriter rewrites
+// https://tools.ietf.org/html/rfc7741#section-4.2
+type vp8Descriptor struct {
+	hasPictureID    bool
+	longPictureID   bool
+	pictureID       uint16
+	pictureIDOffset int
+
+	hasTL0    bool
+	tl0       uint8
+	tl0Offset int
+}
+
+func parseVP8Descriptor(payload []byte) (*vp8Descriptor, error) {
+	d := &vp8Descriptor{}
+	if len(payload) < 1 {
+		return nil, errShortPacket
+	} else if payload[0]&0x80 == 0 {
+		return d, nil
+	}
+
+	if len(payload) < 2 {
+		return nil, errShortPacket
+	}
+	extension := payload[1]
+	offset := 2
+
+	if extension&0x80 != 0 {
+		if len(payload) < offset+1 {
+			return nil, errShortPacket
+		}
+		d.hasPictureID = true
+		d.pictureIDOffset = offset
+		if payload[offset]&0x80 != 0 {
+			if len(payload) < offset+2 {
+				return nil, errShortPacket
+			}
+			d.longPictureID = true
+			d.pictureID = binary.BigEndian.Uint16(payload[offset:]) & 0x7fff
+			offset += 2
+		} else {
+			d.pictureID = uint16(payload[offset] & 0x7f)
+			offset++
+		}
+	}
+
+	if extension&0x40 != 0 {
+		if len(payload) < offset+1 {
+			return nil, errShortPacket
+		}
+		d.hasTL0 = true
+		d.tl0 = payload[offset]
+		d.tl0Offset = offset
+	}
+	return d, nil
+}
+
+// pictureIDMask returns the bits of the picture ID, it is 7 or 15 bits long
+func (d *vp8Descriptor) pictureIDMask() uint16 {
+	if d.longPictureID {
+		return 0x7fff
+	}
+	return 0x7f
+}
+
+// writePictureID replaces the picture ID, keeping its length
+func (d *vp8Descriptor) writePictureID(payload []byte, pictureID uint16) {
+	if d.longPictureID {
+		binary.BigEndian.PutUint16(payload[d.pictureIDOffset:], 0x8000|pictureID&0x7fff)
+	} else {
+		payload[d.pictureIDOffset] = uint8(pictureID & 0x7f)
+	}
+}
@@ -0,0 +1,134 @@
+// Package ivfreader reads the frames of an IVF file, so they can be played
+// into a track.
+package ivfreader
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"io/ioutil"
+	"time"
+)
+
+var (
+	errNilStream          = errors.New("ivfreader: stream is nil")
+	errSignatureMismatch  = errors.New("ivfreader: signature mismatch")
+	errUnknownVersion     = errors.New("ivfreader: unknown IVF version")
+	errInvalidHeaderSize  = errors.New("ivfreader: invalid header size")
+	errInvalidTimebase    = errors.New("ivfreader: invalid timebase")
+	errIncompleteFrameHdr = errors.New("ivfreader: incomplete frame header")
+)
+
+const (
+	ivfFileHeaderSignature = "DKIF"
+	ivfFileHeaderSize      = 32
+	ivfFrameHeaderSize     = 12
+)
+
+// IVFFileHeader is the header at the start of an IVF file
+type IVFFileHeader struct {
+	Signature           string
+	Version             uint16
+	HeaderSize          uint16
+	FourCC              string
+	Width               uint16
+	Height              uint16
+	TimebaseDenominator uint32
+	TimebaseNumerator   uint32
+	NumFrames           uint32
+}
+
+// Duration converts a timestamp of the frames of the file to the time it is
+// played at
+func (h *IVFFileHeader) Duration(timestamp uint64) time.Duration {
+	seconds := timestamp / uint64(h.TimebaseDenominator) * uint64(h.TimebaseNumerator)
+	remainder := timestamp % uint64(h.TimebaseDenominator) * uint64(h.TimebaseNumerator)
+	return time.Duration(seconds)*time.Second + time.Duration(remainder)*time.Second/time.Duration(h.TimebaseDenominator)
+}
+
+// IVFFrameHeader is the header in front of every frame of an IVF file
+type IVFFrameHeader struct {
+	FrameSize uint32
+	Timestamp uint64
+}
+
+// IVFReader is used to read the frames of an IVF file
+type IVFReader struct {
+	stream io.Reader
+}
+
+// NewWith reads the file header of the stream and returns a reader of its
+// frames
+func NewWith(in io.Reader) (*IVFReader, *IVFFileHeader, error) {
+	if in == nil {
+		return nil, nil, errNilStream
+	}
+
+	reader := &IVFReader{stream: in}
+	header, err := reader.parseFileHeader()
+	if err != nil {
+		return nil, nil, err
+	}
+	return reader, header, nil
+}
+
+// ParseNextFrame reads the next frame of the file with its header. It
+// returns io.EOF at the end of the file and io.ErrUnexpectedEOF if the file
+// ends within a frame.
+func (i *IVFReader) ParseNextFrame() ([]byte, *IVFFrameHeader, error) {
+	buffer := make([]byte, ivfFrameHeaderSize)
+	if _, err := io.ReadFull(i.stream, buffer); err == io.ErrUnexpectedEOF {
+		return nil, nil, errIncompleteFrameHdr
+	} else if err != nil {
+		return nil, nil, err
+	}
+
+	header := &IVFFrameHeader{
+		FrameSize: binary.LittleEndian.Uint32(buffer[0:]),
+		Timestamp: binary.LittleEndian.Uint64(buffer[4:]),
+	}
+
+	payload := make([]byte, header.FrameSize)
+	if _, err := io.ReadFull(i.stream, payload); err == io.EOF {
+		return nil, nil, io.ErrUnexpectedEOF
+	} else if err != nil {
+		return nil, nil, err
+	}
+	return payload, header, nil
+}
+
+func (i *IVFReader) parseFileHeader() (*IVFFileHeader, error) {
+	buffer := make([]byte, ivfFileHeaderSize)
+	if _, err := io.ReadFull(i.stream, buffer); err != nil {
+		return nil, err
+	}
+
+	header := &IVFFileHeader{
+		Signature:           string(buffer[0:4]),
+		Version:             binary.LittleEndian.Uint16(buffer[4:]),
+		HeaderSize:          binary.LittleEndian.Uint16(buffer[6:]),
+		FourCC:              string(buffer[8:12]),
+		Width:               binary.LittleEndian.Uint16(buffer[12:]),
+		Height:              binary.LittleEndian.Uint16(buffer[14:]),
+		TimebaseDenominator: binary.LittleEndian.Uint32(buffer[16:]),
+		TimebaseNumerator:   binary.LittleEndian.Uint32(buffer[20:]),
+		NumFrames:           binary.LittleEndian.Uint32(buffer[24:]),
+	}
+
+	switch {
+	case header.Signature != ivfFileHeaderSignature:
+		return nil, errSignatureMismatch
+	case header.Version != 0:
+		return nil, errUnknownVersion
+	case header.HeaderSize < ivfFileHeaderSize:
+		return nil, errInvalidHeaderSize
+	case header.TimebaseDenominator == 0:
+		return nil, errInvalidTimebase
+	}
+
+	// Newer versions of the format may extend the header
+	if _, err := io.CopyN(ioutil.Discard, i.stream, int64(header.HeaderSize-ivfFileHeaderSize)); err != nil {
+		return nil, err
+	}
+	return header, nil
+}
@@ -0,0 +1,77 @@
+package ivfreader
+
+import (
+	"bytes"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/pions/webrtc/pkg/media/ivfwriter"
+	"github.com/pions/webrtc/pkg/rtp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIVFReader_ParseNextFrame(t *testing.T) {
+	buffer := &bytes.Buffer{}
+	writer, err := ivfwriter.NewWith(buffer)
+	assert.Nil(t, err)
+	assert.Nil(t, writer.WriteRTP(&rtp.Packet{Timestamp: 1000, Marker: true, Payload: []byte{0x10, 0x01, 0x02}}))
+	assert.Nil(t, writer.WriteRTP(&rtp.Packet{Timestamp: 4000, Marker: true, Payload: []byte{0x10, 0x03}}))
+	assert.Nil(t, writer.Close())
+
+	reader, header, err := NewWith(bytes.NewReader(buffer.Bytes()))
+	assert.Nil(t, err)
+	assert.Equal(t, "DKIF", header.Signature)
+	assert.Equal(t, "VP80", header.FourCC)
+	assert.Equal(t, uint32(90000), header.TimebaseDenominator)
+	assert.Equal(t, uint32(1), header.TimebaseNumerator)
+
+	frame, frameHeader, err := reader.ParseNextFrame()
+	assert.Nil(t, err)
+	assert.Equal(t, []byte{0x01, 0x02}, frame)
+	assert.Equal(t, uint64(0), frameHeader.Timestamp)
+
+	frame, frameHeader, err = reader.ParseNextFrame()
+	assert.Nil(t, err)
+	assert.Equal(t, []byte{0x03}, frame)
+	assert.Equal(t, time.Second/30, header.Duration(frameHeader.Timestamp))
+
+	_, _, err = reader.ParseNextFrame()
+	assert.Equal(t, io.EOF, err)
+
+	// A file ending within a frame
+	reader, _, err = NewWith(bytes.NewReader(buffer.Bytes()[:buffer.Len()-1]))
+	assert.Nil(t, err)
+	_, _, err = reader.ParseNextFrame()
+	assert.Nil(t, err)
+	_, _, err = reader.ParseNextFrame()
+	assert.Equal(t, io.ErrUnexpectedEOF, err)
+}
+
+func TestIVFReader_FileHeader(t *testing.T) {
+	valid := func() []byte {
+		buffer := &bytes.Buffer{}
+		_, err := ivfwriter.NewWith(buffer)
+		assert.Nil(t, err)
+		return buffer.Bytes()
+	}
+
+	testCases := []struct {
+		modify   func([]byte) []byte
+		expected error
+	}{
+		{func(b []byte) []byte { b[0] = 'X'; return b }, errSignatureMismatch},
+		{func(b []byte) []byte { b[4] = 1; return b }, errUnknownVersion},
+		{func(b []byte) []byte { b[6] = 16; return b }, errInvalidHeaderSize},
+		{func(b []byte) []byte { b[16], b[17], b[18], b[19] = 0, 0, 0, 0; return b }, errInvalidTimebase},
+		{func(b []byte) []byte { return b[:20] }, io.ErrUnexpectedEOF},
+	}
+
+	for i, testCase := range testCases {
+		_, _, err := NewWith(bytes.NewReader(testCase.modify(valid())))
+		assert.Equal(t, testCase.expected, err, "testCase: %d", i)
+	}
+
+	_, _, err := NewWith(nil)
+	assert.Equal(t, errNilStream, err)
+}
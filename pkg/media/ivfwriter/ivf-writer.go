@@ -1,19 +1,48 @@
+// Package ivfwriter writes the VP8 frames carried by RTP packets to an IVF
+// file, a container holding the frames with their timestamps.
 package ivfwriter
 
 import (
 	"encoding/binary"
-	"fmt"
+	"errors"
+	"io"
 	"os"
 
 	"github.com/pions/webrtc/pkg/rtp"
 	"github.com/pions/webrtc/pkg/rtp/codecs"
 )
 
+var (
+	errFileNotOpened = errors.New("ivfwriter: file not opened")
+	errShortPacket   = errors.New("ivfwriter: packet is too short")
+)
+
+const (
+	ivfFileHeaderSize  = 32
+	ivfFrameHeaderSize = 12
+
+	// frameCountOffset is where the amount of frames is in the file header,
+	// it is only known once the file is closed
+	frameCountOffset = 24
+
+	// The timestamps of VP8 RTP packets use a 90kHz clock, they are the
+	// timebase of the file so the frames keep their timing
+	vp8ClockRate = 90000
+)
+
 // IVFWriter is used to take RTP packets and write them to an IVF on disk
 type IVFWriter struct {
-	fd           *os.File
-	count        uint64
+	stream io.Writer
+	fd     *os.File
+
+	count        uint32
 	currentFrame []byte
+
+	// timestamp is the presentation timestamp of the last frame, it follows
+	// the RTP timestamps across their wraparound
+	hasTimestamp  bool
+	lastTimestamp uint32
+	timestamp     uint64
 }
 
 // New builds a new IVF writer
@@ -23,55 +52,123 @@ func New(fileName string) (*IVFWriter, error) {
 		return nil, err
 	}
 
-	header := make([]byte, 32)
-	copy(header[0:], []byte("DKIF"))                // DKIF
-	binary.LittleEndian.PutUint16(header[4:], 0)    // Version
-	binary.LittleEndian.PutUint16(header[6:], 32)   // Header Size
-	copy(header[8:], []byte("VP80"))                // FOURCC
-	binary.LittleEndian.PutUint16(header[12:], 640) // Version
-	binary.LittleEndian.PutUint16(header[14:], 480) // Header Size
-	binary.LittleEndian.PutUint32(header[16:], 30)  // Framerate numerator
-	binary.LittleEndian.PutUint32(header[20:], 1)   // Framerate Denominator
-	binary.LittleEndian.PutUint32(header[24:], 900) // Frame count
-	binary.LittleEndian.PutUint32(header[28:], 0)   // Unused
-
-	if _, err := f.Write(header); err != nil {
+	writer, err := NewWith(f)
+	if err != nil {
+		_ = f.Close()
 		return nil, err
 	}
+	writer.fd = f
+	return writer, nil
+}
 
-	return &IVFWriter{fd: f}, nil
+// NewWith builds a new IVF writer writing to the stream. If the stream is
+// an io.WriteSeeker the amount of frames is written to the file header
+// on Close.
+func NewWith(out io.Writer) (*IVFWriter, error) {
+	if out == nil {
+		return nil, errFileNotOpened
+	}
+
+	header := make([]byte, ivfFileHeaderSize)
+	copy(header[0:], []byte("DKIF"))                         // DKIF
+	binary.LittleEndian.PutUint16(header[4:], 0)             // Version
+	binary.LittleEndian.PutUint16(header[6:], 32)            // Header Size
+	copy(header[8:], []byte("VP80"))                         // FOURCC
+	binary.LittleEndian.PutUint16(header[12:], 640)          // Width
+	binary.LittleEndian.PutUint16(header[14:], 480)          // Height
+	binary.LittleEndian.PutUint32(header[16:], vp8ClockRate) // Timebase denominator
+	binary.LittleEndian.PutUint32(header[20:], 1)            // Timebase numerator
+	binary.LittleEndian.PutUint32(header[24:], 0)            // Frame count
+	binary.LittleEndian.PutUint32(header[28:], 0)            // Unused
+
+	if _, err := out.Write(header); err != nil {
+		return nil, err
+	}
+
+	return &IVFWriter{stream: out}, nil
 }
 
-// AddPacket adds a new packet and writes the appropriate headers for it
-func (i *IVFWriter) AddPacket(packet *rtp.Packet) error {
+// WriteRTP adds a new packet and writes the frame once its last packet,
+// which has the marker bit set, is added
+func (i *IVFWriter) WriteRTP(packet *rtp.Packet) error {
+	if i.stream == nil {
+		return errFileNotOpened
+	} else if len(packet.Payload) == 0 {
+		return errShortPacket
+	}
 
 	vp8Packet := codecs.VP8Packet{}
-	_, err := vp8Packet.Unmarshal(packet)
-	if err != nil {
+	if _, err := vp8Packet.Unmarshal(packet); err != nil {
 		return err
 	}
 
-	i.currentFrame = append(i.currentFrame, vp8Packet.Payload[0:]...)
+	i.currentFrame = append(i.currentFrame, vp8Packet.Payload...)
 
 	if !packet.Marker {
 		return nil
 	} else if len(i.currentFrame) == 0 {
-		fmt.Println("skipping")
 		return nil
 	}
 
-	frameHeader := make([]byte, 12)
+	if i.hasTimestamp {
+		i.timestamp += uint64(packet.Timestamp - i.lastTimestamp)
+	}
+	i.hasTimestamp = true
+	i.lastTimestamp = packet.Timestamp
+
+	frameHeader := make([]byte, ivfFrameHeaderSize)
 	binary.LittleEndian.PutUint32(frameHeader[0:], uint32(len(i.currentFrame))) // Frame length
-	binary.LittleEndian.PutUint64(frameHeader[4:], i.count)                     // PTS
+	binary.LittleEndian.PutUint64(frameHeader[4:], i.timestamp)                 // PTS
 
 	i.count++
 
-	if _, err := i.fd.Write(frameHeader); err != nil {
+	if _, err := i.stream.Write(frameHeader); err != nil {
 		return err
-	} else if _, err := i.fd.Write(i.currentFrame); err != nil {
+	} else if _, err := i.stream.Write(i.currentFrame); err != nil {
 		return err
 	}
 
 	i.currentFrame = nil
 	return nil
 }
+
+// Close writes the amount of frames to the file header and closes the file
+// created by New. A frame missing its last packet is dropped.
+func (i *IVFWriter) Close() error {
+	if i.stream == nil {
+		return nil
+	}
+	defer func() {
+		i.stream = nil
+		i.fd = nil
+	}()
+
+	if seeker, ok := i.stream.(io.WriteSeeker); ok {
+		if err := i.writeFrameCount(seeker); err != nil {
+			if i.fd != nil {
+				_ = i.fd.Close()
+			}
+			return err
+		}
+	}
+
+	if i.fd == nil {
+		return nil
+	}
+	return i.fd.Close()
+}
+
+func (i *IVFWriter) writeFrameCount(seeker io.WriteSeeker) error {
+	if _, err := seeker.Seek(frameCountOffset, io.SeekStart); err != nil {
+		return err
+	}
+
+	count := make([]byte, 4)
+	binary.LittleEndian.PutUint32(count, i.count)
+	if _, err := seeker.Write(count); err != nil {
+		return err
+	}
+
+	_, err := seeker.Seek(0, io.SeekEnd)
+	return err
+}
@@ -0,0 +1,51 @@
+package ivfwriter
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+
+	"github.com/pions/webrtc/pkg/rtp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIVFWriter_WriteRTP(t *testing.T) {
+	buffer := &bytes.Buffer{}
+	writer, err := NewWith(buffer)
+	assert.Nil(t, err)
+	assert.Equal(t, ivfFileHeaderSize, buffer.Len())
+
+	packets := []*rtp.Packet{
+		{Timestamp: 4294967000, Marker: false, Payload: []byte{0x10, 0x01, 0x02}},
+		{Timestamp: 4294967000, Marker: true, Payload: []byte{0x00, 0x03}},
+		{Timestamp: 2704, Marker: true, Payload: []byte{0x10, 0x04}},
+	}
+	for _, packet := range packets {
+		assert.Nil(t, writer.WriteRTP(packet))
+	}
+	assert.Equal(t, errShortPacket, writer.WriteRTP(&rtp.Packet{Marker: true}))
+	assert.Nil(t, writer.Close())
+
+	// The frames are reassembled and the timestamps follow the wraparound of
+	// the RTP timestamps
+	expected := []struct {
+		payload   []byte
+		timestamp uint64
+	}{
+		{[]byte{0x01, 0x02, 0x03}, 0},
+		{[]byte{0x04}, 3000},
+	}
+
+	file := buffer.Bytes()[ivfFileHeaderSize:]
+	for i, frame := range expected {
+		assert.Equal(t, uint32(len(frame.payload)), binary.LittleEndian.Uint32(file[0:]), "testCase: %d", i)
+		assert.Equal(t, frame.timestamp, binary.LittleEndian.Uint64(file[4:]), "testCase: %d", i)
+		assert.Equal(t, frame.payload, file[ivfFrameHeaderSize:ivfFrameHeaderSize+len(frame.payload)], "testCase: %d", i)
+		file = file[ivfFrameHeaderSize+len(frame.payload):]
+	}
+	assert.Empty(t, file)
+
+	assert.Equal(t, errFileNotOpened, writer.WriteRTP(packets[0]))
+	_, err = NewWith(nil)
+	assert.Equal(t, errFileNotOpened, err)
+}
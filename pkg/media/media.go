@@ -4,4 +4,9 @@ package media
 type RTCSample struct {
 	Data    []byte
 	Samples uint32
+
+	// CSRC lists the contributing sources of a sample mixed from several
+	// streams, which every packet of the sample carries. A packet lists at
+	// most 15 of them, the rest are left out.
+	CSRC []uint32
 }
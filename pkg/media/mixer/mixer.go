@@ -0,0 +1,320 @@
+// Package mixer mixes the audio of several sources into a single stream, the
+// core of an MCU which sends the participants of a conference one track
+// instead of one per participant. Sources write 16 bit PCM, or encoded
+// frames like Opus packets which a Decoder of the source turns into PCM. The
+// mixed frames are encoded by an Encoder, typically into Opus, and list the
+// sources heard in them as contributing sources.
+package mixer
+
+import (
+	"errors"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/pions/webrtc/pkg/media"
+)
+
+var (
+	errNoEncoder     = errors.New("mixer: no encoder")
+	errInvalidFormat = errors.New("mixer: invalid sample rate, channels or frame duration")
+	errSourceExists  = errors.New("mixer: source already exists")
+	errUnknownSource = errors.New("mixer: unknown source")
+	errNoDecoder     = errors.New("mixer: source has no decoder")
+	errInvalidGain   = errors.New("mixer: gain is negative")
+	errDecodedSize   = errors.New("mixer: decoder returned more samples than its buffer holds")
+	errEncodedSize   = errors.New("mixer: encoder returned more bytes than its buffer holds")
+)
+
+const (
+	defaultSampleRate    = 48000
+	defaultChannels      = 1
+	defaultFrameDuration = 20 * time.Millisecond
+
+	// maxBufferedFrames bounds the PCM a source buffers ahead of the mix,
+	// a source writing faster than the mix drops its oldest PCM instead of
+	// adding latency
+	maxBufferedFrames = 10
+
+	// maxDecodedDuration is the longest frame decoded, that of Opus
+	maxDecodedDuration = 120 * time.Millisecond
+)
+
+// Decoder decodes an encoded frame of a source, like the payload of an Opus
+// packet, into interleaved PCM at the sample rate and channels of the mixer
+type Decoder interface {
+	// Decode decodes the frame into pcm and returns the number of samples
+	// per channel decoded
+	Decode(frame []byte, pcm []int16) (int, error)
+}
+
+// Encoder encodes the mixed frames, of FrameDuration at the sample rate and
+// channels of the mixer
+type Encoder interface {
+	// Encode encodes the interleaved PCM of a frame into data and returns
+	// the number of bytes written
+	Encode(pcm []int16, data []byte) (int, error)
+}
+
+// Config configures a Mixer, the zero values of the format select 48kHz mono
+// audio in frames of 20ms, which suits Opus
+type Config struct {
+	SampleRate    int
+	Channels      int
+	FrameDuration time.Duration
+
+	// Encoder encodes the mixed frames, it is required
+	Encoder Encoder
+}
+
+// Mixer mixes the PCM of its sources a frame at a time. Every source is
+// scaled by its gain, the sum is clipped to 16 bits. It is safe for
+// concurrent use, sources are usually written from the goroutines reading
+// their tracks while Run mixes.
+type Mixer struct {
+	lock sync.Mutex
+
+	encoder     Encoder
+	channels    int
+	frameSize   int // samples per channel in a frame
+	duration    time.Duration
+	decodedSize int // samples of the longest frame decoded
+
+	sources map[uint32]*source
+
+	// mixed, pcm and encoded are reused by every frame, guarded by lock
+	mixed   []int32
+	pcm     []int16
+	encoded []byte
+}
+
+// source is a participant mixed, identified by the SSRC of its stream
+type source struct {
+	ssrc    uint32
+	gain    float64
+	decoder Decoder
+
+	// buffer holds the PCM written ahead of the mix, decoded the decoded
+	// frames
+	buffer  []int16
+	decoded []int16
+}
+
+// New creates a Mixer
+func New(config Config) (*Mixer, error) {
+	if config.Encoder == nil {
+		return nil, errNoEncoder
+	}
+	if config.SampleRate == 0 {
+		config.SampleRate = defaultSampleRate
+	}
+	if config.Channels == 0 {
+		config.Channels = defaultChannels
+	}
+	if config.FrameDuration == 0 {
+		config.FrameDuration = defaultFrameDuration
+	}
+
+	frameSize := int(time.Duration(config.SampleRate) * config.FrameDuration / time.Second)
+	if config.SampleRate < 0 || config.Channels < 0 || frameSize <= 0 {
+		return nil, errInvalidFormat
+	}
+
+	return &Mixer{
+		encoder:     config.Encoder,
+		channels:    config.Channels,
+		frameSize:   frameSize,
+		duration:    config.FrameDuration,
+		decodedSize: int(time.Duration(config.SampleRate)*maxDecodedDuration/time.Second) * config.Channels,
+		sources:     make(map[uint32]*source),
+		mixed:       make([]int32, frameSize*config.Channels),
+		pcm:         make([]int16, frameSize*config.Channels),
+		// Room for PCM which doesn't compress
+		encoded: make([]byte, frameSize*config.Channels*2),
+	}, nil
+}
+
+// AddSource adds a source with a gain of 1. The decoder decodes the frames
+// written with WriteFrame, it may be nil for sources which write PCM.
+func (m *Mixer) AddSource(ssrc uint32, decoder Decoder) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if _, ok := m.sources[ssrc]; ok {
+		return errSourceExists
+	}
+	m.sources[ssrc] = &source{ssrc: ssrc, gain: 1, decoder: decoder}
+	return nil
+}
+
+// RemoveSource removes a source, the PCM it buffered isn't mixed
+func (m *Mixer) RemoveSource(ssrc uint32) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	delete(m.sources, ssrc)
+}
+
+// SetGain sets the factor the PCM of a source is scaled by, 0 mutes it
+func (m *Mixer) SetGain(ssrc uint32, gain float64) error {
+	if gain < 0 || math.IsNaN(gain) {
+		return errInvalidGain
+	}
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	s, ok := m.sources[ssrc]
+	if !ok {
+		return errUnknownSource
+	}
+	s.gain = gain
+	return nil
+}
+
+// WritePCM buffers interleaved PCM of a source for the next frames
+func (m *Mixer) WritePCM(ssrc uint32, pcm []int16) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	s, ok := m.sources[ssrc]
+	if !ok {
+		return errUnknownSource
+	}
+	m.buffer(s, pcm)
+	return nil
+}
+
+// WriteFrame decodes an encoded frame of a source, like the payload of an
+// Opus packet, and buffers its PCM for the next frames
+func (m *Mixer) WriteFrame(ssrc uint32, frame []byte) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	s, ok := m.sources[ssrc]
+	if !ok {
+		return errUnknownSource
+	} else if s.decoder == nil {
+		return errNoDecoder
+	}
+
+	if s.decoded == nil {
+		s.decoded = make([]int16, m.decodedSize)
+	}
+	n, err := s.decoder.Decode(frame, s.decoded)
+	if err != nil {
+		return err
+	} else if n < 0 || n*m.channels > len(s.decoded) {
+		return errDecodedSize
+	}
+	m.buffer(s, s.decoded[:n*m.channels])
+	return nil
+}
+
+// buffer appends the PCM to the buffer of the source, dropping the oldest
+// PCM beyond maxBufferedFrames
+// Note: the caller should hold the lock.
+func (m *Mixer) buffer(s *source, pcm []int16) {
+	s.buffer = append(s.buffer, pcm...)
+	if max := m.channels * m.frameSize * maxBufferedFrames; len(s.buffer) > max {
+		s.buffer = append(s.buffer[:0], s.buffer[len(s.buffer)-max:]...)
+	}
+}
+
+// Mix mixes the next frame of every source and returns it encoded. Sources
+// which buffered less than a frame are padded with silence. The sample lists
+// the sources heard in the frame as its contributing sources, the loudest
+// first.
+func (m *Mixer) Mix() (media.RTCSample, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	for i := range m.mixed {
+		m.mixed[i] = 0
+	}
+
+	type contribution struct {
+		ssrc   uint32
+		energy float64
+	}
+	var heard []contribution
+
+	for _, s := range m.sources {
+		n := len(s.buffer)
+		if n > len(m.mixed) {
+			n = len(m.mixed)
+		}
+
+		var energy float64
+		for i, sample := range s.buffer[:n] {
+			scaled := clip(float64(sample) * s.gain)
+			m.mixed[i] += int32(scaled)
+			energy += float64(scaled) * float64(scaled)
+		}
+		s.buffer = append(s.buffer[:0], s.buffer[n:]...)
+
+		if energy > 0 {
+			heard = append(heard, contribution{s.ssrc, energy})
+		}
+	}
+
+	for i, sample := range m.mixed {
+		m.pcm[i] = clip(float64(sample))
+	}
+
+	n, err := m.encoder.Encode(m.pcm, m.encoded)
+	if err != nil {
+		return media.RTCSample{}, err
+	} else if n < 0 || n > len(m.encoded) {
+		return media.RTCSample{}, errEncodedSize
+	}
+
+	sample := media.RTCSample{
+		Data:    append([]byte(nil), m.encoded[:n]...),
+		Samples: uint32(m.frameSize),
+	}
+	sort.Slice(heard, func(i, j int) bool { return heard[i].energy > heard[j].energy })
+	for _, c := range heard {
+		sample.CSRC = append(sample.CSRC, c.ssrc)
+	}
+	return sample, nil
+}
+
+// clip returns the sample clipped to 16 bits
+func clip(sample float64) int16 {
+	switch {
+	case sample > math.MaxInt16:
+		return math.MaxInt16
+	case sample < math.MinInt16:
+		return math.MinInt16
+	default:
+		return int16(sample)
+	}
+}
+
+// Run mixes a frame every FrameDuration and writes it to samples, usually
+// the Samples of the outgoing track, until done is closed. It returns the
+// error of a frame which failed to encode.
+func (m *Mixer) Run(samples chan<- media.RTCSample, done <-chan struct{}) error {
+	ticker := time.NewTicker(m.duration)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-done:
+			return nil
+		case <-ticker.C:
+		}
+
+		sample, err := m.Mix()
+		if err != nil {
+			return err
+		}
+		select {
+		case samples <- sample:
+		case <-done:
+			return nil
+		}
+	}
+}
@@ -0,0 +1,172 @@
+package mixer
+
+import (
+	"encoding/binary"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/pions/webrtc/pkg/media"
+	"github.com/stretchr/testify/assert"
+)
+
+// pcmCodec encodes PCM as little endian bytes, and decodes it back
+type pcmCodec struct{}
+
+func (pcmCodec) Encode(pcm []int16, data []byte) (int, error) {
+	for i, sample := range pcm {
+		binary.LittleEndian.PutUint16(data[i*2:], uint16(sample))
+	}
+	return len(pcm) * 2, nil
+}
+
+func (pcmCodec) Decode(frame []byte, pcm []int16) (int, error) {
+	if len(frame)%2 != 0 {
+		return 0, errors.New("odd frame")
+	}
+	for i := 0; i < len(frame)/2; i++ {
+		pcm[i] = int16(binary.LittleEndian.Uint16(frame[i*2:]))
+	}
+	return len(frame) / 2, nil
+}
+
+// oversizedCodec claims to have written more than its buffers hold
+type oversizedCodec struct{}
+
+func (oversizedCodec) Encode(pcm []int16, data []byte) (int, error) {
+	return len(data) + 1, nil
+}
+
+func (oversizedCodec) Decode(frame []byte, pcm []int16) (int, error) {
+	return len(pcm) + 1, nil
+}
+
+func decodeSample(t *testing.T, sample media.RTCSample) []int16 {
+	pcm := make([]int16, len(sample.Data)/2)
+	_, err := pcmCodec{}.Decode(sample.Data, pcm)
+	assert.Nil(t, err)
+	return pcm
+}
+
+func constant(value int16, n int) []int16 {
+	pcm := make([]int16, n)
+	for i := range pcm {
+		pcm[i] = value
+	}
+	return pcm
+}
+
+func TestMixer_Mix(t *testing.T) {
+	// Frames of 4 samples
+	m, err := New(Config{SampleRate: 400, FrameDuration: 10 * time.Millisecond, Encoder: pcmCodec{}})
+	assert.Nil(t, err)
+
+	assert.Nil(t, m.AddSource(1, nil))
+	assert.Nil(t, m.AddSource(2, pcmCodec{}))
+	assert.Nil(t, m.AddSource(3, nil))
+	assert.Equal(t, errSourceExists, m.AddSource(1, nil))
+
+	assert.Nil(t, m.WritePCM(1, []int16{100, 200, 300, 400, 500}))
+	assert.Nil(t, m.WriteFrame(2, []byte{0xe8, 0x03, 0xe8, 0x03})) // 1000, 1000
+	assert.Nil(t, m.SetGain(3, 0.5))
+	assert.Nil(t, m.WritePCM(3, constant(30000, 4)))
+
+	// Sources are summed and padded with silence, the loudest is listed first
+	sample, err := m.Mix()
+	assert.Nil(t, err)
+	assert.Equal(t, uint32(4), sample.Samples)
+	assert.Equal(t, []int16{16100, 16200, 15300, 15400}, decodeSample(t, sample))
+	assert.Equal(t, []uint32{3, 2, 1}, sample.CSRC)
+
+	// The PCM left over is mixed in the next frame
+	sample, err = m.Mix()
+	assert.Nil(t, err)
+	assert.Equal(t, []int16{500, 0, 0, 0}, decodeSample(t, sample))
+	assert.Equal(t, []uint32{1}, sample.CSRC)
+
+	assert.Nil(t, m.SetGain(3, 2))
+	assert.Nil(t, m.WritePCM(3, constant(30000, 4)))
+	sample, err = m.Mix()
+	assert.Nil(t, err)
+	// The sum is clipped
+	assert.Equal(t, []int16{32767, 32767, 32767, 32767}, decodeSample(t, sample))
+
+	// Muted sources aren't listed
+	assert.Nil(t, m.SetGain(3, 0))
+	assert.Nil(t, m.WritePCM(3, constant(30000, 4)))
+	sample, err = m.Mix()
+	assert.Nil(t, err)
+	assert.Equal(t, []int16{0, 0, 0, 0}, decodeSample(t, sample))
+	assert.Empty(t, sample.CSRC)
+
+	m.RemoveSource(1)
+	assert.Equal(t, errUnknownSource, m.WritePCM(1, []int16{1}))
+	assert.Equal(t, errUnknownSource, m.SetGain(1, 1))
+	assert.Equal(t, errNoDecoder, m.WriteFrame(3, []byte{0x00, 0x00}))
+	assert.Error(t, m.WriteFrame(2, []byte{0x00}))
+	assert.Equal(t, errInvalidGain, m.SetGain(2, -1))
+}
+
+func TestMixer_Buffer(t *testing.T) {
+	m, err := New(Config{SampleRate: 400, FrameDuration: 10 * time.Millisecond, Encoder: pcmCodec{}})
+	assert.Nil(t, err)
+	assert.Nil(t, m.AddSource(1, nil))
+
+	// A source writing ahead of the mix keeps its latest PCM
+	for i := 0; i < maxBufferedFrames+2; i++ {
+		assert.Nil(t, m.WritePCM(1, constant(int16(i), 4)))
+	}
+	sample, err := m.Mix()
+	assert.Nil(t, err)
+	assert.Equal(t, constant(2, 4), decodeSample(t, sample))
+}
+
+func TestMixer_Run(t *testing.T) {
+	m, err := New(Config{Encoder: pcmCodec{}})
+	assert.Nil(t, err)
+	assert.Nil(t, m.AddSource(1, nil))
+	assert.Nil(t, m.WritePCM(1, constant(1, 960)))
+
+	samples := make(chan media.RTCSample)
+	done := make(chan struct{})
+	result := make(chan error)
+	go func() { result <- m.Run(samples, done) }()
+
+	// 20ms of 48kHz mono
+	select {
+	case sample := <-samples:
+		assert.Equal(t, uint32(960), sample.Samples)
+		assert.Equal(t, constant(1, 960), decodeSample(t, sample))
+		assert.Equal(t, []uint32{1}, sample.CSRC)
+	case <-time.After(time.Second):
+		t.Fatal("no sample mixed")
+	}
+
+	close(done)
+	select {
+	case err := <-result:
+		assert.Nil(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("Run didn't return")
+	}
+}
+
+func TestMixer_OversizedCodec(t *testing.T) {
+	m, err := New(Config{SampleRate: 400, FrameDuration: 10 * time.Millisecond, Encoder: oversizedCodec{}})
+	assert.Nil(t, err)
+
+	assert.Nil(t, m.AddSource(1, oversizedCodec{}))
+	assert.Equal(t, errDecodedSize, m.WriteFrame(1, []byte{0x00, 0x00}))
+
+	_, err = m.Mix()
+	assert.Equal(t, errEncodedSize, err)
+}
+
+func TestNew(t *testing.T) {
+	_, err := New(Config{})
+	assert.Equal(t, errNoEncoder, err)
+	_, err = New(Config{SampleRate: -1, Encoder: pcmCodec{}})
+	assert.Equal(t, errInvalidFormat, err)
+	_, err = New(Config{FrameDuration: time.Microsecond, Encoder: pcmCodec{}})
+	assert.Equal(t, errInvalidFormat, err)
+}
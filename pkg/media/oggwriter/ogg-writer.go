@@ -0,0 +1,285 @@
+// Package oggwriter writes the Opus packets carried by RTP packets to an OGG
+// file, as described in https://tools.ietf.org/html/rfc7845
+package oggwriter
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"math/rand"
+	"os"
+
+	"github.com/pions/webrtc/pkg/rtp"
+	"github.com/pions/webrtc/pkg/rtp/codecs"
+)
+
+var (
+	errFileNotOpened = errors.New("oggwriter: file not opened")
+	errPacketTooLong = errors.New("oggwriter: packet doesn't fit in a page")
+)
+
+const (
+	pageHeaderSignature = "OggS"
+	pageHeaderSize      = 27
+
+	pageHeaderTypeBeginning = 0x02
+	pageHeaderTypeEnd       = 0x04
+
+	// A page holds up to 255 segments of up to 255 bytes, a segment shorter
+	// than 255 bytes ends the packet
+	maxSegments    = 255
+	maxSegmentSize = 255
+
+	idPageSignature      = "OpusHead"
+	commentPageSignature = "OpusTags"
+	vendorString         = "pion"
+
+	// Opus always counts the granule position in samples of 48kHz
+	opusClockRate = 48000
+)
+
+// OggWriter is used to take RTP packets and write them to an OGG on disk
+type OggWriter struct {
+	stream io.Writer
+	fd     *os.File
+
+	sampleRate   uint32
+	channelCount uint8
+	serial       uint32
+	pageIndex    uint32
+
+	// granulePosition is the amount of samples in the pages written so far.
+	// The last page is held back in pending until the next one is written
+	// or the writer is closed, so it can be flagged as the end of the stream.
+	granulePosition uint64
+	pending         []byte
+}
+
+// New builds a new OGG Opus writer, the sample rate is the one the media was
+// recorded at and the channel count the amount of channels of the stream
+func New(fileName string, sampleRate uint32, channelCount uint8) (*OggWriter, error) {
+	f, err := os.Create(fileName)
+	if err != nil {
+		return nil, err
+	}
+
+	writer, err := NewWith(f, sampleRate, channelCount)
+	if err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+	writer.fd = f
+	return writer, nil
+}
+
+// NewWith builds a new OGG Opus writer writing to the stream
+func NewWith(out io.Writer, sampleRate uint32, channelCount uint8) (*OggWriter, error) {
+	if out == nil {
+		return nil, errFileNotOpened
+	}
+
+	writer := &OggWriter{
+		stream:       out,
+		sampleRate:   sampleRate,
+		channelCount: channelCount,
+		serial:       rand.Uint32(),
+	}
+	if err := writer.writeHeaders(); err != nil {
+		return nil, err
+	}
+	return writer, nil
+}
+
+// https://tools.ietf.org/html/rfc7845#section-5
+func (i *OggWriter) writeHeaders() error {
+	// ID header
+	idHeader := make([]byte, 19)
+	copy(idHeader[0:], idPageSignature)                        // Magic signature
+	idHeader[8] = 1                                            // Version
+	idHeader[9] = i.channelCount                               // Channel count
+	binary.LittleEndian.PutUint16(idHeader[10:], 0)            // Pre-skip
+	binary.LittleEndian.PutUint32(idHeader[12:], i.sampleRate) // Input sample rate
+	binary.LittleEndian.PutUint16(idHeader[16:], 0)            // Output gain
+	idHeader[18] = 0                                           // Channel mapping family
+
+	page, err := i.createPage(idHeader, pageHeaderTypeBeginning)
+	if err != nil {
+		return err
+	} else if _, err = i.stream.Write(page); err != nil {
+		return err
+	}
+
+	// Comment header
+	commentHeader := make([]byte, 8+4+len(vendorString)+4)
+	copy(commentHeader[0:], commentPageSignature)                               // Magic signature
+	binary.LittleEndian.PutUint32(commentHeader[8:], uint32(len(vendorString))) // Vendor length
+	copy(commentHeader[12:], vendorString)                                      // Vendor string
+	binary.LittleEndian.PutUint32(commentHeader[12+len(vendorString):], 0)      // User comment list length
+
+	if page, err = i.createPage(commentHeader, 0); err != nil {
+		return err
+	}
+	_, err = i.stream.Write(page)
+	return err
+}
+
+// WriteRTP adds a new packet and writes it as a page of the file
+func (i *OggWriter) WriteRTP(packet *rtp.Packet) error {
+	if i.stream == nil {
+		return errFileNotOpened
+	}
+
+	opusPacket := codecs.OpusPacket{}
+	if _, err := opusPacket.Unmarshal(packet); err != nil {
+		return err
+	} else if len(opusPacket.Payload) == 0 {
+		// Discontinuous transmission sends empty packets during silence
+		return nil
+	}
+
+	i.granulePosition += uint64(opusSamples(opusPacket.Payload))
+	page, err := i.createPage(opusPacket.Payload, 0)
+	if err != nil {
+		return err
+	}
+
+	if err := i.flush(); err != nil {
+		return err
+	}
+	i.pending = page
+	return nil
+}
+
+// Close writes the last page, flagged as the end of the stream, and closes
+// the file created by New
+func (i *OggWriter) Close() error {
+	if i.stream == nil {
+		return nil
+	}
+	defer func() {
+		i.stream = nil
+		i.fd = nil
+	}()
+
+	if i.pending != nil {
+		i.pending[5] |= pageHeaderTypeEnd
+		updateChecksum(i.pending)
+	}
+	if err := i.flush(); err != nil {
+		if i.fd != nil {
+			_ = i.fd.Close()
+		}
+		return err
+	}
+
+	if i.fd == nil {
+		return nil
+	}
+	return i.fd.Close()
+}
+
+func (i *OggWriter) flush() error {
+	if i.pending == nil {
+		return nil
+	}
+
+	_, err := i.stream.Write(i.pending)
+	i.pending = nil
+	return err
+}
+
+// createPage builds the next page of the stream holding the packet
+// https://tools.ietf.org/html/rfc3533#section-6
+func (i *OggWriter) createPage(payload []byte, headerType uint8) ([]byte, error) {
+	segments := len(payload)/maxSegmentSize + 1
+	if segments > maxSegments {
+		return nil, errPacketTooLong
+	}
+
+	page := make([]byte, pageHeaderSize+segments+len(payload))
+	copy(page[0:], pageHeaderSignature)                        // Capture pattern
+	page[4] = 0                                                // Version
+	page[5] = headerType                                       // Header type
+	binary.LittleEndian.PutUint64(page[6:], i.granulePosition) // Granule position
+	binary.LittleEndian.PutUint32(page[14:], i.serial)         // Bitstream serial number
+	binary.LittleEndian.PutUint32(page[18:], i.pageIndex)      // Page sequence number
+	page[26] = uint8(segments)                                 // Page segments
+	for s := 0; s < segments-1; s++ {
+		page[pageHeaderSize+s] = maxSegmentSize
+	}
+	page[pageHeaderSize+segments-1] = uint8(len(payload) % maxSegmentSize)
+	copy(page[pageHeaderSize+segments:], payload)
+
+	updateChecksum(page)
+	i.pageIndex++
+	return page, nil
+}
+
+// updateChecksum writes the checksum of the page, which covers the whole
+// page with the checksum field zeroed
+func updateChecksum(page []byte) {
+	binary.LittleEndian.PutUint32(page[22:], 0)
+	binary.LittleEndian.PutUint32(page[22:], checksum(page))
+}
+
+// checksumTable is the CRC-32 with the polynomial 0x04c11db7 of every byte,
+// computed MSB first without reflection as Ogg requires
+var checksumTable = func() *[256]uint32 {
+	var table [256]uint32
+	const poly = 0x04c11db7
+
+	for i := range table {
+		r := uint32(i) << 24
+		for j := 0; j < 8; j++ {
+			if (r & 0x80000000) != 0 {
+				r = (r << 1) ^ poly
+			} else {
+				r <<= 1
+			}
+		}
+		table[i] = r
+	}
+	return &table
+}()
+
+func checksum(b []byte) uint32 {
+	var crc uint32
+	for _, v := range b {
+		crc = (crc << 8) ^ checksumTable[byte(crc>>24)^v]
+	}
+	return crc
+}
+
+// opusSamples returns the amount of samples at 48kHz in an Opus packet, from
+// the configuration and the frame count of its TOC byte
+// https://tools.ietf.org/html/rfc6716#section-3.1
+func opusSamples(packet []byte) uint32 {
+	toc := packet[0]
+	config := toc >> 3
+
+	// The frame sizes of the configurations, in units of 2.5ms
+	var frameSize uint32
+	switch {
+	case config < 12: // SILK
+		frameSize = []uint32{4, 8, 16, 24}[config%4]
+	case config < 16: // Hybrid
+		frameSize = []uint32{4, 8}[config%2]
+	default: // CELT
+		frameSize = []uint32{1, 2, 4, 8}[config%4]
+	}
+
+	var frames uint32
+	switch toc & 0x03 {
+	case 0:
+		frames = 1
+	case 1, 2:
+		frames = 2
+	case 3:
+		if len(packet) < 2 {
+			return 0
+		}
+		frames = uint32(packet[1] & 0x3f)
+	}
+
+	return frames * frameSize * opusClockRate / 400
+}
@@ -0,0 +1,81 @@
+package oggwriter
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+
+	"github.com/pions/webrtc/pkg/rtp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestChecksum(t *testing.T) {
+	// CRC-32/CKSUM check value without its final inversion
+	assert.Equal(t, uint32(0x89a1897f), checksum([]byte("123456789")))
+}
+
+func TestOpusSamples(t *testing.T) {
+	testCases := []struct {
+		packet   []byte
+		expected uint32
+	}{
+		{[]byte{0xf8}, 960},              // CELT 20ms, one frame
+		{[]byte{0xf9}, 1920},             // CELT 20ms, two frames
+		{[]byte{0xe3, 0x03}, 360},        // CELT 2.5ms, three frames
+		{[]byte{0x18}, 2880},             // SILK 60ms
+		{[]byte{0x78}, 960},              // Hybrid 20ms
+		{[]byte{0x03}, 0},                // Missing frame count
+		{[]byte{0x0b, 0x83, 0x00}, 2880}, // SILK 20ms, three frames
+	}
+
+	for i, testCase := range testCases {
+		assert.Equal(t, testCase.expected, opusSamples(testCase.packet), "testCase: %d", i)
+	}
+}
+
+func TestOggWriter_WriteRTP(t *testing.T) {
+	buffer := &bytes.Buffer{}
+	writer, err := NewWith(buffer, 48000, 2)
+	assert.Nil(t, err)
+
+	assert.Nil(t, writer.WriteRTP(&rtp.Packet{Payload: []byte{0xf8, 0x01}}))
+	assert.Nil(t, writer.WriteRTP(&rtp.Packet{Payload: []byte{}}))
+	assert.Nil(t, writer.WriteRTP(&rtp.Packet{Payload: append([]byte{0xf8}, make([]byte, 254)...)}))
+	assert.Nil(t, writer.Close())
+	assert.Equal(t, errFileNotOpened, writer.WriteRTP(&rtp.Packet{Payload: []byte{0xf8}}))
+
+	expected := []struct {
+		headerType uint8
+		granule    uint64
+		segments   []byte
+		payload    []byte
+	}{
+		{pageHeaderTypeBeginning, 0, []byte{19}, []byte("OpusHead")},
+		{0, 0, []byte{20}, []byte("OpusTags")},
+		{0, 960, []byte{2}, []byte{0xf8, 0x01}},
+		{pageHeaderTypeEnd, 1920, []byte{255, 0}, []byte{0xf8}},
+	}
+
+	file := buffer.Bytes()
+	serial := binary.LittleEndian.Uint32(file[14:])
+	for i, page := range expected {
+		assert.Equal(t, pageHeaderSignature, string(file[0:4]), "testCase: %d", i)
+		assert.Equal(t, page.headerType, file[5], "testCase: %d", i)
+		assert.Equal(t, page.granule, binary.LittleEndian.Uint64(file[6:]), "testCase: %d", i)
+		assert.Equal(t, serial, binary.LittleEndian.Uint32(file[14:]), "testCase: %d", i)
+		assert.Equal(t, uint32(i), binary.LittleEndian.Uint32(file[18:]), "testCase: %d", i)
+		assert.Equal(t, page.segments, file[27:27+file[26]], "testCase: %d", i)
+
+		size := pageHeaderSize + int(file[26])
+		for _, segment := range page.segments {
+			size += int(segment)
+		}
+		assert.Equal(t, page.payload, file[pageHeaderSize+int(file[26]):][:len(page.payload)], "testCase: %d", i)
+
+		crc := binary.LittleEndian.Uint32(file[22:])
+		binary.LittleEndian.PutUint32(file[22:], 0)
+		assert.Equal(t, checksum(file[:size]), crc, "testCase: %d", i)
+		file = file[size:]
+	}
+	assert.Empty(t, file)
+}
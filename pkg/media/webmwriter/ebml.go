@@ -0,0 +1,103 @@
+package webmwriter
+
+import (
+	"encoding/binary"
+	"math"
+)
+
+// The EBML and Matroska element IDs used by WebM
+// https://www.matroska.org/technical/specs/index.html
+const (
+	idEBML               = 0x1a45dfa3
+	idEBMLVersion        = 0x4286
+	idEBMLReadVersion    = 0x42f7
+	idEBMLMaxIDLength    = 0x42f2
+	idEBMLMaxSizeLength  = 0x42f3
+	idDocType            = 0x4282
+	idDocTypeVersion     = 0x4287
+	idDocTypeReadVersion = 0x4285
+
+	idSegment       = 0x18538067
+	idInfo          = 0x1549a966
+	idTimecodeScale = 0x2ad7b1
+	idMuxingApp     = 0x4d80
+	idWritingApp    = 0x5741
+
+	idTracks            = 0x1654ae6b
+	idTrackEntry        = 0xae
+	idTrackNumber       = 0xd7
+	idTrackUID          = 0x73c5
+	idTrackType         = 0x83
+	idCodecID           = 0x86
+	idCodecPrivate      = 0x63a2
+	idCodecDelay        = 0x56aa
+	idSeekPreRoll       = 0x56bb
+	idVideo             = 0xe0
+	idPixelWidth        = 0xb0
+	idPixelHeight       = 0xba
+	idAudio             = 0xe1
+	idSamplingFrequency = 0xb5
+	idChannels          = 0x9f
+
+	idCluster     = 0x1f43b675
+	idTimecode    = 0xe7
+	idSimpleBlock = 0xa3
+)
+
+// unknownSize marks an element whose size isn't known when it is written
+var unknownSize = []byte{0x01, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}
+
+// ebmlID encodes an element ID, the leading bits of IDs already hold their
+// length
+func ebmlID(id uint32) []byte {
+	b := make([]byte, 4)
+	binary.BigEndian.PutUint32(b, id)
+	for len(b) > 1 && b[0] == 0 {
+		b = b[1:]
+	}
+	return b
+}
+
+// ebmlSize encodes the size of an element as a variable length integer, in
+// as few bytes as possible
+func ebmlSize(size uint64) []byte {
+	length := 1
+	for length < 8 && size >= 1<<(7*uint(length))-1 {
+		length++
+	}
+
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint64(b, size|1<<(7*uint(length)))
+	return b[8-length:]
+}
+
+// ebmlElement encodes an element holding the data, master elements hold the
+// concatenation of their children
+func ebmlElement(id uint32, data ...[]byte) []byte {
+	var payload []byte
+	for _, d := range data {
+		payload = append(payload, d...)
+	}
+
+	element := append(ebmlID(id), ebmlSize(uint64(len(payload)))...)
+	return append(element, payload...)
+}
+
+func ebmlUint(id uint32, v uint64) []byte {
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint64(b, v)
+	for len(b) > 1 && b[0] == 0 {
+		b = b[1:]
+	}
+	return ebmlElement(id, b)
+}
+
+func ebmlFloat(id uint32, v float64) []byte {
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint64(b, math.Float64bits(v))
+	return ebmlElement(id, b)
+}
+
+func ebmlString(id uint32, v string) []byte {
+	return ebmlElement(id, []byte(v))
+}
@@ -0,0 +1,32 @@
+package webmwriter
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEBMLSize(t *testing.T) {
+	testCases := []struct {
+		size     uint64
+		expected []byte
+	}{
+		{0, []byte{0x80}},
+		{126, []byte{0xfe}},
+		{127, []byte{0x40, 0x7f}},
+		{16382, []byte{0x7f, 0xfe}},
+		{16383, []byte{0x20, 0x3f, 0xff}},
+	}
+
+	for i, testCase := range testCases {
+		assert.Equal(t, testCase.expected, ebmlSize(testCase.size), "testCase: %d", i)
+	}
+}
+
+func TestEBMLElement(t *testing.T) {
+	assert.Equal(t, []byte{0xd7, 0x81, 0x01}, ebmlUint(idTrackNumber, 1))
+	assert.Equal(t, []byte{0x2a, 0xd7, 0xb1, 0x83, 0x0f, 0x42, 0x40}, ebmlUint(idTimecodeScale, 1000000))
+	assert.Equal(t, []byte{0x42, 0x82, 0x84, 'w', 'e', 'b', 'm'}, ebmlString(idDocType, "webm"))
+	assert.Equal(t, []byte{0xb5, 0x88, 0x40, 0xe7, 0x70, 0, 0, 0, 0, 0}, ebmlFloat(idSamplingFrequency, 48000))
+	assert.Equal(t, []byte{0xe0, 0x86, 0xb0, 0x81, 0x01, 0xba, 0x81, 0x02}, ebmlElement(idVideo, ebmlUint(idPixelWidth, 1), ebmlUint(idPixelHeight, 2)))
+}
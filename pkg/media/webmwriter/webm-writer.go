@@ -0,0 +1,560 @@
+// Package webmwriter records a VP8 video and an Opus audio track carried by
+// RTP packets to a WebM file. The tracks are synchronized with the RTCP
+// sender reports of their sender, which map the RTP timestamps of every
+// track to a shared wallclock.
+package webmwriter
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/pions/webrtc/pkg/rtcp"
+	"github.com/pions/webrtc/pkg/rtp"
+	"github.com/pions/webrtc/pkg/rtp/codecs"
+)
+
+var (
+	errFileNotOpened = errors.New("webmwriter: file not opened")
+	errShortPacket   = errors.New("webmwriter: packet is too short")
+)
+
+const (
+	videoTrackNumber = 1
+	audioTrackNumber = 2
+
+	vp8ClockRate  = 90000
+	opusClockRate = 48000
+
+	// The timecodes of the file are in milliseconds
+	timecodeScale = time.Millisecond
+
+	// The blocks of a cluster have timecodes relative to the cluster which
+	// fit in 16 bits, a new cluster is started before they overflow
+	maxClusterDuration = 30 * time.Second
+
+	// maxQueueDuration is how much media of a track waits for the sender
+	// reports and for the media of the other track. If it is exceeded the
+	// tracks are written without the sender reports or the other track.
+	maxQueueDuration = 5 * time.Second
+
+	// Opus decoders need 80ms of audio to converge after seeking
+	// https://tools.ietf.org/html/rfc7845#section-4.6
+	opusSeekPreRoll = 80 * time.Millisecond
+
+	// The dimensions declared if the video never starts
+	defaultWidth  = 640
+	defaultHeight = 480
+
+	segmentSizeLength = 8
+)
+
+type frame struct {
+	data      []byte
+	timestamp uint32
+	keyframe  bool
+}
+
+// webmTrack is a track being recorded, its frames are queued until they
+// can be written in order with the frames of the other track
+type webmTrack struct {
+	number    uint64
+	clockRate uint32
+
+	hasSSRC bool
+	ssrc    uint32
+	frames  []*frame
+
+	// The RTP timestamp syncTimestamp was sampled at syncTime on the
+	// wallclock of the sender
+	synced        bool
+	syncTimestamp uint32
+	syncTime      time.Duration
+
+	// The VP8 frame being reassembled from its packets
+	hasSequence   bool
+	lastSequence  uint16
+	frameStarted  bool
+	frameBroken   bool
+	currentFrame  []byte
+	lastTimestamp uint32
+}
+
+func (t *webmTrack) time(f *frame) time.Duration {
+	elapsed := int64(int32(f.timestamp - t.syncTimestamp))
+	return t.syncTime + time.Duration(elapsed*int64(time.Second)/int64(t.clockRate))
+}
+
+// queued returns how much media is waiting to be written
+func (t *webmTrack) queued() time.Duration {
+	if len(t.frames) == 0 {
+		return 0
+	}
+	elapsed := t.frames[len(t.frames)-1].timestamp - t.frames[0].timestamp
+	return time.Duration(uint64(elapsed) * uint64(time.Second) / uint64(t.clockRate))
+}
+
+// WebMWriter is used to take the RTP packets of a video and an audio track
+// and write them to a WebM on disk
+type WebMWriter struct {
+	sync.Mutex
+
+	stream  io.Writer
+	fd      *os.File
+	written int64
+
+	audioChannels uint8
+	video, audio  *webmTrack
+
+	// reports are the first sender reports of SSRCs whose track has not
+	// received a packet yet
+	reports map[uint32]*rtcp.SenderReport
+
+	started    bool
+	start      time.Duration
+	lastTime   time.Duration
+	segmentPos int64
+
+	hasCluster  bool
+	clusterTime time.Duration
+	cluster     []byte
+}
+
+// New builds a new WebM writer, the channel count is the amount of channels
+// of the audio track
+func New(fileName string, audioChannels uint8) (*WebMWriter, error) {
+	f, err := os.Create(fileName)
+	if err != nil {
+		return nil, err
+	}
+
+	writer, err := NewWith(f, audioChannels)
+	if err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+	writer.fd = f
+	return writer, nil
+}
+
+// NewWith builds a new WebM writer writing to the stream. If the stream is
+// an io.WriteSeeker the size of the segment is written on Close, otherwise
+// it is left unknown like in a live stream.
+func NewWith(out io.Writer, audioChannels uint8) (*WebMWriter, error) {
+	if out == nil {
+		return nil, errFileNotOpened
+	}
+
+	return &WebMWriter{
+		stream:        out,
+		audioChannels: audioChannels,
+		video:         &webmTrack{number: videoTrackNumber, clockRate: vp8ClockRate},
+		audio:         &webmTrack{number: audioTrackNumber, clockRate: opusClockRate},
+		reports:       make(map[uint32]*rtcp.SenderReport),
+	}, nil
+}
+
+// WriteSenderReport adds a sender report of the video or the audio track,
+// the first report of each track synchronizes it
+func (w *WebMWriter) WriteSenderReport(sr *rtcp.SenderReport) {
+	w.Lock()
+	defer w.Unlock()
+
+	for _, t := range []*webmTrack{w.video, w.audio} {
+		if t.hasSSRC && t.ssrc == sr.SSRC {
+			w.sync(t, sr)
+			return
+		}
+	}
+	if _, ok := w.reports[sr.SSRC]; !ok {
+		w.reports[sr.SSRC] = sr
+	}
+}
+
+// WriteVideoRTP adds a packet of the VP8 video track, its frame is written
+// once all its packets are added and the audio up to it is added
+func (w *WebMWriter) WriteVideoRTP(packet *rtp.Packet) error {
+	w.Lock()
+	defer w.Unlock()
+
+	if w.stream == nil {
+		return errFileNotOpened
+	} else if len(packet.Payload) == 0 {
+		return errShortPacket
+	}
+
+	vp8Packet := codecs.VP8Packet{}
+	if _, err := vp8Packet.Unmarshal(packet); err != nil {
+		return err
+	}
+
+	t := w.video
+	w.identify(t, packet.SSRC)
+
+	// A lost packet breaks the frame it belonged to
+	if t.hasSequence && packet.SequenceNumber != t.lastSequence+1 {
+		t.frameBroken = true
+	}
+	t.hasSequence = true
+	t.lastSequence = packet.SequenceNumber
+
+	if vp8Packet.S == 1 && vp8Packet.PID == 0 {
+		t.frameStarted = true
+		t.frameBroken = false
+		t.currentFrame = nil
+	} else if t.frameStarted && packet.Timestamp != t.lastTimestamp {
+		t.frameBroken = true
+	}
+	t.lastTimestamp = packet.Timestamp
+
+	if !t.frameStarted {
+		return nil
+	}
+	t.currentFrame = append(t.currentFrame, vp8Packet.Payload...)
+
+	if !packet.Marker {
+		return nil
+	}
+	t.frameStarted = false
+	if t.frameBroken || len(t.currentFrame) == 0 {
+		return nil
+	}
+
+	t.frames = append(t.frames, &frame{
+		data:      t.currentFrame,
+		timestamp: packet.Timestamp,
+		keyframe:  t.currentFrame[0]&0x01 == 0,
+	})
+	t.currentFrame = nil
+	return w.drain(false)
+}
+
+// WriteAudioRTP adds a packet of the Opus audio track, it is written once
+// the video up to it is added
+func (w *WebMWriter) WriteAudioRTP(packet *rtp.Packet) error {
+	w.Lock()
+	defer w.Unlock()
+
+	if w.stream == nil {
+		return errFileNotOpened
+	}
+
+	opusPacket := codecs.OpusPacket{}
+	if _, err := opusPacket.Unmarshal(packet); err != nil {
+		return err
+	} else if len(opusPacket.Payload) == 0 {
+		// Discontinuous transmission sends empty packets during silence
+		return nil
+	}
+
+	t := w.audio
+	w.identify(t, packet.SSRC)
+	t.frames = append(t.frames, &frame{
+		data:      append([]byte{}, opusPacket.Payload...),
+		timestamp: packet.Timestamp,
+		keyframe:  true,
+	})
+	return w.drain(false)
+}
+
+// Close writes the queued media and closes the file created by New
+func (w *WebMWriter) Close() error {
+	w.Lock()
+	defer w.Unlock()
+
+	if w.stream == nil {
+		return nil
+	}
+	defer func() {
+		w.stream = nil
+		w.fd = nil
+	}()
+
+	err := w.drain(true)
+	if err == nil {
+		err = w.flushCluster()
+	}
+	if seeker, ok := w.stream.(io.WriteSeeker); ok && err == nil && w.started {
+		err = w.writeSegmentSize(seeker)
+	}
+
+	if w.fd == nil {
+		return err
+	} else if closeErr := w.fd.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// identify learns the SSRC of the track from its first packet, a sender
+// report may have arrived before it
+func (w *WebMWriter) identify(t *webmTrack, ssrc uint32) {
+	if t.hasSSRC {
+		return
+	}
+	t.hasSSRC = true
+	t.ssrc = ssrc
+
+	if sr, ok := w.reports[ssrc]; ok {
+		w.sync(t, sr)
+		delete(w.reports, ssrc)
+	}
+}
+
+func (w *WebMWriter) sync(t *webmTrack, sr *rtcp.SenderReport) {
+	if t.synced {
+		return
+	}
+	t.synced = true
+	t.syncTimestamp = sr.RTPTime
+	t.syncTime = ntpTime(sr.NTPTime)
+}
+
+// ntpTime converts a 32.32 fixed point NTP timestamp
+func ntpTime(ntp uint64) time.Duration {
+	seconds := time.Duration(ntp>>32) * time.Second
+	return seconds + time.Duration((ntp&0xffffffff)*uint64(time.Second)>>32)
+}
+
+// drain writes the queued frames in the order of their time. A frame is
+// only written once the other track has a later one, unless too much media
+// of its track is queued or the writer is closing.
+func (w *WebMWriter) drain(force bool) error {
+	force = force || w.video.queued() > maxQueueDuration || w.audio.queued() > maxQueueDuration
+
+	if !w.started {
+		if ok, err := w.begin(force); !ok || err != nil {
+			return err
+		}
+	}
+
+	// A track starting without sender reports after the file did starts now
+	for _, t := range []*webmTrack{w.video, w.audio} {
+		if !t.synced && len(t.frames) != 0 {
+			t.synced = true
+			t.syncTimestamp = t.frames[0].timestamp
+			t.syncTime = w.start + w.lastTime
+		}
+	}
+
+	for {
+		var t *webmTrack
+		switch {
+		case len(w.video.frames) != 0 && len(w.audio.frames) != 0:
+			t = w.video
+			if w.audio.time(w.audio.frames[0]) < w.video.time(w.video.frames[0]) {
+				t = w.audio
+			}
+		case force && len(w.video.frames) != 0:
+			t = w.video
+		case force && len(w.audio.frames) != 0:
+			t = w.audio
+		default:
+			return nil
+		}
+
+		f := t.frames[0]
+		t.frames = t.frames[1:]
+		if err := w.writeBlock(t, f); err != nil {
+			return err
+		}
+	}
+}
+
+// begin writes the header of the file once both tracks are synchronized and
+// the video has a keyframe to start with. If forced, tracks without sender
+// reports are aligned to the start of the other track.
+func (w *WebMWriter) begin(force bool) (bool, error) {
+	for len(w.video.frames) != 0 && !w.video.frames[0].keyframe {
+		w.video.frames = w.video.frames[1:]
+	}
+
+	if !force && (!w.video.synced || !w.audio.synced || len(w.video.frames) == 0) {
+		return false, nil
+	}
+	if force {
+		w.alignUnsynced(w.video, w.audio)
+		w.alignUnsynced(w.audio, w.video)
+	}
+
+	width, height := uint64(defaultWidth), uint64(defaultHeight)
+	switch {
+	case len(w.video.frames) != 0:
+		w.start = w.video.time(w.video.frames[0])
+		if frameWidth, frameHeight, ok := vp8KeyframeSize(w.video.frames[0].data); ok {
+			width, height = uint64(frameWidth), uint64(frameHeight)
+		}
+	case len(w.audio.frames) != 0:
+		w.start = w.audio.time(w.audio.frames[0])
+	default:
+		return false, nil
+	}
+
+	if err := w.writeHeader(width, height); err != nil {
+		return false, err
+	}
+	w.started = true
+	return true, nil
+}
+
+// alignUnsynced maps the first queued frame of a track without sender
+// reports to the time of the first queued frame of the other track
+func (w *WebMWriter) alignUnsynced(t, other *webmTrack) {
+	if t.synced || len(t.frames) == 0 {
+		return
+	}
+
+	t.synced = true
+	t.syncTimestamp = t.frames[0].timestamp
+	if other.synced && len(other.frames) != 0 {
+		t.syncTime = other.time(other.frames[0])
+	} else if other.synced {
+		t.syncTime = other.syncTime
+	}
+}
+
+func (w *WebMWriter) writeHeader(width, height uint64) error {
+	header := ebmlElement(idEBML,
+		ebmlUint(idEBMLVersion, 1),
+		ebmlUint(idEBMLReadVersion, 1),
+		ebmlUint(idEBMLMaxIDLength, 4),
+		ebmlUint(idEBMLMaxSizeLength, 8),
+		ebmlString(idDocType, "webm"),
+		ebmlUint(idDocTypeVersion, 2),
+		ebmlUint(idDocTypeReadVersion, 2),
+	)
+
+	header = append(header, ebmlID(idSegment)...)
+	w.segmentPos = w.written + int64(len(header))
+	header = append(header, unknownSize...)
+
+	header = append(header, ebmlElement(idInfo,
+		ebmlUint(idTimecodeScale, uint64(timecodeScale)),
+		ebmlString(idMuxingApp, "pion"),
+		ebmlString(idWritingApp, "pion"),
+	)...)
+
+	header = append(header, ebmlElement(idTracks,
+		ebmlElement(idTrackEntry,
+			ebmlUint(idTrackNumber, videoTrackNumber),
+			ebmlUint(idTrackUID, videoTrackNumber),
+			ebmlUint(idTrackType, 1),
+			ebmlString(idCodecID, "V_VP8"),
+			ebmlElement(idVideo,
+				ebmlUint(idPixelWidth, width),
+				ebmlUint(idPixelHeight, height),
+			),
+		),
+		ebmlElement(idTrackEntry,
+			ebmlUint(idTrackNumber, audioTrackNumber),
+			ebmlUint(idTrackUID, audioTrackNumber),
+			ebmlUint(idTrackType, 2),
+			ebmlString(idCodecID, "A_OPUS"),
+			ebmlElement(idCodecPrivate, w.opusHead()),
+			ebmlUint(idCodecDelay, 0),
+			ebmlUint(idSeekPreRoll, uint64(opusSeekPreRoll)),
+			ebmlElement(idAudio,
+				ebmlFloat(idSamplingFrequency, opusClockRate),
+				ebmlUint(idChannels, uint64(w.audioChannels)),
+			),
+		),
+	)...)
+
+	return w.write(header)
+}
+
+// opusHead is the identification header of the Opus stream
+// https://tools.ietf.org/html/rfc7845#section-5.1
+func (w *WebMWriter) opusHead() []byte {
+	head := make([]byte, 19)
+	copy(head[0:], "OpusHead")                              // Magic signature
+	head[8] = 1                                             // Version
+	head[9] = w.audioChannels                               // Channel count
+	binary.LittleEndian.PutUint16(head[10:], 0)             // Pre-skip
+	binary.LittleEndian.PutUint32(head[12:], opusClockRate) // Input sample rate
+	binary.LittleEndian.PutUint16(head[16:], 0)             // Output gain
+	head[18] = 0                                            // Channel mapping family
+	return head
+}
+
+// writeBlock adds the frame to the current cluster, starting a new cluster
+// on video keyframes. Frames before the start of the file are dropped.
+func (w *WebMWriter) writeBlock(t *webmTrack, f *frame) error {
+	frameTime := t.time(f) - w.start
+	if frameTime < 0 {
+		return nil
+	} else if frameTime < w.lastTime {
+		// The frames of a track that fell behind can't go back in time
+		frameTime = w.lastTime
+	}
+	w.lastTime = frameTime
+
+	if !w.hasCluster || (t == w.video && f.keyframe) || frameTime-w.clusterTime > maxClusterDuration {
+		if err := w.flushCluster(); err != nil {
+			return err
+		}
+		w.hasCluster = true
+		w.clusterTime = frameTime
+		w.cluster = ebmlUint(idTimecode, uint64(frameTime/timecodeScale))
+	}
+
+	block := make([]byte, 4, 4+len(f.data))
+	block[0] = 0x80 | uint8(t.number)                                                             // Track number
+	binary.BigEndian.PutUint16(block[1:], uint16(int16((frameTime-w.clusterTime)/timecodeScale))) // Timecode
+	if f.keyframe {
+		block[3] = 0x80 // Flags
+	}
+	block = append(block, f.data...)
+
+	w.cluster = append(w.cluster, ebmlElement(idSimpleBlock, block)...)
+	return nil
+}
+
+func (w *WebMWriter) flushCluster() error {
+	if !w.hasCluster {
+		return nil
+	}
+
+	w.hasCluster = false
+	err := w.write(ebmlElement(idCluster, w.cluster))
+	w.cluster = nil
+	return err
+}
+
+func (w *WebMWriter) writeSegmentSize(seeker io.WriteSeeker) error {
+	if _, err := seeker.Seek(w.segmentPos, io.SeekStart); err != nil {
+		return err
+	}
+
+	size := make([]byte, segmentSizeLength)
+	binary.BigEndian.PutUint64(size, uint64(w.written-w.segmentPos-segmentSizeLength)|1<<56)
+	if _, err := seeker.Write(size); err != nil {
+		return err
+	}
+
+	_, err := seeker.Seek(0, io.SeekEnd)
+	return err
+}
+
+func (w *WebMWriter) write(b []byte) error {
+	n, err := w.stream.Write(b)
+	w.written += int64(n)
+	return err
+}
+
+// vp8KeyframeSize returns the dimensions of a VP8 keyframe
+// https://tools.ietf.org/html/rfc6386#section-9.1
+func vp8KeyframeSize(frame []byte) (width, height uint16, ok bool) {
+	if len(frame) < 10 || frame[0]&0x01 != 0 {
+		return 0, 0, false
+	} else if frame[3] != 0x9d || frame[4] != 0x01 || frame[5] != 0x2a {
+		return 0, 0, false
+	}
+
+	width = binary.LittleEndian.Uint16(frame[6:]) & 0x3fff
+	height = binary.LittleEndian.Uint16(frame[8:]) & 0x3fff
+	return width, height, true
+}
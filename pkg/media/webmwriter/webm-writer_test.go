@@ -0,0 +1,202 @@
+package webmwriter
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/pions/webrtc/pkg/rtcp"
+	"github.com/pions/webrtc/pkg/rtp"
+	"github.com/stretchr/testify/assert"
+)
+
+type webmBlock struct {
+	track    uint8
+	time     time.Duration
+	keyframe bool
+	data     []byte
+}
+
+type webmFile struct {
+	width, height uint64
+	segmentSize   uint64
+	blocks        []webmBlock
+}
+
+// readVint reads a variable length integer, keeping the length marker for
+// element IDs
+func readVint(t *testing.T, b []byte, keepMarker bool) (uint64, int) {
+	length := 1
+	for length <= 8 && b[0]&(0x80>>uint(length-1)) == 0 {
+		length++
+	}
+	assert.True(t, length <= 8)
+
+	v := uint64(b[0])
+	if !keepMarker {
+		v &= 0xff >> uint(length)
+	}
+	for _, c := range b[1:length] {
+		v = v<<8 | uint64(c)
+	}
+	return v, length
+}
+
+func readUint(b []byte) uint64 {
+	var v uint64
+	for _, c := range b {
+		v = v<<8 | uint64(c)
+	}
+	return v
+}
+
+func parseWebM(t *testing.T, b []byte, file *webmFile, cluster time.Duration) {
+	for len(b) != 0 {
+		id, idLength := readVint(t, b, true)
+		size, sizeLength := readVint(t, b[idLength:], false)
+		b = b[idLength+sizeLength:]
+		if id == idSegment {
+			file.segmentSize = size
+		}
+		if size == 1<<56-1 {
+			size = uint64(len(b))
+		}
+		data := b[:size]
+		b = b[size:]
+
+		switch id {
+		case idSegment:
+			parseWebM(t, data, file, 0)
+		case idTracks, idTrackEntry, idVideo:
+			parseWebM(t, data, file, 0)
+		case idCluster:
+			parseWebM(t, data, file, 0)
+		case idTimecode:
+			cluster = time.Duration(readUint(data)) * time.Millisecond
+		case idPixelWidth:
+			file.width = readUint(data)
+		case idPixelHeight:
+			file.height = readUint(data)
+		case idSimpleBlock:
+			file.blocks = append(file.blocks, webmBlock{
+				track:    data[0] & 0x7f,
+				time:     cluster + time.Duration(int16(binary.BigEndian.Uint16(data[1:])))*time.Millisecond,
+				keyframe: data[3]&0x80 != 0,
+				data:     data[4:],
+			})
+		}
+	}
+}
+
+var (
+	vp8Keyframe = []byte{0x00, 0x00, 0x00, 0x9d, 0x01, 0x2a, 0x40, 0x01, 0xf0, 0x00}
+	vp8Delta    = []byte{0x01, 0x02, 0x03}
+)
+
+func vp8Packet(sequence uint16, timestamp uint32, start, marker bool, frame []byte) *rtp.Packet {
+	descriptor := byte(0x00)
+	if start {
+		descriptor = 0x10
+	}
+	return &rtp.Packet{SSRC: 1, SequenceNumber: sequence, Timestamp: timestamp, Marker: marker, Payload: append([]byte{descriptor}, frame...)}
+}
+
+func TestWebMWriter_Synchronization(t *testing.T) {
+	tmp, err := ioutil.TempFile("", "webmwriter")
+	assert.Nil(t, err)
+	defer func() {
+		assert.Nil(t, os.Remove(tmp.Name()))
+	}()
+	assert.Nil(t, tmp.Close())
+
+	writer, err := New(tmp.Name(), 2)
+	assert.Nil(t, err)
+
+	// The video timestamp 90000 was sampled at 1000s, the audio timestamp
+	// 48000 at 1000.5s
+	writer.WriteSenderReport(&rtcp.SenderReport{SSRC: 2, NTPTime: 1000<<32 | 1<<31, RTPTime: 48000})
+	writer.WriteSenderReport(&rtcp.SenderReport{SSRC: 1, NTPTime: 1000 << 32, RTPTime: 90000})
+
+	// A keyframe split in two packets, a frame missing a packet, and a
+	// frame every 100ms
+	assert.Nil(t, writer.WriteVideoRTP(vp8Packet(10, 90000, true, false, vp8Keyframe[:5])))
+	assert.Nil(t, writer.WriteVideoRTP(vp8Packet(11, 90000, false, true, vp8Keyframe[5:])))
+	assert.Nil(t, writer.WriteVideoRTP(vp8Packet(12, 99000, true, false, vp8Delta)))
+	assert.Nil(t, writer.WriteVideoRTP(vp8Packet(14, 99000, false, true, vp8Delta)))
+	for i := uint16(2); i < 10; i++ {
+		assert.Nil(t, writer.WriteVideoRTP(vp8Packet(13+i, 90000+uint32(i)*9000, true, true, vp8Delta)))
+	}
+
+	// Audio every 20ms from 999.9s, the frames before the video starts are
+	// dropped
+	for i := uint32(0); i < 75; i++ {
+		assert.Nil(t, writer.WriteAudioRTP(&rtp.Packet{SSRC: 2, Timestamp: 19200 + i*960, Payload: []byte{0xf8, byte(i)}}))
+	}
+	assert.Nil(t, writer.Close())
+	assert.Nil(t, writer.Close())
+	assert.Equal(t, errFileNotOpened, writer.WriteAudioRTP(&rtp.Packet{Payload: []byte{0xf8}}))
+
+	raw, err := ioutil.ReadFile(tmp.Name())
+	assert.Nil(t, err)
+
+	file := &webmFile{}
+	parseWebM(t, raw, file, 0)
+	assert.Equal(t, uint64(320), file.width)
+	assert.Equal(t, uint64(240), file.height)
+	assert.Equal(t, uint64(len(raw)), file.segmentSize+uint64(bytes.Index(raw, []byte{0x15, 0x49, 0xa9, 0x66})))
+
+	var video, audio []webmBlock
+	var last time.Duration
+	for _, block := range file.blocks {
+		assert.True(t, block.time >= last)
+		last = block.time
+
+		if block.track == videoTrackNumber {
+			video = append(video, block)
+		} else {
+			audio = append(audio, block)
+		}
+	}
+
+	assert.Equal(t, 9, len(video))
+	assert.Equal(t, vp8Keyframe, video[0].data)
+	assert.True(t, video[0].keyframe)
+	assert.Equal(t, time.Duration(0), video[0].time)
+	assert.Equal(t, 200*time.Millisecond, video[1].time)
+	assert.False(t, video[1].keyframe)
+
+	assert.Equal(t, 70, len(audio))
+	assert.Equal(t, []byte{0xf8, 5}, audio[0].data)
+	assert.Equal(t, time.Duration(0), audio[0].time)
+	assert.Equal(t, 1380*time.Millisecond, audio[69].time)
+}
+
+func TestWebMWriter_Unsynchronized(t *testing.T) {
+	buffer := &bytes.Buffer{}
+	writer, err := NewWith(buffer, 2)
+	assert.Nil(t, err)
+
+	// Without sender reports the first frames of the tracks are aligned,
+	// video preceding the first keyframe is dropped
+	assert.Nil(t, writer.WriteVideoRTP(vp8Packet(1, 2000, true, true, vp8Delta)))
+	assert.Nil(t, writer.WriteVideoRTP(vp8Packet(2, 5000, true, true, vp8Keyframe)))
+	assert.Nil(t, writer.WriteAudioRTP(&rtp.Packet{SSRC: 2, Timestamp: 777, Payload: []byte{0xf8}}))
+	assert.Nil(t, writer.WriteAudioRTP(&rtp.Packet{SSRC: 2, Timestamp: 777 + 960, Payload: []byte{0xf8}}))
+	assert.Nil(t, writer.WriteVideoRTP(vp8Packet(3, 8000, true, true, vp8Delta)))
+	assert.Nil(t, writer.Close())
+
+	file := &webmFile{}
+	parseWebM(t, buffer.Bytes(), file, 0)
+	assert.Equal(t, uint64(1<<56-1), file.segmentSize)
+
+	expected := []webmBlock{
+		{videoTrackNumber, 0, true, vp8Keyframe},
+		{audioTrackNumber, 0, true, []byte{0xf8}},
+		{audioTrackNumber, 20 * time.Millisecond, true, []byte{0xf8}},
+		{videoTrackNumber, 33 * time.Millisecond, false, vp8Delta},
+	}
+	assert.Equal(t, expected, file.blocks)
+}
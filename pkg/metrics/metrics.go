@@ -0,0 +1,121 @@
+// Package metrics aggregates the statistics of RTCPeerConnections into
+// process wide gauges and counters, published with expvar. The Metrics of a
+// Snapshot are plain values a Prometheus or other collector can be fed from.
+package metrics
+
+import (
+	"expvar"
+	"sync"
+
+	"github.com/pions/webrtc"
+)
+
+// Metrics are the gauges and counters of the RTCPeerConnections of a
+// Collector. The counters include the totals of the closed ones, they
+// never decrease.
+type Metrics struct {
+	// Connections is the number of RTCPeerConnections not closed yet, and
+	// ICEConnectionStates their number per ICE connection state
+	Connections         int            `json:"connections"`
+	ICEConnectionStates map[string]int `json:"iceConnectionStates"`
+
+	BytesSent     uint64 `json:"bytesSent"`
+	BytesReceived uint64 `json:"bytesReceived"`
+
+	SRTPAuthFailures uint64 `json:"srtpAuthFailures"`
+
+	DataChannelMessagesSent     uint64 `json:"dataChannelMessagesSent"`
+	DataChannelMessagesReceived uint64 `json:"dataChannelMessagesReceived"`
+}
+
+// counters are the counters of the Metrics of one RTCPeerConnection
+type counters struct {
+	bytesSent, bytesReceived                             uint64
+	srtpAuthFailures                                     uint64
+	dataChannelMessagesSent, dataChannelMessagesReceived uint64
+}
+
+func (c *counters) add(o counters) {
+	c.bytesSent += o.bytesSent
+	c.bytesReceived += o.bytesReceived
+	c.srtpAuthFailures += o.srtpAuthFailures
+	c.dataChannelMessagesSent += o.dataChannelMessagesSent
+	c.dataChannelMessagesReceived += o.dataChannelMessagesReceived
+}
+
+// countersOf reads the counters of the stats report of a RTCPeerConnection
+func countersOf(report webrtc.RTCStatsReport) counters {
+	var c counters
+	for _, s := range report {
+		switch stats := s.(type) {
+		case *webrtc.RTCTransportStats:
+			c.bytesSent += stats.BytesSent
+			c.bytesReceived += stats.BytesReceived
+			c.srtpAuthFailures += stats.SRTPAuthFailures
+		case *webrtc.RTCDataChannelStats:
+			c.dataChannelMessagesSent += stats.MessagesSent
+			c.dataChannelMessagesReceived += stats.MessagesReceived
+		}
+	}
+	return c
+}
+
+// Collector aggregates the metrics of the RTCPeerConnections added to it
+type Collector struct {
+	lock        sync.Mutex
+	connections map[*webrtc.RTCPeerConnection]struct{}
+	closed      counters
+}
+
+// NewCollector creates a Collector without RTCPeerConnections
+func NewCollector() *Collector {
+	return &Collector{connections: make(map[*webrtc.RTCPeerConnection]struct{})}
+}
+
+// Add starts collecting the metrics of the RTCPeerConnection. Once it is
+// closed its final counters are kept and it is forgotten.
+func (c *Collector) Add(pc *webrtc.RTCPeerConnection) {
+	c.lock.Lock()
+	c.connections[pc] = struct{}{}
+	c.lock.Unlock()
+
+	go func() {
+		<-pc.Done()
+		final := countersOf(pc.GetStats())
+
+		c.lock.Lock()
+		defer c.lock.Unlock()
+		delete(c.connections, pc)
+		c.closed.add(final)
+	}()
+}
+
+// Snapshot returns the current metrics of the RTCPeerConnections
+func (c *Collector) Snapshot() Metrics {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	m := Metrics{
+		Connections:         len(c.connections),
+		ICEConnectionStates: make(map[string]int),
+	}
+	total := c.closed
+	for pc := range c.connections {
+		m.ICEConnectionStates[pc.ICEConnectionState().String()]++
+		total.add(countersOf(pc.GetStats()))
+	}
+
+	m.BytesSent, m.BytesReceived = total.bytesSent, total.bytesReceived
+	m.SRTPAuthFailures = total.srtpAuthFailures
+	m.DataChannelMessagesSent = total.dataChannelMessagesSent
+	m.DataChannelMessagesReceived = total.dataChannelMessagesReceived
+	return m
+}
+
+// Publish publishes the Snapshot of the Collector with expvar under the
+// name, like pion_webrtc. expvar panics if the name is already published.
+func (c *Collector) Publish(name string) {
+	expvar.Publish(name, expvar.Func(func() interface{} {
+		return c.Snapshot()
+	}))
+}
@@ -0,0 +1,71 @@
+package metrics
+
+import (
+	"encoding/json"
+	"expvar"
+	"testing"
+	"time"
+
+	"github.com/pions/webrtc"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCollector(t *testing.T) {
+	pc, err := webrtc.NewAPI(webrtc.WithMediaEngine(nil)).NewRTCPeerConnection(webrtc.RTCConfiguration{})
+	assert.NoError(t, err)
+
+	c := NewCollector()
+	assert.Equal(t, Metrics{ICEConnectionStates: map[string]int{}}, c.Snapshot())
+
+	c.Add(pc)
+	m := c.Snapshot()
+	assert.Equal(t, 1, m.Connections)
+	assert.Equal(t, map[string]int{"New": 1}, m.ICEConnectionStates)
+	assert.Equal(t, uint64(0), m.BytesSent)
+
+	assert.NoError(t, pc.Close())
+	for start := time.Now(); c.Snapshot().Connections != 0; time.Sleep(time.Millisecond) {
+		if time.Since(start) > time.Second {
+			t.Fatal("timed out waiting for the closed connection to be forgotten")
+		}
+	}
+	assert.Empty(t, c.Snapshot().ICEConnectionStates)
+}
+
+func TestCollector_countersOf(t *testing.T) {
+	report := webrtc.RTCStatsReport{
+		"RTCTransport_0":      &webrtc.RTCTransportStats{BytesSent: 10, BytesReceived: 20, SRTPAuthFailures: 1},
+		"RTCDataChannel_1":    &webrtc.RTCDataChannelStats{MessagesSent: 2, MessagesReceived: 3},
+		"RTCDataChannel_3":    &webrtc.RTCDataChannelStats{MessagesSent: 4, MessagesReceived: 5},
+		"RTCIceCandidatePair": &webrtc.RTCIceCandidatePairStats{BytesSent: 10, BytesReceived: 20},
+	}
+	assert.Equal(t, counters{
+		bytesSent:                   10,
+		bytesReceived:               20,
+		srtpAuthFailures:            1,
+		dataChannelMessagesSent:     6,
+		dataChannelMessagesReceived: 8,
+	}, countersOf(report))
+
+	// Closed connections are kept in the totals
+	c := NewCollector()
+	c.closed.add(countersOf(report))
+	m := c.Snapshot()
+	assert.Equal(t, 0, m.Connections)
+	assert.Equal(t, uint64(6), m.DataChannelMessagesSent)
+	assert.Equal(t, uint64(1), m.SRTPAuthFailures)
+}
+
+func TestCollector_Publish(t *testing.T) {
+	c := NewCollector()
+	c.closed.dataChannelMessagesSent = 7
+	c.Publish("pion_webrtc_test")
+
+	v := expvar.Get("pion_webrtc_test")
+	if !assert.NotNil(t, v) {
+		return
+	}
+	var m Metrics
+	assert.NoError(t, json.Unmarshal([]byte(v.String()), &m))
+	assert.Equal(t, uint64(7), m.DataChannelMessagesSent)
+}
@@ -0,0 +1,51 @@
+// Package pcap mirrors the decrypted packets of a RTCPeerConnection for
+// debugging, see SettingEngine.SetPacketCapture, and writes them to pcap
+// files Wireshark reads, without the DTLS and SRTP keys.
+package pcap
+
+import (
+	"net"
+	"time"
+)
+
+// Protocol is the protocol of a captured packet
+type Protocol int
+
+const (
+	// ProtocolRTP indicates a decrypted RTP packet
+	ProtocolRTP Protocol = iota + 1
+
+	// ProtocolRTCP indicates a decrypted RTCP compound packet
+	ProtocolRTCP
+
+	// ProtocolSCTP indicates a SCTP packet carried by DTLS
+	ProtocolSCTP
+)
+
+func (p Protocol) String() string {
+	switch p {
+	case ProtocolRTP:
+		return "RTP"
+	case ProtocolRTCP:
+		return "RTCP"
+	case ProtocolSCTP:
+		return "SCTP"
+	default:
+		return "Unknown"
+	}
+}
+
+// Packet is a packet sent or received, as it was before it was encrypted or
+// after it was decrypted
+type Packet struct {
+	Timestamp time.Time
+	Protocol  Protocol
+
+	// Inbound is set for the packets received, Source is then the address
+	// of the remote peer
+	Inbound     bool
+	Source      *net.UDPAddr
+	Destination *net.UDPAddr
+
+	Payload []byte
+}
@@ -0,0 +1,195 @@
+package pcap
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"os"
+	"sync"
+)
+
+var (
+	errFileNotOpened   = errors.New("pcap: file not opened")
+	errMissingAddress  = errors.New("pcap: packet is missing an address")
+	errMixedAddresses  = errors.New("pcap: packet mixes IPv4 and IPv6 addresses")
+	errPacketTooLarge  = errors.New("pcap: packet is too large")
+	errUnknownProtocol = errors.New("pcap: unknown protocol")
+)
+
+// https://wiki.wireshark.org/Development/LibpcapFileFormat
+const (
+	pcapMagic        = 0xa1b2c3d4
+	pcapVersionMajor = 2
+	pcapVersionMinor = 4
+	pcapSnapLen      = 65535
+
+	// linkTypeRaw frames the packets with nothing but an IPv4 or IPv6
+	// header, the version of the header tells them apart
+	linkTypeRaw = 101
+
+	pcapFileHeaderSize   = 24
+	pcapRecordHeaderSize = 16
+
+	ipv4HeaderSize = 20
+	ipv6HeaderSize = 40
+	udpHeaderSize  = 8
+
+	ipProtocolUDP  = 17
+	ipProtocolSCTP = 132
+	ipTTL          = 64
+)
+
+// Writer writes captured packets to a pcap file. RTP and RTCP packets are
+// framed with synthetic IP and UDP headers of the addresses of the packet,
+// Wireshark decodes them once RTP is enabled for the ports with Decode As or
+// the rtp_udp heuristic. SCTP packets are framed with an IP header alone,
+// like SCTP is carried natively.
+type Writer struct {
+	lock   sync.Mutex
+	stream io.Writer
+	fd     *os.File
+}
+
+// New creates the pcap file and a Writer writing to it
+func New(fileName string) (*Writer, error) {
+	f, err := os.Create(fileName)
+	if err != nil {
+		return nil, err
+	}
+
+	writer, err := NewWith(f)
+	if err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+	writer.fd = f
+	return writer, nil
+}
+
+// NewWith writes the pcap file header to the stream and builds a Writer
+// writing to it
+func NewWith(out io.Writer) (*Writer, error) {
+	if out == nil {
+		return nil, errFileNotOpened
+	}
+
+	header := make([]byte, pcapFileHeaderSize)
+	binary.LittleEndian.PutUint32(header[0:], pcapMagic)        // Magic number
+	binary.LittleEndian.PutUint16(header[4:], pcapVersionMajor) // Major version
+	binary.LittleEndian.PutUint16(header[6:], pcapVersionMinor) // Minor version
+	binary.LittleEndian.PutUint32(header[8:], 0)                // GMT offset
+	binary.LittleEndian.PutUint32(header[12:], 0)               // Timestamp accuracy
+	binary.LittleEndian.PutUint32(header[16:], pcapSnapLen)     // Snapshot length
+	binary.LittleEndian.PutUint32(header[20:], linkTypeRaw)     // Link type
+
+	if _, err := out.Write(header); err != nil {
+		return nil, err
+	}
+	return &Writer{stream: out}, nil
+}
+
+// WritePacket writes the packet as a record of the file, it can be called
+// from several goroutines, like by a SettingEngine.SetPacketCapture handler
+func (w *Writer) WritePacket(p Packet) error {
+	data, err := frame(p)
+	if err != nil {
+		return err
+	}
+
+	record := make([]byte, pcapRecordHeaderSize, pcapRecordHeaderSize+len(data))
+	binary.LittleEndian.PutUint32(record[0:], uint32(p.Timestamp.Unix()))            // Seconds
+	binary.LittleEndian.PutUint32(record[4:], uint32(p.Timestamp.Nanosecond()/1000)) // Microseconds
+	binary.LittleEndian.PutUint32(record[8:], uint32(len(data)))                     // Captured length
+	binary.LittleEndian.PutUint32(record[12:], uint32(len(data)))                    // Original length
+	record = append(record, data...)
+
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	if w.stream == nil {
+		return errFileNotOpened
+	}
+	_, err = w.stream.Write(record)
+	return err
+}
+
+// Close closes the file created by New, the packets written after are
+// rejected
+func (w *Writer) Close() error {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	fd := w.fd
+	w.stream, w.fd = nil, nil
+	if fd == nil {
+		return nil
+	}
+	return fd.Close()
+}
+
+// frame prefixes the payload of the packet with the IP header, and the UDP
+// header unless it is SCTP
+func frame(p Packet) ([]byte, error) {
+	if p.Source == nil || p.Destination == nil {
+		return nil, errMissingAddress
+	}
+
+	var transport []byte
+	var protocol byte
+	switch p.Protocol {
+	case ProtocolRTP, ProtocolRTCP:
+		protocol = ipProtocolUDP
+		transport = make([]byte, udpHeaderSize, udpHeaderSize+len(p.Payload))
+		binary.BigEndian.PutUint16(transport[0:], uint16(p.Source.Port))
+		binary.BigEndian.PutUint16(transport[2:], uint16(p.Destination.Port))
+		binary.BigEndian.PutUint16(transport[4:], uint16(udpHeaderSize+len(p.Payload)))
+		binary.BigEndian.PutUint16(transport[6:], 0) // No checksum
+		transport = append(transport, p.Payload...)
+	case ProtocolSCTP:
+		protocol = ipProtocolSCTP
+		transport = p.Payload
+	default:
+		return nil, errUnknownProtocol
+	}
+	if len(transport) > pcapSnapLen-ipv6HeaderSize {
+		return nil, errPacketTooLarge
+	}
+
+	src4, dst4 := p.Source.IP.To4(), p.Destination.IP.To4()
+	switch {
+	case src4 != nil && dst4 != nil:
+		header := make([]byte, ipv4HeaderSize, ipv4HeaderSize+len(transport))
+		header[0] = 0x45 // Version 4, 5 words long
+		binary.BigEndian.PutUint16(header[2:], uint16(ipv4HeaderSize+len(transport)))
+		header[8] = ipTTL
+		header[9] = protocol
+		copy(header[12:], src4)
+		copy(header[16:], dst4)
+		binary.BigEndian.PutUint16(header[10:], ipv4Checksum(header))
+		return append(header, transport...), nil
+	case src4 == nil && dst4 == nil && p.Source.IP.To16() != nil && p.Destination.IP.To16() != nil:
+		header := make([]byte, ipv6HeaderSize, ipv6HeaderSize+len(transport))
+		header[0] = 0x60 // Version 6
+		binary.BigEndian.PutUint16(header[4:], uint16(len(transport)))
+		header[6] = protocol
+		header[7] = ipTTL
+		copy(header[8:], p.Source.IP.To16())
+		copy(header[24:], p.Destination.IP.To16())
+		return append(header, transport...), nil
+	default:
+		return nil, errMixedAddresses
+	}
+}
+
+// ipv4Checksum is the one's complement of the one's complement sum of the
+// 16 bit words of the header
+// https://tools.ietf.org/html/rfc791#section-3.1
+func ipv4Checksum(header []byte) uint16 {
+	var sum uint32
+	for i := 0; i+1 < len(header); i += 2 {
+		sum += uint32(binary.BigEndian.Uint16(header[i:]))
+	}
+	for sum > 0xffff {
+		sum = (sum >> 16) + (sum & 0xffff)
+	}
+	return ^uint16(sum)
+}
@@ -0,0 +1,118 @@
+package pcap
+
+import (
+	"bytes"
+	"encoding/binary"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriter_WritePacket(t *testing.T) {
+	buffer := &bytes.Buffer{}
+	writer, err := NewWith(buffer)
+	assert.Nil(t, err)
+	assert.Equal(t, pcapFileHeaderSize, buffer.Len())
+	assert.Equal(t, uint32(linkTypeRaw), binary.LittleEndian.Uint32(buffer.Bytes()[20:]))
+
+	timestamp := time.Unix(1500000000, 123456000)
+	local := &net.UDPAddr{IP: net.ParseIP("10.0.0.1"), Port: 5000}
+	remote := &net.UDPAddr{IP: net.ParseIP("10.0.0.2"), Port: 6000}
+	payload := []byte{0x80, 0x60, 0x00, 0x01}
+
+	testCases := []struct {
+		packet     Packet
+		headerSize int
+		protocol   byte
+	}{
+		{Packet{Timestamp: timestamp, Protocol: ProtocolRTP, Source: local, Destination: remote, Payload: payload}, ipv4HeaderSize + udpHeaderSize, ipProtocolUDP},
+		{Packet{Timestamp: timestamp, Protocol: ProtocolRTCP, Inbound: true, Source: remote, Destination: local, Payload: payload}, ipv4HeaderSize + udpHeaderSize, ipProtocolUDP},
+		{Packet{Timestamp: timestamp, Protocol: ProtocolSCTP, Source: local, Destination: remote, Payload: payload}, ipv4HeaderSize, ipProtocolSCTP},
+	}
+
+	for i, testCase := range testCases {
+		buffer.Reset()
+		assert.Nil(t, writer.WritePacket(testCase.packet), "testCase: %d", i)
+
+		record := buffer.Bytes()
+		assert.Equal(t, uint32(1500000000), binary.LittleEndian.Uint32(record[0:]), "testCase: %d", i)
+		assert.Equal(t, uint32(123456), binary.LittleEndian.Uint32(record[4:]), "testCase: %d", i)
+		assert.Equal(t, uint32(testCase.headerSize+len(payload)), binary.LittleEndian.Uint32(record[8:]), "testCase: %d", i)
+
+		ip := record[pcapRecordHeaderSize:]
+		assert.Equal(t, byte(0x45), ip[0], "testCase: %d", i)
+		assert.Equal(t, testCase.protocol, ip[9], "testCase: %d", i)
+		assert.Equal(t, testCase.packet.Source.IP.To4(), net.IP(ip[12:16]), "testCase: %d", i)
+		assert.Equal(t, testCase.packet.Destination.IP.To4(), net.IP(ip[16:20]), "testCase: %d", i)
+		assert.Equal(t, uint16(0), ipv4Checksum(ip[:ipv4HeaderSize]), "testCase: %d", i)
+		assert.Equal(t, payload, ip[testCase.headerSize:], "testCase: %d", i)
+
+		if testCase.protocol == ipProtocolUDP {
+			udp := ip[ipv4HeaderSize:]
+			assert.Equal(t, uint16(testCase.packet.Source.Port), binary.BigEndian.Uint16(udp[0:]), "testCase: %d", i)
+			assert.Equal(t, uint16(testCase.packet.Destination.Port), binary.BigEndian.Uint16(udp[2:]), "testCase: %d", i)
+		}
+	}
+
+	assert.Nil(t, writer.Close())
+	assert.Equal(t, errFileNotOpened, writer.WritePacket(testCases[0].packet))
+	_, err = NewWith(nil)
+	assert.Equal(t, errFileNotOpened, err)
+}
+
+func TestWriter_WritePacketIPv6(t *testing.T) {
+	buffer := &bytes.Buffer{}
+	writer, err := NewWith(buffer)
+	assert.Nil(t, err)
+	buffer.Reset()
+
+	local := &net.UDPAddr{IP: net.ParseIP("fe80::1"), Port: 5000}
+	remote := &net.UDPAddr{IP: net.ParseIP("fe80::2"), Port: 6000}
+	assert.Nil(t, writer.WritePacket(Packet{Protocol: ProtocolRTP, Source: local, Destination: remote, Payload: []byte{0x80}}))
+
+	ip := buffer.Bytes()[pcapRecordHeaderSize:]
+	assert.Equal(t, byte(0x60), ip[0])
+	assert.Equal(t, uint16(udpHeaderSize+1), binary.BigEndian.Uint16(ip[4:]))
+	assert.Equal(t, byte(ipProtocolUDP), ip[6])
+	assert.Equal(t, local.IP, net.IP(ip[8:24]))
+	assert.Equal(t, remote.IP, net.IP(ip[24:40]))
+}
+
+func TestWriter_WritePacketErrors(t *testing.T) {
+	writer, err := NewWith(&bytes.Buffer{})
+	assert.Nil(t, err)
+
+	v4 := &net.UDPAddr{IP: net.ParseIP("10.0.0.1"), Port: 5000}
+	v6 := &net.UDPAddr{IP: net.ParseIP("fe80::1"), Port: 5000}
+	testCases := []struct {
+		packet Packet
+		err    error
+	}{
+		{Packet{Protocol: ProtocolRTP, Source: v4}, errMissingAddress},
+		{Packet{Protocol: ProtocolRTP, Source: v4, Destination: v6}, errMixedAddresses},
+		{Packet{Protocol: Protocol(0), Source: v4, Destination: v4}, errUnknownProtocol},
+		{Packet{Protocol: ProtocolSCTP, Source: v4, Destination: v4, Payload: make([]byte, pcapSnapLen)}, errPacketTooLarge},
+	}
+
+	for i, testCase := range testCases {
+		assert.Equal(t, testCase.err, writer.WritePacket(testCase.packet), "testCase: %d", i)
+	}
+}
+
+func TestProtocol_String(t *testing.T) {
+	testCases := []struct {
+		protocol       Protocol
+		expectedString string
+	}{
+		{Protocol(0), "Unknown"},
+		{ProtocolRTP, "RTP"},
+		{ProtocolRTCP, "RTCP"},
+		{ProtocolSCTP, "SCTP"},
+	}
+
+	for i, testCase := range testCases {
+		assert.Equal(t, testCase.expectedString, testCase.protocol.String(), "testCase: %d", i)
+	}
+}
@@ -98,3 +98,13 @@ type RangeError struct {
 func (e *RangeError) Error() string {
 	return fmt.Sprintf("RangeError: %v", e.Err)
 }
+
+// InvalidCharacterError indicates a string contains one or more characters
+// which are invalid.
+type InvalidCharacterError struct {
+	Err error
+}
+
+func (e *InvalidCharacterError) Error() string {
+	return fmt.Sprintf("InvalidCharacterError: %v", e.Err)
+}
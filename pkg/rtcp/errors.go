@@ -14,4 +14,6 @@ var (
 	errSDESMissingType  = errors.New("rtcp: sdes item missing type")
 	errReasonTooLong    = errors.New("rtcp: reason must be < 255 octets long")
 	errBadVersion       = errors.New("rtcp: invalid packet version")
+	errTooManyBlocks    = errors.New("rtcp: too many report sub-blocks")
+	errInvalidBlockSize = errors.New("rtcp: invalid report block size")
 )
@@ -0,0 +1,171 @@
+package rtcp
+
+import (
+	"encoding/binary"
+)
+
+// Report block types of extended reports, RFC 3611 Section 4
+const (
+	blockTypeReceiverReferenceTime = 4
+	blockTypeDLRR                  = 5
+
+	blockHeaderLength           = 4
+	receiverReferenceTimeLength = 8
+	dlrrReportLength            = 12
+)
+
+// ExtendedReport is an RTCP extended report, RFC 3611. It carries the
+// receiver reference time and DLRR report blocks, which let a receiver
+// which doesn't send RTP measure its round trip time to a sender. Report
+// blocks of other types are skipped when unmarshaling.
+type ExtendedReport struct {
+	// The SSRC of the originator of the report
+	SenderSSRC uint32
+	// ReceiverReferenceTime is the NTP timestamp of when a receiver sent the
+	// report, zero if the report has no receiver reference time block
+	ReceiverReferenceTime uint64
+	// DLRR answers the receiver reference times received from receivers
+	DLRR []DLRRReport
+}
+
+// DLRRReport answers the last receiver reference time report of a receiver,
+// RFC 3611 Section 4.5
+type DLRRReport struct {
+	// The SSRC of the receiver
+	SSRC uint32
+	// LastRR is the middle 32 bits of the NTP timestamp of the last receiver
+	// reference time report of the receiver
+	LastRR uint32
+	// DLRR is the delay since that report was received, in units of 1/65536
+	// seconds
+	DLRR uint32
+}
+
+// Marshal encodes the ExtendedReport in binary
+func (x ExtendedReport) Marshal() ([]byte, error) {
+	/*
+	 *  0                   1                   2                   3
+	 *  0 1 2 3 4 5 6 7 8 9 0 1 2 3 4 5 6 7 8 9 0 1 2 3 4 5 6 7 8 9 0 1
+	 * +-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+	 * |V=2|P|reserved |   PT=XR=207   |             length            |
+	 * +-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+	 * |                              SSRC                             |
+	 * +-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+	 * :                         report blocks                         :
+	 * +-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+	 */
+	rawPacket := make([]byte, ssrcLength)
+	binary.BigEndian.PutUint32(rawPacket, x.SenderSSRC)
+
+	if x.ReceiverReferenceTime != 0 {
+		/*
+		 *  0                   1                   2                   3
+		 *  0 1 2 3 4 5 6 7 8 9 0 1 2 3 4 5 6 7 8 9 0 1 2 3 4 5 6 7 8 9 0 1
+		 * +-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+		 * |     BT=4      |   reserved    |       block length = 2        |
+		 * +-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+		 * |              NTP timestamp, most significant word             |
+		 * +-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+		 * |             NTP timestamp, least significant word             |
+		 * +-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+		 */
+		block := make([]byte, blockHeaderLength+receiverReferenceTimeLength)
+		block[0] = blockTypeReceiverReferenceTime
+		binary.BigEndian.PutUint16(block[2:], receiverReferenceTimeLength/4)
+		binary.BigEndian.PutUint64(block[blockHeaderLength:], x.ReceiverReferenceTime)
+		rawPacket = append(rawPacket, block...)
+	}
+
+	if len(x.DLRR) > 0 {
+		/*
+		 *  0                   1                   2                   3
+		 *  0 1 2 3 4 5 6 7 8 9 0 1 2 3 4 5 6 7 8 9 0 1 2 3 4 5 6 7 8 9 0 1
+		 * +-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+		 * |     BT=5      |   reserved    |         block length          |
+		 * +=+=+=+=+=+=+=+=+=+=+=+=+=+=+=+=+=+=+=+=+=+=+=+=+=+=+=+=+=+=+=+=+
+		 * |                 SSRC_1 (SSRC of first receiver)               | sub-
+		 * +-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+ block
+		 * |                         last RR (LRR)                         |   1
+		 * +-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+		 * |                   delay since last RR (DLRR)                  |
+		 * +=+=+=+=+=+=+=+=+=+=+=+=+=+=+=+=+=+=+=+=+=+=+=+=+=+=+=+=+=+=+=+=+
+		 * :                               ...                             :   2
+		 * +=+=+=+=+=+=+=+=+=+=+=+=+=+=+=+=+=+=+=+=+=+=+=+=+=+=+=+=+=+=+=+=+
+		 */
+		block := make([]byte, blockHeaderLength+len(x.DLRR)*dlrrReportLength)
+		block[0] = blockTypeDLRR
+		binary.BigEndian.PutUint16(block[2:], uint16(len(x.DLRR)*dlrrReportLength/4))
+		for i, r := range x.DLRR {
+			offset := blockHeaderLength + i*dlrrReportLength
+			binary.BigEndian.PutUint32(block[offset:], r.SSRC)
+			binary.BigEndian.PutUint32(block[offset+4:], r.LastRR)
+			binary.BigEndian.PutUint32(block[offset+8:], r.DLRR)
+		}
+		rawPacket = append(rawPacket, block...)
+	}
+
+	if (headerLength+len(rawPacket))/4-1 > 0xffff {
+		return nil, errTooManyBlocks
+	}
+	h := Header{
+		Type:   TypeExtendedReport,
+		Length: uint16((headerLength+len(rawPacket))/4 - 1),
+	}
+	hData, err := h.Marshal()
+	if err != nil {
+		return nil, err
+	}
+
+	return append(hData, rawPacket...), nil
+}
+
+// Unmarshal decodes the ExtendedReport from binary
+func (x *ExtendedReport) Unmarshal(rawPacket []byte) error {
+	var header Header
+	if err := header.Unmarshal(rawPacket); err != nil {
+		return err
+	}
+
+	if header.Type != TypeExtendedReport {
+		return errWrongType
+	}
+
+	if len(rawPacket) < headerLength+ssrcLength {
+		return errPacketTooShort
+	}
+	x.SenderSSRC = binary.BigEndian.Uint32(rawPacket[headerLength:])
+	x.ReceiverReferenceTime = 0
+	x.DLRR = nil
+
+	for blocks := rawPacket[headerLength+ssrcLength:]; len(blocks) > 0; {
+		if len(blocks) < blockHeaderLength {
+			return errPacketTooShort
+		}
+		blockType, length := blocks[0], int(binary.BigEndian.Uint16(blocks[2:]))*4
+		if len(blocks) < blockHeaderLength+length {
+			return errPacketTooShort
+		}
+		body := blocks[blockHeaderLength : blockHeaderLength+length]
+		blocks = blocks[blockHeaderLength+length:]
+
+		switch blockType {
+		case blockTypeReceiverReferenceTime:
+			if length != receiverReferenceTimeLength {
+				return errInvalidBlockSize
+			}
+			x.ReceiverReferenceTime = binary.BigEndian.Uint64(body)
+		case blockTypeDLRR:
+			if length%dlrrReportLength != 0 {
+				return errInvalidBlockSize
+			}
+			for ; len(body) > 0; body = body[dlrrReportLength:] {
+				x.DLRR = append(x.DLRR, DLRRReport{
+					SSRC:   binary.BigEndian.Uint32(body),
+					LastRR: binary.BigEndian.Uint32(body[4:]),
+					DLRR:   binary.BigEndian.Uint32(body[8:]),
+				})
+			}
+		}
+	}
+	return nil
+}
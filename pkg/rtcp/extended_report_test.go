@@ -0,0 +1,148 @@
+package rtcp
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestExtendedReportUnmarshal(t *testing.T) {
+	for _, test := range []struct {
+		Name      string
+		Data      []byte
+		Want      ExtendedReport
+		WantError error
+	}{
+		{
+			Name: "valid",
+			Data: []byte{
+				// v=2, p=0, XR, len=9
+				0x80, 0xcf, 0x00, 0x09,
+				// ssrc=0x902f9e2e
+				0x90, 0x2f, 0x9e, 0x2e,
+				// bt=4, block length=2
+				0x04, 0x00, 0x00, 0x02,
+				// ntp timestamp=0xe0e7d3e2a0000000
+				0xe0, 0xe7, 0xd3, 0xe2, 0xa0, 0x00, 0x00, 0x00,
+				// bt=5, block length=3
+				0x05, 0x00, 0x00, 0x03,
+				// ssrc=0xbc5e9a40, lrr=0xd3e2a000, dlrr=0x00010000
+				0xbc, 0x5e, 0x9a, 0x40,
+				0xd3, 0xe2, 0xa0, 0x00,
+				0x00, 0x01, 0x00, 0x00,
+			},
+			Want: ExtendedReport{
+				SenderSSRC:            0x902f9e2e,
+				ReceiverReferenceTime: 0xe0e7d3e2a0000000,
+				DLRR:                  []DLRRReport{{SSRC: 0xbc5e9a40, LastRR: 0xd3e2a000, DLRR: 0x00010000}},
+			},
+		},
+		{
+			Name: "unknown block",
+			Data: []byte{
+				// v=2, p=0, XR, len=3
+				0x80, 0xcf, 0x00, 0x03,
+				// ssrc=0x902f9e2e
+				0x90, 0x2f, 0x9e, 0x2e,
+				// bt=7, block length=1
+				0x07, 0x00, 0x00, 0x01,
+				0x00, 0x00, 0x00, 0x00,
+			},
+			Want: ExtendedReport{SenderSSRC: 0x902f9e2e},
+		},
+		{
+			Name: "short block",
+			Data: []byte{
+				// v=2, p=0, XR, len=3
+				0x80, 0xcf, 0x00, 0x03,
+				// ssrc=0x902f9e2e
+				0x90, 0x2f, 0x9e, 0x2e,
+				// bt=4, block length=2
+				0x04, 0x00, 0x00, 0x02,
+				0xe0, 0xe7, 0xd3, 0xe2,
+			},
+			WantError: errPacketTooShort,
+		},
+		{
+			Name: "invalid dlrr length",
+			Data: []byte{
+				// v=2, p=0, XR, len=4
+				0x80, 0xcf, 0x00, 0x04,
+				// ssrc=0x902f9e2e
+				0x90, 0x2f, 0x9e, 0x2e,
+				// bt=5, block length=2
+				0x05, 0x00, 0x00, 0x02,
+				0xbc, 0x5e, 0x9a, 0x40,
+				0xd3, 0xe2, 0xa0, 0x00,
+			},
+			WantError: errInvalidBlockSize,
+		},
+		{
+			Name: "wrong type",
+			Data: []byte{
+				// v=2, p=0, RR, len=1
+				0x80, 0xc9, 0x00, 0x01,
+				// ssrc=0x902f9e2e
+				0x90, 0x2f, 0x9e, 0x2e,
+			},
+			WantError: errWrongType,
+		},
+		{
+			Name:      "nil",
+			Data:      nil,
+			WantError: errInvalidHeader,
+		},
+	} {
+		var xr ExtendedReport
+		err := xr.Unmarshal(test.Data)
+		if got, want := err, test.WantError; got != want {
+			t.Fatalf("Unmarshal %q xr: err = %v, want %v", test.Name, got, want)
+		}
+		if err != nil {
+			continue
+		}
+
+		if got, want := xr, test.Want; !reflect.DeepEqual(got, want) {
+			t.Fatalf("Unmarshal %q xr: got %v, want %v", test.Name, got, want)
+		}
+	}
+}
+
+func TestExtendedReportRoundTrip(t *testing.T) {
+	for _, test := range []struct {
+		Name string
+		XR   ExtendedReport
+	}{
+		{
+			Name: "empty",
+			XR:   ExtendedReport{SenderSSRC: 0x01020304},
+		},
+		{
+			Name: "receiver reference time",
+			XR:   ExtendedReport{SenderSSRC: 0x01020304, ReceiverReferenceTime: 0xe0e7d3e2a0000000},
+		},
+		{
+			Name: "dlrr",
+			XR: ExtendedReport{
+				SenderSSRC: 0x01020304,
+				DLRR: []DLRRReport{
+					{SSRC: 0x05060708, LastRR: 0xd3e2a000, DLRR: 0x00010000},
+					{SSRC: 0x090a0b0c, LastRR: 0xd3e2b000, DLRR: 0x00000100},
+				},
+			},
+		},
+	} {
+		data, err := test.XR.Marshal()
+		if err != nil {
+			t.Fatalf("Marshal %q: %v", test.Name, err)
+		}
+
+		var xr ExtendedReport
+		if err := xr.Unmarshal(data); err != nil {
+			t.Fatalf("Unmarshal %q: %v", test.Name, err)
+		}
+
+		if got, want := xr, test.XR; !reflect.DeepEqual(got, want) {
+			t.Fatalf("%q xr round trip: got %#v, want %#v", test.Name, got, want)
+		}
+	}
+}
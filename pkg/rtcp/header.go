@@ -9,13 +9,14 @@ type PacketType uint8
 
 // RTCP packet types registered with IANA. See: https://www.iana.org/assignments/rtp-parameters/rtp-parameters.xhtml#rtp-parameters-4
 const (
-	TypeSenderReport            PacketType = 200 // RFC 3550, 6.4.1
-	TypeReceiverReport          PacketType = 201 // RFC 3550, 6.4.2
-	TypeSourceDescription       PacketType = 202 // RFC 3550, 6.5
-	TypeGoodbye                 PacketType = 203 // RFC 3550, 6.6
-	TypeApplicationDefined      PacketType = 204 // RFC 3550, 6.7 (unimplemented)
-	TypePayloadSpecificFeedback PacketType = 206 // RFC 4585, 6.3
-
+	TypeSenderReport              PacketType = 200 // RFC 3550, 6.4.1
+	TypeReceiverReport            PacketType = 201 // RFC 3550, 6.4.2
+	TypeSourceDescription         PacketType = 202 // RFC 3550, 6.5
+	TypeGoodbye                   PacketType = 203 // RFC 3550, 6.6
+	TypeApplicationDefined        PacketType = 204 // RFC 3550, 6.7 (unimplemented)
+	TypeTransportSpecificFeedback PacketType = 205 // RFC 4585, 6.2
+	TypePayloadSpecificFeedback   PacketType = 206 // RFC 4585, 6.3
+	TypeExtendedReport            PacketType = 207 // RFC 3611
 )
 
 func (p PacketType) String() string {
@@ -30,8 +31,12 @@ func (p PacketType) String() string {
 		return "BYE"
 	case TypeApplicationDefined:
 		return "APP"
+	case TypeTransportSpecificFeedback:
+		return "RTPFB"
 	case TypePayloadSpecificFeedback:
 		return "PSFB"
+	case TypeExtendedReport:
+		return "XR"
 	default:
 		return string(p)
 	}
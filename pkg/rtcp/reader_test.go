@@ -137,3 +137,44 @@ func TestReadEOF(t *testing.T) {
 		t.Fatalf("read short header: got err = %v, want %v", got, want)
 	}
 }
+
+func TestReadMarshaled(t *testing.T) {
+	packets := []Packet{
+		&SenderReport{SSRC: 0x902f9e2e, NTPTime: 0xda8bd1fcdddda05a, RTPTime: 0xaaf4edd5},
+		&ReceiverReport{SSRC: 0x902f9e2e, Reports: []ReceptionReport{{SSRC: 0xbc5e9a40}}},
+		&SourceDescription{Chunks: []SourceDescriptionChunk{{
+			Source: 0x902f9e2e,
+			Items:  []SourceDescriptionItem{{Type: SDESCNAME, Text: "{9c00eb92-1afb-9d49-a47d-91f64eee69f5}"}},
+		}}},
+		&Goodbye{Sources: []uint32{0x902f9e2e}, Reason: "bye"},
+	}
+
+	var compound []byte
+	for _, p := range packets {
+		data, err := p.Marshal()
+		if err != nil {
+			t.Fatalf("Marshal %T: %v", p, err)
+		}
+		compound = append(compound, data...)
+	}
+
+	// The length in the headers delimits the packets of a compound packet
+	r := NewReader(bytes.NewReader(compound))
+	for _, p := range packets {
+		header, data, err := r.ReadPacket()
+		if err != nil {
+			t.Fatalf("Read %T: %v", p, err)
+		}
+		// The length is in 32-bit words minus one, RFC 3550 Section 6.4.1
+		if got, want := (int(header.Length)+1)*4, len(data); got != want {
+			t.Errorf("Length %T: got %d bytes, want %d", p, got, want)
+		}
+		want, _ := p.Marshal()
+		if !reflect.DeepEqual(data, want) {
+			t.Errorf("Read %T: got %v, want %v", p, data, want)
+		}
+	}
+	if _, _, err := r.ReadPacket(); err != io.EOF {
+		t.Errorf("Read end: got err = %v, want %v", err, io.EOF)
+	}
+}
@@ -0,0 +1,121 @@
+package rtcp
+
+import (
+	"encoding/binary"
+)
+
+// The ReceiverEstimatedMaximumBitrate packet informs the sender about the
+// total bitrate the receiver estimates it can receive on the listed SSRCs
+// https://tools.ietf.org/html/draft-alvestrand-rmcat-remb-03
+type ReceiverEstimatedMaximumBitrate struct {
+	// SSRC of sender
+	SenderSSRC uint32
+
+	// Estimated maximum bitrate in bits per second
+	Bitrate uint64
+
+	// SSRCs the estimate applies to
+	SSRCs []uint32
+}
+
+// FormatREMB is the feedback message type (FMT) of a ReceiverEstimatedMaximumBitrate
+const FormatREMB = 15
+
+const (
+	rembBaseLength    = 16
+	rembMantissaBits  = 18
+	rembMaxExponent   = 63
+	rembMantissaLimit = 1 << rembMantissaBits
+)
+
+var rembIdentifier = []byte{'R', 'E', 'M', 'B'}
+
+// Marshal encodes the ReceiverEstimatedMaximumBitrate in binary
+func (p ReceiverEstimatedMaximumBitrate) Marshal() ([]byte, error) {
+	/*
+	 *  0                   1                   2                   3
+	 *  0 1 2 3 4 5 6 7 8 9 0 1 2 3 4 5 6 7 8 9 0 1 2 3 4 5 6 7 8 9 0 1
+	 * +-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+	 * |                  SSRC of packet sender                        |
+	 * +-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+	 * |                  SSRC of media source (0)                     |
+	 * +-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+	 * |  Unique identifier 'R' 'E' 'M' 'B'                            |
+	 * +-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+	 * |  Num SSRC     | BR Exp    |  BR Mantissa                      |
+	 * +-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+	 * |   SSRC feedback                                               |
+	 * +-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+	 */
+	if len(p.SSRCs) > 0xff {
+		return nil, errTooManySources
+	}
+
+	exp := uint(0)
+	mantissa := p.Bitrate
+	for mantissa >= rembMantissaLimit && exp < rembMaxExponent {
+		mantissa >>= 1
+		exp++
+	}
+	if mantissa >= rembMantissaLimit {
+		mantissa = rembMantissaLimit - 1
+	}
+
+	rawPacket := make([]byte, rembBaseLength+(len(p.SSRCs)*ssrcLength))
+	binary.BigEndian.PutUint32(rawPacket, p.SenderSSRC)
+	copy(rawPacket[8:], rembIdentifier)
+	binary.BigEndian.PutUint32(rawPacket[12:], uint32(len(p.SSRCs))<<24|uint32(exp)<<rembMantissaBits|uint32(mantissa))
+	for i, ssrc := range p.SSRCs {
+		binary.BigEndian.PutUint32(rawPacket[rembBaseLength+(ssrcLength*i):], ssrc)
+	}
+
+	h := Header{
+		Count:  FormatREMB,
+		Type:   TypePayloadSpecificFeedback,
+		Length: uint16(len(rawPacket) / 4),
+	}
+	hData, err := h.Marshal()
+	if err != nil {
+		return nil, err
+	}
+
+	return append(hData, rawPacket...), nil
+}
+
+// Unmarshal decodes the ReceiverEstimatedMaximumBitrate from binary
+func (p *ReceiverEstimatedMaximumBitrate) Unmarshal(rawPacket []byte) error {
+	if len(rawPacket) < (headerLength + rembBaseLength) {
+		return errPacketTooShort
+	}
+
+	var h Header
+	if err := h.Unmarshal(rawPacket); err != nil {
+		return err
+	}
+
+	if h.Type != TypePayloadSpecificFeedback || h.Count != FormatREMB {
+		return errWrongType
+	}
+
+	body := rawPacket[headerLength:]
+	if string(body[8:12]) != string(rembIdentifier) {
+		return errWrongType
+	}
+
+	field := binary.BigEndian.Uint32(body[12:])
+	count := int(field >> 24)
+	exp := uint((field >> rembMantissaBits) & 0x3f)
+	mantissa := uint64(field & (rembMantissaLimit - 1))
+
+	if len(body) < rembBaseLength+(count*ssrcLength) {
+		return errPacketTooShort
+	}
+
+	p.SenderSSRC = binary.BigEndian.Uint32(body)
+	p.Bitrate = mantissa << exp
+	p.SSRCs = make([]uint32, count)
+	for i := range p.SSRCs {
+		p.SSRCs[i] = binary.BigEndian.Uint32(body[rembBaseLength+(ssrcLength*i):])
+	}
+	return nil
+}
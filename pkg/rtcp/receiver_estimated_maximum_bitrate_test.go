@@ -0,0 +1,132 @@
+package rtcp
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestReceiverEstimatedMaximumBitrateUnmarshal(t *testing.T) {
+	for _, test := range []struct {
+		Name      string
+		Data      []byte
+		Want      ReceiverEstimatedMaximumBitrate
+		WantError error
+	}{
+		{
+			Name: "valid",
+			Data: []byte{
+				// v=2, p=0, FMT=15, PSFB, len=5
+				0x8f, 0xce, 0x00, 0x05,
+				// sender=0x902f9e2e
+				0x90, 0x2f, 0x9e, 0x2e,
+				// media=0x0
+				0x00, 0x00, 0x00, 0x00,
+				// 'R' 'E' 'M' 'B'
+				0x52, 0x45, 0x4d, 0x42,
+				// num=1, exp=2, mantissa=1000
+				0x01, 0x08, 0x03, 0xe8,
+				// ssrc=0x4bc4fcb4
+				0x4b, 0xc4, 0xfc, 0xb4,
+			},
+			Want: ReceiverEstimatedMaximumBitrate{
+				SenderSSRC: 0x902f9e2e,
+				Bitrate:    4000,
+				SSRCs:      []uint32{0x4bc4fcb4},
+			},
+		},
+		{
+			Name: "packet too short",
+			Data: []byte{
+				0x00, 0x00, 0x00, 0x00,
+			},
+			WantError: errPacketTooShort,
+		},
+		{
+			Name: "missing ssrc",
+			Data: []byte{
+				// v=2, p=0, FMT=15, PSFB, len=4
+				0x8f, 0xce, 0x00, 0x04,
+				0x90, 0x2f, 0x9e, 0x2e,
+				0x00, 0x00, 0x00, 0x00,
+				0x52, 0x45, 0x4d, 0x42,
+				// num=1, exp=2, mantissa=1000
+				0x01, 0x08, 0x03, 0xe8,
+			},
+			WantError: errPacketTooShort,
+		},
+		{
+			Name: "wrong identifier",
+			Data: []byte{
+				// v=2, p=0, FMT=15, PSFB, len=4
+				0x8f, 0xce, 0x00, 0x04,
+				0x90, 0x2f, 0x9e, 0x2e,
+				0x00, 0x00, 0x00, 0x00,
+				0x41, 0x42, 0x43, 0x44,
+				0x00, 0x08, 0x03, 0xe8,
+			},
+			WantError: errWrongType,
+		},
+	} {
+		var remb ReceiverEstimatedMaximumBitrate
+		err := remb.Unmarshal(test.Data)
+		if got, want := err, test.WantError; got != want {
+			t.Fatalf("Unmarshal %q remb: err = %v, want %v", test.Name, got, want)
+		}
+		if err != nil {
+			continue
+		}
+
+		if got, want := remb, test.Want; !reflect.DeepEqual(got, want) {
+			t.Fatalf("Unmarshal %q remb: got %v, want %v", test.Name, got, want)
+		}
+	}
+}
+
+func TestReceiverEstimatedMaximumBitrateRoundTrip(t *testing.T) {
+	for _, test := range []struct {
+		Name      string
+		Packet    ReceiverEstimatedMaximumBitrate
+		WantError error
+	}{
+		{
+			Name: "valid",
+			Packet: ReceiverEstimatedMaximumBitrate{
+				SenderSSRC: 1,
+				Bitrate:    1 << 20,
+				SSRCs:      []uint32{2, 3},
+			},
+		},
+		{
+			Name: "no ssrcs",
+			Packet: ReceiverEstimatedMaximumBitrate{
+				SenderSSRC: 1,
+				Bitrate:    1000,
+				SSRCs:      []uint32{},
+			},
+		},
+		{
+			Name: "too many ssrcs",
+			Packet: ReceiverEstimatedMaximumBitrate{
+				SSRCs: make([]uint32, 256),
+			},
+			WantError: errTooManySources,
+		},
+	} {
+		data, err := test.Packet.Marshal()
+		if got, want := err, test.WantError; got != want {
+			t.Fatalf("Marshal %q: err = %v, want %v", test.Name, got, want)
+		}
+		if err != nil {
+			continue
+		}
+
+		var decoded ReceiverEstimatedMaximumBitrate
+		if err := decoded.Unmarshal(data); err != nil {
+			t.Fatalf("Unmarshal %q: %v", test.Name, err)
+		}
+
+		if got, want := decoded, test.Packet; !reflect.DeepEqual(got, want) {
+			t.Fatalf("%q remb round trip: got %#v, want %#v", test.Name, got, want)
+		}
+	}
+}
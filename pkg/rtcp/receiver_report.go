@@ -68,7 +68,7 @@ func (r ReceiverReport) Marshal() ([]byte, error) {
 	h := Header{
 		Count:  uint8(len(r.Reports)),
 		Type:   TypeReceiverReport,
-		Length: uint16(headerLength + len(rawPacket)),
+		Length: uint16((headerLength+len(rawPacket))/4 - 1),
 	}
 	hData, err := h.Marshal()
 	if err != nil {
@@ -0,0 +1,119 @@
+package rtcp
+
+import (
+	"encoding/binary"
+)
+
+// PacketBitmap shouldn't be used like a normal integral,
+// so it's type is masked here. Access it with PacketList().
+type PacketBitmap uint16
+
+// NackPair is a wire-representation of a collection of
+// Lost RTP packets
+type NackPair struct {
+	// ID of lost packets
+	PacketID uint16
+
+	// Bitmask of following lost packets
+	LostPackets PacketBitmap
+}
+
+// PacketList returns the sequence numbers of all packets the NackPair
+// reports as lost
+func (n NackPair) PacketList() []uint16 {
+	out := []uint16{n.PacketID}
+	for i := uint16(0); i < 16; i++ {
+		if (n.LostPackets & (1 << i)) != 0 {
+			out = append(out, n.PacketID+i+1)
+		}
+	}
+	return out
+}
+
+// The TransportLayerNack packet informs the encoder about the loss of a transport packet
+// IETF RFC 4585, Section 6.2.1
+// https://tools.ietf.org/html/rfc4585#section-6.2.1
+type TransportLayerNack struct {
+	// SSRC of sender
+	SenderSSRC uint32
+
+	// SSRC of the media source
+	MediaSSRC uint32
+
+	Nacks []NackPair
+}
+
+// FormatTLN is the feedback message type (FMT) of a TransportLayerNack
+const FormatTLN = 1
+
+const (
+	tlnBaseLength = 8
+	nackPairSize  = 4
+)
+
+// Marshal encodes the TransportLayerNack in binary
+func (p TransportLayerNack) Marshal() ([]byte, error) {
+	/*
+	 *  0                   1                   2                   3
+	 *  0 1 2 3 4 5 6 7 8 9 0 1 2 3 4 5 6 7 8 9 0 1 2 3 4 5 6 7 8 9 0 1
+	 * +-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+	 * |            PID                |             BLP               |
+	 * +-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+	 */
+	if len(p.Nacks)+2 > 0xffff {
+		return nil, errTooManyReports
+	}
+
+	rawPacket := make([]byte, tlnBaseLength+(len(p.Nacks)*nackPairSize))
+	binary.BigEndian.PutUint32(rawPacket, p.SenderSSRC)
+	binary.BigEndian.PutUint32(rawPacket[4:], p.MediaSSRC)
+	for i, n := range p.Nacks {
+		binary.BigEndian.PutUint16(rawPacket[tlnBaseLength+(nackPairSize*i):], n.PacketID)
+		binary.BigEndian.PutUint16(rawPacket[tlnBaseLength+(nackPairSize*i)+2:], uint16(n.LostPackets))
+	}
+
+	h := Header{
+		Count:  FormatTLN,
+		Type:   TypeTransportSpecificFeedback,
+		Length: uint16(len(p.Nacks) + 2),
+	}
+	hData, err := h.Marshal()
+	if err != nil {
+		return nil, err
+	}
+
+	return append(hData, rawPacket...), nil
+}
+
+// Unmarshal decodes the TransportLayerNack from binary
+func (p *TransportLayerNack) Unmarshal(rawPacket []byte) error {
+	if len(rawPacket) < (headerLength + (ssrcLength * 2)) {
+		return errPacketTooShort
+	}
+
+	var h Header
+	if err := h.Unmarshal(rawPacket); err != nil {
+		return err
+	}
+
+	if h.Type != TypeTransportSpecificFeedback || h.Count != FormatTLN {
+		return errWrongType
+	}
+
+	packetLength := (int(h.Length) + 1) * 4
+	if len(rawPacket) < packetLength {
+		return errPacketTooShort
+	}
+
+	p.SenderSSRC = binary.BigEndian.Uint32(rawPacket[headerLength:])
+	p.MediaSSRC = binary.BigEndian.Uint32(rawPacket[headerLength+ssrcLength:])
+
+	p.Nacks = nil
+	for i := headerLength + tlnBaseLength; i+nackPairSize <= packetLength; i += nackPairSize {
+		p.Nacks = append(p.Nacks, NackPair{
+			PacketID:    binary.BigEndian.Uint16(rawPacket[i:]),
+			LostPackets: PacketBitmap(binary.BigEndian.Uint16(rawPacket[i+2:])),
+		})
+	}
+	return nil
+}
@@ -0,0 +1,116 @@
+package rtcp
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestTransportLayerNackUnmarshal(t *testing.T) {
+	for _, test := range []struct {
+		Name      string
+		Data      []byte
+		Want      TransportLayerNack
+		WantError error
+	}{
+		{
+			Name: "valid",
+			Data: []byte{
+				// v=2, p=0, FMT=1, RTPFB, len=3
+				0x81, 0xcd, 0x00, 0x03,
+				// sender=0x902f9e2e
+				0x90, 0x2f, 0x9e, 0x2e,
+				// media=0x902f9e2e
+				0x90, 0x2f, 0x9e, 0x2e,
+				// pid=0x05b5, blp=0x0003
+				0x05, 0xb5, 0x00, 0x03,
+			},
+			Want: TransportLayerNack{
+				SenderSSRC: 0x902f9e2e,
+				MediaSSRC:  0x902f9e2e,
+				Nacks:      []NackPair{{PacketID: 0x05b5, LostPackets: 0x0003}},
+			},
+		},
+		{
+			Name: "packet too short",
+			Data: []byte{
+				0x00, 0x00, 0x00, 0x00,
+			},
+			WantError: errPacketTooShort,
+		},
+		{
+			Name: "truncated nack",
+			Data: []byte{
+				// v=2, p=0, FMT=1, RTPFB, len=3
+				0x81, 0xcd, 0x00, 0x03,
+				0x90, 0x2f, 0x9e, 0x2e,
+				0x90, 0x2f, 0x9e, 0x2e,
+			},
+			WantError: errPacketTooShort,
+		},
+		{
+			Name: "wrong type",
+			Data: []byte{
+				// v=2, p=0, FMT=1, PSFB, len=2
+				0x81, 0xce, 0x00, 0x02,
+				0x00, 0x00, 0x00, 0x00,
+				0x4b, 0xc4, 0xfc, 0xb4,
+			},
+			WantError: errWrongType,
+		},
+	} {
+		var tln TransportLayerNack
+		err := tln.Unmarshal(test.Data)
+		if got, want := err, test.WantError; got != want {
+			t.Fatalf("Unmarshal %q tln: err = %v, want %v", test.Name, got, want)
+		}
+		if err != nil {
+			continue
+		}
+
+		if got, want := tln, test.Want; !reflect.DeepEqual(got, want) {
+			t.Fatalf("Unmarshal %q tln: got %v, want %v", test.Name, got, want)
+		}
+	}
+}
+
+func TestTransportLayerNackRoundTrip(t *testing.T) {
+	for _, test := range []struct {
+		Name      string
+		Packet    TransportLayerNack
+		WantError error
+	}{
+		{
+			Name: "valid",
+			Packet: TransportLayerNack{
+				SenderSSRC: 1,
+				MediaSSRC:  2,
+				Nacks:      []NackPair{{PacketID: 1, LostPackets: 0xAA}, {PacketID: 1034, LostPackets: 0x05}},
+			},
+		},
+	} {
+		data, err := test.Packet.Marshal()
+		if got, want := err, test.WantError; got != want {
+			t.Fatalf("Marshal %q: err = %v, want %v", test.Name, got, want)
+		}
+		if err != nil {
+			continue
+		}
+
+		var decoded TransportLayerNack
+		if err := decoded.Unmarshal(data); err != nil {
+			t.Fatalf("Unmarshal %q: %v", test.Name, err)
+		}
+
+		if got, want := decoded, test.Packet; !reflect.DeepEqual(got, want) {
+			t.Fatalf("%q tln round trip: got %#v, want %#v", test.Name, got, want)
+		}
+	}
+}
+
+func TestNackPairPacketList(t *testing.T) {
+	got := NackPair{PacketID: 42, LostPackets: 0x8003}.PacketList()
+	want := []uint16{42, 43, 44, 58}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("PacketList: got %v, want %v", got, want)
+	}
+}
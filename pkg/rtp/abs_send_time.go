@@ -0,0 +1,49 @@
+package rtp
+
+import (
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// absSendTimeLength is the size of the payload of the abs-send-time header
+// extension
+const absSendTimeLength = 3
+
+// AbsSendTime is the time a packet was sent as carried by the abs-send-time
+// header extension, in seconds as a 6.18 fixed point number. It wraps every
+// 64 seconds, receivers compare it with their arrival time to estimate the
+// changes of the one-way delay.
+// https://webrtc.org/experiments/rtp-hdrext/abs-send-time/
+type AbsSendTime uint32
+
+// NewAbsSendTime returns the abs-send-time of the time
+func NewAbsSendTime(t time.Time) AbsSendTime {
+	// NTP and Unix seconds are congruent modulo 64
+	seconds := uint64(t.Unix())
+	fraction := uint64(t.Nanosecond()) << 18 / uint64(time.Second)
+	return AbsSendTime((seconds<<18 | fraction) & 0xFFFFFF)
+}
+
+// Marshal returns the payload of the header extension
+func (t AbsSendTime) Marshal() []byte {
+	return []byte{byte(t >> 16), byte(t >> 8), byte(t)}
+}
+
+// Unmarshal parses the payload of the header extension
+func (t *AbsSendTime) Unmarshal(payload []byte) error {
+	if len(payload) != absSendTimeLength {
+		return errors.Errorf("abs-send-time payload size %d is not %d", len(payload), absSendTimeLength)
+	}
+	*t = AbsSendTime(payload[0])<<16 | AbsSendTime(payload[1])<<8 | AbsSendTime(payload[2])
+	return nil
+}
+
+// Estimate returns the time the packet was sent, the time within 32 seconds
+// of the arrival time whose abs-send-time is t. It is exact if the clocks of
+// the sender and the receiver are synchronized.
+func (t AbsSendTime) Estimate(arrival time.Time) time.Time {
+	// The difference is a signed 24 bit number of 2^-18 seconds
+	diff := int32(uint32(NewAbsSendTime(arrival)-t)<<8) >> 8
+	return arrival.Add(-time.Duration(diff) * time.Second >> 18)
+}
@@ -0,0 +1,28 @@
+package rtp
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAbsSendTime(t *testing.T) {
+	sent := time.Unix(1500000000, 250000000)
+	abs := NewAbsSendTime(sent)
+	assert.Equal(t, AbsSendTime((1500000000%64)<<18|1<<16), abs)
+
+	var parsed AbsSendTime
+	assert.NoError(t, parsed.Unmarshal(abs.Marshal()))
+	assert.Equal(t, abs, parsed)
+	assert.Error(t, parsed.Unmarshal([]byte{0x01}))
+
+	// The estimate is within the resolution of 2^-18 seconds, also across
+	// the wrap every 64 seconds
+	for _, delay := range []time.Duration{0, 10 * time.Millisecond, 20 * time.Second} {
+		for _, s := range []time.Time{sent, time.Unix(1499999999, 999000000)} {
+			estimate := NewAbsSendTime(s).Estimate(s.Add(delay))
+			assert.True(t, estimate.Sub(s) < 4*time.Microsecond && s.Sub(estimate) < 4*time.Microsecond, "delay %v estimate %v", delay, estimate.Sub(s))
+		}
+	}
+}
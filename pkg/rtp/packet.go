@@ -25,8 +25,16 @@ type Packet struct {
 	Payload          []byte
 }
 
+// MaxCSRC is the number of contributing sources an RTP packet can list
+const MaxCSRC = 15
+
 const (
-	headerLength    = 4
+	// extensionProfileOneByte and extensionProfileTwoByte identify the
+	// header extension formats of RFC 8285, the low 4 bits of the two-byte
+	// profile are application bits
+	extensionProfileOneByte = 0xBEDE
+	extensionProfileTwoByte = 0x1000
+
 	versionShift    = 6
 	versionMask     = 0x3
 	paddingShift    = 5
@@ -47,10 +55,30 @@ const (
 	csrcLength      = 4
 )
 
-// Unmarshal parses the passed byte slice and stores the result in the Packet this method is called upon
+// Clone returns a copy of the packet which shares no memory with it, so the
+// copy can be modified or encrypted in place. Raw is left empty, the copy is
+// marshaled anew.
+func (p *Packet) Clone() *Packet {
+	clone := *p
+	clone.Raw = nil
+	if p.CSRC != nil {
+		clone.CSRC = append([]uint32{}, p.CSRC...)
+	}
+	if p.ExtensionPayload != nil {
+		clone.ExtensionPayload = append([]byte{}, p.ExtensionPayload...)
+	}
+	if p.Payload != nil {
+		clone.Payload = append([]byte{}, p.Payload...)
+	}
+	return &clone
+}
+
+// Unmarshal parses the passed byte slice and stores the result in the Packet this method is called upon.
+// Raw, Payload and ExtensionPayload alias rawPacket and the capacity of CSRC is reused, unmarshaling
+// into a Packet taken from a pool doesn't allocate.
 func (p *Packet) Unmarshal(rawPacket []byte) error {
-	if len(rawPacket) < headerLength {
-		return errors.Errorf("RTP header size insufficient; %d < %d", len(rawPacket), headerLength)
+	if len(rawPacket) < csrcOffset {
+		return errors.Errorf("RTP header size insufficient; %d < %d", len(rawPacket), csrcOffset)
 	}
 
 	/*
@@ -71,7 +99,7 @@ func (p *Packet) Unmarshal(rawPacket []byte) error {
 	p.Version = rawPacket[0] >> versionShift & versionMask
 	p.Padding = (rawPacket[0] >> paddingShift & paddingMask) > 0
 	p.Extension = (rawPacket[0] >> extensionShift & extensionMask) > 0
-	p.CSRC = make([]uint32, rawPacket[0]&ccMask)
+	csrcCount := int(rawPacket[0] & ccMask)
 
 	p.Marker = (rawPacket[1] >> markerShift & markerMask) > 0
 	p.PayloadType = rawPacket[1] & ptMask
@@ -80,23 +108,32 @@ func (p *Packet) Unmarshal(rawPacket []byte) error {
 	p.Timestamp = binary.BigEndian.Uint32(rawPacket[timestampOffset : timestampOffset+timestampLength])
 	p.SSRC = binary.BigEndian.Uint32(rawPacket[ssrcOffset : ssrcOffset+ssrcLength])
 
-	currOffset := csrcOffset + (len(p.CSRC) * csrcLength)
+	currOffset := csrcOffset + (csrcCount * csrcLength)
 	if len(rawPacket) < currOffset {
 		return errors.Errorf("RTP header size insufficient; %d < %d", len(rawPacket), currOffset)
 	}
 
-	for i := range p.CSRC {
+	p.CSRC = p.CSRC[:0]
+	for i := 0; i < csrcCount; i++ {
 		offset := csrcOffset + (i * csrcLength)
-		p.CSRC[i] = binary.BigEndian.Uint32(rawPacket[offset:offset])
+		p.CSRC = append(p.CSRC, binary.BigEndian.Uint32(rawPacket[offset:]))
 	}
 
+	p.ExtensionProfile = 0
+	p.ExtensionPayload = nil
 	if p.Extension {
+		if len(rawPacket) < currOffset+4 {
+			return errors.Errorf("RTP header extension size insufficient; %d < %d", len(rawPacket), currOffset+4)
+		}
 		p.ExtensionProfile = binary.BigEndian.Uint16(rawPacket[currOffset:])
 		currOffset += 2
-		extensionLength := binary.BigEndian.Uint16(rawPacket[currOffset:])
+		extensionLength := int(binary.BigEndian.Uint16(rawPacket[currOffset:])) * 4
 		currOffset += 2
-		p.ExtensionPayload = rawPacket[currOffset : currOffset+int(extensionLength)]
-		currOffset += len(p.ExtensionPayload) * 4
+		if len(rawPacket) < currOffset+extensionLength {
+			return errors.Errorf("RTP header extension size insufficient; %d < %d", len(rawPacket), currOffset+extensionLength)
+		}
+		p.ExtensionPayload = rawPacket[currOffset : currOffset+extensionLength]
+		currOffset += len(p.ExtensionPayload)
 	}
 
 	p.Payload = rawPacket[currOffset:]
@@ -107,6 +144,28 @@ func (p *Packet) Unmarshal(rawPacket []byte) error {
 
 // Marshal returns a raw RTP packet for the instance it is called upon
 func (p *Packet) Marshal() ([]byte, error) {
+	rawPacket := make([]byte, p.MarshalSize())
+	if _, err := p.MarshalTo(rawPacket); err != nil {
+		return nil, err
+	}
+	p.Raw = rawPacket
+
+	return rawPacket, nil
+}
+
+// MarshalSize returns the length of the raw RTP packet of the instance
+func (p *Packet) MarshalSize() int {
+	size := csrcOffset + (len(p.CSRC) * csrcLength)
+	if p.Extension {
+		size += 4 + len(p.ExtensionPayload)
+	}
+	return size + len(p.Payload)
+}
+
+// MarshalTo writes the raw RTP packet of the instance to buf, which must hold
+// MarshalSize bytes, and returns the number of bytes written. Unlike Marshal
+// it doesn't allocate, Raw is left unchanged as buf is usually reused.
+func (p *Packet) MarshalTo(buf []byte) (int, error) {
 
 	/*
 	 *  0                   1                   2                   3
@@ -123,53 +182,170 @@ func (p *Packet) Marshal() ([]byte, error) {
 	 * +-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
 	 */
 
-	rawPacketLength := 12 + (len(p.CSRC) * csrcLength)
-	if p.Extension {
-		rawPacketLength += 4 + len(p.ExtensionPayload)
+	if len(p.CSRC) > MaxCSRC {
+		return 0, errors.Errorf("RTP packet lists %d CSRCs, at most %d fit", len(p.CSRC), MaxCSRC)
+	}
+
+	size := p.MarshalSize()
+	if len(buf) < size {
+		return 0, errors.Errorf("RTP buffer size insufficient; %d < %d", len(buf), size)
 	}
-	rawPacket := make([]byte, rawPacketLength)
 
-	rawPacket[0] |= p.Version << versionShift
+	buf[0] = p.Version << versionShift
 	if p.Padding {
-		rawPacket[0] |= 1 << paddingShift
+		buf[0] |= 1 << paddingShift
 	}
 	if p.Extension {
-		rawPacket[0] |= 1 << extensionShift
+		buf[0] |= 1 << extensionShift
 	}
-	rawPacket[0] |= uint8(len(p.CSRC))
+	buf[0] |= uint8(len(p.CSRC))
 
+	buf[1] = p.PayloadType
 	if p.Marker {
-		rawPacket[1] |= 1 << markerShift
+		buf[1] |= 1 << markerShift
 	}
-	rawPacket[1] |= p.PayloadType
 
-	binary.BigEndian.PutUint16(rawPacket[seqNumOffset:], p.SequenceNumber)
-	binary.BigEndian.PutUint32(rawPacket[timestampOffset:], p.Timestamp)
-	binary.BigEndian.PutUint32(rawPacket[ssrcOffset:], p.SSRC)
+	binary.BigEndian.PutUint16(buf[seqNumOffset:], p.SequenceNumber)
+	binary.BigEndian.PutUint32(buf[timestampOffset:], p.Timestamp)
+	binary.BigEndian.PutUint32(buf[ssrcOffset:], p.SSRC)
 
 	for i, csrc := range p.CSRC {
-		binary.BigEndian.PutUint32(rawPacket[csrcOffset+(i*csrcLength):], csrc)
+		binary.BigEndian.PutUint32(buf[csrcOffset+(i*csrcLength):], csrc)
 	}
 
 	currOffset := csrcOffset + (len(p.CSRC) * csrcLength)
 
-	for i := range p.CSRC {
-		offset := csrcOffset + (i * csrcLength)
-		p.CSRC[i] = binary.BigEndian.Uint32(rawPacket[offset:offset])
-	}
-
 	if p.Extension {
-		binary.BigEndian.PutUint16(rawPacket[currOffset:], p.ExtensionProfile)
+		binary.BigEndian.PutUint16(buf[currOffset:], p.ExtensionProfile)
 		currOffset += 2
-		binary.BigEndian.PutUint16(rawPacket[currOffset:], uint16(len(p.ExtensionPayload))/4)
+		binary.BigEndian.PutUint16(buf[currOffset:], uint16(len(p.ExtensionPayload))/4)
 		currOffset += 2
-		copy(rawPacket[currOffset:], p.ExtensionPayload)
+		currOffset += copy(buf[currOffset:], p.ExtensionPayload)
 	}
 
-	p.PayloadOffset = csrcOffset + (len(p.CSRC) * csrcLength)
+	p.PayloadOffset = currOffset
+	copy(buf[currOffset:], p.Payload)
 
-	rawPacket = append(rawPacket, p.Payload...)
-	p.Raw = rawPacket
+	return size, nil
+}
 
-	return rawPacket, nil
+// GetExtension returns the payload of the RFC 8285 header extension element
+// with the id, or nil if the packet doesn't carry it
+// https://tools.ietf.org/html/rfc8285#section-4
+func (p *Packet) GetExtension(id uint8) []byte {
+	if !p.Extension {
+		return nil
+	}
+
+	payload := p.ExtensionPayload
+	switch {
+	case p.ExtensionProfile == extensionProfileOneByte:
+		for len(payload) > 0 {
+			// Padding between elements is a zero byte
+			if payload[0] == 0 {
+				payload = payload[1:]
+				continue
+			}
+
+			elementID, length := payload[0]>>4, int(payload[0]&0x0F)+1
+			if elementID == 15 || len(payload) < 1+length {
+				// 15 stops the processing of the extension
+				return nil
+			} else if elementID == id {
+				return payload[1 : 1+length]
+			}
+			payload = payload[1+length:]
+		}
+	case p.ExtensionProfile&0xFFF0 == extensionProfileTwoByte:
+		for len(payload) > 0 {
+			if payload[0] == 0 {
+				payload = payload[1:]
+				continue
+			}
+
+			if len(payload) < 2 || len(payload) < 2+int(payload[1]) {
+				return nil
+			}
+			elementID, length := payload[0], int(payload[1])
+			if elementID == id {
+				return payload[2 : 2+length]
+			}
+			payload = payload[2+length:]
+		}
+	}
+	return nil
+}
+
+// SetExtension sets the payload of the RFC 8285 header extension element
+// with the id, replacing the element if the packet carries it already. A
+// packet without header extensions gets the one-byte format, which carries
+// ids 1 to 14 and payloads of 1 to 16 bytes. An element of the same size is
+// replaced in place.
+// https://tools.ietf.org/html/rfc8285#section-4
+func (p *Packet) SetExtension(id uint8, payload []byte) error {
+	if !p.Extension {
+		p.Extension = true
+		p.ExtensionProfile = extensionProfileOneByte
+		p.ExtensionPayload = nil
+	}
+
+	oneByte := p.ExtensionProfile == extensionProfileOneByte
+	switch {
+	case !oneByte && p.ExtensionProfile&0xFFF0 != extensionProfileTwoByte:
+		return errors.Errorf("RTP header extension profile %#x is not RFC 8285", p.ExtensionProfile)
+	case oneByte && (id < 1 || id > 14):
+		return errors.Errorf("RTP header extension id %d is not in 1-14", id)
+	case oneByte && (len(payload) < 1 || len(payload) > 16):
+		return errors.Errorf("RTP header extension payload size %d is not in 1-16", len(payload))
+	case id == 0 || len(payload) > 255:
+		return errors.Errorf("RTP header extension id %d or payload size %d is invalid", id, len(payload))
+	}
+
+	if current := p.GetExtension(id); current != nil && len(current) == len(payload) {
+		copy(current, payload)
+		return nil
+	}
+
+	// The elements are copied without the element replaced and the padding,
+	// the new element is appended and the extension padded to 32 bits
+	elements := make([]byte, 0, len(p.ExtensionPayload)+2+len(payload)+3)
+	for rest := p.ExtensionPayload; len(rest) > 0; {
+		if rest[0] == 0 {
+			rest = rest[1:]
+			continue
+		}
+
+		var elementID uint8
+		var size int
+		if oneByte {
+			elementID, size = rest[0]>>4, 1+int(rest[0]&0x0F)+1
+			if elementID == 15 {
+				break
+			}
+		} else {
+			if len(rest) < 2 {
+				break
+			}
+			elementID, size = rest[0], 2+int(rest[1])
+		}
+		if len(rest) < size {
+			break
+		}
+		if elementID != id {
+			elements = append(elements, rest[:size]...)
+		}
+		rest = rest[size:]
+	}
+
+	if oneByte {
+		elements = append(elements, id<<4|uint8(len(payload)-1))
+	} else {
+		elements = append(elements, id, uint8(len(payload)))
+	}
+	elements = append(elements, payload...)
+	for len(elements)%4 != 0 {
+		elements = append(elements, 0)
+	}
+	p.ExtensionPayload = elements
+	return nil
 }
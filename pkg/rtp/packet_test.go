@@ -0,0 +1,162 @@
+package rtp
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPacket_MarshalTo(t *testing.T) {
+	packet := &Packet{
+		Version:          2,
+		Extension:        true,
+		Marker:           true,
+		PayloadType:      96,
+		SequenceNumber:   27023,
+		Timestamp:        3653407706,
+		SSRC:             476325762,
+		CSRC:             []uint32{1, 2},
+		ExtensionProfile: extensionProfileOneByte,
+		ExtensionPayload: []byte{0x10, 0xAA, 0x00, 0x00},
+		Payload:          []byte{0x98, 0x36, 0xbe, 0x88},
+	}
+
+	raw, err := packet.Marshal()
+	assert.NoError(t, err)
+	assert.Equal(t, packet.MarshalSize(), len(raw))
+	assert.Equal(t, raw, packet.Raw)
+
+	buf := make([]byte, len(raw)+10)
+	n, err := packet.MarshalTo(buf)
+	assert.NoError(t, err)
+	assert.Equal(t, raw, buf[:n])
+	_, err = packet.MarshalTo(buf[:len(raw)-1])
+	assert.Error(t, err)
+
+	// Unmarshaling into a used packet replaces all of it
+	parsed := &Packet{CSRC: []uint32{7, 8, 9}, ExtensionPayload: []byte{0x01}}
+	assert.NoError(t, parsed.Unmarshal(raw))
+	assert.Equal(t, packet.CSRC, parsed.CSRC)
+	assert.Equal(t, packet.ExtensionPayload, parsed.ExtensionPayload)
+	assert.Equal(t, packet.Payload, parsed.Payload)
+	assert.Equal(t, packet.PayloadOffset, parsed.PayloadOffset)
+	assert.Equal(t, []byte{0xAA}, parsed.GetExtension(1))
+
+	packet.Extension = false
+	raw, err = packet.Marshal()
+	assert.NoError(t, err)
+	assert.NoError(t, parsed.Unmarshal(raw))
+	assert.Nil(t, parsed.ExtensionPayload)
+
+	assert.Error(t, parsed.Unmarshal(raw[:csrcOffset-1]))
+	assert.Error(t, parsed.Unmarshal(raw[:csrcOffset+csrcLength]))
+
+	// The CSRC count has 4 bits
+	packet.CSRC = make([]uint32, MaxCSRC+1)
+	_, err = packet.Marshal()
+	assert.Error(t, err)
+}
+
+func TestPacket_SetExtension(t *testing.T) {
+	packet := &Packet{Version: 2, Payload: []byte{0x01}}
+	assert.NoError(t, packet.SetExtension(1, []byte{0xAA}))
+	assert.True(t, packet.Extension)
+	assert.Equal(t, uint16(extensionProfileOneByte), packet.ExtensionProfile)
+	assert.Equal(t, []byte{0x10, 0xAA, 0x00, 0x00}, packet.ExtensionPayload)
+
+	// Elements of the same size are replaced in place, others are moved to
+	// the end
+	assert.NoError(t, packet.SetExtension(2, []byte{0x01, 0x02, 0x03}))
+	assert.NoError(t, packet.SetExtension(1, []byte{0xBB}))
+	assert.Equal(t, []byte{0x10, 0xBB, 0x22, 0x01, 0x02, 0x03, 0x00, 0x00}, packet.ExtensionPayload)
+	assert.NoError(t, packet.SetExtension(1, []byte{0xCC, 0xDD}))
+	assert.Equal(t, []byte{0x22, 0x01, 0x02, 0x03, 0x11, 0xCC, 0xDD, 0x00}, packet.ExtensionPayload)
+
+	raw, err := packet.Marshal()
+	assert.NoError(t, err)
+	parsed := &Packet{}
+	assert.NoError(t, parsed.Unmarshal(raw))
+	assert.Equal(t, []byte{0xCC, 0xDD}, parsed.GetExtension(1))
+	assert.Equal(t, []byte{0x01, 0x02, 0x03}, parsed.GetExtension(2))
+
+	// The two-byte format is kept
+	packet = &Packet{Extension: true, ExtensionProfile: extensionProfileTwoByte, ExtensionPayload: []byte{0x01, 0x00, 0x00, 0x00}}
+	assert.NoError(t, packet.SetExtension(20, []byte{0xAA}))
+	assert.Equal(t, []byte{0x01, 0x00, 0x14, 0x01, 0xAA, 0x00, 0x00, 0x00}, packet.ExtensionPayload)
+	assert.Equal(t, []byte{0xAA}, packet.GetExtension(20))
+
+	assert.Error(t, (&Packet{}).SetExtension(15, []byte{0xAA}))
+	assert.Error(t, (&Packet{}).SetExtension(1, nil))
+	assert.Error(t, (&Packet{Extension: true, ExtensionProfile: 0x1234}).SetExtension(1, []byte{0xAA}))
+}
+
+func benchmarkPacket() *Packet {
+	return &Packet{
+		Version:        2,
+		PayloadType:    96,
+		SequenceNumber: 27023,
+		Timestamp:      3653407706,
+		SSRC:           476325762,
+		Payload:        make([]byte, 1200),
+	}
+}
+
+func BenchmarkPacket_Marshal(b *testing.B) {
+	packet := benchmarkPacket()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := packet.Marshal(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkPacket_MarshalTo(b *testing.B) {
+	packet := benchmarkPacket()
+	buf := make([]byte, packet.MarshalSize())
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := packet.MarshalTo(buf); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkPacket_Unmarshal(b *testing.B) {
+	raw, err := benchmarkPacket().Marshal()
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	packet := &Packet{}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if err := packet.Unmarshal(raw); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestPacket_Clone(t *testing.T) {
+	raw := []byte{
+		0x90, 0xe0, 0x69, 0x8f, 0xd9, 0xc2, 0x93, 0xda, 0x1c, 0x64,
+		0x27, 0x82, 0xbe, 0xde, 0x00, 0x01, 0x50, 0xaa, 0x00, 0x00,
+		0x98, 0x36, 0xbe, 0x88,
+	}
+	packet := &Packet{}
+	assert.Nil(t, packet.Unmarshal(raw))
+	packet.CSRC = []uint32{1, 2}
+
+	clone := packet.Clone()
+	assert.Nil(t, clone.Raw)
+	clone.Raw = packet.Raw
+	assert.Equal(t, packet, clone)
+
+	// The clone shares no memory with the packet
+	clone.CSRC[0] = 3
+	clone.ExtensionPayload[0] = 0x00
+	clone.Payload[0] = 0x00
+	assert.Equal(t, []uint32{1, 2}, packet.CSRC)
+	assert.Equal(t, byte(0x50), packet.ExtensionPayload[0])
+	assert.Equal(t, byte(0x98), packet.Payload[0])
+}
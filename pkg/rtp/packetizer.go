@@ -23,9 +23,12 @@ type packetizer struct {
 	Sequencer   Sequencer
 	Timestamp   uint32
 	ClockRate   uint32
+	CSRC        []uint32
 }
 
-// NewPacketizer returns a new instance of a Packetizer for a specific payloader
+// NewPacketizer returns a new instance of a Packetizer for a specific payloader.
+// Besides Packetize it has the SetMTU, SetCSRC, SkipSamples and NextTimestamp methods, which
+// callers needing them reach through an interface of their own.
 func NewPacketizer(mtu int, pt uint8, ssrc uint32, payloader Payloader, sequencer Sequencer, clockRate uint32) Packetizer {
 	rs := rand.NewSource(time.Now().UnixNano())
 	r := rand.New(rs)
@@ -41,14 +44,27 @@ func NewPacketizer(mtu int, pt uint8, ssrc uint32, payloader Payloader, sequence
 	}
 }
 
-// Packetize packetizes the payload of an RTP packet and returns one or more RTP packets
+// Packetize packetizes the payload of an RTP packet and returns one or more RTP packets,
+// an empty payload, or an MTU leaving no room for it, returns no packets but
+// still advances the timestamp by the samples
 func (p *packetizer) Packetize(payload []byte, samples uint32) []*Packet {
+	// The contributing sources take at most half the room the header leaves
+	csrc := p.CSRC
+	if max := (p.MTU - csrcOffset) / 2 / csrcLength; len(csrc) > max {
+		if max < 0 {
+			max = 0
+		}
+		csrc = csrc[:max]
+	}
+	mtu := p.MTU - csrcOffset - len(csrc)*csrcLength
+
 	// Guard against an empty payload
-	if len(payload) == 0 {
+	if len(payload) == 0 || mtu <= 0 {
+		p.SkipSamples(samples)
 		return nil
 	}
 
-	payloads := p.Payloader.Payload(p.MTU-12, payload)
+	payloads := p.Payloader.Payload(mtu, payload)
 	packets := make([]*Packet, len(payloads))
 
 	for i, pp := range payloads {
@@ -61,6 +77,7 @@ func (p *packetizer) Packetize(payload []byte, samples uint32) []*Packet {
 			SequenceNumber: p.Sequencer.NextSequenceNumber(),
 			Timestamp:      p.Timestamp, // Figure out how to do timestamps
 			SSRC:           p.SSRC,
+			CSRC:           csrc,
 			Payload:        pp,
 		}
 	}
@@ -68,3 +85,27 @@ func (p *packetizer) Packetize(payload []byte, samples uint32) []*Packet {
 
 	return packets
 }
+
+// SetMTU sets the size of the packets of the next payloads, the RTP header
+// included
+func (p *packetizer) SetMTU(mtu int) {
+	p.MTU = mtu
+}
+
+// SetCSRC sets the contributing sources the packets of the next payloads
+// list, their room is taken from the MTU. Those which would take more than
+// half the room the RTP header leaves are left out.
+func (p *packetizer) SetCSRC(csrc []uint32) {
+	p.CSRC = csrc
+}
+
+// SkipSamples advances the timestamp of the next packets by samples which
+// were not sent, like the silence suppressed by discontinuous transmission
+func (p *packetizer) SkipSamples(skippedSamples uint32) {
+	p.Timestamp += skippedSamples
+}
+
+// NextTimestamp returns the timestamp of the packets of the next payload
+func (p *packetizer) NextTimestamp() uint32 {
+	return p.Timestamp
+}
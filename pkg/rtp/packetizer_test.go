@@ -0,0 +1,68 @@
+package rtp
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// testPayloader splits payloads into the MTU
+type testPayloader struct {
+	t *testing.T
+}
+
+func (p testPayloader) Payload(mtu int, payload []byte) [][]byte {
+	if mtu <= 0 {
+		p.t.Fatalf("payloaded with MTU %d", mtu)
+	}
+
+	var payloads [][]byte
+	for len(payload) > mtu {
+		payloads = append(payloads, payload[:mtu])
+		payload = payload[mtu:]
+	}
+	return append(payloads, payload)
+}
+
+func TestPacketizer_SetCSRC(t *testing.T) {
+	csrc := make([]uint32, MaxCSRC)
+	for i := range csrc {
+		csrc[i] = uint32(i)
+	}
+
+	testCases := []struct {
+		mtu      int
+		csrc     []uint32
+		expected []uint32
+	}{
+		{1200, csrc, csrc},
+		{1200, nil, nil},
+		// The CSRCs take at most half the room left by the header
+		{64, csrc, csrc[:6]},
+		{64, csrc[:2], csrc[:2]},
+		{20, csrc, csrc[:1]},
+		{13, csrc, csrc[:0]},
+	}
+
+	for i, testCase := range testCases {
+		p := NewPacketizer(testCase.mtu, 96, 1, testPayloader{t}, NewRandomSequencer(), 90000).(*packetizer)
+		p.SetCSRC(testCase.csrc)
+
+		packets := p.Packetize(make([]byte, 1000), 3000)
+		assert.NotEmpty(t, packets, "testCase: %d", i)
+		for _, packet := range packets {
+			assert.Equal(t, testCase.expected, packet.CSRC, "testCase: %d", i)
+			raw, err := packet.Marshal()
+			assert.Nil(t, err, "testCase: %d", i)
+			assert.True(t, len(raw) <= testCase.mtu, "testCase: %d packet of %d bytes", i, len(raw))
+		}
+	}
+
+	// An MTU leaving no room for the payload packetizes nothing, but the
+	// samples still advance the timestamp
+	p := NewPacketizer(12, 96, 1, testPayloader{t}, NewRandomSequencer(), 90000).(*packetizer)
+	p.SetCSRC(csrc)
+	timestamp := p.Timestamp
+	assert.Empty(t, p.Packetize(make([]byte, 1000), 3000))
+	assert.Equal(t, timestamp+3000, p.Timestamp)
+}
@@ -0,0 +1,177 @@
+// Package signal exchanges session descriptions between peers for examples
+// and prototypes. Descriptions are encoded as a single line of base64, of
+// their gzipped JSON, which is short enough to be copy-pasted between
+// terminals and browsers. A Signal carries them over any channel, the Offer
+// and Answer helpers run a whole exchange with it.
+package signal
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/ioutil"
+	"strings"
+	"sync"
+
+	"github.com/pions/webrtc"
+)
+
+// maxDecodedSize bounds the JSON a description decodes to, so a small
+// input can't inflate to exhaust the memory
+const maxDecodedSize = 1 << 20
+
+var (
+	errUnexpectedType = errors.New("signal: unexpected session description type")
+	errEmpty          = errors.New("signal: empty session description")
+	errTooLarge       = errors.New("signal: session description too large")
+)
+
+// Encode encodes a session description as base64 of its gzipped JSON
+func Encode(desc webrtc.RTCSessionDescription) (string, error) {
+	b, err := json.Marshal(desc)
+	if err != nil {
+		return "", err
+	}
+
+	var buf bytes.Buffer
+	w, err := gzip.NewWriterLevel(&buf, gzip.BestCompression)
+	if err != nil {
+		return "", err
+	}
+	if _, err := w.Write(b); err != nil {
+		return "", err
+	}
+	if err := w.Close(); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(buf.Bytes()), nil
+}
+
+// Decode decodes a session description encoded by Encode, the white space
+// copy-pasting inserts is ignored
+func Decode(in string) (webrtc.RTCSessionDescription, error) {
+	in = strings.Join(strings.Fields(in), "")
+	if in == "" {
+		return webrtc.RTCSessionDescription{}, errEmpty
+	}
+
+	b, err := base64.StdEncoding.DecodeString(in)
+	if err != nil {
+		return webrtc.RTCSessionDescription{}, err
+	}
+	r, err := gzip.NewReader(bytes.NewReader(b))
+	if err != nil {
+		return webrtc.RTCSessionDescription{}, err
+	}
+	b, err = ioutil.ReadAll(io.LimitReader(r, maxDecodedSize+1))
+	if err != nil {
+		return webrtc.RTCSessionDescription{}, err
+	} else if len(b) > maxDecodedSize {
+		return webrtc.RTCSessionDescription{}, errTooLarge
+	}
+
+	var desc webrtc.RTCSessionDescription
+	if err := json.Unmarshal(b, &desc); err != nil {
+		return webrtc.RTCSessionDescription{}, err
+	}
+	return desc, nil
+}
+
+// Signal carries session descriptions to and from the remote peer
+type Signal interface {
+	// Send sends a description to the remote peer
+	Send(desc webrtc.RTCSessionDescription) error
+
+	// Receive blocks until a description of the remote peer is received
+	Receive() (webrtc.RTCSessionDescription, error)
+}
+
+// stream is a Signal which writes and reads encoded descriptions one per
+// line. Sending doesn't wait for a pending Receive.
+type stream struct {
+	readLock  sync.Mutex
+	reader    *bufio.Reader
+	writeLock sync.Mutex
+	writer    io.Writer
+}
+
+// NewStream returns a Signal which writes the encoded descriptions sent to w
+// and reads those received from r, one per line. With os.Stdin and
+// os.Stdout descriptions are copy-pasted between terminals or a browser.
+func NewStream(r io.Reader, w io.Writer) Signal {
+	return &stream{reader: bufio.NewReader(r), writer: w}
+}
+
+func (s *stream) Send(desc webrtc.RTCSessionDescription) error {
+	encoded, err := Encode(desc)
+	if err != nil {
+		return err
+	}
+
+	s.writeLock.Lock()
+	defer s.writeLock.Unlock()
+	_, err = io.WriteString(s.writer, encoded+"\n")
+	return err
+}
+
+func (s *stream) Receive() (webrtc.RTCSessionDescription, error) {
+	s.readLock.Lock()
+	defer s.readLock.Unlock()
+
+	for {
+		line, err := s.reader.ReadString('\n')
+		// Blank lines are skipped, the last line may not be terminated
+		if strings.TrimSpace(line) != "" {
+			return Decode(line)
+		}
+		if err != nil {
+			return webrtc.RTCSessionDescription{}, err
+		}
+	}
+}
+
+// Offer creates an offer, sends it and sets the answer received as the
+// remote description
+func Offer(pc *webrtc.RTCPeerConnection, s Signal) error {
+	offer, err := pc.CreateOffer(nil)
+	if err != nil {
+		return err
+	}
+	if err := s.Send(offer); err != nil {
+		return err
+	}
+
+	answer, err := s.Receive()
+	if err != nil {
+		return err
+	}
+	if answer.Type != webrtc.RTCSdpTypeAnswer {
+		return errUnexpectedType
+	}
+	return pc.SetRemoteDescription(answer)
+}
+
+// Answer sets the offer received as the remote description, then creates an
+// answer and sends it
+func Answer(pc *webrtc.RTCPeerConnection, s Signal) error {
+	offer, err := s.Receive()
+	if err != nil {
+		return err
+	}
+	if offer.Type != webrtc.RTCSdpTypeOffer {
+		return errUnexpectedType
+	}
+	if err := pc.SetRemoteDescription(offer); err != nil {
+		return err
+	}
+
+	answer, err := pc.CreateAnswer(nil)
+	if err != nil {
+		return err
+	}
+	return s.Send(answer)
+}
@@ -0,0 +1,142 @@
+package signal
+
+import (
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pions/webrtc"
+	"github.com/pions/webrtc/pkg/ice"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEncodeDecode(t *testing.T) {
+	desc := webrtc.RTCSessionDescription{
+		Type: webrtc.RTCSdpTypeOffer,
+		SDP:  "v=0\r\no=- 0 0 IN IP4 127.0.0.1\r\ns=-\r\nt=0 0\r\n",
+	}
+	encoded, err := Encode(desc)
+	assert.NoError(t, err)
+	assert.NotContains(t, encoded, "\n")
+
+	// Line breaks of a copy-paste are ignored
+	decoded, err := Decode(" " + encoded[:10] + "\r\n" + encoded[10:] + "\n")
+	assert.NoError(t, err)
+	assert.Equal(t, desc.Type, decoded.Type)
+	assert.Equal(t, desc.SDP, decoded.SDP)
+
+	for i, in := range []string{"", "not base64!", "dj0w"} {
+		_, err := Decode(in)
+		assert.Error(t, err, "testCase: %d", i)
+	}
+}
+
+func TestDecode_TooLarge(t *testing.T) {
+	// Padding which compresses to little inflates past the limit
+	encoded, err := Encode(webrtc.RTCSessionDescription{
+		Type: webrtc.RTCSdpTypeOffer,
+		SDP:  strings.Repeat(" ", maxDecodedSize),
+	})
+	assert.NoError(t, err)
+	assert.True(t, len(encoded) < maxDecodedSize/100)
+
+	_, err = Decode(encoded)
+	assert.Equal(t, errTooLarge, err)
+}
+
+func TestStream_SendWhileReceiving(t *testing.T) {
+	offer := webrtc.RTCSessionDescription{Type: webrtc.RTCSdpTypeOffer, SDP: "offer"}
+
+	// Nothing is received, the description is sent meanwhile
+	r, w := io.Pipe()
+	defer func() { assert.NoError(t, w.Close()) }()
+	var out strings.Builder
+	s := NewStream(r, &out)
+	go func() { _, _ = s.Receive() }()
+	time.Sleep(10 * time.Millisecond)
+
+	sent := make(chan error)
+	go func() { sent <- s.Send(offer) }()
+	select {
+	case err := <-sent:
+		assert.NoError(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("Send waited for Receive")
+	}
+}
+
+func TestStream(t *testing.T) {
+	offer := webrtc.RTCSessionDescription{Type: webrtc.RTCSdpTypeOffer, SDP: "offer"}
+	answer := webrtc.RTCSessionDescription{Type: webrtc.RTCSdpTypeAnswer, SDP: "answer"}
+
+	var out strings.Builder
+	encoded, err := Encode(answer)
+	assert.NoError(t, err)
+	s := NewStream(strings.NewReader("\n"+encoded), &out)
+
+	assert.NoError(t, s.Send(offer))
+	sent, err := Decode(out.String())
+	assert.NoError(t, err)
+	assert.Equal(t, offer.SDP, sent.SDP)
+
+	received, err := s.Receive()
+	assert.NoError(t, err)
+	assert.Equal(t, answer.Type, received.Type)
+	assert.Equal(t, answer.SDP, received.SDP)
+
+	_, err = s.Receive()
+	assert.Equal(t, io.EOF, err)
+}
+
+func TestOfferAnswer(t *testing.T) {
+	offerer, err := webrtc.New(webrtc.RTCConfiguration{})
+	assert.NoError(t, err)
+	defer func() { assert.NoError(t, offerer.Close()) }()
+	answerer, err := webrtc.New(webrtc.RTCConfiguration{})
+	assert.NoError(t, err)
+	defer func() { assert.NoError(t, answerer.Close()) }()
+
+	_, err = offerer.CreateDataChannel("data", nil)
+	assert.NoError(t, err)
+
+	connected := make(chan struct{}, 1)
+	answerer.OnICEConnectionStateChange(func(state ice.ConnectionState) {
+		if state == ice.ConnectionStateConnected {
+			select {
+			case connected <- struct{}{}:
+			default:
+			}
+		}
+	})
+
+	offerReader, offerWriter := io.Pipe()
+	answerReader, answerWriter := io.Pipe()
+	answered := make(chan error)
+	go func() { answered <- Answer(answerer, NewStream(offerReader, answerWriter)) }()
+
+	assert.NoError(t, Offer(offerer, NewStream(answerReader, offerWriter)))
+	assert.NoError(t, <-answered)
+
+	select {
+	case <-connected:
+	case <-time.After(10 * time.Second):
+		t.Fatal("peers didn't connect")
+	}
+}
+
+func TestOfferAnswer_UnexpectedType(t *testing.T) {
+	pc, err := webrtc.New(webrtc.RTCConfiguration{})
+	assert.NoError(t, err)
+	defer func() { assert.NoError(t, pc.Close()) }()
+
+	// An offer where an answer is expected, and the reverse
+	offer, err := Encode(webrtc.RTCSessionDescription{Type: webrtc.RTCSdpTypeOffer})
+	assert.NoError(t, err)
+	answer, err := Encode(webrtc.RTCSessionDescription{Type: webrtc.RTCSdpTypeAnswer})
+	assert.NoError(t, err)
+
+	assert.Equal(t, errUnexpectedType, Offer(pc, NewStream(strings.NewReader(offer), ioutil.Discard)))
+	assert.Equal(t, errUnexpectedType, Answer(pc, NewStream(strings.NewReader(answer), ioutil.Discard)))
+}
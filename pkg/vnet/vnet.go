@@ -0,0 +1,420 @@
+// Package vnet is an in-memory network to run RTCPeerConnections over, with
+// controlled loss, latency and NAT behavior. Every Net attached to a Router
+// is a host with a single IP, pass it to SettingEngine.SetNet.
+package vnet
+
+import (
+	"math/rand"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// ephemeralPortMin is the first port conns listening on port 0 are
+	// bound to, and the first port NATs map to
+	ephemeralPortMin = 49152
+
+	// connBufferSize is how many packets are queued for a conn before
+	// further ones are dropped, like a full socket buffer drops them
+	connBufferSize = 64
+)
+
+var (
+	errInvalidIP        = errors.New("vnet: invalid IP")
+	errIPInUse          = errors.New("vnet: IP is in use")
+	errUnsupportedNet   = errors.New("vnet: only UDP is supported")
+	errAddressInUse     = errors.New("vnet: address is in use")
+	errNoFreePort       = errors.New("vnet: no free port")
+	errAddressNotLocal  = errors.New("vnet: address is not local")
+	errConnClosed       = errors.New("vnet: use of closed conn")
+	errUnsupportedAddr  = errors.New("vnet: address has to be a *net.UDPAddr")
+	errNoRouteToAddress = errors.New("vnet: no route to address")
+)
+
+// Router forwards the packets between the Nets attached to it
+type Router struct {
+	lock     sync.Mutex
+	nets     map[string]*Net
+	lossRate float64
+	latency  time.Duration
+	rand     *rand.Rand
+
+	// delayed are the packets waiting for the latency to pass, they are
+	// delivered in the order they were sent
+	delayed []*delayedPacket
+	running bool
+}
+
+type delayedPacket struct {
+	buffer    []byte
+	srcAddr   *net.UDPAddr
+	dstAddr   *net.UDPAddr
+	deliverAt time.Time
+}
+
+// NewRouter creates a Router which delivers every packet immediately
+func NewRouter() *Router {
+	return &Router{
+		nets: make(map[string]*Net),
+		rand: rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+}
+
+// SetLossRate drops the share of packets, between 0 and 1
+func (r *Router) SetLossRate(rate float64) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.lossRate = rate
+}
+
+// SetLatency delays every packet by the duration
+func (r *Router) SetLatency(latency time.Duration) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.latency = latency
+}
+
+// NewNet attaches a host reachable at the IP to the Router
+func (r *Router) NewNet(ip string) (*Net, error) {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return nil, errInvalidIP
+	}
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if _, ok := r.nets[parsed.String()]; ok {
+		return nil, errIPInUse
+	}
+
+	n := newNet(r, parsed)
+	r.nets[parsed.String()] = n
+	return n, nil
+}
+
+// NewNATedNet attaches a host with the private IP behind a NAT with the
+// public IP, the private IP can't be reached from other hosts. The NAT maps
+// every local address to a port of its own, the mapping doesn't depend on the
+// remote address. Packets to the port are only let in from IPs the local
+// address sent to, like most home routers do.
+// https://tools.ietf.org/html/rfc4787
+func (r *Router) NewNATedNet(privateIP, publicIP string) (*Net, error) {
+	parsedPrivate, parsedPublic := net.ParseIP(privateIP), net.ParseIP(publicIP)
+	if parsedPrivate == nil || parsedPublic == nil {
+		return nil, errInvalidIP
+	}
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if _, ok := r.nets[parsedPublic.String()]; ok {
+		return nil, errIPInUse
+	}
+
+	n := newNet(r, parsedPrivate)
+	n.nat = &nat{
+		publicIP: parsedPublic,
+		nextPort: ephemeralPortMin,
+		byLocal:  make(map[string]*natMapping),
+		byPort:   make(map[int]*natMapping),
+	}
+	r.nets[parsedPublic.String()] = n
+	return n, nil
+}
+
+// send delivers the packet of the host after the latency, unless it is lost
+func (r *Router) send(b []byte, from *Net, src, dst *net.UDPAddr) {
+	// Packets leaving a NATed host come from the public address
+	if from.nat != nil {
+		src = from.nat.outbound(src, dst)
+	}
+	packet := append([]byte{}, b...)
+
+	r.lock.Lock()
+	if r.lossRate > 0 && r.rand.Float64() < r.lossRate {
+		r.lock.Unlock()
+		return
+	} else if r.latency == 0 && len(r.delayed) == 0 {
+		r.lock.Unlock()
+		r.deliver(packet, src, dst)
+		return
+	}
+
+	r.delayed = append(r.delayed, &delayedPacket{
+		buffer:    packet,
+		srcAddr:   src,
+		dstAddr:   dst,
+		deliverAt: time.Now().Add(r.latency),
+	})
+	if !r.running {
+		r.running = true
+		go r.run()
+	}
+	r.lock.Unlock()
+}
+
+// run delivers the delayed packets until none is left
+func (r *Router) run() {
+	for {
+		r.lock.Lock()
+		if len(r.delayed) == 0 {
+			r.running = false
+			r.lock.Unlock()
+			return
+		}
+		p := r.delayed[0]
+		r.delayed = r.delayed[1:]
+		r.lock.Unlock()
+
+		time.Sleep(time.Until(p.deliverAt))
+		r.deliver(p.buffer, p.srcAddr, p.dstAddr)
+	}
+}
+
+func (r *Router) deliver(b []byte, src, dst *net.UDPAddr) {
+	r.lock.Lock()
+	to := r.nets[dst.IP.String()]
+	r.lock.Unlock()
+	if to == nil {
+		return
+	}
+
+	// Packets to the public IP of a NAT are let in through the mapping
+	if to.nat != nil {
+		var ok bool
+		if dst, ok = to.nat.inbound(src, dst); !ok {
+			return
+		}
+	}
+
+	to.lock.Lock()
+	c := to.conns[dst.Port]
+	to.lock.Unlock()
+	if c != nil {
+		c.receive(b, src)
+	}
+}
+
+// Net is a host attached to a Router, it implements the network the ICE
+// agent of a RTCPeerConnection opens its sockets on
+type Net struct {
+	router *Router
+	ip     net.IP
+	nat    *nat
+
+	lock     sync.Mutex
+	conns    map[int]*conn
+	nextPort int
+}
+
+func newNet(r *Router, ip net.IP) *Net {
+	return &Net{
+		router: r,
+		ip:     ip,
+		conns:  make(map[int]*conn),
+	}
+}
+
+// IPs returns the IP of the host
+func (n *Net) IPs() []net.IP {
+	return []net.IP{n.ip}
+}
+
+// ListenPacket announces on the local UDP address of the host, port 0 picks
+// a free port
+func (n *Net) ListenPacket(network, address string) (net.PacketConn, error) {
+	switch network {
+	case "udp", "udp4", "udp6":
+	default:
+		return nil, errUnsupportedNet
+	}
+
+	host, rawPort, err := net.SplitHostPort(address)
+	if err != nil {
+		return nil, err
+	}
+	port, err := strconv.Atoi(rawPort)
+	if err != nil {
+		return nil, err
+	}
+	if ip := net.ParseIP(host); host != "" && ip != nil && !ip.IsUnspecified() && !ip.Equal(n.ip) {
+		return nil, errAddressNotLocal
+	}
+
+	n.lock.Lock()
+	defer n.lock.Unlock()
+
+	if port == 0 {
+		if port, err = n.freePort(); err != nil {
+			return nil, err
+		}
+	} else if _, ok := n.conns[port]; ok {
+		return nil, errAddressInUse
+	}
+
+	c := &conn{
+		net:     n,
+		addr:    &net.UDPAddr{IP: n.ip, Port: port},
+		packets: make(chan *packet, connBufferSize),
+		closed:  make(chan struct{}),
+	}
+	n.conns[port] = c
+	return c, nil
+}
+
+// freePort returns the next ephemeral port no conn is bound to
+// Note: the caller should hold the Net lock.
+func (n *Net) freePort() (int, error) {
+	if n.nextPort < ephemeralPortMin {
+		n.nextPort = ephemeralPortMin
+	}
+	for i := ephemeralPortMin; i <= 0xFFFF; i++ {
+		port := n.nextPort
+		if n.nextPort++; n.nextPort > 0xFFFF {
+			n.nextPort = ephemeralPortMin
+		}
+		if _, ok := n.conns[port]; !ok {
+			return port, nil
+		}
+	}
+	return 0, errNoFreePort
+}
+
+func (n *Net) removeConn(c *conn) {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+	if n.conns[c.addr.Port] == c {
+		delete(n.conns, c.addr.Port)
+	}
+}
+
+// nat maps the local addresses of a host to ports of its public IP
+type nat struct {
+	lock     sync.Mutex
+	publicIP net.IP
+	nextPort int
+	byLocal  map[string]*natMapping
+	byPort   map[int]*natMapping
+}
+
+type natMapping struct {
+	local *net.UDPAddr
+	port  int
+
+	// permitted are the remote IPs packets are let in from
+	permitted map[string]bool
+}
+
+// outbound returns the public address of the local one, permitting the
+// packets of the destination IP in
+func (n *nat) outbound(local, dst *net.UDPAddr) *net.UDPAddr {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+
+	m, ok := n.byLocal[local.String()]
+	if !ok {
+		m = &natMapping{local: local, port: n.nextPort, permitted: make(map[string]bool)}
+		n.nextPort++
+		n.byLocal[local.String()] = m
+		n.byPort[m.port] = m
+	}
+	m.permitted[dst.IP.String()] = true
+	return &net.UDPAddr{IP: n.publicIP, Port: m.port}
+}
+
+// inbound returns the local address packets to the public one are for,
+// ok is false if they aren't let in from the source
+func (n *nat) inbound(src, dst *net.UDPAddr) (local *net.UDPAddr, ok bool) {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+
+	m, ok := n.byPort[dst.Port]
+	if !ok || !m.permitted[src.IP.String()] {
+		return nil, false
+	}
+	return m.local, true
+}
+
+type packet struct {
+	buffer  []byte
+	srcAddr *net.UDPAddr
+}
+
+// conn is a UDP socket of a Net
+type conn struct {
+	net     *Net
+	addr    *net.UDPAddr
+	packets chan *packet
+
+	closed    chan struct{}
+	closeOnce sync.Once
+}
+
+func (c *conn) receive(b []byte, src *net.UDPAddr) {
+	select {
+	case <-c.closed:
+	case c.packets <- &packet{buffer: b, srcAddr: src}:
+	default:
+	}
+}
+
+// ReadFrom reads the next packet sent to the conn
+func (c *conn) ReadFrom(b []byte) (int, net.Addr, error) {
+	select {
+	case p := <-c.packets:
+		return copy(b, p.buffer), p.srcAddr, nil
+	case <-c.closed:
+		return 0, nil, errConnClosed
+	}
+}
+
+// WriteTo sends the packet through the Router
+func (c *conn) WriteTo(b []byte, addr net.Addr) (int, error) {
+	dst, ok := addr.(*net.UDPAddr)
+	if !ok {
+		return 0, errUnsupportedAddr
+	} else if dst.IP == nil {
+		return 0, errNoRouteToAddress
+	}
+
+	select {
+	case <-c.closed:
+		return 0, errConnClosed
+	default:
+	}
+
+	c.net.router.send(b, c.net, c.addr, dst)
+	return len(b), nil
+}
+
+// Close unbinds the conn from its address
+func (c *conn) Close() error {
+	c.closeOnce.Do(func() {
+		close(c.closed)
+		c.net.removeConn(c)
+	})
+	return nil
+}
+
+// LocalAddr returns the address the conn is bound to
+func (c *conn) LocalAddr() net.Addr {
+	return c.addr
+}
+
+// SetDeadline is not supported
+func (c *conn) SetDeadline(t time.Time) error {
+	return nil
+}
+
+// SetReadDeadline is not supported
+func (c *conn) SetReadDeadline(t time.Time) error {
+	return nil
+}
+
+// SetWriteDeadline is not supported
+func (c *conn) SetWriteDeadline(t time.Time) error {
+	return nil
+}
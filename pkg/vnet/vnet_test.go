@@ -0,0 +1,169 @@
+package vnet
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func readFrom(t *testing.T, c net.PacketConn) (string, net.Addr) {
+	received := make(chan string, 1)
+	var from net.Addr
+	go func() {
+		buffer := make([]byte, 100)
+		n, addr, err := c.ReadFrom(buffer)
+		if err != nil {
+			close(received)
+			return
+		}
+		from = addr
+		received <- string(buffer[:n])
+	}()
+
+	select {
+	case s := <-received:
+		return s, from
+	case <-time.After(time.Second):
+		t.Fatal("timed out reading from conn")
+		return "", nil
+	}
+}
+
+func assertNothingReceived(t *testing.T, c net.PacketConn) {
+	received := make(chan struct{})
+	go func() {
+		buffer := make([]byte, 100)
+		if _, _, err := c.ReadFrom(buffer); err == nil {
+			close(received)
+		}
+	}()
+
+	select {
+	case <-received:
+		t.Fatal("packet should have been dropped")
+	case <-time.After(50 * time.Millisecond):
+	}
+	assert.Nil(t, c.Close())
+}
+
+func TestNet_ListenPacket(t *testing.T) {
+	r := NewRouter()
+	n, err := r.NewNet("10.0.0.1")
+	assert.Nil(t, err)
+	assert.Equal(t, []net.IP{net.ParseIP("10.0.0.1")}, n.IPs())
+
+	_, err = r.NewNet("10.0.0.1")
+	assert.Equal(t, errIPInUse, err)
+	_, err = r.NewNet("invalid")
+	assert.Equal(t, errInvalidIP, err)
+
+	testCases := []struct {
+		network string
+		address string
+		addr    string
+		err     error
+	}{
+		{"udp", "10.0.0.1:5000", "10.0.0.1:5000", nil},
+		{"udp4", "10.0.0.1:5000", "", errAddressInUse},
+		{"udp", ":5001", "10.0.0.1:5001", nil},
+		{"udp", "0.0.0.0:0", "10.0.0.1:49152", nil},
+		{"udp", "10.0.0.1:0", "10.0.0.1:49153", nil},
+		{"udp", "10.0.0.2:5002", "", errAddressNotLocal},
+		{"tcp", "10.0.0.1:5002", "", errUnsupportedNet},
+	}
+
+	for i, testCase := range testCases {
+		c, err := n.ListenPacket(testCase.network, testCase.address)
+		assert.Equal(t, testCase.err, err, "testCase: %d", i)
+		if err == nil {
+			assert.Equal(t, testCase.addr, c.LocalAddr().String(), "testCase: %d", i)
+		}
+	}
+}
+
+func TestRouter(t *testing.T) {
+	r := NewRouter()
+	a, err := r.NewNet("10.0.0.1")
+	assert.Nil(t, err)
+	b, err := r.NewNet("10.0.0.2")
+	assert.Nil(t, err)
+
+	connA, err := a.ListenPacket("udp", "10.0.0.1:5000")
+	assert.Nil(t, err)
+	connB, err := b.ListenPacket("udp", "10.0.0.2:5000")
+	assert.Nil(t, err)
+
+	r.SetLatency(20 * time.Millisecond)
+	sent := time.Now()
+	_, err = connA.WriteTo([]byte("ping"), connB.LocalAddr())
+	assert.Nil(t, err)
+	s, from := readFrom(t, connB)
+	assert.Equal(t, "ping", s)
+	assert.Equal(t, connA.LocalAddr().String(), from.String())
+	assert.True(t, time.Since(sent) >= 20*time.Millisecond)
+
+	r.SetLatency(0)
+	r.SetLossRate(1)
+	_, err = connB.WriteTo([]byte("pong"), connA.LocalAddr())
+	assert.Nil(t, err)
+	assertNothingReceived(t, connA)
+
+	// Closed conns free their address
+	_, err = a.ListenPacket("udp", "10.0.0.1:5000")
+	assert.Nil(t, err)
+	_, err = connA.WriteTo([]byte("ping"), connB.LocalAddr())
+	assert.Equal(t, errConnClosed, err)
+}
+
+func TestRouter_NAT(t *testing.T) {
+	r := NewRouter()
+	private, err := r.NewNATedNet("192.168.0.2", "1.2.3.4")
+	assert.Nil(t, err)
+	public, err := r.NewNet("5.6.7.8")
+	assert.Nil(t, err)
+	other, err := r.NewNet("9.9.9.9")
+	assert.Nil(t, err)
+
+	_, err = r.NewNATedNet("192.168.0.3", "5.6.7.8")
+	assert.Equal(t, errIPInUse, err)
+
+	privateConn, err := private.ListenPacket("udp", "192.168.0.2:5000")
+	assert.Nil(t, err)
+	publicConn, err := public.ListenPacket("udp", "5.6.7.8:5000")
+	assert.Nil(t, err)
+	otherConn, err := other.ListenPacket("udp", "9.9.9.9:5000")
+	assert.Nil(t, err)
+
+	// The private address can't be reached from outside
+	_, err = publicConn.WriteTo([]byte("hello"), privateConn.LocalAddr())
+	assert.Nil(t, err)
+
+	_, err = privateConn.WriteTo([]byte("ping"), publicConn.LocalAddr())
+	assert.Nil(t, err)
+	s, mapped := readFrom(t, publicConn)
+	assert.Equal(t, "ping", s)
+	assert.Equal(t, "1.2.3.4:49152", mapped.String())
+
+	// The mapping is the same for every remote address
+	_, err = privateConn.WriteTo([]byte("ping"), otherConn.LocalAddr())
+	assert.Nil(t, err)
+	_, otherMapped := readFrom(t, otherConn)
+	assert.Equal(t, mapped.String(), otherMapped.String())
+
+	_, err = publicConn.WriteTo([]byte("pong"), mapped)
+	assert.Nil(t, err)
+	s, from := readFrom(t, privateConn)
+	assert.Equal(t, "pong", s)
+	assert.Equal(t, publicConn.LocalAddr().String(), from.String())
+
+	// Only IPs the private address sent to are let in
+	otherIP, err := r.NewNet("10.10.10.10")
+	assert.Nil(t, err)
+	unknownConn, err := otherIP.ListenPacket("udp", "10.10.10.10:5000")
+	assert.Nil(t, err)
+	_, err = unknownConn.WriteTo([]byte("hello"), mapped)
+	assert.Nil(t, err)
+	assertNothingReceived(t, privateConn)
+}
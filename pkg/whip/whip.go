@@ -0,0 +1,154 @@
+// Package whip publishes to and plays from media servers implementing WHIP,
+// the WebRTC-HTTP ingestion protocol, and WHEP, its egress counterpart.
+// Both exchange the offer of the RTCPeerConnection for the answer of the
+// server with a single HTTP POST, the session the server creates is ended
+// with a DELETE of its URL. Candidates are not trickled, the offers of the
+// RTCPeerConnection already embed them.
+// https://datatracker.ietf.org/doc/draft-ietf-wish-whip/
+// https://datatracker.ietf.org/doc/draft-murillo-whep/
+package whip
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/pions/webrtc"
+)
+
+const sdpContentType = "application/sdp"
+
+// maxAnswerSize bounds the body read from the server
+const maxAnswerSize = 1 << 20
+
+var (
+	errNoLocation  = errors.New("whip: response has no Location of the session")
+	errContentType = errors.New("whip: response isn't application/sdp")
+	errTooLarge    = errors.New("whip: answer is larger than 1 MiB")
+)
+
+// Client performs the WHIP and WHEP exchanges, its zero value uses
+// http.DefaultClient without authentication
+type Client struct {
+	// HTTPClient sends the requests, http.DefaultClient if nil
+	HTTPClient *http.Client
+
+	// Token is sent as a bearer token when not empty
+	Token string
+}
+
+// Session is a session created by the server, it lasts until it is closed
+type Session struct {
+	// URL is the resource of the session on the server
+	URL string
+
+	client *Client
+}
+
+// Publish ingests the tracks of the RTCPeerConnection to the WHIP endpoint,
+// the tracks should be added before it is called
+func (c *Client) Publish(pc *webrtc.RTCPeerConnection, endpoint string) (*Session, error) {
+	return c.exchange(pc, endpoint)
+}
+
+// Play plays the media of the WHEP endpoint on the RTCPeerConnection, the
+// remote tracks are delivered to its OnTrack handler. Offers have an audio
+// and a video section, recvonly unless a track of their kind was added, so
+// the RTCPeerConnection needs no track to receive the codecs of its
+// MediaEngine.
+func (c *Client) Play(pc *webrtc.RTCPeerConnection, endpoint string) (*Session, error) {
+	return c.exchange(pc, endpoint)
+}
+
+// exchange posts the offer of the RTCPeerConnection to the endpoint and sets
+// the answer of the server as the remote description
+func (c *Client) exchange(pc *webrtc.RTCPeerConnection, endpoint string) (*Session, error) {
+	offer, err := pc.CreateOffer(nil)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, endpoint, strings.NewReader(offer.SDP))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", sdpContentType)
+	resp, err := c.do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	// One more byte than allowed is read to tell a truncated answer apart
+	body, err := ioutil.ReadAll(io.LimitReader(resp.Body, maxAnswerSize+1))
+	if err != nil {
+		return nil, err
+	}
+	tooLarge := len(body) > maxAnswerSize
+	if tooLarge {
+		body = body[:maxAnswerSize]
+	}
+	if resp.StatusCode != http.StatusCreated {
+		return nil, fmt.Errorf("whip: unexpected status %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+
+	// Servers usually return the Location relative to the endpoint
+	location, err := resp.Location()
+	if err != nil {
+		return nil, errNoLocation
+	}
+	s := &Session{URL: location.String(), client: c}
+
+	mediaType, _, err := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if err != nil || mediaType != sdpContentType {
+		// The session was created, it is ended not to leak it on the server
+		_ = s.Close()
+		return nil, errContentType
+	} else if tooLarge {
+		_ = s.Close()
+		return nil, errTooLarge
+	}
+
+	answer := webrtc.RTCSessionDescription{Type: webrtc.RTCSdpTypeAnswer, SDP: string(body)}
+	if err := pc.SetRemoteDescription(answer); err != nil {
+		_ = s.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+// Close ends the session on the server, the RTCPeerConnection is left to
+// the caller to close
+func (s *Session) Close() error {
+	req, err := http.NewRequest(http.MethodDelete, s.URL, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := s.client.do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("whip: unexpected status %s ending the session", resp.Status)
+	}
+	return nil
+}
+
+// do sends the request with the token of the client
+func (c *Client) do(req *http.Request) (*http.Response, error) {
+	if c.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.Token)
+	}
+
+	client := c.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return client.Do(req)
+}
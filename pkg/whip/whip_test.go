@@ -0,0 +1,127 @@
+package whip
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pions/webrtc"
+	"github.com/pions/webrtc/pkg/ice"
+	"github.com/stretchr/testify/assert"
+)
+
+// server answers the offers posted with an RTCPeerConnection of its own
+type server struct {
+	t       *testing.T
+	pc      *webrtc.RTCPeerConnection
+	deleted chan string
+}
+
+func (s *server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("Authorization") != "Bearer secret" {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodPost:
+		assert.Equal(s.t, sdpContentType, r.Header.Get("Content-Type"))
+		offer, err := ioutil.ReadAll(r.Body)
+		assert.Nil(s.t, err)
+		assert.Nil(s.t, s.pc.SetRemoteDescription(webrtc.RTCSessionDescription{Type: webrtc.RTCSdpTypeOffer, SDP: string(offer)}))
+		answer, err := s.pc.CreateAnswer(nil)
+		assert.Nil(s.t, err)
+
+		w.Header().Set("Content-Type", sdpContentType)
+		w.Header().Set("Location", "sessions/1")
+		w.WriteHeader(http.StatusCreated)
+		_, err = w.Write([]byte(answer.SDP))
+		assert.Nil(s.t, err)
+	case http.MethodDelete:
+		s.deleted <- r.URL.Path
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func TestClient_Publish(t *testing.T) {
+	serverPC, err := webrtc.New(webrtc.RTCConfiguration{})
+	assert.Nil(t, err)
+	defer func() { assert.Nil(t, serverPC.Close()) }()
+	connected := make(chan struct{}, 1)
+	serverPC.OnICEConnectionStateChange(func(state ice.ConnectionState) {
+		if state == ice.ConnectionStateConnected {
+			select {
+			case connected <- struct{}{}:
+			default:
+			}
+		}
+	})
+
+	s := &server{t: t, pc: serverPC, deleted: make(chan string, 1)}
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	pc, err := webrtc.New(webrtc.RTCConfiguration{})
+	assert.Nil(t, err)
+	defer func() { assert.Nil(t, pc.Close()) }()
+	_, err = pc.CreateDataChannel("data", nil)
+	assert.Nil(t, err)
+
+	client := &Client{Token: "secret"}
+	session, err := client.Publish(pc, ts.URL+"/whip/endpoint")
+	assert.Nil(t, err)
+	// The Location is resolved against the endpoint
+	assert.Equal(t, ts.URL+"/whip/sessions/1", session.URL)
+
+	select {
+	case <-connected:
+	case <-time.After(10 * time.Second):
+		t.Fatal("client didn't connect")
+	}
+
+	assert.Nil(t, session.Close())
+	assert.Equal(t, "/whip/sessions/1", <-s.deleted)
+}
+
+func TestClient_Errors(t *testing.T) {
+	pc, err := webrtc.New(webrtc.RTCConfiguration{})
+	assert.Nil(t, err)
+	defer func() { assert.Nil(t, pc.Close()) }()
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/no-location":
+			w.Header().Set("Content-Type", sdpContentType)
+			w.WriteHeader(http.StatusCreated)
+		case "/no-sdp":
+			w.Header().Set("Location", "/sessions/1")
+			w.Header().Set("Content-Type", "text/plain")
+			w.WriteHeader(http.StatusCreated)
+		case "/too-large":
+			w.Header().Set("Location", "/sessions/1")
+			w.Header().Set("Content-Type", sdpContentType)
+			w.WriteHeader(http.StatusCreated)
+			_, err := w.Write(make([]byte, maxAnswerSize+1))
+			assert.Nil(t, err)
+		default:
+			http.Error(w, "stream not found", http.StatusNotFound)
+		}
+	}))
+	defer ts.Close()
+
+	client := &Client{}
+	_, err = client.Play(pc, ts.URL+"/no-location")
+	assert.Equal(t, errNoLocation, err)
+	_, err = client.Play(pc, ts.URL+"/no-sdp")
+	assert.Equal(t, errContentType, err)
+	_, err = client.Play(pc, ts.URL+"/too-large")
+	assert.Equal(t, errTooLarge, err)
+	_, err = client.Play(pc, ts.URL+"/missing")
+	if assert.Error(t, err) {
+		assert.True(t, strings.Contains(err.Error(), "stream not found"))
+	}
+}
@@ -0,0 +1,171 @@
+//go:build !js
+
+package webrtc
+
+import (
+	"math/rand"
+	"sync"
+	"sync/atomic"
+
+	"github.com/pions/webrtc/pkg/rtp"
+)
+
+// rtcBroadcastQueueSize is how many packets are queued for a bound track
+// which sends slower than the source writes them, the oldest are dropped
+// beyond it
+const rtcBroadcastQueueSize = 128
+
+// RTCBroadcastTrack fans the RTP packets of one source out to tracks of many
+// RTCPeerConnections, like the media of a publisher forwarded to every
+// viewer. Every bound track is a stream of its own: the packets are copied
+// and get the SSRC of the track, and sequence numbers and timestamps offset
+// by random values, so the peers can't correlate the streams. Every bound
+// track has a queue of its own, a slow RTCPeerConnection loses the oldest
+// packets of its queue instead of delaying the others.
+type RTCBroadcastTrack struct {
+	PayloadType uint8
+	ID          string
+	Label       string
+
+	lock     sync.RWMutex
+	bindings []*rtcBroadcastBinding
+}
+
+// rtcBroadcastBinding is a track a RTCBroadcastTrack forwards to, with the
+// offsets rewriting the packets of the source for it
+type rtcBroadcastBinding struct {
+	track           *RTCTrack
+	sequenceOffset  uint16
+	timestampOffset uint32
+
+	// queue holds the packets to send on the track, they are sent by a
+	// task of the scheduler while sending is 1
+	queue   chan *rtp.Packet
+	sending int32
+}
+
+// NewRTCBroadcastTrack creates a RTCBroadcastTrack of the codec of the
+// payload type
+func NewRTCBroadcastTrack(payloadType uint8, id, label string) *RTCBroadcastTrack {
+	return &RTCBroadcastTrack{
+		PayloadType: payloadType,
+		ID:          id,
+		Label:       label,
+	}
+}
+
+// Bind creates a track of the RTCPeerConnection receiving the packets
+// written to the RTCBroadcastTrack, which is added to the RTCPeerConnection
+// with AddTrack. The track is unbound once the RTCPeerConnection is closed.
+func (b *RTCBroadcastTrack) Bind(pc *RTCPeerConnection) (*RTCTrack, error) {
+	ssrc, err := pc.newSSRC()
+	if err != nil {
+		return nil, err
+	}
+
+	track, err := pc.NewRawRTPTrack(b.PayloadType, ssrc, b.ID, b.Label)
+	if err != nil {
+		return nil, err
+	}
+
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	b.bindings = append(b.bindings, &rtcBroadcastBinding{
+		track:           track,
+		sequenceOffset:  uint16(rand.Uint32()),
+		timestampOffset: rand.Uint32(),
+		queue:           make(chan *rtp.Packet, rtcBroadcastQueueSize),
+	})
+	return track, nil
+}
+
+// Unbind stops forwarding packets to a track created by Bind
+func (b *RTCBroadcastTrack) Unbind(track *RTCTrack) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	for i, binding := range b.bindings {
+		if binding.track == track {
+			b.bindings = append(b.bindings[:i], b.bindings[i+1:]...)
+			return
+		}
+	}
+}
+
+// Tracks returns the tracks the RTCBroadcastTrack is bound to
+func (b *RTCBroadcastTrack) Tracks() []*RTCTrack {
+	b.lock.RLock()
+	defer b.lock.RUnlock()
+
+	tracks := make([]*RTCTrack, len(b.bindings))
+	for i, binding := range b.bindings {
+		tracks[i] = binding.track
+	}
+	return tracks
+}
+
+// WriteRTP queues a copy of the packet on every bound track, it doesn't
+// wait for the packets to be sent. Tracks of closed RTCPeerConnections are
+// unbound.
+func (b *RTCBroadcastTrack) WriteRTP(p *rtp.Packet) error {
+	b.lock.RLock()
+	bindings := append([]*rtcBroadcastBinding{}, b.bindings...)
+	b.lock.RUnlock()
+
+	for _, binding := range bindings {
+		if binding.track.isDone() {
+			b.Unbind(binding.track)
+			continue
+		}
+
+		// The copy is encrypted in place when it is sent
+		packet := p.Clone()
+		packet.PayloadType = binding.track.PayloadType
+		packet.SSRC = binding.track.Ssrc
+		packet.SequenceNumber += binding.sequenceOffset
+		packet.Timestamp += binding.timestampOffset
+		binding.push(packet)
+	}
+	return nil
+}
+
+// push queues the packet, dropping the oldest one of a full queue, and has
+// the scheduler send the queue unless it is being sent
+func (b *rtcBroadcastBinding) push(packet *rtp.Packet) {
+	for {
+		select {
+		case b.queue <- packet:
+			if atomic.CompareAndSwapInt32(&b.sending, 0, 1) {
+				workers.schedule(b.send)
+			}
+			return
+		default:
+		}
+
+		select {
+		case <-b.queue:
+		default:
+		}
+	}
+}
+
+// send sends the queued packets until the queue is empty
+func (b *rtcBroadcastBinding) send() {
+	for {
+		select {
+		case packet := <-b.queue:
+			// The RTCPeerConnection reports the errors of sending, the track
+			// of a closed one is unbound by the next WriteRTP
+			b.track.WriteRTP(packet) // nolint: errcheck
+			continue
+		default:
+		}
+
+		// A packet queued after the queue was found empty, but before sending
+		// was cleared, is sent here
+		atomic.StoreInt32(&b.sending, 0)
+		if len(b.queue) == 0 || !atomic.CompareAndSwapInt32(&b.sending, 0, 1) {
+			return
+		}
+	}
+}
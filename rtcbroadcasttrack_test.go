@@ -0,0 +1,127 @@
+//go:build !js
+
+package webrtc
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pions/webrtc/pkg/rtp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRTCBroadcastTrack(t *testing.T) {
+	RegisterDefaultCodecs()
+
+	broadcast := NewRTCBroadcastTrack(DefaultPayloadTypeVP8, "video", "pion")
+
+	var pcs []*RTCPeerConnection
+	var sent []chan rtp.Packet
+	for i := 0; i < 2; i++ {
+		pc, err := New(RTCConfiguration{})
+		assert.Nil(t, err)
+		pcs = append(pcs, pc)
+
+		track, err := broadcast.Bind(pc)
+		assert.Nil(t, err)
+		sender, err := pc.AddTrack(track)
+		assert.Nil(t, err)
+
+		packets := make(chan rtp.Packet, 10)
+		sender.OnSentRTPPacket(func(p *rtp.Packet) {
+			packet := *p
+			packet.Payload = append([]byte{}, p.Payload...)
+			packets <- packet
+		})
+		sent = append(sent, packets)
+	}
+	tracks := broadcast.Tracks()
+	assert.Equal(t, 2, len(tracks))
+	assert.NotEqual(t, tracks[0].Ssrc, tracks[1].Ssrc)
+
+	receive := func(packets chan rtp.Packet) rtp.Packet {
+		select {
+		case p := <-packets:
+			return p
+		case <-time.After(time.Second):
+			t.Fatal("packet not sent")
+		}
+		return rtp.Packet{}
+	}
+
+	source := []*rtp.Packet{
+		{Version: 2, PayloadType: 111, SSRC: 5000, SequenceNumber: 65535, Timestamp: 3000, Payload: []byte{0x10, 0x01}},
+		{Version: 2, PayloadType: 111, SSRC: 5000, SequenceNumber: 0, Timestamp: 6000, Payload: []byte{0x10, 0x02}},
+	}
+	for _, p := range source {
+		assert.Nil(t, broadcast.WriteRTP(p))
+	}
+
+	// Every peer gets a stream of its own, keeping the spacing of the source
+	for i, track := range tracks {
+		first, second := receive(sent[i]), receive(sent[i])
+		assert.Equal(t, track.Ssrc, first.SSRC, "testCase: %d", i)
+		assert.Equal(t, track.Ssrc, second.SSRC, "testCase: %d", i)
+		assert.Equal(t, uint8(DefaultPayloadTypeVP8), first.PayloadType, "testCase: %d", i)
+		assert.Equal(t, first.SequenceNumber+1, second.SequenceNumber, "testCase: %d", i)
+		assert.Equal(t, first.Timestamp+3000, second.Timestamp, "testCase: %d", i)
+		assert.Equal(t, []byte{0x10, 0x01}, first.Payload, "testCase: %d", i)
+		assert.Equal(t, []byte{0x10, 0x02}, second.Payload, "testCase: %d", i)
+	}
+
+	// The source packets are left untouched
+	assert.Equal(t, uint32(5000), source[0].SSRC)
+	assert.Equal(t, []byte{0x10, 0x01}, source[0].Payload)
+
+	// Closed RTCPeerConnections are unbound
+	assert.Nil(t, pcs[0].Close())
+	assert.Nil(t, broadcast.WriteRTP(source[0]))
+	assert.Equal(t, []*RTCTrack{tracks[1]}, broadcast.Tracks())
+	receive(sent[1])
+
+	broadcast.Unbind(tracks[1])
+	assert.Empty(t, broadcast.Tracks())
+	assert.Nil(t, pcs[1].Close())
+}
+
+func TestRTCBroadcastTrack_SlowTrack(t *testing.T) {
+	RegisterDefaultCodecs()
+
+	broadcast := NewRTCBroadcastTrack(DefaultPayloadTypeVP8, "video", "pion")
+	pc, err := New(RTCConfiguration{})
+	assert.Nil(t, err)
+	track, err := broadcast.Bind(pc)
+	assert.Nil(t, err)
+	sender, err := pc.AddTrack(track)
+	assert.Nil(t, err)
+
+	// The track sends nothing until released
+	release := make(chan struct{})
+	sent := make(chan uint16, 2*rtcBroadcastQueueSize)
+	sender.OnSentRTPPacket(func(p *rtp.Packet) {
+		<-release
+		sent <- p.SequenceNumber
+	})
+
+	// The writer isn't blocked by the slow track, which loses the oldest
+	// packets it queued
+	count := 2 * rtcBroadcastQueueSize
+	for i := 0; i < count; i++ {
+		assert.Nil(t, broadcast.WriteRTP(&rtp.Packet{Version: 2, SequenceNumber: uint16(i), Payload: []byte{0x00}}))
+	}
+	close(release)
+
+	last := uint16(count-1) + broadcast.bindings[0].sequenceOffset
+	var received []uint16
+	for len(received) == 0 || received[len(received)-1] != last {
+		select {
+		case sequenceNumber := <-sent:
+			received = append(received, sequenceNumber)
+		case <-time.After(time.Second):
+			t.Fatalf("last packet not sent, got %d packets", len(received))
+		}
+	}
+	assert.True(t, len(received) <= rtcBroadcastQueueSize+1, "sent %d packets", len(received))
+
+	assert.Nil(t, pc.Close())
+}
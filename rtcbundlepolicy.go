@@ -3,7 +3,14 @@ package webrtc
 // RTCBundlePolicy affects which media tracks are negotiated if the remote
 // endpoint is not bundle-aware, and what ICE candidates are gathered. If the
 // remote endpoint is bundle-aware, all media tracks and data channels are
-// bundled onto the same transport.
+// bundled onto the same transport. Media sections offered without candidates
+// of their own are marked bundle-only.
+//
+// Every media section shares the transport of the RTCPeerConnection, separate
+// transports per media section are not supported. Media sections an offer
+// doesn't bundle are accepted on it as well with the balanced policy and
+// rejected with max-bundle and max-compat. Answers which don't bundle the
+// offered sections are accepted on the shared transport with every policy.
 type RTCBundlePolicy int
 
 const (
@@ -15,7 +22,8 @@ const (
 
 	// RTCBundlePolicyMaxCompat indicates to gather ICE candidates for each
 	// track. If the remote endpoint is not bundle-aware, negotiate all media
-	// tracks on separate transports.
+	// tracks on separate transports. Separate transports are not supported,
+	// only the tracks max-bundle negotiates are.
 	RTCBundlePolicyMaxCompat
 
 	// RTCBundlePolicyMaxBundle indicates to gather ICE candidates for only
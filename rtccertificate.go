@@ -5,10 +5,14 @@ import (
 	"crypto/ecdsa"
 	"crypto/rand"
 	"crypto/rsa"
+	"crypto/sha256"
 	"crypto/x509"
 	"crypto/x509/pkix"
 	"encoding/hex"
+	"encoding/pem"
+	"fmt"
 	"math/big"
+	"strings"
 	"time"
 
 	"github.com/pions/webrtc/pkg/rtcerr"
@@ -89,8 +93,87 @@ func (c RTCCertificate) Expires() time.Time {
 
 // GetFingerprints returns the list of certificate fingerprints, one of which
 // is computed with the digest algorithm used in the certificate signature.
-func (c RTCCertificate) GetFingerprints() {
-	panic("not implemented yet.") // nolint
+func (c RTCCertificate) GetFingerprints() []RTCDtlsFingerprint {
+	if c.x509Cert == nil {
+		return nil
+	}
+
+	digest := sha256.Sum256(c.x509Cert.Raw)
+	hexBytes := make([]string, len(digest))
+	for i, b := range digest {
+		hexBytes[i] = fmt.Sprintf("%.2x", b)
+	}
+	return []RTCDtlsFingerprint{{
+		Algorithm: "sha-256",
+		Value:     strings.Join(hexBytes, ":"),
+	}}
+}
+
+// PEM returns the private key, PKCS#8 encoded, and the x509Cert as PEM
+// blocks. Servers can persist them and load the certificate with
+// NewRTCCertificateFromPEM after a restart, keeping their DTLS fingerprint.
+func (c RTCCertificate) PEM() (string, error) {
+	if c.x509Cert == nil {
+		return "", &rtcerr.InvalidStateError{Err: ErrCertificatePEM}
+	}
+
+	keyDER, err := x509.MarshalPKCS8PrivateKey(c.privateKey)
+	if err != nil {
+		return "", &rtcerr.NotSupportedError{Err: ErrPrivateKeyType}
+	}
+
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER})
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: c.x509Cert.Raw})
+	return string(keyPEM) + string(certPEM), nil
+}
+
+// NewRTCCertificateFromPEM parses a certificate persisted by PEM. Besides
+// PKCS#8 the private key may be encoded like OpenSSL does for RSA and EC keys.
+func NewRTCCertificateFromPEM(pems string) (*RTCCertificate, error) {
+	var privateKey crypto.PrivateKey
+	var cert *x509.Certificate
+
+	rest := []byte(pems)
+	for {
+		var block *pem.Block
+		if block, rest = pem.Decode(rest); block == nil {
+			break
+		}
+
+		var err error
+		switch block.Type {
+		case "PRIVATE KEY":
+			privateKey, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+		case "RSA PRIVATE KEY":
+			privateKey, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+		case "EC PRIVATE KEY":
+			privateKey, err = x509.ParseECPrivateKey(block.Bytes)
+		case "CERTIFICATE":
+			cert, err = x509.ParseCertificate(block.Bytes)
+		}
+		if err != nil {
+			return nil, &rtcerr.SyntaxError{Err: err}
+		}
+	}
+
+	if privateKey == nil || cert == nil {
+		return nil, &rtcerr.SyntaxError{Err: ErrCertificatePEM}
+	}
+
+	var public crypto.PublicKey
+	switch sk := privateKey.(type) {
+	case *rsa.PrivateKey:
+		public = sk.Public()
+	case *ecdsa.PrivateKey:
+		public = sk.Public()
+	default:
+		return nil, &rtcerr.NotSupportedError{Err: ErrPrivateKeyType}
+	}
+	if key, ok := public.(interface{ Equal(crypto.PublicKey) bool }); !ok || !key.Equal(cert.PublicKey) {
+		return nil, &rtcerr.InvalidAccessError{Err: ErrCertificateKeyMismatch}
+	}
+
+	return &RTCCertificate{privateKey: privateKey, x509Cert: cert}, nil
 }
 
 // GenerateCertificate causes the creation of an X.509 certificate and
@@ -1,3 +1,5 @@
+//go:build !js
+
 package webrtc
 
 import (
@@ -8,9 +10,11 @@ import (
 	"crypto/tls"
 	"crypto/x509"
 	"encoding/pem"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/pions/webrtc/pkg/rtcerr"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -86,3 +90,61 @@ func TestGenerateCertificateExpires(t *testing.T) {
 	now := time.Now()
 	assert.False(t, cert.Expires().IsZero() || now.After(cert.Expires()))
 }
+
+func TestRTCCertificate_PEM(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.Nil(t, err)
+	ecdsaKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.Nil(t, err)
+
+	for i, sk := range []interface{}{rsaKey, ecdsaKey} {
+		cert, err := GenerateCertificate(sk)
+		assert.Nil(t, err, "testCase: %d", i)
+
+		pems, err := cert.PEM()
+		assert.Nil(t, err, "testCase: %d", i)
+
+		parsed, err := NewRTCCertificateFromPEM(pems)
+		assert.Nil(t, err, "testCase: %d", i)
+		assert.True(t, cert.Equals(*parsed), "testCase: %d", i)
+		assert.Equal(t, cert.GetFingerprints(), parsed.GetFingerprints(), "testCase: %d", i)
+	}
+
+	// Keys encoded like OpenSSL does are accepted too
+	cert, err := GenerateCertificate(rsaKey)
+	assert.Nil(t, err)
+	pems := string(pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(rsaKey)})) +
+		string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.x509Cert.Raw}))
+	parsed, err := NewRTCCertificateFromPEM(pems)
+	assert.Nil(t, err)
+	assert.True(t, cert.Equals(*parsed))
+
+	_, err = NewRTCCertificateFromPEM(string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.x509Cert.Raw})))
+	assert.Equal(t, &rtcerr.SyntaxError{Err: ErrCertificatePEM}, err)
+
+	otherCert, err := GenerateCertificate(ecdsaKey)
+	assert.Nil(t, err)
+	otherPEMs, err := otherCert.PEM()
+	assert.Nil(t, err)
+	mismatched := otherPEMs[:strings.Index(otherPEMs, "-----BEGIN CERTIFICATE")] +
+		string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.x509Cert.Raw}))
+	_, err = NewRTCCertificateFromPEM(mismatched)
+	assert.Equal(t, &rtcerr.InvalidAccessError{Err: ErrCertificateKeyMismatch}, err)
+}
+
+func TestRTCCertificate_GetFingerprints(t *testing.T) {
+	sk, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.Nil(t, err)
+	cert, err := GenerateCertificate(sk)
+	assert.Nil(t, err)
+
+	pc, err := New(RTCConfiguration{Certificates: []RTCCertificate{*cert}})
+	assert.Nil(t, err)
+	defer func() { assert.Nil(t, pc.Close()) }()
+
+	// DTLS authenticates with the configured certificate
+	fingerprints := cert.GetFingerprints()
+	assert.Equal(t, 1, len(fingerprints))
+	assert.Equal(t, "sha-256", fingerprints[0].Algorithm)
+	assert.Equal(t, strings.ToLower(pc.networkManager.DTLSFingerprint()), fingerprints[0].Value)
+}
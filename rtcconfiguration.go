@@ -43,7 +43,18 @@ type RTCConfiguration struct {
 	Certificates []RTCCertificate
 
 	// IceCandidatePoolSize describes the size of the prefetched ICE pool.
+	// Any size other than zero gathers the candidates of the ICE servers as
+	// soon as the RTCPeerConnection is created, so creating the first offer
+	// or answer doesn't wait for them. Otherwise they are gathered when the
+	// first offer or answer is created. Every media section is bundled on a
+	// single ICE transport, so a single set of candidates is gathered for any
+	// size, with one allocation per URL of the TURN servers.
 	IceCandidatePoolSize uint8
+
+	// SdpSemantics controls whether the RTCPeerConnection uses Unified Plan
+	// or Plan B style descriptions, for interoperability with endpoints that
+	// still use Plan B.
+	SdpSemantics RTCSdpSemantics
 }
 
 func (c RTCConfiguration) getIceServers() (*[]*ice.URL, error) {
@@ -1,3 +1,5 @@
+//go:build !js
+
 package webrtc
 
 import (
@@ -103,6 +105,22 @@ type RTCDataChannel struct {
 	// the underlying data transport has been established (or re-established).
 	OnOpen func()
 
+	// events runs the handlers of the events of the channel in the order
+	// they arrived. OnDataChannel runs before the first message of the
+	// channel, so an Onmessage it sets receives every message, and messages
+	// are delivered in the order of their SCTP stream.
+	events operations
+
+	// counters counts the messages and bytes of application data sent and
+	// received, see RTCPeerConnection.GetStats
+	counters struct {
+		sync.Mutex
+		messagesSent     uint64
+		messagesReceived uint64
+		bytesSent        uint64
+		bytesReceived    uint64
+	}
+
 	// Deprecated: Will be removed when networkManager is deprecated.
 	rtcPeerConnection *RTCPeerConnection
 }
@@ -139,9 +157,38 @@ func (d *RTCDataChannel) Send(p datachannel.Payload) error {
 	if err := d.rtcPeerConnection.networkManager.SendDataChannelMessage(p, *d.ID); err != nil {
 		return &rtcerr.UnknownError{Err: err}
 	}
+
+	d.counters.Lock()
+	d.counters.messagesSent++
+	d.counters.bytesSent += uint64(payloadLength(p))
+	d.counters.Unlock()
 	return nil
 }
 
+// countReceived counts a message received on the channel
+func (d *RTCDataChannel) countReceived(p datachannel.Payload) {
+	d.counters.Lock()
+	d.counters.messagesReceived++
+	d.counters.bytesReceived += uint64(payloadLength(p))
+	d.counters.Unlock()
+}
+
+// payloadLength returns the length of the application data of the payload
+func payloadLength(p datachannel.Payload) int {
+	switch p := p.(type) {
+	case datachannel.PayloadString:
+		return len(p.Data)
+	case *datachannel.PayloadString:
+		return len(p.Data)
+	case datachannel.PayloadBinary:
+		return len(p.Data)
+	case *datachannel.PayloadBinary:
+		return len(p.Data)
+	default:
+		return 0
+	}
+}
+
 func (d *RTCDataChannel) doOnOpen() {
 	d.RLock()
 	onOpen := d.OnOpen
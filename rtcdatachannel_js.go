@@ -0,0 +1,186 @@
+//go:build js && wasm
+
+package webrtc
+
+import (
+	"sync"
+	"syscall/js"
+
+	"github.com/pions/webrtc/pkg/datachannel"
+)
+
+// RTCDataChannel represents a WebRTC DataChannel
+// The RTCDataChannel interface represents a network channel
+// which can be used for bidirectional peer-to-peer transfers of arbitrary data
+//
+// Compiled to WebAssembly it wraps the RTCDataChannel of the browser, its
+// fields are those the browser reports, ID and ReadyState are updated once
+// the channel opens and closes. Read them while holding the lock of the
+// channel.
+type RTCDataChannel struct {
+	sync.RWMutex
+
+	// Label represents a label that can be used to distinguish this
+	// RTCDataChannel object from other RTCDataChannel objects.
+	Label string
+
+	// Ordered represents if the RTCDataChannel is ordered, and false if
+	// out-of-order delivery is allowed.
+	Ordered bool
+
+	// MaxPacketLifeTime represents the length of the time window (msec) during
+	// which transmissions and retransmissions may occur in unreliable mode.
+	MaxPacketLifeTime *uint16
+
+	// MaxRetransmits represents the maximum number of retransmissions that are
+	// attempted in unreliable mode.
+	MaxRetransmits *uint16
+
+	// Protocol represents the name of the sub-protocol used with this
+	// RTCDataChannel.
+	Protocol string
+
+	// Negotiated represents whether this RTCDataChannel was negotiated by the
+	// application (true), or not (false).
+	Negotiated bool
+
+	// ID represents the ID for this RTCDataChannel, nil until it is
+	// negotiated.
+	ID *uint16
+
+	// ReadyState represents the state of the RTCDataChannel object.
+	ReadyState RTCDataChannelState
+
+	// Onmessage designates an event handler which is invoked on a message
+	// arrival from a remote peer.
+	//
+	// Deprecated: use OnMessage instead.
+	Onmessage func(datachannel.Payload)
+
+	// OnMessage designates an event handler which is invoked on a message
+	// arrival from a remote peer.
+	OnMessage func(datachannel.Payload)
+
+	// OnOpen designates an event handler which is invoked when
+	// the underlying data transport has been established (or re-established).
+	OnOpen func()
+
+	// events runs the handlers of the events of the channel in the order
+	// the browser dispatched them
+	events operations
+
+	underlying js.Value
+	callbacks  []js.Func
+}
+
+// newRTCDataChannel wraps the RTCDataChannel of the browser, binary messages
+// are received as ArrayBuffers
+func newRTCDataChannel(underlying js.Value) *RTCDataChannel {
+	underlying.Set("binaryType", "arraybuffer")
+
+	d := &RTCDataChannel{
+		Label:             underlying.Get("label").String(),
+		Ordered:           underlying.Get("ordered").Bool(),
+		MaxPacketLifeTime: valueToUint16(underlying.Get("maxPacketLifeTime")),
+		MaxRetransmits:    valueToUint16(underlying.Get("maxRetransmits")),
+		Protocol:          underlying.Get("protocol").String(),
+		Negotiated:        underlying.Get("negotiated").Bool(),
+		ID:                valueToUint16(underlying.Get("id")),
+		ReadyState:        newRTCDataChannelState(underlying.Get("readyState").String()),
+		underlying:        underlying,
+	}
+	d.callbacks = append(d.callbacks,
+		setEventHandler(underlying, "onopen", func(js.Value) {
+			d.Lock()
+			d.ReadyState = RTCDataChannelStateOpen
+			d.ID = valueToUint16(underlying.Get("id"))
+			d.Unlock()
+			d.events.push(d.doOnOpen)
+		}),
+		setEventHandler(underlying, "onmessage", func(event js.Value) {
+			// The data is copied out of the browser before the event returns
+			payload := valueToPayload(event.Get("data"))
+			d.events.push(func() { d.doOnMessage(payload) })
+		}),
+		setEventHandler(underlying, "onclose", func(js.Value) {
+			d.Lock()
+			defer d.Unlock()
+			d.ReadyState = RTCDataChannelStateClosed
+			for _, property := range []string{"onopen", "onmessage", "onclose"} {
+				underlying.Set(property, js.Null())
+			}
+			for _, callback := range d.callbacks {
+				callback.Release()
+			}
+			d.callbacks = nil
+		}),
+	)
+	return d
+}
+
+// Send sends the passed message to the DataChannel peer
+func (d *RTCDataChannel) Send(p datachannel.Payload) error {
+	var data interface{}
+	switch p := p.(type) {
+	case datachannel.PayloadString:
+		data = string(p.Data)
+	case *datachannel.PayloadString:
+		data = string(p.Data)
+	case datachannel.PayloadBinary:
+		data = bytesToValue(p.Data)
+	case *datachannel.PayloadBinary:
+		data = bytesToValue(p.Data)
+	default:
+		return ErrUnknownType
+	}
+
+	_, err := callBrowser(func() js.Value { return d.underlying.Call("send", data) })
+	return err
+}
+
+// Close closes the channel
+func (d *RTCDataChannel) Close() error {
+	_, err := callBrowser(func() js.Value { return d.underlying.Call("close") })
+	return err
+}
+
+func (d *RTCDataChannel) doOnOpen() {
+	d.RLock()
+	onOpen := d.OnOpen
+	d.RUnlock()
+	if onOpen != nil {
+		onOpen()
+	}
+}
+
+func (d *RTCDataChannel) doOnMessage(payload datachannel.Payload) {
+	d.RLock()
+	onMessage := d.OnMessage
+	if onMessage == nil {
+		onMessage = d.Onmessage
+	}
+	d.RUnlock()
+	if onMessage != nil {
+		onMessage(payload)
+	}
+}
+
+// bytesToValue copies the bytes to a Uint8Array
+func bytesToValue(b []byte) js.Value {
+	array := js.Global().Get("Uint8Array").New(len(b))
+	js.CopyBytesToJS(array, b)
+	return array
+}
+
+// valueToPayload copies the data of a message event, a string or an
+// ArrayBuffer, to a payload
+func valueToPayload(data js.Value) datachannel.Payload {
+	if data.Type() == js.TypeString {
+		return datachannel.PayloadString{Data: []byte(data.String())}
+	}
+
+	array := js.Global().Get("Uint8Array").New(data)
+	b := make([]byte, array.Get("length").Int())
+	js.CopyBytesToGo(b, array)
+	return datachannel.PayloadBinary{Data: b}
+}
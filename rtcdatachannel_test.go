@@ -1,7 +1,14 @@
+//go:build !js
+
 package webrtc
 
 import (
 	"testing"
+	"time"
+
+	"github.com/pions/webrtc/internal/network"
+	"github.com/pions/webrtc/pkg/datachannel"
+	"github.com/stretchr/testify/assert"
 )
 
 func TestGenerateDataChannelID(t *testing.T) {
@@ -33,3 +40,44 @@ func TestGenerateDataChannelID(t *testing.T) {
 		}
 	}
 }
+
+func TestRTCDataChannel_EventOrder(t *testing.T) {
+	pc, err := New(RTCConfiguration{})
+	assert.Nil(t, err)
+	defer func() { assert.Nil(t, pc.Close()) }()
+
+	const messageCount = 100
+	events := make(chan string, messageCount+1)
+
+	pc.OnDataChannel(func(d *RTCDataChannel) {
+		// The messages arrive before Onmessage is set
+		time.Sleep(10 * time.Millisecond)
+		events <- "open"
+
+		d.Lock()
+		d.Onmessage = func(p datachannel.Payload) {
+			events <- string(p.(*datachannel.PayloadString).Data)
+		}
+		d.Unlock()
+	})
+
+	pc.dataChannelEventHandler(&network.DataChannelCreated{Label: "data"})
+	for i := 0; i < messageCount; i++ {
+		pc.dataChannelEventHandler(&network.DataChannelMessage{
+			Payload: &datachannel.PayloadString{Data: []byte{byte(i)}},
+		})
+	}
+
+	expected := []string{"open"}
+	for i := 0; i < messageCount; i++ {
+		expected = append(expected, string([]byte{byte(i)}))
+	}
+	for i, e := range expected {
+		select {
+		case event := <-events:
+			assert.Equal(t, e, event, "event: %d", i)
+		case <-time.After(time.Second):
+			assert.FailNow(t, "event timed out", "event: %d", i)
+		}
+	}
+}
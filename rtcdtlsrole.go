@@ -0,0 +1,100 @@
+package webrtc
+
+import (
+	"github.com/pions/webrtc/internal/sdp"
+	"github.com/pions/webrtc/pkg/rtcerr"
+)
+
+// RTCDtlsRole indicates the role of the DTLS transport, the client initiates
+// the handshake and the server accepts it.
+type RTCDtlsRole int
+
+const (
+	// RTCDtlsRoleAuto indicates that the role is left to the remote peer,
+	// it is offered with the setup attribute actpass.
+	RTCDtlsRoleAuto RTCDtlsRole = iota + 1
+
+	// RTCDtlsRoleClient indicates that the DTLS transport initiates the
+	// handshake, it is signaled with the setup attribute active.
+	RTCDtlsRoleClient
+
+	// RTCDtlsRoleServer indicates that the DTLS transport accepts the
+	// handshake, it is signaled with the setup attribute passive.
+	RTCDtlsRoleServer
+)
+
+// This is done this way because of a linter.
+const (
+	rtcDtlsRoleAutoStr   = "auto"
+	rtcDtlsRoleClientStr = "client"
+	rtcDtlsRoleServerStr = "server"
+)
+
+func newRTCDtlsRole(raw string) RTCDtlsRole {
+	switch raw {
+	case rtcDtlsRoleAutoStr:
+		return RTCDtlsRoleAuto
+	case rtcDtlsRoleClientStr:
+		return RTCDtlsRoleClient
+	case rtcDtlsRoleServerStr:
+		return RTCDtlsRoleServer
+	default:
+		return RTCDtlsRole(Unknown)
+	}
+}
+
+func (r RTCDtlsRole) String() string {
+	switch r {
+	case RTCDtlsRoleAuto:
+		return rtcDtlsRoleAutoStr
+	case RTCDtlsRoleClient:
+		return rtcDtlsRoleClientStr
+	case RTCDtlsRoleServer:
+		return rtcDtlsRoleServerStr
+	default:
+		return ErrUnknownType.Error()
+	}
+}
+
+// connectionRole returns the setup attribute the role is signaled with
+func (r RTCDtlsRole) connectionRole() sdp.ConnectionRole {
+	switch r {
+	case RTCDtlsRoleClient:
+		return sdp.ConnectionRoleActive
+	case RTCDtlsRoleServer:
+		return sdp.ConnectionRolePassive
+	default:
+		return sdp.ConnectionRoleActpass
+	}
+}
+
+// negotiateDTLSRole returns the local role resolved from the setup attribute
+// of the remote description. Offers are made with actpass, the answerer
+// picks the role and is the client unless the offerer insists on it.
+// https://tools.ietf.org/html/rfc5763#section-5
+func negotiateDTLSRole(remote *sdp.SessionDescription, weOffer bool) (RTCDtlsRole, error) {
+	remoteRole, ok := remote.GetConnectionRole()
+	if !ok {
+		// Without the attribute the offerer is active, RFC 4145 Section 4
+		remoteRole = sdp.ConnectionRoleActive
+		if !weOffer {
+			remoteRole = sdp.ConnectionRoleActpass
+		}
+	}
+
+	switch remoteRole {
+	case sdp.ConnectionRoleActive:
+		return RTCDtlsRoleServer, nil
+	case sdp.ConnectionRolePassive:
+		return RTCDtlsRoleClient, nil
+	case sdp.ConnectionRoleActpass:
+		if weOffer {
+			// Answers must pick a role, the remote peer is taken to be the
+			// client like endpoints answering active
+			return RTCDtlsRoleServer, nil
+		}
+		return RTCDtlsRoleClient, nil
+	default:
+		return RTCDtlsRole(Unknown), &rtcerr.NotSupportedError{Err: ErrDTLSRoleHoldconn}
+	}
+}
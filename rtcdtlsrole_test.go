@@ -0,0 +1,80 @@
+//go:build !js
+
+package webrtc
+
+import (
+	"testing"
+
+	"github.com/pions/webrtc/internal/sdp"
+	"github.com/pions/webrtc/pkg/rtcerr"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewRTCDtlsRole(t *testing.T) {
+	testCases := []struct {
+		roleString   string
+		expectedRole RTCDtlsRole
+	}{
+		{"unknown", RTCDtlsRole(Unknown)},
+		{"auto", RTCDtlsRoleAuto},
+		{"client", RTCDtlsRoleClient},
+		{"server", RTCDtlsRoleServer},
+	}
+
+	for i, testCase := range testCases {
+		assert.Equal(t,
+			testCase.expectedRole,
+			newRTCDtlsRole(testCase.roleString),
+			"testCase: %d %v", i, testCase,
+		)
+	}
+}
+
+func TestRTCDtlsRole_String(t *testing.T) {
+	testCases := []struct {
+		role           RTCDtlsRole
+		expectedString string
+	}{
+		{RTCDtlsRole(Unknown), "unknown"},
+		{RTCDtlsRoleAuto, "auto"},
+		{RTCDtlsRoleClient, "client"},
+		{RTCDtlsRoleServer, "server"},
+	}
+
+	for i, testCase := range testCases {
+		assert.Equal(t,
+			testCase.expectedString,
+			testCase.role.String(),
+			"testCase: %d %v", i, testCase,
+		)
+	}
+}
+
+func TestNegotiateDTLSRole(t *testing.T) {
+	testCases := []struct {
+		setup        string
+		weOffer      bool
+		expectedRole RTCDtlsRole
+		expectedErr  error
+	}{
+		{"active", true, RTCDtlsRoleServer, nil},
+		{"passive", true, RTCDtlsRoleClient, nil},
+		{"actpass", true, RTCDtlsRoleServer, nil},
+		{"", true, RTCDtlsRoleServer, nil},
+		{"actpass", false, RTCDtlsRoleClient, nil},
+		{"active", false, RTCDtlsRoleServer, nil},
+		{"passive", false, RTCDtlsRoleClient, nil},
+		{"", false, RTCDtlsRoleClient, nil},
+		{"holdconn", false, RTCDtlsRole(Unknown), &rtcerr.NotSupportedError{Err: ErrDTLSRoleHoldconn}},
+	}
+
+	for i, testCase := range testCases {
+		media := sdp.NewJSEPMediaDescription("audio", []string{})
+		if testCase.setup != "" {
+			media.WithValueAttribute(sdp.AttrKeyConnectionSetup, testCase.setup)
+		}
+		role, err := negotiateDTLSRole((&sdp.SessionDescription{}).WithMedia(media), testCase.weOffer)
+		assert.Equal(t, testCase.expectedErr, err, "testCase: %d", i)
+		assert.Equal(t, testCase.expectedRole, role, "testCase: %d", i)
+	}
+}
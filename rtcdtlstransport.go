@@ -1,13 +1,92 @@
+//go:build !js
+
 package webrtc
 
+import (
+	"crypto/x509"
+	"sync"
+
+	"github.com/pions/webrtc/internal/dtls"
+	"github.com/pions/webrtc/internal/network"
+	"github.com/pions/webrtc/pkg/rtcerr"
+)
+
+var (
+	// ErrFingerprintMismatch indicates that the certificate the remote peer
+	// presented during the DTLS handshake doesn't match the fingerprints of
+	// its session description.
+	ErrFingerprintMismatch = dtls.ErrFingerprintMismatch
+
+	// ErrNoFingerprint indicates that the session description of the remote
+	// peer has no fingerprint with a supported hash function.
+	ErrNoFingerprint = dtls.ErrNoFingerprint
+)
+
 // RTCDtlsTransport allows an application access to information about the DTLS
 // transport over which RTP and RTCP packets are sent and received by
 // RTCRtpSender and RTCRtpReceiver, as well other data such as SCTP packets sent
 // and received by data channels.
 type RTCDtlsTransport struct {
-	// Transport RTCIceTransport
-	// State     RTCDtlsTransportState
+	sync.RWMutex
+
+	// Transport is the ICE transport the DTLS packets are sent over.
+	Transport *RTCIceTransport
+
+	// State is the current state of the transport, read it while holding
+	// the lock of the transport.
+	State RTCDtlsTransportState
+
+	// OnStateChange designates an event handler which is called with the
+	// state of the transport whenever it changes. Set it while holding the
+	// lock of the transport.
+	OnStateChange func(RTCDtlsTransportState)
 
-	// OnStateChange func()
 	// OnError       func()
+
+	manager *network.Manager
+}
+
+func newRTCDtlsTransport(m *network.Manager) *RTCDtlsTransport {
+	return &RTCDtlsTransport{
+		Transport: newRTCIceTransport(m),
+		State:     RTCDtlsTransportStateNew,
+		manager:   m,
+	}
+}
+
+// GetRemoteCertificates returns the DER encoded certificate chain the remote
+// peer authenticated the DTLS handshake with, it is empty until the handshake
+// completed. Only the certificate of the remote peer itself is reported.
+// https://www.w3.org/TR/webrtc/#dom-rtcdtlstransport-getremotecertificates
+func (t *RTCDtlsTransport) GetRemoteCertificates() [][]byte {
+	cert := t.manager.DTLSRemoteCertificate()
+	if cert == nil {
+		return [][]byte{}
+	}
+	return [][]byte{cert}
+}
+
+func (t *RTCDtlsTransport) stateChange(state RTCDtlsTransportState) {
+	t.Lock()
+	// Closed and failed are final
+	if t.State == state || t.State == RTCDtlsTransportStateClosed || t.State == RTCDtlsTransportStateFailed {
+		t.Unlock()
+		return
+	}
+	t.State = state
+	onStateChange := t.OnStateChange
+	t.Unlock()
+
+	if onStateChange != nil {
+		onStateChange(state)
+	}
+}
+
+// dtlsCertificate returns the certificate in the encoding DTLS loads it from
+func (c RTCCertificate) dtlsCertificate() (*dtls.Certificate, error) {
+	keyDER, err := x509.MarshalPKCS8PrivateKey(c.privateKey)
+	if err != nil {
+		return nil, &rtcerr.NotSupportedError{Err: ErrPrivateKeyType}
+	}
+	return &dtls.Certificate{Certificate: c.x509Cert.Raw, PrivateKey: keyDER}, nil
 }
@@ -0,0 +1,238 @@
+//go:build !js
+
+package webrtc
+
+import (
+	"encoding/binary"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pions/webrtc/pkg/rtcerr"
+	"github.com/pions/webrtc/pkg/rtp"
+)
+
+const (
+	dtmfMinDuration     = 40 * time.Millisecond
+	dtmfMaxDuration     = 6000 * time.Millisecond
+	dtmfMinInterToneGap = 30 * time.Millisecond
+	dtmfCommaDelay      = 2000 * time.Millisecond
+
+	// dtmfPacketInterval is how often an event in progress is refreshed
+	dtmfPacketInterval = 50 * time.Millisecond
+
+	// dtmfEndRetransmissions is how often the final packet of an event is
+	// sent, so the end of the event survives packet loss
+	dtmfEndRetransmissions = 3
+
+	// dtmfVolume is the power level of the tone, expressed in -dBm0
+	dtmfVolume = 10
+
+	dtmfTones = "0123456789*#ABCD"
+)
+
+// RTCDTMFSender is used to send DTMF tones on an audio RTCRtpSender, the
+// tones are sent as RFC 4733 telephone-events on the SSRC of the track.
+type RTCDTMFSender struct {
+	sync.RWMutex
+
+	// OnToneChange designates an event handler which is invoked when a tone
+	// starts playing, it is invoked with an empty tone once all tones have
+	// been played.
+	OnToneChange func(tone string)
+
+	toneBuffer   string
+	duration     time.Duration
+	interToneGap time.Duration
+	playing      bool
+
+	rtcRtpSender *RTCRtpSender
+}
+
+func newRTCDTMFSender(sender *RTCRtpSender) *RTCDTMFSender {
+	return &RTCDTMFSender{
+		rtcRtpSender: sender,
+	}
+}
+
+// CanInsertDTMF reports whether the sender is able to send DTMF tones. It
+// requires a sample track and a telephone-event codec registered with the
+// clock rate of the codec of the track.
+func (d *RTCDTMFSender) CanInsertDTMF() bool {
+	return d.getCodec() != nil
+}
+
+func (d *RTCDTMFSender) getCodec() *RTCRtpCodec {
+	s := d.rtcRtpSender
+	if s.rtcPeerConnection == nil || s.Track == nil || s.Track.sequencer == nil || s.Track.Codec == nil {
+		return nil
+	}
+	return s.rtcPeerConnection.mediaEngine.getTelephoneEventCodec(s.Track.Codec.ClockRate)
+}
+
+// ToneBuffer returns the tones which remain to be played
+func (d *RTCDTMFSender) ToneBuffer() string {
+	d.RLock()
+	defer d.RUnlock()
+	return d.toneBuffer
+}
+
+// InsertDTMF replaces the tones which remain to be played. Tones are played
+// for duration, separated by interToneGap, a comma delays the next tone by
+// two seconds. The duration is clamped between 40ms and 6000ms and the gap
+// is at least 30ms.
+// https://www.w3.org/TR/webrtc/#dom-rtcdtmfsender-insertdtmf
+func (d *RTCDTMFSender) InsertDTMF(tones string, duration, interToneGap time.Duration) error {
+	codec := d.getCodec()
+	if codec == nil {
+		return &rtcerr.InvalidStateError{Err: ErrCannotInsertDTMF}
+	}
+
+	tones = strings.ToUpper(tones)
+	for _, tone := range tones {
+		if tone != ',' && !strings.ContainsRune(dtmfTones, tone) {
+			return &rtcerr.InvalidCharacterError{Err: ErrInvalidDTMFTone}
+		}
+	}
+
+	if duration < dtmfMinDuration {
+		duration = dtmfMinDuration
+	} else if duration > dtmfMaxDuration {
+		duration = dtmfMaxDuration
+	}
+	if interToneGap < dtmfMinInterToneGap {
+		interToneGap = dtmfMinInterToneGap
+	}
+
+	d.Lock()
+	defer d.Unlock()
+
+	d.toneBuffer = tones
+	d.duration = duration
+	d.interToneGap = interToneGap
+	if !d.playing && tones != "" {
+		d.playing = true
+		workers.schedule(func() { d.play(codec) })
+	}
+	return nil
+}
+
+func (d *RTCDTMFSender) play(codec *RTCRtpCodec) {
+	for {
+		d.Lock()
+		if d.toneBuffer == "" {
+			d.playing = false
+			d.Unlock()
+			d.doOnToneChange("")
+			return
+		}
+		tone := d.toneBuffer[:1]
+		d.toneBuffer = d.toneBuffer[1:]
+		duration, interToneGap := d.duration, d.interToneGap
+		d.Unlock()
+
+		d.doOnToneChange(tone)
+		if tone == "," {
+			time.Sleep(dtmfCommaDelay)
+			continue
+		}
+
+		if err := d.sendEvent(codec, byte(strings.Index(dtmfTones, tone)), duration); err != nil {
+			d.rtcRtpSender.rtcPeerConnection.log.Warnf("Failed to send DTMF event: %v", err)
+		}
+		time.Sleep(interToneGap)
+	}
+}
+
+// sendEvent sends the packets of a single telephone-event, events which
+// don't fit the 16 bit duration field are split in segments
+// https://tools.ietf.org/html/rfc4733#section-2.5.1
+func (d *RTCDTMFSender) sendEvent(codec *RTCRtpCodec, event byte, duration time.Duration) error {
+	units := func(duration time.Duration) uint32 {
+		return uint32(duration * time.Duration(codec.ClockRate) / time.Second)
+	}
+
+	// The event starts at the current time of the media clock of the track
+	track := d.rtcRtpSender.Track
+	track.sendLock.Lock()
+	timestamp := track.packetizer.NextTimestamp()
+	track.sendLock.Unlock()
+
+	total := units(duration)
+	interval := units(dtmfPacketInterval)
+
+	first := true
+	segmentDuration := uint32(0)
+	for sent := uint32(0); sent < total; {
+		step := interval
+		if total-sent < step {
+			step = total - sent
+		}
+		if segmentDuration+step > 0xffff {
+			timestamp += segmentDuration
+			segmentDuration = 0
+		}
+		segmentDuration += step
+		sent += step
+
+		if err := d.sendPacket(codec, timestamp, first, event, false, segmentDuration); err != nil {
+			return err
+		}
+		first = false
+
+		if sent < total {
+			time.Sleep(dtmfPacketInterval)
+		}
+	}
+
+	for i := 0; i < dtmfEndRetransmissions; i++ {
+		if err := d.sendPacket(codec, timestamp, false, event, true, segmentDuration); err != nil {
+			return err
+		}
+	}
+
+	// The audio sent next continues from the end of the event
+	track.sendLock.Lock()
+	defer track.sendLock.Unlock()
+	if skipped := int32(timestamp + segmentDuration - track.packetizer.NextTimestamp()); skipped > 0 {
+		track.packetizer.SkipSamples(uint32(skipped))
+	}
+	return nil
+}
+
+func (d *RTCDTMFSender) sendPacket(codec *RTCRtpCodec, timestamp uint32, marker bool, event byte, end bool, duration uint32) error {
+	/*
+	 *  0                   1                   2                   3
+	 *  0 1 2 3 4 5 6 7 8 9 0 1 2 3 4 5 6 7 8 9 0 1 2 3 4 5 6 7 8 9 0 1
+	 * +-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+	 * |     event     |E|R| volume    |          duration             |
+	 * +-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+	 */
+	payload := make([]byte, 4)
+	payload[0] = event
+	payload[1] = dtmfVolume
+	if end {
+		payload[1] |= 1 << 7
+	}
+	binary.BigEndian.PutUint16(payload[2:], uint16(duration))
+
+	track := d.rtcRtpSender.Track
+	return d.rtcRtpSender.sendRTP(&rtp.Packet{
+		Version:        2,
+		Marker:         marker,
+		PayloadType:    codec.PayloadType,
+		SequenceNumber: track.sequencer.NextSequenceNumber(),
+		Timestamp:      timestamp,
+		SSRC:           track.Ssrc,
+		Payload:        payload,
+	})
+}
+
+func (d *RTCDTMFSender) doOnToneChange(tone string) {
+	d.RLock()
+	onToneChange := d.OnToneChange
+	d.RUnlock()
+	if onToneChange != nil {
+		onToneChange(tone)
+	}
+}
@@ -0,0 +1,86 @@
+//go:build !js
+
+package webrtc
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pions/webrtc/pkg/rtcerr"
+	"github.com/pions/webrtc/pkg/rtp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRTCDTMFSender_InsertDTMF(t *testing.T) {
+	RegisterDefaultCodecs()
+
+	pc, err := New(RTCConfiguration{})
+	assert.Nil(t, err)
+
+	videoTrack, err := pc.NewRTCSampleTrack(DefaultPayloadTypeVP8, "video", "pion")
+	assert.Nil(t, err)
+	videoSender, err := pc.AddTrack(videoTrack)
+	assert.Nil(t, err)
+	assert.Nil(t, videoSender.DTMF)
+
+	track, err := pc.NewRTCSampleTrack(DefaultPayloadTypeOpus, "audio", "pion")
+	assert.Nil(t, err)
+	sender, err := pc.AddTrack(track)
+	assert.Nil(t, err)
+	if !assert.NotNil(t, sender.DTMF) {
+		return
+	}
+	assert.True(t, sender.DTMF.CanInsertDTMF())
+
+	assert.EqualError(t, sender.DTMF.InsertDTMF("12x", time.Second, time.Second),
+		(&rtcerr.InvalidCharacterError{Err: ErrInvalidDTMFTone}).Error())
+
+	var sent []*rtp.Packet
+	sender.OnSentRTPPacket(func(p *rtp.Packet) {
+		sent = append(sent, p)
+	})
+
+	// The events are timestamped on the clock of the audio
+	assert.Nil(t, track.WriteSample([]byte{0x00}, 20*time.Millisecond))
+	assert.Equal(t, 1, len(sent))
+	audio := sent[0]
+	sent = nil
+
+	var tones []string
+	done := make(chan struct{})
+	sender.DTMF.OnToneChange = func(tone string) {
+		tones = append(tones, tone)
+		if tone == "" {
+			close(done)
+		}
+	}
+
+	assert.Nil(t, sender.DTMF.InsertDTMF("#", 0, 0))
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("DTMF tones were not played")
+	}
+	assert.Equal(t, []string{"#", ""}, tones)
+	assert.Equal(t, "", sender.DTMF.ToneBuffer())
+
+	// 40ms fit in a single packet, followed by the retransmitted end of the event
+	assert.Equal(t, 1+dtmfEndRetransmissions, len(sent))
+	for i, p := range sent {
+		assert.Equal(t, uint8(DefaultPayloadTypeTelephoneEvent), p.PayloadType)
+		assert.Equal(t, track.Ssrc, p.SSRC)
+		assert.Equal(t, audio.Timestamp+960, p.Timestamp)
+		assert.Equal(t, audio.SequenceNumber+1+uint16(i), p.SequenceNumber)
+		assert.Equal(t, i == 0, p.Marker)
+		assert.Equal(t, i > 0, p.Payload[1]&0x80 != 0)
+		// event 11 is #, 40ms at 48kHz are 1920 units
+		assert.Equal(t, []byte{11, 0x07, 0x80}, []byte{p.Payload[0], p.Payload[2], p.Payload[3]})
+	}
+
+	// The audio sent next continues after the event
+	event := sent[0]
+	sent = nil
+	assert.Nil(t, track.WriteSample([]byte{0x00}, 20*time.Millisecond))
+	assert.Equal(t, 1, len(sent))
+	assert.True(t, int32(sent[0].Timestamp-event.Timestamp) >= 1920, "audio at %d, event at %d", sent[0].Timestamp, event.Timestamp)
+}
@@ -0,0 +1,224 @@
+package webrtc
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pions/webrtc/internal/sdp"
+	"github.com/pions/webrtc/pkg/ice"
+	"github.com/pions/webrtc/pkg/rtcerr"
+)
+
+// RTCIceCandidate describes an ICE candidate of the local or the remote peer
+// https://www.w3.org/TR/webrtc/#rtcicecandidate-interface
+type RTCIceCandidate struct {
+	// Foundation identifies candidates which share their type, base and
+	// server, RFC 8445 Section 5.1.1.3.
+	Foundation string
+
+	// Component is the component of the media stream the candidate is for,
+	// it is RTCIceComponentRtcp only for the candidates gathered for peers
+	// which don't multiplex RTCP with RTP, see RTCRtcpMuxPolicyNegotiate.
+	Component RTCIceComponent
+
+	// Priority is the priority of the candidate as described in
+	// https://tools.ietf.org/html/rfc8445#section-5.1.2.1
+	Priority uint32
+
+	// IP and Port are the transport address of the candidate.
+	IP   string
+	Port uint16
+
+	// Protocol is the transport protocol of the candidate.
+	Protocol RTCIceProtocol
+
+	// Type is the type of the candidate, telling whether the peer is
+	// reached directly, through the address a NAT mapped or through a relay.
+	Type RTCIceCandidateType
+
+	// TCPType is the connection role of candidates with RTCIceProtocolTCP.
+	TCPType RTCIceTcpCandidateType
+
+	// RelatedAddress and RelatedPort are the base of server reflexive
+	// candidates of the local peer, the host address the NAT mapped.
+	RelatedAddress string
+	RelatedPort    uint16
+
+	// SDPMid, SDPMLineIndex and UsernameFragment are carried over to the
+	// RTCIceCandidateInit the candidate is serialized to, see
+	// RTCIceCandidateInit.
+	SDPMid           *string
+	SDPMLineIndex    *uint16
+	UsernameFragment string
+}
+
+// NewRTCIceCandidate parses the candidate-attribute of the init, the inverse
+// of ToJSON. Extensions other than raddr, rport and tcptype are ignored.
+// https://www.w3.org/TR/webrtc/#dom-rtcicecandidate-constructor
+func NewRTCIceCandidate(init RTCIceCandidateInit) (*RTCIceCandidate, error) {
+	attribute := strings.TrimPrefix(strings.TrimSpace(init.Candidate), "a=")
+	attribute = strings.TrimPrefix(attribute, sdp.AttrKeyCandidate+":")
+
+	// candidate-attribute = foundation component-id transport priority
+	//                       connection-address port "typ" cand-type
+	//                       *(extension-att-name extension-att-value)
+	// https://tools.ietf.org/html/rfc5245#section-15.1
+	split := strings.Fields(attribute)
+	if len(split) < 8 || split[6] != "typ" || len(split)%2 != 0 {
+		return nil, &rtcerr.SyntaxError{Err: ErrIceCandidateSyntax}
+	}
+
+	component, err := strconv.ParseUint(split[1], 10, 16)
+	if err != nil {
+		return nil, &rtcerr.SyntaxError{Err: ErrIceCandidateSyntax}
+	}
+	priority, err := strconv.ParseUint(split[3], 10, 32)
+	if err != nil {
+		return nil, &rtcerr.SyntaxError{Err: ErrIceCandidateSyntax}
+	}
+	port, err := strconv.ParseUint(split[5], 10, 16)
+	if err != nil {
+		return nil, &rtcerr.SyntaxError{Err: ErrIceCandidateSyntax}
+	}
+
+	candidate := &RTCIceCandidate{
+		Foundation:       split[0],
+		Component:        RTCIceComponent(component),
+		Priority:         uint32(priority),
+		IP:               split[4],
+		Port:             uint16(port),
+		Protocol:         newRTCIceProtocol(strings.ToLower(split[2])),
+		Type:             newRTCIceCandidateType(split[7]),
+		SDPMid:           init.SDPMid,
+		SDPMLineIndex:    init.SDPMLineIndex,
+		UsernameFragment: init.UsernameFragment,
+	}
+	if candidate.Protocol == RTCIceProtocol(Unknown) || candidate.Type == RTCIceCandidateType(Unknown) {
+		return nil, &rtcerr.SyntaxError{Err: ErrIceCandidateSyntax}
+	}
+
+	for i := 8; i < len(split); i += 2 {
+		switch split[i] {
+		case "raddr":
+			candidate.RelatedAddress = split[i+1]
+		case "rport":
+			relatedPort, err := strconv.ParseUint(split[i+1], 10, 16)
+			if err != nil {
+				return nil, &rtcerr.SyntaxError{Err: ErrIceCandidateSyntax}
+			}
+			candidate.RelatedPort = uint16(relatedPort)
+		case "tcptype":
+			candidate.TCPType = newRTCIceTcpCandidateType(split[i+1])
+		}
+	}
+
+	return candidate, nil
+}
+
+// String returns the candidate-attribute of the candidate, the way it is
+// signaled in a=candidate lines
+func (c RTCIceCandidate) String() string {
+	attribute := fmt.Sprintf("%s:%s %d %s %d %s %d typ %s",
+		sdp.AttrKeyCandidate, c.Foundation, c.Component, c.Protocol, c.Priority, c.IP, c.Port, c.Type)
+	if c.RelatedAddress != "" {
+		attribute += fmt.Sprintf(" raddr %s rport %d", c.RelatedAddress, c.RelatedPort)
+	}
+	if c.Protocol == RTCIceProtocolTCP && c.TCPType != RTCIceTcpCandidateType(Unknown) {
+		attribute += fmt.Sprintf(" tcptype %s", c.TCPType)
+	}
+	return attribute
+}
+
+// ToJSON returns the candidate in the shape browsers serialize it to JSON
+// in, it is passed to AddIceCandidate of the remote peer
+// https://www.w3.org/TR/webrtc/#dom-rtcicecandidate-tojson
+func (c RTCIceCandidate) ToJSON() RTCIceCandidateInit {
+	return RTCIceCandidateInit{
+		Candidate:        c.String(),
+		SDPMid:           c.SDPMid,
+		SDPMLineIndex:    c.SDPMLineIndex,
+		UsernameFragment: c.UsernameFragment,
+	}
+}
+
+// toICE returns the candidate the ICE agent checks, only host, srflx, prflx
+// and relay candidates over UDP or passive and active TCP are supported
+func (c RTCIceCandidate) toICE() (ice.Candidate, error) {
+	base := ice.CandidateBase{
+		Address:          c.IP,
+		Port:             int(c.Port),
+		Component:        uint16(c.Component),
+		SignaledPriority: c.Priority,
+	}
+	switch c.Protocol {
+	case RTCIceProtocolUDP:
+		base.Protocol = ice.ProtoTypeUDP
+	case RTCIceProtocolTCP:
+		base.Protocol = ice.ProtoTypeTCP
+		if base.TCPType = ice.NewTCPType(c.TCPType.String()); base.TCPType == ice.TCPType(ice.Unknown) {
+			return nil, &rtcerr.NotSupportedError{Err: ErrIceCandidateNotSupported}
+		}
+	}
+
+	switch c.Type {
+	case RTCIceCandidateTypeHost:
+		return &ice.CandidateHost{CandidateBase: base}, nil
+	case RTCIceCandidateTypeSrflx:
+		return &ice.CandidateSrflx{CandidateBase: base}, nil
+	case RTCIceCandidateTypePrflx:
+		return &ice.CandidatePeerReflexive{CandidateBase: base}, nil
+	case RTCIceCandidateTypeRelay:
+		return &ice.CandidateRelay{CandidateBase: base}, nil
+	default:
+		return nil, &rtcerr.NotSupportedError{Err: ErrIceCandidateNotSupported}
+	}
+}
+
+// RTCIceCandidatePair describes a local and a remote candidate which ICE
+// found to be able to communicate
+// https://www.w3.org/TR/webrtc/#dom-rtcicecandidatepair
+type RTCIceCandidatePair struct {
+	Local  *RTCIceCandidate
+	Remote *RTCIceCandidate
+
+	// RoundTripTime is the round trip time of the last consent check the
+	// remote peer answered on the pair, zero until one is answered.
+	RoundTripTime time.Duration
+}
+
+func newRTCIceCandidate(c ice.Candidate) *RTCIceCandidate {
+	base := c.GetBase()
+	candidate := &RTCIceCandidate{
+		// Foundations are announced the same way in the local description
+		Foundation: base.Protocol.String() + "candidate",
+		Component:  RTCIceComponent(base.GetComponent()),
+		IP:         base.Address,
+		Port:       uint16(base.Port),
+		Protocol:   newRTCIceProtocol(base.Protocol.String()),
+	}
+	if base.Protocol == ice.ProtoTypeTCP {
+		candidate.TCPType = newRTCIceTcpCandidateType(base.TCPType.String())
+	}
+
+	switch c := c.(type) {
+	case *ice.CandidateHost:
+		candidate.Type = RTCIceCandidateTypeHost
+	case *ice.CandidateSrflx:
+		candidate.Type = RTCIceCandidateTypeSrflx
+		if c.RemoteAddress != "" {
+			candidate.RelatedAddress, candidate.RelatedPort = c.RemoteAddress, uint16(c.RemotePort)
+		}
+	case *ice.CandidatePeerReflexive:
+		candidate.Type = RTCIceCandidateTypePrflx
+	case *ice.CandidateRelay:
+		candidate.Type = RTCIceCandidateTypeRelay
+		if c.RelatedAddress != "" {
+			candidate.RelatedAddress, candidate.RelatedPort = c.RelatedAddress, uint16(c.RelatedPort)
+		}
+	}
+	candidate.Priority = ice.CandidatePriority(c)
+
+	return candidate
+}
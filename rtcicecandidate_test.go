@@ -0,0 +1,293 @@
+//go:build !js
+
+package webrtc
+
+import (
+	"testing"
+
+	"github.com/pions/webrtc/pkg/ice"
+	"github.com/pions/webrtc/pkg/rtcerr"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewRTCIceCandidate(t *testing.T) {
+	base := ice.CandidateBase{
+		Protocol: ice.ProtoTypeUDP,
+		Address:  "192.168.0.2",
+		Port:     5000,
+	}
+
+	testCases := []struct {
+		candidate ice.Candidate
+		expected  *RTCIceCandidate
+	}{
+		{
+			&ice.CandidateHost{CandidateBase: base},
+			&RTCIceCandidate{
+				Foundation: "udpcandidate",
+				Component:  RTCIceComponentRtp,
+				Priority:   base.Priority(ice.HostCandidatePreference, 1),
+				IP:         "192.168.0.2",
+				Port:       5000,
+				Protocol:   RTCIceProtocolUDP,
+				Type:       RTCIceCandidateTypeHost,
+			},
+		},
+		{
+			&ice.CandidateSrflx{CandidateBase: base, RemoteAddress: "10.0.0.2", RemotePort: 6000},
+			&RTCIceCandidate{
+				Foundation:     "udpcandidate",
+				Component:      RTCIceComponentRtp,
+				Priority:       base.Priority(ice.SrflxCandidatePreference, 1),
+				IP:             "192.168.0.2",
+				Port:           5000,
+				Protocol:       RTCIceProtocolUDP,
+				Type:           RTCIceCandidateTypeSrflx,
+				RelatedAddress: "10.0.0.2",
+				RelatedPort:    6000,
+			},
+		},
+		{
+			// Remote server reflexive candidates carry their mapped address
+			&ice.CandidateSrflx{CandidateBase: base},
+			&RTCIceCandidate{
+				Foundation: "udpcandidate",
+				Component:  RTCIceComponentRtp,
+				Priority:   base.Priority(ice.SrflxCandidatePreference, 1),
+				IP:         "192.168.0.2",
+				Port:       5000,
+				Protocol:   RTCIceProtocolUDP,
+				Type:       RTCIceCandidateTypeSrflx,
+			},
+		},
+		{
+			&ice.CandidatePeerReflexive{CandidateBase: base},
+			&RTCIceCandidate{
+				Foundation: "udpcandidate",
+				Component:  RTCIceComponentRtp,
+				Priority:   base.Priority(ice.PrflxCandidatePreference, 1),
+				IP:         "192.168.0.2",
+				Port:       5000,
+				Protocol:   RTCIceProtocolUDP,
+				Type:       RTCIceCandidateTypePrflx,
+			},
+		},
+		{
+			&ice.CandidateRelay{CandidateBase: base, RelatedAddress: "10.0.0.2", RelatedPort: 6000},
+			&RTCIceCandidate{
+				Foundation:     "udpcandidate",
+				Component:      RTCIceComponentRtp,
+				Priority:       base.Priority(ice.RelayCandidatePreference, 1),
+				IP:             "192.168.0.2",
+				Port:           5000,
+				Protocol:       RTCIceProtocolUDP,
+				Type:           RTCIceCandidateTypeRelay,
+				RelatedAddress: "10.0.0.2",
+				RelatedPort:    6000,
+			},
+		},
+	}
+
+	for i, testCase := range testCases {
+		assert.Equal(t, testCase.expected, newRTCIceCandidate(testCase.candidate), "testCase: %d", i)
+	}
+}
+
+func TestNewRTCIceCandidateFromInit(t *testing.T) {
+	mid := "0"
+
+	testCases := []struct {
+		init     RTCIceCandidateInit
+		expected *RTCIceCandidate
+		err      error
+	}{
+		{
+			RTCIceCandidateInit{
+				Candidate:        "candidate:1 1 udp 2130706431 192.0.2.1 5000 typ host generation 0",
+				SDPMid:           &mid,
+				UsernameFragment: "ufrag",
+			},
+			&RTCIceCandidate{
+				Foundation:       "1",
+				Component:        RTCIceComponentRtp,
+				Priority:         2130706431,
+				IP:               "192.0.2.1",
+				Port:             5000,
+				Protocol:         RTCIceProtocolUDP,
+				Type:             RTCIceCandidateTypeHost,
+				SDPMid:           &mid,
+				UsernameFragment: "ufrag",
+			},
+			nil,
+		},
+		{
+			RTCIceCandidateInit{Candidate: "a=candidate:2 1 UDP 1694498815 203.0.113.1 6000 typ srflx raddr 192.0.2.1 rport 5000"},
+			&RTCIceCandidate{
+				Foundation:     "2",
+				Component:      RTCIceComponentRtp,
+				Priority:       1694498815,
+				IP:             "203.0.113.1",
+				Port:           6000,
+				Protocol:       RTCIceProtocolUDP,
+				Type:           RTCIceCandidateTypeSrflx,
+				RelatedAddress: "192.0.2.1",
+				RelatedPort:    5000,
+			},
+			nil,
+		},
+		{
+			RTCIceCandidateInit{Candidate: "3 2 tcp 1518280447 192.0.2.1 9 typ host tcptype active"},
+			&RTCIceCandidate{
+				Foundation: "3",
+				Component:  RTCIceComponentRtcp,
+				Priority:   1518280447,
+				IP:         "192.0.2.1",
+				Port:       9,
+				Protocol:   RTCIceProtocolTCP,
+				Type:       RTCIceCandidateTypeHost,
+				TCPType:    RTCIceTcpCandidateTypeActive,
+			},
+			nil,
+		},
+		{RTCIceCandidateInit{Candidate: "candidate:invalid"}, nil, &rtcerr.SyntaxError{Err: ErrIceCandidateSyntax}},
+		{RTCIceCandidateInit{Candidate: "candidate:1 1 udp 2130706431 192.0.2.1 port typ host"}, nil, &rtcerr.SyntaxError{Err: ErrIceCandidateSyntax}},
+		{RTCIceCandidateInit{Candidate: "candidate:1 1 sctp 2130706431 192.0.2.1 5000 typ host"}, nil, &rtcerr.SyntaxError{Err: ErrIceCandidateSyntax}},
+		{RTCIceCandidateInit{Candidate: "candidate:1 1 udp 2130706431 192.0.2.1 5000 typ other"}, nil, &rtcerr.SyntaxError{Err: ErrIceCandidateSyntax}},
+		{RTCIceCandidateInit{Candidate: "candidate:1 1 udp 2130706431 192.0.2.1 5000 typ srflx raddr"}, nil, &rtcerr.SyntaxError{Err: ErrIceCandidateSyntax}},
+	}
+
+	for i, testCase := range testCases {
+		candidate, err := NewRTCIceCandidate(testCase.init)
+		assert.Equal(t, testCase.err, err, "testCase: %d", i)
+		assert.Equal(t, testCase.expected, candidate, "testCase: %d", i)
+	}
+}
+
+func TestRTCIceCandidate_ToJSON(t *testing.T) {
+	var sdpMLineIndex uint16
+
+	testCases := []RTCIceCandidateInit{
+		{Candidate: "candidate:1 1 udp 2130706431 192.0.2.1 5000 typ host", SDPMLineIndex: &sdpMLineIndex, UsernameFragment: "ufrag"},
+		{Candidate: "candidate:2 1 udp 1694498815 203.0.113.1 6000 typ srflx raddr 192.0.2.1 rport 5000"},
+		{Candidate: "candidate:3 1 tcp 1518280447 192.0.2.1 9 typ host tcptype active"},
+	}
+
+	for i, testCase := range testCases {
+		candidate, err := NewRTCIceCandidate(testCase)
+		if assert.Nil(t, err, "testCase: %d", i) {
+			assert.Equal(t, testCase, candidate.ToJSON(), "testCase: %d", i)
+		}
+	}
+
+	// Candidates of the agent round trip through their JSON
+	local := newRTCIceCandidate(&ice.CandidateHost{CandidateBase: ice.CandidateBase{
+		Protocol: ice.ProtoTypeTCP,
+		Address:  "192.0.2.1",
+		Port:     5000,
+		TCPType:  ice.TCPTypePassive,
+	}})
+	parsed, err := NewRTCIceCandidate(local.ToJSON())
+	assert.Nil(t, err)
+	assert.Equal(t, local, parsed)
+}
+
+func TestRTCIceCandidate_toICE(t *testing.T) {
+	base := ice.CandidateBase{
+		Protocol: ice.ProtoTypeUDP,
+		Address:  "192.0.2.1",
+		Port:     5000,
+	}
+
+	testCases := []struct {
+		candidate RTCIceCandidate
+		expected  ice.Candidate
+		err       error
+	}{
+		{
+			RTCIceCandidate{IP: "192.0.2.1", Port: 5000, Protocol: RTCIceProtocolUDP, Type: RTCIceCandidateTypeHost},
+			&ice.CandidateHost{CandidateBase: base},
+			nil,
+		},
+		{
+			RTCIceCandidate{IP: "192.0.2.1", Port: 5000, Protocol: RTCIceProtocolUDP, Type: RTCIceCandidateTypeSrflx},
+			&ice.CandidateSrflx{CandidateBase: base},
+			nil,
+		},
+		{
+			RTCIceCandidate{IP: "192.0.2.1", Port: 5000, Protocol: RTCIceProtocolUDP, Type: RTCIceCandidateTypePrflx},
+			&ice.CandidatePeerReflexive{CandidateBase: base},
+			nil,
+		},
+		{
+			RTCIceCandidate{IP: "192.0.2.1", Port: 5000, Protocol: RTCIceProtocolTCP, Type: RTCIceCandidateTypeHost, TCPType: RTCIceTcpCandidateTypePassive},
+			&ice.CandidateHost{CandidateBase: ice.CandidateBase{
+				Protocol: ice.ProtoTypeTCP,
+				Address:  "192.0.2.1",
+				Port:     5000,
+				TCPType:  ice.TCPTypePassive,
+			}},
+			nil,
+		},
+		{
+			RTCIceCandidate{IP: "192.0.2.1", Port: 5000, Protocol: RTCIceProtocolTCP, Type: RTCIceCandidateTypeHost, TCPType: RTCIceTcpCandidateTypeSo},
+			nil,
+			&rtcerr.NotSupportedError{Err: ErrIceCandidateNotSupported},
+		},
+		{
+			RTCIceCandidate{IP: "192.0.2.1", Port: 5000, Protocol: RTCIceProtocolUDP, Type: RTCIceCandidateTypeRelay},
+			&ice.CandidateRelay{CandidateBase: base},
+			nil,
+		},
+		{
+			RTCIceCandidate{IP: "192.0.2.1", Port: 5000, Protocol: RTCIceProtocolUDP, Type: RTCIceCandidateType(Unknown)},
+			nil,
+			&rtcerr.NotSupportedError{Err: ErrIceCandidateNotSupported},
+		},
+	}
+
+	for i, testCase := range testCases {
+		c, err := testCase.candidate.toICE()
+		assert.Equal(t, testCase.err, err, "testCase: %d", i)
+		assert.Equal(t, testCase.expected, c, "testCase: %d", i)
+	}
+}
+
+func TestRTCIceTransport_GetSelectedCandidatePair(t *testing.T) {
+	pc, err := New(RTCConfiguration{})
+	assert.Nil(t, err)
+	defer func() { assert.Nil(t, pc.Close()) }()
+
+	iceTransport := pc.SCTP().Transport.Transport
+	if assert.NotNil(t, iceTransport) {
+		assert.Nil(t, iceTransport.GetSelectedCandidatePair())
+	}
+}
+
+func TestRTCIceTransport_GetCandidates(t *testing.T) {
+	pc, err := New(RTCConfiguration{})
+	assert.Nil(t, err)
+	defer func() { assert.Nil(t, pc.Close()) }()
+
+	iceTransport := pc.SCTP().Transport.Transport
+	local := iceTransport.GetLocalCandidates()
+	assert.NotEmpty(t, local)
+	for _, c := range local {
+		assert.Equal(t, pc.networkManager.IceAgent.LocalUfrag, c.UsernameFragment)
+		if assert.NotNil(t, c.SDPMLineIndex) {
+			assert.Equal(t, uint16(0), *c.SDPMLineIndex)
+		}
+	}
+
+	assert.Empty(t, iceTransport.GetRemoteCandidates())
+	pc.networkManager.IceAgent.AddRemoteCandidate(&ice.CandidateHost{CandidateBase: ice.CandidateBase{
+		Protocol: ice.ProtoTypeUDP,
+		Address:  "192.0.2.1",
+		Port:     5000,
+		Ufrag:    "ufrag",
+	}})
+	remote := iceTransport.GetRemoteCandidates()
+	if assert.Len(t, remote, 1) {
+		assert.Equal(t, "192.0.2.1", remote[0].IP)
+		assert.Equal(t, "ufrag", remote[0].UsernameFragment)
+	}
+}
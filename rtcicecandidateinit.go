@@ -0,0 +1,42 @@
+package webrtc
+
+import (
+	"github.com/pions/webrtc/internal/sdp"
+	"github.com/pions/webrtc/pkg/rtcerr"
+)
+
+// RTCIceCandidateInit is a candidate the remote peer trickled, in the shape
+// browsers serialize RTCIceCandidate to JSON in
+// https://www.w3.org/TR/webrtc/#dom-rtcicecandidateinit
+type RTCIceCandidateInit struct {
+	// Candidate is the candidate-attribute, an empty one signals the end of
+	// the candidates.
+	Candidate string `json:"candidate"`
+
+	// SDPMid and SDPMLineIndex identify the media section of the remote
+	// description the candidate belongs to, SDPMid takes precedence.
+	SDPMid        *string `json:"sdpMid"`
+	SDPMLineIndex *uint16 `json:"sdpMLineIndex"`
+
+	// UsernameFragment is the ufrag of the ICE session of the candidate,
+	// empty if it is not known.
+	UsernameFragment string `json:"usernameFragment"`
+}
+
+// mediaDescription returns the media section of the description the
+// candidate belongs to
+func (c RTCIceCandidateInit) mediaDescription(s *sdp.SessionDescription) (*sdp.MediaDescription, error) {
+	switch {
+	case c.SDPMid != nil:
+		if m, ok := s.GetMediaForMid(*c.SDPMid); ok {
+			return m, nil
+		}
+	case c.SDPMLineIndex != nil:
+		if int(*c.SDPMLineIndex) < len(s.MediaDescriptions) {
+			return s.MediaDescriptions[*c.SDPMLineIndex], nil
+		}
+	default:
+		return nil, &rtcerr.TypeError{Err: ErrIceCandidateWithoutMedia}
+	}
+	return nil, &rtcerr.OperationError{Err: ErrIceCandidateMediaNotFound}
+}
@@ -1,6 +1,14 @@
 package webrtc
 
 import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/base64"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pions/webrtc/internal/turn"
 	"github.com/pions/webrtc/pkg/ice"
 	"github.com/pions/webrtc/pkg/rtcerr"
 )
@@ -14,6 +22,90 @@ type RTCIceServer struct {
 	CredentialType RTCIceCredentialType
 }
 
+// iceCredentialRefreshMargin is how long before they expire time-limited
+// credentials are refreshed
+const iceCredentialRefreshMargin = time.Minute
+
+// NewTimeLimitedRTCIceServer creates an RTCIceServer with time-limited
+// credentials for TURN servers sharing the secret with the application, like
+// coturn with use-auth-secret. The username is the unix time the credentials
+// expire at followed by the user, the credential the base64 encoded
+// HMAC-SHA1 of the username keyed with the secret.
+// https://tools.ietf.org/html/draft-uberti-behave-turn-rest-00#section-2.2
+func NewTimeLimitedRTCIceServer(urls []string, secret, user string, ttl time.Duration) RTCIceServer {
+	username := strconv.FormatInt(time.Now().Add(ttl).Unix(), 10)
+	if user != "" {
+		username += ":" + user
+	}
+
+	return RTCIceServer{
+		URLs:           urls,
+		Username:       username,
+		Credential:     timeLimitedCredential(secret, username),
+		CredentialType: RTCIceCredentialTypePassword,
+	}
+}
+
+// timeLimitedCredential returns the credential of the time-limited username
+func timeLimitedCredential(secret, username string) string {
+	mac := hmac.New(sha1.New, []byte(secret))
+	mac.Write([]byte(username)) // writes to a hash never fail
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// expiry returns when the time-limited credentials of the server expire, ok
+// is false unless the username starts with a unix time like the ones of
+// NewTimeLimitedRTCIceServer
+func (s RTCIceServer) expiry() (expiry time.Time, ok bool) {
+	if s.CredentialType == RTCIceCredentialTypeOauth {
+		return time.Time{}, false
+	}
+	timestamp, err := strconv.ParseInt(strings.SplitN(s.Username, ":", 2)[0], 10, 64)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return time.Unix(timestamp, 0), true
+}
+
+// refresh returns the server with the credentials of the refresher if its
+// time-limited credentials expire within iceCredentialRefreshMargin of until
+func (s RTCIceServer) refresh(refresher func(RTCIceServer) (RTCIceServer, error), until time.Time) (RTCIceServer, error) {
+	expiry, ok := s.expiry()
+	if !ok || refresher == nil || expiry.Sub(until) > iceCredentialRefreshMargin {
+		return s, nil
+	}
+
+	refreshed, err := refresher(s)
+	if err != nil {
+		return s, err
+	}
+	if err := refreshed.validate(); err != nil {
+		return s, err
+	}
+	return refreshed, nil
+}
+
+// turnCredentials returns the credentials allocations on the TURN servers
+// are authenticated with, none for STUN servers
+func (s RTCIceServer) turnCredentials() (turn.Credentials, error) {
+	switch credential := s.Credential.(type) {
+	case string:
+		return turn.Credentials{Username: s.Username, Password: credential}, nil
+	case RTCOAuthCredential:
+		macKey, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(credential.MacKey, "="))
+		if err != nil {
+			return turn.Credentials{}, ErrTurnCredencials
+		}
+		accessToken, err := base64.StdEncoding.DecodeString(credential.AccessToken)
+		if err != nil {
+			return turn.Credentials{}, ErrTurnCredencials
+		}
+		return turn.Credentials{Username: s.Username, AccessToken: accessToken, MacKey: macKey}, nil
+	default:
+		return turn.Credentials{}, nil
+	}
+}
+
 func (s RTCIceServer) parseURL(i int) (*ice.URL, error) {
 	return ice.ParseURL(s.URLs[i])
 }
@@ -32,16 +124,21 @@ func (s RTCIceServer) validate() error {
 			}
 
 			switch s.CredentialType {
-			case RTCIceCredentialTypePassword:
+			case RTCIceCredentialType(Unknown), RTCIceCredentialTypePassword:
 				// https://www.w3.org/TR/webrtc/#set-the-configuration (step #11.3.3)
+				// The credential type defaults to password
 				if _, ok := s.Credential.(string); !ok {
 					return &rtcerr.InvalidAccessError{Err: ErrTurnCredencials}
 				}
 			case RTCIceCredentialTypeOauth:
 				// https://www.w3.org/TR/webrtc/#set-the-configuration (step #11.3.4)
-				if _, ok := s.Credential.(RTCOAuthCredential); !ok {
+				credential, ok := s.Credential.(RTCOAuthCredential)
+				if !ok {
 					return &rtcerr.InvalidAccessError{Err: ErrTurnCredencials}
 				}
+				if err := credential.validate(); err != nil {
+					return &rtcerr.InvalidAccessError{Err: err}
+				}
 
 			default:
 				return &rtcerr.InvalidAccessError{Err: ErrTurnCredencials}
@@ -1,7 +1,12 @@
+//go:build !js
+
 package webrtc
 
 import (
+	"errors"
+	"strconv"
 	"testing"
+	"time"
 
 	"github.com/pions/webrtc/pkg/ice"
 	"github.com/pions/webrtc/pkg/rtcerr"
@@ -29,6 +34,20 @@ func TestRTCIceServer_validate(t *testing.T) {
 				},
 				CredentialType: RTCIceCredentialTypeOauth,
 			}, true},
+			{RTCIceServer{
+				URLs:       []string{"turn:192.158.29.39?transport=udp"},
+				Username:   "unittest",
+				Credential: "placeholder",
+			}, true},
+			{RTCIceServer{
+				URLs:     []string{"turn:192.158.29.39?transport=udp"},
+				Username: "unittest",
+				Credential: RTCOAuthCredential{
+					MacKey:      "4u_aC8-ZXy1ZR1S3nRhSOQSAbTP1f4Xag6vMgFRAyqI",
+					AccessToken: "AAwg3kPHWPfvk9bDFL936wYvkoctMADzQ5VhNDgeMR3+ZlZ35byg972fW8QjpEl7bx91YLBPFsIhsxloWcXPhA==",
+				},
+				CredentialType: RTCIceCredentialTypeOauth,
+			}, true},
 		}
 
 		for i, testCase := range testCases {
@@ -61,6 +80,23 @@ func TestRTCIceServer_validate(t *testing.T) {
 				Credential:     false,
 				CredentialType: Unknown,
 			}, &rtcerr.InvalidAccessError{Err: ErrTurnCredencials}},
+			{RTCIceServer{
+				URLs:     []string{"turn:192.158.29.39?transport=udp"},
+				Username: "unittest",
+				Credential: RTCOAuthCredential{
+					MacKey: "WmtzanB3ZW9peFhtdm42NzUzNG0=",
+				},
+				CredentialType: RTCIceCredentialTypeOauth,
+			}, &rtcerr.InvalidAccessError{Err: ErrTurnCredencials}},
+			{RTCIceServer{
+				URLs:     []string{"turn:192.158.29.39?transport=udp"},
+				Username: "unittest",
+				Credential: RTCOAuthCredential{
+					MacKey:      "WmtzanB3ZW9peFht",
+					AccessToken: "AAwg3kPHWPfvk9bDFL936wYvkoctMADzQ5VhNDgeMR3+ZlZ35byg972fW8QjpEl7bx91YLBPFsIhsxloWcXPhA==",
+				},
+				CredentialType: RTCIceCredentialTypeOauth,
+			}, &rtcerr.InvalidAccessError{Err: ErrTurnCredencials}},
 			{RTCIceServer{
 				URLs:           []string{"stun:google.de?transport=udp"},
 				Username:       "unittest",
@@ -78,3 +114,70 @@ func TestRTCIceServer_validate(t *testing.T) {
 		}
 	})
 }
+
+func TestNewTimeLimitedRTCIceServer(t *testing.T) {
+	urls := []string{"turn:192.158.29.39?transport=udp"}
+	server := NewTimeLimitedRTCIceServer(urls, "secret", "unittest", time.Hour)
+	assert.Nil(t, server.validate())
+	assert.Equal(t, urls, server.URLs)
+
+	expiry, ok := server.expiry()
+	assert.True(t, ok)
+	assert.InDelta(t, time.Now().Add(time.Hour).Unix(), expiry.Unix(), 1)
+	assert.Equal(t, strconv.FormatInt(expiry.Unix(), 10)+":unittest", server.Username)
+	assert.Equal(t, timeLimitedCredential("secret", server.Username), server.Credential)
+
+	// The credential is the HMAC-SHA1 of the username keyed with the secret
+	assert.Equal(t, "SMpn3MUabz7RzA1R/TmzLIgdZtk=", timeLimitedCredential("secret", "1546300800:unittest"))
+
+	server = NewTimeLimitedRTCIceServer(urls, "secret", "", 0)
+	assert.NotContains(t, server.Username, ":")
+
+	_, ok = RTCIceServer{Username: "unittest", Credential: "placeholder"}.expiry()
+	assert.False(t, ok)
+}
+
+func TestRTCIceServer_refresh(t *testing.T) {
+	urls := []string{"turn:192.158.29.39?transport=udp"}
+	fresh := NewTimeLimitedRTCIceServer(urls, "secret", "unittest", time.Hour)
+	refresher := func(RTCIceServer) (RTCIceServer, error) {
+		return fresh, nil
+	}
+	failing := func(RTCIceServer) (RTCIceServer, error) {
+		return RTCIceServer{}, errors.New("unavailable")
+	}
+
+	expiring := NewTimeLimitedRTCIceServer(urls, "secret", "unittest", 30*time.Second)
+	server, err := expiring.refresh(refresher, time.Now())
+	assert.Nil(t, err)
+	assert.Equal(t, fresh, server)
+
+	// Credentials which don't expire soon are kept
+	server, err = fresh.refresh(failing, time.Now())
+	assert.Nil(t, err)
+	assert.Equal(t, fresh, server)
+
+	server, err = expiring.refresh(nil, time.Now())
+	assert.Nil(t, err)
+	assert.Equal(t, expiring, server)
+
+	server, err = expiring.refresh(failing, time.Now())
+	assert.NotNil(t, err)
+	assert.Equal(t, expiring, server)
+
+	// Invalid credentials of the refresher are rejected
+	server, err = expiring.refresh(func(RTCIceServer) (RTCIceServer, error) {
+		return RTCIceServer{URLs: urls}, nil
+	}, time.Now())
+	assert.NotNil(t, err)
+	assert.Equal(t, expiring, server)
+
+	// Credentials expiring soon after the time they are used until are
+	// refreshed
+	server, err = fresh.refresh(refresher, time.Now().Add(time.Hour))
+	assert.Nil(t, err)
+	assert.Equal(t, fresh, server)
+	server, err = fresh.refresh(failing, time.Now().Add(time.Hour))
+	assert.NotNil(t, err)
+	assert.Equal(t, fresh, server)
+}
@@ -0,0 +1,52 @@
+package webrtc
+
+// RTCIceTcpCandidateType indicates the connection role of an ICE-TCP
+// candidate as described in https://tools.ietf.org/html/rfc6544#section-4.5
+type RTCIceTcpCandidateType int
+
+const (
+	// RTCIceTcpCandidateTypeActive indicates that the candidate opens
+	// connections to passive candidates but doesn't accept any.
+	RTCIceTcpCandidateTypeActive RTCIceTcpCandidateType = iota + 1
+
+	// RTCIceTcpCandidateTypePassive indicates that the candidate accepts
+	// connections from active candidates but doesn't open any.
+	RTCIceTcpCandidateTypePassive
+
+	// RTCIceTcpCandidateTypeSo indicates that the candidate opens a
+	// connection simultaneously with its peer.
+	RTCIceTcpCandidateTypeSo
+)
+
+// This is done this way because of a linter.
+const (
+	rtcIceTcpCandidateTypeActiveStr  = "active"
+	rtcIceTcpCandidateTypePassiveStr = "passive"
+	rtcIceTcpCandidateTypeSoStr      = "so"
+)
+
+func newRTCIceTcpCandidateType(raw string) RTCIceTcpCandidateType {
+	switch raw {
+	case rtcIceTcpCandidateTypeActiveStr:
+		return RTCIceTcpCandidateTypeActive
+	case rtcIceTcpCandidateTypePassiveStr:
+		return RTCIceTcpCandidateTypePassive
+	case rtcIceTcpCandidateTypeSoStr:
+		return RTCIceTcpCandidateTypeSo
+	default:
+		return RTCIceTcpCandidateType(Unknown)
+	}
+}
+
+func (t RTCIceTcpCandidateType) String() string {
+	switch t {
+	case RTCIceTcpCandidateTypeActive:
+		return rtcIceTcpCandidateTypeActiveStr
+	case RTCIceTcpCandidateTypePassive:
+		return rtcIceTcpCandidateTypePassiveStr
+	case RTCIceTcpCandidateTypeSo:
+		return rtcIceTcpCandidateTypeSoStr
+	default:
+		return ErrUnknownType.Error()
+	}
+}
@@ -0,0 +1,49 @@
+//go:build !js
+
+package webrtc
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewRTCIceTcpCandidateType(t *testing.T) {
+	testCases := []struct {
+		typeString   string
+		expectedType RTCIceTcpCandidateType
+	}{
+		{"unknown", RTCIceTcpCandidateType(Unknown)},
+		{"active", RTCIceTcpCandidateTypeActive},
+		{"passive", RTCIceTcpCandidateTypePassive},
+		{"so", RTCIceTcpCandidateTypeSo},
+	}
+
+	for i, testCase := range testCases {
+		assert.Equal(t,
+			testCase.expectedType,
+			newRTCIceTcpCandidateType(testCase.typeString),
+			"testCase: %d %v", i, testCase,
+		)
+	}
+}
+
+func TestRTCIceTcpCandidateType_String(t *testing.T) {
+	testCases := []struct {
+		tcpType        RTCIceTcpCandidateType
+		expectedString string
+	}{
+		{RTCIceTcpCandidateType(Unknown), "unknown"},
+		{RTCIceTcpCandidateTypeActive, "active"},
+		{RTCIceTcpCandidateTypePassive, "passive"},
+		{RTCIceTcpCandidateTypeSo, "so"},
+	}
+
+	for i, testCase := range testCases {
+		assert.Equal(t,
+			testCase.expectedString,
+			testCase.tcpType.String(),
+			"testCase: %d %v", i, testCase,
+		)
+	}
+}
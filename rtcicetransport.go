@@ -1,26 +1,125 @@
+//go:build !js
+
 package webrtc
 
+import (
+	"sync"
+
+	"github.com/pions/webrtc/internal/network"
+	"github.com/pions/webrtc/pkg/ice"
+)
+
 // RTCIceTransport allows an application access to information about the ICE
 // transport over which packets are sent and received.
 type RTCIceTransport struct {
+	sync.RWMutex
+
 	// Role RTCIceRole
 	// Component RTCIceComponent
-	// State RTCIceTransportState
 	// gatheringState RTCIceGathererState
+
+	// State is the current state of the transport, read it while holding
+	// the lock of the transport.
+	State RTCIceTransportState
+
+	// OnStateChange designates an event handler which is called with the
+	// state of the transport whenever it changes. Set it while holding the
+	// lock of the transport.
+	OnStateChange func(RTCIceTransportState)
+
+	// OnSelectedCandidatePairChange is called with the candidate pair ICE
+	// selected whenever it selects another one, telling whether the peers
+	// are connected directly or through a NAT or relay. Set it while holding
+	// the lock of the transport.
+	OnSelectedCandidatePairChange func(pair *RTCIceCandidatePair)
+
+	agent   *ice.Agent
+	manager *network.Manager
+}
+
+func newRTCIceTransport(m *network.Manager) *RTCIceTransport {
+	t := &RTCIceTransport{
+		State:   RTCIceTransportStateNew,
+		agent:   m.IceAgent,
+		manager: m,
+	}
+	t.agent.SetSelectedPairNotifier(t.selectedPairChange)
+	return t
+}
+
+// GetLocalCandidates returns the candidates gathered for the transport, as
+// they are announced to the remote peer. Every media section is bundled on
+// the transport, so the candidates are bound to the first one.
+// https://www.w3.org/TR/webrtc/#dom-rtcicetransport-getlocalcandidates
+func (t *RTCIceTransport) GetLocalCandidates() []*RTCIceCandidate {
+	var sdpMLineIndex uint16
+	candidates := []*RTCIceCandidate{}
+	for _, c := range t.manager.LocalCandidates() {
+		candidate := newRTCIceCandidate(c)
+		candidate.SDPMLineIndex = &sdpMLineIndex
+		candidate.UsernameFragment = t.agent.LocalUfrag
+		candidates = append(candidates, candidate)
+	}
+	return candidates
+}
+
+// GetRemoteCandidates returns the candidates the remote peer signaled and the
+// peer reflexive ones learned from its connectivity checks
+// https://www.w3.org/TR/webrtc/#dom-rtcicetransport-getremotecandidates
+func (t *RTCIceTransport) GetRemoteCandidates() []*RTCIceCandidate {
+	candidates := []*RTCIceCandidate{}
+	for _, c := range t.agent.RemoteCandidates() {
+		candidate := newRTCIceCandidate(c)
+		candidate.UsernameFragment = c.GetBase().Ufrag
+		candidates = append(candidates, candidate)
+	}
+	return candidates
+}
+
+// GetSelectedCandidatePair returns the candidate pair packets are sent on,
+// or nil if ICE didn't select one yet
+// https://www.w3.org/TR/webrtc/#dom-rtcicetransport-getselectedcandidatepair
+func (t *RTCIceTransport) GetSelectedCandidatePair() *RTCIceCandidatePair {
+	local, remote, rtt := t.agent.SelectedCandidatePair()
+	if local == nil || remote == nil {
+		return nil
+	}
+	return &RTCIceCandidatePair{
+		Local:         newRTCIceCandidate(local),
+		Remote:        newRTCIceCandidate(remote),
+		RoundTripTime: rtt,
+	}
+}
+
+func (t *RTCIceTransport) stateChange(state RTCIceTransportState) {
+	t.Lock()
+	// Closed is final
+	if t.State == state || t.State == RTCIceTransportStateClosed {
+		t.Unlock()
+		return
+	}
+	t.State = state
+	onStateChange := t.OnStateChange
+	t.Unlock()
+
+	if onStateChange != nil {
+		onStateChange(state)
+	}
+}
+
+func (t *RTCIceTransport) selectedPairChange(local, remote ice.Candidate) {
+	t.RLock()
+	onSelectedCandidatePairChange := t.OnSelectedCandidatePairChange
+	t.RUnlock()
+
+	if onSelectedCandidatePairChange != nil {
+		onSelectedCandidatePairChange(&RTCIceCandidatePair{
+			Local:  newRTCIceCandidate(local),
+			Remote: newRTCIceCandidate(remote),
+		})
+	}
 }
 
-// func (t *RTCIceTransport) GetLocalCandidates() []RTCIceCandidate {
-//
-// }
-//
-// func (t *RTCIceTransport) GetRemoteCandidates() []RTCIceCandidate {
-//
-// }
-//
-// func (t *RTCIceTransport) GetSelectedCandidatePair() RTCIceCandidatePair {
-//
-// }
-//
 // func (t *RTCIceTransport) GetLocalParameters() RTCIceParameters {
 //
 // }
@@ -11,12 +11,19 @@ const (
 
 	// RTCIceTransportPolicyAll indicates any type of candidate is used.
 	RTCIceTransportPolicyAll
+
+	// RTCIceTransportPolicyHostOnly indicates only host candidates are
+	// used, the local ones and those of the remote peer, for networks
+	// without access to STUN and TURN servers like air-gapped LANs. This
+	// policy is not part of the WebRTC API, browsers don't support it.
+	RTCIceTransportPolicyHostOnly
 )
 
 // This is done this way because of a linter.
 const (
-	rtcIceTransportPolicyRelayStr = "relay"
-	rtcIceTransportPolicyAllStr   = "all"
+	rtcIceTransportPolicyRelayStr    = "relay"
+	rtcIceTransportPolicyAllStr      = "all"
+	rtcIceTransportPolicyHostOnlyStr = "host-only"
 )
 
 func newRTCIceTransportPolicy(raw string) RTCIceTransportPolicy {
@@ -25,6 +32,8 @@ func newRTCIceTransportPolicy(raw string) RTCIceTransportPolicy {
 		return RTCIceTransportPolicyRelay
 	case rtcIceTransportPolicyAllStr:
 		return RTCIceTransportPolicyAll
+	case rtcIceTransportPolicyHostOnlyStr:
+		return RTCIceTransportPolicyHostOnly
 	default:
 		return RTCIceTransportPolicy(Unknown)
 	}
@@ -36,6 +45,8 @@ func (t RTCIceTransportPolicy) String() string {
 		return rtcIceTransportPolicyRelayStr
 	case RTCIceTransportPolicyAll:
 		return rtcIceTransportPolicyAllStr
+	case RTCIceTransportPolicyHostOnly:
+		return rtcIceTransportPolicyHostOnlyStr
 	default:
 		return ErrUnknownType.Error()
 	}
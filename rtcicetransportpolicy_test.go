@@ -1,3 +1,5 @@
+//go:build !js
+
 package webrtc
 
 import (
@@ -14,6 +16,7 @@ func TestNewRTCIceTransportPolicy(t *testing.T) {
 		{"unknown", RTCIceTransportPolicy(Unknown)},
 		{"relay", RTCIceTransportPolicyRelay},
 		{"all", RTCIceTransportPolicyAll},
+		{"host-only", RTCIceTransportPolicyHostOnly},
 	}
 
 	for i, testCase := range testCases {
@@ -33,6 +36,7 @@ func TestRTCIceTransportPolicy_String(t *testing.T) {
 		{RTCIceTransportPolicy(Unknown), "unknown"},
 		{RTCIceTransportPolicyRelay, "relay"},
 		{RTCIceTransportPolicyAll, "all"},
+		{RTCIceTransportPolicyHostOnly, "host-only"},
 	}
 
 	for i, testCase := range testCases {
@@ -0,0 +1,120 @@
+package webrtc
+
+import "github.com/pions/webrtc/pkg/ice"
+
+// RTCIceTransportState represents the current state of the ICE transport.
+type RTCIceTransportState int
+
+const (
+	// RTCIceTransportStateNew indicates that the RTCIceTransport is gathering
+	// candidates and/or waiting for remote candidates to be supplied, and has
+	// not yet started checking.
+	RTCIceTransportStateNew RTCIceTransportState = iota + 1
+
+	// RTCIceTransportStateChecking indicates that the RTCIceTransport has
+	// received at least one remote candidate and is checking candidate pairs
+	// and has either not yet found a connection or consent checks have
+	// failed on all previously successful candidate pairs.
+	RTCIceTransportStateChecking
+
+	// RTCIceTransportStateConnected indicates that the RTCIceTransport has
+	// found a usable connection, but is still checking other candidate pairs
+	// to see if there is a better connection.
+	RTCIceTransportStateConnected
+
+	// RTCIceTransportStateCompleted indicates that the RTCIceTransport has
+	// finished gathering, received an indication that there are no more
+	// remote candidates, finished checking all candidate pairs and found a
+	// connection.
+	RTCIceTransportStateCompleted
+
+	// RTCIceTransportStateDisconnected indicates that the RTCIceTransport has
+	// lost connectivity with the remote peer, but may reconnect.
+	RTCIceTransportStateDisconnected
+
+	// RTCIceTransportStateFailed indicates that the RTCIceTransport has
+	// finished gathering, received an indication that there are no more
+	// remote candidates, finished checking all candidate pairs, and all pairs
+	// have either failed connectivity checks or lost consent.
+	RTCIceTransportStateFailed
+
+	// RTCIceTransportStateClosed indicates that the RTCIceTransport has shut
+	// down and is no longer responding to STUN requests.
+	RTCIceTransportStateClosed
+)
+
+// This is done this way because of a linter.
+const (
+	rtcIceTransportStateNewStr          = "new"
+	rtcIceTransportStateCheckingStr     = "checking"
+	rtcIceTransportStateConnectedStr    = "connected"
+	rtcIceTransportStateCompletedStr    = "completed"
+	rtcIceTransportStateDisconnectedStr = "disconnected"
+	rtcIceTransportStateFailedStr       = "failed"
+	rtcIceTransportStateClosedStr       = "closed"
+)
+
+func newRTCIceTransportState(raw string) RTCIceTransportState {
+	switch raw {
+	case rtcIceTransportStateNewStr:
+		return RTCIceTransportStateNew
+	case rtcIceTransportStateCheckingStr:
+		return RTCIceTransportStateChecking
+	case rtcIceTransportStateConnectedStr:
+		return RTCIceTransportStateConnected
+	case rtcIceTransportStateCompletedStr:
+		return RTCIceTransportStateCompleted
+	case rtcIceTransportStateDisconnectedStr:
+		return RTCIceTransportStateDisconnected
+	case rtcIceTransportStateFailedStr:
+		return RTCIceTransportStateFailed
+	case rtcIceTransportStateClosedStr:
+		return RTCIceTransportStateClosed
+	default:
+		return RTCIceTransportState(Unknown)
+	}
+}
+
+// newRTCIceTransportStateFromICE returns the state of the transport the
+// state of its ICE agent corresponds to
+func newRTCIceTransportStateFromICE(state ice.ConnectionState) RTCIceTransportState {
+	switch state {
+	case ice.ConnectionStateNew:
+		return RTCIceTransportStateNew
+	case ice.ConnectionStateChecking:
+		return RTCIceTransportStateChecking
+	case ice.ConnectionStateConnected:
+		return RTCIceTransportStateConnected
+	case ice.ConnectionStateCompleted:
+		return RTCIceTransportStateCompleted
+	case ice.ConnectionStateDisconnected:
+		return RTCIceTransportStateDisconnected
+	case ice.ConnectionStateFailed:
+		return RTCIceTransportStateFailed
+	case ice.ConnectionStateClosed:
+		return RTCIceTransportStateClosed
+	default:
+		return RTCIceTransportState(Unknown)
+	}
+}
+
+func (t RTCIceTransportState) String() string {
+	switch t {
+	case RTCIceTransportStateNew:
+		return rtcIceTransportStateNewStr
+	case RTCIceTransportStateChecking:
+		return rtcIceTransportStateCheckingStr
+	case RTCIceTransportStateConnected:
+		return rtcIceTransportStateConnectedStr
+	case RTCIceTransportStateCompleted:
+		return rtcIceTransportStateCompletedStr
+	case RTCIceTransportStateDisconnected:
+		return rtcIceTransportStateDisconnectedStr
+	case RTCIceTransportStateFailed:
+		return rtcIceTransportStateFailedStr
+	case RTCIceTransportStateClosed:
+		return rtcIceTransportStateClosedStr
+	default:
+		return ErrUnknownType.Error()
+	}
+}
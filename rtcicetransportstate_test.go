@@ -0,0 +1,82 @@
+//go:build !js
+
+package webrtc
+
+import (
+	"testing"
+
+	"github.com/pions/webrtc/pkg/ice"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewRTCIceTransportState(t *testing.T) {
+	testCases := []struct {
+		stateString   string
+		expectedState RTCIceTransportState
+	}{
+		{"unknown", RTCIceTransportState(Unknown)},
+		{"new", RTCIceTransportStateNew},
+		{"checking", RTCIceTransportStateChecking},
+		{"connected", RTCIceTransportStateConnected},
+		{"completed", RTCIceTransportStateCompleted},
+		{"disconnected", RTCIceTransportStateDisconnected},
+		{"failed", RTCIceTransportStateFailed},
+		{"closed", RTCIceTransportStateClosed},
+	}
+
+	for i, testCase := range testCases {
+		assert.Equal(t,
+			testCase.expectedState,
+			newRTCIceTransportState(testCase.stateString),
+			"testCase: %d %v", i, testCase,
+		)
+	}
+}
+
+func TestNewRTCIceTransportStateFromICE(t *testing.T) {
+	testCases := []struct {
+		iceState      ice.ConnectionState
+		expectedState RTCIceTransportState
+	}{
+		{ice.ConnectionState(Unknown), RTCIceTransportState(Unknown)},
+		{ice.ConnectionStateNew, RTCIceTransportStateNew},
+		{ice.ConnectionStateChecking, RTCIceTransportStateChecking},
+		{ice.ConnectionStateConnected, RTCIceTransportStateConnected},
+		{ice.ConnectionStateCompleted, RTCIceTransportStateCompleted},
+		{ice.ConnectionStateDisconnected, RTCIceTransportStateDisconnected},
+		{ice.ConnectionStateFailed, RTCIceTransportStateFailed},
+		{ice.ConnectionStateClosed, RTCIceTransportStateClosed},
+	}
+
+	for i, testCase := range testCases {
+		assert.Equal(t,
+			testCase.expectedState,
+			newRTCIceTransportStateFromICE(testCase.iceState),
+			"testCase: %d %v", i, testCase,
+		)
+	}
+}
+
+func TestRTCIceTransportState_String(t *testing.T) {
+	testCases := []struct {
+		state          RTCIceTransportState
+		expectedString string
+	}{
+		{RTCIceTransportState(Unknown), "unknown"},
+		{RTCIceTransportStateNew, "new"},
+		{RTCIceTransportStateChecking, "checking"},
+		{RTCIceTransportStateConnected, "connected"},
+		{RTCIceTransportStateCompleted, "completed"},
+		{RTCIceTransportStateDisconnected, "disconnected"},
+		{RTCIceTransportStateFailed, "failed"},
+		{RTCIceTransportStateClosed, "closed"},
+	}
+
+	for i, testCase := range testCases {
+		assert.Equal(t,
+			testCase.expectedString,
+			testCase.state.String(),
+			"testCase: %d %v", i, testCase,
+		)
+	}
+}
@@ -0,0 +1,102 @@
+//go:build !js
+
+package webrtc
+
+import (
+	"sync"
+
+	"github.com/pions/webrtc/internal/util"
+)
+
+// RTCMediaStream groups tracks which are played together, like the camera
+// and the microphone of a participant. Local tracks of a stream announce its
+// ID in their msid lines, and remote tracks announcing the same stream ID
+// are delivered in the same stream.
+// https://www.w3.org/TR/mediacapture-streams/#mediastream
+type RTCMediaStream struct {
+	lock sync.RWMutex
+
+	// ID identifies the stream to the remote peer
+	ID string
+
+	tracks []*RTCTrack
+}
+
+// NewRTCMediaStream creates an empty stream, a random ID is generated if
+// the id is empty
+func NewRTCMediaStream(id string) *RTCMediaStream {
+	if id == "" {
+		id = util.RandSeq(36)
+	}
+	return &RTCMediaStream{ID: id}
+}
+
+// AddTrack adds a local track to the stream, it has to be added before the
+// track is added to an RTCPeerConnection. The label of the track is set to
+// the ID of the stream, which is what the msid lines announce.
+func (s *RTCMediaStream) AddTrack(track *RTCTrack) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	track.Label = s.ID
+	s.addTrack(track)
+}
+
+// addTrack adds the track unless it is already part of the stream
+// Note: the caller should hold the stream lock.
+func (s *RTCMediaStream) addTrack(track *RTCTrack) {
+	for _, t := range s.tracks {
+		if t == track {
+			return
+		}
+	}
+	s.tracks = append(s.tracks, track)
+}
+
+// RemoveTrack removes the track from the stream
+func (s *RTCMediaStream) RemoveTrack(track *RTCTrack) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	for i, t := range s.tracks {
+		if t == track {
+			s.tracks = append(s.tracks[:i], s.tracks[i+1:]...)
+			return
+		}
+	}
+}
+
+// GetTracks returns the tracks of the stream in the order they were added
+func (s *RTCMediaStream) GetTracks() []*RTCTrack {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	return append([]*RTCTrack{}, s.tracks...)
+}
+
+// GetTracksOfKind returns the audio or the video tracks of the stream
+func (s *RTCMediaStream) GetTracksOfKind(kind RTCRtpCodecType) []*RTCTrack {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	var tracks []*RTCTrack
+	for _, t := range s.tracks {
+		if t.Kind == kind {
+			tracks = append(tracks, t)
+		}
+	}
+	return tracks
+}
+
+// GetTrackByID returns the track with the id, or nil if the stream has none
+func (s *RTCMediaStream) GetTrackByID(id string) *RTCTrack {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	for _, t := range s.tracks {
+		if t.ID == id {
+			return t
+		}
+	}
+	return nil
+}
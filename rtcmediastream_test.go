@@ -0,0 +1,47 @@
+//go:build !js
+
+package webrtc
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRTCMediaStream(t *testing.T) {
+	RegisterDefaultCodecs()
+
+	pc, err := New(RTCConfiguration{})
+	assert.Nil(t, err)
+
+	audioTrack, err := pc.NewRTCSampleTrack(DefaultPayloadTypeOpus, "microphone", "pion-audio")
+	assert.Nil(t, err)
+	videoTrack, err := pc.NewRTCSampleTrack(DefaultPayloadTypeVP8, "camera", "pion-video")
+	assert.Nil(t, err)
+
+	stream := NewRTCMediaStream("participant")
+	stream.AddTrack(audioTrack)
+	stream.AddTrack(videoTrack)
+	stream.AddTrack(videoTrack)
+	assert.Equal(t, []*RTCTrack{audioTrack, videoTrack}, stream.GetTracks())
+	assert.Equal(t, []*RTCTrack{videoTrack}, stream.GetTracksOfKind(RTCRtpCodecTypeVideo))
+	assert.Equal(t, audioTrack, stream.GetTrackByID("microphone"))
+	assert.Nil(t, stream.GetTrackByID("screen"))
+
+	// The tracks announce the stream in their msid lines
+	for _, track := range stream.GetTracks() {
+		_, err = pc.AddTrack(track)
+		assert.Nil(t, err)
+	}
+	offer, err := pc.CreateOffer(nil)
+	assert.Nil(t, err)
+	assert.True(t, strings.Contains(offer.SDP, fmt.Sprintf("a=ssrc:%d msid:participant microphone\r\n", audioTrack.Ssrc)))
+	assert.True(t, strings.Contains(offer.SDP, fmt.Sprintf("a=ssrc:%d msid:participant camera\r\n", videoTrack.Ssrc)))
+
+	stream.RemoveTrack(audioTrack)
+	assert.Equal(t, []*RTCTrack{videoTrack}, stream.GetTracks())
+
+	assert.NotEmpty(t, NewRTCMediaStream("").ID)
+}
@@ -1,5 +1,12 @@
 package webrtc
 
+import (
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base64"
+	"strings"
+)
+
 // RTCOAuthCredential represents OAuth credential information which is used by
 // the STUN/TURN client to connect to an ICE server as defined in
 // https://tools.ietf.org/html/rfc7635. Note that the kid parameter is not
@@ -13,3 +20,22 @@ type RTCOAuthCredential struct {
 	// self-contained token that is opaque to the application.
 	AccessToken string
 }
+
+// validate checks that the credential is complete and that the MacKey
+// decodes to a key for the HMAC-SHA1 or HMAC-SHA256-128 message integrity,
+// padding of the base64-url encoding is optional.
+// https://tools.ietf.org/html/rfc7635#section-6.2
+func (c RTCOAuthCredential) validate() error {
+	if c.AccessToken == "" {
+		return ErrTurnCredencials
+	}
+
+	macKey, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(c.MacKey, "="))
+	if err != nil {
+		return ErrTurnCredencials
+	}
+	if len(macKey) != sha1.Size && len(macKey) != sha256.Size {
+		return ErrTurnCredencials
+	}
+	return nil
+}
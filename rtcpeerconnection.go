@@ -1,21 +1,48 @@
+//go:build !js
+
 // Package webrtc implements the WebRTC 1.0 as defined in W3C WebRTC specification document.
+//
+// Builds with the pionprofile tag label the CPU profile samples of the
+// packet read loop, SRTP, SCTP and ICE with the connection, identified by
+// its local ICE ufrag, and the SSRC of SRTP packets. The packets handled and
+// the time spent per section are published with expvar as
+// pion_webrtc_profile.
+//
+// The RTCPeerConnections of a process share their background goroutines.
+// Event handlers run on a pool of workers, the handlers of an object, like
+// the ICE connection state changes of an RTCPeerConnection or the messages
+// of an RTCDataChannel, run one at a time in the order of their events, the
+// handlers of different objects run concurrently. A handler may block, the
+// other handlers run on other workers meanwhile, and OnTrack handlers are
+// expected to read the Packets of their track until it ends. WriteSample and
+// WriteRTP send on the calling goroutine, the Samples and RawRTP channels of
+// the tracks are read by goroutines shared by up to 64 tracks until the
+// RTCPeerConnection is done, writers select on the Done of the track.
 package webrtc
 
 import (
+	"bytes"
 	"crypto/ecdsa"
 	"crypto/elliptic"
 	"crypto/rand"
 	"fmt"
 	"net"
+	"reflect"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
 
 	"encoding/binary"
 
+	"github.com/pions/webrtc/internal/dtls"
 	"github.com/pions/webrtc/internal/network"
+	"github.com/pions/webrtc/internal/sctp"
 	"github.com/pions/webrtc/internal/sdp"
+	"github.com/pions/webrtc/internal/turn"
+	"github.com/pions/webrtc/pkg/datachannel"
 	"github.com/pions/webrtc/pkg/ice"
+	"github.com/pions/webrtc/pkg/logging"
 	"github.com/pions/webrtc/pkg/media"
 	"github.com/pions/webrtc/pkg/rtcerr"
 	"github.com/pions/webrtc/pkg/rtcp"
@@ -23,9 +50,22 @@ import (
 	"github.com/pkg/errors"
 )
 
-// Unknown defines default public constant to use for "enum" like struct
-// comparisons when no value was defined.
-const Unknown = iota
+// defaultMIDExtensionID is the id the MID header extension is mapped to in offers
+const defaultMIDExtensionID = 1
+
+// defaultAbsSendTimeExtensionID is the id the abs-send-time header extension
+// is mapped to in offers
+const defaultAbsSendTimeExtensionID = 2
+
+// sctpShutdownTimeout is how long GracefulClose waits for the remote peer to
+// acknowledge the shutdown of the SCTP association
+const sctpShutdownTimeout = 5 * time.Second
+
+// rtcpGoodbyeMaxSources is the number of sources a single RTCP BYE lists
+const rtcpGoodbyeMaxSources = 31
+
+// senderReportInterval is how often the streams sent are reported
+const senderReportInterval = time.Second
 
 // RTCPeerConnection represents a WebRTC connection that establishes a
 // peer-to-peer communications with another RTCPeerConnection instance in a
@@ -35,48 +75,21 @@ type RTCPeerConnection struct {
 
 	configuration RTCConfiguration
 
-	// CurrentLocalDescription represents the local description that was
-	// successfully negotiated the last time the RTCPeerConnection transitioned
-	// into the stable state plus any local candidates that have been generated
-	// by the IceAgent since the offer or answer was created.
-	CurrentLocalDescription *RTCSessionDescription
-
-	// PendingLocalDescription represents a local description that is in the
-	// process of being negotiated plus any local candidates that have been
-	// generated by the IceAgent since the offer or answer was created. If the
-	// RTCPeerConnection is in the stable state, the value is null.
-	PendingLocalDescription *RTCSessionDescription
-
-	// CurrentRemoteDescription represents the last remote description that was
-	// successfully negotiated the last time the RTCPeerConnection transitioned
-	// into the stable state plus any remote candidates that have been supplied
-	// via AddIceCandidate() since the offer or answer was created.
-	CurrentRemoteDescription *RTCSessionDescription
-
-	// PendingRemoteDescription represents a remote description that is in the
-	// process of being negotiated, complete with any remote candidates that
-	// have been supplied via AddIceCandidate() since the offer or answer was
-	// created. If the RTCPeerConnection is in the stable state, the value is
-	// null.
-	PendingRemoteDescription *RTCSessionDescription
-
-	// SignalingState attribute returns the signaling state of the
-	// RTCPeerConnection instance.
-	SignalingState RTCSignalingState
+	// The descriptions and states are read with their methods, they are
+	// replaced while holding the lock
+	currentLocalDescription  *RTCSessionDescription
+	pendingLocalDescription  *RTCSessionDescription
+	currentRemoteDescription *RTCSessionDescription
+	pendingRemoteDescription *RTCSessionDescription
+	signalingState           RTCSignalingState
+	// iceConnectionState RTCIceConnectionState  // FIXME SWAP-FOR-THIS
+	iceConnectionState ice.ConnectionState // FIXME REMOVE
+	connectionState    RTCPeerConnectionState
 
 	// IceGatheringState attribute returns the ICE gathering state of the
 	// RTCPeerConnection instance.
 	IceGatheringState RTCIceGatheringState // FIXME NOT-USED
 
-	// IceConnectionState attribute returns the ICE connection state of the
-	// RTCPeerConnection instance.
-	// IceConnectionState RTCIceConnectionState  // FIXME SWAP-FOR-THIS
-	IceConnectionState ice.ConnectionState // FIXME REMOVE
-
-	// ConnectionState attribute returns the connection state of the
-	// RTCPeerConnection instance.
-	ConnectionState RTCPeerConnectionState
-
 	idpLoginURL *string
 
 	isClosed          bool
@@ -85,10 +98,29 @@ type RTCPeerConnection struct {
 	lastOffer  string
 	lastAnswer string
 
+	// sdpOrigin is the o= line shared by all local descriptions
+	sdpOrigin *sdp.Origin
+
+	// remotePlanB is set when the remote description uses Plan B
+	remotePlanB bool
+
 	// Media
 	mediaEngine     *MediaEngine
 	rtpTransceivers []*RTCRtpTransceiver
 
+	// payloadTypes maps the payload types of local codecs to the ones of
+	// the offer they were answered with
+	payloadTypes map[uint8]uint8
+
+	// remoteStreams group the remote tracks by the stream ID of their msid
+	remoteStreams map[string]*RTCMediaStream
+
+	// ssrcGenerator returns the SSRCs of local streams, nil picks random ones
+	ssrcGenerator func() uint32
+
+	// dtlsTransport carries every media section, they are bundled
+	dtlsTransport *RTCDtlsTransport
+
 	// sctpTransport
 	sctpTransport *RTCSctpTransport
 
@@ -100,29 +132,77 @@ type RTCPeerConnection struct {
 	// OnIceCandidateError        func() // FIXME NOT-USED
 	// OnSignalingStateChange     func() // FIXME NOT-USED
 
-	// OnIceConnectionStateChange designates an event handler which is called
-	// when an ice connection state is changed.
-	OnICEConnectionStateChange func(ice.ConnectionState)
-
 	// OnIceGatheringStateChange  func() // FIXME NOT-USED
 	// OnConnectionStateChange    func() // FIXME NOT-USED
 
-	// OnTrack designates an event handler which is called when remote track
-	// arrives from a remote peer.
-	OnTrack func(*RTCTrack)
+	// The event handlers are set with OnICEConnectionStateChange, OnTrack
+	// and OnDataChannel while holding the lock
+	onICEConnectionStateChangeHandler func(ice.ConnectionState)
+	onTrackHandler                    func(*RTCTrack)
+	onDataChannelHandler              func(*RTCDataChannel)
+
+	// stopStats ends the goroutine delivering the stats of OnStats, it is
+	// replaced while holding the lock
+	stopStats chan struct{}
 
-	// OnDataChannel designates an event handler which is invoked when a data
-	// channel message arrives from a remote peer.
-	OnDataChannel func(*RTCDataChannel)
+	// operations runs the handlers of the ICE connection state changes and
+	// of the remote tracks in the order of their events
+	operations operations
+
+	// events are delivered to the channel returned by Events
+	events *rtcEventQueue
 
 	// Deprecated: Internal mechanism which will be removed.
 	networkManager *network.Manager
 
-	backgroundActions chan func()
+	log logging.LeveledLogger
+
+	// done is closed once the connection is closed or has failed
+	done     chan struct{}
+	doneOnce sync.Once
+
+	// sdpLimits bound the remote descriptions accepted
+	sdpLimits sdp.Limits
+
+	// sctpPortAttributes offers the data channel section with a=sctp-port
+	// rather than a=sctpmap
+	sctpPortAttributes bool
+
+	// iceCredentialRefresh renews the time-limited credentials of ICE
+	// servers, see SettingEngine.SetICECredentialRefresher
+	iceCredentialRefresh func(RTCIceServer) (RTCIceServer, error)
+
+	// trackQueuePolicy and trackQueueSize queue the Samples of local tracks,
+	// see SettingEngine.SetTrackQueuePolicy
+	trackQueuePolicy RTCTrackQueuePolicy
+	trackQueueSize   int
+
+	// packetizerMTU is the MTU of the packets of new local tracks, see
+	// SettingEngine.SetPacketizerMTU
+	packetizerMTU int
+
+	// unhandledEvents holds the events which arrive before their handler is set
+	unhandledEvents *rtcUnhandledEvents
+
+	// dtlsRole is the role of the DTLS transport negotiated with the setup
+	// attribute of the remote description
+	dtlsRole RTCDtlsRole
+
+	// gatherOnce starts gathering the candidates of the ICE servers, gathered
+	// is closed once they are
+	gatherOnce sync.Once
+	gathered   chan struct{}
 }
 
-// New creates a new RTCPeerConfiguration with the provided configuration
+// New creates a new RTCPeerConfiguration with the provided configuration,
+// using the DefaultMediaEngine and the default settings
 func New(configuration RTCConfiguration) (*RTCPeerConnection, error) {
+	return NewAPI().NewRTCPeerConnection(configuration)
+}
+
+// NewRTCPeerConnection creates a new RTCPeerConnection with the provided
+// configuration, using the MediaEngine and SettingEngine of the API
+func (api *API) NewRTCPeerConnection(configuration RTCConfiguration) (*RTCPeerConnection, error) {
 	// https://w3c.github.io/webrtc-pc/#constructor (Step #2)
 	// Some variables defined explicitly despite their implicit zero values to
 	// allow better readability to understand what is happening.
@@ -134,20 +214,41 @@ func New(configuration RTCConfiguration) (*RTCPeerConnection, error) {
 			RtcpMuxPolicy:        RTCRtcpMuxPolicyRequire,
 			Certificates:         []RTCCertificate{},
 			IceCandidatePoolSize: 0,
+			SdpSemantics:         RTCSdpSemanticsUnifiedPlanWithFallback,
 		},
 		isClosed:          false,
 		negotiationNeeded: false,
 		lastOffer:         "",
 		lastAnswer:        "",
-		SignalingState:    RTCSignalingStateStable,
-		// IceConnectionState: RTCIceConnectionStateNew, // FIXME SWAP-FOR-THIS
-		IceConnectionState: ice.ConnectionStateNew, // FIXME REMOVE
+		signalingState:    RTCSignalingStateStable,
+		// iceConnectionState: RTCIceConnectionStateNew, // FIXME SWAP-FOR-THIS
+		iceConnectionState: ice.ConnectionStateNew, // FIXME REMOVE
 		IceGatheringState:  RTCIceGatheringStateNew,
-		ConnectionState:    RTCPeerConnectionStateNew,
-		mediaEngine:        DefaultMediaEngine,
+		connectionState:    RTCPeerConnectionStateNew,
+		mediaEngine:        api.mediaEngine,
+		log:                api.settingEngine.getLoggerFactory().NewLogger(logging.ScopePC),
+		sdpLimits:          api.settingEngine.getSDPLimits(),
+		ssrcGenerator:      api.settingEngine.ssrcGenerator,
+		sctpPortAttributes: api.settingEngine.sctpPortAttributes,
 		sctpTransport:      newRTCSctpTransport(),
 		dataChannels:       make(map[uint16]*RTCDataChannel),
-		backgroundActions:  make(chan func(), 1),
+		remoteStreams:      make(map[string]*RTCMediaStream),
+		payloadTypes:       make(map[uint8]uint8),
+		events:             newRTCEventQueue(),
+		done:               make(chan struct{}),
+		gathered:           make(chan struct{}),
+	}
+
+	pc.iceCredentialRefresh = api.settingEngine.iceCredentialRefresh
+	pc.trackQueuePolicy, pc.trackQueueSize = api.settingEngine.getTrackQueue()
+	pc.packetizerMTU = api.settingEngine.getPacketizerMTU()
+	if channels := api.settingEngine.sctpMaxChannels; channels > 0 {
+		pc.sctpTransport.localMaxChannels = channels
+	}
+	pc.unhandledEvents = newRTCUnhandledEvents(api.settingEngine.unhandledEventWindow, pc.log, pc.done)
+
+	if len(configuration.Certificates) == 0 && api.settingEngine.certificate != nil {
+		configuration.Certificates = []RTCCertificate{*api.settingEngine.certificate}
 	}
 
 	var err error
@@ -155,31 +256,47 @@ func New(configuration RTCConfiguration) (*RTCPeerConnection, error) {
 		return nil, err
 	}
 
-	pc.networkManager, err = network.NewManager(pc.generateChannel, pc.dataChannelEventHandler, pc.iceStateChange)
-	if err != nil {
+	// DTLS authenticates with the first certificate, its fingerprint is
+	// stable if the certificate is persisted
+	settings := api.settingEngine.networkSettings()
+	if settings.Certificate, err = pc.configuration.Certificates[0].dtlsCertificate(); err != nil {
 		return nil, err
 	}
 
-	// FIXME Temporary code before IceAgent and RTCIceTransport Rebuild
-	for _, server := range pc.configuration.IceServers {
-		for _, rawURL := range server.URLs {
-			url, err := ice.ParseURL(rawURL)
-			if err != nil {
-				return nil, err
-			}
+	// Candidates of the RTCP component are gathered for peers which may not
+	// multiplex RTCP with RTP
+	settings.RTCPCandidates = pc.configuration.RtcpMuxPolicy == RTCRtcpMuxPolicyNegotiate
 
-			err = pc.networkManager.AddURL(url)
-			if err != nil {
-				fmt.Println(err)
-			}
-		}
+	// The transport policy limits the candidates gathered
+	switch pc.configuration.IceTransportPolicy {
+	case RTCIceTransportPolicyRelay:
+		settings.DisableHostCandidates = true
+		settings.DisableSrflxCandidates = true
+	case RTCIceTransportPolicyHostOnly:
+		settings.DisableSrflxCandidates = true
 	}
 
-	go func() {
-		for action := range pc.backgroundActions {
-			action()
-		}
-	}()
+	pc.networkManager, err = network.NewManager(pc.generateChannel, pc.dataChannelEventHandler, pc.iceStateChange, pc.observeInboundRTP, pc.handleRTCP, pc.dtlsStateChange, settings)
+	if err != nil {
+		return nil, err
+	}
+	api.settingEngine.configureICEAgent(pc.networkManager.IceAgent)
+	pc.networkManager.IceAgent.SetCandidateFilter(iceCandidateFilter(pc.configuration.IceTransportPolicy))
+	pc.dtlsTransport = newRTCDtlsTransport(pc.networkManager)
+	pc.sctpTransport.Transport = pc.dtlsTransport
+
+	if interval := api.settingEngine.rtpKeepaliveInterval; interval > 0 {
+		workers.every(interval/2, func() bool { return pc.keepaliveSenders(interval) })
+	}
+	if interval := api.settingEngine.rrtrInterval; interval > 0 {
+		workers.every(interval, pc.sendReceiverReferenceTime)
+	}
+	workers.every(senderReportInterval, pc.sendSenderReports)
+
+	// https://www.w3.org/TR/webrtc/#constructor (step #11)
+	if pc.configuration.IceCandidatePoolSize != 0 {
+		pc.startGathering()
+	}
 
 	return &pc, nil
 }
@@ -231,6 +348,10 @@ func (pc *RTCPeerConnection) initConfiguration(configuration RTCConfiguration) e
 		pc.configuration.IceTransportPolicy = configuration.IceTransportPolicy
 	}
 
+	if configuration.SdpSemantics != RTCSdpSemantics(Unknown) {
+		pc.configuration.SdpSemantics = configuration.SdpSemantics
+	}
+
 	if len(configuration.IceServers) > 0 {
 		for _, server := range configuration.IceServers {
 			if err := server.validate(); err != nil {
@@ -297,8 +418,17 @@ func (pc *RTCPeerConnection) SetConfiguration(configuration RTCConfiguration) er
 	}
 
 	// https://www.w3.org/TR/webrtc/#set-the-configuration (step #8)
+	// The candidates gathered already are kept, the new policy only
+	// filters those paired
 	if configuration.IceTransportPolicy != RTCIceTransportPolicy(Unknown) {
 		pc.configuration.IceTransportPolicy = configuration.IceTransportPolicy
+		pc.networkManager.IceAgent.SetCandidateFilter(iceCandidateFilter(configuration.IceTransportPolicy))
+	}
+
+	if configuration.SdpSemantics != RTCSdpSemantics(Unknown) {
+		if configuration.SdpSemantics != pc.configuration.SdpSemantics {
+			return &rtcerr.InvalidModificationError{Err: ErrModifyingSdpSemantics}
+		}
 	}
 
 	// https://www.w3.org/TR/webrtc/#set-the-configuration (step #11)
@@ -311,6 +441,11 @@ func (pc *RTCPeerConnection) SetConfiguration(configuration RTCConfiguration) er
 		}
 		pc.configuration.IceServers = configuration.IceServers
 	}
+
+	// https://www.w3.org/TR/webrtc/#set-the-configuration (step #12)
+	if pc.configuration.IceCandidatePoolSize != 0 {
+		pc.startGathering()
+	}
 	return nil
 }
 
@@ -338,32 +473,177 @@ func (pc *RTCPeerConnection) CreateOffer(options *RTCOfferOptions) (RTCSessionDe
 		return RTCSessionDescription{}, &rtcerr.InvalidStateError{Err: ErrConnectionClosed}
 	}
 
+	pc.gatherCandidates()
 	d := sdp.NewJSEPSessionDescription(pc.networkManager.DTLSFingerprint(), useIdentity)
 	candidates := pc.generateLocalCandidates()
 
-	bundleValue := "BUNDLE"
+	bundleValue := sdp.SemanticTokenBundle
+	offered := make(map[string]bool)
 
-	if pc.addRTPMediaSection(d, RTCRtpCodecTypeAudio, "audio", RTCRtpTransceiverDirectionSendrecv, candidates, sdp.ConnectionRoleActpass) {
-		bundleValue += " audio"
-	}
-	if pc.addRTPMediaSection(d, RTCRtpCodecTypeVideo, "video", RTCRtpTransceiverDirectionSendrecv, candidates, sdp.ConnectionRoleActpass) {
-		bundleValue += " video"
+	for _, section := range pc.offerMediaSections() {
+		if pc.addRTPMediaSection(d, section.kind, section.mid, RTCRtpTransceiverDirectionSendrecv, candidates, sdp.ConnectionRoleActpass) {
+			bundleValue += " " + section.mid
+			pc.offerBundleOnly(d, offered)
+		}
 	}
 
-	pc.addDataMediaSection(d, "data", candidates, sdp.ConnectionRoleActpass)
-	d = d.WithValueAttribute(sdp.AttrKeyGroup, bundleValue+" data")
+	if pc.offersData() {
+		pc.addDataMediaSection(d, "data", candidates, sdp.ConnectionRoleActpass)
+		pc.offerBundleOnly(d, offered)
+		bundleValue += " data"
+	}
+	if len(d.MediaDescriptions) > 0 {
+		d = d.WithValueAttribute(sdp.AttrKeyGroup, bundleValue)
+	}
 
 	for _, m := range d.MediaDescriptions {
 		m.WithPropertyAttribute("setup:actpass")
 	}
 
-	pc.CurrentLocalDescription = &RTCSessionDescription{
+	desc := RTCSessionDescription{
 		Type:   RTCSdpTypeOffer,
-		Sdp:    d.Marshal(),
+		SDP:    pc.marshalLocalDescription(d),
 		parsed: d,
 	}
+	pc.Lock()
+	pc.currentLocalDescription = &desc
+	pc.Unlock()
+
+	return desc, nil
+}
+
+// marshalLocalDescription keeps the session ID of the o= line stable across
+// local descriptions, the session version is incremented whenever the
+// description changed
+// https://tools.ietf.org/html/draft-ietf-rtcweb-jsep-24#section-5.2.2
+func (pc *RTCPeerConnection) marshalLocalDescription(d *sdp.SessionDescription) string {
+	if pc.sdpOrigin == nil {
+		origin := d.Origin
+		pc.sdpOrigin = &origin
+		return d.Marshal()
+	}
+
+	d.Origin = *pc.sdpOrigin
+	raw := d.Marshal()
+	if pc.currentLocalDescription != nil && withoutCandidates(pc.currentLocalDescription.SDP) == withoutCandidates(raw) {
+		return raw
+	}
+
+	pc.sdpOrigin.SessionVersion++
+	d.Origin = *pc.sdpOrigin
+	return d.Marshal()
+}
+
+// withoutCandidates strips the candidate lines from a description, their
+// priorities are not stable between two marshals of the same candidates
+func withoutCandidates(raw string) string {
+	lines := strings.Split(raw, "\r\n")
+	filtered := lines[:0]
+	for _, line := range lines {
+		if !strings.HasPrefix(line, "a=candidate:") {
+			filtered = append(filtered, line)
+		}
+	}
+	return strings.Join(filtered, "\r\n")
+}
+
+// offerBundleOnly marks the last media section of the offer bundle-only if
+// the bundle policy doesn't gather candidates for it: max-bundle only does
+// for the first section, balanced for the first section of every media type
+// and max-compat for every section. offered holds the media types of the
+// sections before it.
+// https://tools.ietf.org/html/draft-ietf-rtcweb-jsep-24#section-4.1.1
+func (pc *RTCPeerConnection) offerBundleOnly(d *sdp.SessionDescription, offered map[string]bool) {
+	media := d.MediaDescriptions[len(d.MediaDescriptions)-1]
+	kind := media.MediaName.Media
+
+	bundleOnly := false
+	switch pc.configuration.BundlePolicy {
+	case RTCBundlePolicyMaxBundle:
+		bundleOnly = len(offered) > 0
+	case RTCBundlePolicyBalanced:
+		bundleOnly = offered[kind]
+	}
+	offered[kind] = true
+
+	if bundleOnly {
+		media.WithBundleOnly()
+	}
+}
+
+type rtcMediaSection struct {
+	kind RTCRtpCodecType
+	mid  string
+}
+
+// offerMediaSections returns the media sections to offer: an audio and a
+// video section to receive, followed by a section for every other
+// transceiver sending a track, Unified Plan style
+func (pc *RTCPeerConnection) offerMediaSections() []rtcMediaSection {
+	pc.RLock()
+	defer pc.RUnlock()
+
+	sections := []rtcMediaSection{
+		{kind: RTCRtpCodecTypeAudio, mid: RTCRtpCodecTypeAudio.String()},
+		{kind: RTCRtpCodecTypeVideo, mid: RTCRtpCodecTypeVideo.String()},
+	}
+	for _, t := range pc.rtpTransceivers {
+		if t.stopped || t.Sender.Track == nil || t.Mid == "" || pc.hasMid(sections, t.Mid) {
+			continue
+		}
+		sections = append(sections, rtcMediaSection{kind: t.Sender.Track.Kind, mid: t.Mid})
+	}
+	return sections
+}
+
+// offersData reports whether offers carry the data channel section, which
+// they do once a data channel is created or the remote peer negotiated one
+func (pc *RTCPeerConnection) offersData() bool {
+	pc.RLock()
+	defer pc.RUnlock()
+
+	if len(pc.dataChannels) > 0 {
+		return true
+	}
+	if pc.currentRemoteDescription == nil {
+		return false
+	}
+	_, ok := pc.currentRemoteDescription.parsed.GetDataMedia()
+	return ok
+}
+
+func (pc *RTCPeerConnection) hasMid(sections []rtcMediaSection, mid string) bool {
+	for _, section := range sections {
+		if section.mid == mid {
+			return true
+		}
+	}
+	return false
+}
+
+// generateMid returns an unused mid for a transceiver of the kind, the first
+// transceiver of a kind is named after it. With Plan B all transceivers of a
+// kind share its media section.
+// Note: the caller should hold the RTCPeerConnection lock.
+func (pc *RTCPeerConnection) generateMid(kind RTCRtpCodecType) string {
+	if pc.configuration.SdpSemantics == RTCSdpSemanticsPlanB {
+		return kind.String()
+	}
+
+	used := func(mid string) bool {
+		for _, t := range pc.rtpTransceivers {
+			if t.Mid == mid {
+				return true
+			}
+		}
+		return false
+	}
 
-	return *pc.CurrentLocalDescription, nil
+	mid := kind.String()
+	for i := 1; used(mid); i++ {
+		mid = fmt.Sprintf("%s%d", kind.String(), i)
+	}
+	return mid
 }
 
 // CreateAnswer starts the RTCPeerConnection and generates the localDescription
@@ -377,52 +657,148 @@ func (pc *RTCPeerConnection) CreateAnswer(options *RTCAnswerOptions) (RTCSession
 		return RTCSessionDescription{}, &rtcerr.InvalidStateError{Err: ErrConnectionClosed}
 	}
 
+	pc.gatherCandidates()
 	candidates := pc.generateLocalCandidates()
 	d := sdp.NewJSEPSessionDescription(pc.networkManager.DTLSFingerprint(), useIdentity)
 
-	bundleValue := "BUNDLE"
-	for _, remoteMedia := range pc.CurrentRemoteDescription.parsed.MediaDescriptions {
-		// TODO @trivigy better SDP parser
-		var peerDirection RTCRtpTransceiverDirection
-		midValue := ""
-		for _, a := range remoteMedia.Attributes {
-			if strings.HasPrefix(*a.String(), "mid") {
-				midValue = (*a.String())[len("mid:"):]
-			} else if strings.HasPrefix(*a.String(), "sendrecv") {
-				peerDirection = RTCRtpTransceiverDirectionSendrecv
-			} else if strings.HasPrefix(*a.String(), "sendonly") {
-				peerDirection = RTCRtpTransceiverDirectionSendonly
-			} else if strings.HasPrefix(*a.String(), "recvonly") {
-				peerDirection = RTCRtpTransceiverDirectionRecvonly
-			}
-		}
+	pc.associateTransceivers(pc.currentRemoteDescription.parsed)
 
+	pc.Lock()
+	pc.payloadTypes = make(map[uint8]uint8)
+	pc.Unlock()
+
+	bundleValue := sdp.SemanticTokenBundle
+	bundled, offeredBundle := pc.currentRemoteDescription.parsed.GetBundleGroup()
+	for i, remoteMedia := range pc.currentRemoteDescription.parsed.MediaDescriptions {
+		peerDirection := NewRTCRtpTransceiverDirection(remoteMedia.Direction())
+		midValue := remoteMedia.Mid()
+
+		// Sections the offer doesn't bundle are answered on the transport
+		// shared by every section, unless max-bundle or max-compat reject
+		// them. Without a bundle group only the first section is accepted
+		// then. max-compat would negotiate them on transports of their own,
+		// which aren't supported.
+		isBundled := offeredBundle && containsMid(bundled, midValue)
+		rejectsUnbundled := pc.configuration.BundlePolicy == RTCBundlePolicyMaxBundle || pc.configuration.BundlePolicy == RTCBundlePolicyMaxCompat
+		rejected := rejectsUnbundled && !isBundled && (offeredBundle || i > 0)
 		appendBundle := func() {
-			bundleValue += " " + midValue
+			if rejected {
+				d.MediaDescriptions[len(d.MediaDescriptions)-1].MediaName.Port = sdp.RangedPort{Value: 0}
+			} else if isBundled {
+				bundleValue += " " + midValue
+			}
 		}
 
-		if strings.HasPrefix(*remoteMedia.MediaName.String(), "audio") {
-			if pc.addRTPMediaSection(d, RTCRtpCodecTypeAudio, midValue, peerDirection, candidates, sdp.ConnectionRoleActive) {
+		switch remoteMedia.MediaName.Media {
+		case RTCRtpCodecTypeAudio.String():
+			if pc.addRTPMediaSection(d, RTCRtpCodecTypeAudio, midValue, peerDirection, candidates, pc.dtlsRole.connectionRole()) {
 				appendBundle()
 			}
-		} else if strings.HasPrefix(*remoteMedia.MediaName.String(), "video") {
-			if pc.addRTPMediaSection(d, RTCRtpCodecTypeVideo, midValue, peerDirection, candidates, sdp.ConnectionRoleActive) {
+		case RTCRtpCodecTypeVideo.String():
+			if pc.addRTPMediaSection(d, RTCRtpCodecTypeVideo, midValue, peerDirection, candidates, pc.dtlsRole.connectionRole()) {
 				appendBundle()
 			}
-		} else if strings.HasPrefix(*remoteMedia.MediaName.String(), "application") {
-			pc.addDataMediaSection(d, midValue, candidates, sdp.ConnectionRoleActive)
+		case "application":
+			pc.addDataMediaSection(d, midValue, candidates, pc.dtlsRole.connectionRole())
 			appendBundle()
 		}
 	}
 
-	d = d.WithValueAttribute(sdp.AttrKeyGroup, bundleValue)
+	// The answer only bundles if the offer did
+	if offeredBundle {
+		d = d.WithValueAttribute(sdp.AttrKeyGroup, bundleValue)
+	}
+
+	// Media is sent under the payload types of the offer
+	payloadTypes := make(map[uint8]uint8)
+	pc.RLock()
+	for local, negotiated := range pc.payloadTypes {
+		payloadTypes[local] = negotiated
+	}
+	pc.RUnlock()
+	pc.networkManager.SetPayloadTypes(payloadTypes)
 
-	pc.CurrentLocalDescription = &RTCSessionDescription{
+	desc := RTCSessionDescription{
 		Type:   RTCSdpTypeAnswer,
-		Sdp:    d.Marshal(),
+		SDP:    pc.marshalLocalDescription(d),
 		parsed: d,
 	}
-	return *pc.CurrentLocalDescription, nil
+	pc.Lock()
+	pc.currentLocalDescription = &desc
+	pc.Unlock()
+	return desc, nil
+}
+
+func containsMid(mids []string, mid string) bool {
+	for _, m := range mids {
+		if m == mid {
+			return true
+		}
+	}
+	return false
+}
+
+// associateTransceivers binds every transceiver sending media to a media
+// section of the remote description with the same kind, so its track is
+// announced in the answer. Sections are taken in order, transceivers that
+// are left over join the first section of their kind.
+func (pc *RTCPeerConnection) associateTransceivers(remote *sdp.SessionDescription) {
+	pc.Lock()
+	defer pc.Unlock()
+
+	var mids []string
+	kinds := make(map[string]RTCRtpCodecType)
+	for _, remoteMedia := range remote.MediaDescriptions {
+		var kind RTCRtpCodecType
+		switch remoteMedia.MediaName.Media {
+		case RTCRtpCodecTypeAudio.String():
+			kind = RTCRtpCodecTypeAudio
+		case RTCRtpCodecTypeVideo.String():
+			kind = RTCRtpCodecTypeVideo
+		default:
+			continue
+		}
+
+		for _, a := range remoteMedia.Attributes {
+			if mid, ok := a.Mid(); ok {
+				mids = append(mids, mid)
+				kinds[mid] = kind
+			}
+		}
+	}
+
+	var unassociated []*RTCRtpTransceiver
+	associated := make(map[string]bool)
+	for _, t := range pc.rtpTransceivers {
+		if t.stopped || t.Sender.Track == nil {
+			continue
+		}
+		if kind, ok := kinds[t.Mid]; ok && kind == t.Sender.Track.Kind {
+			associated[t.Mid] = true
+			continue
+		}
+		unassociated = append(unassociated, t)
+	}
+
+	for _, t := range unassociated {
+		fallback := ""
+		for _, mid := range mids {
+			if kinds[mid] != t.Sender.Track.Kind {
+				continue
+			}
+			if fallback == "" {
+				fallback = mid
+			}
+			if !associated[mid] {
+				fallback = mid
+				break
+			}
+		}
+		if fallback != "" {
+			t.Mid = fallback
+			associated[fallback] = true
+		}
+	}
 }
 
 // // SetLocalDescription sets the SessionDescription of the local peer
@@ -435,15 +811,38 @@ func (pc *RTCPeerConnection) CreateAnswer(options *RTCAnswerOptions) (RTCSession
 // determine if setLocalDescription has already been called.
 // https://www.w3.org/TR/webrtc/#dom-rtcpeerconnection-localdescription
 func (pc *RTCPeerConnection) LocalDescription() *RTCSessionDescription {
-	if pc.PendingLocalDescription != nil {
-		return pc.PendingLocalDescription
+	pc.RLock()
+	defer pc.RUnlock()
+
+	if pc.pendingLocalDescription != nil {
+		return pc.pendingLocalDescription.copy()
 	}
-	return pc.CurrentLocalDescription
+	return pc.currentLocalDescription.copy()
+}
+
+// CurrentLocalDescription represents the local description that was
+// successfully negotiated the last time the RTCPeerConnection transitioned
+// into the stable state plus any local candidates that have been generated
+// by the IceAgent since the offer or answer was created.
+func (pc *RTCPeerConnection) CurrentLocalDescription() *RTCSessionDescription {
+	pc.RLock()
+	defer pc.RUnlock()
+	return pc.currentLocalDescription.copy()
+}
+
+// PendingLocalDescription represents a local description that is in the
+// process of being negotiated plus any local candidates that have been
+// generated by the IceAgent since the offer or answer was created. If the
+// RTCPeerConnection is in the stable state, the value is null.
+func (pc *RTCPeerConnection) PendingLocalDescription() *RTCSessionDescription {
+	pc.RLock()
+	defer pc.RUnlock()
+	return pc.pendingLocalDescription.copy()
 }
 
 // SetRemoteDescription sets the SessionDescription of the remote peer
 func (pc *RTCPeerConnection) SetRemoteDescription(desc RTCSessionDescription) error {
-	if pc.CurrentRemoteDescription != nil {
+	if pc.currentRemoteDescription != nil {
 		return errors.Errorf("remoteDescription is already defined, SetRemoteDescription can only be called once")
 	}
 
@@ -454,28 +853,175 @@ func (pc *RTCPeerConnection) SetRemoteDescription(desc RTCSessionDescription) er
 		weOffer = false
 	}
 
-	pc.CurrentRemoteDescription = &desc
-	pc.CurrentRemoteDescription.parsed = &sdp.SessionDescription{}
-	if err := pc.CurrentRemoteDescription.parsed.Unmarshal(pc.CurrentRemoteDescription.Sdp); err != nil {
+	// Descriptions of SIP gateways often deviate from the grammar, they are
+	// repaired with a warning
+	desc.parsed = &sdp.SessionDescription{}
+	warnings, err := desc.parsed.UnmarshalTolerant(desc.SDP, pc.sdpLimits)
+	if err != nil {
+		return err
+	}
+	for _, warning := range warnings {
+		pc.log.Warnf("Repaired remote description: %s", warning)
+	}
+
+	// The description is rejected before any state is changed
+	planB := isPlanB(desc.parsed)
+	if planB && pc.configuration.SdpSemantics == RTCSdpSemanticsUnifiedPlan {
+		return &rtcerr.InvalidAccessError{Err: ErrIncorrectSdpSemantics}
+	}
+	dtlsRole, err := negotiateDTLSRole(desc.parsed, weOffer)
+	if err != nil {
 		return err
 	}
 
-	for _, m := range pc.CurrentRemoteDescription.parsed.MediaDescriptions {
+	pc.dtlsRole = dtlsRole
+	pc.remotePlanB = planB
+	pc.Lock()
+	pc.currentRemoteDescription = &desc
+	pc.Unlock()
+
+	// Every media section shares the ICE agent, the credentials of the first
+	// one are those of the bundle. Candidates of sections with credentials
+	// of their own are checked with those.
+	for _, m := range pc.currentRemoteDescription.parsed.MediaDescriptions {
+		ufrag, pwd := pc.currentRemoteDescription.parsed.GetICECredentials(m)
+		if remoteUfrag == "" && remotePwd == "" {
+			remoteUfrag, remotePwd = ufrag, pwd
+		}
+
 		for _, a := range m.Attributes {
-			if strings.HasPrefix(*a.String(), "candidate") {
-				if c := sdp.ICECandidateUnmarshal(*a.String()); c != nil {
+			if c, ok := a.Candidate(); ok {
+				if c != nil {
+					c.GetBase().Ufrag, c.GetBase().Pwd = ufrag, pwd
 					pc.networkManager.IceAgent.AddRemoteCandidate(c)
 				} else {
-					fmt.Printf("Tried to parse ICE candidate, but failed %s ", a)
+					pc.log.Warnf("Tried to parse ICE candidate, but failed %s", a)
 				}
-			} else if strings.HasPrefix(*a.String(), "ice-ufrag") {
-				remoteUfrag = (*a.String())[len("ice-ufrag:"):]
-			} else if strings.HasPrefix(*a.String(), "ice-pwd") {
-				remotePwd = (*a.String())[len("ice-pwd:"):]
+			} else if *a.String() == sdp.AttrKeyEndOfCandidates {
+				pc.networkManager.IceAgent.SetRemoteCandidatesComplete()
+			}
+		}
+	}
+
+	// Retransmissions are unwrapped into the stream they repair
+	rtxSSRCs := make(map[uint32]uint32)
+	for _, group := range pc.currentRemoteDescription.parsed.GetSSRCGroups(sdp.SemanticTokenFlowIdentification) {
+		if len(group) == 2 {
+			rtxSSRCs[group[1]] = group[0]
+		}
+	}
+	pc.networkManager.SetRTX(rtxSSRCs, pc.currentRemoteDescription.parsed.GetRTXPayloadTypes())
+
+	// Lost media is recovered from the FEC streams protecting it
+	fecSSRCs := make(map[uint32]uint32)
+	for _, group := range pc.currentRemoteDescription.parsed.GetSSRCGroups(sdp.SemanticTokenForwardErrorCorrection) {
+		if len(group) == 2 {
+			fecSSRCs[group[1]] = group[0]
+		}
+	}
+	pc.networkManager.SetFEC(fecSSRCs, pc.currentRemoteDescription.parsed.GetPayloadTypesForCodec(ULPFEC))
+
+	// Streams not announced with a=ssrc lines are bound by the mid they carry
+	if id, ok := pc.currentRemoteDescription.parsed.GetExtMapID(sdp.ExtMapURIMID); ok {
+		pc.networkManager.SetMIDExtension(id)
+	}
+
+	// The remote peer estimates the bandwidth from the send times of the packets
+	if id, ok := pc.currentRemoteDescription.parsed.GetExtMapID(sdp.ExtMapURIAbsSendTime); ok {
+		pc.networkManager.SetAbsSendTimeExtension(id)
+	}
+
+	// RTCP is sent to its own address for remote peers which don't multiplex
+	// it with RTP, if the policy allows it, on the RTCP component if ICE
+	// finds a pair for it. Otherwise the RTCP component isn't needed, and
+	// RTCP is multiplexed again if an earlier description didn't.
+	if addr, ok := pc.currentRemoteDescription.parsed.GetRTCPAddress(); ok && pc.configuration.RtcpMuxPolicy == RTCRtcpMuxPolicyNegotiate {
+		pc.networkManager.SetRTCPAddress(addr)
+	} else {
+		pc.networkManager.SetRTCPAddress(nil)
+		pc.networkManager.CloseRTCPComponent()
+	}
+
+	// Feedback is sent on its own once the remote peer accepts reduced-size
+	// RTCP
+	pc.networkManager.SetRTCPReducedSize(pc.currentRemoteDescription.parsed.HasRTCPReducedSize())
+
+	// The SCTP association only connects if a data channel section was
+	// negotiated
+	_, hasData := pc.currentRemoteDescription.parsed.GetDataMedia()
+	pc.networkManager.SetSCTPEnabled(hasData)
+
+	// Data channels are limited to the streams both peers offered
+	streams, _ := pc.currentRemoteDescription.parsed.GetSCTPStreams()
+	pc.networkManager.SetSCTPMaxStreams(pc.sctpTransport.negotiateMaxChannels(streams))
+	if port, ok := pc.currentRemoteDescription.parsed.GetSCTPPort(); ok && port != sctpPort {
+		pc.log.Warnf("Remote SCTP port %d is unsupported, the association uses %d", port, sctpPort)
+	}
+
+	// Messages are limited to the size the remote peer receives
+	remoteMaxMessageSize := uint64(defaultRemoteMaxMessageSize)
+	if size, ok := pc.currentRemoteDescription.parsed.GetMaxMessageSize(); ok {
+		remoteMaxMessageSize = size
+	}
+	pc.sctpTransport.updateMessageSize(float64(remoteMaxMessageSize))
+
+	// The certificate of the remote peer is verified against its fingerprints
+	// during the DTLS handshake
+	var fingerprints []dtls.Fingerprint
+	for _, fingerprint := range pc.currentRemoteDescription.parsed.GetFingerprints() {
+		fingerprints = append(fingerprints, dtls.Fingerprint{Algorithm: fingerprint.Algorithm, Value: fingerprint.Value})
+	}
+	pc.networkManager.SetRemoteFingerprints(fingerprints)
+
+	// A lite remote agent never checks connectivity, the local agent is
+	// controlling then whichever peer offered
+	remoteLite := pc.currentRemoteDescription.parsed.IsICELite()
+	if remoteLite {
+		pc.log.Debug("Remote ICE agent is lite, controlling it")
+	}
+	pc.networkManager.IceAgent.SetRemoteLite(remoteLite)
+
+	if err := pc.networkManager.Start(weOffer, pc.dtlsRole == RTCDtlsRoleClient, remoteUfrag, remotePwd); err != nil {
+		return err
+	}
+	pc.dtlsTransport.stateChange(RTCDtlsTransportStateConnecting)
+	return nil
+}
+
+// isPlanB reports whether a media section of the description carries more
+// than one track. SSRCs repairing or layering another SSRC are not counted as
+// tracks of their own.
+func isPlanB(d *sdp.SessionDescription) bool {
+	secondary := make(map[uint32]bool)
+	for _, semantics := range []string{
+		sdp.SemanticTokenFlowIdentification,
+		sdp.SemanticTokenForwardErrorCorrection,
+		sdp.SemanticTokenSimulcast,
+	} {
+		for _, group := range d.GetSSRCGroups(semantics) {
+			for _, ssrc := range group[1:] {
+				secondary[ssrc] = true
 			}
 		}
 	}
-	return pc.networkManager.Start(weOffer, remoteUfrag, remotePwd)
+
+	tracks := make(map[string]string)
+	for _, source := range d.GetMediaSources() {
+		if secondary[source.SSRC] || source.Label == "" {
+			continue
+		}
+		if label, ok := tracks[source.Mid]; ok && label != source.Label {
+			return true
+		}
+		tracks[source.Mid] = source.Label
+	}
+	return false
+}
+
+// usesPlanB reports whether media sections are negotiated Plan B style,
+// because it is configured or the remote description falls back to it
+func (pc *RTCPeerConnection) usesPlanB() bool {
+	return pc.configuration.SdpSemantics == RTCSdpSemanticsPlanB || pc.remotePlanB
 }
 
 // RemoteDescription returns PendingRemoteDescription if it is not null and
@@ -483,20 +1029,136 @@ func (pc *RTCPeerConnection) SetRemoteDescription(desc RTCSessionDescription) er
 // determine if setRemoteDescription has already been called.
 // https://www.w3.org/TR/webrtc/#dom-rtcpeerconnection-remotedescription
 func (pc *RTCPeerConnection) RemoteDescription() *RTCSessionDescription {
-	if pc.PendingRemoteDescription != nil {
-		return pc.PendingRemoteDescription
+	pc.RLock()
+	defer pc.RUnlock()
+
+	if pc.pendingRemoteDescription != nil {
+		return pc.pendingRemoteDescription.copy()
+	}
+	return pc.currentRemoteDescription.copy()
+}
+
+// CurrentRemoteDescription represents the last remote description that was
+// successfully negotiated the last time the RTCPeerConnection transitioned
+// into the stable state plus any remote candidates that have been supplied
+// via AddIceCandidate() since the offer or answer was created.
+func (pc *RTCPeerConnection) CurrentRemoteDescription() *RTCSessionDescription {
+	pc.RLock()
+	defer pc.RUnlock()
+	return pc.currentRemoteDescription.copy()
+}
+
+// PendingRemoteDescription represents a remote description that is in the
+// process of being negotiated, complete with any remote candidates that
+// have been supplied via AddIceCandidate() since the offer or answer was
+// created. If the RTCPeerConnection is in the stable state, the value is
+// null.
+func (pc *RTCPeerConnection) PendingRemoteDescription() *RTCSessionDescription {
+	pc.RLock()
+	defer pc.RUnlock()
+	return pc.pendingRemoteDescription.copy()
+}
+
+// SignalingState returns the signaling state of the RTCPeerConnection
+// instance.
+func (pc *RTCPeerConnection) SignalingState() RTCSignalingState {
+	pc.RLock()
+	defer pc.RUnlock()
+	return pc.signalingState
+}
+
+// ICEConnectionState returns the ICE connection state of the
+// RTCPeerConnection instance.
+func (pc *RTCPeerConnection) ICEConnectionState() ice.ConnectionState {
+	pc.RLock()
+	defer pc.RUnlock()
+	return pc.iceConnectionState
+}
+
+// ConnectionState returns the connection state of the RTCPeerConnection
+// instance.
+func (pc *RTCPeerConnection) ConnectionState() RTCPeerConnectionState {
+	pc.RLock()
+	defer pc.RUnlock()
+	return pc.connectionState
+}
+
+// GetRemoteStreams returns the streams the remote tracks received so far
+// belong to
+func (pc *RTCPeerConnection) GetRemoteStreams() []*RTCMediaStream {
+	pc.RLock()
+	defer pc.RUnlock()
+
+	streams := make([]*RTCMediaStream, 0, len(pc.remoteStreams))
+	for _, stream := range pc.remoteStreams {
+		streams = append(streams, stream)
 	}
-	return pc.CurrentRemoteDescription
+	return streams
+}
+
+// SCTP returns the SCTP transport data channels are sent over, the DTLS and
+// ICE transports are reached through it
+// https://www.w3.org/TR/webrtc/#dom-rtcpeerconnection-sctp
+func (pc *RTCPeerConnection) SCTP() *RTCSctpTransport {
+	return pc.sctpTransport
 }
 
-// AddIceCandidate accepts an ICE candidate string and adds it
-// to the existing set of candidates
-func (pc *RTCPeerConnection) AddIceCandidate(s string) error {
-	if c := sdp.ICECandidateUnmarshal(s); c != nil {
-		pc.networkManager.IceAgent.AddRemoteCandidate(c)
+// AddIceCandidate adds a candidate the remote peer trickled to the media
+// section of the remote description it belongs to. An empty candidate, or
+// an end-of-candidates attribute, signals that the remote peer gathered all
+// of its candidates.
+// https://www.w3.org/TR/webrtc/#dom-rtcpeerconnection-addicecandidate
+func (pc *RTCPeerConnection) AddIceCandidate(candidate RTCIceCandidateInit) error {
+	remoteDescription := pc.RemoteDescription()
+	if remoteDescription == nil || remoteDescription.parsed == nil {
+		return &rtcerr.InvalidStateError{Err: ErrNoRemoteDescription}
+	}
+
+	if isEndOfCandidates(candidate.Candidate) {
+		pc.networkManager.IceAgent.SetRemoteCandidatesComplete()
 		return nil
 	}
-	return fmt.Errorf("Unable to parse %q as remote candidate", s)
+
+	media, err := candidate.mediaDescription(remoteDescription.parsed)
+	if err != nil {
+		return err
+	}
+	ufrag, pwd := remoteDescription.parsed.GetICECredentials(media)
+	if candidate.UsernameFragment != "" && candidate.UsernameFragment != ufrag {
+		return &rtcerr.OperationError{Err: ErrIceCandidateUfrag}
+	}
+
+	parsed, err := NewRTCIceCandidate(candidate)
+	if err != nil {
+		return err
+	}
+	c, err := parsed.toICE()
+	if err != nil {
+		return err
+	}
+	c.GetBase().Ufrag, c.GetBase().Pwd = ufrag, pwd
+	pc.networkManager.IceAgent.AddRemoteCandidate(c)
+	return nil
+}
+
+// RemoveIceCandidate removes a remote ICE candidate which was added before,
+// the candidate pairs using it are pruned
+func (pc *RTCPeerConnection) RemoveIceCandidate(candidate RTCIceCandidateInit) error {
+	parsed, err := NewRTCIceCandidate(candidate)
+	if err != nil {
+		return err
+	}
+	c, err := parsed.toICE()
+	if err != nil {
+		return err
+	}
+	pc.networkManager.IceAgent.RemoveRemoteCandidate(c)
+	return nil
+}
+
+func isEndOfCandidates(s string) bool {
+	s = strings.TrimPrefix(strings.TrimSpace(s), "a=")
+	return s == "" || s == sdp.AttrKeyEndOfCandidates
 }
 
 // ------------------------------------------------------------------------
@@ -504,34 +1166,35 @@ func (pc *RTCPeerConnection) AddIceCandidate(s string) error {
 // ------------------------------------------------------------------------
 
 // GetSenders returns the RTCRtpSender that are currently attached to this RTCPeerConnection
-func (pc *RTCPeerConnection) GetSenders() []RTCRtpSender {
-	result := make([]RTCRtpSender, len(pc.rtpTransceivers))
+func (pc *RTCPeerConnection) GetSenders() []*RTCRtpSender {
+	result := make([]*RTCRtpSender, len(pc.rtpTransceivers))
 	for i, tranceiver := range pc.rtpTransceivers {
-		result[i] = *tranceiver.Sender
+		result[i] = tranceiver.Sender
 	}
 	return result
 }
 
 // GetReceivers returns the RTCRtpReceivers that are currently attached to this RTCPeerConnection
-func (pc *RTCPeerConnection) GetReceivers() []RTCRtpReceiver {
-	result := make([]RTCRtpReceiver, len(pc.rtpTransceivers))
+func (pc *RTCPeerConnection) GetReceivers() []*RTCRtpReceiver {
+	result := make([]*RTCRtpReceiver, len(pc.rtpTransceivers))
 	for i, tranceiver := range pc.rtpTransceivers {
-		result[i] = *tranceiver.Receiver
+		result[i] = tranceiver.Receiver
 	}
 	return result
 }
 
 // GetTransceivers returns the RTCRtpTransceiver that are currently attached to this RTCPeerConnection
-func (pc *RTCPeerConnection) GetTransceivers() []RTCRtpTransceiver {
-	result := make([]RTCRtpTransceiver, len(pc.rtpTransceivers))
-	for i, tranceiver := range pc.rtpTransceivers {
-		result[i] = *tranceiver
-	}
+func (pc *RTCPeerConnection) GetTransceivers() []*RTCRtpTransceiver {
+	result := make([]*RTCRtpTransceiver, len(pc.rtpTransceivers))
+	copy(result, pc.rtpTransceivers)
 	return result
 }
 
 // AddTrack adds a RTCTrack to the RTCPeerConnection
 func (pc *RTCPeerConnection) AddTrack(track *RTCTrack) (*RTCRtpSender, error) {
+	pc.Lock()
+	defer pc.Unlock()
+
 	if pc.isClosed {
 		return nil, &rtcerr.InvalidStateError{Err: ErrConnectionClosed}
 	}
@@ -559,7 +1222,7 @@ func (pc *RTCPeerConnection) AddTrack(track *RTCTrack) (*RTCRtpSender, error) {
 			return nil, err
 		}
 	} else {
-		var receiver *RTCRtpReceiver
+		receiver := newRTCRtpReceiver(nil)
 		sender := newRTCRtpSender(track)
 		transceiver = pc.newRTCRtpTransceiver(
 			receiver,
@@ -567,13 +1230,27 @@ func (pc *RTCPeerConnection) AddTrack(track *RTCTrack) (*RTCRtpSender, error) {
 			RTCRtpTransceiverDirectionSendonly,
 		)
 	}
+	transceiver.Sender.rtcPeerConnection = pc
+	track.sender = transceiver.Sender
 
-	transceiver.Mid = track.Kind.String() // TODO: Mid generation
-
-	return transceiver.Sender, nil
-}
+	if track.Kind == RTCRtpCodecTypeAudio {
+		transceiver.Sender.DTMF = newRTCDTMFSender(transceiver.Sender)
+	}
 
-// func (pc *RTCPeerConnection) RemoveTrack() {
+	if codec := pc.mediaEngine.getRTXCodec(track.PayloadType); codec != nil {
+		if err := transceiver.Sender.enableRTX(codec); err != nil {
+			return nil, err
+		}
+	}
+
+	if transceiver.Mid == "" {
+		transceiver.Mid = pc.generateMid(track.Kind)
+	}
+
+	return transceiver.Sender, nil
+}
+
+// func (pc *RTCPeerConnection) RemoveTrack() {
 // 	panic("not implemented yet") // FIXME NOT-IMPLEMENTED nolint
 // }
 
@@ -695,8 +1372,7 @@ func (pc *RTCPeerConnection) CreateDataChannel(label string, options *RTCDataCha
 		return nil, &rtcerr.TypeError{Err: ErrMaxDataChannelID}
 	}
 
-	if pc.sctpTransport.State == RTCSctpTransportStateConnected &&
-		*channel.ID >= *pc.sctpTransport.MaxChannels {
+	if maxChannels, ok := pc.sctpTransport.negotiatedMaxChannels(); ok && *channel.ID >= maxChannels {
 		return nil, &rtcerr.OperationError{Err: ErrMaxDataChannelID}
 	}
 
@@ -710,15 +1386,15 @@ func (pc *RTCPeerConnection) CreateDataChannel(label string, options *RTCDataCha
 }
 
 func (pc *RTCPeerConnection) generateDataChannelID(client bool) (*uint16, error) {
-	var id uint16
+	var id uint32
 	if !client {
 		id++
 	}
 
-	for ; id < *pc.sctpTransport.MaxChannels-1; id += 2 {
-		_, ok := pc.dataChannels[id]
-		if !ok {
-			return &id, nil
+	for ; id < uint32(pc.sctpTransport.maxChannels()); id += 2 {
+		if _, ok := pc.dataChannels[uint16(id)]; !ok {
+			streamIdentifier := uint16(id)
+			return &streamIdentifier, nil
 		}
 	}
 	return nil, &rtcerr.OperationError{Err: ErrMaxDataChannelID}
@@ -726,17 +1402,51 @@ func (pc *RTCPeerConnection) generateDataChannelID(client bool) (*uint16, error)
 
 // SetMediaEngine allows overwriting the default media engine used by the RTCPeerConnection
 // This enables RTCPeerConnection with support for different codecs
+//
+// Deprecated: Use NewAPI with WithMediaEngine instead.
 func (pc *RTCPeerConnection) SetMediaEngine(m *MediaEngine) {
 	pc.mediaEngine = m
 }
 
+// SetICECandidateTimeout sets how long the ICE agent waits for the first
+// remote candidate after the remote description is set. Peers which don't
+// trickle candidates and don't include any in their description cause ICE
+// to fail once the timeout expires, instead of checking forever. A timeout
+// of zero waits indefinitely. The default is 30 seconds.
+//
+// Deprecated: Use SettingEngine.SetICECandidateTimeout instead.
+func (pc *RTCPeerConnection) SetICECandidateTimeout(timeout time.Duration) {
+	pc.networkManager.IceAgent.SetCandidateTimeout(timeout)
+}
+
+// SetICEMulticastDNSResolver sets the resolver of the .local hostnames remote
+// peers use to hide their host addresses, a nil resolver disables mDNS and
+// candidates with such hostnames are discarded. Use ice.NewMulticastDNSResolver
+// to limit the interfaces the queries are sent on.
+//
+// Deprecated: Use SettingEngine.SetICEMulticastDNSResolver instead.
+func (pc *RTCPeerConnection) SetICEMulticastDNSResolver(resolver ice.MulticastDNSResolver) {
+	pc.networkManager.IceAgent.SetMulticastDNSResolver(resolver)
+}
+
+// SetICEMulticastDNSTimeout sets how long the .local hostname of a remote
+// candidate is resolved before the candidate is discarded. The default is
+// 5 seconds.
+//
+// Deprecated: Use SettingEngine.SetICEMulticastDNSTimeout instead.
+func (pc *RTCPeerConnection) SetICEMulticastDNSTimeout(timeout time.Duration) {
+	pc.networkManager.IceAgent.SetMulticastDNSTimeout(timeout)
+}
+
 // SetIdentityProvider is used to configure an identity provider to generate identity assertions
 func (pc *RTCPeerConnection) SetIdentityProvider(provider string) error {
 	return errors.Errorf("TODO SetIdentityProvider")
 }
 
 // SendRTCP sends a user provided RTCP packet to the connected peer
-// If no peer is connected the packet is discarded
+// If no peer is connected the packet is discarded. Unless the remote peer
+// accepts reduced-size RTCP, packets other than reports are sent in a
+// compound packet led by a receiver report.
 func (pc *RTCPeerConnection) SendRTCP(pkt rtcp.Packet) error {
 	raw, err := pkt.Marshal()
 	if err != nil {
@@ -746,80 +1456,689 @@ func (pc *RTCPeerConnection) SendRTCP(pkt rtcp.Packet) error {
 	return nil
 }
 
-// Close ends the RTCPeerConnection
-func (pc *RTCPeerConnection) Close() error {
-	// https://www.w3.org/TR/webrtc/#dom-rtcpeerconnection-close (step #2)
+// GetStats returns the statistics of the RTCPeerConnection: those of its
+// transport, of every candidate pair ICE checked, of every stream sent as the
+// remote peer reported its reception, of every stream received as the remote
+// peer reported sending it and of every data channel
+// https://www.w3.org/TR/webrtc/#dom-rtcpeerconnection-getstats
+func (pc *RTCPeerConnection) GetStats() RTCStatsReport {
+	report := make(RTCStatsReport)
+	now := time.Now()
+
+	pc.dtlsTransport.RLock()
+	transport := &RTCTransportStats{
+		RTCStatsBase:     RTCStatsBase{ID: rtcTransportStatsID, Type: RTCStatsTypeTransport, Timestamp: now},
+		DTLSState:        pc.dtlsTransport.State,
+		SRTPAuthFailures: pc.networkManager.SRTPAuthFailures(),
+	}
+	pc.dtlsTransport.RUnlock()
+	if local, remote, _ := pc.networkManager.IceAgent.SelectedCandidatePair(); local != nil && remote != nil {
+		transport.SelectedCandidatePairID = candidatePairStatsID(local, remote)
+	}
+	report[transport.ID] = transport
+
+	for _, s := range pc.networkManager.CandidatePairStats() {
+		stats := newRTCIceCandidatePairStats(s, now)
+		transport.BytesSent += stats.BytesSent
+		transport.BytesReceived += stats.BytesReceived
+		report[stats.ID] = stats
+	}
+	for _, sender := range pc.GetSenders() {
+		for _, stats := range sender.remoteInboundStats() {
+			report[stats.ID] = stats
+		}
+	}
+
+	pc.RLock()
+	defer pc.RUnlock()
+	for _, t := range pc.rtpTransceivers {
+		if stats := t.Receiver.remoteOutboundStats(); stats != nil {
+			report[stats.ID] = stats
+		}
+	}
+	for _, d := range pc.dataChannels {
+		stats := newRTCDataChannelStats(d, now)
+		report[stats.ID] = stats
+	}
+	return report
+}
+
+// Events returns a channel delivering the events of the RTCPeerConnection
+// in the order they happen, as an alternative to the OnICEConnectionStateChange,
+// OnTrack and OnDataChannel handlers which keep being invoked. Events are only
+// recorded from the first call to Events, remote tracks are accepted even if
+// OnTrack is unset. The channel is closed after Close, it must be drained
+// until then.
+func (pc *RTCPeerConnection) Events() <-chan RTCPeerConnectionEvent {
+	return pc.events.start()
+}
+
+// OnICEConnectionStateChange sets an event handler which is called when an
+// ICE connection state is changed.
+func (pc *RTCPeerConnection) OnICEConnectionStateChange(f func(ice.ConnectionState)) {
+	pc.Lock()
+	defer pc.Unlock()
+	pc.onICEConnectionStateChangeHandler = f
+}
+
+// OnTrack sets an event handler which is called when a remote track arrives
+// from a remote peer. Every track is handled on a goroutine of its own.
+func (pc *RTCPeerConnection) OnTrack(f func(*RTCTrack)) {
+	pc.Lock()
+	defer pc.Unlock()
+	pc.onTrackHandler = f
+}
+
+// OnDataChannel sets an event handler which is invoked when a data channel
+// arrives from a remote peer, before the first message of the channel.
+func (pc *RTCPeerConnection) OnDataChannel(f func(*RTCDataChannel)) {
+	pc.Lock()
+	defer pc.Unlock()
+	pc.onDataChannelHandler = f
+}
+
+// OnStats sets an event handler which is called with a report of GetStats
+// every interval until the RTCPeerConnection is closed, replacing the
+// previous one. Reports are collected and delivered in the background, a
+// handler taking longer than the interval delays the next report. A nil
+// handler or an interval which isn't positive stops the reports.
+func (pc *RTCPeerConnection) OnStats(interval time.Duration, f func(RTCStatsReport)) {
+	pc.Lock()
+	defer pc.Unlock()
+
+	if pc.stopStats != nil {
+		close(pc.stopStats)
+		pc.stopStats = nil
+	}
+	if f == nil || interval <= 0 {
+		return
+	}
+
+	stop := make(chan struct{})
+	pc.stopStats = stop
+	workers.every(interval, func() bool { return pc.deliverStats(f, stop) })
+}
+
+// deliverStats calls the handler of OnStats with a report, it returns false
+// once the reports are stopped or the RTCPeerConnection is done
+func (pc *RTCPeerConnection) deliverStats(f func(RTCStatsReport), stop <-chan struct{}) bool {
+	select {
+	case <-pc.Done():
+		return false
+	case <-stop:
+		return false
+	default:
+	}
+	f(pc.GetStats())
+	return true
+}
+
+// Done returns a channel which is closed once the RTCPeerConnection is
+// closed or its connection has failed. Goroutines writing to tracks can
+// select on it to know when to stop.
+func (pc *RTCPeerConnection) Done() <-chan struct{} {
+	return pc.done
+}
+
+func (pc *RTCPeerConnection) closeDone() {
+	pc.doneOnce.Do(func() {
+		close(pc.done)
+	})
+}
+
+// GracefulClose ends the RTCPeerConnection like Close, after signaling the
+// end of the session to the remote peer. An RTCP BYE is sent for every
+// stream of the senders, and the SCTP association is shut down once the data
+// channel messages sent so far are delivered. The connection is closed even
+// if the remote peer doesn't acknowledge the shutdown within a few seconds.
+func (pc *RTCPeerConnection) GracefulClose() error {
+	pc.RLock()
 	if pc.isClosed {
+		pc.RUnlock()
 		return nil
 	}
+	var ssrcs []uint32
+	for _, t := range pc.rtpTransceivers {
+		ssrcs = append(ssrcs, t.Sender.ssrcs()...)
+	}
+	pc.RUnlock()
+
+	var err error
+	for len(ssrcs) != 0 {
+		n := len(ssrcs)
+		if n > rtcpGoodbyeMaxSources {
+			n = rtcpGoodbyeMaxSources
+		}
+		if sendErr := pc.SendRTCP(&rtcp.Goodbye{Sources: ssrcs[:n]}); sendErr != nil && err == nil {
+			err = sendErr
+		}
+		ssrcs = ssrcs[n:]
+	}
 
-	close(pc.backgroundActions)
+	pc.sctpTransport.RLock()
+	sctpConnected := pc.sctpTransport.State == RTCSctpTransportStateConnected
+	pc.sctpTransport.RUnlock()
+	if sctpConnected {
+		if shutdownErr := pc.networkManager.ShutdownSCTP(sctpShutdownTimeout); shutdownErr != nil && err == nil {
+			err = shutdownErr
+		}
+	}
 
-	pc.networkManager.Close()
+	if closeErr := pc.Close(); closeErr != nil && err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// Close ends the RTCPeerConnection. The goroutines of the connection and of
+// its local tracks exit, samples and packets written to local tracks are no
+// longer sent, and the Packets channels of the remote tracks are closed. It
+// is safe to call from any handler and from many goroutines.
+func (pc *RTCPeerConnection) Close() error {
+	// https://www.w3.org/TR/webrtc/#dom-rtcpeerconnection-close (step #2)
+	pc.Lock()
+	if pc.isClosed {
+		pc.Unlock()
+		return nil
+	}
 
 	// https://www.w3.org/TR/webrtc/#dom-rtcpeerconnection-close (step #3)
 	pc.isClosed = true
 
 	// https://www.w3.org/TR/webrtc/#dom-rtcpeerconnection-close (step #4)
-	pc.SignalingState = RTCSignalingStateClosed
+	pc.signalingState = RTCSignalingStateClosed
 
+	// https://www.w3.org/TR/webrtc/#dom-rtcpeerconnection-close (step #7)
+	for _, d := range pc.dataChannels {
+		d.Lock()
+		d.ReadyState = RTCDataChannelStateClosed
+		d.Unlock()
+	}
+	pc.Unlock()
+
+	// SCTP, DTLS and ICE are closed in order without the lock, the network
+	// takes it to deliver events
+	pc.networkManager.Close()
+
+	// https://www.w3.org/TR/webrtc/#dom-rtcpeerconnection-close (step #8)
+	pc.sctpTransport.stateChange(RTCSctpTransportStateClosed)
+	pc.dtlsTransport.stateChange(RTCDtlsTransportStateClosed)
+	pc.dtlsTransport.Transport.stateChange(RTCIceTransportStateClosed)
+
+	pc.Lock()
 	// https://www.w3.org/TR/webrtc/#dom-rtcpeerconnection-close (step #11)
-	// pc.IceConnectionState = RTCIceConnectionStateClosed
-	pc.IceConnectionState = ice.ConnectionStateClosed // FIXME REMOVE
+	// pc.iceConnectionState = RTCIceConnectionStateClosed
+	pc.iceConnectionState = ice.ConnectionStateClosed // FIXME REMOVE
 
 	// https://www.w3.org/TR/webrtc/#dom-rtcpeerconnection-close (step #12)
-	pc.ConnectionState = RTCPeerConnectionStateClosed
+	pc.connectionState = RTCPeerConnectionStateClosed
+	pc.Unlock()
+
+	pc.events.push(RTCIceConnectionStateChangeEvent{State: ice.ConnectionStateClosed})
+	pc.events.close()
+	pc.closeDone()
 
 	return nil
 }
 
 /* Everything below is private */
-func (pc *RTCPeerConnection) generateChannel(ssrc uint32, payloadType uint8) (buffers chan<- *rtp.Packet) {
-	if pc.OnTrack == nil {
-		return nil
+func (pc *RTCPeerConnection) generateChannel(ssrc uint32, payloadType uint8, mid string) (buffers chan<- *rtp.Packet, hold bool) {
+	pc.RLock()
+	onTrack := pc.onTrackHandler
+	localDescription, remoteDescription := pc.currentLocalDescription, pc.currentRemoteDescription
+	pc.RUnlock()
+
+	// Media can arrive before the local answer has been applied, holding the
+	// packets has them delivered once we are able to bind
+	if localDescription == nil {
+		return nil, true
 	}
 
-	sdpCodec, err := pc.CurrentLocalDescription.parsed.GetCodecForPayloadType(payloadType)
+	if onTrack == nil && !pc.events.isStarted() && !pc.unhandledEvents.enabled() {
+		return nil, false
+	}
+
+	sdpCodec, err := localDescription.parsed.GetCodecForPayloadType(payloadType)
 	if err != nil {
-		fmt.Printf("No codec could be found in RemoteDescription for payloadType %d \n", payloadType)
-		return nil
+		pc.log.Warnf("No codec could be found in RemoteDescription for payloadType %d", payloadType)
+		return nil, false
 	}
 
 	codec, err := pc.mediaEngine.getCodecSDP(sdpCodec)
 	if err != nil {
-		fmt.Printf("Codec %s in not registered\n", sdpCodec)
-		return nil
+		pc.log.Warnf("Codec %s in not registered", sdpCodec)
+		return nil, false
+	}
+
+	// The track is identified by the remote description, with Plan B every
+	// SSRC of a media section may belong to a different track
+	id, label := "0", ""
+	if remoteDescription != nil {
+		var source sdp.MediaSource
+		source, mid = remoteMediaSource(remoteDescription.parsed, ssrc, payloadType, mid)
+		if source.Label != "" {
+			id, label = source.Label, source.StreamLabel
+		}
 	}
 
-	bufferTransport := make(chan *rtp.Packet, 15)
+	bufferTransport := make(chan *rtp.Packet, 15+network.EarlyMediaBufferSize)
 
 	track := &RTCTrack{
 		PayloadType: payloadType,
 		Kind:        codec.Type,
-		ID:          "0", // TODO extract from remoteDescription
-		Label:       "",  // TODO extract from remoteDescription
+		ID:          id,
+		Label:       label,
 		Ssrc:        ssrc,
 		Codec:       codec,
 		Packets:     bufferTransport,
 	}
 
-	// TODO: Register the receiving Track
+	receiver := newRTCRtpReceiver(track)
+	pc.Lock()
+	pc.addRTCRtpReceiver(receiver, mid)
+	var streams []*RTCMediaStream
+	if label != "" {
+		streams = append(streams, pc.addRemoteStreamTrack(label, track))
+	}
+	pc.Unlock()
+
+	pc.events.push(RTCTrackEvent{Track: track, Receiver: receiver, Streams: streams})
+	deliver, _ := pc.unhandledEvents.dispatch(fmt.Sprintf("track %d", ssrc), func() func() {
+		pc.RLock()
+		onTrack := pc.onTrackHandler
+		pc.RUnlock()
+		if onTrack == nil {
+			return nil
+		}
+		// The handler commonly reads the Packets of the track until it
+		// ends, so it gets a worker of its own once it's its turn
+		return func() { pc.operations.push(func() { workers.schedule(func() { onTrack(track) }) }) }
+	})
+	if deliver != nil {
+		deliver()
+	}
+	return bufferTransport, false
+}
+
+// addRemoteStreamTrack adds the remote track to the stream with the id,
+// which is created for its first track
+// Note: the caller should hold the RTCPeerConnection lock.
+func (pc *RTCPeerConnection) addRemoteStreamTrack(id string, track *RTCTrack) *RTCMediaStream {
+	stream, ok := pc.remoteStreams[id]
+	if !ok {
+		stream = NewRTCMediaStream(id)
+		pc.remoteStreams[id] = stream
+	}
+
+	stream.lock.Lock()
+	stream.addTrack(track)
+	stream.lock.Unlock()
+	return stream
+}
+
+// remoteMediaSource looks up the track of an inbound stream in the remote
+// description. Streams announced with a=ssrc lines are found by their SSRC,
+// others by the mid they carry in the MID header extension and lastly by
+// the first media section offering their payload type. The mid of the
+// media section is returned with the source, if it is known.
+func remoteMediaSource(remote *sdp.SessionDescription, ssrc uint32, payloadType uint8, mid string) (sdp.MediaSource, string) {
+	for _, source := range remote.GetMediaSources() {
+		if source.SSRC == ssrc && source.Label != "" {
+			return source, source.Mid
+		}
+	}
+
+	if mid == "" {
+		var ok bool
+		if mid, ok = remote.GetMidForPayloadType(payloadType); !ok {
+			return sdp.MediaSource{}, ""
+		}
+	}
+
+	source, _ := remote.GetMediaSourceForMid(mid)
+	return source, mid
+}
+
+// addRTCRtpReceiver attaches the receiver to the transceiver of the mid, or
+// the transceiver sending the same kind of media if the mid is not known.
+// A new recvonly transceiver is created if there is none.
+// Note: the caller should hold the RTCPeerConnection lock.
+func (pc *RTCPeerConnection) addRTCRtpReceiver(receiver *RTCRtpReceiver, mid string) {
+	receiver.rtcPeerConnection = pc
+
+	for _, t := range pc.rtpTransceivers {
+		if !t.stopped &&
+			t.Receiver.Track == nil &&
+			t.Sender.Track != nil &&
+			t.Sender.Track.Kind == receiver.Track.Kind &&
+			(mid == "" || t.Mid == "" || t.Mid == mid) {
+			receiver.Transport = pc.dtlsTransport
+			t.Receiver = receiver
+			if t.Direction == RTCRtpTransceiverDirectionSendonly {
+				t.Direction = RTCRtpTransceiverDirectionSendrecv
+			}
+			return
+		}
+	}
+
+	t := pc.newRTCRtpTransceiver(
+		receiver,
+		newRTCRtpSender(nil),
+		RTCRtpTransceiverDirectionRecvonly,
+	)
+	t.Mid = mid
+}
+
+func (pc *RTCPeerConnection) observeInboundRTP(p *rtp.Packet) {
+	if receiver := pc.receiverForSSRC(p.SSRC); receiver != nil {
+		receiver.doOnRTPPacket(p)
+	}
+}
+
+// receiverForSSRC returns the RTCRtpReceiver of the remote track of the SSRC
+func (pc *RTCPeerConnection) receiverForSSRC(ssrc uint32) *RTCRtpReceiver {
+	pc.RLock()
+	defer pc.RUnlock()
+
+	for _, t := range pc.rtpTransceivers {
+		if t.Receiver.Track != nil && t.Receiver.Track.Ssrc == ssrc {
+			return t.Receiver
+		}
+	}
+	return nil
+}
+
+// sendRTP sends a packet of a local track, notifying the sender of the track
+func (pc *RTCPeerConnection) sendRTP(track *RTCTrack, p *rtp.Packet) {
+	pc.RLock()
+	sender := track.sender
+	pc.RUnlock()
+
+	if sender == nil {
+		pc.networkManager.SendRTP(p)
+		return
+	}
+
+	if err := sender.sendRTP(p); err != nil {
+		err = errors.Wrap(err, "Failed to send RTP packet")
+		pc.log.Warn(err.Error())
+		pc.events.push(RTCErrorEvent{Err: err})
+	}
+}
+
+// keepaliveSenders keeps the silent streams of sending transceivers alive,
+// it returns false once the RTCPeerConnection is done
+func (pc *RTCPeerConnection) keepaliveSenders(interval time.Duration) bool {
+	select {
+	case <-pc.Done():
+		return false
+	default:
+	}
+
+	var senders []*RTCRtpSender
+	pc.RLock()
+	for _, t := range pc.rtpTransceivers {
+		if !t.stopped &&
+			(t.Direction == RTCRtpTransceiverDirectionSendonly || t.Direction == RTCRtpTransceiverDirectionSendrecv) {
+			senders = append(senders, t.Sender)
+		}
+	}
+	pc.RUnlock()
+
+	for _, sender := range senders {
+		sender.keepalive(interval)
+	}
+	return true
+}
+
+// sendSenderReports sends a sender report for every stream sent, the
+// remote peer refers to it in its reception reports so the round trip is
+// measured
+func (pc *RTCPeerConnection) sendSenderReports() bool {
+	select {
+	case <-pc.Done():
+		return false
+	default:
+	}
+
+	var senders []*RTCRtpSender
+	pc.RLock()
+	for _, t := range pc.rtpTransceivers {
+		if !t.stopped && t.Sender != nil {
+			senders = append(senders, t.Sender)
+		}
+	}
+	pc.RUnlock()
+
+	now := time.Now()
+	for _, sender := range senders {
+		for _, report := range sender.senderReports(now) {
+			if err := pc.SendRTCP(report); err != nil {
+				pc.log.Warnf("Failed to send sender report: %v", err)
+			}
+		}
+	}
+	return true
+}
+
+// sendReceiverReferenceTime sends an extended report with the receiver
+// reference time while there are remote tracks, the remote peer answers it
+// to measure the round trip without sending RTP
+func (pc *RTCPeerConnection) sendReceiverReferenceTime() bool {
+	select {
+	case <-pc.Done():
+		return false
+	default:
+	}
+
+	receiving := false
+	pc.RLock()
+	for _, t := range pc.rtpTransceivers {
+		if t.Receiver.Track != nil {
+			receiving = true
+			break
+		}
+	}
+	pc.RUnlock()
+
+	if receiving {
+		if err := pc.SendRTCP(&rtcp.ExtendedReport{
+			SenderSSRC:            pc.networkManager.RTCPSSRC(),
+			ReceiverReferenceTime: ntpTime(time.Now()),
+		}); err != nil {
+			pc.log.Warnf("Failed to send receiver reference time: %v", err)
+		}
+	}
+	return true
+}
+
+// handleRTCP dispatches the feedback of the remote peer to our senders
+func (pc *RTCPeerConnection) handleRTCP(raw []byte) {
+	receivedAt := time.Now()
+	r := rtcp.NewReader(bytes.NewReader(raw))
+	for {
+		header, data, err := r.ReadPacket()
+		if err != nil {
+			// The end of the compound packet, or trailing garbage we can't parse
+			return
+		}
+
+		switch {
+		case header.Type == rtcp.TypeTransportSpecificFeedback && header.Count == rtcp.FormatTLN:
+			nack := &rtcp.TransportLayerNack{}
+			if err := nack.Unmarshal(data); err != nil {
+				pc.log.Warn(errors.Wrap(err, "Failed to unmarshal NACK").Error())
+				continue
+			}
+
+			for _, sender := range pc.GetSenders() {
+				sender.retransmit(nack)
+			}
+
+		case header.Type == rtcp.TypePayloadSpecificFeedback && header.Count == rtcp.FormatREMB:
+			remb := &rtcp.ReceiverEstimatedMaximumBitrate{}
+			if err := remb.Unmarshal(data); err != nil {
+				// Other application layer feedback shares the format
+				continue
+			}
+			pc.handleREMB(remb)
+
+		case header.Type == rtcp.TypeSenderReport:
+			sr := &rtcp.SenderReport{}
+			if err := sr.Unmarshal(data); err != nil {
+				pc.log.Warn(errors.Wrap(err, "Failed to unmarshal sender report").Error())
+				continue
+			}
+
+			if receiver := pc.receiverForSSRC(sr.SSRC); receiver != nil {
+				receiver.handleSenderReport(sr, receivedAt)
+				receiver.doOnSenderReport(sr)
+			}
+			pc.handleReceptionReports(sr.Reports)
+
+		case header.Type == rtcp.TypeReceiverReport:
+			rr := &rtcp.ReceiverReport{}
+			if err := rr.Unmarshal(data); err != nil {
+				pc.log.Warn(errors.Wrap(err, "Failed to unmarshal receiver report").Error())
+				continue
+			}
+			pc.handleReceptionReports(rr.Reports)
+
+		case header.Type == rtcp.TypeExtendedReport:
+			xr := &rtcp.ExtendedReport{}
+			if err := xr.Unmarshal(data); err != nil {
+				pc.log.Warn(errors.Wrap(err, "Failed to unmarshal extended report").Error())
+				continue
+			}
+			pc.handleExtendedReport(xr, receivedAt)
+		}
+	}
+}
+
+// handleExtendedReport answers the receiver reference time of the remote
+// peer with a DLRR report, and measures the round trip from the DLRR reports
+// answering ours, RFC 3611 Section 4.5. Every stream is bundled on the same
+// transport, so the round trip measured applies to all the remote tracks.
+func (pc *RTCPeerConnection) handleExtendedReport(xr *rtcp.ExtendedReport, receivedAt time.Time) {
+	if xr.ReceiverReferenceTime != 0 {
+		// In units of 1/65536 seconds
+		delay := uint32(time.Since(receivedAt) * 65536 / time.Second)
+		if err := pc.SendRTCP(&rtcp.ExtendedReport{
+			SenderSSRC: pc.networkManager.RTCPSSRC(),
+			DLRR: []rtcp.DLRRReport{{
+				SSRC:   xr.SenderSSRC,
+				LastRR: uint32(xr.ReceiverReferenceTime >> 16),
+				DLRR:   delay,
+			}},
+		}); err != nil {
+			pc.log.Warnf("Failed to answer receiver reference time: %v", err)
+		}
+	}
+
+	for _, report := range xr.DLRR {
+		if report.SSRC != pc.networkManager.RTCPSSRC() {
+			continue
+		}
+		pc.RLock()
+		for _, t := range pc.rtpTransceivers {
+			if t.Receiver.Track != nil {
+				t.Receiver.handleDLRR(report, receivedAt)
+			}
+		}
+		pc.RUnlock()
+	}
+}
+
+// handleReceptionReports passes the reports about the streams sent to their
+// senders
+func (pc *RTCPeerConnection) handleReceptionReports(reports []rtcp.ReceptionReport) {
+	now := time.Now()
+	for _, report := range reports {
+		for _, sender := range pc.GetSenders() {
+			if sender.hasSSRC(report.SSRC) {
+				sender.handleReceptionReport(report, now)
+				break
+			}
+		}
+	}
+}
 
-	go pc.OnTrack(track)
-	return bufferTransport
+// handleREMB shares the estimate between the senders of the listed SSRCs
+func (pc *RTCPeerConnection) handleREMB(remb *rtcp.ReceiverEstimatedMaximumBitrate) {
+	var senders []*RTCRtpSender
+	for _, sender := range pc.GetSenders() {
+		for _, ssrc := range remb.SSRCs {
+			if sender.hasSSRC(ssrc) {
+				senders = append(senders, sender)
+				break
+			}
+		}
+	}
+
+	for _, sender := range senders {
+		sender.doOnTargetBitrate(remb.Bitrate / uint64(len(senders)))
+	}
 }
 
 func (pc *RTCPeerConnection) iceStateChange(newState ice.ConnectionState) {
+	pc.dtlsTransport.Transport.stateChange(newRTCIceTransportStateFromICE(newState))
+
+	// The agent notifies in the background, it may report a state after the
+	// connection has been closed
 	pc.Lock()
-	defer pc.Unlock()
+	if pc.isClosed {
+		pc.Unlock()
+		return
+	}
+	onICEConnectionStateChange := pc.onICEConnectionStateChangeHandler
+	pc.events.push(RTCIceConnectionStateChangeEvent{State: newState})
+	pc.iceConnectionState = newState
+
+	if newState == ice.ConnectionStateFailed {
+		pc.connectionState = RTCPeerConnectionStateFailed
+		pc.closeDone()
+	}
+	pc.Unlock()
+
+	// The handler runs without the lock, so it may use the connection
+	if onICEConnectionStateChange != nil {
+		pc.operations.push(func() { onICEConnectionStateChange(newState) })
+	}
+}
 
-	if pc.OnICEConnectionStateChange != nil {
-		pc.OnICEConnectionStateChange(newState)
+func (pc *RTCPeerConnection) dtlsStateChange(state dtls.ConnectionState) {
+	switch state {
+	case dtls.Established:
+		pc.dtlsTransport.stateChange(RTCDtlsTransportStateConnected)
+	case dtls.Failed:
+		pc.dtlsTransport.stateChange(RTCDtlsTransportStateFailed)
+		pc.dtlsFailure(pc.networkManager.DTLSErr())
 	}
-	pc.IceConnectionState = newState
+}
+
+// dtlsFailure fails the connection if the DTLS handshake failed, like when
+// the certificate of the remote peer doesn't match its fingerprints
+func (pc *RTCPeerConnection) dtlsFailure(err error) {
+	pc.log.Errorf("DTLS handshake failed: %v", err)
+
+	pc.Lock()
+	defer pc.Unlock()
+
+	pc.events.push(RTCErrorEvent{Err: err})
+	pc.connectionState = RTCPeerConnectionStateFailed
+	pc.closeDone()
 }
 
 func (pc *RTCPeerConnection) dataChannelEventHandler(e network.DataChannelEvent) {
+	// The state of the SCTP transport changes without holding the lock of
+	// the connection, its handler may use the connection
+	switch event := e.(type) {
+	case *network.DataChannelOpen:
+		pc.sctpTransport.connected(event.Streams)
+	case *network.DataChannelClose:
+		pc.sctpTransport.stateChange(RTCSctpTransportStateClosed)
+	}
+
 	pc.Lock()
 	defer pc.Unlock()
 
@@ -828,28 +2147,14 @@ func (pc *RTCPeerConnection) dataChannelEventHandler(e network.DataChannelEvent)
 		id := event.StreamIdentifier()
 		newDataChannel := &RTCDataChannel{ID: &id, Label: event.Label, rtcPeerConnection: pc, ReadyState: RTCDataChannelStateOpen}
 		pc.dataChannels[e.StreamIdentifier()] = newDataChannel
-		if pc.OnDataChannel != nil {
-			pc.backgroundActions <- func() {
-				pc.OnDataChannel(newDataChannel) // This should actually be called when processing the SDP answer.
-				if newDataChannel.OnOpen != nil {
-					newDataChannel.doOnOpen()
-				}
-			}
-		} else {
-			fmt.Println("OnDataChannel is unset, discarding message")
-		}
+		pc.events.push(RTCDataChannelEvent{Channel: newDataChannel})
+		newDataChannel.events.push(func() { pc.dispatchDataChannel(newDataChannel) })
 	case *network.DataChannelMessage:
 		if datachannel, ok := pc.dataChannels[e.StreamIdentifier()]; ok {
-			datachannel.RLock()
-			defer datachannel.RUnlock()
-
-			if datachannel.Onmessage != nil {
-				pc.backgroundActions <- func() { datachannel.Onmessage(event.Payload) }
-			} else {
-				fmt.Printf("Onmessage has not been set for Datachannel %s %d \n", datachannel.Label, e.StreamIdentifier())
-			}
+			datachannel.countReceived(event.Payload)
+			datachannel.events.push(func() { pc.dispatchDataChannelMessage(datachannel, event.Payload) })
 		} else {
-			fmt.Printf("No datachannel found for streamIdentifier %d \n", e.StreamIdentifier())
+			pc.log.Warnf("No datachannel found for streamIdentifier %d", e.StreamIdentifier())
 
 		}
 	case *network.DataChannelOpen:
@@ -857,33 +2162,169 @@ func (pc *RTCPeerConnection) dataChannelEventHandler(e network.DataChannelEvent)
 			dc.Lock()
 			err := dc.sendOpenChannelMessage()
 			if err != nil {
-				fmt.Println("failed to send openchannel", err)
+				pc.log.Errorf("failed to send openchannel: %v", err)
+				pc.events.push(RTCErrorEvent{Err: errors.Wrap(err, "failed to send openchannel")})
 				dc.Unlock()
 				continue
 			}
 			dc.ReadyState = RTCDataChannelStateOpen
 			dc.Unlock()
 
-			pc.backgroundActions <- func() {
-				dc.doOnOpen() // TODO: move to ChannelAck handling
+			dc.events.push(dc.doOnOpen) // TODO: move to ChannelAck handling
+		}
+	case *network.DataChannelClose:
+		for _, dc := range pc.dataChannels {
+			dc.Lock()
+			dc.ReadyState = RTCDataChannelStateClosed
+			dc.Unlock()
+		}
+	default:
+		pc.log.Warnf("Unhandled DataChannelEvent %v", event)
+	}
+}
+
+// dispatchDataChannel invokes OnDataChannel with the data channel the remote
+// peer created, it runs on the event queue of the channel
+func (pc *RTCPeerConnection) dispatchDataChannel(d *RTCDataChannel) {
+	deliver, held := pc.unhandledEvents.dispatch(fmt.Sprintf("data channel %s", d.Label), func() func() {
+		pc.RLock()
+		onDataChannel := pc.onDataChannelHandler
+		pc.RUnlock()
+		if onDataChannel == nil {
+			return nil
+		}
+		return func() {
+			onDataChannel(d) // This should actually be called when processing the SDP answer.
+			d.doOnOpen()
+		}
+	})
+	if deliver != nil {
+		deliver()
+	} else if !held && !pc.events.isStarted() {
+		pc.log.Warn("OnDataChannel is unset, discarding message")
+	}
+}
+
+// dispatchDataChannelMessage invokes Onmessage of the data channel with the
+// payload, it runs on the event queue of the channel after the handlers of
+// the earlier events
+func (pc *RTCPeerConnection) dispatchDataChannelMessage(d *RTCDataChannel, payload datachannel.Payload) {
+	deliver, held := pc.unhandledEvents.dispatch(fmt.Sprintf("message of data channel %s", d.Label), func() func() {
+		d.RLock()
+		onmessage := d.Onmessage
+		d.RUnlock()
+		if onmessage == nil {
+			return nil
+		}
+		return func() { onmessage(payload) }
+	})
+	if deliver != nil {
+		deliver()
+	} else if !held {
+		pc.log.Warnf("Onmessage has not been set for Datachannel %s %d", d.Label, *d.ID)
+	}
+}
+
+// startGathering gathers the candidates of the ICE servers in the background,
+// unless they are gathered already
+func (pc *RTCPeerConnection) startGathering() {
+	pc.gatherOnce.Do(func() {
+		pc.Lock()
+		pc.IceGatheringState = RTCIceGatheringStateGathering
+		iceServers := pc.configuration.IceServers
+		pc.Unlock()
+
+		workers.schedule(func() {
+			defer close(pc.gathered)
+
+			// FIXME Temporary code before IceAgent and RTCIceTransport Rebuild
+			for _, server := range iceServers {
+				credentials := pc.iceServerCredentials(server)
+				for i := range server.URLs {
+					url, err := server.parseURL(i)
+					if err == nil {
+						err = pc.networkManager.AddURL(url, credentials)
+					}
+					if err != nil {
+						pc.log.Warnf("Failed to add ICE server: %v", err)
+					}
+				}
 			}
+
+			pc.Lock()
+			pc.IceGatheringState = RTCIceGatheringStateComplete
+			pc.Unlock()
+		})
+	})
+}
+
+// iceServerCredentials returns the credentials allocations on the TURN
+// server are authenticated with until a time, its time-limited credentials
+// are renewed first when they expire within a minute of it. The allocations
+// of the URLs of the server share the renewed credentials, which are kept
+// if they fail to be renewed.
+func (pc *RTCPeerConnection) iceServerCredentials(server RTCIceServer) turn.CredentialsFunc {
+	var lock sync.Mutex
+	return func(until time.Time) (turn.Credentials, error) {
+		lock.Lock()
+		defer lock.Unlock()
+
+		refreshed, err := server.refresh(pc.iceCredentialRefresh, until)
+		if err != nil {
+			pc.log.Warnf("Failed to refresh the credentials of ICE server %v: %v", server.URLs, err)
+		}
+		server = refreshed
+		return server.turnCredentials()
+	}
+}
+
+// iceCandidateFilter returns the filter enforcing the transport policy on the
+// candidates paired
+func iceCandidateFilter(policy RTCIceTransportPolicy) ice.CandidateFilter {
+	switch policy {
+	case RTCIceTransportPolicyRelay:
+		// Only local relay candidates may be paired, with remote candidates
+		// of any type
+		return func(c ice.Candidate, local bool) bool {
+			_, isRelay := c.(*ice.CandidateRelay)
+			return isRelay || !local
+		}
+	case RTCIceTransportPolicyHostOnly:
+		return func(c ice.Candidate, local bool) bool {
+			_, isHost := c.(*ice.CandidateHost)
+			return isHost
 		}
 	default:
-		fmt.Printf("Unhandled DataChannelEvent %v \n", event)
+		return nil
 	}
 }
 
-func (pc *RTCPeerConnection) generateLocalCandidates() []string {
-	pc.networkManager.IceAgent.RLock()
-	defer pc.networkManager.IceAgent.RUnlock()
+// gatherCandidates returns once the candidates of the ICE servers are
+// gathered, the candidate pool may have gathered them already
+func (pc *RTCPeerConnection) gatherCandidates() {
+	pc.startGathering()
+	<-pc.gathered
+}
 
+func (pc *RTCPeerConnection) generateLocalCandidates() []string {
 	candidates := make([]string, 0)
-	for _, c := range pc.networkManager.IceAgent.LocalCandidates {
+	for _, c := range pc.networkManager.LocalCandidates() {
 		candidates = append(candidates, sdp.ICECandidateMarshal(c)...)
 	}
 	return candidates
 }
 
+// rtcpMux reports whether RTCP is multiplexed with RTP, which is offered
+// and always accepted. Only the negotiate policy falls back to separate RTCP
+// for remote peers which don't multiplex it.
+func (pc *RTCPeerConnection) rtcpMux() bool {
+	if pc.configuration.RtcpMuxPolicy != RTCRtcpMuxPolicyNegotiate || pc.currentRemoteDescription == nil {
+		return true
+	}
+	_, separate := pc.currentRemoteDescription.parsed.GetRTCPAddress()
+	return !separate
+}
+
 func localDirection(weSend bool, peerDirection RTCRtpTransceiverDirection) RTCRtpTransceiverDirection {
 	theySend := (peerDirection == RTCRtpTransceiverDirectionSendrecv || peerDirection == RTCRtpTransceiverDirectionSendonly)
 	if weSend && theySend {
@@ -898,49 +2339,300 @@ func localDirection(weSend bool, peerDirection RTCRtpTransceiverDirection) RTCRt
 }
 
 func (pc *RTCPeerConnection) addRTPMediaSection(d *sdp.SessionDescription, codecType RTCRtpCodecType, midValue string, peerDirection RTCRtpTransceiverDirection, candidates []string, dtlsRole sdp.ConnectionRole) bool {
-	if codecs := pc.mediaEngine.getCodecsByKind(codecType); len(codecs) == 0 {
+	codecs := pc.mediaEngine.getCodecsByKind(codecType)
+	for _, transceiver := range pc.rtpTransceivers {
+		if transceiver.Mid == midValue {
+			codecs = transceiver.getCodecs(pc.mediaEngine, codecType)
+			break
+		}
+	}
+
+	// Answers take the codecs both peers support, under the payload types
+	// and format parameters of the offer
+	offered := pc.offeredCodecs(codecs, codecType, midValue)
+	if offered != nil {
+		var answered []*RTCRtpCodec
+		added := make(map[uint8]bool)
+		for _, codec := range codecs {
+			if _, ok := offered[codec.PayloadType]; ok && !added[codec.PayloadType] {
+				answered = append(answered, codec)
+				added[codec.PayloadType] = true
+			}
+		}
+		codecs = answered
+	}
+	if len(codecs) == 0 {
 		return false
 	}
 
+	// The RTCP feedback is limited to the one the remote peer announced
+	var remote *sdp.SessionDescription
+	if pc.currentRemoteDescription != nil {
+		remote = pc.currentRemoteDescription.parsed
+	}
+
 	media := sdp.NewJSEPMediaDescription(codecType.String(), []string{}).
 		WithValueAttribute(sdp.AttrKeyConnectionSetup, dtlsRole.String()). // TODO: Support other connection types
 		WithValueAttribute(sdp.AttrKeyMID, midValue).
-		WithICECredentials(pc.networkManager.IceAgent.LocalUfrag, pc.networkManager.IceAgent.LocalPwd).
-		WithPropertyAttribute(sdp.AttrKeyRtcpMux).  // TODO: support RTCP fallback
-		WithPropertyAttribute(sdp.AttrKeyRtcpRsize) // TODO: Support Reduced-Size RTCP?
+		WithICECredentials(pc.networkManager.IceAgent.LocalUfrag, pc.networkManager.IceAgent.LocalPwd)
+	if pc.rtcpMux() {
+		media.WithPropertyAttribute(sdp.AttrKeyRtcpMux)
+	}
+	media.WithPropertyAttribute(sdp.AttrKeyRtcpRsize)
 
-	for _, codec := range pc.mediaEngine.getCodecsByKind(codecType) {
-		media.WithCodec(codec.PayloadType, codec.Name, codec.ClockRate, codec.Channels, codec.SdpFmtpLine)
+	if id, ok := pc.midExtensionID(); ok {
+		media.WithExtMap(id, sdp.ExtMapURIMID)
+	}
+	if id, ok := pc.absSendTimeExtensionID(); ok {
+		media.WithExtMap(id, sdp.ExtMapURIAbsSendTime)
 	}
 
+	for _, codec := range codecs {
+		payloadType, fmtp := codec.PayloadType, codec.SdpFmtpLine
+		if o, ok := offered[codec.PayloadType]; ok {
+			payloadType, fmtp = o.PayloadType, o.Fmtp
+			pc.Lock()
+			pc.payloadTypes[codec.PayloadType] = payloadType
+			pc.Unlock()
+		}
+
+		media.WithCodec(payloadType, codec.Name, codec.ClockRate, codec.Channels, fmtp)
+		for _, feedback := range pc.mediaEngine.getRTCPFeedback(codec, payloadType, remote) {
+			media.WithValueAttribute(sdp.AttrKeyRtcpFb, fmt.Sprintf("%d %s", payloadType, feedback))
+		}
+	}
+
+	// We can only send if the peer is willing to receive, RFC 3264 Section 6.1
+	theyReceive := peerDirection == RTCRtpTransceiverDirectionSendrecv || peerDirection == RTCRtpTransceiverDirectionRecvonly
+
 	weSend := false
 	for _, transceiver := range pc.rtpTransceivers {
-		if transceiver.Sender == nil ||
+		if !theyReceive ||
+			transceiver.Sender == nil ||
 			transceiver.Sender.Track == nil ||
-			transceiver.Sender.Track.Kind != codecType {
+			transceiver.Sender.Track.Kind != codecType ||
+			transceiver.Mid != midValue {
 			continue
 		}
 		weSend = true
 		track := transceiver.Sender.Track
-		media = media.WithMediaSource(track.Ssrc, track.Label /* cname */, track.Label /* streamLabel */, track.Label)
+		if !pc.usesPlanB() {
+			// Plan B identifies the tracks of a media section by their SSRCs only
+			media = media.WithValueAttribute(sdp.AttrKeyMsid, track.Label+" "+track.ID)
+		}
+		encodings := transceiver.Sender.getEncodings()
+		if len(encodings) == 0 {
+			media = media.WithMediaSource(track.Ssrc, track.Label /* cname */, track.Label /* streamLabel */, track.ID)
+			media = withRTXMediaSource(media, transceiver.Sender, track.Ssrc)
+			media = withFECMediaSource(media, transceiver.Sender, track.Ssrc)
+			continue
+		}
+
+		// Every simulcast layer is announced with its own SSRC
+		rids := make([]string, len(encodings))
+		ssrcs := make([]uint32, len(encodings))
+		for i, encoding := range encodings {
+			rids[i] = encoding.RID
+			ssrcs[i] = encoding.SSRC
+			media = media.WithMediaSource(encoding.SSRC, track.Label /* cname */, track.Label /* streamLabel */, track.ID)
+			media = withRTXMediaSource(media, transceiver.Sender, encoding.SSRC)
+			media = withFECMediaSource(media, transceiver.Sender, encoding.SSRC)
+		}
+		media = media.WithSimulcast("send", rids, ssrcs)
 	}
 	media = media.WithPropertyAttribute(localDirection(weSend, peerDirection).String())
 
 	for _, c := range candidates {
 		media.WithCandidate(c)
 	}
-	media.WithPropertyAttribute("end-of-candidates")
+	media.WithPropertyAttribute(sdp.AttrKeyEndOfCandidates)
 	d.WithMedia(media)
 	return true
 }
 
+// offeredCodecs pairs the codecs with the ones the remote peer offered in
+// the media section of the mid, see matchOfferedCodecs. It returns nil
+// unless the remote description is an offer with a section of the kind.
+func (pc *RTCPeerConnection) offeredCodecs(codecs []*RTCRtpCodec, kind RTCRtpCodecType, midValue string) map[uint8]sdp.Codec {
+	if pc.currentRemoteDescription == nil || pc.currentRemoteDescription.Type != RTCSdpTypeOffer {
+		return nil
+	}
+	m, ok := pc.currentRemoteDescription.parsed.GetMediaForMid(midValue)
+	if !ok || m.MediaName.Media != kind.String() {
+		return nil
+	}
+
+	section := &sdp.SessionDescription{MediaDescriptions: []*sdp.MediaDescription{m}}
+	return matchOfferedCodecs(codecs, section.GetCodecsForMedia(kind.String()))
+}
+
+// midExtensionID returns the id the MID header extension is mapped to in
+// local descriptions. Answers follow the offer, leaving it out if the offer
+// didn't negotiate it. Plan B media sections aren't identified by mid.
+func (pc *RTCPeerConnection) midExtensionID() (uint8, bool) {
+	if pc.usesPlanB() {
+		return 0, false
+	}
+	if pc.currentRemoteDescription == nil {
+		return defaultMIDExtensionID, true
+	}
+	return pc.currentRemoteDescription.parsed.GetExtMapID(sdp.ExtMapURIMID)
+}
+
+// absSendTimeExtensionID returns the id the abs-send-time header extension is
+// mapped to in local descriptions, answers follow the offer like the MID
+func (pc *RTCPeerConnection) absSendTimeExtensionID() (uint8, bool) {
+	if pc.currentRemoteDescription == nil {
+		return defaultAbsSendTimeExtensionID, true
+	}
+	return pc.currentRemoteDescription.parsed.GetExtMapID(sdp.ExtMapURIAbsSendTime)
+}
+
+// negotiatedCodecs returns the registered codecs of the kind, limited to the
+// ones the remote peer knows about once its description is set
+func (pc *RTCPeerConnection) negotiatedCodecs(kind RTCRtpCodecType) []RTCRtpCodecParameters {
+	var remote *sdp.SessionDescription
+	pc.RLock()
+	if pc.currentRemoteDescription != nil {
+		remote = pc.currentRemoteDescription.parsed
+	}
+	pc.RUnlock()
+
+	codecs := []RTCRtpCodecParameters{}
+	for _, codec := range pc.mediaEngine.getCodecsByKind(kind) {
+		payloadType := pc.negotiatedPayloadType(codec.PayloadType)
+		if remote != nil {
+			sdpCodec, err := remote.GetCodecForPayloadType(payloadType)
+			if err != nil || !strings.EqualFold(sdpCodec.Name, codec.Name) {
+				continue
+			}
+		}
+
+		capability := codec.RTCRtpCodecCapability
+		capability.RTCPFeedback = pc.mediaEngine.getRTCPFeedback(codec, payloadType, remote)
+		codecs = append(codecs, RTCRtpCodecParameters{
+			RTCRtpCodecCapability: capability,
+			PayloadType:           payloadType,
+		})
+	}
+	return codecs
+}
+
+// comfortNoiseCodec returns the comfort noise codec of the clock rate once
+// the remote peer negotiated it, nil until then as it would drop the packets
+func (pc *RTCPeerConnection) comfortNoiseCodec(clockRate uint32) *RTCRtpCodec {
+	codec := pc.mediaEngine.getComfortNoiseCodec(clockRate)
+	if codec == nil {
+		return nil
+	}
+
+	pc.RLock()
+	remote := pc.currentRemoteDescription
+	pc.RUnlock()
+	if remote == nil {
+		return nil
+	}
+	sdpCodec, err := remote.parsed.GetCodecForPayloadType(pc.negotiatedPayloadType(codec.PayloadType))
+	if err != nil || !strings.EqualFold(sdpCodec.Name, codec.Name) || sdpCodec.ClockRate != clockRate {
+		return nil
+	}
+	return codec
+}
+
+// negotiatedPayloadType returns the payload type the local codec is sent with
+func (pc *RTCPeerConnection) negotiatedPayloadType(payloadType uint8) uint8 {
+	pc.RLock()
+	defer pc.RUnlock()
+
+	if negotiated, ok := pc.payloadTypes[payloadType]; ok {
+		return negotiated
+	}
+	return payloadType
+}
+
+// GetRemoteCapabilities returns the codecs and RTP header extensions of the
+// kind offered by the remote peer in the current remote description, in its
+// order of preference. They are empty until a remote description is set.
+func (pc *RTCPeerConnection) GetRemoteCapabilities(kind RTCRtpCodecType) RTCRtpCapabilities {
+	capabilities := RTCRtpCapabilities{
+		Codecs:           []RTCRtpCodecCapability{},
+		HeaderExtensions: []RTCRtpHeaderExtensionCapability{},
+	}
+
+	pc.RLock()
+	defer pc.RUnlock()
+	if pc.currentRemoteDescription == nil || pc.currentRemoteDescription.parsed == nil {
+		return capabilities
+	}
+	remote := pc.currentRemoteDescription.parsed
+
+	for _, codec := range remote.GetCodecsForMedia(kind.String()) {
+		// The encoding parameters of audio codecs are their channels
+		channels, err := strconv.ParseUint(codec.EncodingParameters, 10, 16)
+		if err != nil {
+			channels = 0
+		}
+		capability := RTCRtpCodecCapability{
+			MimeType:    kind.String() + "/" + codec.Name,
+			ClockRate:   codec.ClockRate,
+			Channels:    uint16(channels),
+			SdpFmtpLine: codec.Fmtp,
+		}
+		for _, raw := range codec.RTCPFeedback {
+			capability.RTCPFeedback = append(capability.RTCPFeedback, newRTCRtcpFeedback(raw))
+		}
+		capabilities.Codecs = append(capabilities.Codecs, capability)
+	}
+	for _, uri := range remote.GetExtMapURIsForMedia(kind.String()) {
+		capabilities.HeaderExtensions = append(capabilities.HeaderExtensions, RTCRtpHeaderExtensionCapability{URI: uri})
+	}
+	return capabilities
+}
+
+// withRTXMediaSource announces the RTX stream repairing the SSRC, if the sender has one
+func withRTXMediaSource(media *sdp.MediaDescription, sender *RTCRtpSender, ssrc uint32) *sdp.MediaDescription {
+	rtxSSRC, ok := sender.getRTXSSRC(ssrc)
+	if !ok {
+		return media
+	}
+
+	label := sender.Track.Label
+	return media.
+		WithSSRCGroup(sdp.SemanticTokenFlowIdentification, ssrc, rtxSSRC).
+		WithMediaSource(rtxSSRC, label /* cname */, label /* streamLabel */, label)
+}
+
+// withFECMediaSource announces the FEC stream protecting the SSRC, if the sender has one
+func withFECMediaSource(media *sdp.MediaDescription, sender *RTCRtpSender, ssrc uint32) *sdp.MediaDescription {
+	fecSSRC, ok := sender.getFECSSRC(ssrc)
+	if !ok {
+		return media
+	}
+
+	label := sender.Track.Label
+	return media.
+		WithSSRCGroup(sdp.SemanticTokenForwardErrorCorrection, ssrc, fecSSRC).
+		WithMediaSource(fecSSRC, label /* cname */, label /* streamLabel */, label)
+}
+
+// addDataMediaSection adds the data channel section. Answers take the
+// format of the data channel section of the offer, offers announce the SCTP
+// port with a=sctp-port if SettingEngine.SetSCTPPortAttributes is enabled,
+// and with the legacy a=sctpmap otherwise.
 func (pc *RTCPeerConnection) addDataMediaSection(d *sdp.SessionDescription, midValue string, candidates []string, dtlsRole sdp.ConnectionRole) {
-	media := (&sdp.MediaDescription{
+	sctpPortAttributes := pc.sctpPortAttributes
+	if remote := pc.currentRemoteDescription; remote != nil && remote.Type == RTCSdpTypeOffer {
+		if _, ok := remote.parsed.GetDataMedia(); ok {
+			sctpPortAttributes = remote.parsed.UsesSCTPPort()
+		}
+	}
+
+	media := &sdp.MediaDescription{
 		MediaName: sdp.MediaName{
 			Media:   "application",
 			Port:    sdp.RangedPort{Value: 9},
 			Protos:  []string{"DTLS", "SCTP"},
-			Formats: []int{5000},
+			Formats: []string{strconv.Itoa(sctpPort)},
 		},
 		ConnectionInformation: &sdp.ConnectionInformation{
 			NetworkType: "IN",
@@ -949,17 +2641,29 @@ func (pc *RTCPeerConnection) addDataMediaSection(d *sdp.SessionDescription, midV
 				IP: net.ParseIP("0.0.0.0"),
 			},
 		},
-	}).
+	}
+	if sctpPortAttributes {
+		media.MediaName.Protos = []string{"UDP", "DTLS", "SCTP"}
+		media.MediaName.Formats = []string{sdp.FormatWebRTCDataChannel}
+	}
+
+	media.
 		WithValueAttribute(sdp.AttrKeyConnectionSetup, dtlsRole.String()). // TODO: Support other connection types
 		WithValueAttribute(sdp.AttrKeyMID, midValue).
-		WithPropertyAttribute(RTCRtpTransceiverDirectionSendrecv.String()).
-		WithPropertyAttribute("sctpmap:5000 webrtc-datachannel 1024").
-		WithICECredentials(pc.networkManager.IceAgent.LocalUfrag, pc.networkManager.IceAgent.LocalPwd)
+		WithPropertyAttribute(RTCRtpTransceiverDirectionSendrecv.String())
+	if sctpPortAttributes {
+		media.
+			WithValueAttribute(sdp.AttrKeySCTPPort, strconv.Itoa(sctpPort)).
+			WithValueAttribute(sdp.AttrKeyMaxMessageSize, strconv.Itoa(sctp.MaxMessageSize))
+	} else {
+		media.WithPropertyAttribute(fmt.Sprintf("sctpmap:%d webrtc-datachannel %d", sctpPort, pc.sctpTransport.maxChannels()))
+	}
+	media.WithICECredentials(pc.networkManager.IceAgent.LocalUfrag, pc.networkManager.IceAgent.LocalPwd)
 
 	for _, c := range candidates {
 		media.WithCandidate(c)
 	}
-	media.WithPropertyAttribute("end-of-candidates")
+	media.WithPropertyAttribute(sdp.AttrKeyEndOfCandidates)
 
 	d.WithMedia(media)
 }
@@ -971,48 +2675,22 @@ func (pc *RTCPeerConnection) newRTCTrack(payloadType uint8, ssrc uint32, id, lab
 	}
 
 	if codec.Payloader == nil {
-		return nil, errors.New("codec payloader not set")
+		return nil, ErrNoPayloader
 	}
 
-	trackInput := make(chan media.RTCSample, 15) // Is the buffering needed?
+	// The channel is the queue of the block policy, the reader queues the
+	// samples of the drop policies as they are written
+	trackInput := make(chan media.RTCSample, pc.trackQueueSize)
+	if pc.trackQueuePolicy != RTCTrackQueuePolicyBlock {
+		trackInput = make(chan media.RTCSample)
+	}
 	rawPackets := make(chan *rtp.Packet)
-	if ssrc == 0 {
-		buf := make([]byte, 4)
-		_, err = rand.Read(buf)
+	isRawRTP := ssrc != 0
+	if !isRawRTP {
+		ssrc, err = pc.newSSRC()
 		if err != nil {
-			return nil, errors.New("failed to generate random value")
-		}
-		ssrc = binary.LittleEndian.Uint32(buf)
-
-		go func() {
-			packetizer := rtp.NewPacketizer(
-				1400,
-				payloadType,
-				ssrc,
-				codec.Payloader,
-				rtp.NewRandomSequencer(),
-				codec.ClockRate,
-			)
-
-			for {
-				in := <-trackInput
-				packets := packetizer.Packetize(in.Data, in.Samples)
-				for _, p := range packets {
-					pc.networkManager.SendRTP(p)
-				}
-			}
-		}()
-		close(rawPackets)
-	} else {
-		// If SSRC is not 0, then we are working with an established RTP stream
-		// and need to accept raw RTP packets for forwarding.
-		go func() {
-			for {
-				p := <-rawPackets
-				pc.networkManager.SendRTP(p)
-			}
-		}()
-		close(trackInput)
+			return nil, err
+		}
 	}
 
 	t := &RTCTrack{
@@ -1024,15 +2702,66 @@ func (pc *RTCPeerConnection) newRTCTrack(payloadType uint8, ssrc uint32, id, lab
 		Codec:       codec,
 		Samples:     trackInput,
 		RawRTP:      rawPackets,
+		pc:          pc,
+		done:        pc.done,
+	}
+
+	if !isRawRTP {
+		t.sequencer = rtp.NewRandomSequencer()
+		t.mtu = pc.packetizerMTU
+		t.packetizer = newRTCPacketizer(t.mtu, codec, ssrc, t.sequencer)
+		if codec.Type == RTCRtpCodecTypeAudio {
+			t.dtx = newRTCAudioDTX(codec.ClockRate)
+		}
+		readTrackInput(&rtcTrackInput{
+			track:   t,
+			channel: reflect.ValueOf((<-chan media.RTCSample)(trackInput)),
+			write: func(v reflect.Value) {
+				t.writeSample(v.Interface().(media.RTCSample))
+			},
+			full:   t.queueFull,
+			policy: pc.trackQueuePolicy,
+			size:   pc.trackQueueSize,
+		})
+		close(rawPackets)
+	} else {
+		// If SSRC is not 0, then we are working with an established RTP stream
+		// and need to accept raw RTP packets for forwarding.
+		readTrackInput(&rtcTrackInput{
+			track:   t,
+			channel: reflect.ValueOf((<-chan *rtp.Packet)(rawPackets)),
+			write: func(v reflect.Value) {
+				t.writeRTP(v.Interface().(*rtp.Packet))
+			},
+			policy: RTCTrackQueuePolicyBlock,
+		})
+		close(trackInput)
 	}
 
 	return t, nil
 }
 
+// newSSRC returns the SSRC of a new local stream
+func (pc *RTCPeerConnection) newSSRC() (uint32, error) {
+	if pc != nil && pc.ssrcGenerator != nil {
+		return pc.ssrcGenerator(), nil
+	}
+	return randomSSRC()
+}
+
+func randomSSRC() (uint32, error) {
+	buf := make([]byte, 4)
+	if _, err := rand.Read(buf); err != nil {
+		return 0, errors.New("failed to generate random value")
+	}
+	return binary.LittleEndian.Uint32(buf), nil
+}
+
 // NewRawRTPTrack initializes a new *RTCTrack configured to accept raw *rtp.Packet
 //
 // NB: If the source RTP stream is being broadcast to multiple tracks, each track
 // must receive its own copies of the source packets in order to avoid packet corruption.
+// RTCBroadcastTrack creates such tracks and copies the packets for them.
 func (pc *RTCPeerConnection) NewRawRTPTrack(payloadType uint8, ssrc uint32, id, label string) (*RTCTrack, error) {
 	if ssrc == 0 {
 		return nil, errors.New("SSRC supplied to NewRawRTPTrack() must be non-zero")
@@ -1058,10 +2787,14 @@ func (pc *RTCPeerConnection) newRTCRtpTransceiver(
 	direction RTCRtpTransceiverDirection,
 ) *RTCRtpTransceiver {
 
+	receiver.Transport = pc.dtlsTransport
+	sender.Transport = pc.dtlsTransport
+
 	t := &RTCRtpTransceiver{
-		Receiver:  receiver,
-		Sender:    sender,
-		Direction: direction,
+		Receiver:          receiver,
+		Sender:            sender,
+		Direction:         direction,
+		rtcPeerConnection: pc,
 	}
 	pc.rtpTransceivers = append(pc.rtpTransceivers, t)
 	return t
@@ -0,0 +1,393 @@
+//go:build js && wasm
+
+package webrtc
+
+import (
+	"sync"
+	"syscall/js"
+
+	"github.com/pions/webrtc/pkg/ice"
+	"github.com/pions/webrtc/pkg/rtcerr"
+)
+
+// RTCPeerConnection represents a WebRTC connection that establishes a
+// peer-to-peer communications with another RTCPeerConnection instance in a
+// browser, or to another endpoint implementing the required protocols.
+//
+// Compiled to WebAssembly it delegates to the RTCPeerConnection of the
+// browser, so applications share their signaling and data channel code
+// between Go servers and browser clients. Only data channels are supported,
+// media is left to the APIs of the browser. Like the native one, offers and
+// answers are set as the local description and returned once ICE gathering
+// is complete, with every candidate embedded. The methods block until the
+// browser settles their promise, so they must not be called from a callback
+// of the browser. The handlers run on goroutines of their own, in the order
+// of their events.
+type RTCPeerConnection struct {
+	sync.RWMutex
+
+	underlying js.Value
+
+	onICEConnectionStateChangeHandler func(ice.ConnectionState)
+	onDataChannelHandler              func(*RTCDataChannel)
+
+	// events runs the handlers of the events of the connection in the order
+	// the browser dispatched them
+	events operations
+
+	// callbacks are the event handlers set on the browser object, released
+	// once it is closed
+	callbacks []js.Func
+	isClosed  bool
+	done      chan struct{}
+}
+
+// New creates a new RTCPeerConnection with the provided configuration, the
+// certificates of the configuration are not supported since the browser
+// generates its own
+func New(configuration RTCConfiguration) (*RTCPeerConnection, error) {
+	constructor := js.Global().Get("RTCPeerConnection")
+	if constructor.IsUndefined() {
+		return nil, &rtcerr.NotSupportedError{Err: ErrNoBrowserPeerConnection}
+	}
+	config, err := configurationToValue(configuration)
+	if err != nil {
+		return nil, err
+	}
+	underlying, err := callBrowser(func() js.Value { return constructor.New(config) })
+	if err != nil {
+		return nil, err
+	}
+
+	pc := &RTCPeerConnection{underlying: underlying, done: make(chan struct{})}
+	pc.callbacks = append(pc.callbacks,
+		setEventHandler(underlying, "oniceconnectionstatechange", func(js.Value) {
+			state := iceConnectionState(underlying.Get("iceConnectionState").String())
+			pc.events.push(func() { pc.doOnICEConnectionStateChange(state) })
+		}),
+		setEventHandler(underlying, "ondatachannel", func(event js.Value) {
+			// The handler runs before the first message of the channel
+			d := newRTCDataChannel(event.Get("channel"))
+			d.events.push(func() { pc.doOnDataChannel(d) })
+		}),
+	)
+	return pc, nil
+}
+
+// CreateOffer creates an offer, sets it as the local description and returns
+// it once ICE gathering is complete
+func (pc *RTCPeerConnection) CreateOffer(options *RTCOfferOptions) (RTCSessionDescription, error) {
+	jsOptions := map[string]interface{}{}
+	if options != nil {
+		jsOptions["iceRestart"] = options.IceRestart
+		jsOptions["voiceActivityDetection"] = options.VoiceActivityDetection
+	}
+	return pc.createDescription("createOffer", jsOptions)
+}
+
+// CreateAnswer creates an answer to the remote description, sets it as the
+// local description and returns it once ICE gathering is complete
+func (pc *RTCPeerConnection) CreateAnswer(options *RTCAnswerOptions) (RTCSessionDescription, error) {
+	jsOptions := map[string]interface{}{}
+	if options != nil {
+		jsOptions["voiceActivityDetection"] = options.VoiceActivityDetection
+	}
+	return pc.createDescription("createAnswer", jsOptions)
+}
+
+// createDescription creates an offer or an answer with the method of the
+// browser and sets it as the local description
+func (pc *RTCPeerConnection) createDescription(method string, options map[string]interface{}) (RTCSessionDescription, error) {
+	desc, err := awaitPromise(pc.underlying.Call(method, options))
+	if err != nil {
+		return RTCSessionDescription{}, err
+	}
+	if _, err := awaitPromise(pc.underlying.Call("setLocalDescription", desc)); err != nil {
+		return RTCSessionDescription{}, err
+	}
+
+	if err := pc.waitGatheringComplete(); err != nil {
+		return RTCSessionDescription{}, err
+	}
+	return *valueToSessionDescription(pc.underlying.Get("localDescription")), nil
+}
+
+// waitGatheringComplete waits until the candidates of the local description
+// are gathered, or the RTCPeerConnection is closed
+func (pc *RTCPeerConnection) waitGatheringComplete() error {
+	complete := make(chan struct{})
+	var once sync.Once
+	callback := js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+		if pc.underlying.Get("iceGatheringState").String() == "complete" {
+			once.Do(func() { close(complete) })
+		}
+		return nil
+	})
+	defer callback.Release()
+	pc.underlying.Call("addEventListener", "icegatheringstatechange", callback)
+	defer pc.underlying.Call("removeEventListener", "icegatheringstatechange", callback)
+
+	if pc.underlying.Get("iceGatheringState").String() == "complete" {
+		return nil
+	}
+	select {
+	case <-complete:
+		return nil
+	case <-pc.done:
+		return &rtcerr.InvalidStateError{Err: ErrConnectionClosed}
+	}
+}
+
+// SetRemoteDescription sets the SessionDescription of the remote peer
+func (pc *RTCPeerConnection) SetRemoteDescription(desc RTCSessionDescription) error {
+	_, err := awaitPromise(pc.underlying.Call("setRemoteDescription", sessionDescriptionToValue(desc)))
+	return err
+}
+
+// LocalDescription returns the local description, nil until an offer or
+// answer is created
+func (pc *RTCPeerConnection) LocalDescription() *RTCSessionDescription {
+	return valueToSessionDescription(pc.underlying.Get("localDescription"))
+}
+
+// RemoteDescription returns the remote description, nil until it is set
+func (pc *RTCPeerConnection) RemoteDescription() *RTCSessionDescription {
+	return valueToSessionDescription(pc.underlying.Get("remoteDescription"))
+}
+
+// AddIceCandidate adds a candidate the remote peer trickled to the media
+// section of the remote description it belongs to. An empty candidate, or
+// an end-of-candidates attribute, signals that the remote peer gathered all
+// of its candidates.
+// https://www.w3.org/TR/webrtc/#dom-rtcpeerconnection-addicecandidate
+func (pc *RTCPeerConnection) AddIceCandidate(candidate RTCIceCandidateInit) error {
+	init := map[string]interface{}{
+		"candidate":     candidate.Candidate,
+		"sdpMid":        stringOrNull(candidate.SDPMid),
+		"sdpMLineIndex": uint16OrNull(candidate.SDPMLineIndex),
+	}
+	if candidate.UsernameFragment != "" {
+		init["usernameFragment"] = candidate.UsernameFragment
+	}
+	_, err := awaitPromise(pc.underlying.Call("addIceCandidate", init))
+	return err
+}
+
+// CreateDataChannel creates a new RTCDataChannel object with the given label
+// and optional RTCDataChannelInit used to configure properties of the
+// underlying channel such as data reliability.
+func (pc *RTCPeerConnection) CreateDataChannel(label string, options *RTCDataChannelInit) (*RTCDataChannel, error) {
+	init := map[string]interface{}{}
+	if options != nil {
+		if options.Ordered != nil {
+			init["ordered"] = *options.Ordered
+		}
+		if options.MaxPacketLifeTime != nil {
+			init["maxPacketLifeTime"] = int(*options.MaxPacketLifeTime)
+		}
+		if options.MaxRetransmits != nil {
+			init["maxRetransmits"] = int(*options.MaxRetransmits)
+		}
+		if options.Protocol != nil {
+			init["protocol"] = *options.Protocol
+		}
+		if options.Negotiated != nil {
+			init["negotiated"] = *options.Negotiated
+		}
+		if options.ID != nil {
+			init["id"] = int(*options.ID)
+		}
+		if options.Priority != nil {
+			init["priority"] = options.Priority.String()
+		}
+	}
+
+	channel, err := callBrowser(func() js.Value { return pc.underlying.Call("createDataChannel", label, init) })
+	if err != nil {
+		return nil, err
+	}
+	return newRTCDataChannel(channel), nil
+}
+
+// SignalingState returns the signaling state of the RTCPeerConnection
+func (pc *RTCPeerConnection) SignalingState() RTCSignalingState {
+	return newRTCSignalingState(pc.underlying.Get("signalingState").String())
+}
+
+// ICEConnectionState returns the ICE connection state of the
+// RTCPeerConnection
+func (pc *RTCPeerConnection) ICEConnectionState() ice.ConnectionState {
+	return iceConnectionState(pc.underlying.Get("iceConnectionState").String())
+}
+
+// ConnectionState returns the connection state of the RTCPeerConnection
+func (pc *RTCPeerConnection) ConnectionState() RTCPeerConnectionState {
+	return newRTCPeerConnectionState(pc.underlying.Get("connectionState").String())
+}
+
+// OnICEConnectionStateChange sets an event handler which is called when an
+// ICE connection state is changed.
+func (pc *RTCPeerConnection) OnICEConnectionStateChange(f func(ice.ConnectionState)) {
+	pc.Lock()
+	defer pc.Unlock()
+	pc.onICEConnectionStateChangeHandler = f
+}
+
+// OnDataChannel sets an event handler which is invoked when a data channel
+// arrives from a remote peer, before the first message of the channel.
+func (pc *RTCPeerConnection) OnDataChannel(f func(*RTCDataChannel)) {
+	pc.Lock()
+	defer pc.Unlock()
+	pc.onDataChannelHandler = f
+}
+
+// Close ends the RTCPeerConnection, it is safe to call from any handler and
+// from many goroutines
+func (pc *RTCPeerConnection) Close() error {
+	pc.Lock()
+	defer pc.Unlock()
+	if pc.isClosed {
+		return nil
+	}
+	pc.isClosed = true
+	close(pc.done)
+
+	pc.underlying.Call("close")
+	for _, property := range []string{"oniceconnectionstatechange", "ondatachannel"} {
+		pc.underlying.Set(property, js.Null())
+	}
+	for _, callback := range pc.callbacks {
+		callback.Release()
+	}
+	pc.callbacks = nil
+	return nil
+}
+
+func (pc *RTCPeerConnection) doOnICEConnectionStateChange(state ice.ConnectionState) {
+	pc.RLock()
+	handler := pc.onICEConnectionStateChangeHandler
+	pc.RUnlock()
+	if handler != nil {
+		handler(state)
+	}
+}
+
+func (pc *RTCPeerConnection) doOnDataChannel(d *RTCDataChannel) {
+	pc.RLock()
+	handler := pc.onDataChannelHandler
+	pc.RUnlock()
+	if handler != nil {
+		handler(d)
+	}
+}
+
+// setEventHandler sets the event handler property of the browser object to
+// call f with the event, on the event loop of the browser
+func setEventHandler(target js.Value, property string, f func(event js.Value)) js.Func {
+	callback := js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+		f(argument(args, 0))
+		return nil
+	})
+	target.Set(property, callback)
+	return callback
+}
+
+// iceConnectionState maps the iceConnectionState of the browser to the
+// state the native ICE agent reports
+func iceConnectionState(raw string) ice.ConnectionState {
+	switch newRTCIceConnectionState(raw) {
+	case RTCIceConnectionStateNew:
+		return ice.ConnectionStateNew
+	case RTCIceConnectionStateChecking:
+		return ice.ConnectionStateChecking
+	case RTCIceConnectionStateConnected:
+		return ice.ConnectionStateConnected
+	case RTCIceConnectionStateCompleted:
+		return ice.ConnectionStateCompleted
+	case RTCIceConnectionStateDisconnected:
+		return ice.ConnectionStateDisconnected
+	case RTCIceConnectionStateFailed:
+		return ice.ConnectionStateFailed
+	case RTCIceConnectionStateClosed:
+		return ice.ConnectionStateClosed
+	default:
+		return ice.ConnectionState(Unknown)
+	}
+}
+
+// configurationToValue returns the RTCConfiguration dictionary of the browser
+// https://www.w3.org/TR/webrtc/#rtcconfiguration-dictionary
+func configurationToValue(c RTCConfiguration) (map[string]interface{}, error) {
+	if len(c.Certificates) > 0 {
+		return nil, &rtcerr.NotSupportedError{Err: ErrBrowserCertificates}
+	} else if c.IceTransportPolicy == RTCIceTransportPolicyHostOnly {
+		return nil, &rtcerr.NotSupportedError{Err: ErrBrowserHostOnly}
+	}
+
+	iceServers := make([]interface{}, len(c.IceServers))
+	for i, server := range c.IceServers {
+		urls := make([]interface{}, len(server.URLs))
+		for j, url := range server.URLs {
+			urls[j] = url
+		}
+		s := map[string]interface{}{"urls": urls}
+		if server.Username != "" {
+			s["username"] = server.Username
+		}
+		switch credential := server.Credential.(type) {
+		case string:
+			s["credential"] = credential
+		case RTCOAuthCredential:
+			s["credential"] = map[string]interface{}{
+				"macKey":      credential.MacKey,
+				"accessToken": credential.AccessToken,
+			}
+		}
+		if server.CredentialType != RTCIceCredentialType(Unknown) {
+			s["credentialType"] = server.CredentialType.String()
+		}
+		iceServers[i] = s
+	}
+
+	config := map[string]interface{}{
+		"iceServers":           iceServers,
+		"iceCandidatePoolSize": int(c.IceCandidatePoolSize),
+	}
+	if c.IceTransportPolicy != RTCIceTransportPolicy(Unknown) {
+		config["iceTransportPolicy"] = c.IceTransportPolicy.String()
+	}
+	if c.BundlePolicy != RTCBundlePolicy(Unknown) {
+		config["bundlePolicy"] = c.BundlePolicy.String()
+	}
+	if c.RtcpMuxPolicy != RTCRtcpMuxPolicy(Unknown) {
+		config["rtcpMuxPolicy"] = c.RtcpMuxPolicy.String()
+	}
+	if c.PeerIdentity != "" {
+		config["peerIdentity"] = c.PeerIdentity
+	}
+	if c.SdpSemantics != RTCSdpSemantics(Unknown) {
+		config["sdpSemantics"] = c.SdpSemantics.String()
+	}
+	return config, nil
+}
+
+// sessionDescriptionToValue returns the RTCSessionDescriptionInit of the
+// browser
+func sessionDescriptionToValue(desc RTCSessionDescription) map[string]interface{} {
+	return map[string]interface{}{
+		"type": desc.Type.String(),
+		"sdp":  desc.SDP,
+	}
+}
+
+// valueToSessionDescription returns the RTCSessionDescription of the
+// browser, nil if it is null
+func valueToSessionDescription(v js.Value) *RTCSessionDescription {
+	if v.IsNull() || v.IsUndefined() {
+		return nil
+	}
+	return &RTCSessionDescription{
+		Type: newRTCSdpType(v.Get("type").String()),
+		SDP:  v.Get("sdp").String(),
+	}
+}
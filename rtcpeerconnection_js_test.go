@@ -0,0 +1,53 @@
+//go:build js && wasm
+
+package webrtc
+
+import (
+	"testing"
+
+	"github.com/pions/webrtc/pkg/rtcerr"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNew_NoBrowserPeerConnection(t *testing.T) {
+	// Node has no RTCPeerConnection
+	_, err := New(RTCConfiguration{})
+	assert.Equal(t, &rtcerr.NotSupportedError{Err: ErrNoBrowserPeerConnection}, err)
+}
+
+func TestConfigurationToValue(t *testing.T) {
+	config, err := configurationToValue(RTCConfiguration{
+		IceServers: []RTCIceServer{
+			{URLs: []string{"stun:stun.l.google.com:19302"}},
+			{
+				URLs:           []string{"turn:turn.example.org"},
+				Username:       "unittest",
+				Credential:     RTCOAuthCredential{MacKey: "WmtzanB3ZW9peFhtdm42NzUzNG0=", AccessToken: "AAwg3kPHWPfvk9bDFL936wYvkoctMADzQ=="},
+				CredentialType: RTCIceCredentialTypeOauth,
+			},
+		},
+		IceTransportPolicy: RTCIceTransportPolicyRelay,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"iceServers": []interface{}{
+			map[string]interface{}{"urls": []interface{}{"stun:stun.l.google.com:19302"}},
+			map[string]interface{}{
+				"urls":     []interface{}{"turn:turn.example.org"},
+				"username": "unittest",
+				"credential": map[string]interface{}{
+					"macKey":      "WmtzanB3ZW9peFhtdm42NzUzNG0=",
+					"accessToken": "AAwg3kPHWPfvk9bDFL936wYvkoctMADzQ==",
+				},
+				"credentialType": "oauth",
+			},
+		},
+		"iceCandidatePoolSize": 0,
+		"iceTransportPolicy":   "relay",
+	}, config)
+
+	_, err = configurationToValue(RTCConfiguration{Certificates: []RTCCertificate{{}}})
+	assert.Equal(t, &rtcerr.NotSupportedError{Err: ErrBrowserCertificates}, err)
+	_, err = configurationToValue(RTCConfiguration{IceTransportPolicy: RTCIceTransportPolicyHostOnly})
+	assert.Equal(t, &rtcerr.NotSupportedError{Err: ErrBrowserHostOnly}, err)
+}
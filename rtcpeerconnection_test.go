@@ -1,16 +1,30 @@
+//go:build !js
+
 package webrtc
 
 import (
+	"bytes"
 	"crypto/ecdsa"
 	"crypto/elliptic"
 	"crypto/rand"
 	"crypto/x509"
+	"fmt"
 	"math/big"
+	"runtime"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
+	"github.com/pions/webrtc/internal/dtls"
+	"github.com/pions/webrtc/internal/sdp"
+	"github.com/pions/webrtc/pkg/datachannel"
+	"github.com/pions/webrtc/pkg/ice"
+	"github.com/pions/webrtc/pkg/logging"
 	"github.com/pions/webrtc/pkg/media"
+	"github.com/pions/webrtc/pkg/rtcp"
 	"github.com/pions/webrtc/pkg/rtp"
+	"github.com/pions/webrtc/pkg/vnet"
 
 	"github.com/pions/webrtc/pkg/rtcerr"
 	"github.com/stretchr/testify/assert"
@@ -202,6 +216,13 @@ func TestRTCPeerConnection_SetConfiguration(t *testing.T) {
 					RtcpMuxPolicy: RTCRtcpMuxPolicyNegotiate,
 				}
 			}, &rtcerr.InvalidModificationError{Err: ErrModifyingRtcpMuxPolicy}},
+			{func() (*RTCPeerConnection, error) {
+				return New(RTCConfiguration{})
+			}, func() RTCConfiguration {
+				return RTCConfiguration{
+					SdpSemantics: RTCSdpSemanticsPlanB,
+				}
+			}, &rtcerr.InvalidModificationError{Err: ErrModifyingSdpSemantics}},
 			// TODO Unittest for IceCandidatePoolSize cannot be done now needs pc.LocalDescription()
 			{func() (*RTCPeerConnection, error) {
 				return New(RTCConfiguration{})
@@ -254,6 +275,41 @@ func TestRTCPeerConnection_GetConfiguration(t *testing.T) {
 	assert.Equal(t, expected.IceCandidatePoolSize, actual.IceCandidatePoolSize)
 }
 
+func TestRTCPeerConnection_IceCandidatePool(t *testing.T) {
+	// Without a pool the candidates are gathered for the first offer
+	pc, err := New(RTCConfiguration{})
+	assert.Nil(t, err)
+	assert.Equal(t, RTCIceGatheringStateNew, pc.IceGatheringState)
+	_, err = pc.CreateOffer(nil)
+	assert.Nil(t, err)
+	assert.Equal(t, RTCIceGatheringStateComplete, pc.IceGatheringState)
+	assert.Nil(t, pc.Close())
+
+	// A pool gathers them right away
+	pc, err = New(RTCConfiguration{IceCandidatePoolSize: 1})
+	assert.Nil(t, err)
+	select {
+	case <-pc.gathered:
+	case <-time.After(time.Second):
+		assert.Fail(t, "candidate pool not gathered")
+	}
+	pc.RLock()
+	assert.Equal(t, RTCIceGatheringStateComplete, pc.IceGatheringState)
+	pc.RUnlock()
+	assert.Nil(t, pc.Close())
+
+	// Setting a pool before the first offer gathers them too
+	pc, err = New(RTCConfiguration{})
+	assert.Nil(t, err)
+	assert.Nil(t, pc.SetConfiguration(RTCConfiguration{IceCandidatePoolSize: 1}))
+	select {
+	case <-pc.gathered:
+	case <-time.After(time.Second):
+		assert.Fail(t, "candidate pool not gathered")
+	}
+	assert.Nil(t, pc.Close())
+}
+
 // TODO - This unittest needs to be completed when CreateDataChannel is complete
 // func TestRTCPeerConnection_CreateDataChannel(t *testing.T) {
 // 	pc, err := New(RTCConfiguration{})
@@ -287,7 +343,7 @@ func TestSetRemoteDescription(t *testing.T) {
 	testCases := []struct {
 		desc RTCSessionDescription
 	}{
-		{RTCSessionDescription{Type: RTCSdpTypeOffer, Sdp: minimalOffer}},
+		{RTCSessionDescription{Type: RTCSdpTypeOffer, SDP: minimalOffer}},
 	}
 
 	for i, testCase := range testCases {
@@ -341,4 +397,1465 @@ func TestRTCPeerConnection_NewRTCSampleTrack(t *testing.T) {
 	assert.NotPanics(t, func() {
 		track.Samples <- media.RTCSample{}
 	})
+
+	// The channel isn't read once the RTCPeerConnection is closed
+	assert.Nil(t, pc.Close())
+	for i := 0; i <= pc.trackQueueSize; i++ {
+		select {
+		case track.Samples <- media.RTCSample{}:
+		case <-track.Done():
+			return
+		}
+	}
+	t.Fatal("Done isn't closed")
+}
+
+const offerWithMids = `v=0
+o=- 7193157174393298413 2 IN IP4 127.0.0.1
+s=-
+t=0 0
+a=group:BUNDLE 0 1
+m=audio 9 UDP/TLS/RTP/SAVPF 111
+c=IN IP4 0.0.0.0
+a=ice-ufrag:OgYk
+a=ice-pwd:G0ka4ts7hRhMLNljuuXzqnOF
+a=fingerprint:sha-256 D7:06:10:DE:69:66:B1:53:0E:02:33:45:63:F8:AF:78:B2:C7:CE:AF:8E:FD:E5:13:20:50:74:93:CD:B5:C8:69
+a=setup:actpass
+a=mid:0
+a=sendonly
+a=rtpmap:111 opus/48000/2
+m=video 9 UDP/TLS/RTP/SAVPF 96
+c=IN IP4 0.0.0.0
+a=setup:actpass
+a=mid:1
+a=rtpmap:96 VP8/90000
+`
+
+func TestRTCPeerConnection_CreateAnswerWithTracks(t *testing.T) {
+	RegisterDefaultCodecs()
+
+	pc, err := New(RTCConfiguration{})
+	assert.Nil(t, err)
+
+	audioTrack, err := pc.NewRTCSampleTrack(DefaultPayloadTypeOpus, "audio", "pion-audio")
+	assert.Nil(t, err)
+	_, err = pc.AddTrack(audioTrack)
+	assert.Nil(t, err)
+
+	videoTrack, err := pc.NewRTCSampleTrack(DefaultPayloadTypeVP8, "video", "pion-video")
+	assert.Nil(t, err)
+	_, err = pc.AddTrack(videoTrack)
+	assert.Nil(t, err)
+
+	assert.Nil(t, pc.SetRemoteDescription(RTCSessionDescription{Type: RTCSdpTypeOffer, SDP: offerWithMids}))
+
+	answer, err := pc.CreateAnswer(nil)
+	assert.Nil(t, err)
+
+	sections := strings.Split(answer.SDP, "m=")
+	assert.Equal(t, 3, len(sections))
+	audio, video := sections[1], sections[2]
+
+	// The offerer only sends audio, our audio track can't be announced
+	assert.True(t, strings.Contains(audio, "a=mid:0\r\n"))
+	assert.True(t, strings.Contains(audio, "a=recvonly\r\n"))
+	assert.False(t, strings.Contains(audio, "a=ssrc:"))
+
+	// Without a direction attribute the offerer is sendrecv
+	assert.True(t, strings.Contains(video, "a=mid:1\r\n"))
+	assert.True(t, strings.Contains(video, "a=sendrecv\r\n"))
+	assert.True(t, strings.Contains(video, fmt.Sprintf("a=ssrc:%d msid:pion-video video\r\n", videoTrack.Ssrc)))
+}
+
+func TestRTCPeerConnection_CreateOfferWithTracks(t *testing.T) {
+	RegisterDefaultCodecs()
+
+	pc, err := New(RTCConfiguration{})
+	assert.Nil(t, err)
+
+	var tracks []*RTCTrack
+	for _, id := range []string{"camera", "screen"} {
+		track, err := pc.NewRTCSampleTrack(DefaultPayloadTypeVP8, id, "pion")
+		assert.Nil(t, err)
+		_, err = pc.AddTrack(track)
+		assert.Nil(t, err)
+		tracks = append(tracks, track)
+	}
+
+	offer, err := pc.CreateOffer(nil)
+	assert.Nil(t, err)
+	assert.True(t, strings.Contains(offer.SDP, "a=group:BUNDLE audio video video1\r\n"))
+
+	// Every track is sent in its own media section
+	sections := strings.Split(offer.SDP, "m=")
+	assert.Equal(t, 4, len(sections))
+	for i, mid := range []string{"video", "video1"} {
+		section := sections[i+2]
+		assert.True(t, strings.Contains(section, "a=mid:"+mid+"\r\n"))
+		assert.True(t, strings.Contains(section, "a=msid:pion "+tracks[i].ID+"\r\n"))
+		assert.True(t, strings.Contains(section, fmt.Sprintf("a=ssrc:%d msid:pion %s\r\n", tracks[i].Ssrc, tracks[i].ID)))
+		assert.Equal(t, 1, strings.Count(section, "a=msid:"))
+		assert.True(t, strings.Contains(section, "a=extmap:2 "+sdp.ExtMapURIAbsSendTime+"\r\n"))
+	}
+}
+
+func TestLocalDirection(t *testing.T) {
+	testCases := []struct {
+		weSend        bool
+		peerDirection RTCRtpTransceiverDirection
+		expected      RTCRtpTransceiverDirection
+	}{
+		{true, RTCRtpTransceiverDirectionSendrecv, RTCRtpTransceiverDirectionSendrecv},
+		{true, RTCRtpTransceiverDirectionRecvonly, RTCRtpTransceiverDirectionSendonly},
+		{false, RTCRtpTransceiverDirectionSendonly, RTCRtpTransceiverDirectionRecvonly},
+		{false, RTCRtpTransceiverDirectionRecvonly, RTCRtpTransceiverDirectionInactive},
+	}
+
+	for i, testCase := range testCases {
+		assert.Equal(t,
+			testCase.expected,
+			localDirection(testCase.weSend, testCase.peerDirection),
+			"testCase: %d %v", i, testCase,
+		)
+	}
+}
+
+func TestRTCPeerConnection_SessionVersion(t *testing.T) {
+	RegisterDefaultCodecs()
+
+	pc, err := New(RTCConfiguration{})
+	assert.Nil(t, err)
+
+	origin := func() sdp.Origin {
+		offer, err := pc.CreateOffer(nil)
+		assert.Nil(t, err)
+		return offer.parsed.Origin
+	}
+
+	first := origin()
+	assert.Equal(t, first, origin())
+
+	track, err := pc.NewRTCSampleTrack(DefaultPayloadTypeVP8, "video", "pion")
+	assert.Nil(t, err)
+	_, err = pc.AddTrack(track)
+	assert.Nil(t, err)
+
+	second := origin()
+	assert.Equal(t, first.SessionID, second.SessionID)
+	assert.Equal(t, first.SessionVersion+1, second.SessionVersion)
+}
+
+const planBOffer = `v=0
+o=- 7193157174393298413 2 IN IP4 127.0.0.1
+s=-
+t=0 0
+a=group:BUNDLE video
+m=video 9 UDP/TLS/RTP/SAVPF 96 97
+c=IN IP4 0.0.0.0
+a=ice-ufrag:OgYk
+a=ice-pwd:G0ka4ts7hRhMLNljuuXzqnOF
+a=fingerprint:sha-256 D7:06:10:DE:69:66:B1:53:0E:02:33:45:63:F8:AF:78:B2:C7:CE:AF:8E:FD:E5:13:20:50:74:93:CD:B5:C8:69
+a=setup:actpass
+a=mid:video
+a=sendrecv
+a=rtpmap:96 VP8/90000
+a=rtpmap:97 rtx/90000
+a=fmtp:97 apt=96
+a=ssrc-group:FID 1000 1001
+a=ssrc:1000 msid:stream camera
+a=ssrc:1001 msid:stream camera
+a=ssrc:2000 msid:stream screen
+`
+
+const remappedOffer = `v=0
+o=- 7193157174393298413 2 IN IP4 127.0.0.1
+s=-
+t=0 0
+a=group:BUNDLE 0
+m=video 9 UDP/TLS/RTP/SAVPF 120 121 122 123 124 125
+c=IN IP4 0.0.0.0
+a=ice-ufrag:OgYk
+a=ice-pwd:G0ka4ts7hRhMLNljuuXzqnOF
+a=fingerprint:sha-256 D7:06:10:DE:69:66:B1:53:0E:02:33:45:63:F8:AF:78:B2:C7:CE:AF:8E:FD:E5:13:20:50:74:93:CD:B5:C8:69
+a=setup:actpass
+a=mid:0
+a=sendrecv
+a=rtpmap:120 VP8/90000
+a=rtcp-fb:120 nack pli
+a=rtpmap:121 rtx/90000
+a=fmtp:121 apt=120
+a=rtpmap:122 H264/90000
+a=fmtp:122 level-asymmetry-allowed=1;packetization-mode=0;profile-level-id=42001f
+a=rtpmap:123 H264/90000
+a=fmtp:123 level-asymmetry-allowed=1;packetization-mode=1;profile-level-id=42e01f
+a=rtpmap:124 rtx/90000
+a=fmtp:124 apt=123
+a=rtpmap:125 AV1X/90000
+`
+
+func TestRTCPeerConnection_AnswerPayloadTypes(t *testing.T) {
+	RegisterDefaultCodecs()
+
+	pc, err := New(RTCConfiguration{})
+	assert.Nil(t, err)
+
+	assert.Nil(t, pc.SetRemoteDescription(RTCSessionDescription{Type: RTCSdpTypeOffer, SDP: remappedOffer}))
+	answer, err := pc.CreateAnswer(nil)
+	assert.Nil(t, err)
+
+	// The codecs both peers support are answered under the payload types
+	// and format parameters of the offer
+	sections := strings.Split(answer.SDP, "m=")
+	assert.Equal(t, 2, len(sections))
+	assert.True(t, strings.HasPrefix(sections[1], "video 9 UDP/TLS/RTP/SAVPF 120 123 121 124\r\n"))
+	assert.Contains(t, sections[1], "a=rtpmap:120 VP8/90000\r\n")
+	assert.Contains(t, sections[1], "a=rtcp-fb:120 nack pli\r\n")
+	assert.Contains(t, sections[1], "a=fmtp:121 apt=120\r\n")
+	assert.Contains(t, sections[1], "a=fmtp:123 level-asymmetry-allowed=1;packetization-mode=1;profile-level-id=42e01f\r\n")
+	assert.Contains(t, sections[1], "a=fmtp:124 apt=123\r\n")
+	assert.NotContains(t, sections[1], "a=rtpmap:122")
+	assert.NotContains(t, sections[1], "AV1X")
+	assert.NotContains(t, sections[1], "VP9")
+
+	// Media of the local codecs is sent under the answered payload types
+	assert.Equal(t, map[uint8]uint8{
+		DefaultPayloadTypeVP8:     120,
+		DefaultPayloadTypeVP8RTX:  121,
+		DefaultPayloadTypeH264:    123,
+		DefaultPayloadTypeH264RTX: 124,
+	}, pc.payloadTypes)
+	assert.Equal(t, uint8(123), pc.negotiatedPayloadType(DefaultPayloadTypeH264))
+	assert.Equal(t, uint8(DefaultPayloadTypeOpus), pc.negotiatedPayloadType(DefaultPayloadTypeOpus))
+
+	assert.Nil(t, pc.Close())
+}
+
+// gatewayOffer is offered the way SIP gateways do: without BUNDLE, with the
+// DTLS and ICE attributes at the session level, LF line endings and lines
+// out of order
+const gatewayOffer = "v=0\n" +
+	"o=- 1234 1234 IN IP4 192.0.2.10\n" +
+	"s=Asterisk\n" +
+	"c=IN IP4 192.0.2.10\n" +
+	"a=fingerprint:sha-256 D7:06:10:DE:69:66:B1:53:0E:02:33:45:63:F8:AF:78:B2:C7:CE:AF:8E:FD:E5:13:20:50:74:93:CD:B5:C8:69\n" +
+	"a=setup:actpass\n" +
+	"a=ice-ufrag:OgYk\n" +
+	"a=ice-pwd:G0ka4ts7hRhMLNljuuXzqnOF\n" +
+	"t=0 0\n" +
+	"m=audio 10000 UDP/TLS/RTP/SAVPF 111\n" +
+	"a=rtpmap:111 opus/48000/2\n" +
+	"a=rtcp-mux\n" +
+	"a=sendrecv\n" +
+	"a=mid:audio\n" +
+	"c=IN IP4 192.0.2.10\n" +
+	"\n" +
+	"m=video 10002 UDP/TLS/RTP/SAVPF 96\n" +
+	"a=rtpmap:96 VP8/90000\n" +
+	"a=rtcp-mux\n" +
+	"a=sendrecv\n" +
+	"a=mid:video\n"
+
+func TestRTCPeerConnection_GatewayOffer(t *testing.T) {
+	RegisterDefaultCodecs()
+
+	logs := &bytes.Buffer{}
+	s := SettingEngine{}
+	s.SetLoggerFactory(&logging.DefaultLoggerFactory{
+		Writer:          logs,
+		DefaultLogLevel: logging.LogLevelDisabled,
+		ScopeLevels:     map[string]logging.LogLevel{logging.ScopePC: logging.LogLevelWarn},
+	})
+
+	pc, err := NewAPI(WithSettingEngine(s)).NewRTCPeerConnection(RTCConfiguration{})
+	assert.Nil(t, err)
+
+	assert.Nil(t, pc.SetRemoteDescription(RTCSessionDescription{Type: RTCSdpTypeOffer, SDP: gatewayOffer}))
+	answer, err := pc.CreateAnswer(nil)
+	assert.Nil(t, err)
+	assert.Nil(t, pc.Close())
+
+	// Both sections are answered, without BUNDLE as the offer has none
+	assert.Equal(t, 2, strings.Count(answer.SDP, "m="))
+	assert.NotContains(t, answer.SDP, "a=group:BUNDLE")
+
+	// The repairs are reported
+	for _, warning := range []string{
+		"lines are not terminated with CRLF",
+		"dropped empty lines",
+		"reordered the lines of the session",
+		"reordered the lines of the media section `m=audio 10000 UDP/TLS/RTP/SAVPF 111`",
+	} {
+		assert.Contains(t, logs.String(), "Repaired remote description: "+warning)
+	}
+}
+
+func TestRTCPeerConnection_SdpSemantics(t *testing.T) {
+	RegisterDefaultCodecs()
+
+	t.Run("UnifiedPlan", func(t *testing.T) {
+		pc, err := New(RTCConfiguration{SdpSemantics: RTCSdpSemanticsUnifiedPlan})
+		assert.Nil(t, err)
+
+		dtlsRole := pc.dtlsRole
+		err = pc.SetRemoteDescription(RTCSessionDescription{Type: RTCSdpTypeOffer, SDP: planBOffer})
+		assert.EqualError(t, err, (&rtcerr.InvalidAccessError{Err: ErrIncorrectSdpSemantics}).Error())
+
+		// The rejected description left the state untouched
+		assert.Nil(t, pc.RemoteDescription())
+		assert.Equal(t, RTCSignalingStateStable, pc.SignalingState())
+		assert.Equal(t, dtlsRole, pc.dtlsRole)
+		assert.False(t, pc.remotePlanB)
+
+		offerer, err := New(RTCConfiguration{})
+		assert.Nil(t, err)
+		track, err := offerer.NewRTCSampleTrack(DefaultPayloadTypeVP8, "video", "pion")
+		assert.Nil(t, err)
+		_, err = offerer.AddTrack(track)
+		assert.Nil(t, err)
+		offer, err := offerer.CreateOffer(nil)
+		assert.Nil(t, err)
+		assert.Nil(t, pc.SetRemoteDescription(offer))
+
+		assert.Nil(t, offerer.Close())
+		assert.Nil(t, pc.Close())
+	})
+
+	t.Run("UnifiedPlanWithFallback", func(t *testing.T) {
+		pc, err := New(RTCConfiguration{})
+		assert.Nil(t, err)
+
+		var tracks []*RTCTrack
+		for _, id := range []string{"camera", "screen"} {
+			track, err := pc.NewRTCSampleTrack(DefaultPayloadTypeVP8, id, "pion")
+			assert.Nil(t, err)
+			_, err = pc.AddTrack(track)
+			assert.Nil(t, err)
+			tracks = append(tracks, track)
+		}
+
+		assert.Nil(t, pc.SetRemoteDescription(RTCSessionDescription{Type: RTCSdpTypeOffer, SDP: planBOffer}))
+
+		answer, err := pc.CreateAnswer(nil)
+		assert.Nil(t, err)
+
+		// Both tracks are answered in the single video section
+		sections := strings.Split(answer.SDP, "m=")
+		assert.Equal(t, 2, len(sections))
+		for _, track := range tracks {
+			assert.True(t, strings.Contains(sections[1], fmt.Sprintf("a=ssrc:%d msid:pion %s\r\n", track.Ssrc, track.ID)))
+		}
+		assert.False(t, strings.Contains(sections[1], "a=msid:"))
+	})
+
+	t.Run("PlanB", func(t *testing.T) {
+		pc, err := New(RTCConfiguration{SdpSemantics: RTCSdpSemanticsPlanB})
+		assert.Nil(t, err)
+
+		var tracks []*RTCTrack
+		for _, id := range []string{"camera", "screen"} {
+			track, err := pc.NewRTCSampleTrack(DefaultPayloadTypeVP8, id, "pion")
+			assert.Nil(t, err)
+			_, err = pc.AddTrack(track)
+			assert.Nil(t, err)
+			tracks = append(tracks, track)
+		}
+
+		offer, err := pc.CreateOffer(nil)
+		assert.Nil(t, err)
+		assert.True(t, strings.Contains(offer.SDP, "a=group:BUNDLE audio video\r\n"))
+
+		// Both tracks are offered in the single video section
+		sections := strings.Split(offer.SDP, "m=")
+		assert.Equal(t, 3, len(sections))
+		for _, track := range tracks {
+			assert.True(t, strings.Contains(sections[2], fmt.Sprintf("a=ssrc:%d msid:pion %s\r\n", track.Ssrc, track.ID)))
+		}
+	})
+}
+
+const offerWithoutSSRCs = `v=0
+o=- 4596489990601351948 2 IN IP4 127.0.0.1
+s=-
+t=0 0
+a=fingerprint:sha-256 D7:06:10:DE:69:66:B1:53:0E:02:33:45:63:F8:AF:78:B2:C7:CE:AF:8E:FD:E5:13:20:50:74:93:CD:B5:C8:69
+a=group:BUNDLE 0 1
+m=video 9 UDP/TLS/RTP/SAVPF 96
+c=IN IP4 0.0.0.0
+a=ice-ufrag:OgYk
+a=ice-pwd:G0ka4ts7hRhMLNljuuXzqnOF
+a=setup:actpass
+a=mid:0
+a=extmap:3 urn:ietf:params:rtp-hdrext:sdes:mid
+a=sendonly
+a=msid:stream camera
+a=rtpmap:96 VP8/90000
+a=rtcp-fb:96 nack pli
+m=video 9 UDP/TLS/RTP/SAVPF 96
+c=IN IP4 0.0.0.0
+a=setup:actpass
+a=mid:1
+a=extmap:3 urn:ietf:params:rtp-hdrext:sdes:mid
+a=sendonly
+a=msid:stream screen
+a=rtpmap:96 VP8/90000
+`
+
+func TestRTCPeerConnection_GenerateChannelWithoutSSRCs(t *testing.T) {
+	RegisterDefaultCodecs()
+
+	pc, err := New(RTCConfiguration{})
+	assert.Nil(t, err)
+	pc.OnTrack(func(*RTCTrack) {})
+
+	assert.Nil(t, pc.SetRemoteDescription(RTCSessionDescription{Type: RTCSdpTypeOffer, SDP: offerWithoutSSRCs}))
+
+	answer, err := pc.CreateAnswer(nil)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, strings.Count(answer.SDP, "a=extmap:3 "+sdp.ExtMapURIMID+"\r\n"))
+	// The offer didn't negotiate abs-send-time
+	assert.False(t, strings.Contains(answer.SDP, sdp.ExtMapURIAbsSendTime))
+
+	// The stream carrying the MID header extension is bound to its media section
+	bufferTransport, _ := pc.generateChannel(1000, 96, "1")
+	assert.NotNil(t, bufferTransport)
+	// Without it the first media section offering the payload type is used
+	bufferTransport, _ = pc.generateChannel(2000, 96, "")
+	assert.NotNil(t, bufferTransport)
+
+	transceivers := pc.GetTransceivers()
+	assert.Equal(t, 2, len(transceivers))
+	for i, expected := range []struct {
+		mid  string
+		ssrc uint32
+		id   string
+	}{
+		{"1", 1000, "screen"},
+		{"0", 2000, "camera"},
+	} {
+		track := transceivers[i].Receiver.Track
+		assert.Equal(t, expected.mid, transceivers[i].Mid)
+		assert.Equal(t, expected.ssrc, track.Ssrc)
+		assert.Equal(t, expected.id, track.ID)
+		assert.Equal(t, "stream", track.Label)
+	}
+
+	// Both tracks announce the same stream
+	streams := pc.GetRemoteStreams()
+	if assert.Equal(t, 1, len(streams)) {
+		assert.Equal(t, "stream", streams[0].ID)
+		assert.Equal(t, []*RTCTrack{transceivers[0].Receiver.Track, transceivers[1].Receiver.Track}, streams[0].GetTracks())
+	}
+}
+
+func TestRTCPeerConnection_Done(t *testing.T) {
+	isDone := func(pc *RTCPeerConnection) bool {
+		select {
+		case <-pc.Done():
+			return true
+		default:
+			return false
+		}
+	}
+
+	t.Run("Close", func(t *testing.T) {
+		pc, err := New(RTCConfiguration{})
+		assert.Nil(t, err)
+		assert.False(t, isDone(pc))
+
+		assert.Nil(t, pc.Close())
+		assert.True(t, isDone(pc))
+		assert.Nil(t, pc.Close())
+	})
+
+	t.Run("Failed", func(t *testing.T) {
+		pc, err := New(RTCConfiguration{})
+		assert.Nil(t, err)
+
+		pc.iceStateChange(ice.ConnectionStateFailed)
+		assert.True(t, isDone(pc))
+		assert.Equal(t, RTCPeerConnectionStateFailed, pc.ConnectionState())
+
+		// Closing a failed connection doesn't close the channel again
+		assert.Nil(t, pc.Close())
+	})
+
+	t.Run("DTLSFailure", func(t *testing.T) {
+		pc, err := New(RTCConfiguration{})
+		assert.Nil(t, err)
+
+		pc.dtlsFailure(ErrFingerprintMismatch)
+		assert.True(t, isDone(pc))
+		assert.Equal(t, RTCPeerConnectionStateFailed, pc.ConnectionState())
+		assert.Nil(t, pc.Close())
+	})
+}
+
+func TestRTCPeerConnection_Close(t *testing.T) {
+	t.Run("Concurrent", func(t *testing.T) {
+		pc, err := New(RTCConfiguration{})
+		assert.Nil(t, err)
+
+		var wg sync.WaitGroup
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				assert.Nil(t, pc.Close())
+			}()
+		}
+		wg.Wait()
+		assert.Equal(t, RTCPeerConnectionStateClosed, pc.ConnectionState())
+		assert.Equal(t, RTCSignalingStateClosed, pc.SignalingState())
+	})
+
+	t.Run("FromCallback", func(t *testing.T) {
+		RegisterDefaultCodecs()
+		goroutines := runtime.NumGoroutine()
+
+		offerer, err := New(RTCConfiguration{})
+		assert.Nil(t, err)
+		answerer, err := New(RTCConfiguration{})
+		assert.Nil(t, err)
+
+		track, err := offerer.NewRTCSampleTrack(DefaultPayloadTypeOpus, "audio", "pion")
+		assert.Nil(t, err)
+		_, err = offerer.AddTrack(track)
+		assert.Nil(t, err)
+
+		packetsClosed := make(chan struct{})
+		answerer.OnTrack(func(remote *RTCTrack) {
+			go func() {
+				for range remote.Packets {
+				}
+				close(packetsClosed)
+			}()
+		})
+
+		closed := make(chan struct{})
+		answerer.OnDataChannel(func(d *RTCDataChannel) {
+			d.Lock()
+			defer d.Unlock()
+			d.Onmessage = func(datachannel.Payload) {
+				assert.Nil(t, answerer.Close())
+				assert.Nil(t, offerer.Close())
+				close(closed)
+			}
+		})
+
+		d, err := offerer.CreateDataChannel("data", nil)
+		assert.Nil(t, err)
+		d.Lock()
+		d.OnOpen = func() {
+			go func() {
+				for i := 0; i < 10; i++ {
+					track.Samples <- media.RTCSample{Data: []byte{0x00}, Samples: 960}
+					time.Sleep(20 * time.Millisecond)
+				}
+				assert.Nil(t, d.Send(datachannel.PayloadString{Data: []byte("close")}))
+			}()
+		}
+		d.Unlock()
+
+		offer, err := offerer.CreateOffer(nil)
+		assert.Nil(t, err)
+		assert.Nil(t, answerer.SetRemoteDescription(offer))
+		answer, err := answerer.CreateAnswer(nil)
+		assert.Nil(t, err)
+		assert.Nil(t, offerer.SetRemoteDescription(answer))
+
+		select {
+		case <-closed:
+		case <-time.After(10 * time.Second):
+			t.Fatal("Close from a callback did not return")
+		}
+
+		select {
+		case <-packetsClosed:
+		case <-time.After(5 * time.Second):
+			t.Fatal("Packets of the remote track were not closed")
+		}
+
+		// The goroutines of both connections and of the track exit
+		deadline := time.Now().Add(5 * time.Second)
+		for runtime.NumGoroutine() > goroutines && time.Now().Before(deadline) {
+			time.Sleep(50 * time.Millisecond)
+		}
+		assert.True(t, runtime.NumGoroutine() <= goroutines, "goroutines leaked after Close")
+	})
+}
+
+func TestRTCPeerConnection_GracefulClose(t *testing.T) {
+	// The latency keeps the messages in flight when the shutdown starts
+	router := vnet.NewRouter()
+	router.SetLatency(10 * time.Millisecond)
+
+	newPeerConnection := func(ip string) *RTCPeerConnection {
+		n, err := router.NewNet(ip)
+		assert.Nil(t, err)
+
+		s := SettingEngine{}
+		s.SetNet(n)
+		pc, err := NewAPI(WithSettingEngine(s)).NewRTCPeerConnection(RTCConfiguration{})
+		assert.Nil(t, err)
+		return pc
+	}
+	offerer := newPeerConnection("1.2.3.4")
+	answerer := newPeerConnection("5.6.7.8")
+
+	const messages = 10
+	received := make(chan string, messages)
+	answerer.OnDataChannel(func(d *RTCDataChannel) {
+		d.Lock()
+		defer d.Unlock()
+		d.Onmessage = func(p datachannel.Payload) {
+			if payload, ok := p.(*datachannel.PayloadString); ok {
+				received <- string(payload.Data)
+			}
+		}
+	})
+
+	sctpClosed := make(chan struct{})
+	answerer.SCTP().Lock()
+	answerer.SCTP().OnStateChange = func(state RTCSctpTransportState) {
+		if state == RTCSctpTransportStateClosed {
+			close(sctpClosed)
+		}
+	}
+	answerer.SCTP().Unlock()
+
+	closed := make(chan error)
+	d, err := offerer.CreateDataChannel("data", nil)
+	assert.Nil(t, err)
+	d.Lock()
+	d.OnOpen = func() {
+		for i := 0; i < messages; i++ {
+			assert.Nil(t, d.Send(datachannel.PayloadString{Data: []byte(fmt.Sprintf("message %d", i))}))
+		}
+		go func() {
+			closed <- offerer.GracefulClose()
+		}()
+	}
+	d.Unlock()
+
+	offer, err := offerer.CreateOffer(nil)
+	assert.Nil(t, err)
+	assert.Nil(t, answerer.SetRemoteDescription(offer))
+	answer, err := answerer.CreateAnswer(nil)
+	assert.Nil(t, err)
+	assert.Nil(t, offerer.SetRemoteDescription(answer))
+
+	select {
+	case err := <-closed:
+		assert.Nil(t, err)
+	case <-time.After(10 * time.Second):
+		t.Fatal("timed out waiting for GracefulClose")
+	}
+	assert.Equal(t, RTCPeerConnectionStateClosed, offerer.ConnectionState())
+
+	// The remote peer got every message and a clean end of the association
+	for i := 0; i < messages; i++ {
+		select {
+		case message := <-received:
+			assert.Equal(t, fmt.Sprintf("message %d", i), message)
+		case <-time.After(time.Second):
+			t.Fatalf("message %d was not delivered", i)
+		}
+	}
+	select {
+	case <-sctpClosed:
+	case <-time.After(time.Second):
+		t.Fatal("SCTP transport of the remote peer was not closed")
+	}
+
+	assert.Nil(t, answerer.Close())
+	assert.Nil(t, offerer.GracefulClose())
+}
+
+func TestRTCPeerConnection_OnICEConnectionStateChange(t *testing.T) {
+	pc, err := New(RTCConfiguration{})
+	assert.Nil(t, err)
+
+	states := make(chan ice.ConnectionState, 3)
+	pc.OnICEConnectionStateChange(func(state ice.ConnectionState) {
+		states <- state
+	})
+
+	// The handlers run in the order of the state changes
+	pc.iceStateChange(ice.ConnectionStateChecking)
+	pc.iceStateChange(ice.ConnectionStateConnected)
+	for _, expected := range []ice.ConnectionState{ice.ConnectionStateChecking, ice.ConnectionStateConnected} {
+		select {
+		case state := <-states:
+			assert.Equal(t, expected, state)
+		case <-time.After(time.Second):
+			t.Fatalf("handler not called with %s", expected)
+		}
+	}
+
+	// Replacing the handler while states change is safe
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		pc.OnICEConnectionStateChange(func(ice.ConnectionState) {})
+	}()
+	pc.iceStateChange(ice.ConnectionStateDisconnected)
+	<-done
+
+	assert.Nil(t, pc.Close())
+}
+
+func TestRTCPeerConnection_StateAccessors(t *testing.T) {
+	offerer, err := New(RTCConfiguration{})
+	assert.Nil(t, err)
+	answerer, err := New(RTCConfiguration{})
+	assert.Nil(t, err)
+
+	assert.Nil(t, offerer.LocalDescription())
+	assert.Equal(t, RTCSignalingStateStable, offerer.SignalingState())
+	assert.True(t, offerer.ICEConnectionState() == ice.ConnectionStateNew)
+	assert.Equal(t, RTCPeerConnectionStateNew, offerer.ConnectionState())
+
+	// The state is read while the connection negotiates and connects
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	for _, pc := range []*RTCPeerConnection{offerer, answerer} {
+		wg.Add(1)
+		go func(pc *RTCPeerConnection) {
+			defer wg.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				pc.LocalDescription()
+				pc.RemoteDescription()
+				pc.CurrentLocalDescription()
+				pc.PendingLocalDescription()
+				pc.CurrentRemoteDescription()
+				pc.PendingRemoteDescription()
+				pc.SignalingState()
+				pc.ICEConnectionState()
+				pc.ConnectionState()
+			}
+		}(pc)
+	}
+
+	offer, err := offerer.CreateOffer(nil)
+	assert.Nil(t, err)
+	assert.Nil(t, answerer.SetRemoteDescription(offer))
+	answer, err := answerer.CreateAnswer(nil)
+	assert.Nil(t, err)
+	assert.Nil(t, offerer.SetRemoteDescription(answer))
+
+	// The descriptions are snapshots, modifying them has no effect
+	local := offerer.CurrentLocalDescription()
+	assert.Equal(t, offer.SDP, local.SDP)
+	local.SDP = ""
+	assert.Equal(t, offer.SDP, offerer.LocalDescription().SDP)
+	assert.Equal(t, answer.SDP, offerer.CurrentRemoteDescription().SDP)
+	assert.Nil(t, offerer.PendingRemoteDescription())
+
+	close(stop)
+	wg.Wait()
+	assert.Nil(t, offerer.Close())
+	assert.Nil(t, answerer.Close())
+	assert.Equal(t, RTCSignalingStateClosed, offerer.SignalingState())
+	assert.True(t, offerer.ICEConnectionState() == ice.ConnectionStateClosed)
+}
+
+func TestRTCPeerConnection_Transports(t *testing.T) {
+	RegisterDefaultCodecs()
+
+	pc, err := New(RTCConfiguration{})
+	assert.Nil(t, err)
+
+	dtlsTransport := pc.SCTP().Transport
+	iceTransport := dtlsTransport.Transport
+	assert.Equal(t, RTCDtlsTransportStateNew, dtlsTransport.State)
+	assert.Equal(t, RTCIceTransportStateNew, iceTransport.State)
+	assert.Empty(t, dtlsTransport.GetRemoteCertificates())
+
+	// Every media section is bundled on the same transport
+	track, err := pc.NewRTCSampleTrack(DefaultPayloadTypeOpus, "audio", "pion")
+	assert.Nil(t, err)
+	sender, err := pc.AddTrack(track)
+	assert.Nil(t, err)
+	assert.Equal(t, dtlsTransport, sender.Transport)
+	assert.Equal(t, dtlsTransport, pc.GetReceivers()[0].Transport)
+
+	var dtlsStates []RTCDtlsTransportState
+	dtlsTransport.Lock()
+	dtlsTransport.OnStateChange = func(state RTCDtlsTransportState) {
+		dtlsStates = append(dtlsStates, state)
+	}
+	dtlsTransport.Unlock()
+
+	var iceStates []RTCIceTransportState
+	iceTransport.Lock()
+	iceTransport.OnStateChange = func(state RTCIceTransportState) {
+		iceStates = append(iceStates, state)
+	}
+	iceTransport.Unlock()
+
+	pc.iceStateChange(ice.ConnectionStateChecking)
+	pc.dtlsTransport.stateChange(RTCDtlsTransportStateConnecting)
+	assert.Nil(t, pc.Close())
+
+	// Closed is final
+	pc.iceStateChange(ice.ConnectionStateConnected)
+	pc.dtlsStateChange(dtls.Established)
+
+	assert.Equal(t, []RTCDtlsTransportState{RTCDtlsTransportStateConnecting, RTCDtlsTransportStateClosed}, dtlsStates)
+	assert.Equal(t, []RTCIceTransportState{RTCIceTransportStateChecking, RTCIceTransportStateClosed}, iceStates)
+}
+
+func TestRTCPeerConnection_GetRemoteCapabilities(t *testing.T) {
+	RegisterDefaultCodecs()
+
+	pc, err := New(RTCConfiguration{})
+	assert.Nil(t, err)
+	pc.OnTrack(func(*RTCTrack) {})
+
+	assert.Equal(t, RTCRtpCapabilities{
+		Codecs:           []RTCRtpCodecCapability{},
+		HeaderExtensions: []RTCRtpHeaderExtensionCapability{},
+	}, pc.GetRemoteCapabilities(RTCRtpCodecTypeVideo))
+
+	assert.Nil(t, pc.SetRemoteDescription(RTCSessionDescription{Type: RTCSdpTypeOffer, SDP: offerWithoutSSRCs}))
+
+	assert.Equal(t, RTCRtpCapabilities{
+		Codecs: []RTCRtpCodecCapability{
+			{MimeType: "video/VP8", ClockRate: 90000, RTCPFeedback: []RTCRtcpFeedback{{Type: RTCRtcpFeedbackTypeNACK, Parameter: "pli"}}},
+		},
+		HeaderExtensions: []RTCRtpHeaderExtensionCapability{
+			{URI: sdp.ExtMapURIMID},
+		},
+	}, pc.GetRemoteCapabilities(RTCRtpCodecTypeVideo))
+	assert.Empty(t, pc.GetRemoteCapabilities(RTCRtpCodecTypeAudio).Codecs)
+}
+
+func TestRTCPeerConnection_RTCPFeedback(t *testing.T) {
+	RegisterDefaultCodecs()
+
+	pc, err := New(RTCConfiguration{})
+	assert.Nil(t, err)
+	pc.OnTrack(func(*RTCTrack) {})
+
+	offer, err := pc.CreateOffer(nil)
+	assert.Nil(t, err)
+	for _, feedback := range []string{"goog-remb", "ccm fir", "nack pli", "nack"} {
+		assert.Contains(t, offer.SDP, "a=rtcp-fb:96 "+feedback+"\r\n")
+	}
+	assert.NotContains(t, offer.SDP, "a=rtcp-fb:111 ")
+
+	// The answer only keeps the feedback of the offer
+	answerer, err := New(RTCConfiguration{})
+	assert.Nil(t, err)
+	answerer.OnTrack(func(*RTCTrack) {})
+	assert.Nil(t, answerer.SetRemoteDescription(RTCSessionDescription{Type: RTCSdpTypeOffer, SDP: offerWithoutSSRCs}))
+	answer, err := answerer.CreateAnswer(nil)
+	assert.Nil(t, err)
+	assert.Contains(t, answer.SDP, "a=rtcp-fb:96 nack pli\r\n")
+	assert.NotContains(t, answer.SDP, "a=rtcp-fb:96 goog-remb")
+
+	assert.Nil(t, pc.Close())
+	assert.Nil(t, answerer.Close())
+}
+
+func TestRTCPeerConnection_RTCPMuxPolicyNegotiate(t *testing.T) {
+	RegisterDefaultCodecs()
+	router := vnet.NewRouter()
+
+	newPeerConnection := func(ip string, tap *packetTap) *RTCPeerConnection {
+		n, err := router.NewNet(ip)
+		assert.Nil(t, err)
+
+		s := SettingEngine{}
+		s.SetNet(n)
+		s.SetInterceptor(tap)
+		pc, err := NewAPI(WithSettingEngine(s)).NewRTCPeerConnection(RTCConfiguration{
+			RtcpMuxPolicy: RTCRtcpMuxPolicyNegotiate,
+		})
+		assert.Nil(t, err)
+		pc.OnTrack(func(remote *RTCTrack) {
+			go func() {
+				for range remote.Packets {
+				}
+			}()
+		})
+		return pc
+	}
+
+	t.Run("Mux", func(t *testing.T) {
+		offerer := newPeerConnection("10.0.0.3", newPacketTap())
+		answerer := newPeerConnection("10.0.0.4", newPacketTap())
+
+		// RTCP candidates are offered, and released once RTCP is multiplexed
+		offer, err := offerer.CreateOffer(nil)
+		assert.Nil(t, err)
+		assert.Contains(t, offer.SDP, "a=rtcp-mux\r\n")
+		assert.Contains(t, offer.SDP, " 2 udp ")
+
+		assert.Nil(t, answerer.SetRemoteDescription(offer))
+		answer, err := answerer.CreateAnswer(nil)
+		assert.Nil(t, err)
+		assert.Contains(t, answer.SDP, "a=rtcp-mux\r\n")
+		assert.NotContains(t, answer.SDP, " 2 udp ")
+
+		assert.Nil(t, offerer.SetRemoteDescription(answer))
+		for _, c := range offerer.networkManager.LocalCandidates() {
+			assert.Equal(t, ice.ComponentRTP, c.GetBase().GetComponent())
+		}
+
+		assert.Nil(t, offerer.Close())
+		assert.Nil(t, answerer.Close())
+	})
+
+	t.Run("Separate", func(t *testing.T) {
+		offererTap, answererTap := newPacketTap(), newPacketTap()
+		offerer := newPeerConnection("10.0.0.5", offererTap)
+		answerer := newPeerConnection("10.0.0.6", answererTap)
+
+		track, err := offerer.NewRTCSampleTrack(DefaultPayloadTypeOpus, "audio", "pion")
+		assert.Nil(t, err)
+		_, err = offerer.AddTrack(track)
+		assert.Nil(t, err)
+
+		// The offer of a peer which doesn't multiplex RTCP with RTP
+		offer, err := offerer.CreateOffer(nil)
+		assert.Nil(t, err)
+		offer.SDP = strings.Replace(offer.SDP, "a=rtcp-mux\r\n", "", -1)
+		assert.Nil(t, answerer.SetRemoteDescription(offer))
+		answer, err := answerer.CreateAnswer(nil)
+		assert.Nil(t, err)
+		assert.NotContains(t, answer.SDP, "a=rtcp-mux")
+		assert.Contains(t, answer.SDP, " 2 udp ")
+		assert.Nil(t, offerer.SetRemoteDescription(answer))
+
+		done := make(chan struct{})
+		defer close(done)
+		go func() {
+			for {
+				select {
+				case <-done:
+					return
+				case track.Samples <- media.RTCSample{Data: []byte{0x00}, Samples: 960}:
+					time.Sleep(20 * time.Millisecond)
+				}
+			}
+		}()
+
+		// RTCP reaches the offerer once the RTCP component is connected
+		pli := &rtcp.PictureLossIndication{MediaSSRC: track.Ssrc}
+		raw, err := pli.Marshal()
+		assert.Nil(t, err)
+		timeout := time.After(20 * time.Second)
+	received:
+		for {
+			assert.Nil(t, answerer.SendRTCP(pli))
+			select {
+			case inbound := <-offererTap.inboundRTCP:
+				if bytes.Equal(raw, inbound) {
+					break received
+				}
+			case <-time.After(100 * time.Millisecond):
+			case <-timeout:
+				t.Fatal("timed out waiting for the RTCP packet")
+			}
+		}
+
+		// It was sent on the RTCP component
+		rtpLocal, _ := answerer.networkManager.IceAgent.SelectedPair()
+		rtcpLocal, rtcpRemote := answerer.networkManager.IceAgent.SelectedRTCPPair()
+		if assert.NotNil(t, rtcpLocal) && assert.NotNil(t, rtcpRemote) {
+			assert.NotEqual(t, rtpLocal.Port, rtcpLocal.Port)
+		}
+
+		assert.Nil(t, offerer.Close())
+		assert.Nil(t, answerer.Close())
+	})
+}
+
+func TestRTCPeerConnection_DataChannelOnly(t *testing.T) {
+	router := vnet.NewRouter()
+	newPeerConnection := func(ip string) *RTCPeerConnection {
+		n, err := router.NewNet(ip)
+		assert.Nil(t, err)
+
+		s := SettingEngine{}
+		s.SetNet(n)
+		pc, err := NewAPI(WithMediaEngine(nil), WithSettingEngine(s)).NewRTCPeerConnection(RTCConfiguration{})
+		assert.Nil(t, err)
+		return pc
+	}
+	offerer := newPeerConnection("10.0.0.7")
+	answerer := newPeerConnection("10.0.0.8")
+
+	// Without a MediaEngine tracks can't be created
+	_, err := offerer.NewRTCSampleTrack(DefaultPayloadTypeVP8, "video", "pion")
+	assert.NotNil(t, err)
+
+	// Nothing is offered until a data channel is created
+	offer, err := offerer.CreateOffer(nil)
+	assert.Nil(t, err)
+	assert.Empty(t, offer.parsed.MediaDescriptions)
+	assert.NotContains(t, offer.SDP, "a=group:BUNDLE")
+
+	received := make(chan string, 1)
+	answerer.OnDataChannel(func(d *RTCDataChannel) {
+		d.Lock()
+		defer d.Unlock()
+		d.Onmessage = func(p datachannel.Payload) {
+			if payload, ok := p.(*datachannel.PayloadString); ok {
+				received <- string(payload.Data)
+			}
+		}
+	})
+
+	d, err := offerer.CreateDataChannel("data", nil)
+	assert.Nil(t, err)
+	d.Lock()
+	d.OnOpen = func() {
+		assert.Nil(t, d.Send(datachannel.PayloadString{Data: []byte("hello")}))
+	}
+	d.Unlock()
+
+	offer, err = offerer.CreateOffer(nil)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(offer.parsed.MediaDescriptions))
+	assert.Equal(t, "application", offer.parsed.MediaDescriptions[0].MediaName.Media)
+	assert.Contains(t, offer.SDP, "a=group:BUNDLE data\r\n")
+
+	assert.Nil(t, answerer.SetRemoteDescription(offer))
+	answer, err := answerer.CreateAnswer(nil)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(answer.parsed.MediaDescriptions))
+	assert.Nil(t, offerer.SetRemoteDescription(answer))
+
+	select {
+	case message := <-received:
+		assert.Equal(t, "hello", message)
+	case <-time.After(10 * time.Second):
+		t.Fatal("timed out waiting for the message")
+	}
+
+	assert.Nil(t, offerer.Close())
+	assert.Nil(t, answerer.Close())
+}
+
+func TestRTCPeerConnection_GetStats(t *testing.T) {
+	router := vnet.NewRouter()
+	newPeerConnection := func(ip string) *RTCPeerConnection {
+		n, err := router.NewNet(ip)
+		assert.Nil(t, err)
+
+		s := SettingEngine{}
+		s.SetNet(n)
+		pc, err := NewAPI(WithMediaEngine(nil), WithSettingEngine(s)).NewRTCPeerConnection(RTCConfiguration{})
+		assert.Nil(t, err)
+		return pc
+	}
+	offerer := newPeerConnection("10.0.0.9")
+	answerer := newPeerConnection("10.0.0.10")
+
+	// Nothing is checked before the remote description is set
+	initial := offerer.GetStats()
+	assert.Len(t, initial, 1)
+	if transport, ok := initial[rtcTransportStatsID].(*RTCTransportStats); assert.True(t, ok) {
+		assert.Equal(t, RTCStatsTypeTransport, transport.Type)
+		assert.Equal(t, RTCDtlsTransportStateNew, transport.DTLSState)
+		assert.Empty(t, transport.SelectedCandidatePairID)
+	}
+
+	received := make(chan struct{})
+	answerer.OnDataChannel(func(d *RTCDataChannel) {
+		d.Lock()
+		defer d.Unlock()
+		d.Onmessage = func(datachannel.Payload) { close(received) }
+	})
+
+	d, err := offerer.CreateDataChannel("data", nil)
+	assert.Nil(t, err)
+	d.Lock()
+	d.OnOpen = func() {
+		assert.Nil(t, d.Send(datachannel.PayloadBinary{Data: []byte{0x01, 0x02, 0x03}}))
+	}
+	d.Unlock()
+
+	offer, err := offerer.CreateOffer(nil)
+	assert.Nil(t, err)
+	assert.Nil(t, answerer.SetRemoteDescription(offer))
+	answer, err := answerer.CreateAnswer(nil)
+	assert.Nil(t, err)
+	assert.Nil(t, offerer.SetRemoteDescription(answer))
+
+	select {
+	case <-received:
+	case <-time.After(10 * time.Second):
+		t.Fatal("timed out waiting for the message")
+	}
+
+	for _, pc := range []*RTCPeerConnection{offerer, answerer} {
+		var nominated *RTCIceCandidatePairStats
+		report := pc.GetStats()
+		for id, s := range report {
+			assert.Equal(t, id, s.GetBase().ID)
+			if stats, ok := s.(*RTCIceCandidatePairStats); ok && stats.Nominated {
+				assert.Equal(t, RTCStatsTypeCandidatePair, stats.Type)
+				nominated = stats
+			}
+		}
+		if !assert.NotNil(t, nominated) {
+			continue
+		}
+
+		assert.Equal(t, RTCStatsIceCandidatePairStateSucceeded, nominated.State)
+		assert.True(t, nominated.RequestsSent+nominated.RequestsReceived > 0)
+		assert.True(t, nominated.ResponsesSent+nominated.ResponsesReceived > 0)
+		assert.True(t, nominated.BytesSent > 0)
+		assert.True(t, nominated.BytesReceived > 0)
+
+		// IDs are stable across reports
+		assert.Contains(t, pc.GetStats(), nominated.ID)
+
+		transport, ok := report[rtcTransportStatsID].(*RTCTransportStats)
+		if assert.True(t, ok) {
+			assert.Equal(t, RTCDtlsTransportStateConnected, transport.DTLSState)
+			assert.Equal(t, nominated.ID, transport.SelectedCandidatePairID)
+			assert.True(t, transport.BytesSent >= nominated.BytesSent)
+			assert.True(t, transport.BytesReceived >= nominated.BytesReceived)
+			assert.Equal(t, uint64(0), transport.SRTPAuthFailures)
+		}
+	}
+
+	sent, ok := offerer.GetStats()[fmt.Sprintf("RTCDataChannel_%d", *d.ID)].(*RTCDataChannelStats)
+	if assert.True(t, ok) {
+		assert.Equal(t, RTCStatsTypeDataChannel, sent.Type)
+		assert.Equal(t, "data", sent.Label)
+		assert.Equal(t, RTCDataChannelStateOpen, sent.State)
+		assert.Equal(t, uint64(1), sent.MessagesSent)
+		assert.Equal(t, uint64(3), sent.BytesSent)
+		assert.Equal(t, uint64(0), sent.MessagesReceived)
+	}
+	delivered, ok := answerer.GetStats()[fmt.Sprintf("RTCDataChannel_%d", *d.ID)].(*RTCDataChannelStats)
+	if assert.True(t, ok) {
+		assert.Equal(t, uint64(1), delivered.MessagesReceived)
+		assert.Equal(t, uint64(3), delivered.BytesReceived)
+		assert.Equal(t, uint64(0), delivered.MessagesSent)
+	}
+
+	assert.Nil(t, offerer.Close())
+	assert.Nil(t, answerer.Close())
+}
+
+func TestRTCPeerConnection_OnStats(t *testing.T) {
+	pc, err := NewAPI(WithMediaEngine(nil)).NewRTCPeerConnection(RTCConfiguration{})
+	assert.Nil(t, err)
+	d, err := pc.CreateDataChannel("data", nil)
+	assert.Nil(t, err)
+
+	reports := make(chan RTCStatsReport)
+	pc.OnStats(10*time.Millisecond, func(report RTCStatsReport) { reports <- report })
+	for i := 0; i < 2; i++ {
+		select {
+		case report := <-reports:
+			assert.Contains(t, report, fmt.Sprintf("RTCDataChannel_%d", *d.ID))
+		case <-time.After(time.Second):
+			t.Fatal("timed out waiting for a report")
+		}
+	}
+
+	// Replacing the handler stops the previous one
+	replaced := make(chan RTCStatsReport, 1)
+	pc.OnStats(time.Hour, func(report RTCStatsReport) { replaced <- report })
+	select {
+	case <-reports:
+	case <-time.After(50 * time.Millisecond):
+	}
+	select {
+	case <-reports:
+		t.Fatal("report delivered after the handler was replaced")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	pc.OnStats(0, nil)
+	assert.Nil(t, pc.Close())
+	assert.Empty(t, replaced)
+}
+
+func TestRTCPeerConnection_BundlePolicy(t *testing.T) {
+	RegisterDefaultCodecs()
+
+	// The sections of the offer which are bundle-only, in order
+	offerBundleOnly := func(policy RTCBundlePolicy) []bool {
+		pc, err := New(RTCConfiguration{BundlePolicy: policy})
+		assert.Nil(t, err)
+		defer func() { assert.Nil(t, pc.Close()) }()
+		_, err = pc.CreateDataChannel("data", nil)
+		assert.Nil(t, err)
+
+		offer, err := pc.CreateOffer(nil)
+		assert.Nil(t, err)
+
+		var bundleOnly []bool
+		for _, m := range offer.parsed.MediaDescriptions {
+			isBundleOnly := false
+			for _, a := range m.Attributes {
+				if *a.String() == sdp.AttrKeyBundleOnly {
+					isBundleOnly = true
+					assert.Equal(t, 0, m.MediaName.Port.Value)
+				}
+				if isBundleOnly {
+					assert.False(t, strings.HasPrefix(*a.String(), sdp.AttrKeyCandidate))
+				}
+			}
+			bundleOnly = append(bundleOnly, isBundleOnly)
+		}
+		assert.Contains(t, offer.SDP, "a=group:BUNDLE audio video data\r\n")
+		return bundleOnly
+	}
+	assert.Equal(t, []bool{false, false, false}, offerBundleOnly(RTCBundlePolicyBalanced))
+	assert.Equal(t, []bool{false, false, false}, offerBundleOnly(RTCBundlePolicyMaxCompat))
+	assert.Equal(t, []bool{false, true, true}, offerBundleOnly(RTCBundlePolicyMaxBundle))
+
+	// The ports of the sections of the answer, rejected ones have port zero
+	offerer, err := New(RTCConfiguration{})
+	assert.Nil(t, err)
+	_, err = offerer.CreateDataChannel("data", nil)
+	assert.Nil(t, err)
+	offer, err := offerer.CreateOffer(nil)
+	assert.Nil(t, err)
+	assert.Nil(t, offerer.Close())
+
+	answerPorts := func(policy RTCBundlePolicy, offerSDP string) (ports []int, group string) {
+		pc, err := New(RTCConfiguration{BundlePolicy: policy})
+		assert.Nil(t, err)
+		defer func() { assert.Nil(t, pc.Close()) }()
+
+		assert.Nil(t, pc.SetRemoteDescription(RTCSessionDescription{Type: RTCSdpTypeOffer, SDP: offerSDP}))
+		answer, err := pc.CreateAnswer(nil)
+		assert.Nil(t, err)
+		for _, m := range answer.parsed.MediaDescriptions {
+			ports = append(ports, m.MediaName.Port.Value)
+		}
+		mids, _ := answer.parsed.GetBundleGroup()
+		return ports, strings.Join(mids, " ")
+	}
+
+	partial := strings.Replace(offer.SDP, "a=group:BUNDLE audio video data", "a=group:BUNDLE audio data", 1)
+	unbundled := strings.Replace(offer.SDP, "a=group:BUNDLE audio video data\r\n", "", 1)
+
+	testCases := []struct {
+		policy RTCBundlePolicy
+		offer  string
+		ports  []int
+		group  string
+	}{
+		{RTCBundlePolicyBalanced, offer.SDP, []int{9, 9, 9}, "audio video data"},
+		{RTCBundlePolicyMaxBundle, offer.SDP, []int{9, 9, 9}, "audio video data"},
+		{RTCBundlePolicyBalanced, partial, []int{9, 9, 9}, "audio data"},
+		{RTCBundlePolicyMaxBundle, partial, []int{9, 0, 9}, "audio data"},
+		{RTCBundlePolicyMaxCompat, offer.SDP, []int{9, 9, 9}, "audio video data"},
+		{RTCBundlePolicyMaxBundle, unbundled, []int{9, 0, 0}, ""},
+		// max-compat would need a transport per section the offer doesn't
+		// bundle, it rejects them like max-bundle does
+		{RTCBundlePolicyMaxCompat, partial, []int{9, 0, 9}, "audio data"},
+		{RTCBundlePolicyMaxCompat, unbundled, []int{9, 0, 0}, ""},
+	}
+
+	for i, testCase := range testCases {
+		ports, group := answerPorts(testCase.policy, testCase.offer)
+		assert.Equal(t, testCase.ports, ports, "testCase: %d", i)
+		assert.Equal(t, testCase.group, group, "testCase: %d", i)
+	}
+}
+
+func TestRTCPeerConnection_SCTPMaxChannels(t *testing.T) {
+	offerer, err := New(RTCConfiguration{})
+	assert.Nil(t, err)
+	assert.Nil(t, offerer.sctpTransport.MaxChannels)
+
+	s := SettingEngine{}
+	s.SetSCTPMaxChannels(4)
+	answerer, err := NewAPI(WithSettingEngine(s)).NewRTCPeerConnection(RTCConfiguration{})
+	assert.Nil(t, err)
+
+	// The odd stream leaves the ones picked for new data channels free
+	negotiated, id := true, uint16(1)
+	_, err = offerer.CreateDataChannel("data", &RTCDataChannelInit{Negotiated: &negotiated, ID: &id})
+	assert.Nil(t, err)
+
+	offer, err := offerer.CreateOffer(nil)
+	assert.Nil(t, err)
+	assert.Contains(t, offer.SDP, "sctpmap:5000 webrtc-datachannel 1024")
+
+	// The answer offers the lower stream count
+	assert.Nil(t, answerer.SetRemoteDescription(offer))
+	answer, err := answerer.CreateAnswer(nil)
+	assert.Nil(t, err)
+	assert.Contains(t, answer.SDP, "sctpmap:5000 webrtc-datachannel 4")
+
+	assert.Nil(t, offerer.SetRemoteDescription(answer))
+	for _, pc := range []*RTCPeerConnection{offerer, answerer} {
+		assert.Equal(t, uint16(4), *pc.sctpTransport.MaxChannels)
+
+		for i := 0; i < 2; i++ {
+			_, err = pc.CreateDataChannel("data", nil)
+			assert.Nil(t, err)
+		}
+		_, err = pc.CreateDataChannel("data", nil)
+		assert.Equal(t, &rtcerr.OperationError{Err: ErrMaxDataChannelID}, err)
+
+		negotiated := true
+		id := uint16(4)
+		_, err = pc.CreateDataChannel("data", &RTCDataChannelInit{Negotiated: &negotiated, ID: &id})
+		assert.Equal(t, &rtcerr.OperationError{Err: ErrMaxDataChannelID}, err)
+	}
+}
+
+func TestRTCPeerConnection_SCTPPortAttributes(t *testing.T) {
+	s := SettingEngine{}
+	s.SetSCTPPortAttributes(true)
+	offerer, err := NewAPI(WithSettingEngine(s)).NewRTCPeerConnection(RTCConfiguration{})
+	assert.Nil(t, err)
+	_, err = offerer.CreateDataChannel("data", nil)
+	assert.Nil(t, err)
+	answerer, err := New(RTCConfiguration{})
+	assert.Nil(t, err)
+
+	offer, err := offerer.CreateOffer(nil)
+	assert.Nil(t, err)
+	assert.Contains(t, offer.SDP, "m=application 9 UDP/DTLS/SCTP webrtc-datachannel\r\n")
+	assert.Contains(t, offer.SDP, "a=sctp-port:5000\r\n")
+	assert.Contains(t, offer.SDP, "a=max-message-size:65535\r\n")
+	assert.NotContains(t, offer.SDP, "sctpmap")
+
+	// The answer takes the format of the offer
+	assert.Nil(t, answerer.SetRemoteDescription(offer))
+	answer, err := answerer.CreateAnswer(nil)
+	assert.Nil(t, err)
+	assert.Contains(t, answer.SDP, "m=application 9 UDP/DTLS/SCTP webrtc-datachannel\r\n")
+	assert.Contains(t, answer.SDP, "a=sctp-port:5000\r\n")
+	assert.NotContains(t, answer.SDP, "sctpmap")
+
+	assert.Nil(t, offerer.SetRemoteDescription(answer))
+	for _, pc := range []*RTCPeerConnection{offerer, answerer} {
+		assert.Equal(t, float64(65535), pc.sctpTransport.MaxMessageSize)
+		assert.Equal(t, uint16(defaultSCTPMaxChannels), *pc.sctpTransport.MaxChannels)
+	}
+
+	// Legacy offers are answered in the legacy format
+	legacy, err := New(RTCConfiguration{})
+	assert.Nil(t, err)
+	_, err = legacy.CreateDataChannel("data", nil)
+	assert.Nil(t, err)
+	offer, err = legacy.CreateOffer(nil)
+	assert.Nil(t, err)
+
+	answerer, err = NewAPI(WithSettingEngine(s)).NewRTCPeerConnection(RTCConfiguration{})
+	assert.Nil(t, err)
+	assert.Nil(t, answerer.SetRemoteDescription(offer))
+	answer, err = answerer.CreateAnswer(nil)
+	assert.Nil(t, err)
+	assert.Contains(t, answer.SDP, "m=application 9 DTLS/SCTP 5000\r\n")
+	assert.Contains(t, answer.SDP, "a=sctpmap:5000 webrtc-datachannel 1024\r\n")
+	assert.NotContains(t, answer.SDP, "sctp-port")
+}
+
+func TestRTCPeerConnection_IceTransportPolicy(t *testing.T) {
+	router := vnet.NewRouter()
+	n, err := router.NewNet("10.0.0.7")
+	assert.Nil(t, err)
+	s := SettingEngine{}
+	s.SetNet(n)
+	assert.Nil(t, s.SetNAT1To1IPs([]string{"1.2.3.4"}, RTCIceCandidateTypeSrflx))
+	api := NewAPI(WithSettingEngine(s))
+
+	testCases := []struct {
+		policy        RTCIceTransportPolicy
+		expectedHost  int
+		expectedSrflx int
+		remoteSrflx   bool
+	}{
+		{RTCIceTransportPolicyAll, 1, 1, true},
+		{RTCIceTransportPolicyHostOnly, 1, 0, false},
+		// The virtual network has no TURN server to relay through
+		{RTCIceTransportPolicyRelay, 0, 0, true},
+	}
+
+	for i, testCase := range testCases {
+		pc, err := api.NewRTCPeerConnection(RTCConfiguration{IceTransportPolicy: testCase.policy})
+		assert.Nil(t, err)
+
+		var host, srflx int
+		for _, c := range pc.networkManager.IceAgent.LocalCandidates {
+			switch c.(type) {
+			case *ice.CandidateHost:
+				host++
+			case *ice.CandidateSrflx:
+				srflx++
+			}
+		}
+		assert.Equal(t, testCase.expectedHost, host, "testCase: %d", i)
+		assert.Equal(t, testCase.expectedSrflx, srflx, "testCase: %d", i)
+
+		pc.networkManager.IceAgent.AddRemoteCandidate(&ice.CandidateSrflx{CandidateBase: ice.CandidateBase{Address: "192.0.2.1", Port: 5000}})
+		assert.Equal(t, testCase.remoteSrflx, len(pc.networkManager.IceAgent.RemoteCandidates()) == 1, "testCase: %d", i)
+		assert.Nil(t, pc.Close())
+	}
+
+	// Changing the policy filters the candidates paired from then on
+	pc, err := api.NewRTCPeerConnection(RTCConfiguration{})
+	assert.Nil(t, err)
+	defer func() { assert.Nil(t, pc.Close()) }()
+	assert.Nil(t, pc.SetConfiguration(RTCConfiguration{IceTransportPolicy: RTCIceTransportPolicyHostOnly}))
+	assert.Equal(t, RTCIceTransportPolicyHostOnly, pc.GetConfiguration().IceTransportPolicy)
+	pc.networkManager.IceAgent.AddRemoteCandidate(&ice.CandidateSrflx{CandidateBase: ice.CandidateBase{Address: "192.0.2.1", Port: 5000}})
+	assert.Empty(t, pc.networkManager.IceAgent.RemoteCandidates())
+}
+
+func TestRTCPeerConnection_AddIceCandidate(t *testing.T) {
+	offerer, err := New(RTCConfiguration{})
+	assert.Nil(t, err)
+	_, err = offerer.CreateDataChannel("data", nil)
+	assert.Nil(t, err)
+	offer, err := offerer.CreateOffer(nil)
+	assert.Nil(t, err)
+
+	pc, err := New(RTCConfiguration{})
+	assert.Nil(t, err)
+
+	candidate := "candidate:1 1 udp 2130706431 192.0.2.1 5000 typ host"
+	mid := "data"
+	unknownMid := "unknown"
+	index := uint16(0)
+
+	assert.Equal(t,
+		&rtcerr.InvalidStateError{Err: ErrNoRemoteDescription},
+		pc.AddIceCandidate(RTCIceCandidateInit{Candidate: candidate, SDPMid: &mid}),
+	)
+	assert.Nil(t, pc.SetRemoteDescription(offer))
+	parsed := pc.RemoteDescription().parsed
+	ufrag, _ := parsed.GetICECredentials(parsed.MediaDescriptions[0])
+	unknownIndex := uint16(len(parsed.MediaDescriptions))
+
+	testCases := []struct {
+		candidate RTCIceCandidateInit
+		err       error
+	}{
+		{RTCIceCandidateInit{Candidate: candidate, SDPMid: &mid}, nil},
+		{RTCIceCandidateInit{Candidate: candidate, SDPMLineIndex: &index, UsernameFragment: ufrag}, nil},
+		{RTCIceCandidateInit{Candidate: candidate}, &rtcerr.TypeError{Err: ErrIceCandidateWithoutMedia}},
+		{RTCIceCandidateInit{Candidate: candidate, SDPMid: &unknownMid, SDPMLineIndex: &index}, &rtcerr.OperationError{Err: ErrIceCandidateMediaNotFound}},
+		{RTCIceCandidateInit{Candidate: candidate, SDPMLineIndex: &unknownIndex}, &rtcerr.OperationError{Err: ErrIceCandidateMediaNotFound}},
+		{RTCIceCandidateInit{Candidate: candidate, SDPMid: &mid, UsernameFragment: "other"}, &rtcerr.OperationError{Err: ErrIceCandidateUfrag}},
+		{RTCIceCandidateInit{Candidate: ""}, nil},
+		{RTCIceCandidateInit{Candidate: "a=end-of-candidates", SDPMid: &mid}, nil},
+	}
+
+	for i, testCase := range testCases {
+		assert.Equal(t, testCase.err, pc.AddIceCandidate(testCase.candidate), "testCase: %d", i)
+	}
+	assert.NotNil(t, pc.AddIceCandidate(RTCIceCandidateInit{Candidate: "candidate:invalid", SDPMid: &mid}))
 }
@@ -0,0 +1,136 @@
+//go:build !js
+
+package webrtc
+
+import (
+	"sync"
+
+	"github.com/pions/webrtc/pkg/ice"
+)
+
+// RTCPeerConnectionEvent is an event delivered by RTCPeerConnection.Events,
+// it is one of RTCIceConnectionStateChangeEvent, RTCTrackEvent,
+// RTCDataChannelEvent or RTCErrorEvent.
+type RTCPeerConnectionEvent interface {
+	isRTCPeerConnectionEvent()
+}
+
+// RTCIceConnectionStateChangeEvent is emitted when the ICE connection state
+// changes, like OnICEConnectionStateChange.
+type RTCIceConnectionStateChangeEvent struct {
+	State ice.ConnectionState
+}
+
+// RTCTrackEvent is emitted when a remote track arrives, like OnTrack.
+// https://www.w3.org/TR/webrtc/#rtctrackevent
+type RTCTrackEvent struct {
+	Track    *RTCTrack
+	Receiver *RTCRtpReceiver
+
+	// Streams are the streams the track belongs to, empty if the remote
+	// peer didn't announce one
+	Streams []*RTCMediaStream
+}
+
+// RTCDataChannelEvent is emitted when the remote peer opens a data channel,
+// like OnDataChannel.
+// https://www.w3.org/TR/webrtc/#rtcdatachannelevent
+type RTCDataChannelEvent struct {
+	Channel *RTCDataChannel
+}
+
+// RTCErrorEvent is emitted for failures which happen in the background and
+// can't be returned to the caller, such as failing to send a packet.
+type RTCErrorEvent struct {
+	Err error
+}
+
+func (RTCIceConnectionStateChangeEvent) isRTCPeerConnectionEvent() {}
+func (RTCTrackEvent) isRTCPeerConnectionEvent()                    {}
+func (RTCDataChannelEvent) isRTCPeerConnectionEvent()              {}
+func (RTCErrorEvent) isRTCPeerConnectionEvent()                    {}
+
+// rtcEventQueue delivers events to the channel returned by Events in the
+// order they were emitted, without ever blocking the emitter. Events are
+// discarded until the queue is started.
+type rtcEventQueue struct {
+	sync.Mutex
+	cond *sync.Cond
+
+	started bool
+	closed  bool
+	pending []RTCPeerConnectionEvent
+	events  chan RTCPeerConnectionEvent
+}
+
+func newRTCEventQueue() *rtcEventQueue {
+	q := &rtcEventQueue{
+		events: make(chan RTCPeerConnectionEvent),
+	}
+	q.cond = sync.NewCond(q)
+	return q
+}
+
+// start begins the delivery of events, it is safe to call multiple times
+func (q *rtcEventQueue) start() <-chan RTCPeerConnectionEvent {
+	q.Lock()
+	defer q.Unlock()
+
+	if !q.started && !q.closed {
+		q.started = true
+		workers.schedule(q.run)
+	}
+	return q.events
+}
+
+func (q *rtcEventQueue) isStarted() bool {
+	q.Lock()
+	defer q.Unlock()
+	return q.started
+}
+
+func (q *rtcEventQueue) push(e RTCPeerConnectionEvent) {
+	q.Lock()
+	defer q.Unlock()
+
+	if !q.started || q.closed {
+		return
+	}
+	q.pending = append(q.pending, e)
+	q.cond.Signal()
+}
+
+// close closes the channel once the pending events have been delivered
+func (q *rtcEventQueue) close() {
+	q.Lock()
+	defer q.Unlock()
+
+	if q.closed {
+		return
+	}
+	q.closed = true
+	if !q.started {
+		close(q.events)
+		return
+	}
+	q.cond.Signal()
+}
+
+func (q *rtcEventQueue) run() {
+	for {
+		q.Lock()
+		for len(q.pending) == 0 && !q.closed {
+			q.cond.Wait()
+		}
+		if len(q.pending) == 0 {
+			q.Unlock()
+			close(q.events)
+			return
+		}
+		e := q.pending[0]
+		q.pending = q.pending[1:]
+		q.Unlock()
+
+		q.events <- e
+	}
+}
@@ -0,0 +1,42 @@
+//go:build !js
+
+package webrtc
+
+import (
+	"testing"
+
+	"github.com/pions/webrtc/pkg/ice"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRTCPeerConnection_Events(t *testing.T) {
+	pc, err := New(RTCConfiguration{})
+	assert.Nil(t, err)
+
+	// Nothing is recorded before Events is called
+	pc.iceStateChange(ice.ConnectionStateChecking)
+
+	events := pc.Events()
+	assert.Equal(t, events, pc.Events())
+
+	pc.iceStateChange(ice.ConnectionStateConnected)
+	pc.events.push(RTCErrorEvent{Err: ErrUnknownType})
+	assert.Nil(t, pc.Close())
+
+	var received []RTCPeerConnectionEvent
+	for e := range events {
+		received = append(received, e)
+	}
+	assert.Equal(t, []RTCPeerConnectionEvent{
+		RTCIceConnectionStateChangeEvent{State: ice.ConnectionStateConnected},
+		RTCErrorEvent{Err: ErrUnknownType},
+		RTCIceConnectionStateChangeEvent{State: ice.ConnectionStateClosed},
+	}, received)
+
+	// The channel of a closed RTCPeerConnection is closed right away
+	pc, err = New(RTCConfiguration{})
+	assert.Nil(t, err)
+	assert.Nil(t, pc.Close())
+	_, ok := <-pc.Events()
+	assert.False(t, ok)
+}
@@ -0,0 +1,64 @@
+package webrtc
+
+import (
+	"strings"
+)
+
+// RTCRtcpFeedback signals the RTCP feedback a codec supports, announced with
+// a=rtcp-fb. The remote peer only sends the feedback negotiated for a codec.
+// https://tools.ietf.org/html/rfc4585#section-4.2
+type RTCRtcpFeedback struct {
+	// Type is the type of feedback, like nack or ccm
+	Type string
+
+	// Parameter refines the type, like pli for nack or fir for ccm, it is
+	// empty for the generic feedback of the type
+	Parameter string
+}
+
+// The types of RTCP feedback
+const (
+	// RTCRtcpFeedbackTypeNACK requests retransmissions with generic NACKs,
+	// or a keyframe with the pli parameter
+	RTCRtcpFeedbackTypeNACK = "nack"
+
+	// RTCRtcpFeedbackTypeCCM carries codec control messages, like requesting
+	// a keyframe with the fir parameter
+	RTCRtcpFeedbackTypeCCM = "ccm"
+
+	// RTCRtcpFeedbackTypeGoogREMB carries the bandwidth estimates of the
+	// receiver, delivered with RTCRtpSender.OnTargetBitrate
+	RTCRtcpFeedbackTypeGoogREMB = "goog-remb"
+
+	// RTCRtcpFeedbackTypeTransportCC carries transport wide congestion
+	// control feedback
+	RTCRtcpFeedbackTypeTransportCC = "transport-cc"
+)
+
+// newRTCRtcpFeedback parses the value of an a=rtcp-fb attribute following
+// the payload type
+func newRTCRtcpFeedback(raw string) RTCRtcpFeedback {
+	split := strings.SplitN(raw, " ", 2)
+	feedback := RTCRtcpFeedback{Type: split[0]}
+	if len(split) == 2 {
+		feedback.Parameter = split[1]
+	}
+	return feedback
+}
+
+func (f RTCRtcpFeedback) String() string {
+	if f.Parameter == "" {
+		return f.Type
+	}
+	return f.Type + " " + f.Parameter
+}
+
+// hasRTCPFeedback reports whether the feedback is one of the list
+func hasRTCPFeedback(list []RTCRtcpFeedback, feedback RTCRtcpFeedback) bool {
+	for _, f := range list {
+		if strings.EqualFold(f.Type, feedback.Type) && strings.EqualFold(f.Parameter, feedback.Parameter) {
+			return true
+		}
+	}
+	return false
+}
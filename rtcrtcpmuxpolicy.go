@@ -8,7 +8,10 @@ const (
 	// RTCRtcpMuxPolicyNegotiate indicates to gather ICE candidates for both
 	// RTP and RTCP candidates. If the remote-endpoint is capable of
 	// multiplexing RTCP, multiplex RTCP on the RTP candidates. If it is not,
-	// use both the RTP and RTCP candidates separately.
+	// use both the RTP and RTCP candidates separately, the RTCP component
+	// runs a DTLS handshake of its own. RTCP is sent to the address announced
+	// by the a=rtcp line of the remote description until ICE connects the
+	// RTCP component.
 	RTCRtcpMuxPolicyNegotiate RTCRtcpMuxPolicy = iota + 1
 
 	// RTCRtcpMuxPolicyRequire indicates to gather ICE candidates only for
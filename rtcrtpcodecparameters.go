@@ -0,0 +1,10 @@
+//go:build !js
+
+package webrtc
+
+// RTCRtpCodecParameters describes a codec negotiated for an RTCRtpSender or
+// RTCRtpReceiver
+type RTCRtpCodecParameters struct {
+	RTCRtpCodecCapability
+	PayloadType uint8
+}
@@ -0,0 +1,24 @@
+package webrtc
+
+// RTCRtpEncodingParameters provides information relating to the encoding of a
+// single layer sent by an RTCRtpSender. Multiple encodings on the same sender
+// are negotiated as simulcast layers, each identified by its RID.
+type RTCRtpEncodingParameters struct {
+	// RID is the restriction identifier of the layer, signaled with the
+	// a=rid and a=simulcast attributes as described in
+	// https://tools.ietf.org/html/draft-ietf-mmusic-sdp-simulcast-13
+	RID string
+
+	// SSRC is the synchronization source used by the RTP packets of this
+	// layer. A random value is generated when it is left as zero.
+	SSRC uint32
+
+	// Active indicates the layer is being sent, packets of an inactive
+	// layer are discarded. It can be changed with SetParameters, layers are
+	// always active when added.
+	Active bool
+
+	// MaxBitrate is the maximum bitrate of the layer in bits per second,
+	// packets exceeding it are discarded. Zero means unlimited.
+	MaxBitrate uint64
+}
@@ -0,0 +1,8 @@
+package webrtc
+
+// RTCRtpHeaderExtensionParameters describes an RFC 5285 RTP header extension
+// negotiated for an RTCRtpSender or RTCRtpReceiver
+type RTCRtpHeaderExtensionParameters struct {
+	URI string
+	ID  int
+}
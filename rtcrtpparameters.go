@@ -0,0 +1,28 @@
+//go:build !js
+
+package webrtc
+
+// RTCRtpParameters contains the parameters shared by senders and receivers
+type RTCRtpParameters struct {
+	HeaderExtensions []RTCRtpHeaderExtensionParameters
+	Codecs           []RTCRtpCodecParameters
+}
+
+// RTCRtpSendParameters contains the parameters of an RTCRtpSender, they are
+// retrieved with GetParameters and applied with SetParameters
+type RTCRtpSendParameters struct {
+	RTCRtpParameters
+
+	// TransactionID identifies the GetParameters call the parameters were
+	// returned by, SetParameters only accepts the last one
+	TransactionID string
+
+	// Encodings contains a single encoding unless the sender has simulcast
+	// layers, in which case there is one encoding per layer
+	Encodings []RTCRtpEncodingParameters
+}
+
+// RTCRtpReceiveParameters contains the parameters of an RTCRtpReceiver
+type RTCRtpReceiveParameters struct {
+	RTCRtpParameters
+}
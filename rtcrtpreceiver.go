@@ -1,14 +1,141 @@
+//go:build !js
+
 package webrtc
 
+import (
+	"sync"
+	"time"
+
+	"github.com/pions/webrtc/pkg/rtcp"
+	"github.com/pions/webrtc/pkg/rtp"
+)
+
 // RTCRtpReceiver allows an application to inspect the receipt of a RTCTrack
 type RTCRtpReceiver struct {
+	sync.RWMutex
+
 	Track *RTCTrack
 	// receiverTrack *RTCTrack
-	// receiverTransport
 	// receiverRtcpTransport
+
+	// Transport is the DTLS transport the RTP and RTCP packets of the Track
+	// are received over.
+	Transport *RTCDtlsTransport
+
+	onRTPPacketHandler    func(*rtp.Packet)
+	onSenderReportHandler func(*rtcp.SenderReport)
+
+	// Deprecated: Will be removed when networkManager is deprecated.
+	rtcPeerConnection *RTCPeerConnection
+
+	// remoteOutbound holds what the remote peer reported about sending the
+	// Track
+	remoteOutbound rtcRemoteOutbound
+}
+
+// rtcRemoteOutbound is the last sender report of the remote peer about the
+// Track, and the round trip measured from the DLRR reports answering our
+// receiver reference times
+type rtcRemoteOutbound struct {
+	report     *rtcp.SenderReport
+	receivedAt time.Time
+
+	roundTripTime             time.Duration
+	totalRoundTripTime        time.Duration
+	roundTripTimeMeasurements uint64
+}
+
+func newRTCRtpReceiver(track *RTCTrack) *RTCRtpReceiver {
+	r := &RTCRtpReceiver{
+		Track: track,
+	}
+	return r
+}
+
+// GetRTCRtpReceiverCapabilities returns the codecs and RTP header extensions
+// of the kind RTCPeerConnections using the DefaultMediaEngine can receive
+// https://www.w3.org/TR/webrtc/#dom-rtcrtpreceiver-getcapabilities
+func GetRTCRtpReceiverCapabilities(kind RTCRtpCodecType) RTCRtpCapabilities {
+	return DefaultMediaEngine.GetCapabilities(kind)
 }
 
-// TODO: receiving side
-// func newRTCRtpReceiver(kind, id string) {
-//
-// }
+// GetParameters returns the codecs negotiated for the Track
+// https://www.w3.org/TR/webrtc/#dom-rtcrtpreceiver-getparameters
+func (r *RTCRtpReceiver) GetParameters() RTCRtpReceiveParameters {
+	params := RTCRtpReceiveParameters{
+		RTCRtpParameters: RTCRtpParameters{
+			HeaderExtensions: []RTCRtpHeaderExtensionParameters{},
+		},
+	}
+	if r.rtcPeerConnection != nil && r.Track != nil {
+		params.Codecs = r.rtcPeerConnection.negotiatedCodecs(r.Track.Kind)
+	}
+	return params
+}
+
+// OnRTPPacket sets an event handler which is invoked for every RTP packet
+// received for the Track, after it has been decrypted. The packet is shared
+// with the rest of the receive path, so it must not be modified or retained
+// after the handler returns.
+func (r *RTCRtpReceiver) OnRTPPacket(f func(*rtp.Packet)) {
+	r.Lock()
+	defer r.Unlock()
+	r.onRTPPacketHandler = f
+}
+
+func (r *RTCRtpReceiver) doOnRTPPacket(p *rtp.Packet) {
+	r.RLock()
+	onRTPPacket := r.onRTPPacketHandler
+	r.RUnlock()
+	if onRTPPacket != nil {
+		onRTPPacket(p)
+	}
+}
+
+// OnSenderReport sets an event handler which is invoked for every RTCP sender
+// report received for the Track. The reports map the RTP timestamps of the
+// Track to the wallclock of the remote peer, which synchronizes Tracks sent by
+// the same peer.
+func (r *RTCRtpReceiver) OnSenderReport(f func(*rtcp.SenderReport)) {
+	r.Lock()
+	defer r.Unlock()
+	r.onSenderReportHandler = f
+}
+
+func (r *RTCRtpReceiver) doOnSenderReport(sr *rtcp.SenderReport) {
+	r.RLock()
+	onSenderReport := r.onSenderReportHandler
+	r.RUnlock()
+	if onSenderReport != nil {
+		onSenderReport(sr)
+	}
+}
+
+// handleSenderReport records the sender report of the remote peer
+func (r *RTCRtpReceiver) handleSenderReport(sr *rtcp.SenderReport, now time.Time) {
+	r.Lock()
+	defer r.Unlock()
+
+	r.remoteOutbound.report = sr
+	r.remoteOutbound.receivedAt = now
+}
+
+// handleDLRR records the round trip measured from the DLRR report answering
+// our last receiver reference time, RFC 3611 Section 4.5
+func (r *RTCRtpReceiver) handleDLRR(report rtcp.DLRRReport, now time.Time) {
+	if report.LastRR == 0 {
+		return
+	}
+	// In units of 1/65536 seconds, negative with skewed clocks
+	rtt := int32(ntpMiddle(now) - report.LastRR - report.DLRR)
+	if rtt < 0 {
+		return
+	}
+
+	r.Lock()
+	defer r.Unlock()
+	o := &r.remoteOutbound
+	o.roundTripTime = time.Duration(rtt) * time.Second / 65536
+	o.totalRoundTripTime += o.roundTripTime
+	o.roundTripTimeMeasurements++
+}
@@ -0,0 +1,116 @@
+//go:build !js
+
+package webrtc
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pions/webrtc/pkg/rtcp"
+	"github.com/pions/webrtc/pkg/rtp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRTCRtpReceiver_OnRTPPacket(t *testing.T) {
+	RegisterDefaultCodecs()
+
+	pc, err := New(RTCConfiguration{})
+	assert.Nil(t, err)
+
+	codec, err := pc.mediaEngine.getCodec(DefaultPayloadTypeOpus)
+	assert.Nil(t, err)
+
+	pc.Lock()
+	pc.addRTCRtpReceiver(newRTCRtpReceiver(&RTCTrack{Kind: codec.Type, Ssrc: 3333, Codec: codec}), "")
+	pc.Unlock()
+
+	receivers := pc.GetReceivers()
+	assert.Equal(t, 1, len(receivers))
+	assert.Equal(t, RTCRtpTransceiverDirectionRecvonly, pc.GetTransceivers()[0].Direction)
+
+	var received []*rtp.Packet
+	receivers[0].OnRTPPacket(func(p *rtp.Packet) {
+		received = append(received, p)
+	})
+
+	pc.observeInboundRTP(&rtp.Packet{SSRC: 3333})
+	pc.observeInboundRTP(&rtp.Packet{SSRC: 4444})
+	assert.Equal(t, 1, len(received))
+	assert.Equal(t, uint32(3333), received[0].SSRC)
+}
+
+func TestRTCRtpReceiver_OnSenderReport(t *testing.T) {
+	RegisterDefaultCodecs()
+
+	pc, err := New(RTCConfiguration{})
+	assert.Nil(t, err)
+
+	codec, err := pc.mediaEngine.getCodec(DefaultPayloadTypeOpus)
+	assert.Nil(t, err)
+
+	pc.Lock()
+	pc.addRTCRtpReceiver(newRTCRtpReceiver(&RTCTrack{Kind: codec.Type, Ssrc: 3333, Codec: codec}), "")
+	pc.Unlock()
+
+	var received []*rtcp.SenderReport
+	pc.GetReceivers()[0].OnSenderReport(func(sr *rtcp.SenderReport) {
+		received = append(received, sr)
+	})
+
+	pc.handleRTCP(mustMarshal(t, &rtcp.SenderReport{SSRC: 3333, NTPTime: 0xda8bd1fcdddda05a, RTPTime: 0xaaf4edd5}))
+	pc.handleRTCP(mustMarshal(t, &rtcp.SenderReport{SSRC: 4444}))
+	assert.Equal(t, 1, len(received))
+	assert.Equal(t, uint64(0xda8bd1fcdddda05a), received[0].NTPTime)
+	assert.Equal(t, uint32(0xaaf4edd5), received[0].RTPTime)
+}
+
+func TestRTCRtpReceiver_RemoteOutboundStats(t *testing.T) {
+	RegisterDefaultCodecs()
+
+	pc, err := New(RTCConfiguration{})
+	assert.Nil(t, err)
+
+	codec, err := pc.mediaEngine.getCodec(DefaultPayloadTypeOpus)
+	assert.Nil(t, err)
+
+	pc.Lock()
+	pc.addRTCRtpReceiver(newRTCRtpReceiver(&RTCTrack{Kind: codec.Type, Ssrc: 3333, Codec: codec}), "")
+	pc.Unlock()
+
+	// Nothing is reported until a sender report arrives
+	for _, stats := range pc.GetStats() {
+		assert.NotEqual(t, RTCStatsTypeRemoteOutboundRTP, stats.GetBase().Type)
+	}
+
+	sentAt := time.Now().Add(-time.Second).Truncate(time.Millisecond)
+	pc.handleRTCP(mustMarshal(t, &rtcp.SenderReport{SSRC: 3333, NTPTime: ntpTime(sentAt), PacketCount: 50, OctetCount: 5000}))
+
+	// A DLRR report answering a receiver reference time we sent 300ms ago,
+	// which the remote peer held for 100ms
+	pc.handleRTCP(mustMarshal(t, &rtcp.ExtendedReport{
+		SenderSSRC: 5555,
+		DLRR: []rtcp.DLRRReport{{
+			SSRC:   pc.networkManager.RTCPSSRC(),
+			LastRR: ntpMiddle(time.Now().Add(-300 * time.Millisecond)),
+			DLRR:   65536 / 10,
+		}},
+	}))
+	// Reports answering other receivers are ignored
+	pc.handleRTCP(mustMarshal(t, &rtcp.ExtendedReport{
+		SenderSSRC: 5555,
+		DLRR:       []rtcp.DLRRReport{{SSRC: pc.networkManager.RTCPSSRC() + 1, LastRR: 1}},
+	}))
+
+	stats, ok := pc.GetStats()["RTCRemoteOutboundRTPStream_3333"].(*RTCRemoteOutboundRTPStreamStats)
+	if !assert.True(t, ok) {
+		return
+	}
+	assert.Equal(t, RTCStatsTypeRemoteOutboundRTP, stats.Type)
+	assert.Equal(t, RTCRtpCodecTypeAudio, stats.Kind)
+	assert.Equal(t, uint64(50), stats.PacketsSent)
+	assert.Equal(t, uint64(5000), stats.BytesSent)
+	assert.WithinDuration(t, sentAt, stats.RemoteTimestamp, time.Millisecond)
+	assert.InDelta(t, 200*time.Millisecond, stats.RoundTripTime, float64(50*time.Millisecond))
+	assert.Equal(t, stats.RoundTripTime, stats.TotalRoundTripTime)
+	assert.Equal(t, uint64(1), stats.RoundTripTimeMeasurements)
+}
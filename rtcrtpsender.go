@@ -1,16 +1,785 @@
+//go:build !js
+
 package webrtc
 
+import (
+	"encoding/binary"
+	"sync"
+	"time"
+
+	"github.com/pions/webrtc/internal/ulpfec"
+	"github.com/pions/webrtc/internal/util"
+	"github.com/pions/webrtc/pkg/media"
+	"github.com/pions/webrtc/pkg/rtcerr"
+	"github.com/pions/webrtc/pkg/rtcp"
+	"github.com/pions/webrtc/pkg/rtp"
+)
+
+// rtxHistorySize is the amount of sent packets kept per stream to answer
+// retransmission requests, it has to divide 65536 so the history wraps
+// together with the sequence numbers
+const rtxHistorySize = 512
+
 // RTCRtpSender allows an application to control how a given RTCTrack is encoded and transmitted to a remote peer
 type RTCRtpSender struct {
+	sync.RWMutex
+
 	Track *RTCTrack
 	// senderTrack *RTCTrack
-	// senderTransport
 	// senderRtcpTransport
+
+	// Transport is the DTLS transport the RTP and RTCP packets of the Track
+	// are sent over.
+	Transport *RTCDtlsTransport
+
+	onSentRTPPacketHandler func(*rtp.Packet)
+	onTargetBitrateHandler func(bitrate uint64)
+
+	// DTMF is used to send DTMF tones, it is only set for audio senders
+	DTMF *RTCDTMFSender
+
+	encodings []*rtcRtpSenderEncoding
+
+	// trackEncoding is the state of the stream of the Track itself
+	trackEncoding     *rtcRtpSenderEncoding
+	lastTransactionID string
+
+	// rtx holds the retransmission state of every stream, indexed by
+	// the SSRC of the stream it repairs
+	rtxCodec *RTCRtpCodec
+	rtx      map[uint32]*rtcRtpSenderRTX
+
+	// fec holds the FEC encoder of every stream, indexed by the SSRC of
+	// the stream it protects
+	fecCodec     *RTCRtpCodec
+	fecGroupSize int
+	fec          map[uint32]*ulpfec.Encoder
+
+	// remoteInbound holds the last reception report the remote peer sent
+	// about every stream, indexed by the SSRC of the stream
+	remoteInbound map[uint32]*rtcRemoteInbound
+
+	// Deprecated: Will be removed when networkManager is deprecated.
+	rtcPeerConnection *RTCPeerConnection
+}
+
+// rtcRtpSenderEncoding holds the sending state of a single simulcast layer
+type rtcRtpSenderEncoding struct {
+	RTCRtpEncodingParameters
+	packetizer rtcPacketizer
+	sequencer  rtp.Sequencer
+	dtx        *rtcAudioDTX
+
+	// lastSent and lastTimestamp describe the last packet sent, keepalives
+	// are sent once the stream has been silent for too long. packetCount and
+	// octetCount are the packets and payload octets sent, which sender
+	// reports carry.
+	lastSent      time.Time
+	lastTimestamp uint32
+	packetCount   uint32
+	octetCount    uint32
+
+	// budget is the amount of bytes that can be sent without exceeding
+	// MaxBitrate, it is refilled over time up to a second worth of data
+	budget      float64
+	lastRefresh time.Time
+}
+
+// rtcRemoteInbound is the reception of a stream as the remote peer reported
+// it, with the round trip times measured from its reports
+type rtcRemoteInbound struct {
+	report     rtcp.ReceptionReport
+	receivedAt time.Time
+
+	roundTripTime             time.Duration
+	totalRoundTripTime        time.Duration
+	roundTripTimeMeasurements uint64
+}
+
+// rtcRtpSenderRTX holds the RFC 4588 retransmission state of a single stream
+type rtcRtpSenderRTX struct {
+	sync.Mutex
+	ssrc        uint32
+	payloadType uint8
+	sequencer   rtp.Sequencer
+	history     [rtxHistorySize]*rtp.Packet
 }
 
 func newRTCRtpSender(track *RTCTrack) *RTCRtpSender {
 	s := &RTCRtpSender{
 		Track: track,
+		rtx:   make(map[uint32]*rtcRtpSenderRTX),
+		fec:   make(map[uint32]*ulpfec.Encoder),
+
+		remoteInbound: make(map[uint32]*rtcRemoteInbound),
+
+		trackEncoding: &rtcRtpSenderEncoding{
+			RTCRtpEncodingParameters: RTCRtpEncodingParameters{Active: true},
+		},
 	}
 	return s
 }
+
+// GetRTCRtpSenderCapabilities returns the codecs and RTP header extensions of
+// the kind RTCPeerConnections using the DefaultMediaEngine can send
+// https://www.w3.org/TR/webrtc/#dom-rtcrtpsender-getcapabilities
+func GetRTCRtpSenderCapabilities(kind RTCRtpCodecType) RTCRtpCapabilities {
+	return DefaultMediaEngine.GetCapabilities(kind)
+}
+
+// AddEncoding attaches a simulcast layer to the sender. Every layer is
+// identified by its RID and is sent on its own SSRC, the layers are
+// announced in the next offer or answer generated by the RTCPeerConnection.
+// Layers are always added active.
+func (s *RTCRtpSender) AddEncoding(params RTCRtpEncodingParameters) error {
+	if s.Track == nil {
+		return &rtcerr.InvalidStateError{Err: ErrNoSenderTrack}
+	} else if params.RID == "" {
+		return &rtcerr.TypeError{Err: ErrRIDEmpty}
+	}
+
+	s.Lock()
+	defer s.Unlock()
+
+	for _, e := range s.encodings {
+		if e.RID == params.RID {
+			return &rtcerr.InvalidAccessError{Err: ErrExistingRID}
+		}
+	}
+
+	if params.SSRC == 0 {
+		ssrc, err := s.rtcPeerConnection.newSSRC()
+		if err != nil {
+			return &rtcerr.UnknownError{Err: err}
+		}
+		params.SSRC = ssrc
+	}
+
+	if s.rtxCodec != nil {
+		if err := s.addRTXStream(params.SSRC); err != nil {
+			return err
+		}
+	}
+	if s.fecCodec != nil {
+		if err := s.addFECStream(params.SSRC); err != nil {
+			return err
+		}
+	}
+
+	params.Active = true
+	e := &rtcRtpSenderEncoding{RTCRtpEncodingParameters: params}
+	if codec := s.Track.Codec; codec != nil && codec.Payloader != nil {
+		// Raw RTP tracks have no MTU of their own
+		mtu := s.Track.getMTU()
+		if mtu == 0 {
+			mtu = defaultPacketizerMTU
+		}
+		e.sequencer = rtp.NewRandomSequencer()
+		e.packetizer = newRTCPacketizer(mtu, codec, params.SSRC, e.sequencer)
+		if codec.Type == RTCRtpCodecTypeAudio {
+			e.dtx = newRTCAudioDTX(codec.ClockRate)
+		}
+	}
+
+	s.encodings = append(s.encodings, e)
+	return nil
+}
+
+// enableRTX has the sender answer NACKs of the Track and all of its
+// simulcast layers by retransmitting on a dedicated RTX stream
+func (s *RTCRtpSender) enableRTX(codec *RTCRtpCodec) error {
+	s.Lock()
+	defer s.Unlock()
+
+	s.rtxCodec = codec
+	if err := s.addRTXStream(s.Track.Ssrc); err != nil {
+		return err
+	}
+	for _, e := range s.encodings {
+		if err := s.addRTXStream(e.SSRC); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// addRTXStream allocates the RTX stream repairing the given SSRC
+// Note: the caller should hold the RTCRtpSender lock.
+func (s *RTCRtpSender) addRTXStream(ssrc uint32) error {
+	if _, ok := s.rtx[ssrc]; ok {
+		return nil
+	}
+
+	rtxSSRC, err := s.rtcPeerConnection.newSSRC()
+	if err != nil {
+		return &rtcerr.UnknownError{Err: err}
+	}
+	s.rtx[ssrc] = &rtcRtpSenderRTX{
+		ssrc:        rtxSSRC,
+		payloadType: s.rtxCodec.PayloadType,
+		sequencer:   rtp.NewRandomSequencer(),
+	}
+	return nil
+}
+
+// getRTXSSRC returns the SSRC retransmissions of the stream are sent on
+func (s *RTCRtpSender) getRTXSSRC(ssrc uint32) (uint32, bool) {
+	s.RLock()
+	defer s.RUnlock()
+
+	r, ok := s.rtx[ssrc]
+	if !ok {
+		return 0, false
+	}
+	return r.ssrc, true
+}
+
+// EnableFEC has the sender protect the Track and all of its simulcast layers
+// with RFC 5109 forward error correction, sending an FEC packet for every
+// groupSize media packets. A single lost packet per group can be recovered
+// by the remote peer without waiting for a retransmission. The ulpfec codec,
+// see NewRTCRtpULPFECCodec, has to be registered with the MediaEngine.
+func (s *RTCRtpSender) EnableFEC(groupSize int) error {
+	if s.Track == nil {
+		return &rtcerr.InvalidStateError{Err: ErrNoSenderTrack}
+	} else if s.rtcPeerConnection == nil {
+		return &rtcerr.InvalidStateError{Err: ErrSenderNotAttached}
+	} else if groupSize < 1 || groupSize > ulpfec.MaxGroupSize {
+		return &rtcerr.RangeError{Err: ErrInvalidFECGroupSize}
+	}
+
+	codec := s.rtcPeerConnection.mediaEngine.getFECCodec(s.Track.Kind)
+	if codec == nil {
+		return &rtcerr.NotSupportedError{Err: ErrNoFECCodec}
+	}
+
+	s.Lock()
+	defer s.Unlock()
+
+	s.fecCodec = codec
+	s.fecGroupSize = groupSize
+	s.fec = make(map[uint32]*ulpfec.Encoder)
+	if err := s.addFECStream(s.Track.Ssrc); err != nil {
+		return err
+	}
+	for _, e := range s.encodings {
+		if err := s.addFECStream(e.SSRC); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// addFECStream allocates the FEC stream protecting the given SSRC
+// Note: the caller should hold the RTCRtpSender lock.
+func (s *RTCRtpSender) addFECStream(ssrc uint32) error {
+	fecSSRC, err := s.rtcPeerConnection.newSSRC()
+	if err != nil {
+		return &rtcerr.UnknownError{Err: err}
+	}
+	s.fec[ssrc] = ulpfec.NewEncoder(s.fecGroupSize, fecSSRC, s.fecCodec.PayloadType)
+	return nil
+}
+
+// getFECSSRC returns the SSRC the FEC packets protecting the stream are sent on
+func (s *RTCRtpSender) getFECSSRC(ssrc uint32) (uint32, bool) {
+	s.RLock()
+	defer s.RUnlock()
+
+	e, ok := s.fec[ssrc]
+	if !ok {
+		return 0, false
+	}
+	return e.SSRC(), true
+}
+
+func (s *RTCRtpSender) getEncoding(rid string) (*rtcRtpSenderEncoding, error) {
+	s.RLock()
+	defer s.RUnlock()
+
+	for _, e := range s.encodings {
+		if e.RID == rid {
+			return e, nil
+		}
+	}
+	return nil, &rtcerr.InvalidAccessError{Err: ErrUnknownRID}
+}
+
+// GetParameters returns the codecs negotiated for the Track and the
+// parameters of its encodings, see SetParameters.
+// https://www.w3.org/TR/webrtc/#dom-rtcrtpsender-getparameters
+func (s *RTCRtpSender) GetParameters() RTCRtpSendParameters {
+	var codecs []RTCRtpCodecParameters
+	if s.rtcPeerConnection != nil && s.Track != nil {
+		codecs = s.rtcPeerConnection.negotiatedCodecs(s.Track.Kind)
+	}
+
+	s.Lock()
+	defer s.Unlock()
+
+	s.lastTransactionID = util.RandSeq(16)
+	params := RTCRtpSendParameters{
+		RTCRtpParameters: RTCRtpParameters{
+			HeaderExtensions: []RTCRtpHeaderExtensionParameters{},
+			Codecs:           codecs,
+		},
+		TransactionID: s.lastTransactionID,
+	}
+	for _, e := range s.sendEncodings() {
+		params.Encodings = append(params.Encodings, e.RTCRtpEncodingParameters)
+	}
+	return params
+}
+
+// SetParameters changes the Active and MaxBitrate of the encodings at
+// runtime, without renegotiation. The parameters have to be the ones
+// returned by the last call to GetParameters, encodings can't be added,
+// removed or reordered.
+// https://www.w3.org/TR/webrtc/#dom-rtcrtpsender-setparameters
+func (s *RTCRtpSender) SetParameters(params RTCRtpSendParameters) error {
+	s.Lock()
+	defer s.Unlock()
+
+	if s.lastTransactionID == "" {
+		return &rtcerr.InvalidStateError{Err: ErrNoParameters}
+	} else if params.TransactionID != s.lastTransactionID {
+		return &rtcerr.InvalidModificationError{Err: ErrParametersTransactionID}
+	}
+
+	encodings := s.sendEncodings()
+	if len(params.Encodings) != len(encodings) {
+		return &rtcerr.InvalidModificationError{Err: ErrModifiedEncodings}
+	}
+	for i, e := range encodings {
+		if params.Encodings[i].RID != e.RID || params.Encodings[i].SSRC != e.SSRC {
+			return &rtcerr.InvalidModificationError{Err: ErrModifiedEncodings}
+		}
+	}
+
+	for i, e := range encodings {
+		e.Active = params.Encodings[i].Active
+		e.MaxBitrate = params.Encodings[i].MaxBitrate
+	}
+	s.lastTransactionID = ""
+	return nil
+}
+
+// sendEncodings returns the simulcast layers, or the stream of the Track
+// if there are none
+// Note: the caller should hold the RTCRtpSender lock.
+func (s *RTCRtpSender) sendEncodings() []*rtcRtpSenderEncoding {
+	if len(s.encodings) > 0 {
+		return s.encodings
+	}
+	if s.Track != nil {
+		s.trackEncoding.SSRC = s.Track.Ssrc
+		s.trackEncoding.sequencer = s.Track.sequencer
+	}
+	return []*rtcRtpSenderEncoding{s.trackEncoding}
+}
+
+// allowed applies Active and MaxBitrate of the encoding the packet is sent on
+func (s *RTCRtpSender) allowed(packet *rtp.Packet) bool {
+	s.Lock()
+	defer s.Unlock()
+
+	var e *rtcRtpSenderEncoding
+	for _, encoding := range s.encodings {
+		if encoding.SSRC == packet.SSRC {
+			e = encoding
+		}
+	}
+	if e == nil && s.Track != nil && s.Track.Ssrc == packet.SSRC {
+		e = s.trackEncoding
+	}
+	if e == nil {
+		return true
+	} else if !e.allowed(len(packet.Payload) + 12) {
+		return false
+	}
+
+	e.lastSent = time.Now()
+	e.lastTimestamp = packet.Timestamp
+	e.packetCount++
+	e.octetCount += uint32(len(packet.Payload))
+	if packet.Padding && len(packet.Payload) > 0 {
+		e.octetCount -= uint32(packet.Payload[len(packet.Payload)-1])
+	}
+	return true
+}
+
+// allowed reports if size bytes can be sent, consuming them from the budget
+// Note: the caller should hold the RTCRtpSender lock.
+func (e *rtcRtpSenderEncoding) allowed(size int) bool {
+	if !e.Active {
+		return false
+	} else if e.MaxBitrate == 0 {
+		return true
+	}
+
+	now := time.Now()
+	limit := float64(e.MaxBitrate) / 8
+	if e.lastRefresh.IsZero() {
+		e.budget = limit
+	} else {
+		e.budget += now.Sub(e.lastRefresh).Seconds() * limit
+		if e.budget > limit {
+			e.budget = limit
+		}
+	}
+	e.lastRefresh = now
+
+	if float64(size) > e.budget {
+		return false
+	}
+	e.budget -= float64(size)
+	return true
+}
+
+func (s *RTCRtpSender) getEncodings() []RTCRtpEncodingParameters {
+	s.RLock()
+	defer s.RUnlock()
+
+	result := make([]RTCRtpEncodingParameters, len(s.encodings))
+	for i, e := range s.encodings {
+		result[i] = e.RTCRtpEncodingParameters
+	}
+	return result
+}
+
+// WriteSample packetizes the sample and sends it on the simulcast layer
+// identified by rid
+func (s *RTCRtpSender) WriteSample(rid string, sample media.RTCSample) error {
+	e, err := s.getEncoding(rid)
+	if err != nil {
+		return err
+	}
+	if e.packetizer == nil {
+		return &rtcerr.InvalidStateError{Err: ErrNoPayloader}
+	}
+
+	e.packetizer.SetCSRC(sampleCSRC(sample))
+	var packets []*rtp.Packet
+	if e.dtx != nil {
+		var comfortNoise *RTCRtpCodec
+		if s.rtcPeerConnection != nil {
+			comfortNoise = s.rtcPeerConnection.comfortNoiseCodec(s.Track.Codec.ClockRate)
+		}
+		packets = e.dtx.packetize(e.packetizer, sample, comfortNoise, time.Now())
+	} else {
+		packets = e.packetizer.Packetize(sample.Data, sample.Samples)
+	}
+
+	for _, p := range packets {
+		if err := s.sendRTP(p); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WriteRTP sends a copy of an already packetized RTP packet on the simulcast
+// layer identified by rid, with the SSRC of the layer. The packet is left
+// untouched.
+func (s *RTCRtpSender) WriteRTP(rid string, packet *rtp.Packet) error {
+	e, err := s.getEncoding(rid)
+	if err != nil {
+		return err
+	}
+
+	// The copy is encrypted in place when it is sent
+	packet = packet.Clone()
+	packet.SSRC = e.SSRC
+	return s.sendRTP(packet)
+}
+
+func (s *RTCRtpSender) sendRTP(packet *rtp.Packet) error {
+	if s.rtcPeerConnection == nil {
+		return &rtcerr.InvalidStateError{Err: ErrSenderNotAttached}
+	}
+	if !s.allowed(packet) {
+		return nil
+	}
+
+	// The FEC packet is built first, the send path encrypts the payload in place
+	fec, err := s.protect(packet)
+	if err != nil {
+		return err
+	}
+
+	s.storeForRetransmission(packet)
+	s.doOnSentRTPPacket(packet)
+	s.rtcPeerConnection.networkManager.SendRTP(packet)
+
+	if fec != nil {
+		s.doOnSentRTPPacket(fec)
+		s.rtcPeerConnection.networkManager.SendRTP(fec)
+	}
+	return nil
+}
+
+// keepalive sends a padding-only packet on every stream which has been
+// silent for the interval since its last packet, keeping NAT bindings and
+// the SRTP state of the remote peer warm. Streams which haven't started
+// yet or are numbered by the application, written with raw RTP packets,
+// are not kept alive.
+func (s *RTCRtpSender) keepalive(interval time.Duration) {
+	if s.Track == nil {
+		return
+	}
+
+	var packets []*rtp.Packet
+	s.Lock()
+	for _, e := range s.sendEncodings() {
+		if e.sequencer == nil || e.lastSent.IsZero() || time.Since(e.lastSent) < interval {
+			continue
+		}
+		packets = append(packets, &rtp.Packet{
+			Version:        2,
+			Padding:        true,
+			PayloadType:    s.Track.PayloadType,
+			SequenceNumber: e.sequencer.NextSequenceNumber(),
+			Timestamp:      e.lastTimestamp,
+			SSRC:           e.SSRC,
+			Payload:        []byte{1}, // The padding is just its own length
+		})
+	}
+	s.Unlock()
+
+	for _, packet := range packets {
+		if err := s.sendRTP(packet); err != nil {
+			return
+		}
+	}
+}
+
+// senderReports returns a sender report for every stream which has sent
+// media, the reception reports referring to them measure the round trip,
+// RFC 3550 Section 6.4.1
+func (s *RTCRtpSender) senderReports(now time.Time) []rtcp.Packet {
+	if s.Track == nil || s.Track.Codec == nil {
+		return nil
+	}
+	clockRate := uint64(s.Track.Codec.ClockRate)
+
+	var reports []rtcp.Packet
+	s.Lock()
+	defer s.Unlock()
+	for _, e := range s.sendEncodings() {
+		if e.lastSent.IsZero() {
+			continue
+		}
+		// The timestamp of the last packet advanced by the media clock
+		elapsed := uint64(now.Sub(e.lastSent))
+		reports = append(reports, &rtcp.SenderReport{
+			SSRC:        e.SSRC,
+			NTPTime:     ntpTime(now),
+			RTPTime:     e.lastTimestamp + uint32(elapsed*clockRate/uint64(time.Second)),
+			PacketCount: e.packetCount,
+			OctetCount:  e.octetCount,
+		})
+	}
+	return reports
+}
+
+// protect adds the packet to its FEC group, returning the FEC packet once
+// the group is complete
+func (s *RTCRtpSender) protect(packet *rtp.Packet) (*rtp.Packet, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	e, ok := s.fec[packet.SSRC]
+	if !ok {
+		return nil, nil
+	}
+	return e.Push(packet)
+}
+
+// storeForRetransmission keeps a copy of the packet around, as the send
+// path encrypts the payload in place
+func (s *RTCRtpSender) storeForRetransmission(packet *rtp.Packet) {
+	s.RLock()
+	r, ok := s.rtx[packet.SSRC]
+	s.RUnlock()
+	if !ok {
+		return
+	}
+
+	stored := *packet
+	stored.Raw = nil
+	stored.Payload = append([]byte{}, packet.Payload...)
+
+	r.Lock()
+	r.history[packet.SequenceNumber%rtxHistorySize] = &stored
+	r.Unlock()
+}
+
+// retransmit resends every packet reported lost by the NACK that is still
+// in the history, wrapped in an RTX packet
+func (s *RTCRtpSender) retransmit(nack *rtcp.TransportLayerNack) {
+	s.RLock()
+	r, ok := s.rtx[nack.MediaSSRC]
+	s.RUnlock()
+	if !ok || s.rtcPeerConnection == nil {
+		return
+	}
+
+	for _, pair := range nack.Nacks {
+		for _, sequenceNumber := range pair.PacketList() {
+			if packet := r.wrap(sequenceNumber); packet != nil {
+				s.doOnSentRTPPacket(packet)
+				s.rtcPeerConnection.networkManager.SendRTP(packet)
+			}
+		}
+	}
+}
+
+// wrap builds the retransmission of a packet from the history, the original
+// sequence number (OSN) is prepended to the payload
+// https://tools.ietf.org/html/rfc4588#section-4
+func (r *rtcRtpSenderRTX) wrap(sequenceNumber uint16) *rtp.Packet {
+	r.Lock()
+	defer r.Unlock()
+
+	original := r.history[sequenceNumber%rtxHistorySize]
+	if original == nil || original.SequenceNumber != sequenceNumber {
+		return nil
+	}
+
+	payload := make([]byte, 2+len(original.Payload))
+	binary.BigEndian.PutUint16(payload, original.SequenceNumber)
+	copy(payload[2:], original.Payload)
+
+	packet := *original
+	packet.SSRC = r.ssrc
+	packet.PayloadType = r.payloadType
+	packet.SequenceNumber = r.sequencer.NextSequenceNumber()
+	packet.Payload = payload
+	return &packet
+}
+
+// hasSSRC reports if the Track or one of its layers is sent on the SSRC
+func (s *RTCRtpSender) hasSSRC(ssrc uint32) bool {
+	s.RLock()
+	defer s.RUnlock()
+
+	if s.Track != nil && s.Track.Ssrc == ssrc {
+		return true
+	}
+	for _, e := range s.encodings {
+		if e.SSRC == ssrc {
+			return true
+		}
+	}
+	return false
+}
+
+// ssrcs returns the SSRCs the sender sends on, the ones of the Track and of
+// its layers and the ones repairing them
+func (s *RTCRtpSender) ssrcs() []uint32 {
+	s.RLock()
+	defer s.RUnlock()
+
+	var ssrcs []uint32
+	if s.Track != nil {
+		ssrcs = append(ssrcs, s.Track.Ssrc)
+	}
+	for _, e := range s.encodings {
+		ssrcs = append(ssrcs, e.SSRC)
+	}
+	for _, r := range s.rtx {
+		ssrcs = append(ssrcs, r.ssrc)
+	}
+	for _, e := range s.fec {
+		ssrcs = append(ssrcs, e.SSRC())
+	}
+	return ssrcs
+}
+
+// handleReceptionReport records the report the remote peer sent about a
+// stream of the sender. The round trip time is measured from reports
+// referring to a sender report, RFC 3550 Section 6.4.1.
+func (s *RTCRtpSender) handleReceptionReport(report rtcp.ReceptionReport, now time.Time) {
+	s.Lock()
+	defer s.Unlock()
+
+	r, ok := s.remoteInbound[report.SSRC]
+	if !ok {
+		r = &rtcRemoteInbound{}
+		s.remoteInbound[report.SSRC] = r
+	}
+	r.report = report
+	r.receivedAt = now
+
+	if report.LastSenderReport == 0 {
+		return
+	}
+	// In units of 1/65536 seconds, negative with skewed clocks
+	rtt := int32(ntpMiddle(now) - report.LastSenderReport - report.Delay)
+	if rtt < 0 {
+		return
+	}
+	r.roundTripTime = time.Duration(rtt) * time.Second / 65536
+	r.totalRoundTripTime += r.roundTripTime
+	r.roundTripTimeMeasurements++
+}
+
+// ntpTime returns the 64 bit NTP timestamp of the time
+func ntpTime(t time.Time) uint64 {
+	const ntpEpochOffset = 2208988800 // seconds from 1900 to 1970
+	seconds := uint64(t.Unix() + ntpEpochOffset)
+	fraction := uint64(t.Nanosecond()) << 32 / uint64(time.Second)
+	return seconds<<32 | fraction
+}
+
+// ntpToTime returns the time of the 64 bit NTP timestamp
+func ntpToTime(ntp uint64) time.Time {
+	const ntpEpochOffset = 2208988800 // seconds from 1900 to 1970
+	seconds := int64(ntp>>32) - ntpEpochOffset
+	nanoseconds := (ntp & 0xffffffff) * uint64(time.Second) >> 32
+	return time.Unix(seconds, int64(nanoseconds))
+}
+
+// ntpMiddle returns the middle 32 bits of the NTP timestamp of the time, the
+// seconds and fraction reception reports measure round trips in
+func ntpMiddle(t time.Time) uint32 {
+	return uint32(ntpTime(t) >> 16)
+}
+
+// OnTargetBitrate sets an event handler which is invoked with the bitrate, in
+// bits per second, the remote peer estimates it is able to receive for the
+// Track, so external encoders can adapt to the network. Estimates covering
+// several senders are shared evenly between them.
+func (s *RTCRtpSender) OnTargetBitrate(f func(bitrate uint64)) {
+	s.Lock()
+	defer s.Unlock()
+	s.onTargetBitrateHandler = f
+}
+
+func (s *RTCRtpSender) doOnTargetBitrate(bitrate uint64) {
+	s.RLock()
+	onTargetBitrate := s.onTargetBitrateHandler
+	s.RUnlock()
+	if onTargetBitrate != nil {
+		onTargetBitrate(bitrate)
+	}
+}
+
+// OnSentRTPPacket sets an event handler which is invoked for every RTP packet
+// sent for the Track, before it is encrypted. The packet is shared with the
+// rest of the send path, so it must not be modified or retained after the
+// handler returns.
+func (s *RTCRtpSender) OnSentRTPPacket(f func(*rtp.Packet)) {
+	s.Lock()
+	defer s.Unlock()
+	s.onSentRTPPacketHandler = f
+}
+
+func (s *RTCRtpSender) doOnSentRTPPacket(p *rtp.Packet) {
+	s.RLock()
+	onSentRTPPacket := s.onSentRTPPacketHandler
+	s.RUnlock()
+	if onSentRTPPacket != nil {
+		onSentRTPPacket(p)
+	}
+}
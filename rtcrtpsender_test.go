@@ -0,0 +1,408 @@
+//go:build !js
+
+package webrtc
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pions/webrtc/pkg/rtcerr"
+	"github.com/pions/webrtc/pkg/rtcp"
+	"github.com/pions/webrtc/pkg/rtp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRTCRtpSender_AddEncoding(t *testing.T) {
+	RegisterDefaultCodecs()
+
+	pc, err := New(RTCConfiguration{})
+	assert.Nil(t, err)
+
+	track, err := pc.NewRTCSampleTrack(DefaultPayloadTypeVP8, "video", "pion")
+	assert.Nil(t, err)
+
+	sender, err := pc.AddTrack(track)
+	assert.Nil(t, err)
+
+	assert.Nil(t, sender.AddEncoding(RTCRtpEncodingParameters{RID: "h", SSRC: 1111}))
+	assert.Nil(t, sender.AddEncoding(RTCRtpEncodingParameters{RID: "l"}))
+
+	assert.EqualError(t, sender.AddEncoding(RTCRtpEncodingParameters{RID: "h"}),
+		(&rtcerr.InvalidAccessError{Err: ErrExistingRID}).Error())
+	assert.EqualError(t, sender.AddEncoding(RTCRtpEncodingParameters{}),
+		(&rtcerr.TypeError{Err: ErrRIDEmpty}).Error())
+	assert.EqualError(t, sender.WriteRTP("m", nil),
+		(&rtcerr.InvalidAccessError{Err: ErrUnknownRID}).Error())
+
+	encodings := sender.getEncodings()
+	assert.Equal(t, 2, len(encodings))
+	assert.Equal(t, uint32(1111), encodings[0].SSRC)
+	assert.NotEqual(t, uint32(0), encodings[1].SSRC)
+
+	offer, err := pc.CreateOffer(nil)
+	assert.Nil(t, err)
+	assert.True(t, strings.Contains(offer.SDP, "a=rid:h send\r\n"))
+	assert.True(t, strings.Contains(offer.SDP, "a=rid:l send\r\n"))
+	assert.True(t, strings.Contains(offer.SDP, "a=simulcast:send h;l\r\n"))
+	assert.True(t, strings.Contains(offer.SDP, "a=ssrc-group:SIM 1111 "))
+	assert.True(t, strings.Contains(offer.SDP, "a=ssrc:1111 cname:pion\r\n"))
+}
+
+func TestRTCRtpSender_OnSentRTPPacket(t *testing.T) {
+	RegisterDefaultCodecs()
+
+	pc, err := New(RTCConfiguration{})
+	assert.Nil(t, err)
+
+	track, err := pc.NewRTCSampleTrack(DefaultPayloadTypeVP8, "video", "pion")
+	assert.Nil(t, err)
+
+	sender, err := pc.AddTrack(track)
+	assert.Nil(t, err)
+	assert.Nil(t, sender.AddEncoding(RTCRtpEncodingParameters{RID: "h", SSRC: 2222}))
+
+	var sent []*rtp.Packet
+	sender.OnSentRTPPacket(func(p *rtp.Packet) {
+		sent = append(sent, p)
+	})
+
+	written := &rtp.Packet{SSRC: 1, Payload: []byte{0x00}}
+	assert.Nil(t, sender.WriteRTP("h", written))
+	assert.Equal(t, 1, len(sent))
+	assert.Equal(t, uint32(2222), sent[0].SSRC)
+
+	// The written packet is left untouched
+	assert.Equal(t, uint32(1), written.SSRC)
+	assert.Equal(t, []byte{0x00}, written.Payload)
+}
+
+func TestRTCRtpSender_RTX(t *testing.T) {
+	RegisterDefaultCodecs()
+
+	pc, err := New(RTCConfiguration{})
+	assert.Nil(t, err)
+
+	track, err := pc.NewRTCSampleTrack(DefaultPayloadTypeVP8, "video", "pion")
+	assert.Nil(t, err)
+
+	sender, err := pc.AddTrack(track)
+	assert.Nil(t, err)
+
+	rtxSSRC, ok := sender.getRTXSSRC(track.Ssrc)
+	assert.True(t, ok)
+
+	offer, err := pc.CreateOffer(nil)
+	assert.Nil(t, err)
+	assert.True(t, strings.Contains(offer.SDP, "a=rtpmap:97 rtx/90000\r\n"))
+	assert.True(t, strings.Contains(offer.SDP, "a=fmtp:97 apt=96\r\n"))
+	assert.True(t, strings.Contains(offer.SDP, "a=rtcp-fb:96 nack\r\n"))
+	assert.True(t, strings.Contains(offer.SDP, fmt.Sprintf("a=ssrc-group:FID %d %d\r\n", track.Ssrc, rtxSSRC)))
+
+	var sent []*rtp.Packet
+	sender.OnSentRTPPacket(func(p *rtp.Packet) {
+		sent = append(sent, p)
+	})
+
+	pc.sendRTP(track, &rtp.Packet{Version: 2, SSRC: track.Ssrc, PayloadType: DefaultPayloadTypeVP8, SequenceNumber: 1000, Payload: []byte{0xAA, 0xBB}})
+	pc.handleRTCP(mustMarshal(t, &rtcp.TransportLayerNack{
+		MediaSSRC: track.Ssrc,
+		Nacks:     []rtcp.NackPair{{PacketID: 999, LostPackets: 0x1}},
+	}))
+
+	// Only 1000 is still in the history, 999 was never sent
+	assert.Equal(t, 2, len(sent))
+	assert.Equal(t, rtxSSRC, sent[1].SSRC)
+	assert.Equal(t, uint8(DefaultPayloadTypeVP8RTX), sent[1].PayloadType)
+	assert.Equal(t, []byte{0x03, 0xE8, 0xAA, 0xBB}, sent[1].Payload)
+}
+
+func mustMarshal(t *testing.T, p rtcp.Packet) []byte {
+	raw, err := p.Marshal()
+	assert.Nil(t, err)
+	return raw
+}
+
+func TestRTCRtpSender_EnableFEC(t *testing.T) {
+	m := NewMediaEngine()
+	m.RegisterCodec(NewRTCRtpVP8Codec(DefaultPayloadTypeVP8, 90000))
+
+	pc, err := NewAPI(WithMediaEngine(m)).NewRTCPeerConnection(RTCConfiguration{})
+	assert.Nil(t, err)
+
+	track, err := pc.NewRTCSampleTrack(DefaultPayloadTypeVP8, "video", "pion")
+	assert.Nil(t, err)
+
+	sender, err := pc.AddTrack(track)
+	assert.Nil(t, err)
+
+	assert.EqualError(t, sender.EnableFEC(2),
+		(&rtcerr.NotSupportedError{Err: ErrNoFECCodec}).Error())
+
+	m.RegisterCodec(NewRTCRtpULPFECCodec(125, 90000))
+	assert.EqualError(t, sender.EnableFEC(0),
+		(&rtcerr.RangeError{Err: ErrInvalidFECGroupSize}).Error())
+	assert.Nil(t, sender.EnableFEC(2))
+
+	fecSSRC, ok := sender.getFECSSRC(track.Ssrc)
+	assert.True(t, ok)
+
+	offer, err := pc.CreateOffer(nil)
+	assert.Nil(t, err)
+	assert.True(t, strings.Contains(offer.SDP, "a=rtpmap:125 ulpfec/90000\r\n"))
+	assert.True(t, strings.Contains(offer.SDP, fmt.Sprintf("a=ssrc-group:FEC %d %d\r\n", track.Ssrc, fecSSRC)))
+
+	var sent []*rtp.Packet
+	sender.OnSentRTPPacket(func(p *rtp.Packet) {
+		sent = append(sent, p)
+	})
+
+	for i := 0; i < 4; i++ {
+		pc.sendRTP(track, &rtp.Packet{Version: 2, SSRC: track.Ssrc, PayloadType: DefaultPayloadTypeVP8, SequenceNumber: uint16(i), Payload: []byte{byte(i)}})
+	}
+
+	// Every second media packet is followed by the FEC packet of its group
+	assert.Equal(t, 6, len(sent))
+	assert.Equal(t, fecSSRC, sent[2].SSRC)
+	assert.Equal(t, uint8(125), sent[2].PayloadType)
+	assert.Equal(t, fecSSRC, sent[5].SSRC)
+}
+
+func TestRTCRtpSender_SetParameters(t *testing.T) {
+	RegisterDefaultCodecs()
+
+	pc, err := New(RTCConfiguration{})
+	assert.Nil(t, err)
+
+	track, err := pc.NewRTCSampleTrack(DefaultPayloadTypeVP8, "video", "pion")
+	assert.Nil(t, err)
+
+	sender, err := pc.AddTrack(track)
+	assert.Nil(t, err)
+
+	assert.EqualError(t, sender.SetParameters(RTCRtpSendParameters{}),
+		(&rtcerr.InvalidStateError{Err: ErrNoParameters}).Error())
+
+	params := sender.GetParameters()
+	assert.NotEqual(t, 0, len(params.Codecs))
+	for _, codec := range params.Codecs {
+		assert.True(t, strings.HasPrefix(codec.MimeType, "video/"))
+	}
+	assert.Equal(t, 1, len(params.Encodings))
+	assert.Equal(t, track.Ssrc, params.Encodings[0].SSRC)
+	assert.True(t, params.Encodings[0].Active)
+
+	assert.EqualError(t, sender.SetParameters(RTCRtpSendParameters{TransactionID: "invalid"}),
+		(&rtcerr.InvalidModificationError{Err: ErrParametersTransactionID}).Error())
+	assert.EqualError(t, sender.SetParameters(RTCRtpSendParameters{TransactionID: params.TransactionID}),
+		(&rtcerr.InvalidModificationError{Err: ErrModifiedEncodings}).Error())
+
+	var sent []*rtp.Packet
+	sender.OnSentRTPPacket(func(p *rtp.Packet) {
+		sent = append(sent, p)
+	})
+
+	params.Encodings[0].Active = false
+	assert.Nil(t, sender.SetParameters(params))
+	pc.sendRTP(track, &rtp.Packet{Version: 2, SSRC: track.Ssrc, PayloadType: DefaultPayloadTypeVP8, Payload: []byte{0x00}})
+	assert.Equal(t, 0, len(sent))
+
+	// The budget of a second worth of data lets the first packet through
+	params = sender.GetParameters()
+	params.Encodings[0].Active = true
+	params.Encodings[0].MaxBitrate = 8 * 100
+	assert.Nil(t, sender.SetParameters(params))
+	for i := 0; i < 2; i++ {
+		pc.sendRTP(track, &rtp.Packet{Version: 2, SSRC: track.Ssrc, PayloadType: DefaultPayloadTypeVP8, Payload: make([]byte, 80)})
+	}
+	assert.Equal(t, 1, len(sent))
+}
+
+func TestRTCRtpSender_OnTargetBitrate(t *testing.T) {
+	RegisterDefaultCodecs()
+
+	pc, err := New(RTCConfiguration{})
+	assert.Nil(t, err)
+
+	var senders []*RTCRtpSender
+	var bitrates []uint64
+	for i := 0; i < 3; i++ {
+		track, err := pc.NewRTCSampleTrack(DefaultPayloadTypeVP8, fmt.Sprintf("video%d", i), "pion")
+		assert.Nil(t, err)
+
+		sender, err := pc.AddTrack(track)
+		assert.Nil(t, err)
+		sender.OnTargetBitrate(func(bitrate uint64) {
+			bitrates = append(bitrates, bitrate)
+		})
+		senders = append(senders, sender)
+	}
+
+	offer, err := pc.CreateOffer(nil)
+	assert.Nil(t, err)
+	assert.True(t, strings.Contains(offer.SDP, "a=rtcp-fb:96 goog-remb\r\n"))
+	assert.False(t, strings.Contains(offer.SDP, "a=rtcp-fb:97 goog-remb\r\n"))
+
+	pc.handleRTCP(mustMarshal(t, &rtcp.ReceiverEstimatedMaximumBitrate{
+		Bitrate: 1000000,
+		SSRCs:   []uint32{senders[0].Track.Ssrc, senders[2].Track.Ssrc},
+	}))
+	assert.Equal(t, []uint64{500000, 500000}, bitrates)
+}
+
+func TestRTCRtpSender_Keepalive(t *testing.T) {
+	RegisterDefaultCodecs()
+
+	pc, err := New(RTCConfiguration{})
+	assert.Nil(t, err)
+
+	track, err := pc.NewRTCSampleTrack(DefaultPayloadTypeVP8, "video", "pion")
+	assert.Nil(t, err)
+
+	sender, err := pc.AddTrack(track)
+	assert.Nil(t, err)
+
+	var sent []*rtp.Packet
+	sender.OnSentRTPPacket(func(p *rtp.Packet) {
+		sent = append(sent, p)
+	})
+
+	// Streams which haven't started aren't kept alive
+	sender.keepalive(0)
+	assert.Equal(t, 0, len(sent))
+
+	assert.Nil(t, sender.sendRTP(&rtp.Packet{
+		Version:        2,
+		PayloadType:    track.PayloadType,
+		SequenceNumber: track.sequencer.NextSequenceNumber(),
+		Timestamp:      1234,
+		SSRC:           track.Ssrc,
+		Payload:        []byte{0x01, 0x02},
+	}))
+	assert.Equal(t, 1, len(sent))
+
+	sender.keepalive(time.Hour)
+	assert.Equal(t, 1, len(sent))
+
+	sender.keepalive(0)
+	assert.Equal(t, 2, len(sent))
+	keepalive := sent[1]
+	assert.True(t, keepalive.Padding)
+	assert.Equal(t, []byte{1}, keepalive.Payload)
+	assert.Equal(t, track.Ssrc, keepalive.SSRC)
+	assert.Equal(t, uint32(1234), keepalive.Timestamp)
+	assert.Equal(t, sent[0].SequenceNumber+1, keepalive.SequenceNumber)
+}
+
+func TestRTCRtpSender_ReceptionReports(t *testing.T) {
+	RegisterDefaultCodecs()
+
+	pc, err := New(RTCConfiguration{})
+	assert.Nil(t, err)
+	defer func() { assert.Nil(t, pc.Close()) }()
+
+	track, err := pc.NewRTCSampleTrack(DefaultPayloadTypeVP8, "video", "pion")
+	assert.Nil(t, err)
+	_, err = pc.AddTrack(track)
+	assert.Nil(t, err)
+
+	// Reports about other streams are ignored
+	pc.handleRTCP(mustMarshal(t, &rtcp.ReceiverReport{
+		SSRC:    1,
+		Reports: []rtcp.ReceptionReport{{SSRC: track.Ssrc + 1}},
+	}))
+	assert.NotContains(t, pc.GetStats(), fmt.Sprintf("RTCRemoteInboundRTPStream_%d", track.Ssrc+1))
+
+	// The sender report was sent 300ms ago and held for 100ms by the remote
+	// peer, the round trip took 200ms
+	pc.handleRTCP(mustMarshal(t, &rtcp.ReceiverReport{
+		SSRC: 1,
+		Reports: []rtcp.ReceptionReport{{
+			SSRC:             track.Ssrc,
+			FractionLost:     64,
+			TotalLost:        0xFFFFFE,
+			Jitter:           9000,
+			LastSenderReport: ntpMiddle(time.Now().Add(-300 * time.Millisecond)),
+			Delay:            65536 / 10,
+		}},
+	}))
+
+	stats, ok := pc.GetStats()[fmt.Sprintf("RTCRemoteInboundRTPStream_%d", track.Ssrc)].(*RTCRemoteInboundRTPStreamStats)
+	if !assert.True(t, ok) {
+		return
+	}
+	assert.Equal(t, RTCStatsTypeRemoteInboundRTP, stats.Type)
+	assert.Equal(t, track.Ssrc, stats.SSRC)
+	assert.Equal(t, RTCRtpCodecTypeVideo, stats.Kind)
+	assert.Equal(t, 0.25, stats.FractionLost)
+	assert.Equal(t, int64(-2), stats.PacketsLost)
+	assert.Equal(t, 100*time.Millisecond, stats.Jitter)
+	assert.InDelta(t, float64(200*time.Millisecond), float64(stats.RoundTripTime), float64(20*time.Millisecond))
+	assert.Equal(t, stats.RoundTripTime, stats.TotalRoundTripTime)
+	assert.Equal(t, uint64(1), stats.RoundTripTimeMeasurements)
+
+	// Reports without a sender report keep the last round trip time
+	pc.handleRTCP(mustMarshal(t, &rtcp.SenderReport{
+		SSRC:    1,
+		Reports: []rtcp.ReceptionReport{{SSRC: track.Ssrc}},
+	}))
+	updated := pc.GetStats()[stats.ID].(*RTCRemoteInboundRTPStreamStats)
+	assert.Equal(t, float64(0), updated.FractionLost)
+	assert.Equal(t, stats.RoundTripTime, updated.RoundTripTime)
+	assert.Equal(t, uint64(1), updated.RoundTripTimeMeasurements)
+}
+
+func TestRTCRtpSender_SenderReports(t *testing.T) {
+	RegisterDefaultCodecs()
+
+	pc, err := New(RTCConfiguration{})
+	assert.Nil(t, err)
+	defer func() { assert.Nil(t, pc.Close()) }()
+
+	track, err := pc.NewRTCSampleTrack(DefaultPayloadTypeOpus, "audio", "pion")
+	assert.Nil(t, err)
+	sender, err := pc.AddTrack(track)
+	assert.Nil(t, err)
+
+	// Streams which haven't started aren't reported
+	assert.Empty(t, sender.senderReports(time.Now()))
+
+	for i := 0; i < 2; i++ {
+		assert.Nil(t, sender.sendRTP(&rtp.Packet{
+			Version:        2,
+			PayloadType:    track.PayloadType,
+			SequenceNumber: track.sequencer.NextSequenceNumber(),
+			Timestamp:      1000,
+			SSRC:           track.Ssrc,
+			Payload:        []byte{0x01, 0x02, 0x03},
+		}))
+	}
+	sender.keepalive(0)
+
+	// The padding of the keepalive isn't counted, the RTP time advances by
+	// the 48 kHz clock of opus since the last packet
+	time.Sleep(20 * time.Millisecond)
+	now := time.Now()
+	reports := sender.senderReports(now)
+	if !assert.Equal(t, 1, len(reports)) {
+		return
+	}
+	sr := reports[0].(*rtcp.SenderReport)
+	assert.Equal(t, track.Ssrc, sr.SSRC)
+	assert.Equal(t, ntpTime(now), sr.NTPTime)
+	assert.True(t, sr.RTPTime >= 1000+960 && sr.RTPTime < 1000+960+480, "RTP time %d", sr.RTPTime)
+	assert.Equal(t, uint32(3), sr.PacketCount)
+	assert.Equal(t, uint32(6), sr.OctetCount)
+
+	// The reception report referring to it measures the round trip
+	pc.handleRTCP(mustMarshal(t, &rtcp.ReceiverReport{
+		SSRC: 1,
+		Reports: []rtcp.ReceptionReport{{
+			SSRC:             track.Ssrc,
+			LastSenderReport: uint32(sr.NTPTime >> 16),
+		}},
+	}))
+	stats := pc.GetStats()[fmt.Sprintf("RTCRemoteInboundRTPStream_%d", track.Ssrc)].(*RTCRemoteInboundRTPStreamStats)
+	assert.Equal(t, uint64(1), stats.RoundTripTimeMeasurements)
+}
@@ -1,6 +1,9 @@
+//go:build !js
+
 package webrtc
 
 import (
+	"github.com/pions/webrtc/pkg/rtcerr"
 	"github.com/pkg/errors"
 )
 
@@ -14,6 +17,11 @@ type RTCRtpTransceiver struct {
 	// firedDirection   RTCRtpTransceiverDirection
 	// receptive bool
 	stopped bool
+
+	codecPreferences []RTCRtpCodecCapability
+
+	// Deprecated: Will be removed when networkManager is deprecated.
+	rtcPeerConnection *RTCPeerConnection
 }
 
 func (t *RTCRtpTransceiver) setSendingTrack(track *RTCTrack) error {
@@ -30,6 +38,53 @@ func (t *RTCRtpTransceiver) setSendingTrack(track *RTCTrack) error {
 	return nil
 }
 
+// SetCodecPreferences sets the codecs negotiated for the transceiver, in
+// order of preference, instead of every codec of the MediaEngine in the
+// order they were registered. Every codec must be registered with the
+// MediaEngine, retransmission codecs follow the codec they protect. Passing
+// no codecs resets the preferences.
+// https://www.w3.org/TR/webrtc/#dom-rtcrtptransceiver-setcodecpreferences
+func (t *RTCRtpTransceiver) SetCodecPreferences(codecs []RTCRtpCodecCapability) error {
+	for _, codec := range codecs {
+		if t.rtcPeerConnection == nil || t.rtcPeerConnection.mediaEngine.getCodecCapability(codec) == nil {
+			return &rtcerr.InvalidModificationError{Err: ErrUnsupportedCodec}
+		}
+	}
+
+	t.codecPreferences = codecs
+	return nil
+}
+
+// getCodecs returns the codecs to negotiate, honoring the preferences
+func (t *RTCRtpTransceiver) getCodecs(m *MediaEngine, kind RTCRtpCodecType) []*RTCRtpCodec {
+	if len(t.codecPreferences) == 0 {
+		return m.getCodecsByKind(kind)
+	}
+
+	var codecs []*RTCRtpCodec
+	added := func(codec *RTCRtpCodec) bool {
+		for _, c := range codecs {
+			if c == codec {
+				return true
+			}
+		}
+		return false
+	}
+
+	for _, preference := range t.codecPreferences {
+		codec := m.getCodecCapability(preference)
+		if codec == nil || codec.Type != kind || added(codec) {
+			continue
+		}
+		codecs = append(codecs, codec)
+
+		if rtx := m.getRTXCodec(codec.PayloadType); rtx != nil && !added(rtx) {
+			codecs = append(codecs, rtx)
+		}
+	}
+	return codecs
+}
+
 // Stop irreversibly stops the RTCRtpTransceiver
 func (t *RTCRtpTransceiver) Stop() error {
 	return errors.Errorf("TODO")
@@ -0,0 +1,44 @@
+//go:build !js
+
+package webrtc
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/pions/webrtc/pkg/rtcerr"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRTCRtpTransceiver_SetCodecPreferences(t *testing.T) {
+	RegisterDefaultCodecs()
+
+	pc, err := New(RTCConfiguration{})
+	assert.Nil(t, err)
+
+	track, err := pc.NewRTCSampleTrack(DefaultPayloadTypeVP8, "video", "pion")
+	assert.Nil(t, err)
+
+	_, err = pc.AddTrack(track)
+	assert.Nil(t, err)
+	transceiver := pc.GetTransceivers()[0]
+
+	h264, err := pc.mediaEngine.getCodec(DefaultPayloadTypeH264)
+	assert.Nil(t, err)
+	vp8, err := pc.mediaEngine.getCodec(DefaultPayloadTypeVP8)
+	assert.Nil(t, err)
+
+	assert.EqualError(t, transceiver.SetCodecPreferences([]RTCRtpCodecCapability{{MimeType: "video/AV1", ClockRate: 90000}}),
+		(&rtcerr.InvalidModificationError{Err: ErrUnsupportedCodec}).Error())
+
+	assert.Nil(t, transceiver.SetCodecPreferences([]RTCRtpCodecCapability{h264.RTCRtpCodecCapability, vp8.RTCRtpCodecCapability}))
+	offer, err := pc.CreateOffer(nil)
+	assert.Nil(t, err)
+	assert.True(t, strings.Contains(offer.SDP, "m=video 9 UDP/TLS/RTP/SAVPF 100 101 96 97\r\n"))
+	assert.False(t, strings.Contains(offer.SDP, "VP9"))
+
+	assert.Nil(t, transceiver.SetCodecPreferences(nil))
+	offer, err = pc.CreateOffer(nil)
+	assert.Nil(t, err)
+	assert.True(t, strings.Contains(offer.SDP, "VP9"))
+}
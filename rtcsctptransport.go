@@ -1,16 +1,37 @@
+//go:build !js
+
 package webrtc
 
 import (
 	"math"
+	"sync"
+
+	"github.com/pions/webrtc/internal/sctp"
 )
 
+// defaultSCTPMaxChannels is the number of streams offered to the remote peer
+// unless SettingEngine.SetSCTPMaxChannels sets another
+const defaultSCTPMaxChannels = 1024
+
+// sctpPort is the SCTP port of the association, announced in the data
+// channel section
+const sctpPort = 5000
+
+// defaultRemoteMaxMessageSize is the size of the largest message the remote
+// peer receives if it doesn't announce one
+// https://tools.ietf.org/html/draft-ietf-mmusic-sctp-sdp-26#section-6.1
+const defaultRemoteMaxMessageSize = 65536
+
 // RTCSctpTransport provides details about the SCTP transport.
 type RTCSctpTransport struct {
+	sync.RWMutex
+
 	// Transport represents the transport over which all SCTP packets for data
 	// channels will be sent and received.
 	Transport *RTCDtlsTransport
 
-	// State represents the current state of the SCTP transport.
+	// State represents the current state of the SCTP transport, read it
+	// while holding the lock of the transport.
 	State RTCSctpTransportState
 
 	// MaxMessageSize represents the maximum size of data that can be passed to
@@ -18,10 +39,18 @@ type RTCSctpTransport struct {
 	MaxMessageSize float64
 
 	// MaxChannels represents the maximum amount of RTCDataChannel's that can
-	// be used simultaneously.
+	// be used simultaneously. It is nil until the number of streams is
+	// negotiated with the remote description, and is lowered to the streams
+	// negotiated with the association once it is connected.
 	MaxChannels *uint16
 
-	// OnStateChange  func()
+	// OnStateChange designates an event handler which is called with the
+	// state of the transport whenever it changes. Set it while holding the
+	// lock of the transport.
+	OnStateChange func(RTCSctpTransportState)
+
+	// localMaxChannels is the number of streams offered to the remote peer
+	localMaxChannels uint16
 
 	// dataChannels
 	// dataChannels map[uint16]*RTCDataChannel
@@ -29,19 +58,23 @@ type RTCSctpTransport struct {
 
 func newRTCSctpTransport() *RTCSctpTransport {
 	res := &RTCSctpTransport{
-		State: RTCSctpTransportStateConnecting,
+		State:            RTCSctpTransportStateConnecting,
+		localMaxChannels: defaultSCTPMaxChannels,
 	}
 
-	res.updateMessageSize()
-	res.updateMaxChannels()
+	res.updateMessageSize(defaultRemoteMaxMessageSize)
 
 	return res
 }
 
-func (r *RTCSctpTransport) updateMessageSize() {
-	var remoteMaxMessageSize float64 = 65536 // TODO: get from SDP
-	var canSendSize float64 = 65536          // TODO: Get from SCTP implementation
+// updateMessageSize sets MaxMessageSize to the size of the largest message
+// both the remote peer and the association handle, remoteMaxMessageSize is
+// zero if the remote peer handles messages of any size
+func (r *RTCSctpTransport) updateMessageSize(remoteMaxMessageSize float64) {
+	var canSendSize float64 = sctp.MaxMessageSize
 
+	r.Lock()
+	defer r.Unlock()
 	r.MaxMessageSize = r.calcMessageSize(remoteMaxMessageSize, canSendSize)
 }
 
@@ -65,7 +98,68 @@ func (r *RTCSctpTransport) calcMessageSize(remoteMaxMessageSize, canSendSize flo
 	}
 }
 
-func (r *RTCSctpTransport) updateMaxChannels() {
-	val := uint16(65535)
-	r.MaxChannels = &val // TODO: Get from implementation
+// negotiateMaxChannels sets MaxChannels to the streams both peers offered,
+// remoteMaxChannels is zero if the remote peer didn't announce a limit
+func (r *RTCSctpTransport) negotiateMaxChannels(remoteMaxChannels uint16) uint16 {
+	r.Lock()
+	defer r.Unlock()
+
+	val := r.localMaxChannels
+	if remoteMaxChannels != 0 && remoteMaxChannels < val {
+		val = remoteMaxChannels
+	}
+	r.MaxChannels = &val
+	return val
+}
+
+// negotiatedMaxChannels returns MaxChannels, ok is false until it is
+// negotiated
+func (r *RTCSctpTransport) negotiatedMaxChannels() (maxChannels uint16, ok bool) {
+	r.RLock()
+	defer r.RUnlock()
+
+	if r.MaxChannels == nil {
+		return 0, false
+	}
+	return *r.MaxChannels, true
+}
+
+// maxChannels returns the negotiated MaxChannels, or the streams offered to
+// the remote peer until they are negotiated
+func (r *RTCSctpTransport) maxChannels() uint16 {
+	if maxChannels, ok := r.negotiatedMaxChannels(); ok {
+		return maxChannels
+	}
+
+	r.RLock()
+	defer r.RUnlock()
+	return r.localMaxChannels
+}
+
+// connected is called once the association is established with the number
+// of streams negotiated for it
+func (r *RTCSctpTransport) connected(streams uint16) {
+	r.Lock()
+	if r.MaxChannels == nil || streams < *r.MaxChannels {
+		r.MaxChannels = &streams
+	}
+	r.Unlock()
+
+	r.stateChange(RTCSctpTransportStateConnected)
+}
+
+func (r *RTCSctpTransport) stateChange(state RTCSctpTransportState) {
+	r.Lock()
+	// Closed is final
+	if r.State == state || r.State == RTCSctpTransportStateClosed {
+		r.Unlock()
+		return
+	}
+	r.State = state
+	onStateChange := r.OnStateChange
+	r.Unlock()
+
+	if onStateChange != nil {
+		onStateChange(state)
+	}
 }
@@ -0,0 +1,48 @@
+//go:build !js
+
+package webrtc
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRTCSctpTransport_updateMessageSize(t *testing.T) {
+	testCases := []struct {
+		remoteMaxMessageSize float64
+		expected             float64
+	}{
+		{defaultRemoteMaxMessageSize, 65535},
+		{1024, 1024},
+		{0, 65535},
+	}
+
+	for i, testCase := range testCases {
+		r := newRTCSctpTransport()
+		r.updateMessageSize(testCase.remoteMaxMessageSize)
+		assert.Equal(t, testCase.expected, r.MaxMessageSize, "testCase: %d", i)
+	}
+}
+
+func TestRTCSctpTransport_stateChange(t *testing.T) {
+	r := newRTCSctpTransport()
+	assert.Equal(t, RTCSctpTransportStateConnecting, r.State)
+	assert.Equal(t, uint16(1024), r.negotiateMaxChannels(2048))
+
+	var states []RTCSctpTransportState
+	r.OnStateChange = func(state RTCSctpTransportState) {
+		states = append(states, state)
+	}
+
+	// The association may negotiate fewer streams than the descriptions
+	r.connected(256)
+	assert.Equal(t, uint16(256), *r.MaxChannels)
+	r.connected(512)
+	assert.Equal(t, uint16(256), *r.MaxChannels)
+
+	r.stateChange(RTCSctpTransportStateClosed)
+	r.stateChange(RTCSctpTransportStateConnected)
+	assert.Equal(t, []RTCSctpTransportState{RTCSctpTransportStateConnected, RTCSctpTransportStateClosed}, states)
+	assert.Equal(t, RTCSctpTransportStateClosed, r.State)
+}
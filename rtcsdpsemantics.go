@@ -0,0 +1,54 @@
+package webrtc
+
+// RTCSdpSemantics determines which style of SDP offers and answers the
+// RTCPeerConnection produces and accepts. Unified Plan announces every track
+// in its own media section, Plan B announces all tracks of a kind in one
+// media section with an SSRC per track, as done by older Chrome versions.
+type RTCSdpSemantics int
+
+const (
+	// RTCSdpSemanticsUnifiedPlanWithFallback indicates to use Unified Plan,
+	// a remote description using Plan B is accepted and answered in kind.
+	RTCSdpSemanticsUnifiedPlanWithFallback RTCSdpSemantics = iota + 1
+
+	// RTCSdpSemanticsUnifiedPlan indicates to use Unified Plan only, a remote
+	// description using Plan B is rejected.
+	RTCSdpSemanticsUnifiedPlan
+
+	// RTCSdpSemanticsPlanB indicates to use Plan B, all tracks of a kind are
+	// offered in a single media section.
+	RTCSdpSemanticsPlanB
+)
+
+// This is done this way because of a linter.
+const (
+	rtcSdpSemanticsUnifiedPlanWithFallbackStr = "unified-plan-with-fallback"
+	rtcSdpSemanticsUnifiedPlanStr             = "unified-plan"
+	rtcSdpSemanticsPlanBStr                   = "plan-b"
+)
+
+func newRTCSdpSemantics(raw string) RTCSdpSemantics {
+	switch raw {
+	case rtcSdpSemanticsUnifiedPlanWithFallbackStr:
+		return RTCSdpSemanticsUnifiedPlanWithFallback
+	case rtcSdpSemanticsUnifiedPlanStr:
+		return RTCSdpSemanticsUnifiedPlan
+	case rtcSdpSemanticsPlanBStr:
+		return RTCSdpSemanticsPlanB
+	default:
+		return RTCSdpSemantics(Unknown)
+	}
+}
+
+func (s RTCSdpSemantics) String() string {
+	switch s {
+	case RTCSdpSemanticsUnifiedPlanWithFallback:
+		return rtcSdpSemanticsUnifiedPlanWithFallbackStr
+	case RTCSdpSemanticsUnifiedPlan:
+		return rtcSdpSemanticsUnifiedPlanStr
+	case RTCSdpSemanticsPlanB:
+		return rtcSdpSemanticsPlanBStr
+	default:
+		return ErrUnknownType.Error()
+	}
+}
@@ -0,0 +1,49 @@
+//go:build !js
+
+package webrtc
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewRTCSdpSemantics(t *testing.T) {
+	testCases := []struct {
+		semanticsString   string
+		expectedSemantics RTCSdpSemantics
+	}{
+		{"unknown", RTCSdpSemantics(Unknown)},
+		{"unified-plan-with-fallback", RTCSdpSemanticsUnifiedPlanWithFallback},
+		{"unified-plan", RTCSdpSemanticsUnifiedPlan},
+		{"plan-b", RTCSdpSemanticsPlanB},
+	}
+
+	for i, testCase := range testCases {
+		assert.Equal(t,
+			testCase.expectedSemantics,
+			newRTCSdpSemantics(testCase.semanticsString),
+			"testCase: %d %v", i, testCase,
+		)
+	}
+}
+
+func TestRTCSdpSemantics_String(t *testing.T) {
+	testCases := []struct {
+		semantics      RTCSdpSemantics
+		expectedString string
+	}{
+		{RTCSdpSemantics(Unknown), "unknown"},
+		{RTCSdpSemanticsUnifiedPlanWithFallback, "unified-plan-with-fallback"},
+		{RTCSdpSemanticsUnifiedPlan, "unified-plan"},
+		{RTCSdpSemanticsPlanB, "plan-b"},
+	}
+
+	for i, testCase := range testCases {
+		assert.Equal(t,
+			testCase.expectedString,
+			testCase.semantics.String(),
+			"testCase: %d %v", i, testCase,
+		)
+	}
+}
@@ -1,14 +1,52 @@
 package webrtc
 
 import (
+	"encoding/json"
+
 	"github.com/pions/webrtc/internal/sdp"
 )
 
 // RTCSessionDescription is used to expose local and remote session descriptions.
+// It is serialized to JSON the way browsers serialize theirs, as
+// {"type":"offer","sdp":"..."}, so descriptions signaled by browsers can be
+// unmarshaled directly.
+// https://www.w3.org/TR/webrtc/#rtcsessiondescription-class
 type RTCSessionDescription struct {
-	Type RTCSdpType `json:"type"`
-	Sdp  string     `json:"sdp"`
+	Type RTCSdpType
+	SDP  string
 
 	// This will never be initialized by callers, internal use only
 	parsed *sdp.SessionDescription
 }
+
+// copy returns a copy of the description, so the one held by the
+// RTCPeerConnection isn't modified through it
+func (d *RTCSessionDescription) copy() *RTCSessionDescription {
+	if d == nil {
+		return nil
+	}
+	c := *d
+	return &c
+}
+
+// rtcSessionDescriptionJSON is the JSON form of RTCSessionDescription
+// https://www.w3.org/TR/webrtc/#dom-rtcsessiondescriptioninit
+type rtcSessionDescriptionJSON struct {
+	Type RTCSdpType `json:"type"`
+	SDP  string     `json:"sdp"`
+}
+
+// MarshalJSON enables JSON marshaling of a RTCSessionDescription
+func (d RTCSessionDescription) MarshalJSON() ([]byte, error) {
+	return json.Marshal(rtcSessionDescriptionJSON{Type: d.Type, SDP: d.SDP})
+}
+
+// UnmarshalJSON enables JSON unmarshaling of a RTCSessionDescription
+func (d *RTCSessionDescription) UnmarshalJSON(b []byte) error {
+	var desc rtcSessionDescriptionJSON
+	if err := json.Unmarshal(b, &desc); err != nil {
+		return err
+	}
+	*d = RTCSessionDescription{Type: desc.Type, SDP: desc.SDP}
+	return nil
+}
@@ -1,3 +1,5 @@
+//go:build !js
+
 package webrtc
 
 import (
@@ -13,11 +15,11 @@ func TestRTCSessionDescription_JSON(t *testing.T) {
 		expectedString string
 		unmarshalErr   error
 	}{
-		{RTCSessionDescription{Type: RTCSdpTypeOffer, Sdp: "sdp"}, `{"type":"offer","sdp":"sdp"}`, nil},
-		{RTCSessionDescription{Type: RTCSdpTypePranswer, Sdp: "sdp"}, `{"type":"pranswer","sdp":"sdp"}`, nil},
-		{RTCSessionDescription{Type: RTCSdpTypeAnswer, Sdp: "sdp"}, `{"type":"answer","sdp":"sdp"}`, nil},
-		{RTCSessionDescription{Type: RTCSdpTypeRollback, Sdp: "sdp"}, `{"type":"rollback","sdp":"sdp"}`, nil},
-		{RTCSessionDescription{Type: RTCSdpType(Unknown), Sdp: "sdp"}, `{"type":"unknown","sdp":"sdp"}`, ErrUnknownType},
+		{RTCSessionDescription{Type: RTCSdpTypeOffer, SDP: "sdp"}, `{"type":"offer","sdp":"sdp"}`, nil},
+		{RTCSessionDescription{Type: RTCSdpTypePranswer, SDP: "sdp"}, `{"type":"pranswer","sdp":"sdp"}`, nil},
+		{RTCSessionDescription{Type: RTCSdpTypeAnswer, SDP: "sdp"}, `{"type":"answer","sdp":"sdp"}`, nil},
+		{RTCSessionDescription{Type: RTCSdpTypeRollback, SDP: "sdp"}, `{"type":"rollback","sdp":"sdp"}`, nil},
+		{RTCSessionDescription{Type: RTCSdpType(Unknown), SDP: "sdp"}, `{"type":"unknown","sdp":"sdp"}`, ErrUnknownType},
 	}
 
 	for i, testCase := range testCases {
@@ -57,3 +59,13 @@ func TestRTCSessionDescription_JSON(t *testing.T) {
 		)
 	}
 }
+
+func TestRTCSessionDescription_UnmarshalBrowserJSON(t *testing.T) {
+	// Browsers may order the keys differently
+	var desc RTCSessionDescription
+	err := json.Unmarshal([]byte(`{"sdp":"v=0\r\n","type":"answer"}`), &desc)
+	assert.Nil(t, err)
+	assert.Equal(t, RTCSessionDescription{Type: RTCSdpTypeAnswer, SDP: "v=0\r\n"}, desc)
+
+	assert.NotNil(t, json.Unmarshal([]byte(`{"type":"answer","sdp":0}`), &desc))
+}
@@ -0,0 +1,321 @@
+//go:build !js
+
+package webrtc
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/pions/webrtc/internal/network"
+	"github.com/pions/webrtc/pkg/ice"
+)
+
+// RTCStatsType indicates the type of the object stats describe
+// https://www.w3.org/TR/webrtc-stats/#rtcstatstype-str*
+type RTCStatsType int
+
+const (
+	// RTCStatsTypeCandidatePair indicates RTCIceCandidatePairStats
+	RTCStatsTypeCandidatePair RTCStatsType = iota + 1
+
+	// RTCStatsTypeDataChannel indicates RTCDataChannelStats
+	RTCStatsTypeDataChannel
+
+	// RTCStatsTypeRemoteInboundRTP indicates RTCRemoteInboundRTPStreamStats
+	RTCStatsTypeRemoteInboundRTP
+
+	// RTCStatsTypeTransport indicates RTCTransportStats
+	RTCStatsTypeTransport
+
+	// RTCStatsTypeRemoteOutboundRTP indicates RTCRemoteOutboundRTPStreamStats
+	RTCStatsTypeRemoteOutboundRTP
+)
+
+func (t RTCStatsType) String() string {
+	switch t {
+	case RTCStatsTypeCandidatePair:
+		return "candidate-pair"
+	case RTCStatsTypeDataChannel:
+		return "data-channel"
+	case RTCStatsTypeRemoteInboundRTP:
+		return "remote-inbound-rtp"
+	case RTCStatsTypeTransport:
+		return "transport"
+	case RTCStatsTypeRemoteOutboundRTP:
+		return "remote-outbound-rtp"
+	default:
+		return ErrUnknownType.Error()
+	}
+}
+
+// RTCStats are the statistics of an object of an RTCPeerConnection, switch
+// on the type of the stats for their members, like
+// *RTCIceCandidatePairStats
+type RTCStats interface {
+	GetBase() *RTCStatsBase
+}
+
+// RTCStatsBase holds the members every type of stats has
+// https://www.w3.org/TR/webrtc-stats/#dom-rtcstats
+type RTCStatsBase struct {
+	// ID identifies the object the stats describe, it is the same in every
+	// report
+	ID string
+
+	Type      RTCStatsType
+	Timestamp time.Time
+}
+
+// GetBase returns the members every type of stats has
+func (s *RTCStatsBase) GetBase() *RTCStatsBase {
+	return s
+}
+
+// RTCStatsReport maps the IDs of the objects of an RTCPeerConnection to their
+// stats, as returned by RTCPeerConnection.GetStats
+// https://www.w3.org/TR/webrtc/#dom-rtcstatsreport
+type RTCStatsReport map[string]RTCStats
+
+// RTCIceCandidatePairStats are the statistics of a candidate pair ICE
+// checked, the connectivity checks and consent requests sent and received on
+// it and the bytes of every protocol exchanged on it
+// https://www.w3.org/TR/webrtc-stats/#candidatepair-dict*
+type RTCIceCandidatePairStats struct {
+	RTCStatsBase
+
+	Local  *RTCIceCandidate
+	Remote *RTCIceCandidate
+	State  RTCStatsIceCandidatePairState
+
+	// Nominated is set once ICE selected the pair, it stays set after
+	// another pair is selected
+	Nominated bool
+
+	RequestsSent      uint64
+	RequestsReceived  uint64
+	ResponsesSent     uint64
+	ResponsesReceived uint64
+
+	// CurrentRoundTripTime is the round trip time of the last check answered
+	// on the pair, TotalRoundTripTime the sum of those of every answered
+	// check, divide it by ResponsesReceived for the average
+	CurrentRoundTripTime time.Duration
+	TotalRoundTripTime   time.Duration
+
+	BytesSent     uint64
+	BytesReceived uint64
+}
+
+// RTCDataChannelStats are the statistics of a data channel, the messages
+// sent and received on it and the bytes of their application data
+// https://www.w3.org/TR/webrtc-stats/#dcstats-dict*
+type RTCDataChannelStats struct {
+	RTCStatsBase
+
+	Label                 string
+	Protocol              string
+	DataChannelIdentifier uint16
+	State                 RTCDataChannelState
+
+	MessagesSent     uint64
+	BytesSent        uint64
+	MessagesReceived uint64
+	BytesReceived    uint64
+}
+
+// RTCRemoteInboundRTPStreamStats are the statistics of a stream sent as the
+// remote peer reported its reception in the last RTCP reception report, the
+// Timestamp is when the report arrived
+// https://www.w3.org/TR/webrtc-stats/#remoteinboundrtpstats-dict*
+type RTCRemoteInboundRTPStreamStats struct {
+	RTCStatsBase
+
+	SSRC uint32
+	Kind RTCRtpCodecType
+
+	// FractionLost is the fraction of the packets lost since the previous
+	// report, PacketsLost the packets lost since the stream started, which is
+	// negative if duplicates were received
+	FractionLost float64
+	PacketsLost  int64
+
+	// Jitter is the interarrival jitter, zero if the clock rate of the codec
+	// is unknown
+	Jitter time.Duration
+
+	// RoundTripTime is measured from the last report referring to one of the
+	// sender reports sent every second, it is zero until one arrives.
+	// TotalRoundTripTime is the sum of the RoundTripTimeMeasurements.
+	RoundTripTime             time.Duration
+	TotalRoundTripTime        time.Duration
+	RoundTripTimeMeasurements uint64
+}
+
+// RTCRemoteOutboundRTPStreamStats are the statistics of a stream received as
+// the remote peer reported sending it in the last RTCP sender report, the
+// Timestamp is when the report arrived
+// https://www.w3.org/TR/webrtc-stats/#remoteoutboundrtpstats-dict*
+type RTCRemoteOutboundRTPStreamStats struct {
+	RTCStatsBase
+
+	SSRC uint32
+	Kind RTCRtpCodecType
+
+	PacketsSent uint64
+	BytesSent   uint64
+
+	// RemoteTimestamp is the wallclock of the remote peer when it sent the
+	// report
+	RemoteTimestamp time.Time
+
+	// RoundTripTime is measured from the last DLRR report answering a
+	// receiver reference time, it is zero unless
+	// SettingEngine.SetReceiverReferenceTimeInterval enables sending them and
+	// the remote peer answers. TotalRoundTripTime is the sum of the
+	// RoundTripTimeMeasurements.
+	RoundTripTime             time.Duration
+	TotalRoundTripTime        time.Duration
+	RoundTripTimeMeasurements uint64
+}
+
+// RTCTransportStats are the statistics of the transport every media section
+// is bundled on
+// https://www.w3.org/TR/webrtc-stats/#transportstats-dict*
+type RTCTransportStats struct {
+	RTCStatsBase
+
+	// BytesSent and BytesReceived are the bytes of every candidate pair
+	BytesSent     uint64
+	BytesReceived uint64
+
+	DTLSState RTCDtlsTransportState
+
+	// SelectedCandidatePairID is the ID of the stats of the candidate pair
+	// ICE selected, empty until one is selected
+	SelectedCandidatePairID string
+
+	// SRTPAuthFailures counts the SRTP and SRTCP packets received which
+	// failed authentication or replay protection
+	SRTPAuthFailures uint64
+}
+
+// rtcTransportStatsID is the ID of the stats of the transport
+const rtcTransportStatsID = "RTCTransport_0"
+
+// candidatePairStatsID returns the ID of the stats of the candidate pair
+func candidatePairStatsID(local, remote ice.Candidate) string {
+	return fmt.Sprintf("RTCIceCandidatePair_%s_%s_%s", local.GetBase().Protocol, local, remote)
+}
+
+func newRTCIceCandidatePairStats(s network.CandidatePairStats, timestamp time.Time) *RTCIceCandidatePairStats {
+	return &RTCIceCandidatePairStats{
+		RTCStatsBase: RTCStatsBase{
+			ID:        candidatePairStatsID(s.Local, s.Remote),
+			Type:      RTCStatsTypeCandidatePair,
+			Timestamp: timestamp,
+		},
+		Local:                newRTCIceCandidate(s.Local),
+		Remote:               newRTCIceCandidate(s.Remote),
+		State:                newRTCStatsIceCandidatePairState(s.State.String()),
+		Nominated:            s.Nominated,
+		RequestsSent:         s.RequestsSent,
+		RequestsReceived:     s.RequestsReceived,
+		ResponsesSent:        s.ResponsesSent,
+		ResponsesReceived:    s.ResponsesReceived,
+		CurrentRoundTripTime: s.CurrentRoundTripTime,
+		TotalRoundTripTime:   s.TotalRoundTripTime,
+		BytesSent:            s.BytesSent,
+		BytesReceived:        s.BytesReceived,
+	}
+}
+
+func newRTCDataChannelStats(d *RTCDataChannel, timestamp time.Time) *RTCDataChannelStats {
+	d.RLock()
+	stats := &RTCDataChannelStats{
+		RTCStatsBase: RTCStatsBase{
+			ID:        fmt.Sprintf("RTCDataChannel_%d", *d.ID),
+			Type:      RTCStatsTypeDataChannel,
+			Timestamp: timestamp,
+		},
+		Label:                 d.Label,
+		Protocol:              d.Protocol,
+		DataChannelIdentifier: *d.ID,
+		State:                 d.ReadyState,
+	}
+	d.RUnlock()
+
+	d.counters.Lock()
+	defer d.counters.Unlock()
+	stats.MessagesSent = d.counters.messagesSent
+	stats.BytesSent = d.counters.bytesSent
+	stats.MessagesReceived = d.counters.messagesReceived
+	stats.BytesReceived = d.counters.bytesReceived
+	return stats
+}
+
+// remoteInboundStats returns the stats of the streams of the sender the
+// remote peer reported the reception of
+func (s *RTCRtpSender) remoteInboundStats() []*RTCRemoteInboundRTPStreamStats {
+	s.RLock()
+	defer s.RUnlock()
+
+	var kind RTCRtpCodecType
+	var clockRate uint32
+	if s.Track != nil {
+		kind = s.Track.Kind
+		if s.Track.Codec != nil {
+			clockRate = s.Track.Codec.ClockRate
+		}
+	}
+
+	var stats []*RTCRemoteInboundRTPStreamStats
+	for ssrc, r := range s.remoteInbound {
+		st := &RTCRemoteInboundRTPStreamStats{
+			RTCStatsBase: RTCStatsBase{
+				ID:        fmt.Sprintf("RTCRemoteInboundRTPStream_%d", ssrc),
+				Type:      RTCStatsTypeRemoteInboundRTP,
+				Timestamp: r.receivedAt,
+			},
+			SSRC:         ssrc,
+			Kind:         kind,
+			FractionLost: float64(r.report.FractionLost) / 256,
+			// The cumulative loss is a signed 24 bit integer
+			PacketsLost:               int64(int32(r.report.TotalLost<<8) >> 8),
+			RoundTripTime:             r.roundTripTime,
+			TotalRoundTripTime:        r.totalRoundTripTime,
+			RoundTripTimeMeasurements: r.roundTripTimeMeasurements,
+		}
+		if clockRate != 0 {
+			st.Jitter = time.Duration(r.report.Jitter) * time.Second / time.Duration(clockRate)
+		}
+		stats = append(stats, st)
+	}
+	return stats
+}
+
+// remoteOutboundStats returns the stats of the Track of the receiver as the
+// remote peer reported sending it, nil until a sender report arrives
+func (r *RTCRtpReceiver) remoteOutboundStats() *RTCRemoteOutboundRTPStreamStats {
+	r.RLock()
+	defer r.RUnlock()
+
+	o := r.remoteOutbound
+	if o.report == nil || r.Track == nil {
+		return nil
+	}
+	return &RTCRemoteOutboundRTPStreamStats{
+		RTCStatsBase: RTCStatsBase{
+			ID:        fmt.Sprintf("RTCRemoteOutboundRTPStream_%d", o.report.SSRC),
+			Type:      RTCStatsTypeRemoteOutboundRTP,
+			Timestamp: o.receivedAt,
+		},
+		SSRC:                      o.report.SSRC,
+		Kind:                      r.Track.Kind,
+		PacketsSent:               uint64(o.report.PacketCount),
+		BytesSent:                 uint64(o.report.OctetCount),
+		RemoteTimestamp:           ntpToTime(o.report.NTPTime),
+		RoundTripTime:             o.roundTripTime,
+		TotalRoundTripTime:        o.totalRoundTripTime,
+		RoundTripTimeMeasurements: o.roundTripTimeMeasurements,
+	}
+}
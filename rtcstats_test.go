@@ -0,0 +1,31 @@
+//go:build !js
+
+package webrtc
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRTCStatsType_String(t *testing.T) {
+	testCases := []struct {
+		statsType      RTCStatsType
+		expectedString string
+	}{
+		{RTCStatsType(Unknown), "unknown"},
+		{RTCStatsTypeCandidatePair, "candidate-pair"},
+		{RTCStatsTypeDataChannel, "data-channel"},
+		{RTCStatsTypeRemoteInboundRTP, "remote-inbound-rtp"},
+		{RTCStatsTypeTransport, "transport"},
+		{RTCStatsTypeRemoteOutboundRTP, "remote-outbound-rtp"},
+	}
+
+	for i, testCase := range testCases {
+		assert.Equal(t,
+			testCase.expectedString,
+			testCase.statsType.String(),
+			"testCase: %d %v", i, testCase,
+		)
+	}
+}
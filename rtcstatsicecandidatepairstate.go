@@ -0,0 +1,53 @@
+package webrtc
+
+// RTCStatsIceCandidatePairState is the state of the connectivity checks of
+// a candidate pair
+// https://www.w3.org/TR/webrtc-stats/#dom-rtcstatsicecandidatepairstate
+type RTCStatsIceCandidatePairState int
+
+const (
+	// RTCStatsIceCandidatePairStateInProgress indicates a check has been sent
+	// on the pair but none succeeded yet.
+	RTCStatsIceCandidatePairStateInProgress RTCStatsIceCandidatePairState = iota + 1
+
+	// RTCStatsIceCandidatePairStateSucceeded indicates a check on the pair
+	// succeeded.
+	RTCStatsIceCandidatePairStateSucceeded
+
+	// RTCStatsIceCandidatePairStateFailed indicates the pair was selected and
+	// the remote peer stopped granting consent to send on it.
+	RTCStatsIceCandidatePairStateFailed
+)
+
+// This is done this way because of a linter.
+const (
+	rtcStatsIceCandidatePairStateInProgressStr = "in-progress"
+	rtcStatsIceCandidatePairStateSucceededStr  = "succeeded"
+	rtcStatsIceCandidatePairStateFailedStr     = "failed"
+)
+
+func newRTCStatsIceCandidatePairState(raw string) RTCStatsIceCandidatePairState {
+	switch raw {
+	case rtcStatsIceCandidatePairStateInProgressStr:
+		return RTCStatsIceCandidatePairStateInProgress
+	case rtcStatsIceCandidatePairStateSucceededStr:
+		return RTCStatsIceCandidatePairStateSucceeded
+	case rtcStatsIceCandidatePairStateFailedStr:
+		return RTCStatsIceCandidatePairStateFailed
+	default:
+		return RTCStatsIceCandidatePairState(Unknown)
+	}
+}
+
+func (t RTCStatsIceCandidatePairState) String() string {
+	switch t {
+	case RTCStatsIceCandidatePairStateInProgress:
+		return rtcStatsIceCandidatePairStateInProgressStr
+	case RTCStatsIceCandidatePairStateSucceeded:
+		return rtcStatsIceCandidatePairStateSucceededStr
+	case RTCStatsIceCandidatePairStateFailed:
+		return rtcStatsIceCandidatePairStateFailedStr
+	default:
+		return ErrUnknownType.Error()
+	}
+}
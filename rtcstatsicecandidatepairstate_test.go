@@ -0,0 +1,49 @@
+//go:build !js
+
+package webrtc
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewRTCStatsIceCandidatePairState(t *testing.T) {
+	testCases := []struct {
+		stateString   string
+		expectedState RTCStatsIceCandidatePairState
+	}{
+		{"unknown", RTCStatsIceCandidatePairState(Unknown)},
+		{"in-progress", RTCStatsIceCandidatePairStateInProgress},
+		{"succeeded", RTCStatsIceCandidatePairStateSucceeded},
+		{"failed", RTCStatsIceCandidatePairStateFailed},
+	}
+
+	for i, testCase := range testCases {
+		assert.Equal(t,
+			testCase.expectedState,
+			newRTCStatsIceCandidatePairState(testCase.stateString),
+			"testCase: %d %v", i, testCase,
+		)
+	}
+}
+
+func TestRTCStatsIceCandidatePairState_String(t *testing.T) {
+	testCases := []struct {
+		state          RTCStatsIceCandidatePairState
+		expectedString string
+	}{
+		{RTCStatsIceCandidatePairState(Unknown), "unknown"},
+		{RTCStatsIceCandidatePairStateInProgress, "in-progress"},
+		{RTCStatsIceCandidatePairStateSucceeded, "succeeded"},
+		{RTCStatsIceCandidatePairStateFailed, "failed"},
+	}
+
+	for i, testCase := range testCases {
+		assert.Equal(t,
+			testCase.expectedString,
+			testCase.state.String(),
+			"testCase: %d %v", i, testCase,
+		)
+	}
+}
@@ -0,0 +1,52 @@
+package webrtc
+
+// RTCTrackQueuePolicy decides what happens to a sample written to the
+// Samples channel of a local track while its queue is full, which happens
+// when samples are written faster than they can be sent.
+type RTCTrackQueuePolicy int
+
+const (
+	// RTCTrackQueuePolicyBlock indicates the writer blocks until a sample of
+	// the queue is sent.
+	RTCTrackQueuePolicyBlock RTCTrackQueuePolicy = iota + 1
+
+	// RTCTrackQueuePolicyDropOldest indicates the sample which has been
+	// queued the longest is discarded to make room for the new one.
+	RTCTrackQueuePolicyDropOldest
+
+	// RTCTrackQueuePolicyDropNewest indicates the new sample is discarded.
+	RTCTrackQueuePolicyDropNewest
+)
+
+// This is done this way because of a linter.
+const (
+	rtcTrackQueuePolicyBlockStr      = "block"
+	rtcTrackQueuePolicyDropOldestStr = "drop-oldest"
+	rtcTrackQueuePolicyDropNewestStr = "drop-newest"
+)
+
+func newRTCTrackQueuePolicy(raw string) RTCTrackQueuePolicy {
+	switch raw {
+	case rtcTrackQueuePolicyBlockStr:
+		return RTCTrackQueuePolicyBlock
+	case rtcTrackQueuePolicyDropOldestStr:
+		return RTCTrackQueuePolicyDropOldest
+	case rtcTrackQueuePolicyDropNewestStr:
+		return RTCTrackQueuePolicyDropNewest
+	default:
+		return RTCTrackQueuePolicy(Unknown)
+	}
+}
+
+func (t RTCTrackQueuePolicy) String() string {
+	switch t {
+	case RTCTrackQueuePolicyBlock:
+		return rtcTrackQueuePolicyBlockStr
+	case RTCTrackQueuePolicyDropOldest:
+		return rtcTrackQueuePolicyDropOldestStr
+	case RTCTrackQueuePolicyDropNewest:
+		return rtcTrackQueuePolicyDropNewestStr
+	default:
+		return ErrUnknownType.Error()
+	}
+}
@@ -0,0 +1,49 @@
+//go:build !js
+
+package webrtc
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewRTCTrackQueuePolicy(t *testing.T) {
+	testCases := []struct {
+		policyString   string
+		expectedPolicy RTCTrackQueuePolicy
+	}{
+		{"unknown", RTCTrackQueuePolicy(Unknown)},
+		{"block", RTCTrackQueuePolicyBlock},
+		{"drop-oldest", RTCTrackQueuePolicyDropOldest},
+		{"drop-newest", RTCTrackQueuePolicyDropNewest},
+	}
+
+	for i, testCase := range testCases {
+		assert.Equal(t,
+			testCase.expectedPolicy,
+			newRTCTrackQueuePolicy(testCase.policyString),
+			"testCase: %d %v", i, testCase,
+		)
+	}
+}
+
+func TestRTCTrackQueuePolicy_String(t *testing.T) {
+	testCases := []struct {
+		policy         RTCTrackQueuePolicy
+		expectedString string
+	}{
+		{RTCTrackQueuePolicy(Unknown), "unknown"},
+		{RTCTrackQueuePolicyBlock, "block"},
+		{RTCTrackQueuePolicyDropOldest, "drop-oldest"},
+		{RTCTrackQueuePolicyDropNewest, "drop-newest"},
+	}
+
+	for i, testCase := range testCases {
+		assert.Equal(t,
+			testCase.expectedString,
+			testCase.policy.String(),
+			"testCase: %d %v", i, testCase,
+		)
+	}
+}
@@ -0,0 +1,76 @@
+package webrtc
+
+import (
+	"sync"
+	"time"
+)
+
+// workerIdleTimeout is how long a worker of the scheduler waits for a task
+// before it exits
+const workerIdleTimeout = 10 * time.Second
+
+// scheduler runs the background work of all the RTCPeerConnections on a set
+// of goroutines they share, instead of a goroutine per connection, track and
+// data channel. A task runs on an idle worker if there is one and on a new
+// worker otherwise, so a task which blocks never delays the others. Workers
+// exit once they have been idle for workerIdleTimeout.
+//
+// The scheduler doesn't order tasks, the work of an object which must run in
+// order is pushed to its operations, which run one at a time.
+type scheduler struct {
+	tasks chan func()
+}
+
+// workers is the scheduler shared by all the RTCPeerConnections
+var workers = &scheduler{tasks: make(chan func())}
+
+// schedule runs the task on a worker
+func (s *scheduler) schedule(task func()) {
+	select {
+	case s.tasks <- task:
+	default:
+		go s.work(task)
+	}
+}
+
+func (s *scheduler) work(task func()) {
+	idle := time.NewTimer(workerIdleTimeout)
+	defer idle.Stop()
+
+	for {
+		task()
+
+		if !idle.Stop() {
+			select {
+			case <-idle.C:
+			default:
+			}
+		}
+		idle.Reset(workerIdleTimeout)
+
+		select {
+		case task = <-s.tasks:
+		case <-idle.C:
+			return
+		}
+	}
+}
+
+// every runs the task every interval until it returns false. No goroutine
+// waits between the runs, a run taking longer than the interval delays the
+// next one.
+func (s *scheduler) every(interval time.Duration, task func() bool) {
+	var lock sync.Mutex
+	var timer *time.Timer
+
+	lock.Lock()
+	defer lock.Unlock()
+	timer = time.AfterFunc(interval, func() {
+		if !task() {
+			return
+		}
+		lock.Lock()
+		timer.Reset(interval)
+		lock.Unlock()
+	})
+}
@@ -0,0 +1,58 @@
+//go:build !js
+
+package webrtc
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScheduler_Schedule(t *testing.T) {
+	s := &scheduler{tasks: make(chan func())}
+
+	// A blocked task doesn't delay the others
+	block := make(chan struct{})
+	defer close(block)
+	s.schedule(func() { <-block })
+
+	done := make(chan struct{})
+	s.schedule(func() { close(done) })
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		assert.Fail(t, "task delayed by a blocked task")
+	}
+
+	// The idle worker takes the next task
+	ran := make(chan struct{})
+	select {
+	case s.tasks <- func() { close(ran) }:
+	case <-time.After(5 * time.Second):
+		assert.Fail(t, "no idle worker")
+		return
+	}
+	<-ran
+}
+
+func TestScheduler_Every(t *testing.T) {
+	var runs int32
+	stopped := make(chan struct{})
+	workers.every(time.Millisecond, func() bool {
+		if atomic.AddInt32(&runs, 1) == 3 {
+			close(stopped)
+			return false
+		}
+		return true
+	})
+
+	select {
+	case <-stopped:
+	case <-time.After(5 * time.Second):
+		assert.Fail(t, "task not run every interval")
+	}
+	time.Sleep(10 * time.Millisecond)
+	assert.Equal(t, int32(3), atomic.LoadInt32(&runs))
+}
@@ -0,0 +1,710 @@
+//go:build !js
+
+package webrtc
+
+import (
+	"net"
+	"strings"
+	"time"
+
+	"github.com/pions/webrtc/internal/network"
+	"github.com/pions/webrtc/internal/sdp"
+	"github.com/pions/webrtc/pkg/ice"
+	"github.com/pions/webrtc/pkg/interceptor"
+	"github.com/pions/webrtc/pkg/logging"
+	"github.com/pions/webrtc/pkg/pcap"
+	"golang.org/x/net/proxy"
+)
+
+// SettingEngine allows influencing behavior in ways that are not supported
+// by the WebRTC API. This allows server operators to tune RTCPeerConnections
+// without deviating from the WebRTC API elsewhere. The zero value uses the
+// defaults, pass it to NewAPI with WithSettingEngine.
+type SettingEngine struct {
+	timeout struct {
+		ICECandidate    *time.Duration
+		ICECheck        *time.Duration
+		ICEConnection   *time.Duration
+		ICEDisconnected *time.Duration
+		ICEKeepalive    *time.Duration
+		ICEMulticastDNS *time.Duration
+	}
+	mDNSResolver struct {
+		Resolver ice.MulticastDNSResolver
+		Set      bool
+	}
+	ephemeralUDP struct {
+		PortMin uint16
+		PortMax uint16
+	}
+	icePriority struct {
+		TypePreferences map[RTCIceCandidateType]uint16
+		LocalPreference func(interfaceName string, preference uint16) uint16
+		Formula         func(typePreference, localPreference, component uint16) uint32
+	}
+	interfaceFilter        func(string) bool
+	candidateTypes         []RTCIceCandidateType
+	iceTCP                 bool
+	udpMux                 *UDPMux
+	udpBatchSize           int
+	net                    Net
+	proxyDialer            proxy.Dialer
+	srtpProtectionProfiles []SRTPProtectionProfile
+	nat1To1                struct {
+		IPs     map[string]string
+		AsSrflx bool
+	}
+	iceCredentials struct {
+		Ufrag string
+		Pwd   string
+	}
+	certificate          *RTCCertificate
+	ssrcGenerator        func() uint32
+	sdpLimits            *sdp.Limits
+	sctpMaxChannels      uint16
+	sctpPortAttributes   bool
+	iceCredentialRefresh func(RTCIceServer) (RTCIceServer, error)
+	unhandledEventWindow time.Duration
+	rtpKeepaliveInterval time.Duration
+	rrtrInterval         time.Duration
+	loggerFactory        logging.LoggerFactory
+	interceptor          interceptor.Interceptor
+	packetCapture        struct {
+		Handler func(pcap.Packet)
+		SCTP    bool
+	}
+	trackQueue struct {
+		Policy RTCTrackQueuePolicy
+		Size   int
+	}
+	packetizerMTU int
+}
+
+// defaultTrackQueueSize is the number of samples queued by the Samples
+// channel of local tracks
+const defaultTrackQueueSize = 15
+
+// The MTU of the packets of local tracks before SRTP adds its tag, the
+// default leaves room for the IP, UDP, TURN and SRTP overhead on Ethernet.
+// The minimum leaves room for the RTP and payload headers and some payload.
+const (
+	defaultPacketizerMTU = 1400
+	minPacketizerMTU     = 64
+)
+
+// Default limits of remote descriptions, generous enough for descriptions
+// carrying dozens of tracks
+const (
+	defaultSDPMaxSize       = 256 * 1024
+	defaultSDPMaxLineLength = 4096
+	defaultSDPMaxAttributes = 4096
+)
+
+// SRTPProtectionProfile is a protection profile SRTP is keyed with by the
+// DTLS handshake
+// https://tools.ietf.org/html/rfc5764#section-4.1.2
+type SRTPProtectionProfile int
+
+const (
+	// SRTPProtectionProfileAES128CM80 indicates AES-128 in counter mode with
+	// an 80 bit HMAC-SHA1 authentication tag, SRTP_AES128_CM_HMAC_SHA1_80.
+	SRTPProtectionProfileAES128CM80 SRTPProtectionProfile = iota + 1
+
+	// SRTPProtectionProfileAES128CM32 indicates AES-128 in counter mode with
+	// a 32 bit HMAC-SHA1 authentication tag, SRTP_AES128_CM_HMAC_SHA1_32.
+	SRTPProtectionProfileAES128CM32
+
+	// SRTPProtectionProfileAEADAES128GCM indicates AES-128 in Galois/Counter
+	// Mode, which encrypts and authenticates at once, SRTP_AEAD_AES_128_GCM.
+	// https://tools.ietf.org/html/rfc7714
+	SRTPProtectionProfileAEADAES128GCM
+
+	// SRTPProtectionProfileAEADAES256GCM indicates AES-256 in Galois/Counter
+	// Mode, SRTP_AEAD_AES_256_GCM.
+	SRTPProtectionProfileAEADAES256GCM
+)
+
+func (p SRTPProtectionProfile) String() string {
+	switch p {
+	case SRTPProtectionProfileAES128CM80:
+		return "SRTP_AES128_CM_SHA1_80"
+	case SRTPProtectionProfileAES128CM32:
+		return "SRTP_AES128_CM_SHA1_32"
+	case SRTPProtectionProfileAEADAES128GCM:
+		return "SRTP_AEAD_AES_128_GCM"
+	case SRTPProtectionProfileAEADAES256GCM:
+		return "SRTP_AEAD_AES_256_GCM"
+	default:
+		return ErrUnknownType.Error()
+	}
+}
+
+// SetICECandidateTimeout sets how long the ICE agent waits for the first
+// remote candidate after the remote description is set, see
+// RTCPeerConnection.SetICECandidateTimeout.
+func (e *SettingEngine) SetICECandidateTimeout(timeout time.Duration) {
+	e.timeout.ICECandidate = &timeout
+}
+
+// SetICECheckInterval sets how often the ICE agent checks connectivity until
+// a candidate pair is selected, and requests consent and sends keepalives on
+// the selected pair after. The other ICE timeouts and intervals take effect
+// at these checks, shorter ones need a shorter check interval. Embedded
+// devices may check less often to save battery, servers more often to detect
+// dead peers sooner. The default is 2 seconds.
+func (e *SettingEngine) SetICECheckInterval(interval time.Duration) {
+	e.timeout.ICECheck = &interval
+}
+
+// SetICEConnectionTimeout sets how long the selected candidate pair may go
+// without the remote peer granting consent to send on it before ICE fails,
+// RFC 7675. The default is 30 seconds.
+func (e *SettingEngine) SetICEConnectionTimeout(timeout time.Duration) {
+	e.timeout.ICEConnection = &timeout
+}
+
+// SetICEDisconnectedTimeout sets how long the selected candidate pair may go
+// without the remote peer granting consent to send on it before ICE is
+// disconnected, ICE is connected again once consent is granted. Consent is
+// requested every 5 seconds, or twice within shorter timeouts. The default
+// is 10 seconds.
+func (e *SettingEngine) SetICEDisconnectedTimeout(timeout time.Duration) {
+	e.timeout.ICEDisconnected = &timeout
+}
+
+// SetICEKeepaliveInterval sets how long the selected candidate pair may go
+// without sending anything before a keepalive is sent on it. The default is
+// 10 seconds.
+func (e *SettingEngine) SetICEKeepaliveInterval(interval time.Duration) {
+	e.timeout.ICEKeepalive = &interval
+}
+
+// SetICECredentialRefresher sets the callback renewing the time-limited
+// credentials of TURN servers, like the ones of NewTimeLimitedRTCIceServer,
+// which expire within a minute of the allocation of a relay candidate or of
+// its next refresh. It returns the server with new credentials, the server
+// keeps its credentials if it fails.
+func (e *SettingEngine) SetICECredentialRefresher(refresh func(server RTCIceServer) (RTCIceServer, error)) {
+	e.iceCredentialRefresh = refresh
+}
+
+// SetICEMulticastDNSTimeout sets how long the .local hostname of a remote
+// candidate is resolved before the candidate is discarded, see
+// RTCPeerConnection.SetICEMulticastDNSTimeout.
+func (e *SettingEngine) SetICEMulticastDNSTimeout(timeout time.Duration) {
+	e.timeout.ICEMulticastDNS = &timeout
+}
+
+// SetICEMulticastDNSResolver sets the resolver of the .local hostnames of
+// remote candidates, see RTCPeerConnection.SetICEMulticastDNSResolver.
+func (e *SettingEngine) SetICEMulticastDNSResolver(resolver ice.MulticastDNSResolver) {
+	e.mDNSResolver.Resolver = resolver
+	e.mDNSResolver.Set = true
+}
+
+// SetEphemeralUDPPortRange limits the ports host candidates listen on to
+// the range, both ends included. Creating an RTCPeerConnection fails if
+// every port of the range is taken. A range of 0 to 0 allows any port again.
+func (e *SettingEngine) SetEphemeralUDPPortRange(portMin, portMax uint16) error {
+	if portMax < portMin || (portMin == 0 && portMax != 0) {
+		return ErrInvalidPortRange
+	}
+
+	e.ephemeralUDP.PortMin = portMin
+	e.ephemeralUDP.PortMax = portMax
+	return nil
+}
+
+// SetInterfaceFilter sets which network interfaces host candidates are
+// gathered on, the filter is called with the name of every interface. By
+// default all interfaces are used.
+func (e *SettingEngine) SetInterfaceFilter(filter func(interfaceName string) bool) {
+	e.interfaceFilter = filter
+}
+
+// SetCandidateTypes limits the local candidates gathered to the types. By
+// default host, server reflexive and relay candidates are gathered, peer
+// reflexive candidates are learned from the remote peer.
+func (e *SettingEngine) SetCandidateTypes(candidateTypes ...RTCIceCandidateType) {
+	e.candidateTypes = candidateTypes
+}
+
+// SetICETypePreferences overrides the preferences of the candidate types in
+// the priorities of the local candidates, from 0 to 126 with 126 preferred
+// the most. Types missing from the map keep their default preference, 126
+// for host, 110 for peer reflexive, 100 for server reflexive and 0 for relay
+// candidates. The remote peer checks the pairs of the candidates with the
+// highest priority first, and so does the agent.
+func (e *SettingEngine) SetICETypePreferences(preferences map[RTCIceCandidateType]uint16) error {
+	for _, preference := range preferences {
+		if preference > ice.HostCandidatePreference {
+			return ErrInvalidTypePreference
+		}
+	}
+	e.icePriority.TypePreferences = preferences
+	return nil
+}
+
+// SetICELocalPreference sets the function ranking the local candidates of the
+// same type, like deprioritizing the interfaces of a VPN. It is called with
+// the name of the interface of the host and server reflexive candidates,
+// empty if not found, and the local preference they were gathered with, the
+// highest for the interface gathered first. The preference it returns is
+// used instead.
+func (e *SettingEngine) SetICELocalPreference(f func(interfaceName string, preference uint16) uint16) {
+	e.icePriority.LocalPreference = f
+}
+
+// SetICEPriorityFormula sets the formula combining the type preference, the
+// local preference and the component of local candidates into their
+// priority. By default it is the one of RFC 8445 Section 5.1.2.1, where the
+// type preference outweighs the local one.
+func (e *SettingEngine) SetICEPriorityFormula(formula func(typePreference, localPreference, component uint16) uint32) {
+	e.icePriority.Formula = formula
+}
+
+// SetICETCP enables gathering TCP host candidates next to the UDP ones, a
+// passive candidate accepting connections and an active one connecting to
+// passive remote candidates. This lets peers on networks blocking UDP
+// connect without a TURN server. By default only UDP is used.
+func (e *SettingEngine) SetICETCP(enabled bool) {
+	e.iceTCP = enabled
+}
+
+// SetUDPMux makes RTCPeerConnections share the port of the UDPMux for
+// their host candidate, instead of listening on a port of every interface.
+// No srflx candidates are gathered with it, SetNAT1To1IPs announces the
+// public IP of servers behind a static NAT.
+func (e *SettingEngine) SetUDPMux(mux *UDPMux) {
+	e.udpMux = mux
+}
+
+// SetUDPBatchSize makes RTCPeerConnections read and write up to size
+// packets per syscall on the UDP ports of their host candidates, which cuts
+// the CPU spent on busy servers. Linux moves a batch with recvmmsg and
+// sendmmsg, other platforms still move a packet per syscall. Writes aren't
+// delayed, the packets written while a batch is written are sent with the
+// next one. By default, or with a size of one, packets are moved one by one.
+// It has no effect with SetNet or SetUDPMux.
+func (e *SettingEngine) SetUDPBatchSize(size int) {
+	e.udpBatchSize = size
+}
+
+// Net is a network RTCPeerConnections open their sockets on in place of the
+// network of the OS, the conns have to exchange the packets with
+// *net.UDPAddr addresses. A *vnet.Net satisfies it.
+type Net interface {
+	// IPs returns the IPs host candidates are gathered on
+	IPs() []net.IP
+
+	// ListenPacket announces on the local address like net.ListenPacket
+	ListenPacket(network, address string) (net.PacketConn, error)
+}
+
+// SetNet makes RTCPeerConnections gather their host candidates on the Net,
+// such as the in-memory network of pkg/vnet which runs them with controlled
+// loss, latency and NAT behavior in tests. No srflx, relay or TCP candidates
+// are gathered with it.
+func (e *SettingEngine) SetNet(n Net) {
+	e.net = n
+}
+
+// SetProxyDialer sets the dialer TCP and TLS connections to TURN servers
+// are opened with, for clients which reach the internet only through a
+// proxy. A dialer of golang.org/x/net/proxy reaches a SOCKS5 proxy. By
+// default the proxy of the HTTPS_PROXY or ALL_PROXY variables of the
+// environment is used, http:// proxies with CONNECT and socks5:// ones
+// directly, bypassed for the hosts of NO_PROXY.
+func (e *SettingEngine) SetProxyDialer(dialer proxy.Dialer) {
+	e.proxyDialer = dialer
+}
+
+// SetNAT1To1IPs sets the public IPs host candidates are reachable at, for
+// servers behind a static 1:1 NAT such as cloud instances. This saves a
+// STUN round trip and allows connecting from outside the private network
+// without a STUN server. An entry is either a single public IP, used for
+// every host candidate of its address family, or a public/private pair,
+// used for the host candidate with the private IP. With RTCIceCandidateTypeHost the IP of
+// host candidates is replaced by the public IP, with
+// RTCIceCandidateTypeSrflx the public IP is announced as an additional
+// srflx candidate.
+func (e *SettingEngine) SetNAT1To1IPs(ips []string, candidateType RTCIceCandidateType) error {
+	if candidateType != RTCIceCandidateTypeHost && candidateType != RTCIceCandidateTypeSrflx {
+		return ErrNAT1To1CandidateType
+	}
+
+	mapping := make(map[string]string)
+	for _, ip := range ips {
+		parts := strings.Split(ip, "/")
+		publicIP, privateIP := net.ParseIP(parts[0]), net.IP(nil)
+		if len(parts) == 2 {
+			privateIP = net.ParseIP(parts[1])
+			if privateIP == nil {
+				return ErrInvalidNAT1To1IP
+			}
+		}
+		if publicIP == nil || len(parts) > 2 {
+			return ErrInvalidNAT1To1IP
+		}
+
+		if privateIP == nil {
+			// The unspecified IP of the family stands for every IP of it
+			unspecified := net.IPv6unspecified
+			if publicIP.To4() != nil {
+				unspecified = net.IPv4zero
+			}
+			mapping[unspecified.String()] = publicIP.String()
+		} else {
+			mapping[privateIP.String()] = publicIP.String()
+		}
+	}
+
+	e.nat1To1.IPs = mapping
+	e.nat1To1.AsSrflx = candidateType == RTCIceCandidateTypeSrflx
+	return nil
+}
+
+// SetICECredentials sets the local ICE ufrag and pwd of RTCPeerConnections
+// instead of generating random ones, for reproducible tests and golden SDP
+// comparisons. The ufrag has to be 4 to 256 and the pwd 22 to 256 ice-chars
+// long. Peer connections sharing credentials can't use the same UDPMux: it
+// returns ErrUDPMuxUfragInUse if one of the UDPMux set already has the
+// ufrag, and creating a second one with it fails with the same error.
+// https://tools.ietf.org/html/rfc8839#section-5.4
+func (e *SettingEngine) SetICECredentials(ufrag, pwd string) error {
+	if !isICEChars(ufrag, 4) || !isICEChars(pwd, 22) {
+		return ErrInvalidICECredentials
+	} else if e.udpMux != nil && e.udpMux.mux.HasUfrag(ufrag) {
+		return ErrUDPMuxUfragInUse
+	}
+
+	e.iceCredentials.Ufrag = ufrag
+	e.iceCredentials.Pwd = pwd
+	return nil
+}
+
+// isICEChars reports whether s is a sequence of minLength to 256 ice-chars,
+// which are alphanumerics, '+' and '/'
+func isICEChars(s string, minLength int) bool {
+	if len(s) < minLength || len(s) > 256 {
+		return false
+	}
+	for _, c := range s {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9', c == '+', c == '/':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// SetCertificate sets the certificate DTLS authenticates with for
+// RTCPeerConnections whose RTCConfiguration has no Certificates, instead of
+// generating one for every peer connection. The fingerprint of their
+// descriptions stays the same.
+func (e *SettingEngine) SetCertificate(certificate *RTCCertificate) {
+	e.certificate = certificate
+}
+
+// SetSSRCGenerator sets the function the SSRCs of local tracks and of their
+// RTX and FEC streams are taken from, instead of random values. SSRCs have
+// to be unique within an RTCPeerConnection, the generator is called from
+// many goroutines.
+func (e *SettingEngine) SetSSRCGenerator(generate func() uint32) {
+	e.ssrcGenerator = generate
+}
+
+// SetSRTPProtectionProfiles sets the SRTP protection profiles offered during
+// the DTLS handshake, in order of preference. Only these profiles are
+// negotiated, deployments which have to comply with a policy can restrict
+// them. By default the AEAD profiles are preferred over the AES-CM ones, when
+// OpenSSL provides them, which it does from 1.1.0. With an older OpenSSL,
+// creating an RTCPeerConnection with AEAD profiles set fails.
+func (e *SettingEngine) SetSRTPProtectionProfiles(profiles ...SRTPProtectionProfile) {
+	e.srtpProtectionProfiles = profiles
+}
+
+// SetRTPKeepaliveInterval has senders send a padding-only RTP packet on
+// streams which have been silent for the interval, keeping NAT bindings
+// and the SRTP state of the remote peer warm so media resuming after a
+// long silence isn't lost. Streams of tracks written with raw RTP packets
+// are not kept alive. It is disabled by default.
+func (e *SettingEngine) SetRTPKeepaliveInterval(interval time.Duration) {
+	e.rtpKeepaliveInterval = interval
+}
+
+// SetReceiverReferenceTimeInterval has an RTCP extended report with the
+// receiver reference time sent at the interval while there are remote
+// tracks, RFC 3611. The remote peer answers it with a DLRR report, which
+// measures the round trip of the stats of remote-outbound-rtp streams
+// without sending media. It is disabled by default.
+func (e *SettingEngine) SetReceiverReferenceTimeInterval(interval time.Duration) {
+	e.rrtrInterval = interval
+}
+
+// SetSDPLimits bounds the remote descriptions accepted by
+// SetRemoteDescription: their size and the length of their lines in bytes
+// and the amount of a= lines they carry. Exceeding a limit fails with
+// ErrSDPSizeLimit, ErrSDPLineLengthLimit or ErrSDPAttributeLimit, a zero
+// limit disables it. By default descriptions are limited to 256 KiB, lines
+// of 4096 bytes and 4096 attributes.
+func (e *SettingEngine) SetSDPLimits(maxSize, maxLineLength, maxAttributes int) {
+	e.sdpLimits = &sdp.Limits{
+		MaxSize:       maxSize,
+		MaxLineLength: maxLineLength,
+		MaxAttributes: maxAttributes,
+	}
+}
+
+// SetSCTPMaxChannels sets the number of SCTP streams offered to the remote
+// peer, answers offer the lower of it and the streams of the offer. Data
+// channels can't be created beyond the negotiated count, see
+// RTCSctpTransport.MaxChannels. The default is 1024.
+func (e *SettingEngine) SetSCTPMaxChannels(channels uint16) {
+	e.sctpMaxChannels = channels
+}
+
+// SetSCTPPortAttributes offers the data channel section in the format of
+// newer browsers, m=application 9 UDP/DTLS/SCTP webrtc-datachannel with the
+// SCTP port in a=sctp-port and the largest message received in
+// a=max-message-size. By default offers use the legacy format with
+// a=sctpmap, which older browsers require. Answers always take the format of
+// the offer.
+func (e *SettingEngine) SetSCTPPortAttributes(enabled bool) {
+	e.sctpPortAttributes = enabled
+}
+
+// SetUnhandledEventWindow holds remote tracks, data channels and data
+// channel messages which arrive while OnTrack, OnDataChannel or Onmessage is
+// unset for up to the window, and delivers them once the handler is set.
+// Handlers are often set just after the RTCPeerConnection is created, when
+// the first events may already have arrived. By default such events are
+// discarded.
+func (e *SettingEngine) SetUnhandledEventWindow(window time.Duration) {
+	e.unhandledEventWindow = window
+}
+
+// SetLoggerFactory sets the factory of the loggers the subsystems of
+// RTCPeerConnections write their diagnostics to, every subsystem gets the
+// logger of its scope: logging.ScopeICE, ScopeDTLS, ScopeSCTP, ScopeRTP and
+// ScopePC. The default is silent unless the environment enables the scopes,
+// see logging.NewDefaultLoggerFactory.
+func (e *SettingEngine) SetLoggerFactory(factory logging.LoggerFactory) {
+	e.loggerFactory = factory
+}
+
+// SetInterceptor sets the Interceptor every RTP and RTCP packet of
+// RTCPeerConnections passes through, outbound ones before they are
+// encrypted and inbound ones after they are decrypted. It is bound once per
+// RTCPeerConnection, compose several with interceptor.NewChain.
+func (e *SettingEngine) SetInterceptor(i interceptor.Interceptor) {
+	e.interceptor = i
+}
+
+// SetPacketCapture sets the handler every RTP and RTCP packet of
+// RTCPeerConnections is mirrored to, outbound ones before they are encrypted
+// and inbound ones after they are decrypted, and their SCTP packets if
+// captureSCTP is set. Write them with a pcap.Writer to analyze them in
+// Wireshark without the DTLS keys. The handler is called while the packets
+// are sent and received, it must not block.
+func (e *SettingEngine) SetPacketCapture(handler func(pcap.Packet), captureSCTP bool) {
+	e.packetCapture.Handler = handler
+	e.packetCapture.SCTP = captureSCTP
+}
+
+// SetTrackQueuePolicy sets what happens to a sample written to the Samples
+// channel of a local track while size samples are queued, waiting to be
+// sent. With RTCTrackQueuePolicyBlock, the default, the writer blocks until
+// one of them is sent. The drop policies discard a sample instead, so an
+// encoder is never blocked by a stalled network. RTCTrack.OnQueueFull is
+// called either way. The default size is 15 samples, a size which isn't
+// positive keeps it.
+func (e *SettingEngine) SetTrackQueuePolicy(policy RTCTrackQueuePolicy, size int) {
+	e.trackQueue.Policy = policy
+	e.trackQueue.Size = size
+}
+
+// SetPacketizerMTU sets the size of the RTP packets media written to local
+// tracks is packetized into, before SRTP adds its authentication tag. The
+// default of 1400 bytes suits most paths, TURN relays, VPNs and IPv6 tunnels
+// may need smaller packets to avoid IP fragmentation. RTCTrack.SetMTU
+// overrides it per track. It returns ErrInvalidMTU if the MTU is smaller
+// than 64 bytes.
+func (e *SettingEngine) SetPacketizerMTU(mtu int) error {
+	if mtu < minPacketizerMTU {
+		return ErrInvalidMTU
+	}
+	e.packetizerMTU = mtu
+	return nil
+}
+
+// getPacketizerMTU returns the MTU of the packets of local tracks
+func (e *SettingEngine) getPacketizerMTU() int {
+	if e.packetizerMTU == 0 {
+		return defaultPacketizerMTU
+	}
+	return e.packetizerMTU
+}
+
+// getTrackQueue returns the policy and the size of the queues of the Samples
+// of local tracks
+func (e *SettingEngine) getTrackQueue() (RTCTrackQueuePolicy, int) {
+	policy, size := e.trackQueue.Policy, e.trackQueue.Size
+	if policy == RTCTrackQueuePolicy(Unknown) {
+		policy = RTCTrackQueuePolicyBlock
+	}
+	if size <= 0 {
+		size = defaultTrackQueueSize
+	}
+	return policy, size
+}
+
+// getLoggerFactory returns the factory of the loggers
+func (e *SettingEngine) getLoggerFactory() logging.LoggerFactory {
+	if e.loggerFactory == nil {
+		return logging.NewDefaultLoggerFactory()
+	}
+	return e.loggerFactory
+}
+
+// networkSettings returns the settings of the network.Manager
+func (e *SettingEngine) networkSettings() network.Settings {
+	settings := network.Settings{
+		PortMin:         e.ephemeralUDP.PortMin,
+		PortMax:         e.ephemeralUDP.PortMax,
+		InterfaceFilter: e.interfaceFilter,
+		TCPCandidates:   e.iceTCP,
+		NAT1To1IPs:      e.nat1To1.IPs,
+		NAT1To1AsSrflx:  e.nat1To1.AsSrflx,
+		Net:             e.net,
+		ProxyDialer:     e.proxyDialer,
+		BatchSize:       e.udpBatchSize,
+		ICEUfrag:        e.iceCredentials.Ufrag,
+		ICEPwd:          e.iceCredentials.Pwd,
+		PriorityPolicy:  e.icePriorityPolicy(),
+		LoggerFactory:   e.getLoggerFactory(),
+		Interceptor:     e.interceptor,
+	}
+
+	if e.udpMux != nil {
+		settings.UDPMux = e.udpMux.mux
+	}
+	settings.PacketCapture = e.packetCapture.Handler
+	settings.CaptureSCTP = e.packetCapture.SCTP
+
+	if e.candidateTypes != nil {
+		settings.DisableHostCandidates = !e.hasCandidateType(RTCIceCandidateTypeHost)
+		settings.DisableSrflxCandidates = !e.hasCandidateType(RTCIceCandidateTypeSrflx)
+		settings.DisableRelayCandidates = !e.hasCandidateType(RTCIceCandidateTypeRelay)
+	}
+
+	for _, profile := range e.srtpProtectionProfiles {
+		settings.SRTPProtectionProfiles = append(settings.SRTPProtectionProfiles, profile.String())
+	}
+	return settings
+}
+
+// getSDPLimits returns the limits of remote descriptions
+func (e *SettingEngine) getSDPLimits() sdp.Limits {
+	if e.sdpLimits == nil {
+		return sdp.Limits{
+			MaxSize:       defaultSDPMaxSize,
+			MaxLineLength: defaultSDPMaxLineLength,
+			MaxAttributes: defaultSDPMaxAttributes,
+		}
+	}
+	return *e.sdpLimits
+}
+
+// icePriorityPolicy returns the policy computing the priorities of the local
+// candidates
+func (e *SettingEngine) icePriorityPolicy() ice.PriorityPolicy {
+	policy := ice.PriorityPolicy{Formula: e.icePriority.Formula}
+
+	if e.icePriority.TypePreferences != nil {
+		preferences := ice.DefaultTypePreferences
+		for candidateType, preference := range e.icePriority.TypePreferences {
+			switch candidateType {
+			case RTCIceCandidateTypeHost:
+				preferences.Host = preference
+			case RTCIceCandidateTypeSrflx:
+				preferences.ServerReflexive = preference
+			case RTCIceCandidateTypePrflx:
+				preferences.PeerReflexive = preference
+			case RTCIceCandidateTypeRelay:
+				preferences.Relay = preference
+			}
+		}
+		policy.TypePreferences = &preferences
+	}
+
+	if f := e.icePriority.LocalPreference; f != nil {
+		policy.LocalPreference = func(c ice.Candidate, preference uint16) uint16 {
+			// Server reflexive candidates are sent from their base
+			ip := c.GetBase().Address
+			if srflx, ok := c.(*ice.CandidateSrflx); ok {
+				ip = srflx.RemoteAddress
+			}
+			return f(interfaceName(ip), preference)
+		}
+	}
+	return policy
+}
+
+// interfaceName returns the name of the network interface with the IP, empty
+// if there is none
+func interfaceName(ip string) string {
+	parsed := net.ParseIP(ip)
+	ifaces, err := net.Interfaces()
+	if parsed == nil || err != nil {
+		return ""
+	}
+
+	for _, iface := range ifaces {
+		addrs, err := iface.Addrs()
+		if err != nil {
+			continue
+		}
+		for _, addr := range addrs {
+			if ipNet, ok := addr.(*net.IPNet); ok && ipNet.IP.Equal(parsed) {
+				return iface.Name
+			}
+		}
+	}
+	return ""
+}
+
+func (e *SettingEngine) hasCandidateType(candidateType RTCIceCandidateType) bool {
+	for _, t := range e.candidateTypes {
+		if t == candidateType {
+			return true
+		}
+	}
+	return false
+}
+
+// configureICEAgent applies the ICE settings to the agent
+func (e *SettingEngine) configureICEAgent(agent *ice.Agent) {
+	if e.timeout.ICECandidate != nil {
+		agent.SetCandidateTimeout(*e.timeout.ICECandidate)
+	}
+	if e.timeout.ICECheck != nil {
+		agent.SetCheckInterval(*e.timeout.ICECheck)
+	}
+	if e.timeout.ICEConnection != nil {
+		agent.SetConnectionTimeout(*e.timeout.ICEConnection)
+	}
+	if e.timeout.ICEDisconnected != nil {
+		agent.SetDisconnectedTimeout(*e.timeout.ICEDisconnected)
+	}
+	if e.timeout.ICEKeepalive != nil {
+		agent.SetKeepaliveInterval(*e.timeout.ICEKeepalive)
+	}
+	if e.timeout.ICEMulticastDNS != nil {
+		agent.SetMulticastDNSTimeout(*e.timeout.ICEMulticastDNS)
+	}
+	if e.mDNSResolver.Set {
+		agent.SetMulticastDNSResolver(e.mDNSResolver.Resolver)
+	}
+}
@@ -0,0 +1,769 @@
+//go:build !js
+
+package webrtc
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pions/webrtc/internal/turn"
+	"github.com/pions/webrtc/pkg/datachannel"
+	"github.com/pions/webrtc/pkg/ice"
+	"github.com/pions/webrtc/pkg/interceptor"
+	"github.com/pions/webrtc/pkg/logging"
+	"github.com/pions/webrtc/pkg/media"
+	"github.com/pions/webrtc/pkg/pcap"
+	"github.com/pions/webrtc/pkg/rtcp"
+	"github.com/pions/webrtc/pkg/rtp"
+	"github.com/pions/webrtc/pkg/vnet"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSettingEngine_SetEphemeralUDPPortRange(t *testing.T) {
+	s := SettingEngine{}
+	assert.Equal(t, ErrInvalidPortRange, s.SetEphemeralUDPPortRange(3999, 3000))
+	assert.Equal(t, ErrInvalidPortRange, s.SetEphemeralUDPPortRange(0, 3999))
+	assert.Nil(t, s.SetEphemeralUDPPortRange(3000, 3999))
+
+	pc, err := NewAPI(WithSettingEngine(s)).NewRTCPeerConnection(RTCConfiguration{})
+	assert.Nil(t, err)
+	defer func() { assert.Nil(t, pc.Close()) }()
+
+	for _, c := range pc.networkManager.IceAgent.LocalCandidates {
+		port := c.GetBase().Port
+		assert.True(t, port >= 3000 && port <= 3999, "port %d is out of range", port)
+	}
+}
+
+func TestSettingEngine_SetCandidateTypes(t *testing.T) {
+	s := SettingEngine{}
+	s.SetCandidateTypes(RTCIceCandidateTypeSrflx)
+
+	pc, err := NewAPI(WithSettingEngine(s)).NewRTCPeerConnection(RTCConfiguration{})
+	assert.Nil(t, err)
+	defer func() { assert.Nil(t, pc.Close()) }()
+
+	for _, c := range pc.networkManager.IceAgent.LocalCandidates {
+		_, isHost := c.(*ice.CandidateHost)
+		assert.False(t, isHost)
+	}
+}
+
+func TestSettingEngine_SetICEPriorities(t *testing.T) {
+	s := SettingEngine{}
+	assert.Equal(t, ErrInvalidTypePreference, s.SetICETypePreferences(map[RTCIceCandidateType]uint16{RTCIceCandidateTypeHost: 127}))
+	assert.Nil(t, s.SetICETypePreferences(map[RTCIceCandidateType]uint16{RTCIceCandidateTypeHost: 10}))
+	s.SetICELocalPreference(func(interfaceName string, preference uint16) uint16 {
+		assert.Equal(t, "", interfaceName) // the virtual network has no interfaces
+		return 7
+	})
+
+	router := vnet.NewRouter()
+	n, err := router.NewNet("10.0.0.7")
+	assert.Nil(t, err)
+	s.SetNet(n)
+
+	pc, err := NewAPI(WithSettingEngine(s)).NewRTCPeerConnection(RTCConfiguration{})
+	assert.Nil(t, err)
+	defer func() { assert.Nil(t, pc.Close()) }()
+
+	// The priority is announced in the candidates of the offer
+	priority := ice.DefaultPriorityFormula(10, 7, ice.ComponentRTP)
+	_, err = pc.CreateDataChannel("data", nil)
+	assert.Nil(t, err)
+	offer, err := pc.CreateOffer(nil)
+	assert.Nil(t, err)
+	assert.Contains(t, offer.SDP, fmt.Sprintf(" udp %d 10.0.0.7 ", priority))
+
+	s.SetICEPriorityFormula(func(typePreference, localPreference, component uint16) uint32 {
+		return uint32(localPreference)
+	})
+	pc, err = NewAPI(WithSettingEngine(s)).NewRTCPeerConnection(RTCConfiguration{})
+	assert.Nil(t, err)
+	defer func() { assert.Nil(t, pc.Close()) }()
+	if assert.Equal(t, 1, len(pc.networkManager.IceAgent.LocalCandidates)) {
+		assert.Equal(t, uint32(7), ice.CandidatePriority(pc.networkManager.IceAgent.LocalCandidates[0]))
+	}
+}
+
+func TestSettingEngine_networkSettings(t *testing.T) {
+	s := SettingEngine{}
+	assert.False(t, s.networkSettings().DisableHostCandidates)
+	assert.False(t, s.networkSettings().DisableSrflxCandidates)
+	assert.False(t, s.networkSettings().DisableRelayCandidates)
+	assert.Nil(t, s.networkSettings().SRTPProtectionProfiles)
+	assert.False(t, s.networkSettings().TCPCandidates)
+
+	s.SetCandidateTypes(RTCIceCandidateTypeHost)
+	s.SetSRTPProtectionProfiles(SRTPProtectionProfileAES128CM80)
+	s.SetICETCP(true)
+	settings := s.networkSettings()
+	assert.False(t, settings.DisableHostCandidates)
+	assert.True(t, settings.DisableSrflxCandidates)
+	assert.True(t, settings.DisableRelayCandidates)
+	assert.True(t, settings.TCPCandidates)
+	assert.Equal(t, []string{"SRTP_AES128_CM_SHA1_80"}, settings.SRTPProtectionProfiles)
+}
+
+func TestSRTPProtectionProfile_String(t *testing.T) {
+	testCases := []struct {
+		profile        SRTPProtectionProfile
+		expectedString string
+	}{
+		{SRTPProtectionProfile(Unknown), "unknown"},
+		{SRTPProtectionProfileAES128CM80, "SRTP_AES128_CM_SHA1_80"},
+		{SRTPProtectionProfileAES128CM32, "SRTP_AES128_CM_SHA1_32"},
+		{SRTPProtectionProfileAEADAES128GCM, "SRTP_AEAD_AES_128_GCM"},
+		{SRTPProtectionProfileAEADAES256GCM, "SRTP_AEAD_AES_256_GCM"},
+	}
+
+	for i, testCase := range testCases {
+		assert.Equal(t,
+			testCase.expectedString,
+			testCase.profile.String(),
+			"testCase: %d %v", i, testCase,
+		)
+	}
+}
+
+func TestSettingEngine_SetSDPLimits(t *testing.T) {
+	s := SettingEngine{}
+	assert.Equal(t, defaultSDPMaxSize, s.getSDPLimits().MaxSize)
+
+	s.SetSDPLimits(len(offerWithMids)-1, 0, 0)
+	pc, err := NewAPI(WithSettingEngine(s)).NewRTCPeerConnection(RTCConfiguration{})
+	assert.Nil(t, err)
+	defer func() { assert.Nil(t, pc.Close()) }()
+
+	err = pc.SetRemoteDescription(RTCSessionDescription{Type: RTCSdpTypeOffer, SDP: offerWithMids})
+	assert.Equal(t, ErrSDPSizeLimit, err)
+	assert.Nil(t, pc.RemoteDescription())
+}
+
+func TestSettingEngine_SetNAT1To1IPs(t *testing.T) {
+	s := SettingEngine{}
+	assert.Equal(t, ErrNAT1To1CandidateType, s.SetNAT1To1IPs([]string{"1.2.3.4"}, RTCIceCandidateTypeRelay))
+	assert.Equal(t, ErrInvalidNAT1To1IP, s.SetNAT1To1IPs([]string{"public"}, RTCIceCandidateTypeHost))
+	assert.Equal(t, ErrInvalidNAT1To1IP, s.SetNAT1To1IPs([]string{"1.2.3.4/private"}, RTCIceCandidateTypeHost))
+
+	assert.Nil(t, s.SetNAT1To1IPs([]string{"1.2.3.4", "2001:db8::1", "5.6.7.8/10.0.0.1"}, RTCIceCandidateTypeSrflx))
+	settings := s.networkSettings()
+	assert.Equal(t, map[string]string{"0.0.0.0": "1.2.3.4", "::": "2001:db8::1", "10.0.0.1": "5.6.7.8"}, settings.NAT1To1IPs)
+	assert.True(t, settings.NAT1To1AsSrflx)
+
+	for _, candidateType := range []RTCIceCandidateType{RTCIceCandidateTypeHost, RTCIceCandidateTypeSrflx} {
+		assert.Nil(t, s.SetNAT1To1IPs([]string{"1.2.3.4"}, candidateType))
+
+		pc, err := NewAPI(WithSettingEngine(s)).NewRTCPeerConnection(RTCConfiguration{})
+		assert.Nil(t, err)
+
+		if len(pc.networkManager.IceAgent.LocalCandidates) > 0 {
+			// The candidates are announced in the data section
+			_, err = pc.CreateDataChannel("data", nil)
+			assert.Nil(t, err)
+			offer, err := pc.CreateOffer(nil)
+			assert.Nil(t, err)
+			assert.True(t, strings.Contains(offer.SDP, " 1.2.3.4 "))
+			assert.Equal(t, candidateType == RTCIceCandidateTypeSrflx, strings.Contains(offer.SDP, " typ srflx "))
+		}
+		assert.Nil(t, pc.Close())
+	}
+}
+
+func TestSettingEngine_SetUDPMux(t *testing.T) {
+	_, err := NewUDPMux(":0")
+	assert.Equal(t, ErrUDPMuxUnspecifiedIP, err)
+
+	mux, err := NewUDPMux("127.0.0.1:0")
+	assert.Nil(t, err)
+	defer func() { assert.Nil(t, mux.Close()) }()
+
+	s := SettingEngine{}
+	assert.Nil(t, s.networkSettings().UDPMux)
+	s.SetUDPMux(mux)
+	assert.Equal(t, mux.mux, s.networkSettings().UDPMux)
+
+	// RTCPeerConnections of the UDPMux can't share a ufrag
+	assert.Nil(t, s.SetICECredentials("ufrag", "passwordpasswordpassword"))
+	pc, err := NewAPI(WithSettingEngine(s)).NewRTCPeerConnection(RTCConfiguration{})
+	assert.Nil(t, err)
+	_, err = NewAPI(WithSettingEngine(s)).NewRTCPeerConnection(RTCConfiguration{})
+	assert.Equal(t, ErrUDPMuxUfragInUse, err)
+	assert.Equal(t, ErrUDPMuxUfragInUse, s.SetICECredentials("ufrag", "passwordpasswordpassword"))
+	assert.Nil(t, pc.Close())
+	assert.Nil(t, s.SetICECredentials("ufrag", "passwordpasswordpassword"))
+}
+
+func TestSettingEngine_SetUDPBatchSize(t *testing.T) {
+	s := SettingEngine{}
+	assert.Equal(t, 0, s.networkSettings().BatchSize)
+	s.SetUDPBatchSize(32)
+	assert.Equal(t, 32, s.networkSettings().BatchSize)
+
+	pc, err := NewAPI(WithSettingEngine(s)).NewRTCPeerConnection(RTCConfiguration{})
+	assert.Nil(t, err)
+	assert.Nil(t, pc.Close())
+}
+
+func TestSettingEngine_SetNet(t *testing.T) {
+	router := vnet.NewRouter()
+	router.SetLatency(10 * time.Millisecond)
+
+	offererNet, err := router.NewNATedNet("192.168.0.2", "1.2.3.4")
+	assert.Nil(t, err)
+	answererNet, err := router.NewNet("5.6.7.8")
+	assert.Nil(t, err)
+
+	newPeerConnection := func(n Net) *RTCPeerConnection {
+		s := SettingEngine{}
+		assert.Nil(t, s.networkSettings().Net)
+		s.SetNet(n)
+		assert.Equal(t, n, s.networkSettings().Net)
+
+		pc, err := NewAPI(WithSettingEngine(s)).NewRTCPeerConnection(RTCConfiguration{})
+		assert.Nil(t, err)
+		return pc
+	}
+	offerer := newPeerConnection(offererNet)
+	answerer := newPeerConnection(answererNet)
+
+	received := make(chan string, 1)
+	answerer.OnDataChannel(func(d *RTCDataChannel) {
+		d.Lock()
+		d.Onmessage = func(p datachannel.Payload) {
+			if payload, ok := p.(*datachannel.PayloadString); ok {
+				received <- string(payload.Data)
+			}
+		}
+		d.Unlock()
+	})
+
+	d, err := offerer.CreateDataChannel("data", nil)
+	assert.Nil(t, err)
+	d.Lock()
+	d.OnOpen = func() {
+		assert.Nil(t, d.Send(datachannel.PayloadString{Data: []byte("hello")}))
+	}
+	d.Unlock()
+
+	// The offerer is only reachable through the peer-reflexive candidate
+	// the answerer learns from its connectivity checks
+	offer, err := offerer.CreateOffer(nil)
+	assert.Nil(t, err)
+	assert.Contains(t, offer.SDP, " 192.168.0.2 ")
+	assert.Nil(t, answerer.SetRemoteDescription(offer))
+
+	answer, err := answerer.CreateAnswer(nil)
+	assert.Nil(t, err)
+	assert.Contains(t, answer.SDP, " 5.6.7.8 ")
+	assert.Nil(t, offerer.SetRemoteDescription(answer))
+
+	select {
+	case message := <-received:
+		assert.Equal(t, "hello", message)
+	case <-time.After(10 * time.Second):
+		t.Fatal("timed out waiting for the message")
+	}
+
+	assert.Nil(t, offerer.Close())
+	assert.Nil(t, answerer.Close())
+}
+
+// dialerFunc is a proxy.Dialer dialing with the function
+type dialerFunc func(network, addr string) (net.Conn, error)
+
+func (f dialerFunc) Dial(network, addr string) (net.Conn, error) {
+	return f(network, addr)
+}
+
+func TestSettingEngine_SetProxyDialer(t *testing.T) {
+	credentials := turn.Credentials{Username: "user", Password: "pass"}
+	server, err := turn.NewServer("127.0.0.1", credentials, 0)
+	assert.Nil(t, err)
+	defer func() { assert.Nil(t, server.Close()) }()
+
+	// The offerer reaches the TURN server over TCP through the dialer, the
+	// answerer over UDP, both only through the relay
+	dialed := make(chan string, 1)
+	s := SettingEngine{}
+	s.SetProxyDialer(dialerFunc(func(network, addr string) (net.Conn, error) {
+		dialed <- addr
+		return net.Dial(network, addr)
+	}))
+	newPeerConnection := func(api *API, url string) *RTCPeerConnection {
+		pc, err := api.NewRTCPeerConnection(RTCConfiguration{
+			IceServers:         []RTCIceServer{{URLs: []string{url}, Username: credentials.Username, Credential: credentials.Password}},
+			IceTransportPolicy: RTCIceTransportPolicyRelay,
+		})
+		assert.Nil(t, err)
+		return pc
+	}
+	offerer := newPeerConnection(NewAPI(WithSettingEngine(s)), "turn:"+server.TCPAddr().String()+"?transport=tcp")
+	answerer := newPeerConnection(NewAPI(), "turn:"+server.UDPAddr().String())
+
+	received := make(chan string, 1)
+	answerer.OnDataChannel(func(d *RTCDataChannel) {
+		d.Lock()
+		d.Onmessage = func(p datachannel.Payload) {
+			if payload, ok := p.(*datachannel.PayloadString); ok {
+				received <- string(payload.Data)
+			}
+		}
+		d.Unlock()
+	})
+
+	d, err := offerer.CreateDataChannel("data", nil)
+	assert.Nil(t, err)
+	d.Lock()
+	d.OnOpen = func() {
+		assert.Nil(t, d.Send(datachannel.PayloadString{Data: []byte("hello")}))
+	}
+	d.Unlock()
+
+	offer, err := offerer.CreateOffer(nil)
+	assert.Nil(t, err)
+	assert.Equal(t, server.TCPAddr().String(), <-dialed)
+	assert.Contains(t, offer.SDP, " typ relay raddr ")
+	assert.Nil(t, answerer.SetRemoteDescription(offer))
+
+	answer, err := answerer.CreateAnswer(nil)
+	assert.Nil(t, err)
+	assert.Contains(t, answer.SDP, " typ relay raddr ")
+	assert.Nil(t, offerer.SetRemoteDescription(answer))
+
+	select {
+	case message := <-received:
+		assert.Equal(t, "hello", message)
+	case <-time.After(10 * time.Second):
+		t.Fatal("timed out waiting for the message")
+	}
+
+	assert.Nil(t, offerer.Close())
+	assert.Nil(t, answerer.Close())
+}
+
+func TestSettingEngine_SetICECredentialRefresher(t *testing.T) {
+	expiring := NewTimeLimitedRTCIceServer(nil, "secret", "unittest", 30*time.Second)
+	fresh := NewTimeLimitedRTCIceServer(nil, "secret", "unittest", time.Hour)
+	server, err := turn.NewServer("127.0.0.1", turn.Credentials{Username: fresh.Username, Password: fresh.Credential.(string)}, 0)
+	assert.Nil(t, err)
+	defer func() { assert.Nil(t, server.Close()) }()
+
+	// The TURN server only accepts the refreshed credentials
+	url := "turn:" + server.UDPAddr().String()
+	expiring.URLs = []string{url}
+	fresh.URLs = []string{url}
+	refreshed := make(chan RTCIceServer, 1)
+	s := SettingEngine{}
+	s.SetICECredentialRefresher(func(server RTCIceServer) (RTCIceServer, error) {
+		refreshed <- server
+		return fresh, nil
+	})
+
+	pc, err := NewAPI(WithSettingEngine(s)).NewRTCPeerConnection(RTCConfiguration{
+		IceServers:         []RTCIceServer{expiring},
+		IceTransportPolicy: RTCIceTransportPolicyRelay,
+	})
+	assert.Nil(t, err)
+
+	offer, err := pc.CreateOffer(nil)
+	assert.Nil(t, err)
+	assert.Equal(t, expiring, <-refreshed)
+	assert.Contains(t, offer.SDP, " typ relay raddr ")
+	assert.Nil(t, pc.Close())
+}
+
+func TestSettingEngine_SetICECredentials(t *testing.T) {
+	testCases := []struct {
+		ufrag string
+		pwd   string
+		err   error
+	}{
+		{"abcd", "abcdefghijklmnopqrstuv", nil},
+		{"ab+/", "ABCDEFGHIJKLMNOPQRSTU1", nil},
+		{"abc", "abcdefghijklmnopqrstuv", ErrInvalidICECredentials},
+		{"abcd", "abcdefghijklmnopqrstu", ErrInvalidICECredentials},
+		{"ab:d", "abcdefghijklmnopqrstuv", ErrInvalidICECredentials},
+		{"abcd", strings.Repeat("a", 257), ErrInvalidICECredentials},
+	}
+
+	for i, testCase := range testCases {
+		s := SettingEngine{}
+		assert.Equal(t, testCase.err, s.SetICECredentials(testCase.ufrag, testCase.pwd), "testCase: %d", i)
+		if testCase.err == nil {
+			assert.Equal(t, testCase.ufrag, s.networkSettings().ICEUfrag, "testCase: %d", i)
+			assert.Equal(t, testCase.pwd, s.networkSettings().ICEPwd, "testCase: %d", i)
+		} else {
+			assert.Empty(t, s.networkSettings().ICEUfrag, "testCase: %d", i)
+		}
+	}
+}
+
+func TestSettingEngine_Deterministic(t *testing.T) {
+	RegisterDefaultCodecs()
+
+	sk, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.Nil(t, err)
+	certificate, err := GenerateCertificate(sk)
+	assert.Nil(t, err)
+
+	s := SettingEngine{}
+	assert.Nil(t, s.SetICECredentials("ufrag", "passwordpasswordpassword"))
+	s.SetCertificate(certificate)
+
+	var offers []string
+	for i := 0; i < 2; i++ {
+		ssrc := uint32(1000)
+		s.SetSSRCGenerator(func() uint32 {
+			ssrc++
+			return ssrc
+		})
+
+		pc, err := NewAPI(WithSettingEngine(s)).NewRTCPeerConnection(RTCConfiguration{})
+		assert.Nil(t, err)
+		assert.True(t, pc.GetConfiguration().Certificates[0].Equals(*certificate))
+
+		track, err := pc.NewRTCSampleTrack(DefaultPayloadTypeOpus, "audio", "pion")
+		assert.Nil(t, err)
+		assert.Equal(t, uint32(1001), track.Ssrc)
+		_, err = pc.AddTrack(track)
+		assert.Nil(t, err)
+
+		offer, err := pc.CreateOffer(nil)
+		assert.Nil(t, err)
+		assert.Contains(t, offer.SDP, "a=ice-ufrag:ufrag\r\n")
+		assert.Contains(t, offer.SDP, "a=ice-pwd:passwordpasswordpassword\r\n")
+		assert.Contains(t, offer.SDP, "a=ssrc:1001 ")
+		offers = append(offers, offer.SDP[strings.Index(offer.SDP, "a=fingerprint"):])
+		assert.Nil(t, pc.Close())
+	}
+
+	// The fingerprint is the same as well
+	assert.Equal(t, strings.Split(offers[0], "\r\n")[0], strings.Split(offers[1], "\r\n")[0])
+}
+
+type scopeRecorder struct {
+	sync.Mutex
+	scopes []string
+}
+
+func (r *scopeRecorder) NewLogger(scope string) logging.LeveledLogger {
+	r.Lock()
+	defer r.Unlock()
+	r.scopes = append(r.scopes, scope)
+	return logging.NewDefaultLeveledLoggerForScope(scope, logging.LogLevelDisabled, ioutil.Discard)
+}
+
+func TestSettingEngine_SetLoggerFactory(t *testing.T) {
+	recorder := &scopeRecorder{}
+	s := SettingEngine{}
+	s.SetLoggerFactory(recorder)
+
+	pc, err := NewAPI(WithSettingEngine(s)).NewRTCPeerConnection(RTCConfiguration{})
+	assert.Nil(t, err)
+	assert.Nil(t, pc.Close())
+
+	recorder.Lock()
+	defer recorder.Unlock()
+	for _, scope := range []string{logging.ScopeICE, logging.ScopeDTLS, logging.ScopeSCTP, logging.ScopeRTP, logging.ScopePC} {
+		assert.Contains(t, recorder.scopes, scope)
+	}
+}
+
+// packetTap is an Interceptor reporting the packets passing through it
+type packetTap struct {
+	outboundRTP, inboundRTP   chan *rtp.Packet
+	outboundRTCP, inboundRTCP chan []byte
+}
+
+func newPacketTap() *packetTap {
+	return &packetTap{
+		outboundRTP:  make(chan *rtp.Packet, 100),
+		inboundRTP:   make(chan *rtp.Packet, 100),
+		outboundRTCP: make(chan []byte, 100),
+		inboundRTCP:  make(chan []byte, 100),
+	}
+}
+
+func tapRTP(tap chan *rtp.Packet, next interceptor.RTPWriter) interceptor.RTPWriter {
+	return interceptor.RTPWriterFunc(func(p *rtp.Packet) error {
+		packet := *p
+		select {
+		case tap <- &packet:
+		default:
+		}
+		return next.WriteRTP(p)
+	})
+}
+
+func tapRTCP(tap chan []byte, next interceptor.RTCPWriter) interceptor.RTCPWriter {
+	return interceptor.RTCPWriterFunc(func(raw []byte) error {
+		select {
+		case tap <- append([]byte{}, raw...):
+		default:
+		}
+		return next.WriteRTCP(raw)
+	})
+}
+
+func (t *packetTap) BindOutboundRTP(next interceptor.RTPWriter) interceptor.RTPWriter {
+	return tapRTP(t.outboundRTP, next)
+}
+
+func (t *packetTap) BindInboundRTP(next interceptor.RTPWriter) interceptor.RTPWriter {
+	return tapRTP(t.inboundRTP, next)
+}
+
+func (t *packetTap) BindOutboundRTCP(next interceptor.RTCPWriter) interceptor.RTCPWriter {
+	return tapRTCP(t.outboundRTCP, next)
+}
+
+func (t *packetTap) BindInboundRTCP(next interceptor.RTCPWriter) interceptor.RTCPWriter {
+	return tapRTCP(t.inboundRTCP, next)
+}
+
+func TestSettingEngine_SetInterceptor(t *testing.T) {
+	RegisterDefaultCodecs()
+	router := vnet.NewRouter()
+
+	newPeerConnection := func(ip string, tap *packetTap) *RTCPeerConnection {
+		n, err := router.NewNet(ip)
+		assert.Nil(t, err)
+
+		s := SettingEngine{}
+		s.SetNet(n)
+		s.SetInterceptor(tap)
+		assert.Equal(t, tap, s.networkSettings().Interceptor)
+
+		pc, err := NewAPI(WithSettingEngine(s)).NewRTCPeerConnection(RTCConfiguration{})
+		assert.Nil(t, err)
+		return pc
+	}
+	offererTap, answererTap := newPacketTap(), newPacketTap()
+	offerer := newPeerConnection("10.0.0.1", offererTap)
+	answerer := newPeerConnection("10.0.0.2", answererTap)
+
+	track, err := offerer.NewRTCSampleTrack(DefaultPayloadTypeOpus, "audio", "pion")
+	assert.Nil(t, err)
+	_, err = offerer.AddTrack(track)
+	assert.Nil(t, err)
+	answerer.OnTrack(func(remote *RTCTrack) {
+		go func() {
+			for range remote.Packets {
+			}
+		}()
+	})
+
+	offer, err := offerer.CreateOffer(nil)
+	assert.Nil(t, err)
+	assert.Nil(t, answerer.SetRemoteDescription(offer))
+	answer, err := answerer.CreateAnswer(nil)
+	assert.Nil(t, err)
+	assert.Nil(t, offerer.SetRemoteDescription(answer))
+
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		for {
+			select {
+			case <-done:
+				return
+			case track.Samples <- media.RTCSample{Data: []byte{0x00}, Samples: 960}:
+				time.Sleep(20 * time.Millisecond)
+			}
+		}
+	}()
+
+	receiveRTP := func(tap chan *rtp.Packet) *rtp.Packet {
+		select {
+		case p := <-tap:
+			return p
+		case <-time.After(10 * time.Second):
+			t.Fatal("timed out waiting for an RTP packet")
+		}
+		return nil
+	}
+	receiveRTCP := func(tap chan []byte, expected []byte) {
+		timeout := time.After(10 * time.Second)
+		for {
+			select {
+			case raw := <-tap:
+				if bytes.Equal(expected, raw) {
+					return
+				}
+			case <-timeout:
+				t.Fatal("timed out waiting for the RTCP packet")
+			}
+		}
+	}
+
+	// Outbound packets are seen before they are encrypted, inbound ones
+	// after they are decrypted
+	assert.Equal(t, track.Ssrc, receiveRTP(offererTap.outboundRTP).SSRC)
+	assert.Equal(t, track.Ssrc, receiveRTP(answererTap.inboundRTP).SSRC)
+
+	pli := &rtcp.PictureLossIndication{MediaSSRC: track.Ssrc}
+	raw, err := pli.Marshal()
+	assert.Nil(t, err)
+	assert.Nil(t, answerer.SendRTCP(pli))
+	receiveRTCP(answererTap.outboundRTCP, raw)
+	receiveRTCP(offererTap.inboundRTCP, raw)
+
+	assert.Nil(t, offerer.Close())
+	assert.Nil(t, answerer.Close())
+}
+
+// capturedPackets are the packets captured per protocol
+type capturedPackets map[pcap.Protocol]chan pcap.Packet
+
+func newCapturedPackets() capturedPackets {
+	return capturedPackets{
+		pcap.ProtocolRTP:  make(chan pcap.Packet, 100),
+		pcap.ProtocolRTCP: make(chan pcap.Packet, 100),
+		pcap.ProtocolSCTP: make(chan pcap.Packet, 100),
+	}
+}
+
+func (c capturedPackets) capture(p pcap.Packet) {
+	select {
+	case c[p.Protocol] <- p:
+	default:
+	}
+}
+
+func TestSettingEngine_SetPacketCapture(t *testing.T) {
+	RegisterDefaultCodecs()
+	router := vnet.NewRouter()
+
+	newPeerConnection := func(ip string, captured capturedPackets, captureSCTP bool) *RTCPeerConnection {
+		n, err := router.NewNet(ip)
+		assert.Nil(t, err)
+
+		s := SettingEngine{}
+		s.SetNet(n)
+		s.SetPacketCapture(captured.capture, captureSCTP)
+		assert.NotNil(t, s.networkSettings().PacketCapture)
+		assert.Equal(t, captureSCTP, s.networkSettings().CaptureSCTP)
+
+		pc, err := NewAPI(WithSettingEngine(s)).NewRTCPeerConnection(RTCConfiguration{})
+		assert.Nil(t, err)
+		return pc
+	}
+	offererCaptured, answererCaptured := newCapturedPackets(), newCapturedPackets()
+	offerer := newPeerConnection("10.0.0.11", offererCaptured, false)
+	answerer := newPeerConnection("10.0.0.12", answererCaptured, true)
+
+	track, err := offerer.NewRTCSampleTrack(DefaultPayloadTypeOpus, "audio", "pion")
+	assert.Nil(t, err)
+	_, err = offerer.AddTrack(track)
+	assert.Nil(t, err)
+	_, err = offerer.CreateDataChannel("data", nil)
+	assert.Nil(t, err)
+	answerer.OnTrack(func(remote *RTCTrack) {
+		go func() {
+			for range remote.Packets {
+			}
+		}()
+	})
+
+	offer, err := offerer.CreateOffer(nil)
+	assert.Nil(t, err)
+	assert.Nil(t, answerer.SetRemoteDescription(offer))
+	answer, err := answerer.CreateAnswer(nil)
+	assert.Nil(t, err)
+	assert.Nil(t, offerer.SetRemoteDescription(answer))
+
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		for {
+			select {
+			case <-done:
+				return
+			case track.Samples <- media.RTCSample{Data: []byte{0x00}, Samples: 960}:
+				time.Sleep(20 * time.Millisecond)
+			}
+		}
+	}()
+
+	receive := func(captured chan pcap.Packet, inbound bool) pcap.Packet {
+		timeout := time.After(10 * time.Second)
+		for {
+			select {
+			case p := <-captured:
+				if p.Inbound == inbound {
+					return p
+				}
+			case <-timeout:
+				t.Fatal("timed out waiting for a captured packet")
+			}
+		}
+	}
+
+	// Outbound packets are captured before they are encrypted, inbound ones
+	// after they are decrypted
+	sent := receive(offererCaptured[pcap.ProtocolRTP], false)
+	assert.Equal(t, "10.0.0.11", sent.Source.IP.String())
+	assert.Equal(t, "10.0.0.12", sent.Destination.IP.String())
+	packet := &rtp.Packet{}
+	assert.Nil(t, packet.Unmarshal(sent.Payload))
+	assert.Equal(t, track.Ssrc, packet.SSRC)
+	assert.Equal(t, []byte{0x00}, packet.Payload)
+
+	received := receive(answererCaptured[pcap.ProtocolRTP], true)
+	assert.Equal(t, "10.0.0.11", received.Source.IP.String())
+	assert.Equal(t, "10.0.0.12", received.Destination.IP.String())
+	assert.Nil(t, packet.Unmarshal(received.Payload))
+	assert.Equal(t, []byte{0x00}, packet.Payload)
+
+	// SCTP packets are only captured if asked for
+	sctp := receive(answererCaptured[pcap.ProtocolSCTP], true)
+	assert.Empty(t, offererCaptured[pcap.ProtocolSCTP])
+
+	writer, err := pcap.NewWith(&bytes.Buffer{})
+	assert.Nil(t, err)
+	assert.Nil(t, writer.WritePacket(sctp))
+	assert.Nil(t, writer.WritePacket(received))
+
+	assert.Nil(t, offerer.Close())
+	assert.Nil(t, answerer.Close())
+}
+
+func TestSettingEngine_SetTrackQueuePolicy(t *testing.T) {
+	RegisterDefaultCodecs()
+
+	s := SettingEngine{}
+	policy, size := s.getTrackQueue()
+	assert.Equal(t, RTCTrackQueuePolicyBlock, policy)
+	assert.Equal(t, defaultTrackQueueSize, size)
+
+	pc, err := NewAPI(WithSettingEngine(s)).NewRTCPeerConnection(RTCConfiguration{})
+	assert.Nil(t, err)
+	track, err := pc.NewRTCSampleTrack(DefaultPayloadTypeOpus, "audio", "pion")
+	assert.Nil(t, err)
+	assert.Equal(t, defaultTrackQueueSize, cap(track.Samples))
+	assert.Nil(t, pc.Close())
+
+	// The drop policies queue the samples as they are written
+	s.SetTrackQueuePolicy(RTCTrackQueuePolicyDropOldest, 4)
+	policy, size = s.getTrackQueue()
+	assert.Equal(t, RTCTrackQueuePolicyDropOldest, policy)
+	assert.Equal(t, 4, size)
+
+	pc, err = NewAPI(WithSettingEngine(s)).NewRTCPeerConnection(RTCConfiguration{})
+	assert.Nil(t, err)
+	track, err = pc.NewRTCSampleTrack(DefaultPayloadTypeOpus, "audio", "pion")
+	assert.Nil(t, err)
+	assert.Equal(t, 0, cap(track.Samples))
+	track.Samples <- media.RTCSample{Data: []byte{0x00}, Samples: 960}
+	assert.Nil(t, pc.Close())
+}
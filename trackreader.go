@@ -0,0 +1,216 @@
+//go:build !js
+
+package webrtc
+
+import (
+	"reflect"
+	"sync"
+)
+
+// tracksPerReader bounds the tracks a reader selects on, the cost of every
+// select grows with their number
+const tracksPerReader = 64
+
+// rtcTrackInput is the Samples or RawRTP channel of a local track, write
+// sends a value received from it and full is called when the queue of the
+// channel is full, if it is set
+type rtcTrackInput struct {
+	track   *RTCTrack
+	channel reflect.Value
+	write   func(reflect.Value)
+	full    func()
+
+	// The channel is the queue of the block policy, the values received
+	// while one is written are queued up to size with the drop policies
+	policy RTCTrackQueuePolicy
+	size   int
+
+	// busy is set while a value is written and queue holds the values
+	// received meanwhile, guarded by the lock of the reader
+	busy  bool
+	queue []reflect.Value
+}
+
+// rtcTrackReader reads the channels of up to tracksPerReader local tracks, of
+// any RTCPeerConnection, on a single goroutine which only runs while it has
+// tracks. The values are written by the scheduler, one at a time and in
+// order per track. With the block policy a channel isn't read again until its
+// value is written, so a writer outpacing the network is blocked by the
+// channel, with the drop policies the channel is read at once and the values
+// are queued. The channel of a track is drained and left once its
+// RTCPeerConnection is done, or once it is closed.
+type rtcTrackReader struct {
+	sync.Mutex
+
+	inputs  []*rtcTrackInput
+	running bool
+
+	// wake interrupts the select once a value is written, to read the
+	// channel again
+	wake chan struct{}
+}
+
+// trackReaders read the channels of all the local tracks
+var trackReaders struct {
+	sync.Mutex
+	readers []*rtcTrackReader
+}
+
+// readTrackInput has the channel read by a reader with room for it
+func readTrackInput(input *rtcTrackInput) {
+	trackReaders.Lock()
+	defer trackReaders.Unlock()
+
+	for _, r := range trackReaders.readers {
+		if r.add(input) {
+			return
+		}
+	}
+	r := &rtcTrackReader{wake: make(chan struct{}, 1)}
+	trackReaders.readers = append(trackReaders.readers, r)
+	r.add(input)
+}
+
+// add reads the channel, unless the reader is full
+func (r *rtcTrackReader) add(input *rtcTrackInput) bool {
+	r.Lock()
+	defer r.Unlock()
+
+	if len(r.inputs) >= tracksPerReader {
+		return false
+	}
+	r.inputs = append(r.inputs, input)
+	if !r.running {
+		r.running = true
+		go r.run()
+	} else {
+		r.interrupt()
+	}
+	return true
+}
+
+// remove stops reading the channel
+func (r *rtcTrackReader) remove(input *rtcTrackInput) {
+	r.Lock()
+	defer r.Unlock()
+
+	for i := range r.inputs {
+		if r.inputs[i] == input {
+			r.inputs = append(r.inputs[:i], r.inputs[i+1:]...)
+			return
+		}
+	}
+}
+
+// interrupt wakes the select to read the channels again
+func (r *rtcTrackReader) interrupt() {
+	select {
+	case r.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (r *rtcTrackReader) run() {
+	var cases []reflect.SelectCase
+	var selected []*rtcTrackInput
+	wake := reflect.SelectCase{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(r.wake)}
+
+	for {
+		// Every input which isn't busy has the case of its channel followed
+		// by the case of its done channel
+		r.Lock()
+		if len(r.inputs) == 0 {
+			r.running = false
+			r.Unlock()
+			return
+		}
+		cases, selected = append(cases[:0], wake), selected[:0]
+		for _, input := range r.inputs {
+			if input.busy && input.policy == RTCTrackQueuePolicyBlock {
+				continue
+			}
+			cases = append(cases,
+				reflect.SelectCase{Dir: reflect.SelectRecv, Chan: input.channel},
+				reflect.SelectCase{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(input.track.done)},
+			)
+			selected = append(selected, input)
+		}
+		r.Unlock()
+
+		chosen, value, ok := reflect.Select(cases)
+		if chosen == 0 {
+			continue
+		}
+
+		input := selected[(chosen-1)/2]
+		switch {
+		case (chosen-1)%2 == 1:
+			// Values written before the RTCPeerConnection was closed are
+			// never sent
+			for {
+				if _, ok := input.channel.TryRecv(); !ok {
+					break
+				}
+			}
+			r.remove(input)
+		case !ok:
+			r.remove(input)
+		default:
+			r.receive(input, value)
+		}
+	}
+}
+
+// receive has the scheduler write the value, unless another value of the
+// channel is written. Then it is queued, and a value is discarded if the
+// queue is full.
+func (r *rtcTrackReader) receive(input *rtcTrackInput, value reflect.Value) {
+	r.Lock()
+	if !input.busy {
+		input.busy = true
+		r.Unlock()
+		workers.schedule(func() { r.write(input, value) })
+		return
+	}
+
+	input.queue = append(input.queue, value)
+	full := len(input.queue) > input.size
+	if full && input.policy == RTCTrackQueuePolicyDropOldest {
+		input.queue = input.queue[1:]
+	} else if full {
+		input.queue = input.queue[:len(input.queue)-1]
+	}
+	r.Unlock()
+
+	if full && input.full != nil {
+		input.full()
+	}
+}
+
+// write writes the value and the values queued meanwhile, then the channel
+// is read again. The values still queued once the RTCPeerConnection is done
+// are discarded.
+func (r *rtcTrackReader) write(input *rtcTrackInput, value reflect.Value) {
+	for {
+		input.write(value)
+
+		r.Lock()
+		if len(input.queue) == 0 || input.track.isDone() {
+			input.busy = false
+			input.queue = nil
+			// Writers of a full channel were blocked meanwhile
+			full := input.policy == RTCTrackQueuePolicyBlock &&
+				input.channel.Cap() > 0 && input.channel.Len() == input.channel.Cap()
+			r.Unlock()
+
+			r.interrupt()
+			if full && input.full != nil {
+				input.full()
+			}
+			return
+		}
+		value = input.queue[0]
+		input.queue = input.queue[1:]
+		r.Unlock()
+	}
+}
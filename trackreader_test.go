@@ -0,0 +1,142 @@
+//go:build !js
+
+package webrtc
+
+import (
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRTCTrackReader(t *testing.T) {
+	done := make(chan struct{})
+	r := &rtcTrackReader{wake: make(chan struct{}, 1)}
+
+	var lock sync.Mutex
+	written := map[int][]int{}
+	channels := make([]chan int, tracksPerReader)
+	for i := range channels {
+		i := i
+		channels[i] = make(chan int, 4)
+		assert.True(t, r.add(&rtcTrackInput{
+			track:   &RTCTrack{done: done},
+			channel: reflect.ValueOf(channels[i]),
+			write: func(v reflect.Value) {
+				lock.Lock()
+				written[i] = append(written[i], int(v.Int()))
+				lock.Unlock()
+			},
+			policy: RTCTrackQueuePolicyBlock,
+		}))
+	}
+	assert.False(t, r.add(&rtcTrackInput{}))
+
+	// The values of a channel are written in order
+	for i, c := range channels {
+		for j := 0; j < 10; j++ {
+			c <- i*100 + j
+		}
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		lock.Lock()
+		complete := len(written) == len(channels)
+		for _, values := range written {
+			complete = complete && len(values) == 10
+		}
+		lock.Unlock()
+		if complete || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	lock.Lock()
+	for i := range channels {
+		for j, v := range written[i] {
+			assert.Equal(t, i*100+j, v, "track: %d", i)
+		}
+	}
+	lock.Unlock()
+
+	// Closed channels are left
+	close(channels[0])
+	// Once done, the channels are drained and left
+	channels[1] <- 0
+	close(done)
+
+	deadline = time.Now().Add(5 * time.Second)
+	for {
+		r.Lock()
+		running := r.running
+		r.Unlock()
+		if !running || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	assert.False(t, r.running)
+	assert.Empty(t, r.inputs)
+	assert.Equal(t, 0, len(channels[1]))
+}
+
+func TestRTCTrackReader_QueuePolicy(t *testing.T) {
+	testCases := []struct {
+		policy   RTCTrackQueuePolicy
+		expected []int
+	}{
+		{RTCTrackQueuePolicyDropOldest, []int{0, 3, 4}},
+		{RTCTrackQueuePolicyDropNewest, []int{0, 1, 2}},
+	}
+
+	for i, testCase := range testCases {
+		done := make(chan struct{})
+		r := &rtcTrackReader{wake: make(chan struct{}, 1)}
+
+		// The first value is written once the others are queued
+		release := make(chan struct{})
+		var full int32
+		var lock sync.Mutex
+		var written []int
+		channel := make(chan int)
+		r.add(&rtcTrackInput{
+			track:   &RTCTrack{done: done},
+			channel: reflect.ValueOf(channel),
+			write: func(v reflect.Value) {
+				<-release
+				lock.Lock()
+				written = append(written, int(v.Int()))
+				lock.Unlock()
+			},
+			full:   func() { atomic.AddInt32(&full, 1) },
+			policy: testCase.policy,
+			size:   2,
+		})
+
+		for v := 0; v < 5; v++ {
+			channel <- v
+		}
+		for deadline := time.Now().Add(5 * time.Second); atomic.LoadInt32(&full) < 2 && time.Now().Before(deadline); {
+			time.Sleep(time.Millisecond)
+		}
+		close(release)
+
+		for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); {
+			lock.Lock()
+			n := len(written)
+			lock.Unlock()
+			if n == len(testCase.expected) {
+				break
+			}
+			time.Sleep(time.Millisecond)
+		}
+		lock.Lock()
+		assert.Equal(t, testCase.expected, written, "testCase: %d", i)
+		lock.Unlock()
+		assert.Equal(t, int32(2), atomic.LoadInt32(&full), "testCase: %d", i)
+		close(done)
+	}
+}
@@ -0,0 +1,53 @@
+//go:build !js
+
+package webrtc
+
+import (
+	"net"
+
+	"github.com/pions/webrtc/internal/network"
+)
+
+// ErrUDPMuxUnspecifiedIP indicates that a UDPMux is not bound to a specific
+// IP, which its host candidates need.
+var ErrUDPMuxUnspecifiedIP = network.ErrUDPMuxUnspecifiedIP
+
+// ErrUDPMuxUfragInUse indicates that a RTCPeerConnection of the UDPMux
+// already has the ICE ufrag, the packets of its remote peer couldn't be told
+// apart.
+var ErrUDPMuxUfragInUse = network.ErrUDPMuxUfragInUse
+
+// UDPMux shares a single UDP port between RTCPeerConnections, so servers
+// hosting many of them only need to open one port in their firewall. The
+// packets of the remote peers are told apart by the ICE ufrag of their
+// connectivity checks. Pass it to SettingEngine.SetUDPMux.
+type UDPMux struct {
+	mux *network.UDPMux
+}
+
+// NewUDPMux listens on the UDP address, which has to have a specific IP
+// since it is announced in the host candidate of every RTCPeerConnection
+func NewUDPMux(address string) (*UDPMux, error) {
+	conn, err := net.ListenPacket("udp", address)
+	if err != nil {
+		return nil, err
+	}
+
+	mux, err := network.NewUDPMux(conn)
+	if err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+	return &UDPMux{mux: mux}, nil
+}
+
+// LocalAddr returns the address the UDPMux listens on
+func (m *UDPMux) LocalAddr() net.Addr {
+	return m.mux.LocalAddr()
+}
+
+// Close stops listening, the RTCPeerConnections using the UDPMux lose
+// their host candidate
+func (m *UDPMux) Close() error {
+	return m.mux.Close()
+}
@@ -0,0 +1,130 @@
+//go:build !js
+
+package webrtc
+
+import (
+	"sync"
+	"time"
+
+	"github.com/pions/webrtc/pkg/logging"
+)
+
+// unhandledEventPollInterval is how often held events check whether their
+// handler has been set
+const unhandledEventPollInterval = 10 * time.Millisecond
+
+// unhandledEvent is an event held until its handler is set. delivery returns
+// the invocation of the handler, or nil while the handler is unset.
+type unhandledEvent struct {
+	description string
+	delivery    func() func()
+	expires     time.Time
+}
+
+// rtcUnhandledEvents holds the remote tracks, data channels and data channel
+// messages which arrive while their handler is unset, for the window set by
+// SettingEngine.SetUnhandledEventWindow. Handlers are commonly set just after
+// New returns, when the first events may already have raced in. Held events
+// are delivered in the order they arrived.
+type rtcUnhandledEvents struct {
+	sync.Mutex
+
+	window  time.Duration
+	pending []*unhandledEvent
+	running bool
+
+	log  logging.LeveledLogger
+	done <-chan struct{}
+}
+
+func newRTCUnhandledEvents(window time.Duration, log logging.LeveledLogger, done <-chan struct{}) *rtcUnhandledEvents {
+	return &rtcUnhandledEvents{
+		window: window,
+		log:    log,
+		done:   done,
+	}
+}
+
+// enabled reports whether events are held at all
+func (q *rtcUnhandledEvents) enabled() bool {
+	return q.window > 0
+}
+
+// dispatch returns the invocation of the handler of the event if it is set
+// and no earlier event is held, the caller is responsible for running it.
+// Otherwise the event is held and delivered once its handler is set, held is
+// false if the event is discarded because holding events is disabled.
+func (q *rtcUnhandledEvents) dispatch(description string, delivery func() func()) (deliver func(), held bool) {
+	q.Lock()
+	defer q.Unlock()
+
+	if len(q.pending) == 0 {
+		if deliver := delivery(); deliver != nil {
+			return deliver, false
+		}
+	}
+	if !q.enabled() {
+		return nil, false
+	}
+
+	q.pending = append(q.pending, &unhandledEvent{
+		description: description,
+		delivery:    delivery,
+		expires:     time.Now().Add(q.window),
+	})
+	if !q.running {
+		q.running = true
+		workers.schedule(q.run)
+	}
+	return nil, true
+}
+
+func (q *rtcUnhandledEvents) run() {
+	ticker := time.NewTicker(unhandledEventPollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-q.done:
+			q.Lock()
+			q.pending = nil
+			q.running = false
+			q.Unlock()
+			return
+		case now := <-ticker.C:
+			if !q.deliver(now) {
+				return
+			}
+		}
+	}
+}
+
+// deliver invokes the handlers which have been set since the last call and
+// discards the expired events. It returns false once no event is held.
+func (q *rtcUnhandledEvents) deliver(now time.Time) bool {
+	q.Lock()
+	defer q.Unlock()
+
+	for i := 0; i < len(q.pending); {
+		e := q.pending[i]
+		if deliver := e.delivery(); deliver != nil {
+			// The event stays pending while its handler runs, so events
+			// arriving meanwhile are held behind it
+			q.Unlock()
+			deliver()
+			q.Lock()
+		} else if now.After(e.expires) {
+			q.log.Warnf("Handler of %s is unset, discarding it", e.description)
+		} else {
+			i++
+			continue
+		}
+		q.pending = append(q.pending[:i], q.pending[i+1:]...)
+	}
+
+	if len(q.pending) == 0 {
+		q.running = false
+		return false
+	}
+	return true
+}
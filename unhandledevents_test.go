@@ -0,0 +1,89 @@
+//go:build !js
+
+package webrtc
+
+import (
+	"io/ioutil"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pions/webrtc/pkg/logging"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRTCUnhandledEvents_dispatch(t *testing.T) {
+	done := make(chan struct{})
+	defer close(done)
+
+	var lock sync.Mutex
+	var handler func(string)
+	delivery := func(event string) func() func() {
+		return func() func() {
+			lock.Lock()
+			defer lock.Unlock()
+			if handler == nil {
+				return nil
+			}
+			h := handler
+			return func() { h(event) }
+		}
+	}
+
+	// Events are discarded without a window
+	q := newRTCUnhandledEvents(0, logging.NewDefaultLeveledLoggerForScope(logging.ScopePC, logging.LogLevelDisabled, ioutil.Discard), done)
+	deliver, held := q.dispatch("a", delivery("a"))
+	assert.Nil(t, deliver)
+	assert.False(t, held)
+
+	q = newRTCUnhandledEvents(time.Second, logging.NewDefaultLeveledLoggerForScope(logging.ScopePC, logging.LogLevelDisabled, ioutil.Discard), done)
+	for _, event := range []string{"a", "b"} {
+		deliver, held = q.dispatch(event, delivery(event))
+		assert.Nil(t, deliver)
+		assert.True(t, held)
+	}
+
+	delivered := make(chan string, 3)
+	lock.Lock()
+	handler = func(event string) { delivered <- event }
+	lock.Unlock()
+
+	// Events arriving while earlier ones are held queue up behind them
+	deliver, held = q.dispatch("c", delivery("c"))
+	assert.Nil(t, deliver)
+	assert.True(t, held)
+
+	for _, expected := range []string{"a", "b", "c"} {
+		select {
+		case event := <-delivered:
+			assert.Equal(t, expected, event)
+		case <-time.After(time.Second):
+			t.Fatalf("%s was not delivered", expected)
+		}
+	}
+
+	// Once nothing is held events are delivered by the caller
+	time.Sleep(5 * unhandledEventPollInterval)
+	deliver, held = q.dispatch("d", delivery("d"))
+	assert.NotNil(t, deliver)
+	assert.False(t, held)
+}
+
+func TestRTCUnhandledEvents_deliver(t *testing.T) {
+	done := make(chan struct{})
+	defer close(done)
+
+	q := newRTCUnhandledEvents(time.Minute, logging.NewDefaultLeveledLoggerForScope(logging.ScopePC, logging.LogLevelDisabled, ioutil.Discard), done)
+	unset := func() func() { return nil }
+	q.pending = []*unhandledEvent{
+		{description: "expired", delivery: unset, expires: time.Unix(1, 0)},
+		{description: "waiting", delivery: unset, expires: time.Unix(3, 0)},
+	}
+
+	assert.True(t, q.deliver(time.Unix(2, 0)))
+	if assert.Equal(t, 1, len(q.pending)) {
+		assert.Equal(t, "waiting", q.pending[0].description)
+	}
+	assert.False(t, q.deliver(time.Unix(4, 0)))
+	assert.Empty(t, q.pending)
+}